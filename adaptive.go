@@ -0,0 +1,235 @@
+package fastalice
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// LimitSample is a request served under AdaptiveLimit.
+type LimitSample struct {
+	// Latency is how long the following handlers took.
+	Latency time.Duration
+	// InFlight is the number of requests in flight
+	// when the request started, itself included.
+	InFlight int
+	// Dropped reports whether the request failed from overload,
+	// answered with 503 Service Unavailable or 504 Gateway Timeout.
+	Dropped bool
+}
+
+// LimitAlgorithm adjusts the concurrency limit of AdaptiveLimit.
+// AdaptiveLimit serializes its calls,
+// so an algorithm must not be shared between middleware.
+type LimitAlgorithm interface {
+	// InitialLimit returns the limit to start with.
+	InitialLimit() int
+	// Update returns the new limit from the current one
+	// once the request described by sample completed.
+	Update(limit int, sample LimitSample) int
+}
+
+// AIMD is a LimitAlgorithm growing the limit by one
+// after each successful request using at least half of it,
+// and multiplying it by Backoff after each dropped
+// or too slow request, like TCP congestion control.
+// Zero fields take the documented defaults.
+type AIMD struct {
+	// Initial is the starting limit, 20 by default.
+	Initial int
+	// Min and Max bound the limit, 1 and 1000 by default.
+	Min, Max int
+	// Backoff is the factor applied to the limit on drops, 0.9 by default.
+	Backoff float64
+	// Timeout is the latency above which a request counts as dropped,
+	// 5 seconds by default.
+	Timeout time.Duration
+}
+
+func (a *AIMD) InitialLimit() int {
+	return orDefault(a.Initial, 20)
+}
+
+func (a *AIMD) Update(limit int, s LimitSample) int {
+	timeout := a.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	backoff := a.Backoff
+	if backoff <= 0 || backoff >= 1 {
+		backoff = 0.9
+	}
+
+	switch {
+	case s.Dropped || s.Latency > timeout:
+		limit = int(float64(limit) * backoff)
+	case 2*s.InFlight >= limit:
+		limit++
+	}
+	return clampLimit(limit, orDefault(a.Min, 1), orDefault(a.Max, 1000))
+}
+
+// Gradient is a LimitAlgorithm, like the Gradient2 limit
+// of Netflix concurrency-limits, comparing the latency of each request
+// to the long-term average latency: the limit grows while they match
+// and shrinks as requests queue up and the latency climbs.
+// Zero fields take the documented defaults.
+type Gradient struct {
+	// Initial is the starting limit, 20 by default.
+	Initial int
+	// Min and Max bound the limit, 1 and 1000 by default.
+	Min, Max int
+	// Smoothing is the weight of each new limit, 0.2 by default.
+	Smoothing float64
+	// Tolerance is the ratio of the latency to the long-term latency
+	// tolerated before shrinking the limit, 1.5 by default.
+	Tolerance float64
+	// Window is the number of samples the long-term latency
+	// is averaged over, 600 by default.
+	Window int
+
+	estimate float64
+	longRTT  float64
+}
+
+func (g *Gradient) InitialLimit() int {
+	return orDefault(g.Initial, 20)
+}
+
+func (g *Gradient) Update(limit int, s LimitSample) int {
+	smoothing := g.Smoothing
+	if smoothing <= 0 || smoothing > 1 {
+		smoothing = 0.2
+	}
+	tolerance := g.Tolerance
+	if tolerance < 1 {
+		tolerance = 1.5
+	}
+	window := float64(orDefault(g.Window, 600))
+
+	if g.estimate == 0 {
+		g.estimate = float64(limit)
+	}
+	rtt := float64(s.Latency)
+	if rtt <= 0 {
+		rtt = 1
+	}
+	if g.longRTT == 0 {
+		g.longRTT = rtt
+	}
+	g.longRTT += (rtt - g.longRTT) / window
+	// Let the long-term latency recover quickly after a load spike.
+	if g.longRTT/rtt > 2 {
+		g.longRTT *= 0.95
+	}
+
+	// An application using less than half of the limit
+	// says nothing about how far it could grow.
+	if 2*s.InFlight < limit && !s.Dropped {
+		return limit
+	}
+
+	gradient := math.Max(0.5, math.Min(1, tolerance*g.longRTT/rtt))
+	if s.Dropped {
+		gradient = 0.5
+	}
+	queue := math.Sqrt(g.estimate)
+	g.estimate = g.estimate*(1-smoothing) + (g.estimate*gradient+queue)*smoothing
+	min, max := orDefault(g.Min, 1), orDefault(g.Max, 1000)
+	g.estimate = math.Max(float64(min), math.Min(float64(max), g.estimate))
+	return clampLimit(int(g.estimate), min, max)
+}
+
+// AdaptiveLimit returns a constructor bounding the requests
+// inside the following handlers at the same time by a limit
+// that algorithm adjusts from their latency,
+// such as AIMD or Gradient, in the style of Netflix concurrency-limits:
+// the limit follows what the handlers and their upstreams can take
+// instead of being tuned by hand.
+// Requests beyond the limit are answered with 503 Service Unavailable
+// without calling the following handlers.
+//
+//	fastalice.New(fastalice.AdaptiveLimit(&fastalice.Gradient{Max: 500}))
+//
+// The limit is shared by every handler built from the returned constructor.
+func AdaptiveLimit(algorithm LimitAlgorithm) Constructor {
+	l := &adaptiveLimiter{algorithm: algorithm, limit: algorithm.InitialLimit()}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			inFlight, ok := l.acquire()
+			if !ok {
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusServiceUnavailable), fasthttp.StatusServiceUnavailable)
+				return
+			}
+
+			start := now()
+			completed := false
+			defer func() {
+				if !completed {
+					l.release(nil)
+				}
+			}()
+			next(ctx)
+			completed = true
+
+			status := ctx.Response.StatusCode()
+			l.release(&LimitSample{
+				Latency:  now().Sub(start),
+				InFlight: inFlight,
+				Dropped:  status == fasthttp.StatusServiceUnavailable || status == fasthttp.StatusGatewayTimeout,
+			})
+		}
+	}
+}
+
+// adaptiveLimiter is the state of an AdaptiveLimit middleware.
+type adaptiveLimiter struct {
+	algorithm LimitAlgorithm
+
+	mu       sync.Mutex
+	limit    int
+	inFlight int
+}
+
+// acquire counts a request in flight if the limit allows it,
+// returning the number of requests in flight.
+func (l *adaptiveLimiter) acquire() (int, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inFlight >= l.limit {
+		return 0, false
+	}
+	l.inFlight++
+	return l.inFlight, true
+}
+
+// release counts a request out, updating the limit from sample,
+// unless it is nil as for panicking requests.
+func (l *adaptiveLimiter) release(sample *LimitSample) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+	if sample != nil {
+		l.limit = l.algorithm.Update(l.limit, *sample)
+	}
+}
+
+func clampLimit(limit, min, max int) int {
+	if limit < min {
+		return min
+	}
+	if limit > max {
+		return max
+	}
+	return limit
+}
+
+func orDefault(v, fallback int) int {
+	if v <= 0 {
+		return fallback
+	}
+	return v
+}
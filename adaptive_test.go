@@ -0,0 +1,85 @@
+package fastalice
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestAIMD(t *testing.T) {
+	a := &AIMD{Initial: 10, Max: 12, Timeout: time.Second}
+	assert.Equal(t, 10, a.InitialLimit(), "The initial limit should be used")
+
+	limit := a.Update(10, LimitSample{Latency: time.Millisecond, InFlight: 5})
+	assert.Equal(t, 11, limit, "A busy successful request should grow the limit")
+	assert.Equal(t, 11, a.Update(limit, LimitSample{Latency: time.Millisecond, InFlight: 1}), "An idle request should keep the limit")
+	assert.Equal(t, 12, a.Update(12, LimitSample{Latency: time.Millisecond, InFlight: 12}), "The limit should not exceed Max")
+
+	assert.Equal(t, 9, a.Update(10, LimitSample{Latency: time.Millisecond, InFlight: 5, Dropped: true}), "A drop should back off")
+	assert.Equal(t, 9, a.Update(10, LimitSample{Latency: 2 * time.Second, InFlight: 5}), "A slow request should back off")
+	assert.Equal(t, 1, a.Update(1, LimitSample{Dropped: true}), "The limit should not go below Min")
+}
+
+func TestGradient(t *testing.T) {
+	g := &Gradient{Initial: 20, Window: 10}
+	limit := g.InitialLimit()
+	for i := 0; i < 20; i++ {
+		limit = g.Update(limit, LimitSample{Latency: 10 * time.Millisecond, InFlight: limit})
+	}
+	grown := limit
+	assert.Greater(t, grown, 20, "A steady latency at full use should grow the limit")
+
+	for i := 0; i < 5; i++ {
+		limit = g.Update(limit, LimitSample{Latency: 100 * time.Millisecond, InFlight: limit})
+	}
+	assert.Less(t, limit, grown, "A climbing latency should shrink the limit")
+
+	idle := g.Update(limit, LimitSample{Latency: 10 * time.Millisecond, InFlight: 1})
+	assert.Equal(t, limit, idle, "An application using little of the limit should keep it")
+}
+
+func TestAdaptiveLimitRejectsBeyondLimit(t *testing.T) {
+	release := make(chan struct{})
+	entered := make(chan struct{}, 2)
+	h := New(AdaptiveLimit(&AIMD{Initial: 2})).Then(func(ctx *fasthttp.RequestCtx) {
+		entered <- struct{}{}
+		<-release
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h(newTestCtx("GET", "http://localhost/"))
+		}()
+	}
+	<-entered
+	<-entered
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, ctx.Response.StatusCode(), "Requests beyond the limit should be rejected")
+
+	close(release)
+	wg.Wait()
+	ctx = newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Requests should be served once slots free up")
+}
+
+func TestAdaptiveLimitReleasesOnPanic(t *testing.T) {
+	h := New(AdaptiveLimit(&AIMD{Initial: 1})).Then(func(ctx *fasthttp.RequestCtx) {
+		if ctx.QueryArgs().Has("panic") {
+			panic("boom")
+		}
+	})
+
+	assert.Panics(t, func() { h(newTestCtx("GET", "http://localhost/?panic")) }, "The panic should propagate")
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "The slot of a panicking request should be freed")
+}
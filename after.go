@@ -0,0 +1,21 @@
+package fastalice
+
+import (
+	"github.com/valyala/fasthttp"
+)
+
+// After returns a constructor that runs fn
+// once the following handlers have returned.
+//
+// fn is deferred, so it still runs
+// when one of the following handlers panics;
+// place a recovering middleware before it
+// to turn such a panic into a response.
+func After(fn func(ctx *fasthttp.RequestCtx)) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			defer fn(ctx)
+			next(ctx)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestAfterRunsAfterHandler(t *testing.T) {
+	var body string
+	after := After(func(ctx *fasthttp.RequestCtx) {
+		body = string(ctx.Response.Body())
+		ctx.WriteString("\nafter")
+	})
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(tagMiddleware("t1\n"), after).Then(testApp)(ctx)
+	assert.Equal(t, "t1\napp", body, "After should see the body written by the handler")
+	assert.Equal(t, "t1\napp\nafter", string(ctx.Response.Body()), "After should run once the handler returned")
+}
+
+func TestAfterRunsOnPanic(t *testing.T) {
+	ran := false
+	h := New(After(func(ctx *fasthttp.RequestCtx) {
+		ran = true
+	})).Then(func(ctx *fasthttp.RequestCtx) {
+		panic("boom")
+	})
+
+	assert.Panics(t, func() { h(newTestCtx("GET", "http://localhost/")) }, "The panic should propagate")
+	assert.True(t, ran, "After should run even when the handler panics")
+}
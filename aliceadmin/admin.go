@@ -0,0 +1,392 @@
+// Package aliceadmin serves an admin API managing fastalice chains
+// at runtime: listing and explaining them, toggling their middleware groups,
+// rebuilding them from configuration, flipping maintenance mode
+// and tuning settings such as rate limits.
+//
+// It lives apart from fastalice so that applications
+// not exposing such an API do not ship it.
+package aliceadmin
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/brunvieira/fastalice"
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultAllowedIPs are the clients allowed by the admin API
+// when Options sets neither AllowedIPs nor Auth.
+var DefaultAllowedIPs = []string{"127.0.0.0/8", "::1"}
+
+// Options configures an Admin.
+type Options struct {
+	// AllowedIPs lists the IPs and CIDR ranges
+	// allowed to reach the endpoints.
+	AllowedIPs []string
+	// Auth gates the endpoints, such as fastalice.BasicAuth.
+	// It runs after the IP check.
+	Auth fastalice.Constructor
+	// Registry, when set, lets chains be rebuilt
+	// from a configuration with Registry.BuildFromConfig.
+	Registry *fastalice.Registry
+	// Maintenance, when set, is flipped by the maintenance endpoint.
+	Maintenance *fastalice.MaintenanceState
+	// Configs are the configs exposed by the settings endpoints,
+	// by name, such as the config of a rate limiter
+	// set up with fastalice.ConfigureWith.
+	Configs map[string]*fastalice.Config
+}
+
+// Admin serves the admin API of the chains it manages.
+type Admin struct {
+	prefix  string
+	opts    Options
+	handler fasthttp.RequestHandler
+
+	mu     sync.Mutex
+	chains map[string]*managedChain
+}
+
+// managedChain is a chain managed by an Admin.
+type managedChain struct {
+	swappable *fastalice.SwappableChain
+	// base is the chain with every group enabled,
+	// served without the disabled groups.
+	base     fastalice.Chain
+	disabled []string
+}
+
+// NewAdmin returns an Admin serving its endpoints under prefix,
+// such as "/admin", once added to a chain with Middleware:
+//
+//	GET    prefix/chains                        the managed chains
+//	GET    prefix/chains/{name}                 the Explain output of a chain
+//	PUT    prefix/chains/{name}                 rebuild a chain from a JSON or YAML config
+//	POST   prefix/chains/{name}/groups/{group}  enable a group of a chain
+//	DELETE prefix/chains/{name}/groups/{group}  disable a group of a chain
+//	*      prefix/maintenance                   see fastalice.MaintenanceState.Handler
+//	GET    prefix/settings/{name}               the settings of a config
+//	PATCH  prefix/settings/{name}               change settings from a JSON object
+//
+// Settings changes are validated before being applied:
+// fastalice.SettingLimit must be a positive integer,
+// fastalice.SettingProbability a number between 0 and 1,
+// and other settings must already be in the config,
+// keeping their type; invalid changes are answered
+// with 400 Bad Request and a problem listing them.
+//
+// Every endpoint answers in JSON.
+// Requests from clients outside of opts.AllowedIPs are answered
+// with 403 Forbidden; without AllowedIPs nor Auth,
+// only DefaultAllowedIPs are allowed.
+//
+// An error is returned if any of the allowed IPs is malformed.
+func NewAdmin(prefix string, opts Options) (*Admin, error) {
+	a := &Admin{
+		prefix: strings.TrimSuffix(prefix, "/"),
+		opts:   opts,
+		chains: make(map[string]*managedChain),
+	}
+
+	allowed := opts.AllowedIPs
+	if len(allowed) == 0 && opts.Auth == nil {
+		allowed = DefaultAllowedIPs
+	}
+	guards := fastalice.New()
+	if len(allowed) > 0 {
+		filter, err := fastalice.NewIPFilter(allowed, nil)
+		if err != nil {
+			return nil, err
+		}
+		guards = guards.Append(filter)
+	}
+	a.handler = guards.Append(opts.Auth).Then(a.serve)
+	return a, nil
+}
+
+// Manage makes the chain served by s manageable under name.
+// Its current chain is taken as having every group enabled.
+// Manage panics if name is already managed.
+func (a *Admin) Manage(name string, s *fastalice.SwappableChain) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.chains[name]; ok {
+		panic(fmt.Sprintf("aliceadmin: chain %q managed twice", name))
+	}
+	a.chains[name] = &managedChain{swappable: s, base: s.Chain()}
+}
+
+// Middleware returns a constructor serving the endpoints under the prefix
+// without calling the following handlers.
+// Requests to any other path pass through.
+func (a *Admin) Middleware() fastalice.Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			path := string(ctx.Path())
+			if path != a.prefix && !strings.HasPrefix(path, a.prefix+"/") {
+				next(ctx)
+				return
+			}
+			a.handler(ctx)
+		}
+	}
+}
+
+// chainInfo describes a managed chain.
+type chainInfo struct {
+	Name       string   `json:"name"`
+	Middleware []string `json:"middleware"`
+	Groups     []string `json:"groups"`
+	Disabled   []string `json:"disabled"`
+}
+
+// chainDetail is the Explain output of a managed chain.
+type chainDetail struct {
+	Name         string                      `json:"name"`
+	Disabled     []string                    `json:"disabled"`
+	Constructors []fastalice.ConstructorInfo `json:"constructors"`
+}
+
+// serve dispatches a request under the prefix to its endpoint.
+func (a *Admin) serve(ctx *fasthttp.RequestCtx) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(string(ctx.Path()), a.prefix), "/"), "/")
+
+	switch {
+	case len(parts) == 1 && parts[0] == "chains" && ctx.IsGet():
+		a.listChains(ctx)
+	case len(parts) == 2 && parts[0] == "chains":
+		switch {
+		case ctx.IsGet():
+			a.explainChain(ctx, parts[1])
+		case ctx.IsPut():
+			a.rebuildChain(ctx, parts[1])
+		default:
+			writeError(ctx, fasthttp.StatusMethodNotAllowed, "method not allowed")
+		}
+	case len(parts) == 4 && parts[0] == "chains" && parts[2] == "groups":
+		switch {
+		case ctx.IsPost():
+			a.toggleGroup(ctx, parts[1], parts[3], true)
+		case ctx.IsDelete():
+			a.toggleGroup(ctx, parts[1], parts[3], false)
+		default:
+			writeError(ctx, fasthttp.StatusMethodNotAllowed, "method not allowed")
+		}
+	case len(parts) == 1 && parts[0] == "maintenance" && a.opts.Maintenance != nil:
+		a.opts.Maintenance.Handler()(ctx)
+	case len(parts) == 2 && parts[0] == "settings":
+		a.settings(ctx, parts[1])
+	default:
+		writeError(ctx, fasthttp.StatusNotFound, "not found")
+	}
+}
+
+func (a *Admin) listChains(ctx *fasthttp.RequestCtx) {
+	a.mu.Lock()
+	infos := make([]chainInfo, 0, len(a.chains))
+	for name, mc := range a.chains {
+		infos = append(infos, chainInfo{
+			Name:       name,
+			Middleware: mc.base.Names(),
+			Groups:     distinct(mc.base.Groups()),
+			Disabled:   append([]string{}, mc.disabled...),
+		})
+	}
+	a.mu.Unlock()
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	writeJSON(ctx, fasthttp.StatusOK, infos)
+}
+
+func (a *Admin) explainChain(ctx *fasthttp.RequestCtx, name string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	mc, ok := a.chains[name]
+	if !ok {
+		writeError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("unknown chain %q", name))
+		return
+	}
+	writeJSON(ctx, fasthttp.StatusOK, a.detail(name, mc))
+}
+
+func (a *Admin) detail(name string, mc *managedChain) chainDetail {
+	return chainDetail{
+		Name:         name,
+		Disabled:     append([]string{}, mc.disabled...),
+		Constructors: mc.swappable.Chain().Explain(),
+	}
+}
+
+// rebuildChain replaces a chain with the one built
+// by the registry from the request body.
+func (a *Admin) rebuildChain(ctx *fasthttp.RequestCtx, name string) {
+	if a.opts.Registry == nil {
+		writeError(ctx, fasthttp.StatusNotImplemented, "no registry configured")
+		return
+	}
+	format := "json"
+	if strings.Contains(string(ctx.Request.Header.ContentType()), "yaml") {
+		format = "yaml"
+	}
+	chain, err := a.opts.Registry.BuildFromConfig(ctx.PostBody(), format)
+	if err != nil {
+		writeError(ctx, fasthttp.StatusBadRequest, err.Error())
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	mc, ok := a.chains[name]
+	if !ok {
+		writeError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("unknown chain %q", name))
+		return
+	}
+	mc.base = chain
+	mc.swappable.Swap(chain.WithoutGroups(mc.disabled...))
+	writeJSON(ctx, fasthttp.StatusOK, a.detail(name, mc))
+}
+
+// toggleGroup enables or disables a group of a chain.
+func (a *Admin) toggleGroup(ctx *fasthttp.RequestCtx, name, group string, enable bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	mc, ok := a.chains[name]
+	if !ok {
+		writeError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("unknown chain %q", name))
+		return
+	}
+	if !contains(mc.base.Groups(), group) {
+		writeError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("unknown group %q", group))
+		return
+	}
+
+	disabled := make([]string, 0, len(mc.disabled)+1)
+	for _, g := range mc.disabled {
+		if g != group {
+			disabled = append(disabled, g)
+		}
+	}
+	if !enable {
+		disabled = append(disabled, group)
+	}
+	mc.disabled = disabled
+	mc.swappable.Swap(mc.base.WithoutGroups(disabled...))
+	writeJSON(ctx, fasthttp.StatusOK, a.detail(name, mc))
+}
+
+// settings shows or changes the settings of a config.
+func (a *Admin) settings(ctx *fasthttp.RequestCtx, name string) {
+	cfg, ok := a.opts.Configs[name]
+	if !ok {
+		writeError(ctx, fasthttp.StatusNotFound, fmt.Sprintf("unknown config %q", name))
+		return
+	}
+
+	switch {
+	case ctx.IsGet():
+	case string(ctx.Method()) == fasthttp.MethodPatch:
+		var changes map[string]interface{}
+		if err := json.Unmarshal(ctx.PostBody(), &changes); err != nil {
+			writeError(ctx, fasthttp.StatusBadRequest, "body must be a JSON object")
+			return
+		}
+		// Config.Apply calls must be serialized.
+		a.mu.Lock()
+		invalid := validateSettings(cfg, changes)
+		if len(invalid) == 0 {
+			cfg.Apply(fastalice.Settings(changes))
+		}
+		a.mu.Unlock()
+		if len(invalid) > 0 {
+			fastalice.WriteProblem(ctx, fastalice.Problem{
+				Type:       "about:blank",
+				Title:      fasthttp.StatusMessage(fasthttp.StatusBadRequest),
+				Status:     fasthttp.StatusBadRequest,
+				Detail:     "invalid settings",
+				Extensions: map[string]interface{}{"invalid": invalid},
+			})
+			return
+		}
+	default:
+		writeError(ctx, fasthttp.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(ctx, fasthttp.StatusOK, cfg.Snapshot())
+}
+
+// validateSettings returns why each invalid setting of changes is rejected,
+// by name. The shipped settings must be numbers in their range;
+// other settings must already be in cfg, and keep the JSON type they have.
+func validateSettings(cfg *fastalice.Config, changes map[string]interface{}) map[string]string {
+	invalid := make(map[string]string)
+	for name, value := range changes {
+		n, isNumber := value.(float64)
+		switch name {
+		case fastalice.SettingLimit:
+			if !isNumber || n <= 0 || n != float64(int(n)) {
+				invalid[name] = "must be a positive integer"
+			}
+		case fastalice.SettingProbability:
+			if !isNumber || n < 0 || n > 1 {
+				invalid[name] = "must be a number between 0 and 1"
+			}
+		default:
+			current, ok := cfg.Get(name)
+			if !ok {
+				invalid[name] = "unknown setting"
+			} else if jsonType(current) != jsonType(value) {
+				invalid[name] = "must be a " + jsonType(current)
+			}
+		}
+	}
+	return invalid
+}
+
+// jsonType returns the JSON type v is encoded as.
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float32, float64, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "number"
+	case nil:
+		return "null"
+	}
+	return "JSON value"
+}
+
+func writeJSON(ctx *fasthttp.RequestCtx, code int, v interface{}) {
+	fastalice.JSON(ctx, code, v)
+}
+
+func writeError(ctx *fasthttp.RequestCtx, code int, msg string) {
+	writeJSON(ctx, code, struct {
+		Error string `json:"error"`
+	}{msg})
+}
+
+// distinct returns the non-empty values of list, in order, once each.
+func distinct(list []string) []string {
+	out := []string{}
+	for _, s := range list {
+		if s != "" && !contains(out, s) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
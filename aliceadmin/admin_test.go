@@ -0,0 +1,162 @@
+package aliceadmin
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/brunvieira/fastalice"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func newTestCtx(method, uri, ip string) *fasthttp.RequestCtx {
+	var req fasthttp.Request
+	req.Header.SetMethod(method)
+	req.SetRequestURI(uri)
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Init(&req, &net.TCPAddr{IP: net.ParseIP(ip)}, nil)
+	return ctx
+}
+
+func app(ctx *fasthttp.RequestCtx) {
+	ctx.WriteString("app")
+}
+
+func tag(name string) fastalice.Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			ctx.WriteString(name)
+			next(ctx)
+		}
+	}
+}
+
+// newTestAdmin returns an admin API and the handler of the chain it manages.
+func newTestAdmin(t *testing.T, opts Options) (fasthttp.RequestHandler, fasthttp.RequestHandler) {
+	chain := fastalice.NewNamed(fastalice.NamedConstructor{Name: "a", Constructor: tag("a")}).
+		Group("observability", tag("o"))
+	sc := fastalice.NewSwappable(chain, app)
+
+	admin, err := NewAdmin("/admin", opts)
+	if !assert.NoError(t, err, "NewAdmin should accept the options") {
+		t.FailNow()
+	}
+	admin.Manage("api", sc)
+	return fastalice.New(admin.Middleware()).Then(app), sc.Handler()
+}
+
+func serveAdmin(h fasthttp.RequestHandler, method, uri, body string) *fasthttp.RequestCtx {
+	ctx := newTestCtx(method, uri, "127.0.0.1")
+	ctx.Request.SetBodyString(body)
+	h(ctx)
+	return ctx
+}
+
+func TestAdminChains(t *testing.T) {
+	admin, api := newTestAdmin(t, Options{})
+
+	ctx := serveAdmin(admin, "GET", "http://localhost/admin/chains", "")
+	assert.JSONEq(t, `[{"name":"api","middleware":["a",""],"groups":["observability"],"disabled":[]}]`,
+		string(ctx.Response.Body()), "Managed chains should be listed")
+
+	ctx = serveAdmin(admin, "GET", "http://localhost/admin/chains/api", "")
+	var detail chainDetail
+	assert.NoError(t, json.Unmarshal(ctx.Response.Body(), &detail), "The chain should be explained in JSON")
+	assert.Len(t, detail.Constructors, 2, "Every constructor should be explained")
+
+	ctx = serveAdmin(admin, "DELETE", "http://localhost/admin/chains/api/groups/observability", "")
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Groups should be disabled")
+	ctx = newTestCtx("GET", "http://localhost/", "127.0.0.1")
+	api(ctx)
+	assert.Equal(t, "aapp", string(ctx.Response.Body()), "Disabled groups should be left out")
+
+	serveAdmin(admin, "POST", "http://localhost/admin/chains/api/groups/observability", "")
+	ctx = newTestCtx("GET", "http://localhost/", "127.0.0.1")
+	api(ctx)
+	assert.Equal(t, "aoapp", string(ctx.Response.Body()), "Enabled groups should be back")
+
+	ctx = serveAdmin(admin, "DELETE", "http://localhost/admin/chains/api/groups/unknown", "")
+	assert.Equal(t, fasthttp.StatusNotFound, ctx.Response.StatusCode(), "Unknown groups should not be found")
+	ctx = serveAdmin(admin, "GET", "http://localhost/admin/chains/web", "")
+	assert.Equal(t, fasthttp.StatusNotFound, ctx.Response.StatusCode(), "Unknown chains should not be found")
+
+	ctx = serveAdmin(admin, "GET", "http://localhost/users", "")
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Other paths should reach the app")
+}
+
+func TestAdminRebuild(t *testing.T) {
+	registry := fastalice.NewRegistry()
+	registry.RegisterConstructor("b", tag("b"))
+	admin, api := newTestAdmin(t, Options{Registry: registry})
+
+	ctx := newTestCtx("PUT", "http://localhost/admin/chains/api", "127.0.0.1")
+	ctx.Request.Header.SetContentType("application/yaml")
+	ctx.Request.SetBodyString("middleware:\n  - name: b\n")
+	admin(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Chains should be rebuilt from config")
+
+	ctx = newTestCtx("GET", "http://localhost/", "127.0.0.1")
+	api(ctx)
+	assert.Equal(t, "bapp", string(ctx.Response.Body()), "The rebuilt chain should serve requests")
+
+	ctx = serveAdmin(admin, "PUT", "http://localhost/admin/chains/api", `{"middleware": [{"name": "missing"}]}`)
+	assert.Equal(t, fasthttp.StatusBadRequest, ctx.Response.StatusCode(), "Invalid configs should be rejected")
+}
+
+func TestAdminMaintenanceAndSettings(t *testing.T) {
+	state, _ := fastalice.NewMaintenanceState(fastalice.MaintenanceOptions{RetryAfter: time.Minute})
+	limits := fastalice.NewConfig(fastalice.Setting(fastalice.SettingLimit, 10))
+	admin, _ := newTestAdmin(t, Options{Maintenance: state, Configs: map[string]*fastalice.Config{"ratelimit": limits}})
+
+	serveAdmin(admin, "POST", "http://localhost/admin/maintenance", "")
+	assert.True(t, state.Enabled(), "Maintenance should be flipped on")
+
+	ctx := serveAdmin(admin, "PATCH", "http://localhost/admin/settings/ratelimit", `{"limit": 20}`)
+	assert.JSONEq(t, `{"limit": 20}`, string(ctx.Response.Body()), "Settings should be changed")
+	v, _ := limits.Get(fastalice.SettingLimit)
+	assert.Equal(t, float64(20), v, "The config should see the change")
+
+	ctx = serveAdmin(admin, "PATCH", "http://localhost/admin/settings/ratelimit", `[1]`)
+	assert.Equal(t, fasthttp.StatusBadRequest, ctx.Response.StatusCode(), "Invalid settings should be rejected")
+	ctx = serveAdmin(admin, "GET", "http://localhost/admin/settings/other", "")
+	assert.Equal(t, fasthttp.StatusNotFound, ctx.Response.StatusCode(), "Unknown configs should not be found")
+}
+
+func TestAdminValidatesSettings(t *testing.T) {
+	limits := fastalice.NewConfig(fastalice.Setting(fastalice.SettingLimit, 10), fastalice.Setting("mode", "strict"))
+	admin, _ := newTestAdmin(t, Options{Configs: map[string]*fastalice.Config{"ratelimit": limits}})
+
+	for body, invalid := range map[string]string{
+		`{"limit": 0}`:             "limit",
+		`{"limit": -3}`:            "limit",
+		`{"limit": 2.5}`:           "limit",
+		`{"limit": "20"}`:          "limit",
+		`{"probability": 1.5}`:     "probability",
+		`{"mode": 1}`:              "mode",
+		`{"limit": 5, "burst": 1}`: "burst",
+	} {
+		ctx := serveAdmin(admin, "PATCH", "http://localhost/admin/settings/ratelimit", body)
+		assert.Equal(t, fasthttp.StatusBadRequest, ctx.Response.StatusCode(), "%s should be rejected", body)
+		assert.Equal(t, "application/problem+json", string(ctx.Response.Header.ContentType()), "%s should be answered with a problem", body)
+		var p struct{ Invalid map[string]string }
+		assert.NoError(t, json.Unmarshal(ctx.Response.Body(), &p), "The problem should be JSON")
+		assert.Contains(t, p.Invalid, invalid, "%s should list the invalid setting", body)
+	}
+	v, _ := limits.Get(fastalice.SettingLimit)
+	assert.Equal(t, 10, v, "Rejected changes should not be applied")
+
+	ctx := serveAdmin(admin, "PATCH", "http://localhost/admin/settings/ratelimit", `{"mode": "lenient", "probability": 0.5}`)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Valid changes should be applied")
+}
+
+func TestAdminGuards(t *testing.T) {
+	admin, _ := newTestAdmin(t, Options{})
+	ctx := newTestCtx("GET", "http://localhost/admin/chains", "203.0.113.7")
+	admin(ctx)
+	assert.Equal(t, fasthttp.StatusForbidden, ctx.Response.StatusCode(), "Remote clients should be rejected by default")
+
+	_, err := NewAdmin("/admin", Options{AllowedIPs: []string{"nope"}})
+	assert.Error(t, err, "Malformed IPs should be rejected")
+}
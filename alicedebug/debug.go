@@ -0,0 +1,127 @@
+// Package alicedebug exposes the net/http/pprof profiles
+// and expvar variables of fastalice applications.
+//
+// It lives apart from fastalice since importing net/http/pprof
+// registers its handlers on http.DefaultServeMux.
+package alicedebug
+
+import (
+	"expvar"
+	"net/http/pprof"
+	"strings"
+
+	"github.com/brunvieira/fastalice"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+// DefaultAllowedIPs are the clients allowed by Debug
+// when DebugOptions sets neither AllowedIPs nor Auth.
+var DefaultAllowedIPs = []string{"127.0.0.0/8", "::1"}
+
+// DebugOptions configures Debug.
+type DebugOptions struct {
+	// AllowedIPs lists the IPs and CIDR ranges
+	// allowed to reach the endpoints.
+	AllowedIPs []string
+	// Auth gates the endpoints, such as fastalice.BasicAuth.
+	// It runs after the IP check.
+	Auth fastalice.Constructor
+	// DisablePprof and DisableExpvar turn off
+	// the profiles and the variables.
+	DisablePprof  bool
+	DisableExpvar bool
+}
+
+var (
+	pprofIndex   = fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Index)
+	pprofCmdline = fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Cmdline)
+	pprofProfile = fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Profile)
+	pprofSymbol  = fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Symbol)
+	pprofTrace   = fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Trace)
+	expvarVars   = fasthttpadaptor.NewFastHTTPHandler(expvar.Handler())
+)
+
+// NewDebug returns a constructor serving debug endpoints
+// under prefix, such as "/debug",
+// without calling the following handlers:
+//
+//	prefix/pprof/    the net/http/pprof index and profiles
+//	prefix/vars      the expvar variables, in JSON
+//
+// Requests from clients outside of opts.AllowedIPs are answered
+// with 403 Forbidden; without AllowedIPs nor Auth,
+// only DefaultAllowedIPs are allowed.
+// Requests to any other path pass through.
+//
+// An error is returned if any of the allowed IPs is malformed.
+func NewDebug(prefix string, opts DebugOptions) (fastalice.Constructor, error) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	allowed := opts.AllowedIPs
+	if len(allowed) == 0 && opts.Auth == nil {
+		allowed = DefaultAllowedIPs
+	}
+
+	guards := fastalice.New()
+	if len(allowed) > 0 {
+		filter, err := fastalice.NewIPFilter(allowed, nil)
+		if err != nil {
+			return nil, err
+		}
+		guards = guards.Append(filter)
+	}
+	handler := guards.Append(opts.Auth).Then(func(ctx *fasthttp.RequestCtx) {
+		serve(ctx, prefix, opts)
+	})
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			path := string(ctx.Path())
+			if path != prefix && !strings.HasPrefix(path, prefix+"/") {
+				next(ctx)
+				return
+			}
+			handler(ctx)
+		}
+	}, nil
+}
+
+// Debug is like NewDebug but panics
+// if any of the allowed IPs is malformed.
+func Debug(prefix string, opts DebugOptions) fastalice.Constructor {
+	c, err := NewDebug(prefix, opts)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// serve dispatches a request under prefix to its endpoint.
+func serve(ctx *fasthttp.RequestCtx, prefix string, opts DebugOptions) {
+	rest := strings.TrimPrefix(string(ctx.Path()), prefix)
+
+	switch {
+	case rest == "/vars" && !opts.DisableExpvar:
+		expvarVars(ctx)
+	case (rest == "/pprof" || strings.HasPrefix(rest, "/pprof/")) && !opts.DisablePprof:
+		// pprof.Index finds the profile name
+		// after its hard-coded /debug/pprof/ prefix.
+		name := strings.TrimPrefix(strings.TrimPrefix(rest, "/pprof"), "/")
+		ctx.URI().SetPath("/debug/pprof/" + name)
+
+		switch name {
+		case "cmdline":
+			pprofCmdline(ctx)
+		case "profile":
+			pprofProfile(ctx)
+		case "symbol":
+			pprofSymbol(ctx)
+		case "trace":
+			pprofTrace(ctx)
+		default:
+			pprofIndex(ctx)
+		}
+	default:
+		ctx.Error(fasthttp.StatusMessage(fasthttp.StatusNotFound), fasthttp.StatusNotFound)
+	}
+}
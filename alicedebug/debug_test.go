@@ -0,0 +1,76 @@
+package alicedebug
+
+import (
+	"net"
+	"testing"
+
+	"github.com/brunvieira/fastalice"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func newTestCtx(uri, ip string) *fasthttp.RequestCtx {
+	var req fasthttp.Request
+	req.SetRequestURI(uri)
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Init(&req, &net.TCPAddr{IP: net.ParseIP(ip)}, nil)
+	return ctx
+}
+
+func app(ctx *fasthttp.RequestCtx) {
+	ctx.WriteString("app")
+}
+
+func TestDebugEndpoints(t *testing.T) {
+	h := fastalice.New(Debug("/debug", DebugOptions{})).Then(app)
+
+	ctx := newTestCtx("http://localhost/debug/vars", "127.0.0.1")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Variables should be served")
+	assert.Contains(t, string(ctx.Response.Body()), `"memstats"`, "Variables should be listed")
+
+	ctx = newTestCtx("http://localhost/debug/pprof/", "127.0.0.1")
+	h(ctx)
+	assert.Contains(t, string(ctx.Response.Body()), "goroutine", "The profile index should be served")
+
+	ctx = newTestCtx("http://localhost/debug/pprof/goroutine?debug=1", "127.0.0.1")
+	h(ctx)
+	assert.Contains(t, string(ctx.Response.Body()), "goroutine profile", "Named profiles should be served")
+
+	ctx = newTestCtx("http://localhost/debug/unknown", "127.0.0.1")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusNotFound, ctx.Response.StatusCode(), "Unknown endpoints should not be found")
+
+	ctx = newTestCtx("http://localhost/users", "127.0.0.1")
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Other paths should reach the app")
+}
+
+func TestDebugGuards(t *testing.T) {
+	h := fastalice.New(Debug("/debug", DebugOptions{})).Then(app)
+	ctx := newTestCtx("http://localhost/debug/vars", "203.0.113.7")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusForbidden, ctx.Response.StatusCode(), "Remote clients should be rejected by default")
+
+	auth := fastalice.BasicAuth(func(user, pass string) bool { return user == "ops" && pass == "secret" }, fastalice.BasicAuthRealm("debug"))
+	h = fastalice.New(Debug("/debug", DebugOptions{Auth: auth, DisablePprof: true})).Then(app)
+
+	ctx = newTestCtx("http://localhost/debug/vars", "203.0.113.7")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "Unauthenticated requests should be rejected")
+
+	ctx = newTestCtx("http://localhost/debug/vars", "203.0.113.7")
+	ctx.Request.Header.Set(fasthttp.HeaderAuthorization, "Basic b3BzOnNlY3JldA==")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Authenticated requests should be served")
+
+	ctx = newTestCtx("http://localhost/debug/pprof/", "203.0.113.7")
+	ctx.Request.Header.Set(fasthttp.HeaderAuthorization, "Basic b3BzOnNlY3JldA==")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusNotFound, ctx.Response.StatusCode(), "Disabled endpoints should not be found")
+}
+
+func TestNewDebugInvalidIP(t *testing.T) {
+	_, err := NewDebug("/debug", DebugOptions{AllowedIPs: []string{"nope"}})
+	assert.Error(t, err, "Malformed IPs should be rejected")
+}
@@ -0,0 +1,54 @@
+// Package alicefiber adapts Fiber middleware to fastalice chains.
+//
+// The package is a module of its own,
+// so fastalice does not depend on Fiber.
+//
+// Atreugo and Gearbox have no adapter:
+// neither can run a single middleware as a standalone fasthttp handler,
+// so their middleware are better rewritten as constructors.
+package alicefiber
+
+import (
+	"github.com/brunvieira/fastalice"
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// FromFiber returns a constructor running the Fiber middleware h
+// in front of the following handlers, which it reaches
+// when it calls c.Next().
+//
+// Errors returned by h are answered by the Fiber error handler,
+// 500 Internal Server Error by default.
+//
+//	chain := fastalice.New(alicefiber.FromFiber(helmet.New()), logger)
+func FromFiber(h fiber.Handler) fastalice.Constructor {
+	return FromFiberConfig(fiber.Config{}, h)
+}
+
+// FromFiberConfig is like FromFiber,
+// building the Fiber app with cfg, such as for a custom error handler.
+//
+// The Fiber app holding h is built once and shared
+// by every handler the constructor wraps.
+func FromFiberConfig(cfg fiber.Config, h fiber.Handler) fastalice.Constructor {
+	cfg.DisableStartupMessage = true
+	nextKey := fastalice.NewKey[fasthttp.RequestHandler]("alicefiber.next")
+
+	app := fiber.New(cfg)
+	app.Use(h)
+	app.Use(func(c *fiber.Ctx) error {
+		if next, ok := fastalice.Get(c.Context(), nextKey); ok {
+			next(c.Context())
+		}
+		return nil
+	})
+	handler := app.Handler()
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			fastalice.Set(ctx, nextKey, next)
+			handler(ctx)
+		}
+	}
+}
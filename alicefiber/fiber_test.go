@@ -0,0 +1,68 @@
+package alicefiber
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/brunvieira/fastalice"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func newTestCtx(method, uri string) *fasthttp.RequestCtx {
+	var req fasthttp.Request
+	req.Header.SetMethod(method)
+	req.SetRequestURI(uri)
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Init(&req, nil, nil)
+	return ctx
+}
+
+func TestFromFiber(t *testing.T) {
+	mw := FromFiber(func(c *fiber.Ctx) error {
+		c.Set("X-Fiber", "yes")
+		return c.Next()
+	})
+	h := fastalice.New(mw).Then(func(ctx *fasthttp.RequestCtx) {
+		ctx.WriteString("app")
+	})
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+
+	assert.Equal(t, "yes", string(ctx.Response.Header.Peek("X-Fiber")), "Fiber middleware runs")
+	assert.Equal(t, "app", string(ctx.Response.Body()), "c.Next reaches the chain")
+}
+
+func TestFromFiberStopsChain(t *testing.T) {
+	called := false
+	mw := FromFiber(func(c *fiber.Ctx) error {
+		return errors.New("denied")
+	})
+	h := fastalice.New(mw).Then(func(ctx *fasthttp.RequestCtx) {
+		called = true
+	})
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+
+	assert.False(t, called, "the chain is not reached without c.Next")
+	assert.Equal(t, fasthttp.StatusInternalServerError, ctx.Response.StatusCode(), "errors go to the Fiber error handler")
+}
+
+func TestFromFiberSharesApp(t *testing.T) {
+	mw := FromFiber(func(c *fiber.Ctx) error {
+		return c.Next()
+	})
+	first := mw(func(ctx *fasthttp.RequestCtx) { ctx.WriteString("first") })
+	second := mw(func(ctx *fasthttp.RequestCtx) { ctx.WriteString("second") })
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	second(ctx)
+	assert.Equal(t, "second", string(ctx.Response.Body()), "each wrapped handler keeps its next")
+
+	ctx = newTestCtx("GET", "http://localhost/")
+	first(ctx)
+	assert.Equal(t, "first", string(ctx.Response.Body()), "each wrapped handler keeps its next")
+}
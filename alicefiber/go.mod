@@ -0,0 +1,23 @@
+module github.com/brunvieira/fastalice/alicefiber
+
+go 1.18
+
+require (
+	github.com/brunvieira/fastalice v0.0.0
+	github.com/gofiber/fiber/v2 v2.0.0
+	github.com/stretchr/testify v1.7.0
+	github.com/valyala/fasthttp v1.16.0
+)
+
+require (
+	github.com/andybalholm/brotli v1.0.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/klauspost/compress v1.11.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	github.com/valyala/tcplisten v0.0.0-20161114210144-ceec8f93295a // indirect
+	golang.org/x/sys v0.0.0-20210423185535-09eb48e85fd7 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/brunvieira/fastalice => ../
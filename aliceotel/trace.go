@@ -0,0 +1,127 @@
+// Package aliceotel traces fastalice chains with OpenTelemetry.
+//
+// The package is a module of its own,
+// so fastalice does not depend on OpenTelemetry.
+package aliceotel
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/brunvieira/fastalice"
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// contextKey is the key holding
+// the context of the current span.
+var contextKey = fastalice.NewKey[context.Context]("aliceotel.context")
+
+// propagator reads W3C traceparent and tracestate headers.
+var propagator = propagation.TraceContext{}
+
+// Trace returns a constructor that starts a server span
+// for each request, continuing the trace described by the
+// W3C traceparent header when there is one.
+// The span ends once the following handlers have run,
+// recording the status code; 5xx responses mark it as failed.
+//
+// The span can be read with SpanFromRequest,
+// and its context with ContextFromRequest.
+func Trace(tracer trace.Tracer) fastalice.Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			parent := propagator.Extract(context.Background(), requestCarrier{&ctx.Request.Header})
+			method := string(ctx.Method())
+			spanCtx, span := tracer.Start(parent, "HTTP "+method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPMethodKey.String(method),
+					semconv.HTTPTargetKey.String(string(ctx.URI().RequestURI())),
+				),
+			)
+			defer span.End()
+
+			fastalice.Set(ctx, contextKey, spanCtx)
+			next(ctx)
+
+			status := ctx.Response.StatusCode()
+			span.SetAttributes(semconv.HTTPStatusCodeKey.Int(status))
+			if status >= fasthttp.StatusInternalServerError {
+				span.SetStatus(codes.Error, fasthttp.StatusMessage(status))
+			}
+		}
+	}
+}
+
+// TraceChain returns a copy of chain where every middleware
+// runs in a child span of the request span, for latency attribution.
+// Named constructors give their name to their span,
+// others are named after their index.
+// It is meant to be extended after Trace:
+//
+//	fastalice.New(aliceotel.Trace(tracer)).Extend(aliceotel.TraceChain(tracer, chain))
+func TraceChain(tracer trace.Tracer, chain fastalice.Chain) fastalice.Chain {
+	return chain.Map(func(i int, name string, c fastalice.Constructor) fastalice.Constructor {
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+		spanName := "middleware " + name
+
+		return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+			inner := c(next)
+
+			return func(ctx *fasthttp.RequestCtx) {
+				parent := ContextFromRequest(ctx)
+				spanCtx, span := tracer.Start(parent, spanName)
+				fastalice.Set(ctx, contextKey, spanCtx)
+				inner(ctx)
+				fastalice.Set(ctx, contextKey, parent)
+				span.End()
+			}
+		}
+	})
+}
+
+// ContextFromRequest returns the context of the current span
+// started by Trace or TraceChain,
+// or context.Background() if there is none.
+// Handlers should pass it on to outgoing calls.
+func ContextFromRequest(ctx *fasthttp.RequestCtx) context.Context {
+	if spanCtx, ok := fastalice.Get(ctx, contextKey); ok {
+		return spanCtx
+	}
+	return context.Background()
+}
+
+// SpanFromRequest returns the current span
+// started by Trace or TraceChain,
+// or a no-op span if there is none.
+func SpanFromRequest(ctx *fasthttp.RequestCtx) trace.Span {
+	return trace.SpanFromContext(ContextFromRequest(ctx))
+}
+
+// requestCarrier adapts fasthttp request headers
+// to propagation.TextMapCarrier.
+type requestCarrier struct {
+	header *fasthttp.RequestHeader
+}
+
+func (c requestCarrier) Get(key string) string {
+	return string(c.header.Peek(key))
+}
+
+func (c requestCarrier) Set(key, value string) {
+	c.header.Set(key, value)
+}
+
+func (c requestCarrier) Keys() []string {
+	var keys []string
+	c.header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
@@ -0,0 +1,79 @@
+package aliceotel
+
+import (
+	"testing"
+
+	"github.com/brunvieira/fastalice"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestCtx(method, uri string) *fasthttp.RequestCtx {
+	var req fasthttp.Request
+	req.Header.SetMethod(method)
+	req.SetRequestURI(uri)
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Init(&req, nil, nil)
+	return ctx
+}
+
+func newTestTracer() (trace.Tracer, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return provider.Tracer("aliceotel_test"), recorder
+}
+
+func TestTraceContinuesTraceparent(t *testing.T) {
+	tracer, recorder := newTestTracer()
+	var seen trace.SpanContext
+	h := fastalice.New(Trace(tracer)).Then(func(ctx *fasthttp.RequestCtx) {
+		seen = SpanFromRequest(ctx).SpanContext()
+	})
+
+	ctx := newTestCtx("GET", "http://localhost/users?page=2")
+	ctx.Request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h(ctx)
+
+	spans := recorder.Ended()
+	assert.Equal(t, 1, len(spans), "A span should be recorded")
+	assert.Equal(t, "HTTP GET", spans[0].Name(), "The span should be named after the method")
+	assert.Equal(t, trace.SpanKindServer, spans[0].SpanKind(), "The span should be a server span")
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String(), "The trace should be continued")
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String(), "The remote span should be the parent")
+	assert.Equal(t, spans[0].SpanContext(), seen, "The span should be exposed to handlers")
+}
+
+func TestTraceRecordsFailures(t *testing.T) {
+	tracer, recorder := newTestTracer()
+	h := fastalice.New(Trace(tracer)).Then(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusBadGateway)
+	})
+
+	h(newTestCtx("GET", "http://localhost/"))
+	assert.Equal(t, codes.Error, recorder.Ended()[0].Status().Code, "5xx responses should fail the span")
+}
+
+func TestTraceChainCreatesChildSpans(t *testing.T) {
+	tracer, recorder := newTestTracer()
+	pass := func(next fasthttp.RequestHandler) fasthttp.RequestHandler { return next }
+	chain := fastalice.NewNamed(fastalice.NamedConstructor{Name: "auth", Constructor: pass}).Append(pass)
+
+	h := fastalice.New(Trace(tracer)).Extend(TraceChain(tracer, chain)).Then(func(ctx *fasthttp.RequestCtx) {})
+	h(newTestCtx("GET", "http://localhost/"))
+
+	spans := recorder.Ended()
+	assert.Equal(t, 3, len(spans), "Every middleware should get a span")
+	assert.Equal(t, "middleware 1", spans[0].Name(), "Unnamed middleware should be named after their index")
+	assert.Equal(t, "middleware auth", spans[1].Name(), "Named middleware should be named after their name")
+	assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID(), "Middleware spans should nest")
+	assert.Equal(t, spans[2].SpanContext().SpanID(), spans[1].Parent().SpanID(), "Middleware spans should be children of the request span")
+}
+
+func TestSpanFromRequestWithoutTrace(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	assert.False(t, SpanFromRequest(ctx).SpanContext().IsValid(), "Untraced requests should get a no-op span")
+}
@@ -0,0 +1,177 @@
+// Package aliceprom records Prometheus metrics
+// for fastalice chains.
+//
+// The package is a module of its own,
+// so fastalice does not depend on the Prometheus client.
+package aliceprom
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/brunvieira/fastalice"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/valyala/fasthttp"
+)
+
+// MetricsOptions configures the metrics recorded by aliceprom.
+type MetricsOptions struct {
+	// Namespace and Subsystem prefix the metric names.
+	Namespace string
+	Subsystem string
+	// Buckets are the histogram buckets, in seconds.
+	// They default to prometheus.DefBuckets.
+	Buckets []float64
+	// Route returns the route label of a request.
+	// It defaults to the request path, which should be replaced
+	// by a route pattern when paths hold identifiers,
+	// to keep the number of series bounded.
+	Route func(ctx *fasthttp.RequestCtx) string
+}
+
+// NewMetrics returns a constructor that records,
+// for each route, the number of requests by method and status code,
+// their duration and the number of requests in flight,
+// registering the collectors with reg.
+// A nil reg defaults to prometheus.DefaultRegisterer.
+//
+// Collectors already registered with reg are reused,
+// so several chains may share the same metrics.
+// An error is returned if they cannot be registered.
+func NewMetrics(reg prometheus.Registerer, opts MetricsOptions) (fastalice.Constructor, error) {
+	requests, err := register(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: opts.Namespace,
+		Subsystem: opts.Subsystem,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests served.",
+	}, []string{"route", "method", "code"}))
+	if err != nil {
+		return nil, err
+	}
+	duration, err := register(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: opts.Namespace,
+		Subsystem: opts.Subsystem,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time spent serving HTTP requests.",
+		Buckets:   opts.Buckets,
+	}, []string{"route", "method"}))
+	if err != nil {
+		return nil, err
+	}
+	inFlight, err := register(reg, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: opts.Namespace,
+		Subsystem: opts.Subsystem,
+		Name:      "http_requests_in_flight",
+		Help:      "Number of HTTP requests being served.",
+	}, []string{"route"}))
+	if err != nil {
+		return nil, err
+	}
+
+	route := opts.Route
+	if route == nil {
+		route = func(ctx *fasthttp.RequestCtx) string { return string(ctx.Path()) }
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			r := route(ctx)
+			gauge := inFlight.(*prometheus.GaugeVec).WithLabelValues(r)
+			gauge.Inc()
+			start := time.Now()
+
+			next(ctx)
+
+			d := time.Since(start)
+			gauge.Dec()
+			method := string(ctx.Method())
+			requests.(*prometheus.CounterVec).WithLabelValues(r, method, strconv.Itoa(ctx.Response.StatusCode())).Inc()
+			duration.(*prometheus.HistogramVec).WithLabelValues(r, method).Observe(d.Seconds())
+		}
+	}, nil
+}
+
+// Metrics is like NewMetrics,
+// but it panics if the collectors cannot be registered.
+// It is meant for chains built at startup.
+func Metrics(reg prometheus.Registerer, opts MetricsOptions) fastalice.Constructor {
+	c, err := NewMetrics(reg, opts)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// NewInstrumentedChain returns a copy of chain where every middleware
+// is timed individually, excluding the time spent in the following ones,
+// in a histogram labeled by middleware.
+// Named constructors are labeled by name, others by their index.
+//
+// Options other than Route apply; the collector is registered
+// with reg as in NewMetrics.
+func NewInstrumentedChain(reg prometheus.Registerer, chain fastalice.Chain, opts MetricsOptions) (fastalice.Chain, error) {
+	collector, err := register(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: opts.Namespace,
+		Subsystem: opts.Subsystem,
+		Name:      "http_middleware_duration_seconds",
+		Help:      "Time spent in each middleware, excluding the following ones.",
+		Buckets:   opts.Buckets,
+	}, []string{"middleware"}))
+	if err != nil {
+		return fastalice.Chain{}, err
+	}
+	latency := collector.(*prometheus.HistogramVec)
+
+	return chain.Map(func(i int, name string, c fastalice.Constructor) fastalice.Constructor {
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+		observer := latency.WithLabelValues(name)
+		key := fastalice.NewKey[*time.Duration]("aliceprom.downstream")
+
+		return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+			inner := c(func(ctx *fasthttp.RequestCtx) {
+				start := time.Now()
+				next(ctx)
+				if downstream, ok := fastalice.Get(ctx, key); ok {
+					*downstream += time.Since(start)
+				}
+			})
+
+			return func(ctx *fasthttp.RequestCtx) {
+				var downstream time.Duration
+				fastalice.Set(ctx, key, &downstream)
+				start := time.Now()
+				inner(ctx)
+				observer.Observe((time.Since(start) - downstream).Seconds())
+				fastalice.Delete(ctx, key)
+			}
+		}
+	}), nil
+}
+
+// InstrumentedChain is like NewInstrumentedChain,
+// but it panics if the collector cannot be registered.
+func InstrumentedChain(reg prometheus.Registerer, chain fastalice.Chain, opts MetricsOptions) fastalice.Chain {
+	instrumented, err := NewInstrumentedChain(reg, chain, opts)
+	if err != nil {
+		panic(err)
+	}
+	return instrumented
+}
+
+// register registers c with reg,
+// returning the collector already registered in its place if any.
+func register(reg prometheus.Registerer, c prometheus.Collector) (prometheus.Collector, error) {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	if err := reg.Register(c); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return are.ExistingCollector, nil
+		}
+		return nil, fmt.Errorf("aliceprom: %v", err)
+	}
+	return c, nil
+}
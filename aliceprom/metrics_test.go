@@ -0,0 +1,105 @@
+package aliceprom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/brunvieira/fastalice"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func newTestCtx(method, uri string) *fasthttp.RequestCtx {
+	var req fasthttp.Request
+	req.Header.SetMethod(method)
+	req.SetRequestURI(uri)
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Init(&req, nil, nil)
+	return ctx
+}
+
+func testApp(ctx *fasthttp.RequestCtx) {
+	ctx.WriteString("app")
+}
+
+func TestMetricsRecordsRequests(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	route := func(ctx *fasthttp.RequestCtx) string { return "/users/:id" }
+	h := fastalice.New(Metrics(reg, MetricsOptions{Route: route})).Then(testApp)
+
+	h(newTestCtx("GET", "http://localhost/users/1"))
+	h(newTestCtx("GET", "http://localhost/users/2"))
+
+	expected := `
+# HELP http_requests_total Number of HTTP requests served.
+# TYPE http_requests_total counter
+http_requests_total{code="200",method="GET",route="/users/:id"} 2
+# HELP http_requests_in_flight Number of HTTP requests being served.
+# TYPE http_requests_in_flight gauge
+http_requests_in_flight{route="/users/:id"} 0
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "http_requests_total", "http_requests_in_flight"), "Requests should be counted by route")
+	count, err := testutil.GatherAndCount(reg, "http_request_duration_seconds")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count, "Durations should be observed by route")
+}
+
+func TestMetricsInFlight(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	var inFlight float64
+	h := fastalice.New(Metrics(reg, MetricsOptions{})).Then(func(ctx *fasthttp.RequestCtx) {
+		families, _ := reg.Gather()
+		for _, f := range families {
+			if f.GetName() == "http_requests_in_flight" {
+				inFlight = f.GetMetric()[0].GetGauge().GetValue()
+			}
+		}
+	})
+
+	h(newTestCtx("GET", "http://localhost/"))
+	assert.Equal(t, float64(1), inFlight, "The request should be in flight while served")
+}
+
+func TestMetricsSharesCollectors(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	first := fastalice.New(Metrics(reg, MetricsOptions{})).Then(testApp)
+	second := fastalice.New(Metrics(reg, MetricsOptions{})).Then(testApp)
+
+	first(newTestCtx("GET", "http://localhost/"))
+	second(newTestCtx("GET", "http://localhost/"))
+
+	count, err := testutil.GatherAndCount(reg, "http_requests_total")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count, "Chains should share the same series")
+}
+
+func TestNewMetricsConflict(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{Name: "http_requests_total", Help: "conflict"}))
+
+	_, err := NewMetrics(reg, MetricsOptions{})
+	assert.Error(t, err, "Conflicting collectors should be reported")
+	assert.Panics(t, func() { Metrics(reg, MetricsOptions{}) }, "Metrics should panic on conflicts")
+}
+
+func TestInstrumentedChain(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	pass := func(next fasthttp.RequestHandler) fasthttp.RequestHandler { return next }
+	chain := fastalice.NewNamed(fastalice.NamedConstructor{Name: "auth", Constructor: pass}).Append(pass)
+	instrumented := InstrumentedChain(reg, chain, MetricsOptions{})
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	instrumented.Then(testApp)(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "The instrumented chain should serve the request")
+
+	families, err := reg.Gather()
+	assert.NoError(t, err)
+	var labels []string
+	for _, m := range families[0].GetMetric() {
+		assert.Equal(t, uint64(1), m.GetHistogram().GetSampleCount(), "Every middleware should be timed once")
+		labels = append(labels, m.GetLabel()[0].GetValue())
+	}
+	assert.Equal(t, []string{"1", "auth"}, labels, "Middleware should be labeled by name or index")
+}
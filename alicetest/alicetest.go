@@ -0,0 +1,173 @@
+// Package alicetest provides utilities for testing
+// fastalice chains and middleware without binding network ports.
+//
+// It does not import fastalice, so that fastalice's own tests can use it;
+// any fastalice.Chain satisfies its Chain interface.
+package alicetest
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// recordTimeout bounds how long Record waits for a response.
+const recordTimeout = 10 * time.Second
+
+// Chain is a chain of middleware, such as a fastalice.Chain.
+type Chain interface {
+	Then(h fasthttp.RequestHandler) fasthttp.RequestHandler
+}
+
+// Response is a response recorded by Record.
+type Response struct {
+	StatusCode int
+	// Header holds the response headers under their canonical names.
+	Header http.Header
+	Body   []byte
+	// Err is set when the request could not be served,
+	// such as when the server closed the connection
+	// without answering.
+	Err error
+}
+
+// NewRequest returns a request with the given method and URI,
+// ready to be passed to Record.
+func NewRequest(method, uri string) *fasthttp.Request {
+	req := &fasthttp.Request{}
+	req.Header.SetMethod(method)
+	req.SetRequestURI(uri)
+	return req
+}
+
+// Record serves req through chain and handler
+// and returns the response.
+//
+// The request goes through a real fasthttp.Server
+// over an in-memory connection made with net.Pipe,
+// so it is parsed and the response serialized
+// exactly as over the network, without listening on a port.
+// A nil chain serves req with handler alone.
+func Record(chain Chain, handler fasthttp.RequestHandler, req *fasthttp.Request) *Response {
+	var raw bytes.Buffer
+	w := bufio.NewWriter(&raw)
+	if err := req.Write(w); err != nil {
+		return &Response{Err: fmt.Errorf("alicetest: %v", err)}
+	}
+	w.Flush()
+	return serveRaw(then(chain, handler), raw.Bytes(), req.Header.IsHead(), recordTimeout)
+}
+
+// then chains handler with chain, when it is not nil.
+func then(chain Chain, handler fasthttp.RequestHandler) fasthttp.RequestHandler {
+	if chain == nil {
+		return handler
+	}
+	return chain.Then(handler)
+}
+
+// serveRaw writes the raw request to a fasthttp.Server serving h
+// over net.Pipe and reads back the response,
+// giving up after timeout.
+func serveRaw(h fasthttp.RequestHandler, raw []byte, head bool, timeout time.Duration) *Response {
+	server := &fasthttp.Server{Handler: h}
+	client, conn := net.Pipe()
+
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		server.ServeConn(conn)
+	}()
+	defer func() {
+		client.Close()
+		<-served
+	}()
+
+	client.SetDeadline(time.Now().Add(timeout))
+	go client.Write(raw)
+
+	var resp fasthttp.Response
+	resp.SkipBody = head
+	if err := resp.Read(bufio.NewReader(client)); err != nil {
+		return &Response{Err: fmt.Errorf("alicetest: %v", err)}
+	}
+
+	r := &Response{
+		StatusCode: resp.StatusCode(),
+		Header:     make(http.Header),
+		Body:       append([]byte(nil), resp.Body()...),
+	}
+	resp.Header.VisitAll(func(k, v []byte) {
+		r.Header.Add(string(k), string(v))
+	})
+	return r
+}
+
+// Order records the order in which middleware and handlers run.
+// It is safe for concurrent use.
+type Order struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (o *Order) record(name string) {
+	o.mu.Lock()
+	o.names = append(o.names, name)
+	o.mu.Unlock()
+}
+
+// Middleware returns a middleware constructor
+// recording name when a request enters it.
+// It can be added to a fastalice chain as is.
+func (o *Order) Middleware(name string) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			o.record(name)
+			next(ctx)
+		}
+	}
+}
+
+// Handler returns a handler recording name
+// and answering with 200 OK.
+func (o *Order) Handler(name string) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		o.record(name)
+	}
+}
+
+// Names returns the names recorded so far, in order.
+func (o *Order) Names() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]string(nil), o.names...)
+}
+
+// Reset clears the recorded names.
+func (o *Order) Reset() {
+	o.mu.Lock()
+	o.names = nil
+	o.mu.Unlock()
+}
+
+// AssertOrder checks that o recorded exactly the names want, in order,
+// reporting a test error otherwise, and returns whether it did.
+func AssertOrder(t testing.TB, o *Order, want ...string) bool {
+	t.Helper()
+	got := o.Names()
+	equal := len(got) == len(want)
+	for i := 0; equal && i < len(got); i++ {
+		equal = got[i] == want[i]
+	}
+	if !equal {
+		t.Errorf("alicetest: middleware ran in order %v, want %v", got, want)
+	}
+	return equal
+}
@@ -0,0 +1,65 @@
+package alicetest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// chain is a minimal Chain, as fastalice cannot be imported here.
+type chain []func(fasthttp.RequestHandler) fasthttp.RequestHandler
+
+func (c chain) Then(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+	for i := len(c) - 1; i >= 0; i-- {
+		h = c[i](h)
+	}
+	return h
+}
+
+func TestRecord(t *testing.T) {
+	header := func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			ctx.Response.Header.Set("X-Middleware", "yes")
+			next(ctx)
+		}
+	}
+	req := NewRequest("POST", "http://example.com/echo?x=1")
+	req.SetBodyString("ping")
+
+	resp := Record(chain{header}, func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusCreated)
+		ctx.Write(ctx.Host())
+		ctx.Write(ctx.RequestURI())
+		ctx.Write(ctx.PostBody())
+	}, req)
+
+	assert.NoError(t, resp.Err, "The request should be served")
+	assert.Equal(t, fasthttp.StatusCreated, resp.StatusCode, "The status should be recorded")
+	assert.Equal(t, "yes", resp.Header.Get("X-Middleware"), "Headers should be recorded")
+	assert.Equal(t, "example.com/echo?x=1ping", string(resp.Body), "The request should reach the handler intact")
+}
+
+func TestRecordHead(t *testing.T) {
+	resp := Record(nil, func(ctx *fasthttp.RequestCtx) {
+		ctx.SetBodyString("body")
+	}, NewRequest("HEAD", "http://example.com/"))
+
+	assert.NoError(t, resp.Err, "HEAD requests should be served")
+	assert.Empty(t, resp.Body, "HEAD responses should have no body")
+	assert.Equal(t, "4", resp.Header.Get("Content-Length"), "HEAD responses should keep their length")
+}
+
+func TestOrder(t *testing.T) {
+	var o Order
+	c := chain{o.Middleware("first"), o.Middleware("second")}
+
+	Record(c, o.Handler("handler"), NewRequest("GET", "http://example.com/"))
+	assert.True(t, AssertOrder(t, &o, "first", "second", "handler"), "The order should be recorded")
+
+	o.Reset()
+	assert.Empty(t, o.Names(), "Reset should clear the order")
+
+	var mock testing.T
+	assert.False(t, AssertOrder(&mock, &o, "first"), "Mismatched orders should fail")
+}
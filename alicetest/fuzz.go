@@ -0,0 +1,181 @@
+package alicetest
+
+import (
+	"bytes"
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// fuzzTimeout bounds how long Fuzz waits for each response.
+var fuzzTimeout = 2 * time.Second
+
+// fuzzBodySize is the size of the huge bodies made by Mutations.
+const fuzzBodySize = 1 << 20
+
+// DefaultCorpus is the corpus used by Fuzz when given none.
+var DefaultCorpus = [][]byte{
+	[]byte("GET /?q=1 HTTP/1.1\r\nHost: example.com\r\nAccept: */*\r\n\r\n"),
+	[]byte("POST /items HTTP/1.1\r\nHost: example.com\r\nContent-Type: application/json\r\nContent-Length: 13\r\n\r\n{\"name\":\"x\"}\n"),
+}
+
+// FuzzFailure is an input that made a chain panic or hang.
+type FuzzFailure struct {
+	// Input is the raw request sent.
+	Input []byte
+	// Panic is the value the chain panicked with, if it did,
+	// and Stack the stack trace of the panic.
+	Panic interface{}
+	Stack []byte
+	// Err is set when no response came back.
+	Err error
+}
+
+func (f FuzzFailure) String() string {
+	if f.Panic != nil {
+		return fmt.Sprintf("panic %v on %q\n%s", f.Panic, f.Input, f.Stack)
+	}
+	return fmt.Sprintf("%v on %q", f.Err, f.Input)
+}
+
+// Fuzz sends every raw request of corpus, and the mutations of each
+// made by Mutations, through chain and handler,
+// and returns the inputs that made them panic or hang.
+// A nil corpus defaults to DefaultCorpus.
+//
+// Malformed requests rejected by fasthttp itself, such as with
+// 400 Bad Request, are not failures: middleware only has to cope
+// with those fasthttp lets through.
+//
+//	for _, f := range alicetest.Fuzz(chain, handler, nil) {
+//		t.Error(f)
+//	}
+func Fuzz(chain Chain, handler fasthttp.RequestHandler, corpus [][]byte) []FuzzFailure {
+	if corpus == nil {
+		corpus = DefaultCorpus
+	}
+
+	var mu sync.Mutex
+	var panicked *FuzzFailure
+	h := then(chain, handler)
+	guarded := func(ctx *fasthttp.RequestCtx) {
+		defer func() {
+			if p := recover(); p != nil {
+				mu.Lock()
+				panicked = &FuzzFailure{Panic: p, Stack: debug.Stack()}
+				mu.Unlock()
+				ctx.Error("panic", fasthttp.StatusInternalServerError)
+			}
+		}()
+		h(ctx)
+	}
+
+	var failures []FuzzFailure
+	for _, raw := range corpus {
+		for _, input := range append([][]byte{raw}, Mutations(raw)...) {
+			resp := serveRaw(guarded, input, bytes.HasPrefix(input, []byte("HEAD ")), fuzzTimeout)
+
+			mu.Lock()
+			p := panicked
+			panicked = nil
+			mu.Unlock()
+			switch {
+			case p != nil:
+				p.Input = input
+				failures = append(failures, *p)
+			case resp.Err != nil:
+				failures = append(failures, FuzzFailure{Input: input, Err: resp.Err})
+			}
+		}
+	}
+	return failures
+}
+
+// Mutations returns variants of the raw request raw
+// meant to stress middleware: malformed and oversized headers,
+// huge bodies and chunked encodings, valid or not.
+func Mutations(raw []byte) [][]byte {
+	head, body := raw, []byte(nil)
+	if i := bytes.Index(raw, []byte("\r\n\r\n")); i >= 0 {
+		head, body = raw[:i], raw[i+4:]
+	}
+	lines := bytes.Split(head, []byte("\r\n"))
+	requestLine, headers := lines[0], withoutHeader(lines[1:], "Content-Length")
+
+	build := func(extra []string, body []byte) []byte {
+		var b bytes.Buffer
+		b.Write(requestLine)
+		b.WriteString("\r\n")
+		for _, h := range headers {
+			b.Write(h)
+			b.WriteString("\r\n")
+		}
+		for _, h := range extra {
+			b.WriteString(h)
+			b.WriteString("\r\n")
+		}
+		b.WriteString("\r\n")
+		b.Write(body)
+		return b.Bytes()
+	}
+	length := func(body []byte) string {
+		return "Content-Length: " + strconv.Itoa(len(body))
+	}
+	huge := bytes.Repeat([]byte("A"), fuzzBodySize)
+
+	return [][]byte{
+		// Malformed headers.
+		build([]string{"X-No-Colon", length(body)}, body),
+		build([]string{"X-Null: a\x00b", length(body)}, body),
+		build([]string{"X-Long: " + string(huge[:64<<10]), length(body)}, body),
+		build([]string{"Bad Name: value", length(body)}, body),
+		build([]string{"X-Empty:", length(body)}, body),
+		build([]string{"Accept: " + string(bytes.Repeat([]byte("*/*;q=0.1,"), 1000)), length(body)}, body),
+		build([]string{"Cookie: " + string(bytes.Repeat([]byte("a=b; "), 1000)), length(body)}, body),
+		build([]string{"Content-Type: ;;;=", length(body)}, body),
+		build([]string{"X-Forwarded-For: ,,, not-an-ip, 999.1.1.1", length(body)}, body),
+		build([]string{length(body), length(body)}, body),
+		bytes.ReplaceAll(build([]string{length(body)}, body), []byte("\r\n"), []byte("\n")),
+		// Huge bodies.
+		build([]string{length(huge)}, huge),
+		build([]string{"Content-Type: application/json", length(huge)}, huge),
+		// Chunked encodings.
+		build([]string{"Transfer-Encoding: chunked"}, chunked(body, 1)),
+		build([]string{"Transfer-Encoding: chunked"}, chunked(huge, 4096)),
+		build([]string{"Transfer-Encoding: chunked"}, []byte("zz\r\nabc\r\n0\r\n\r\n")),
+		build([]string{"Transfer-Encoding: chunked"}, []byte("3;ext=1\r\nabc\r\n0\r\nX-Trailer: 1\r\n\r\n")),
+		build([]string{"Transfer-Encoding: chunked", length(body)}, chunked(body, 2)),
+	}
+}
+
+// withoutHeader returns lines without the header name.
+func withoutHeader(lines [][]byte, name string) [][]byte {
+	prefix := []byte(name + ":")
+	var out [][]byte
+	for _, l := range lines {
+		if len(l) >= len(prefix) && bytes.EqualFold(l[:len(prefix)], prefix) {
+			continue
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+// chunked encodes body in chunks of size bytes.
+func chunked(body []byte, size int) []byte {
+	var b bytes.Buffer
+	for len(body) > 0 {
+		n := size
+		if n > len(body) {
+			n = len(body)
+		}
+		fmt.Fprintf(&b, "%x\r\n%s\r\n", n, body[:n])
+		body = body[n:]
+	}
+	b.WriteString("0\r\n\r\n")
+	return b.Bytes()
+}
@@ -0,0 +1,35 @@
+package alicetest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestFuzz(t *testing.T) {
+	echo := func(ctx *fasthttp.RequestCtx) {
+		ctx.Write(ctx.PostBody())
+	}
+	assert.Empty(t, Fuzz(nil, echo, nil), "A robust handler should survive the corpus")
+
+	fragile := func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if len(ctx.PostBody()) > 1<<16 {
+				panic("body too large")
+			}
+			next(ctx)
+		}
+	}
+	failures := Fuzz(chain{fragile}, echo, nil)
+	if assert.NotEmpty(t, failures, "Panics should be reported") {
+		assert.Equal(t, "body too large", failures[0].Panic, "The panic value should be reported")
+		assert.NotEmpty(t, failures[0].Input, "The failing input should be reported")
+	}
+}
+
+func TestMutations(t *testing.T) {
+	for _, raw := range DefaultCorpus {
+		assert.NotEmpty(t, Mutations(raw), "Mutations should be produced")
+	}
+}
@@ -0,0 +1,247 @@
+package fastalice
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+)
+
+// apiKeyKey holds the API key accepted by APIKey.
+var apiKeyKey = NewKey[APIKeyInfo]("apiKey")
+
+// APIKeyInfo describes an API key.
+type APIKeyInfo struct {
+	// ID identifies the key, or its owner, without revealing it.
+	ID string `json:"id"`
+	// Scopes are the scopes granted to the key.
+	Scopes []string `json:"scopes,omitempty"`
+	// Tier is the rate limit tier of the key,
+	// for downstream middleware to pick a policy from.
+	Tier string `json:"tier,omitempty"`
+}
+
+// HasScope reports whether the key is granted scope.
+func (i APIKeyInfo) HasScope(scope string) bool {
+	return containsString(i.Scopes, scope)
+}
+
+// KeyStore looks API keys up.
+// Implementations should compare keys in constant time,
+// such as by the SHA-256 hash of keys with HashAPIKey.
+type KeyStore interface {
+	// Lookup returns the info of key, reporting whether it is known.
+	Lookup(key string) (APIKeyInfo, bool, error)
+}
+
+// HashAPIKey returns the hex SHA-256 hash of key,
+// under which stores hold keys rather than in clear.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// apiKeyConfig holds the settings of APIKey.
+type apiKeyConfig struct {
+	header string
+	query  string
+	scopes []string
+}
+
+// APIKeyOption configures APIKey.
+type APIKeyOption func(*apiKeyConfig)
+
+// APIKeyHeader reads keys from the request header name
+// instead of X-API-Key.
+func APIKeyHeader(name string) APIKeyOption {
+	return func(c *apiKeyConfig) { c.header = name }
+}
+
+// APIKeyQuery also reads keys from the query argument name,
+// when the request has none in the header.
+func APIKeyQuery(name string) APIKeyOption {
+	return func(c *apiKeyConfig) { c.query = name }
+}
+
+// APIKeyScopes requires keys to be granted all of scopes.
+func APIKeyScopes(scopes ...string) APIKeyOption {
+	return func(c *apiKeyConfig) { c.scopes = append(c.scopes, scopes...) }
+}
+
+// APIKey returns a constructor authenticating requests by an API key
+// looked up in store. The key is read from the X-API-Key header
+// unless changed by the options.
+// Requests without a known key are answered with 401 Unauthorized,
+// those whose key lacks a scope required by APIKeyScopes with 403 Forbidden,
+// and those whose key cannot be looked up with 503 Service Unavailable,
+// without calling the following handlers.
+//
+// The accepted key can be read with APIKeyFrom,
+// and rate limited by ID with KeyByAPIKey:
+//
+//	keys := fastalice.NewMemoryKeyStore()
+//	keys.Add(secret, fastalice.APIKeyInfo{ID: "acme", Scopes: []string{"orders:read"}, Tier: "gold"})
+//	chain := fastalice.New(
+//		fastalice.APIKey(keys, fastalice.APIKeyScopes("orders:read")),
+//		fastalice.RateLimit(store, fastalice.Policy{Limit: 100, Window: time.Minute, Key: fastalice.KeyByAPIKey}),
+//	)
+func APIKey(store KeyStore, opts ...APIKeyOption) Constructor {
+	cfg := apiKeyConfig{header: "X-API-Key"}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			key := string(ctx.Request.Header.Peek(cfg.header))
+			if key == "" && cfg.query != "" {
+				key = string(ctx.QueryArgs().Peek(cfg.query))
+			}
+			if key == "" {
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusUnauthorized), fasthttp.StatusUnauthorized)
+				return
+			}
+
+			info, ok, err := store.Lookup(key)
+			switch {
+			case err != nil:
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusServiceUnavailable), fasthttp.StatusServiceUnavailable)
+				return
+			case !ok:
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusUnauthorized), fasthttp.StatusUnauthorized)
+				return
+			}
+			for _, scope := range cfg.scopes {
+				if !info.HasScope(scope) {
+					ctx.Error(fasthttp.StatusMessage(fasthttp.StatusForbidden), fasthttp.StatusForbidden)
+					return
+				}
+			}
+
+			Set(ctx, apiKeyKey, info)
+			next(ctx)
+		}
+	}
+}
+
+// APIKeyFrom returns the info of the key accepted by APIKey,
+// reporting whether there is one.
+func APIKeyFrom(ctx *fasthttp.RequestCtx) (APIKeyInfo, bool) {
+	return Get(ctx, apiKeyKey)
+}
+
+// KeyByAPIKey counts requests by the ID of the key accepted by APIKey.
+func KeyByAPIKey(ctx *fasthttp.RequestCtx) string {
+	info, _ := Get(ctx, apiKeyKey)
+	return info.ID
+}
+
+// hashedKeys maps key hashes to the info of their key.
+type hashedKeys map[string]APIKeyInfo
+
+// lookup returns the info of key, comparing hashes in constant time.
+func (keys hashedKeys) lookup(key string) (APIKeyInfo, bool) {
+	hash := HashAPIKey(key)
+	for h, info := range keys {
+		if subtle.ConstantTimeCompare([]byte(h), []byte(hash)) == 1 {
+			return info, true
+		}
+	}
+	return APIKeyInfo{}, false
+}
+
+// MemoryKeyStore is a KeyStore holding keys in memory,
+// by their hash.
+type MemoryKeyStore struct {
+	mu   sync.RWMutex
+	keys hashedKeys
+}
+
+// NewMemoryKeyStore returns an empty MemoryKeyStore.
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{keys: hashedKeys{}}
+}
+
+// Add adds key with info, replacing any previous info.
+func (s *MemoryKeyStore) Add(key string, info APIKeyInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[HashAPIKey(key)] = info
+}
+
+// Remove revokes key.
+func (s *MemoryKeyStore) Remove(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, HashAPIKey(key))
+}
+
+func (s *MemoryKeyStore) Lookup(key string) (APIKeyInfo, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	info, ok := s.keys.lookup(key)
+	return info, ok, nil
+}
+
+// FileKeyStore is a KeyStore reading its keys from a JSON file
+// holding an array of keys, given by their SHA-256 hash,
+// as returned by HashAPIKey, so that the file does not reveal them:
+//
+//	[{"sha256": "9f86d0...", "id": "acme", "scopes": ["orders:read"], "tier": "gold"}]
+type FileKeyStore struct {
+	path string
+
+	mu   sync.RWMutex
+	keys hashedKeys
+}
+
+// NewFileKeyStore returns a FileKeyStore reading the file at path.
+// An error is returned if the file cannot be read or is malformed.
+func NewFileKeyStore(path string) (*FileKeyStore, error) {
+	s := &FileKeyStore{path: path}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload reads the file again, such as after keys were rotated.
+// The keys are left unchanged if it cannot be read or is malformed.
+func (s *FileKeyStore) Reload() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("fastalice: cannot read API keys: %w", err)
+	}
+	var entries []struct {
+		SHA256 string `json:"sha256"`
+		APIKeyInfo
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("fastalice: invalid API keys in %s: %w", s.path, err)
+	}
+
+	keys := make(hashedKeys, len(entries))
+	for i, e := range entries {
+		if len(e.SHA256) != 2*sha256.Size {
+			return fmt.Errorf("fastalice: invalid API key hash at index %d in %s", i, s.path)
+		}
+		keys[strings.ToLower(e.SHA256)] = e.APIKeyInfo
+	}
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *FileKeyStore) Lookup(key string) (APIKeyInfo, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	info, ok := s.keys.lookup(key)
+	return info, ok, nil
+}
@@ -0,0 +1,107 @@
+package fastalice
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+type failingKeyStore struct{}
+
+func (failingKeyStore) Lookup(string) (APIKeyInfo, bool, error) {
+	return APIKeyInfo{}, false, errors.New("down")
+}
+
+func TestAPIKey(t *testing.T) {
+	keys := NewMemoryKeyStore()
+	keys.Add("k-123", APIKeyInfo{ID: "acme", Scopes: []string{"orders:read"}, Tier: "gold"})
+
+	var seen APIKeyInfo
+	h := New(APIKey(keys, APIKeyQuery("api_key"))).Then(func(ctx *fasthttp.RequestCtx) {
+		seen, _ = APIKeyFrom(ctx)
+	})
+
+	ctx := newTestCtx("GET", "http://localhost/orders")
+	ctx.Request.Header.Set("X-API-Key", "k-123")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "A known key should be accepted")
+	assert.Equal(t, "gold", seen.Tier, "The key info should be exposed")
+	assert.Equal(t, "acme", KeyByAPIKey(ctx), "Requests should be keyed by key ID")
+
+	ctx = newTestCtx("GET", "http://localhost/orders?api_key=k-123")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Keys should be read from the query")
+
+	ctx = newTestCtx("GET", "http://localhost/orders")
+	ctx.Request.Header.Set("X-API-Key", "k-456")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "Unknown keys should be rejected")
+
+	ctx = newTestCtx("GET", "http://localhost/orders")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "Requests without a key should be rejected")
+
+	keys.Remove("k-123")
+	ctx = newTestCtx("GET", "http://localhost/orders")
+	ctx.Request.Header.Set("X-API-Key", "k-123")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "Removed keys should be rejected")
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	keys := NewMemoryKeyStore()
+	keys.Add("reader", APIKeyInfo{ID: "r", Scopes: []string{"orders:read"}})
+	keys.Add("writer", APIKeyInfo{ID: "w", Scopes: []string{"orders:read", "orders:write"}})
+	h := New(APIKey(keys, APIKeyHeader("Authorization"), APIKeyScopes("orders:write"))).Then(testApp)
+
+	ctx := newTestCtx("POST", "http://localhost/orders")
+	ctx.Request.Header.Set("Authorization", "reader")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusForbidden, ctx.Response.StatusCode(), "Keys lacking a scope should be forbidden")
+
+	ctx = newTestCtx("POST", "http://localhost/orders")
+	ctx.Request.Header.Set("Authorization", "writer")
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Keys with the scopes should be accepted")
+}
+
+func TestAPIKeyStoreError(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/orders")
+	ctx.Request.Header.Set("X-API-Key", "k")
+	New(APIKey(failingKeyStore{})).Then(testApp)(ctx)
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, ctx.Response.StatusCode(), "Store failures should be unavailable")
+}
+
+func TestFileKeyStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	write := func(content string) {
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o600), "The keys file should be written")
+	}
+	write(`[{"sha256": "` + strings.ToUpper(HashAPIKey("k-123")) + `", "id": "acme", "tier": "gold"}]`)
+
+	store, err := NewFileKeyStore(path)
+	assert.NoError(t, err, "The keys file should be read")
+	info, ok, _ := store.Lookup("k-123")
+	assert.True(t, ok, "Keys should be found by their hash")
+	assert.Equal(t, APIKeyInfo{ID: "acme", Tier: "gold"}, info, "The key info should be read")
+
+	write(`[{"sha256": "` + HashAPIKey("k-456") + `", "id": "acme"}]`)
+	assert.NoError(t, store.Reload(), "The keys file should be reloaded")
+	_, ok, _ = store.Lookup("k-123")
+	assert.False(t, ok, "Rotated keys should be forgotten")
+	_, ok, _ = store.Lookup("k-456")
+	assert.True(t, ok, "New keys should be found")
+
+	write(`[{"sha256": "nope"}]`)
+	assert.Error(t, store.Reload(), "Malformed hashes should be an error")
+	_, ok, _ = store.Lookup("k-456")
+	assert.True(t, ok, "Keys should be kept when the file is malformed")
+
+	_, err = NewFileKeyStore(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err, "A missing file should be an error")
+}
@@ -0,0 +1,270 @@
+package fastalice
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// DefaultAuditBatchSize is the number of events
+	// an Auditor hands to its sink at once
+	// unless AuditBatchSize is given.
+	DefaultAuditBatchSize = 100
+	// DefaultAuditFlushInterval is how often an Auditor
+	// flushes a partial batch unless AuditFlushInterval is given.
+	DefaultAuditFlushInterval = time.Second
+)
+
+// Audit outcomes, derived from the response status.
+const (
+	AuditSuccess = "success"
+	AuditDenied  = "denied"
+	AuditFailure = "failure"
+)
+
+// AuditEvent records who did what, and how it went.
+type AuditEvent struct {
+	Time time.Time
+	// RequestID is the ID assigned by RequestID, if any.
+	RequestID string
+	// Principal identifies the authenticated caller:
+	// the principal set by BearerAuth or BasicAuth,
+	// or the subject of the JWT, if any.
+	Principal string
+	RemoteIP  string
+	Method    string
+	Resource  string
+	Status    int
+	// Outcome is AuditSuccess, AuditDenied for
+	// 401 and 403 responses or AuditFailure.
+	Outcome string
+	// Fields holds the details added by handlers with AuditField.
+	Fields map[string]string
+}
+
+// AuditSink stores audit events, such as in a log or a database.
+type AuditSink interface {
+	// WriteAudit stores a batch of events, in the order they happened.
+	WriteAudit(events []AuditEvent) error
+}
+
+// AuditSinkFunc adapts a function to an AuditSink.
+type AuditSinkFunc func(events []AuditEvent) error
+
+// WriteAudit calls f(events).
+func (f AuditSinkFunc) WriteAudit(events []AuditEvent) error { return f(events) }
+
+// Redactor rewrites an audit event before it is stored,
+// for example to mask personal data.
+type Redactor func(e AuditEvent) AuditEvent
+
+// RedactFields returns a redactor masking the given fields
+// of audit events.
+func RedactFields(names ...string) Redactor {
+	return func(e AuditEvent) AuditEvent {
+		if len(e.Fields) == 0 {
+			return e
+		}
+		fields := make(map[string]string, len(e.Fields))
+		for k, v := range e.Fields {
+			fields[k] = v
+		}
+		for _, name := range names {
+			if _, ok := fields[name]; ok {
+				fields[name] = dumpRedacted
+			}
+		}
+		e.Fields = fields
+		return e
+	}
+}
+
+// auditFieldsKey holds the fields added with AuditField.
+var auditFieldsKey = NewKey[map[string]string]("auditFields")
+
+// AuditField adds a detail to the audit event of the request,
+// such as the ID of the resource it changed.
+func AuditField(ctx *fasthttp.RequestCtx, key, value string) {
+	fields, ok := Get(ctx, auditFieldsKey)
+	if !ok {
+		fields = make(map[string]string)
+		Set(ctx, auditFieldsKey, fields)
+	}
+	fields[key] = value
+}
+
+// auditConfig holds the settings of an Auditor.
+type auditConfig struct {
+	batchSize int
+	interval  time.Duration
+	onError   func(err error, events []AuditEvent)
+}
+
+// AuditOption configures an Auditor.
+type AuditOption func(*auditConfig)
+
+// AuditBatchSize sets the number of events handed to the sink at once,
+// which defaults to DefaultAuditBatchSize.
+func AuditBatchSize(n int) AuditOption {
+	return func(c *auditConfig) { c.batchSize = n }
+}
+
+// AuditFlushInterval sets how often a partial batch is flushed,
+// which defaults to DefaultAuditFlushInterval.
+func AuditFlushInterval(d time.Duration) AuditOption {
+	return func(c *auditConfig) { c.interval = d }
+}
+
+// AuditErrorHandler sets a function called with the batches
+// the sink failed to store. By default they are dropped.
+func AuditErrorHandler(fn func(err error, events []AuditEvent)) AuditOption {
+	return func(c *auditConfig) { c.onError = fn }
+}
+
+// Auditor records audit events asynchronously,
+// handing them to its sink in batches from a background goroutine.
+type Auditor struct {
+	sink     AuditSink
+	redactor Redactor
+	cfg      auditConfig
+	events   chan AuditEvent
+	done     chan struct{}
+	close    sync.Once
+}
+
+// NewAuditor creates an auditor storing events in sink,
+// rewritten by redactor when it is not nil.
+// Close must be called to flush the last events.
+func NewAuditor(sink AuditSink, redactor Redactor, opts ...AuditOption) *Auditor {
+	cfg := auditConfig{
+		batchSize: DefaultAuditBatchSize,
+		interval:  DefaultAuditFlushInterval,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.batchSize <= 0 {
+		cfg.batchSize = DefaultAuditBatchSize
+	}
+	if cfg.interval <= 0 {
+		cfg.interval = DefaultAuditFlushInterval
+	}
+
+	a := &Auditor{
+		sink:     sink,
+		redactor: redactor,
+		cfg:      cfg,
+		events:   make(chan AuditEvent, cfg.batchSize),
+		done:     make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// run batches events until the auditor is closed.
+func (a *Auditor) run() {
+	defer close(a.done)
+	ticker := time.NewTicker(a.cfg.interval)
+	defer ticker.Stop()
+
+	batch := make([]AuditEvent, 0, a.cfg.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := a.sink.WriteAudit(batch); err != nil && a.cfg.onError != nil {
+			a.cfg.onError(err, batch)
+		}
+		batch = make([]AuditEvent, 0, a.cfg.batchSize)
+	}
+
+	for {
+		select {
+		case e, ok := <-a.events:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, e)
+			if len(batch) >= a.cfg.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// Middleware returns a constructor recording an audit event
+// for every request once the following handlers have run.
+// When the sink falls behind, requests wait for room in the queue
+// rather than dropping events.
+// It must not be used after Close.
+func (a *Auditor) Middleware() Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			start := now()
+			next(ctx)
+
+			e := AuditEvent{
+				Time:      start,
+				RequestID: GetRequestID(ctx),
+				Principal: auditPrincipal(ctx),
+				RemoteIP:  ClientIP(ctx).String(),
+				Method:    string(ctx.Method()),
+				Resource:  string(ctx.Path()),
+				Status:    ctx.Response.StatusCode(),
+			}
+			e.Outcome = auditOutcome(e.Status)
+			if fields, ok := Get(ctx, auditFieldsKey); ok {
+				e.Fields = fields
+			}
+			if a.redactor != nil {
+				e = a.redactor(e)
+			}
+			a.events <- e
+		}
+	}
+}
+
+// Close flushes the recorded events and stops the auditor.
+func (a *Auditor) Close() {
+	a.close.Do(func() { close(a.events) })
+	<-a.done
+}
+
+// Audit returns a constructor recording audit events
+// of every request into sink through a new Auditor,
+// rewritten by redactor when it is not nil.
+//
+// Events are stored asynchronously, in batches
+// flushed every DefaultAuditFlushInterval;
+// use NewAuditor directly to flush them on shutdown.
+func Audit(sink AuditSink, redactor Redactor) Constructor {
+	return NewAuditor(sink, redactor).Middleware()
+}
+
+// auditPrincipal returns the authenticated caller of the request.
+func auditPrincipal(ctx *fasthttp.RequestCtx) string {
+	if p := Principal(ctx); p != nil {
+		return fmt.Sprint(p)
+	}
+	if sub, ok := JWTClaims(ctx)["sub"]; ok {
+		return fmt.Sprint(sub)
+	}
+	return ""
+}
+
+// auditOutcome returns the outcome of a response status.
+func auditOutcome(status int) string {
+	switch {
+	case status == fasthttp.StatusUnauthorized || status == fasthttp.StatusForbidden:
+		return AuditDenied
+	case status >= 400:
+		return AuditFailure
+	}
+	return AuditSuccess
+}
@@ -0,0 +1,67 @@
+package fastalice
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestAuditor(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]AuditEvent
+	sink := AuditSinkFunc(func(events []AuditEvent) error {
+		mu.Lock()
+		batches = append(batches, events)
+		mu.Unlock()
+		return nil
+	})
+	a := NewAuditor(sink, RedactFields("card"), AuditBatchSize(2), AuditFlushInterval(time.Hour))
+
+	h := New(a.Middleware(), BasicAuth(func(user, pass string) bool { return pass == "secret" })).Then(func(ctx *fasthttp.RequestCtx) {
+		AuditField(ctx, "order", "42")
+		AuditField(ctx, "card", "4111111111111111")
+		ctx.SetStatusCode(fasthttp.StatusCreated)
+	})
+	for i := 0; i < 3; i++ {
+		ctx := newTestCtx("POST", "http://localhost/orders")
+		ctx.Request.Header.Set(fasthttp.HeaderAuthorization, basicAuthHeader("bob", "secret"))
+		h(ctx)
+	}
+	a.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if assert.Len(t, batches, 2, "Events should be batched and flushed on close") {
+		assert.Len(t, batches[0], 2, "Full batches should be written")
+		e := batches[0][0]
+		assert.Equal(t, "bob", e.Principal, "The principal should be recorded")
+		assert.Equal(t, "POST", e.Method, "The method should be recorded")
+		assert.Equal(t, "/orders", e.Resource, "The resource should be recorded")
+		assert.Equal(t, AuditSuccess, e.Outcome, "The outcome should be recorded")
+		assert.Equal(t, "42", e.Fields["order"], "Fields should be recorded")
+		assert.Equal(t, "[REDACTED]", e.Fields["card"], "Fields should be redacted")
+	}
+}
+
+func TestAuditOutcome(t *testing.T) {
+	assert.Equal(t, AuditSuccess, auditOutcome(fasthttp.StatusOK), "2xx responses should succeed")
+	assert.Equal(t, AuditDenied, auditOutcome(fasthttp.StatusForbidden), "403 responses should be denied")
+	assert.Equal(t, AuditFailure, auditOutcome(fasthttp.StatusInternalServerError), "5xx responses should fail")
+}
+
+func TestAuditorErrors(t *testing.T) {
+	var failed []AuditEvent
+	a := NewAuditor(AuditSinkFunc(func(events []AuditEvent) error {
+		return errors.New("disk full")
+	}), nil, AuditErrorHandler(func(err error, events []AuditEvent) {
+		failed = append(failed, events...)
+	}))
+
+	New(a.Middleware()).Then(testApp)(newTestCtx("GET", "http://localhost/"))
+	a.Close()
+	assert.Len(t, failed, 1, "Failed batches should be handed to the error handler")
+}
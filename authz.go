@@ -0,0 +1,41 @@
+package fastalice
+
+import (
+	"github.com/valyala/fasthttp"
+)
+
+// authzDecisionsKey is the key
+// under which authorization decisions are collected.
+var authzDecisionsKey = NewKey[[]authzDecision]("authzDecisions")
+
+type authzDecision struct {
+	allowed bool
+	reason  string
+}
+
+// AuthzDecision records an authorization decision for the request,
+// so it can be forwarded to an audit trail by AuditAuthz.
+// Authorization middleware should call it
+// every time it allows or denies a request.
+func AuthzDecision(ctx *fasthttp.RequestCtx, allowed bool, reason string) {
+	decisions, _ := Get(ctx, authzDecisionsKey)
+	Set(ctx, authzDecisionsKey, append(decisions, authzDecision{allowed, reason}))
+}
+
+// AuditAuthz returns a constructor that forwards every decision
+// recorded with AuthzDecision to sink, in the order they were made,
+// once the following handlers have run.
+//
+// It should be placed before any authorization middleware in the chain.
+func AuditAuthz(sink func(ctx *fasthttp.RequestCtx, allowed bool, reason string)) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			next(ctx)
+
+			decisions, _ := Get(ctx, authzDecisionsKey)
+			for _, d := range decisions {
+				sink(ctx, d.allowed, d.reason)
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestAuditAuthzRecordsDeniedRequest(t *testing.T) {
+	type record struct {
+		allowed bool
+		reason  string
+	}
+	var records []record
+
+	audit := AuditAuthz(func(ctx *fasthttp.RequestCtx, allowed bool, reason string) {
+		records = append(records, record{allowed, reason})
+	})
+	deny := func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			AuthzDecision(ctx, false, "missing admin role")
+			ctx.SetStatusCode(fasthttp.StatusForbidden)
+		}
+	}
+
+	ctx := newTestCtx("GET", "http://localhost/admin")
+	New(audit, deny).Then(testApp)(ctx)
+	assert.Equal(t, fasthttp.StatusForbidden, ctx.Response.StatusCode(), "Request should be denied")
+	assert.Equal(t, []record{{false, "missing admin role"}}, records, "The denial should be audited with its reason")
+}
+
+func TestAuditAuthzRecordsEveryDecision(t *testing.T) {
+	var reasons []string
+	audit := AuditAuthz(func(ctx *fasthttp.RequestCtx, allowed bool, reason string) {
+		reasons = append(reasons, reason)
+	})
+	allow := func(reason string) Constructor {
+		return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+			return func(ctx *fasthttp.RequestCtx) {
+				AuthzDecision(ctx, true, reason)
+				next(ctx)
+			}
+		}
+	}
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(audit, allow("valid session"), allow("owns resource")).Then(testApp)(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Allowed requests should reach the app")
+	assert.Equal(t, []string{"valid session", "owns resource"}, reasons, "Decisions should be audited in order")
+}
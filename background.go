@@ -0,0 +1,147 @@
+package fastalice
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/valyala/fasthttp"
+)
+
+// WorkerPool runs tasks on a bounded number of goroutines,
+// for work done in the background of requests by Go.
+type WorkerPool struct {
+	tasks   chan func()
+	onPanic func(recovered interface{})
+	wg      sync.WaitGroup
+	dropped int64
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// workerPoolConfig holds the settings of NewWorkerPool.
+type workerPoolConfig struct {
+	onPanic func(recovered interface{})
+}
+
+// WorkerPoolOption configures NewWorkerPool.
+type WorkerPoolOption func(*workerPoolConfig)
+
+// WorkerPoolOnPanic calls fn with the value of tasks that panic,
+// which are otherwise recovered silently.
+func WorkerPoolOnPanic(fn func(recovered interface{})) WorkerPoolOption {
+	return func(c *workerPoolConfig) { c.onPanic = fn }
+}
+
+// NewWorkerPool returns a WorkerPool running tasks on workers goroutines,
+// with up to queue more tasks waiting for one of them.
+// It panics if workers is not positive.
+func NewWorkerPool(workers, queue int, opts ...WorkerPoolOption) *WorkerPool {
+	if workers <= 0 {
+		panic("fastalice: NewWorkerPool needs a positive number of workers")
+	}
+	var cfg workerPoolConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	p := &WorkerPool{tasks: make(chan func(), queue), onPanic: cfg.onPanic}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+func (p *WorkerPool) work() {
+	defer p.wg.Done()
+	for task := range p.tasks {
+		p.run(task)
+	}
+}
+
+func (p *WorkerPool) run(task func()) {
+	defer func() {
+		if r := recover(); r != nil && p.onPanic != nil {
+			p.onPanic(r)
+		}
+	}()
+	task()
+}
+
+// Submit queues task, reporting whether it was accepted.
+// Tasks are dropped when the queue is full or the pool closed.
+func (p *WorkerPool) Submit(task func()) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if !p.closed {
+		select {
+		case p.tasks <- task:
+			return true
+		default:
+		}
+	}
+	atomic.AddInt64(&p.dropped, 1)
+	return false
+}
+
+// Dropped returns the number of tasks dropped by Submit.
+func (p *WorkerPool) Dropped() int64 {
+	return atomic.LoadInt64(&p.dropped)
+}
+
+// Close stops accepting tasks and waits for the queued ones to finish,
+// such as when the server shuts down.
+func (p *WorkerPool) Close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.tasks)
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+}
+
+// backgroundKey holds the pool and the tasks of Background.
+var backgroundKey = NewKey[*backgroundTasks]("background")
+
+type backgroundTasks struct {
+	pool  *WorkerPool
+	tasks []func()
+}
+
+// Background returns a constructor submitting the tasks
+// given to Go by the following handlers to pool once they have returned,
+// so that work such as sending emails or audit events
+// runs after the request on a bounded number of goroutines.
+// Tasks of requests whose handlers panic are dropped.
+func Background(pool *WorkerPool) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			bg := &backgroundTasks{pool: pool}
+			Set(ctx, backgroundKey, bg)
+			next(ctx)
+			Delete(ctx, backgroundKey)
+			for _, task := range bg.tasks {
+				pool.Submit(task)
+			}
+		}
+	}
+}
+
+// Go runs fn in the background once the request is served,
+// on the pool of Background, or in a new goroutine without it.
+//
+// fasthttp reuses the RequestCtx once the handler returns,
+// so fn must not use ctx nor anything read from it without copying:
+// extract what it needs beforehand.
+//
+//	email := string(ctx.FormValue("email"))
+//	fastalice.Go(ctx, func() { mailer.SendWelcome(email) })
+func Go(ctx *fasthttp.RequestCtx, fn func()) {
+	if bg, ok := Get(ctx, backgroundKey); ok {
+		bg.tasks = append(bg.tasks, fn)
+		return
+	}
+	go fn()
+}
@@ -0,0 +1,77 @@
+package fastalice
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestBackgroundRunsAfterHandlers(t *testing.T) {
+	pool := NewWorkerPool(2, 10)
+	var mu sync.Mutex
+	var order []string
+	record := func(s string) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, s)
+	}
+
+	handlerDone := make(chan struct{})
+	h := New(Background(pool)).Then(func(ctx *fasthttp.RequestCtx) {
+		path := string(ctx.Path())
+		Go(ctx, func() {
+			<-handlerDone
+			record("task " + path)
+		})
+		record("handler")
+		close(handlerDone)
+	})
+
+	h(newTestCtx("POST", "http://localhost/signup"))
+	pool.Close()
+	assert.Equal(t, []string{"handler", "task /signup"}, order, "Tasks should run after the handler")
+}
+
+func TestBackgroundDropsTasksOfPanickingRequests(t *testing.T) {
+	pool := NewWorkerPool(1, 10)
+	ran := false
+	h := New(Background(pool)).Then(func(ctx *fasthttp.RequestCtx) {
+		Go(ctx, func() { ran = true })
+		panic("boom")
+	})
+
+	assert.Panics(t, func() { h(newTestCtx("GET", "http://localhost/")) }, "The panic should propagate")
+	pool.Close()
+	assert.False(t, ran, "Tasks of panicking requests should be dropped")
+}
+
+func TestWorkerPoolBounds(t *testing.T) {
+	var recovered interface{}
+	pool := NewWorkerPool(1, 1, WorkerPoolOnPanic(func(r interface{}) { recovered = r }))
+	block := make(chan struct{})
+	started := make(chan struct{})
+
+	assert.True(t, pool.Submit(func() { close(started); <-block }), "A task should be accepted")
+	<-started
+	assert.True(t, pool.Submit(func() { panic("task failed") }), "A task should be queued")
+	assert.False(t, pool.Submit(func() {}), "Tasks beyond the queue should be dropped")
+	assert.Equal(t, int64(1), pool.Dropped(), "Dropped tasks should be counted")
+
+	close(block)
+	pool.Close()
+	assert.Equal(t, "task failed", recovered, "Panicking tasks should be recovered")
+	assert.False(t, pool.Submit(func() {}), "A closed pool should drop tasks")
+}
+
+func TestGoWithoutBackground(t *testing.T) {
+	done := make(chan struct{})
+	Go(newTestCtx("GET", "http://localhost/"), func() { close(done) })
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Tasks should run without Background")
+	}
+}
@@ -0,0 +1,94 @@
+package fastalice
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+)
+
+// basicAuthUserKey is the key holding
+// the user authenticated by BasicAuth.
+var basicAuthUserKey = NewKey[string]("basicAuthUser")
+
+// basicAuthConfig holds the settings of BasicAuth.
+type basicAuthConfig struct {
+	realm string
+}
+
+// BasicAuthOption configures BasicAuth.
+type BasicAuthOption func(*basicAuthConfig)
+
+// BasicAuthRealm names the realm of the WWW-Authenticate challenge
+// instead of "Restricted".
+func BasicAuthRealm(realm string) BasicAuthOption {
+	return func(c *basicAuthConfig) { c.realm = realm }
+}
+
+// BasicAuth returns a constructor that gates the following handlers
+// behind HTTP basic authentication.
+// Credentials from the Authorization header are checked with validate;
+// when they are missing or invalid, the request is answered
+// with 401 and a WWW-Authenticate challenge for the realm
+// set with BasicAuthRealm.
+//
+// The authenticated user can be read with BasicAuthUser,
+// or with Principal.
+// Validators should compare credentials with SecureCompare
+// to avoid leaking them through timing.
+func BasicAuth(validate func(user, pass string) bool, opts ...BasicAuthOption) Constructor {
+	cfg := basicAuthConfig{realm: "Restricted"}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	challenge := fmt.Sprintf("Basic realm=%q", cfg.realm)
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			user, pass, ok := parseBasicAuth(ctx.Request.Header.Peek(fasthttp.HeaderAuthorization))
+			if !ok || !validate(user, pass) {
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusUnauthorized), fasthttp.StatusUnauthorized)
+				ctx.Response.Header.Set(fasthttp.HeaderWWWAuthenticate, challenge)
+				return
+			}
+
+			Set(ctx, basicAuthUserKey, user)
+			Set[interface{}](ctx, principalKey, user)
+			next(ctx)
+		}
+	}
+}
+
+// BasicAuthUser returns the user authenticated by BasicAuth,
+// or an empty string if there is none.
+func BasicAuthUser(ctx *fasthttp.RequestCtx) string {
+	user, _ := Get(ctx, basicAuthUserKey)
+	return user
+}
+
+// SecureCompare reports whether a and b are equal
+// in constant time.
+func SecureCompare(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// parseBasicAuth extracts the credentials
+// from a basic Authorization header value.
+func parseBasicAuth(auth []byte) (user, pass string, ok bool) {
+	const prefix = "Basic "
+	if len(auth) < len(prefix) || !bytes.EqualFold(auth[:len(prefix)], []byte(prefix)) {
+		return "", "", false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(string(auth[len(prefix):]))
+	if err != nil {
+		return "", "", false
+	}
+	i := bytes.IndexByte(decoded, ':')
+	if i < 0 {
+		return "", "", false
+	}
+	return string(decoded[:i]), string(decoded[i+1:]), true
+}
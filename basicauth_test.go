@@ -0,0 +1,51 @@
+package fastalice
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func testBasicAuth() fasthttp.RequestHandler {
+	auth := BasicAuth(func(user, pass string) bool {
+		return SecureCompare(user, "admin") && SecureCompare(pass, "secret")
+	}, BasicAuthRealm("tools"))
+	return New(auth).Then(func(ctx *fasthttp.RequestCtx) {
+		ctx.WriteString("hello " + BasicAuthUser(ctx))
+	})
+}
+
+func basicAuthHeader(user, pass string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+pass))
+}
+
+func TestBasicAuthMissingHeader(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	testBasicAuth()(ctx)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "Requests without credentials should be rejected")
+	assert.Equal(t, `Basic realm="tools"`, string(ctx.Response.Header.Peek(fasthttp.HeaderWWWAuthenticate)), "Rejections should carry a challenge")
+}
+
+func TestBasicAuthWrongCredentials(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set(fasthttp.HeaderAuthorization, basicAuthHeader("admin", "wrong"))
+	testBasicAuth()(ctx)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "Requests with wrong credentials should be rejected")
+}
+
+func TestBasicAuthCorrectCredentials(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set(fasthttp.HeaderAuthorization, basicAuthHeader("admin", "secret"))
+	testBasicAuth()(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Requests with correct credentials should pass")
+	assert.Equal(t, "hello admin", string(ctx.Response.Body()), "The authenticated user should be available downstream")
+}
+
+func TestBasicAuthDefaultRealm(t *testing.T) {
+	h := New(BasicAuth(func(user, pass string) bool { return false })).Then(testApp)
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, `Basic realm="Restricted"`, string(ctx.Response.Header.Peek(fasthttp.HeaderWWWAuthenticate)), "The challenge should default to the Restricted realm")
+}
@@ -0,0 +1,57 @@
+package fastalice
+
+import (
+	"bytes"
+
+	"github.com/valyala/fasthttp"
+)
+
+// principalKey is the key holding
+// the principal authenticated by BasicAuth or BearerAuth.
+var principalKey = NewKey[interface{}]("principal")
+
+// BearerAuth returns a constructor that gates the following handlers
+// behind bearer token authentication.
+// The token from the Authorization header is checked with validate,
+// which returns the principal it identifies;
+// when it is missing or invalid, the request is answered
+// with 401 and a Bearer WWW-Authenticate challenge.
+//
+// The principal can be read with Principal.
+func BearerAuth(validate func(token string) (principal interface{}, ok bool)) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			token, ok := parseBearerAuth(ctx.Request.Header.Peek(fasthttp.HeaderAuthorization))
+			var principal interface{}
+			if ok {
+				principal, ok = validate(token)
+			}
+			if !ok {
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusUnauthorized), fasthttp.StatusUnauthorized)
+				ctx.Response.Header.Set(fasthttp.HeaderWWWAuthenticate, "Bearer")
+				return
+			}
+
+			Set(ctx, principalKey, principal)
+			next(ctx)
+		}
+	}
+}
+
+// Principal returns the principal authenticated by BearerAuth,
+// or the user authenticated by BasicAuth,
+// or nil if there is none.
+func Principal(ctx *fasthttp.RequestCtx) interface{} {
+	principal, _ := Get(ctx, principalKey)
+	return principal
+}
+
+// parseBearerAuth extracts the token
+// from a bearer Authorization header value.
+func parseBearerAuth(auth []byte) (token string, ok bool) {
+	const prefix = "Bearer "
+	if len(auth) <= len(prefix) || !bytes.EqualFold(auth[:len(prefix)], []byte(prefix)) {
+		return "", false
+	}
+	return string(bytes.TrimSpace(auth[len(prefix):])), true
+}
@@ -0,0 +1,51 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+type testPrincipal struct {
+	ID string
+}
+
+func testBearerAuth() fasthttp.RequestHandler {
+	auth := BearerAuth(func(token string) (interface{}, bool) {
+		if !SecureCompare(token, "s3cr3t") {
+			return nil, false
+		}
+		return testPrincipal{ID: "svc-1"}, true
+	})
+	return New(auth).Then(func(ctx *fasthttp.RequestCtx) {
+		ctx.WriteString("hello " + Principal(ctx).(testPrincipal).ID)
+	})
+}
+
+func TestBearerAuthRejects(t *testing.T) {
+	for _, header := range []string{"", "Bearer ", "Bearer wrong", "Basic s3cr3t"} {
+		ctx := newTestCtx("GET", "http://localhost/")
+		if header != "" {
+			ctx.Request.Header.Set(fasthttp.HeaderAuthorization, header)
+		}
+		testBearerAuth()(ctx)
+		assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "Authorization %q should be rejected", header)
+		assert.Equal(t, "Bearer", string(ctx.Response.Header.Peek(fasthttp.HeaderWWWAuthenticate)), "Rejections should carry a challenge")
+	}
+}
+
+func TestBearerAuthAccepts(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set(fasthttp.HeaderAuthorization, "bearer s3cr3t")
+	testBearerAuth()(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Valid tokens should pass")
+	assert.Equal(t, "hello svc-1", string(ctx.Response.Body()), "The principal should be available downstream")
+}
+
+func TestBasicAuthSetsPrincipal(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set(fasthttp.HeaderAuthorization, basicAuthHeader("admin", "secret"))
+	testBasicAuth()(ctx)
+	assert.Equal(t, "admin", Principal(ctx), "BasicAuth should set the principal")
+}
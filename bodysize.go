@@ -0,0 +1,90 @@
+package fastalice
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/valyala/fasthttp"
+)
+
+// MaxBodySize returns a constructor that rejects requests
+// whose body is larger than limit bytes
+// with 413 Request Entity Too Large,
+// without calling the following handlers.
+//
+// Both the declared Content-Length
+// and the actual body length are checked,
+// so requests that omit or understate their length are caught too.
+//
+// When the request body is a stream,
+// as when the server streams request bodies,
+// at most limit+1 bytes are read from it before rejection,
+// so oversized bodies are never fully buffered.
+// Accepted streamed bodies are buffered
+// for the following handlers, unless Streaming asks
+// for StreamBodies: only their declared length is then checked,
+// and the server should bound them with its MaxRequestBodySize.
+func MaxBodySize(limit int64) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if int64(ctx.Request.Header.ContentLength()) > limit || !keepStreaming(ctx) && !bodyWithin(&ctx.Request, limit) {
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusRequestEntityTooLarge), fasthttp.StatusRequestEntityTooLarge)
+				return
+			}
+			next(ctx)
+		}
+	}
+}
+
+// errBodyTooLarge stops reading a streamed body past its limit.
+var errBodyTooLarge = errors.New("fastalice: request body too large")
+
+// bodyWithin reports whether the body of req is at most limit bytes.
+// A streamed body is read up to limit+1 bytes
+// and, when within limit, replaces the stream.
+func bodyWithin(req *fasthttp.Request, limit int64) bool {
+	if !req.IsBodyStream() {
+		return int64(len(req.Body())) <= limit
+	}
+
+	w := &limitedBuffer{limit: limit}
+	if err := req.BodyWriteTo(w); err != nil {
+		return false
+	}
+	req.SetBody(w.buf.Bytes())
+	return true
+}
+
+// limitedBuffer is a buffer refusing to grow past limit bytes.
+type limitedBuffer struct {
+	buf   bytes.Buffer
+	limit int64
+}
+
+func (w *limitedBuffer) Write(p []byte) (int, error) {
+	if int64(w.buf.Len()+len(p)) > w.limit {
+		return 0, errBodyTooLarge
+	}
+	return w.buf.Write(p)
+}
+
+// MaxResponseSize returns a constructor that replaces responses
+// whose body is larger than limit bytes
+// with 500 Internal Server Error and a short message,
+// once the following handlers have run.
+//
+// It is a guard applied after the fact, not a streaming cap:
+// the oversized body is still produced in memory,
+// it is only kept from reaching the client.
+// Streaming responses are not checked.
+func MaxResponseSize(limit int) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			next(ctx)
+
+			if !IsStreaming(ctx) && len(ctx.Response.Body()) > limit {
+				ctx.Error("response too large", fasthttp.StatusInternalServerError)
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package fastalice
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestMaxBodySizeUnderLimit(t *testing.T) {
+	ctx := newTestCtx("POST", "http://localhost/")
+	ctx.Request.SetBodyString("small")
+	ctx.Request.Header.SetContentLength(5)
+	New(MaxBodySize(10)).Then(testApp)(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Small bodies should pass")
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Small bodies should reach the app")
+}
+
+func TestMaxBodySizeOverLimit(t *testing.T) {
+	ctx := newTestCtx("POST", "http://localhost/")
+	ctx.Request.SetBodyString(strings.Repeat("a", 20))
+	ctx.Request.Header.SetContentLength(20)
+	New(MaxBodySize(10)).Then(testApp)(ctx)
+	assert.Equal(t, fasthttp.StatusRequestEntityTooLarge, ctx.Response.StatusCode(), "Large bodies should be rejected")
+}
+
+func TestMaxBodySizeMissingContentLength(t *testing.T) {
+	ctx := newTestCtx("POST", "http://localhost/")
+	ctx.Request.SetBodyString(strings.Repeat("a", 20))
+	New(MaxBodySize(10)).Then(testApp)(ctx)
+	assert.Equal(t, fasthttp.StatusRequestEntityTooLarge, ctx.Response.StatusCode(), "Large bodies without Content-Length should be rejected")
+}
+
+func TestMaxResponseSizeUnderLimit(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(MaxResponseSize(10)).Then(testApp)(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Small responses should pass")
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Small responses should be kept")
+}
+
+func TestMaxResponseSizeOverLimit(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(MaxResponseSize(10)).Then(func(ctx *fasthttp.RequestCtx) {
+		ctx.WriteString(strings.Repeat("a", 20))
+	})(ctx)
+	assert.Equal(t, fasthttp.StatusInternalServerError, ctx.Response.StatusCode(), "Large responses should be replaced")
+	assert.Equal(t, "response too large", string(ctx.Response.Body()), "Large responses should not leak")
+}
+
+// countingReader counts the bytes read from it.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func TestMaxBodySizeStreamOverLimit(t *testing.T) {
+	body := &countingReader{r: strings.NewReader(strings.Repeat("a", 1<<20))}
+	ctx := newTestCtx("POST", "http://localhost/")
+	ctx.Request.SetBodyStream(body, -1)
+	New(MaxBodySize(10)).Then(testApp)(ctx)
+	assert.Equal(t, fasthttp.StatusRequestEntityTooLarge, ctx.Response.StatusCode(), "Large streamed bodies should be rejected")
+	assert.Less(t, body.n, 1<<20, "Large streamed bodies should not be read fully")
+}
+
+func TestMaxBodySizeStreamUnderLimit(t *testing.T) {
+	var got string
+	ctx := newTestCtx("POST", "http://localhost/")
+	ctx.Request.SetBodyStream(strings.NewReader("small"), -1)
+	New(MaxBodySize(10)).Then(func(ctx *fasthttp.RequestCtx) {
+		got = string(ctx.PostBody())
+	})(ctx)
+	assert.Equal(t, "small", got, "Small streamed bodies should reach the app")
+}
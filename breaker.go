@@ -0,0 +1,187 @@
+package fastalice
+
+import (
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// BreakerPolicy configures CircuitBreaker.
+// Zero fields take the documented defaults.
+type BreakerPolicy struct {
+	// FailureRatio is the ratio of failed requests in a window
+	// that opens the breaker, 0.5 by default.
+	FailureRatio float64
+	// MinRequests is the number of requests a window needs
+	// before the failure ratio is considered, 10 by default.
+	MinRequests int
+	// Window is the period over which requests are counted,
+	// 10 seconds by default.
+	Window time.Duration
+	// OpenTimeout is how long the breaker stays open
+	// before letting probes through, 30 seconds by default.
+	OpenTimeout time.Duration
+	// HalfOpenProbes is the number of requests let through at once
+	// while probing whether the downstream recovered, 1 by default.
+	HalfOpenProbes int
+	// Key selects the breaker of a request,
+	// such as one per route; all requests share one by default.
+	Key func(ctx *fasthttp.RequestCtx) string
+	// IsFailure reports whether a served request failed.
+	// By default 5xx responses and 408 Request Timeout,
+	// as written by Timeout, are failures.
+	IsFailure func(ctx *fasthttp.RequestCtx) bool
+}
+
+// breakerState is the state of a circuit breaker.
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// breaker is the circuit breaker of a key.
+type breaker struct {
+	mu          sync.Mutex
+	state       breakerState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openUntil   time.Time
+	probes      int
+}
+
+// CircuitBreaker returns a constructor that stops calling
+// the following handlers once too many of them fail.
+//
+// While closed, the breaker counts requests and failures,
+// panics included, in fixed windows. It opens when the failure ratio
+// of a window reaches policy.FailureRatio, answering requests
+// with 503 Service Unavailable and a Retry-After header.
+// After policy.OpenTimeout it lets probe requests through:
+// a successful probe closes it, a failed one opens it again.
+func CircuitBreaker(policy BreakerPolicy) Constructor {
+	if policy.FailureRatio <= 0 {
+		policy.FailureRatio = 0.5
+	}
+	if policy.MinRequests <= 0 {
+		policy.MinRequests = 10
+	}
+	if policy.Window <= 0 {
+		policy.Window = 10 * time.Second
+	}
+	if policy.OpenTimeout <= 0 {
+		policy.OpenTimeout = 30 * time.Second
+	}
+	if policy.HalfOpenProbes <= 0 {
+		policy.HalfOpenProbes = 1
+	}
+	if policy.IsFailure == nil {
+		policy.IsFailure = func(ctx *fasthttp.RequestCtx) bool {
+			code := ctx.Response.StatusCode()
+			return code >= 500 || code == fasthttp.StatusRequestTimeout
+		}
+	}
+
+	var (
+		mu       sync.Mutex
+		breakers = make(map[string]*breaker)
+	)
+	breakerFor := func(key string) *breaker {
+		mu.Lock()
+		defer mu.Unlock()
+		b, ok := breakers[key]
+		if !ok {
+			b = &breaker{}
+			breakers[key] = b
+		}
+		return b
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			var key string
+			if policy.Key != nil {
+				key = policy.Key(ctx)
+			}
+			b := breakerFor(key)
+
+			probe, retryAfter := b.allow(policy)
+			if retryAfter > 0 {
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusServiceUnavailable), fasthttp.StatusServiceUnavailable)
+				ctx.Response.Header.Set(fasthttp.HeaderRetryAfter, seconds(retryAfter))
+				return
+			}
+
+			failed := true
+			defer func() {
+				b.done(policy, probe, failed)
+			}()
+			next(ctx)
+			failed = policy.IsFailure(ctx)
+		}
+	}
+}
+
+// allow reports whether a request may go through.
+// When it may not, retryAfter is how long the breaker stays open.
+func (b *breaker) allow(policy BreakerPolicy) (probe bool, retryAfter time.Duration) {
+	t := now()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerOpen {
+		if t.Before(b.openUntil) {
+			return false, b.openUntil.Sub(t)
+		}
+		b.state, b.probes = breakerHalfOpen, 0
+	}
+	if b.state == breakerHalfOpen {
+		if b.probes >= policy.HalfOpenProbes {
+			return false, time.Second
+		}
+		b.probes++
+		return true, 0
+	}
+
+	if t.Sub(b.windowStart) >= policy.Window {
+		b.windowStart, b.requests, b.failures = t, 0, 0
+	}
+	return false, 0
+}
+
+// done records the outcome of a request let through by allow.
+func (b *breaker) done(policy BreakerPolicy, probe, failed bool) {
+	t := now()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		if b.state != breakerHalfOpen {
+			return
+		}
+		if failed {
+			b.state, b.openUntil = breakerOpen, t.Add(policy.OpenTimeout)
+			return
+		}
+		b.state, b.windowStart, b.requests, b.failures = breakerClosed, t, 0, 0
+		return
+	}
+
+	if b.state != breakerClosed {
+		return
+	}
+	b.requests++
+	if failed {
+		b.failures++
+	}
+	if b.requests >= policy.MinRequests &&
+		float64(b.failures) >= policy.FailureRatio*float64(b.requests) {
+		b.state, b.openUntil = breakerOpen, t.Add(policy.OpenTimeout)
+	}
+}
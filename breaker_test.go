@@ -0,0 +1,84 @@
+package fastalice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestCircuitBreakerOpens(t *testing.T) {
+	defer fakeClock(time.Millisecond)()
+
+	failing := true
+	h := New(CircuitBreaker(BreakerPolicy{MinRequests: 4, OpenTimeout: time.Minute})).Then(func(ctx *fasthttp.RequestCtx) {
+		if failing {
+			ctx.SetStatusCode(fasthttp.StatusBadGateway)
+		}
+	})
+
+	for i := 0; i < 4; i++ {
+		ctx := newTestCtx("GET", "http://localhost/")
+		h(ctx)
+		assert.Equal(t, fasthttp.StatusBadGateway, ctx.Response.StatusCode(), "Requests should go through while closed")
+	}
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, ctx.Response.StatusCode(), "Requests should be rejected once open")
+	assert.Equal(t, "60", string(ctx.Response.Header.Peek(fasthttp.HeaderRetryAfter)), "Retry-After should tell when the breaker closes")
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	defer fakeClock(time.Minute)()
+
+	failing := true
+	h := New(CircuitBreaker(BreakerPolicy{MinRequests: 1, OpenTimeout: 90 * time.Second})).Then(func(ctx *fasthttp.RequestCtx) {
+		if failing {
+			panic("boom")
+		}
+	})
+	serve := func() int {
+		ctx := newTestCtx("GET", "http://localhost/")
+		func() {
+			defer func() { recover() }()
+			h(ctx)
+		}()
+		return ctx.Response.StatusCode()
+	}
+
+	serve()
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, serve(), "Panics should count as failures")
+	assert.Equal(t, fasthttp.StatusOK, serve(), "A probe should go through after the open timeout")
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, serve(), "A failed probe should open the breaker again")
+
+	failing = false
+	serve()
+	serve()
+	assert.Equal(t, fasthttp.StatusOK, serve(), "A successful probe should close the breaker")
+	assert.Equal(t, fasthttp.StatusOK, serve(), "The closed breaker should let requests through")
+}
+
+func TestCircuitBreakerPerKey(t *testing.T) {
+	defer fakeClock(time.Millisecond)()
+
+	h := New(CircuitBreaker(BreakerPolicy{
+		MinRequests: 1,
+		Key:         func(ctx *fasthttp.RequestCtx) string { return string(ctx.Path()) },
+	})).Then(func(ctx *fasthttp.RequestCtx) {
+		if string(ctx.Path()) == "/broken" {
+			ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		}
+	})
+
+	ctx := newTestCtx("GET", "http://localhost/broken")
+	h(ctx)
+	ctx = newTestCtx("GET", "http://localhost/broken")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, ctx.Response.StatusCode(), "The failing key should be open")
+
+	ctx = newTestCtx("GET", "http://localhost/ok")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Other keys should stay closed")
+}
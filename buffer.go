@@ -0,0 +1,85 @@
+package fastalice
+
+import (
+	"sync"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultBufferMaxSize is the largest body buffered by Buffer
+// unless BufferOptions.MaxSize is set.
+const DefaultBufferMaxSize = 4 << 20
+
+// skipBufferKey marks requests whose response Buffer passes through.
+var skipBufferKey = NewKey[bool]("skipBuffer")
+
+// bufferPool holds the buffers streamed bodies are read into.
+var bufferPool = sync.Pool{New: func() interface{} { return new(limitedBuffer) }}
+
+// BufferOptions configures the Buffer middleware.
+type BufferOptions struct {
+	// MaxSize is the largest streamed body buffered,
+	// which defaults to DefaultBufferMaxSize.
+	MaxSize int64
+	// Passthrough, when not nil, reports whether
+	// a response should be left streaming.
+	// Responses marked by StreamingResponse or StreamEvents,
+	// or sent as text/event-stream, are always left streaming.
+	Passthrough func(ctx *fasthttp.RequestCtx) bool
+}
+
+// Buffer returns a constructor that reads responses
+// whose body the following handlers set as a stream
+// into memory, once they have run.
+//
+// Middleware placed before it then see the whole body
+// with ctx.Response.Body() and can hash, cache or measure it,
+// as ETag, Cache and MaxResponseSize do,
+// while the client gets a response with a Content-Length.
+// Streamed bodies larger than opts.MaxSize are replaced
+// with 500 Internal Server Error, as MaxResponseSize does.
+//
+// Server-sent events and responses selected by opts.Passthrough
+// or by SkipBuffer are left streaming.
+func Buffer(opts BufferOptions) Constructor {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = DefaultBufferMaxSize
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			next(ctx)
+
+			if !ctx.Response.IsBodyStream() || ctx.Hijacked() {
+				return
+			}
+			if skip, _ := Get(ctx, skipBufferKey); skip {
+				return
+			}
+			if marked, _ := Get(ctx, streamingKey); marked || ProtocolInfo(ctx).HTTP2() ||
+				mediaType(ctx.Response.Header.ContentType()) == "text/event-stream" ||
+				opts.Passthrough != nil && opts.Passthrough(ctx) {
+				return
+			}
+
+			w := bufferPool.Get().(*limitedBuffer)
+			defer func() {
+				w.buf.Reset()
+				bufferPool.Put(w)
+			}()
+			w.limit = opts.MaxSize
+			if err := ctx.Response.BodyWriteTo(w); err != nil {
+				ctx.Error("response too large", fasthttp.StatusInternalServerError)
+				return
+			}
+			ctx.Response.SetBody(w.buf.Bytes())
+		}
+	}
+}
+
+// SkipBuffer makes Buffer leave the response of the request streaming.
+// Handlers call it when they stream a response meant
+// to reach the client as it is produced.
+func SkipBuffer(ctx *fasthttp.RequestCtx) {
+	Set(ctx, skipBufferKey, true)
+}
@@ -0,0 +1,47 @@
+package fastalice
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func streamingApp(ctx *fasthttp.RequestCtx) {
+	ctx.SetBodyStream(strings.NewReader("streamed body"), -1)
+}
+
+func TestBuffer(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(ETag(false), Buffer(BufferOptions{})).Then(streamingApp)(ctx)
+
+	assert.False(t, ctx.Response.IsBodyStream(), "The body should be buffered")
+	assert.Equal(t, "streamed body", string(ctx.Response.Body()), "The whole body should be kept")
+	assert.NotEmpty(t, ctx.Response.Header.Peek(fasthttp.HeaderETag), "Earlier middleware should see the buffered body")
+}
+
+func TestBufferPassthrough(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(Buffer(BufferOptions{})).Then(func(ctx *fasthttp.RequestCtx) {
+		SkipBuffer(ctx)
+		streamingApp(ctx)
+	})(ctx)
+	assert.True(t, ctx.Response.IsBodyStream(), "SkipBuffer should leave the body streaming")
+
+	ctx = newTestCtx("GET", "http://localhost/events")
+	New(Buffer(BufferOptions{})).Then(SSE(func(w *EventWriter) {}))(ctx)
+	assert.True(t, ctx.Response.IsBodyStream(), "Server-sent events should be left streaming")
+
+	ctx = newTestCtx("GET", "http://localhost/download")
+	New(Buffer(BufferOptions{Passthrough: func(ctx *fasthttp.RequestCtx) bool {
+		return string(ctx.Path()) == "/download"
+	}})).Then(streamingApp)(ctx)
+	assert.True(t, ctx.Response.IsBodyStream(), "Passthrough should leave the body streaming")
+}
+
+func TestBufferTooLarge(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(Buffer(BufferOptions{MaxSize: 4})).Then(streamingApp)(ctx)
+	assert.Equal(t, fasthttp.StatusInternalServerError, ctx.Response.StatusCode(), "Oversized bodies should be rejected")
+}
@@ -0,0 +1,370 @@
+package fastalice
+
+import (
+	"container/list"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// CacheEntry is a response stored by Cache.
+type CacheEntry struct {
+	// Status, Header and Body are the cached response.
+	Status int
+	Header map[string][]string
+	Body   []byte
+	// Vary lists the request headers the response varies by.
+	// An entry with Vary set is an index: the response itself
+	// is stored under a key including the values of those headers.
+	Vary []string
+	// Stored is when the response was cached.
+	// It is fresh until Expires, then may be served stale
+	// while being revalidated until StaleUntil,
+	// after which stores may drop it.
+	Stored     time.Time
+	Expires    time.Time
+	StaleUntil time.Time
+}
+
+// CacheStore holds the entries of Cache.
+// Implementations backed by Redis or memcached
+// should expire entries at their StaleUntil time.
+// They must be safe for concurrent use.
+type CacheStore interface {
+	// Get returns the entry stored under key,
+	// or nil and no error when there is none.
+	Get(key string) (*CacheEntry, error)
+	// Set stores e under key, replacing any previous entry.
+	Set(key string, e *CacheEntry) error
+	// Delete removes the entry stored under key.
+	Delete(key string) error
+}
+
+// CachePolicy configures Cache.
+type CachePolicy struct {
+	// TTL is how long responses stay fresh
+	// when their Cache-Control sets no max-age or s-maxage.
+	// Responses without either are not cached when it is zero.
+	TTL time.Duration
+	// StaleWhileRevalidate is how long stale responses
+	// may still be served while a fresh one is fetched
+	// in the background, unless their Cache-Control
+	// sets stale-while-revalidate.
+	StaleWhileRevalidate time.Duration
+	// Key returns the base cache key of the request,
+	// its method and URI by default.
+	// The values of the headers listed in Vary are appended to it.
+	Key func(ctx *fasthttp.RequestCtx) string
+}
+
+// revalidate runs background revalidations.
+// Tests replace it to make them synchronous.
+var revalidate = func(fn func()) { go fn() }
+
+// Cache returns a constructor that caches
+// successful responses to GET and HEAD requests in store,
+// serving them without calling the following handlers
+// while they are fresh.
+//
+// Cache-Control is honored: responses marked no-store,
+// private or no-cache, and responses setting cookies, are not cached,
+// nor responses to requests with an Authorization header
+// unless marked public, s-maxage or must-revalidate (RFC 9111, 3.5);
+// max-age, s-maxage and stale-while-revalidate
+// override the policy; requests marked no-cache or max-age=0 skip
+// the cached response and requests marked no-store bypass the cache.
+// Responses are cached per value of the request headers
+// listed in their Vary header, and not at all for "Vary: *".
+//
+// Stale responses within their stale-while-revalidate window
+// are served at once, while a single background request
+// refreshes the entry. Cached responses carry an Age header.
+// Store errors are ignored, the request being served uncached.
+func Cache(store CacheStore, policy CachePolicy) Constructor {
+	if policy.Key == nil {
+		policy.Key = func(ctx *fasthttp.RequestCtx) string {
+			return string(ctx.Method()) + " " + string(ctx.URI().RequestURI())
+		}
+	}
+	var refreshing sync.Map
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		var refresh func(key string, ctx *fasthttp.RequestCtx)
+
+		serve := func(ctx *fasthttp.RequestCtx) {
+			if !ctx.IsGet() && !ctx.IsHead() {
+				next(ctx)
+				return
+			}
+			reqCC := parseCacheControl(ctx.Request.Header.Peek(fasthttp.HeaderCacheControl))
+			if _, ok := reqCC["no-store"]; ok {
+				next(ctx)
+				return
+			}
+
+			base := policy.Key(ctx)
+			_, noCache := reqCC["no-cache"]
+			if !noCache && reqCC["max-age"] != "0" {
+				if key, e := lookupCache(store, base, ctx); e != nil {
+					t := now()
+					if t.Before(e.Expires) {
+						writeCacheEntry(ctx, e, t)
+						return
+					}
+					if t.Before(e.StaleUntil) {
+						writeCacheEntry(ctx, e, t)
+						if _, busy := refreshing.LoadOrStore(key, true); !busy {
+							refresh(key, ctx)
+						}
+						return
+					}
+				}
+			}
+
+			next(ctx)
+			storeCacheEntry(store, policy, base, ctx)
+		}
+
+		refresh = func(key string, ctx *fasthttp.RequestCtx) {
+			bg := &fasthttp.RequestCtx{}
+			bg.Init(&ctx.Request, ctx.RemoteAddr(), nil)
+			base := policy.Key(ctx)
+			revalidate(func() {
+				defer refreshing.Delete(key)
+				next(bg)
+				storeCacheEntry(store, policy, base, bg)
+			})
+		}
+
+		return serve
+	}
+}
+
+// lookupCache returns the entry matching the request, if any,
+// along with the key it is stored under.
+func lookupCache(store CacheStore, base string, ctx *fasthttp.RequestCtx) (string, *CacheEntry) {
+	e, err := store.Get(base)
+	if err != nil || e == nil {
+		return "", nil
+	}
+	if len(e.Vary) == 0 {
+		return base, e
+	}
+	key := variantKey(base, e.Vary, ctx)
+	if e, err = store.Get(key); err != nil || e == nil {
+		return "", nil
+	}
+	return key, e
+}
+
+// variantKey appends the values of the vary request headers to base.
+func variantKey(base string, vary []string, ctx *fasthttp.RequestCtx) string {
+	var b strings.Builder
+	b.WriteString(base)
+	for _, name := range vary {
+		b.WriteByte('\n')
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.Write(ctx.Request.Header.Peek(name))
+	}
+	return b.String()
+}
+
+// storeCacheEntry caches the response of ctx when it is cacheable.
+func storeCacheEntry(store CacheStore, policy CachePolicy, base string, ctx *fasthttp.RequestCtx) {
+	resp := &ctx.Response
+	status := resp.StatusCode()
+	if status < 200 || status > 299 || IsStreaming(ctx) ||
+		len(resp.Header.Peek(fasthttp.HeaderSetCookie)) > 0 {
+		return
+	}
+
+	cc := parseCacheControl(resp.Header.Peek(fasthttp.HeaderCacheControl))
+	for _, directive := range []string{"no-store", "no-cache", "private"} {
+		if _, ok := cc[directive]; ok {
+			return
+		}
+	}
+	if len(ctx.Request.Header.Peek(fasthttp.HeaderAuthorization)) > 0 && !sharedAuthorized(cc) {
+		return
+	}
+	ttl, swr := policy.TTL, policy.StaleWhileRevalidate
+	if v, ok := cc["max-age"]; ok {
+		ttl = cacheSeconds(v)
+	}
+	if v, ok := cc["s-maxage"]; ok {
+		ttl = cacheSeconds(v)
+	}
+	if v, ok := cc["stale-while-revalidate"]; ok {
+		swr = cacheSeconds(v)
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	var vary []string
+	for _, v := range strings.Split(string(resp.Header.Peek(fasthttp.HeaderVary)), ",") {
+		if v = strings.TrimSpace(v); v == "*" {
+			return
+		} else if v != "" {
+			vary = append(vary, textproto.CanonicalMIMEHeaderKey(v))
+		}
+	}
+
+	t := now()
+	e := &CacheEntry{
+		Status:     status,
+		Header:     make(map[string][]string),
+		Body:       append([]byte(nil), resp.Body()...),
+		Stored:     t,
+		Expires:    t.Add(ttl),
+		StaleUntil: t.Add(ttl + swr),
+	}
+	resp.Header.VisitAll(func(k, v []byte) {
+		switch name := string(k); name {
+		case fasthttp.HeaderContentLength, fasthttp.HeaderDate, fasthttp.HeaderConnection, fasthttp.HeaderAge:
+		default:
+			e.Header[name] = append(e.Header[name], string(v))
+		}
+	})
+
+	if len(vary) == 0 {
+		store.Set(base, e)
+		return
+	}
+	index := &CacheEntry{Vary: vary, Stored: t, Expires: e.Expires, StaleUntil: e.StaleUntil}
+	if store.Set(base, index) == nil {
+		store.Set(variantKey(base, vary, ctx), e)
+	}
+}
+
+// sharedAuthorized reports whether the response Cache-Control cc
+// allows caching the response to a request with an Authorization header.
+func sharedAuthorized(cc map[string]string) bool {
+	for _, directive := range []string{"public", "s-maxage", "must-revalidate"} {
+		if _, ok := cc[directive]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// writeCacheEntry answers the request with e.
+func writeCacheEntry(ctx *fasthttp.RequestCtx, e *CacheEntry, t time.Time) {
+	ctx.SetStatusCode(e.Status)
+	for name, values := range e.Header {
+		for i, v := range values {
+			if i == 0 {
+				ctx.Response.Header.Set(name, v)
+			} else {
+				ctx.Response.Header.Add(name, v)
+			}
+		}
+	}
+	ctx.Response.Header.Set(fasthttp.HeaderAge, strconv.FormatInt(int64(t.Sub(e.Stored)/time.Second), 10))
+	ctx.SetBody(e.Body)
+}
+
+// parseCacheControl returns the directives of a Cache-Control header,
+// lowercased, with their unquoted values.
+func parseCacheControl(h []byte) map[string]string {
+	directives := make(map[string]string)
+	for _, d := range strings.Split(string(h), ",") {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+		name, value := d, ""
+		if i := strings.IndexByte(d, '='); i >= 0 {
+			name, value = d[:i], strings.Trim(d[i+1:], `"`)
+		}
+		directives[strings.ToLower(name)] = value
+	}
+	return directives
+}
+
+// cacheSeconds parses a delta-seconds value, zero when invalid.
+func cacheSeconds(v string) time.Duration {
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Second
+}
+
+// memoryCacheStore is an in-memory LRU CacheStore.
+type memoryCacheStore struct {
+	maxEntries int
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+// memoryCacheItem is an element of memoryCacheStore.lru.
+type memoryCacheItem struct {
+	key   string
+	entry *CacheEntry
+}
+
+// NewMemoryCacheStore returns an in-memory CacheStore
+// keeping at most maxEntries entries,
+// evicting the least recently used ones first.
+func NewMemoryCacheStore(maxEntries int) CacheStore {
+	return &memoryCacheStore{
+		maxEntries: maxEntries,
+		lru:        list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+func (s *memoryCacheStore) Get(key string) (*CacheEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	item := el.Value.(*memoryCacheItem)
+	if !now().Before(item.entry.StaleUntil) {
+		s.lru.Remove(el)
+		delete(s.entries, key)
+		return nil, nil
+	}
+	s.lru.MoveToFront(el)
+	return item.entry, nil
+}
+
+func (s *memoryCacheStore) Set(key string, e *CacheEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[key]; ok {
+		el.Value.(*memoryCacheItem).entry = e
+		s.lru.MoveToFront(el)
+		return nil
+	}
+	s.entries[key] = s.lru.PushFront(&memoryCacheItem{key, e})
+	for s.lru.Len() > s.maxEntries {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		delete(s.entries, oldest.Value.(*memoryCacheItem).key)
+	}
+	return nil
+}
+
+func (s *memoryCacheStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[key]; ok {
+		s.lru.Remove(el)
+		delete(s.entries, key)
+	}
+	return nil
+}
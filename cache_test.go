@@ -0,0 +1,189 @@
+package fastalice
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// countingApp returns a handler writing how many times it was called.
+func countingApp(calls *int) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		*calls++
+		ctx.SetContentType("text/plain")
+		fmt.Fprintf(ctx, "call %d", *calls)
+	}
+}
+
+func TestCacheHit(t *testing.T) {
+	calls := 0
+	h := New(Cache(NewMemoryCacheStore(10), CachePolicy{TTL: time.Minute})).Then(countingApp(&calls))
+
+	for i := 0; i < 2; i++ {
+		ctx := newTestCtx("GET", "http://localhost/items")
+		h(ctx)
+		assert.Equal(t, "call 1", string(ctx.Response.Body()), "Cached responses should be served")
+		assert.Equal(t, "text/plain", string(ctx.Response.Header.ContentType()), "Cached responses should keep their content type")
+	}
+	assert.Equal(t, 1, calls, "A cache hit should not reach the handler")
+}
+
+func TestCacheExpiry(t *testing.T) {
+	defer fakeClock(time.Minute)()
+
+	calls := 0
+	h := New(Cache(NewMemoryCacheStore(10), CachePolicy{TTL: 30 * time.Second})).Then(countingApp(&calls))
+
+	ctx := newTestCtx("GET", "http://localhost/items")
+	h(ctx)
+	ctx = newTestCtx("GET", "http://localhost/items")
+	h(ctx)
+	assert.Equal(t, "call 2", string(ctx.Response.Body()), "Expired entries should not be served")
+	assert.Equal(t, 2, calls, "Expired entries should reach the handler")
+}
+
+func TestCacheBypassesOtherMethods(t *testing.T) {
+	calls := 0
+	h := New(Cache(NewMemoryCacheStore(10), CachePolicy{TTL: time.Minute})).Then(countingApp(&calls))
+
+	h(newTestCtx("POST", "http://localhost/items"))
+	h(newTestCtx("POST", "http://localhost/items"))
+	assert.Equal(t, 2, calls, "Non-GET requests should bypass the cache")
+}
+
+func TestCacheEviction(t *testing.T) {
+	calls := 0
+	h := New(Cache(NewMemoryCacheStore(1), CachePolicy{TTL: time.Minute})).Then(countingApp(&calls))
+
+	h(newTestCtx("GET", "http://localhost/a"))
+	h(newTestCtx("GET", "http://localhost/b"))
+	h(newTestCtx("GET", "http://localhost/a"))
+	assert.Equal(t, 3, calls, "Evicted entries should reach the handler again")
+}
+
+func TestCacheControl(t *testing.T) {
+	defer fakeClock(time.Second)()
+
+	calls := 0
+	cacheControl := ""
+	h := New(Cache(NewMemoryCacheStore(10), CachePolicy{})).Then(func(ctx *fasthttp.RequestCtx) {
+		ctx.Response.Header.Set(fasthttp.HeaderCacheControl, cacheControl)
+		countingApp(&calls)(ctx)
+	})
+	serve := func(uri, reqCacheControl string) string {
+		ctx := newTestCtx("GET", uri)
+		if reqCacheControl != "" {
+			ctx.Request.Header.Set(fasthttp.HeaderCacheControl, reqCacheControl)
+		}
+		h(ctx)
+		return string(ctx.Response.Body())
+	}
+
+	serve("http://localhost/a", "")
+	assert.Equal(t, "call 2", serve("http://localhost/a", ""), "Responses without a TTL should not be cached")
+
+	cacheControl = "no-store, max-age=60"
+	serve("http://localhost/b", "")
+	assert.Equal(t, "call 4", serve("http://localhost/b", ""), "no-store responses should not be cached")
+
+	cacheControl = "public, max-age=60"
+	serve("http://localhost/c", "")
+	assert.Equal(t, "call 5", serve("http://localhost/c", ""), "max-age should make responses cacheable")
+	assert.Equal(t, "call 6", serve("http://localhost/c", "no-cache"), "no-cache requests should skip the cached response")
+	assert.Equal(t, "call 6", serve("http://localhost/c", ""), "no-cache requests should refresh the cached response")
+}
+
+func TestCacheAuthorization(t *testing.T) {
+	for _, tc := range []struct {
+		cacheControl string
+		cached       bool
+	}{
+		{"max-age=60", false},
+		{"public, max-age=60", true},
+		{"s-maxage=60", true},
+		{"max-age=60, must-revalidate", true},
+	} {
+		calls := 0
+		h := New(Cache(NewMemoryCacheStore(10), CachePolicy{})).Then(func(ctx *fasthttp.RequestCtx) {
+			ctx.Response.Header.Set(fasthttp.HeaderCacheControl, tc.cacheControl)
+			countingApp(&calls)(ctx)
+		})
+
+		for _, user := range []string{"Bearer alice", "Bearer bob"} {
+			ctx := newTestCtx("GET", "http://localhost/me")
+			ctx.Request.Header.Set(fasthttp.HeaderAuthorization, user)
+			h(ctx)
+		}
+		if tc.cached {
+			assert.Equal(t, 1, calls, "Authorized responses marked %q should be cached", tc.cacheControl)
+		} else {
+			assert.Equal(t, 2, calls, "Authorized responses marked %q should not be cached", tc.cacheControl)
+		}
+	}
+}
+
+func TestCacheAge(t *testing.T) {
+	defer fakeClock(time.Second)()
+
+	calls := 0
+	h := New(Cache(NewMemoryCacheStore(10), CachePolicy{TTL: time.Minute})).Then(countingApp(&calls))
+
+	h(newTestCtx("GET", "http://localhost/items"))
+	ctx := newTestCtx("GET", "http://localhost/items")
+	h(ctx)
+	assert.Equal(t, "2", string(ctx.Response.Header.Peek(fasthttp.HeaderAge)), "Cached responses should carry their age")
+}
+
+func TestCacheVary(t *testing.T) {
+	calls := 0
+	h := New(Cache(NewMemoryCacheStore(10), CachePolicy{TTL: time.Minute})).Then(func(ctx *fasthttp.RequestCtx) {
+		ctx.Response.Header.Set(fasthttp.HeaderVary, "accept-language")
+		countingApp(&calls)(ctx)
+	})
+	serve := func(lang string) string {
+		ctx := newTestCtx("GET", "http://localhost/items")
+		ctx.Request.Header.Set(fasthttp.HeaderAcceptLanguage, lang)
+		h(ctx)
+		return string(ctx.Response.Body())
+	}
+
+	assert.Equal(t, "call 1", serve("en"), "The first variant should be served")
+	assert.Equal(t, "call 2", serve("fr"), "Other variants should not get the first one")
+	assert.Equal(t, "call 1", serve("en"), "Each variant should be cached")
+	assert.Equal(t, "call 2", serve("fr"), "Each variant should be cached")
+}
+
+func TestCacheStaleWhileRevalidate(t *testing.T) {
+	defer fakeClock(time.Minute)()
+	var pending []func()
+	revalidate = func(fn func()) { pending = append(pending, fn) }
+	defer func() { revalidate = func(fn func()) { go fn() } }()
+
+	calls := 0
+	h := New(Cache(NewMemoryCacheStore(10), CachePolicy{TTL: 90 * time.Second, StaleWhileRevalidate: 5 * time.Minute})).Then(countingApp(&calls))
+	serve := func() string {
+		ctx := newTestCtx("GET", "http://localhost/items")
+		h(ctx)
+		return string(ctx.Response.Body())
+	}
+
+	serve()
+	serve()
+	assert.Equal(t, "call 1", serve(), "Stale responses should be served while revalidating")
+	assert.Len(t, pending, 1, "A single revalidation should run at a time")
+
+	pending[0]()
+	assert.Equal(t, "call 2", serve(), "Revalidated responses should be served")
+}
+
+func TestMemoryCacheStoreDelete(t *testing.T) {
+	store := NewMemoryCacheStore(10)
+	store.Set("k", &CacheEntry{StaleUntil: time.Now().Add(time.Minute)})
+	store.Delete("k")
+	e, err := store.Get("k")
+	assert.NoError(t, err)
+	assert.Nil(t, e, "Deleted entries should be gone")
+}
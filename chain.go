@@ -2,6 +2,10 @@
 package fastalice
 
 import (
+	"fmt"
+	"reflect"
+	"sync/atomic"
+
 	"github.com/valyala/fasthttp"
 )
 
@@ -20,20 +24,138 @@ var DefaultFastHTTPMux = fasthttp.RequestHandler(func(ctx *fasthttp.RequestCtx)
 	ctx.WriteString(Default404Message)
 })
 
+// defaultHandler holds the handler set by SetDefaultHandler.
+var defaultHandler atomic.Value
+
+// handlerHolder wraps a handler so that it can be stored
+// in an atomic.Value.
+type handlerHolder struct {
+	h fasthttp.RequestHandler
+}
+
+// SetDefaultHandler sets the handler used by Then()
+// when it is given a nil handler, for example to serve a JSON 404.
+// Passing nil restores DefaultFastHTTPMux.
+//
+// It is safe to call SetDefaultHandler while serving requests;
+// handlers already returned by Then() pick up the change.
+func SetDefaultHandler(h fasthttp.RequestHandler) {
+	defaultHandler.Store(handlerHolder{h})
+}
+
+// serveDefault serves the request with the handler
+// set by SetDefaultHandler, or DefaultFastHTTPMux if unset.
+func serveDefault(ctx *fasthttp.RequestCtx) {
+	if holder, ok := defaultHandler.Load().(handlerHolder); ok && holder.h != nil {
+		holder.h(ctx)
+		return
+	}
+	DefaultFastHTTPMux(ctx)
+}
+
 // Chain acts as a list of fasthttp.RequestHandler constructors.
 // Chain is effectively immutable:
 // once created, it will always hold
 // the same set of constructors in the same order.
 type Chain struct {
 	constructors []Constructor
+	// meta describes each constructor,
+	// or is nil when none of them carries a description.
+	meta []constructorMeta
+}
+
+// constructorMeta describes a constructor of a chain.
+type constructorMeta struct {
+	name  string
+	group string
+	// stage is the stage the constructor was built from, if any.
+	stage Stage
+	// when is set for constructors added by When.
+	when *conditionMeta
+}
+
+// conditionMeta describes a constructor added by When.
+type conditionMeta struct {
+	predicate func(ctx *fasthttp.RequestCtx) bool
+	chain     Chain
 }
 
 // New creates a new chain,
 // memorizing the given list of middleware constructors.
 // New serves no other function,
 // constructors are only called upon a call to Then().
+//
+// Nil constructors are dropped,
+// so a nil slot never reaches Then().
 func New(constructors ...Constructor) Chain {
-	return Chain{append(([]Constructor)(nil), constructors...)}
+	return join(Chain{constructors: constructors})
+}
+
+// NewFrom creates a new chain from a slice of constructors,
+// such as one assembled from configuration.
+// The slice is copied, so later changes to it
+// do not affect the chain.
+// As with New, nil constructors are dropped.
+func NewFrom(constructors []Constructor) Chain {
+	return join(Chain{constructors: constructors})
+}
+
+// join returns a new chain holding the non-nil constructors
+// of all the given chains in order, along with their descriptions.
+// The result never shares storage with its inputs.
+func join(chains ...Chain) Chain {
+	size, described := 0, false
+	for _, chain := range chains {
+		size += len(chain.constructors)
+		described = described || chain.meta != nil
+	}
+
+	out := Chain{constructors: make([]Constructor, 0, size)}
+	if described {
+		out.meta = make([]constructorMeta, 0, size)
+	}
+	for _, chain := range chains {
+		for i, cons := range chain.constructors {
+			if cons == nil {
+				continue
+			}
+			out.constructors = append(out.constructors, cons)
+			if described {
+				out.meta = append(out.meta, chain.metaAt(i))
+			}
+		}
+	}
+	return out
+}
+
+// slice returns the part of the chain from index i to j,
+// sharing its storage.
+func (c Chain) slice(i, j int) Chain {
+	s := Chain{constructors: c.constructors[i:j]}
+	if c.meta != nil {
+		s.meta = c.meta[i:j]
+	}
+	return s
+}
+
+// metaAt returns the description of the constructor at index i.
+func (c Chain) metaAt(i int) constructorMeta {
+	if i < len(c.meta) {
+		return c.meta[i]
+	}
+	return constructorMeta{}
+}
+
+// nameAt returns the name of the constructor at index i,
+// or an empty string if it is unnamed.
+func (c Chain) nameAt(i int) string {
+	return c.metaAt(i).name
+}
+
+// groupAt returns the group of the constructor at index i,
+// or an empty string if it belongs to none.
+func (c Chain) groupAt(i int) string {
+	return c.metaAt(i).group
 }
 
 // Then chains the middleware and returns the final fasthttp.RequestHandler.
@@ -53,10 +175,11 @@ func New(constructors ...Constructor) Chain {
 // when a chain is reused in this way.
 // For proper middleware, this should cause no problems.
 //
-// Then() addesses nil by returning the defaultFastHTTPMux
+// Then() addesses nil by returning the handler set by SetDefaultHandler(),
+// or the DefaultFastHTTPMux when none is set.
 func (c Chain) Then(h fasthttp.RequestHandler) fasthttp.RequestHandler {
 	if h == nil {
-		return DefaultFastHTTPMux
+		return serveDefault
 	}
 
 	for i := range c.constructors {
@@ -66,21 +189,74 @@ func (c Chain) Then(h fasthttp.RequestHandler) fasthttp.RequestHandler {
 	return h
 }
 
+// ThenFunc works identically to Then, but takes
+// a function literal instead of a fasthttp.RequestHandler,
+// mirroring alice.
+//     New(m1, m2).ThenFunc(func(ctx *fasthttp.RequestCtx) { ... })
+// A nil function is addressed like a nil handler in Then().
+func (c Chain) ThenFunc(fn func(ctx *fasthttp.RequestCtx)) fasthttp.RequestHandler {
+	if fn == nil {
+		return c.Then(nil)
+	}
+	return c.Then(fasthttp.RequestHandler(fn))
+}
+
 // Append extends a chain, adding the specified constructors
 // as the last ones in the request flow.
 //
 // Append returns a new chain, leaving the original one untouched.
+// Nil constructors are dropped.
 //
 //     stdChain := alice.New(m1, m2)
 //     extChain := stdChain.Append(m3, m4)
 //     // requests in stdChain go m1 -> m2
 //     // requests in extChain go m1 -> m2 -> m3 -> m4
 func (c Chain) Append(constructors ...Constructor) Chain {
-	newCons := make([]Constructor, 0, len(c.constructors)+len(constructors))
-	newCons = append(newCons, c.constructors...)
-	newCons = append(newCons, constructors...)
+	return join(c, Chain{constructors: constructors})
+}
 
-	return Chain{newCons}
+// Prepend extends a chain, adding the specified constructors
+// as the first ones in the request flow.
+//
+// Prepend returns a new chain, leaving the original one untouched.
+// Nil constructors are dropped.
+//
+//     vendorChain := alice.New(m1, m2)
+//     ownChain := vendorChain.Prepend(requestID)
+//     // requests in ownChain go requestID -> m1 -> m2
+func (c Chain) Prepend(constructors ...Constructor) Chain {
+	return join(Chain{constructors: constructors}, c)
+}
+
+// InsertBefore returns a new chain with the specified constructors
+// inserted before the constructor at index,
+// leaving the original one untouched.
+// An index equal to the length of the chain inserts them last.
+// Nil constructors are dropped.
+//
+//     stdChain := alice.New(m1, m2)
+//     tracedChain := stdChain.InsertBefore(1, tracing)
+//     // requests in tracedChain go m1 -> tracing -> m2
+//
+// InsertBefore panics if index is out of range.
+func (c Chain) InsertBefore(index int, constructors ...Constructor) Chain {
+	if index < 0 || index > len(c.constructors) {
+		panic(fmt.Sprintf("fastalice: insert index %d out of range [0, %d]", index, len(c.constructors)))
+	}
+	return join(c.slice(0, index), Chain{constructors: constructors}, c.slice(index, len(c.constructors)))
+}
+
+// InsertAfter returns a new chain with the specified constructors
+// inserted after the constructor at index,
+// leaving the original one untouched.
+// Nil constructors are dropped.
+//
+// InsertAfter panics if index is out of range.
+func (c Chain) InsertAfter(index int, constructors ...Constructor) Chain {
+	if index < 0 || index >= len(c.constructors) {
+		panic(fmt.Sprintf("fastalice: insert index %d out of range [0, %d)", index, len(c.constructors)))
+	}
+	return c.InsertBefore(index+1, constructors...)
 }
 
 // Extend extends a chain by adding the specified chain
@@ -105,5 +281,226 @@ func (c Chain) Append(constructors ...Constructor) Chain {
 //		// requests to aHtml hitting nosurfs success handler go m1 -> nosurf -> m2 -> target-handler
 //		// requests to aHtml hitting nosurfs failure handler go m1 -> nosurf -> m2 -> csrfFail
 func (c Chain) Extend(chain Chain) Chain {
-	return c.Append(chain.constructors...)
+	return join(c, chain)
+}
+
+// Merge extends a chain by adding each of the specified chains,
+// in order, as the last ones in the request flow.
+// It is equivalent to calling Extend repeatedly.
+//
+// Merge returns a new chain, leaving the original ones untouched.
+//
+//     stdChain := alice.New(m1, m2)
+//     authChain := alice.New(m3)
+//     logChain := alice.New(m4, m5)
+//     fullChain := stdChain.Merge(authChain, logChain)
+//     // requests in fullChain go m1 -> m2 -> m3 -> m4 -> m5
+func (c Chain) Merge(chains ...Chain) Chain {
+	return join(append([]Chain{c}, chains...)...)
+}
+
+// Clone returns a copy of the chain
+// that does not share its storage with the original one.
+func (c Chain) Clone() Chain {
+	return join(c)
+}
+
+// AppendIf is like Append when cond is true.
+// Otherwise it returns a clone of the chain,
+// so the result is always independent from the original one.
+//
+//     chain = chain.AppendIf(cfg.Debug, dumpHandler)
+func (c Chain) AppendIf(cond bool, constructors ...Constructor) Chain {
+	if !cond {
+		return c.Clone()
+	}
+	return c.Append(constructors...)
+}
+
+// ExtendIf is like Extend when cond is true.
+// Otherwise it returns a clone of the chain,
+// so the result is always independent from the original one.
+//
+//     chain = chain.ExtendIf(cfg.Logging, loggingChain)
+func (c Chain) ExtendIf(cond bool, chain Chain) Chain {
+	if !cond {
+		return c.Clone()
+	}
+	return c.Extend(chain)
+}
+
+// Reverse returns a new chain holding the constructors
+// of the original one in the opposite order,
+// leaving the original one untouched.
+//
+//     stdChain := alice.New(m1, m2, m3)
+//     revChain := stdChain.Reverse()
+//     // requests in revChain go m3 -> m2 -> m1
+func (c Chain) Reverse() Chain {
+	reversed := join(c)
+	for i, j := 0, len(reversed.constructors)-1; i < j; i, j = i+1, j-1 {
+		reversed.constructors[i], reversed.constructors[j] = reversed.constructors[j], reversed.constructors[i]
+		if reversed.meta != nil {
+			reversed.meta[i], reversed.meta[j] = reversed.meta[j], reversed.meta[i]
+		}
+	}
+
+	return reversed
+}
+
+// Map returns a new chain holding the constructors
+// returned by fn for each constructor of the original one,
+// along with their index and name, leaving the original one untouched.
+// Names and groups are kept; nil results are dropped.
+//
+// It lets instrumentation wrap every middleware of a chain:
+//
+//     timed := chain.Map(func(i int, name string, c alice.Constructor) alice.Constructor {
+//         return timing(name, c)
+//     })
+func (c Chain) Map(fn func(index int, name string, constructor Constructor) Constructor) Chain {
+	mapped := Chain{constructors: make([]Constructor, len(c.constructors))}
+	for i, cons := range c.constructors {
+		mapped.constructors[i] = fn(i, c.nameAt(i), cons)
+	}
+	mapped = join(Chain{constructors: mapped.constructors, meta: c.meta})
+	for i := range mapped.meta {
+		// Mapped constructors no longer run their stage alone.
+		mapped.meta[i].stage = nil
+	}
+	return mapped
+}
+
+// Validate checks that the chain can be safely composed,
+// returning an error naming the index of the first constructor
+// that is nil, that returns a nil handler
+// or that fails to compose, such as a Validated one,
+// or describing the first of the given rules it breaks.
+//
+//     err := chain.Validate(alice.Outermost("recover"), alice.Before("etag", "compress"), alice.NoDuplicates())
+//
+// Validate calls every constructor once,
+// so it should be done at startup, not on the request path.
+func (c Chain) Validate(rules ...OrderRule) error {
+	probe := fasthttp.RequestHandler(func(ctx *fasthttp.RequestCtx) {})
+	for i, cons := range c.constructors {
+		if cons == nil {
+			return fmt.Errorf("fastalice: constructor at index %d is nil", i)
+		}
+		h, err := construct(cons, probe)
+		if err != nil {
+			return fmt.Errorf("fastalice: constructor at index %d failed: %w", i, err)
+		}
+		if h == nil {
+			return fmt.Errorf("fastalice: constructor at index %d returned a nil handler", i)
+		}
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	names := c.Names()
+	for _, rule := range rules {
+		if err := rule(names); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MustThen is like Then,
+// but it validates the chain first
+// and panics with a descriptive message if Validate fails.
+// It is meant for chains built at startup.
+func (c Chain) MustThen(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+	if err := c.Validate(); err != nil {
+		panic(err)
+	}
+	return c.Then(h)
+}
+
+// Equal reports whether both chains hold the same constructors
+// in the same order, comparing them by function identity.
+//
+// Closures are compared by their code, not by what they capture:
+// a constructor returned twice by the same function,
+// such as two tag("x") middleware, compares equal,
+// while behaviorally identical closures defined separately do not.
+func (c Chain) Equal(other Chain) bool {
+	if len(c.constructors) != len(other.constructors) {
+		return false
+	}
+	for i := range c.constructors {
+		if reflect.ValueOf(c.constructors[i]).Pointer() != reflect.ValueOf(other.constructors[i]).Pointer() {
+			return false
+		}
+	}
+	return true
+}
+
+// When extends a chain with middleware
+// that only runs for requests matching predicate.
+// Other requests skip it and go on with the rest of the chain.
+//
+// When returns a new chain, leaving the original one untouched.
+//
+//     isAPI := func(ctx *fasthttp.RequestCtx) bool {
+//         return bytes.HasPrefix(ctx.Path(), []byte("/api/"))
+//     }
+//     chain := alice.New(m1).When(isAPI, auth, rateLimit).Append(m2)
+//     // requests to /api/ go m1 -> auth -> rateLimit -> m2
+//     // other requests go m1 -> m2
+func (c Chain) When(predicate func(ctx *fasthttp.RequestCtx) bool, constructors ...Constructor) Chain {
+	chain := New(constructors...)
+	return join(c, Chain{
+		constructors: []Constructor{conditional(predicate, chain)},
+		meta:         []constructorMeta{{when: &conditionMeta{predicate, chain}}},
+	})
+}
+
+// conditional returns a constructor running the given chain
+// only for requests matching predicate.
+func conditional(predicate func(ctx *fasthttp.RequestCtx) bool, chain Chain) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		wrapped := chain.Then(next)
+
+		return func(ctx *fasthttp.RequestCtx) {
+			if predicate(ctx) {
+				wrapped(ctx)
+				return
+			}
+			next(ctx)
+		}
+	}
+}
+
+// Router is implemented by fasthttp routers,
+// such as fasthttprouter and fasthttp/router,
+// whose Handler method serves the routed request.
+type Router interface {
+	Handler(ctx *fasthttp.RequestCtx)
+}
+
+// ThenRouter chains the middleware with a router
+// and returns the final fasthttp.RequestHandler.
+//     New(m1, m2).ThenRouter(r)
+// is equivalent to:
+//     New(m1, m2).Then(r.Handler)
+// A nil router is addressed like a nil handler in Then().
+func (c Chain) ThenRouter(r Router) fasthttp.RequestHandler {
+	if r == nil {
+		return c.Then(nil)
+	}
+	return c.Then(r.Handler)
+}
+
+// ThenChain nests inner inside the chain
+// and returns the final fasthttp.RequestHandler.
+//     platform.ThenChain(billing, billingHandler)
+// is equivalent to:
+//     platform.Extend(billing).Then(billingHandler)
+// so that a shared chain can wrap several per-service chains
+// without building each combination by hand.
+// A nil handler is addressed like in Then().
+func (c Chain) ThenChain(inner Chain, h fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return c.Extend(inner).Then(h)
 }
@@ -3,13 +3,10 @@ package fastalice
 
 import (
 	"fmt"
-	"io"
-	"io/ioutil"
-	"net"
-	"net/http"
 	"reflect"
 	"testing"
 
+	"github.com/brunvieira/fastalice/alicetest"
 	"github.com/stretchr/testify/assert"
 	"github.com/valyala/fasthttp"
 )
@@ -45,6 +42,18 @@ func testStatusOk(next fasthttp.RequestHandler) fasthttp.RequestHandler {
 	}
 }
 
+// newTestCtx builds a request context for the given method and uri
+// that handlers can be invoked against directly,
+// without binding a real port.
+func newTestCtx(method, uri string) *fasthttp.RequestCtx {
+	var req fasthttp.Request
+	req.Header.SetMethod(method)
+	req.SetRequestURI(uri)
+
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Init(&req, nil, nil)
+	return ctx
+}
 
 func TestNew(t *testing.T) {
 	c1 := func(h fasthttp.RequestHandler) fasthttp.RequestHandler {
@@ -69,15 +78,6 @@ func TestThenWorksWithNoMiddleware(t *testing.T) {
 	}
 }
 
-func startServerOnPort(t *testing.T, port int, h fasthttp.RequestHandler) io.Closer {
-	ln, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
-	if err != nil {
-		t.Fatalf("cannot start tcp server on port %d: %s", port, err)
-	}
-	go fasthttp.Serve(ln, h)
-	return ln
-}
-
 func TestThenOrdersHandlersCorrectly(t *testing.T) {
 	t1 := tagMiddleware("t1\n")
 	t2 := tagMiddleware("t2\n")
@@ -85,20 +85,10 @@ func TestThenOrdersHandlersCorrectly(t *testing.T) {
 
 	chained := New(t1, t2, t3).Then(testApp)
 
-	ln := startServerOnPort(t, 8081, chained)
-	defer ln.Close()
-
-	req, err := http.NewRequest("GET", "http://localhost:8081", nil)
-	assert.Nil(t, err, "Test Then Handler should be able to create a  Request")
-
-	resp, err := http.DefaultClient.Do(req)
-	assert.Nil(t, err, "Sending the request must not return an error")
-	assert.NotNil(t, resp, "Request response must not be nil")
+	resp := alicetest.Record(nil, chained, alicetest.NewRequest("GET", "http://localhost/"))
+	assert.NoError(t, resp.Err, "Sending the request must not return an error")
 	assert.Equal(t, fasthttp.StatusOK, resp.StatusCode, "Test Then Handler Order should return an OK status")
-
-	body, err := ioutil.ReadAll(resp.Body)
-	assert.Nil(t, err, "Reading the body response should not return an error")
-	assert.Equal(t, "t1\nt2\nt3\napp", string(body), "Request response should return the correct middleware output order")
+	assert.Equal(t, "t1\nt2\nt3\napp", string(resp.Body), "Request response should return the correct middleware output order")
 }
 
 func TestAppendAddsHandlersCorrectly(t *testing.T) {
@@ -108,19 +98,10 @@ func TestAppendAddsHandlersCorrectly(t *testing.T) {
 	assert.Equal(t, 4, len(newChain.constructors), "newChain should have 4 constructors")
 	chained := newChain.Then(testApp)
 
-	ln := startServerOnPort(t, 8082, chained)
-	defer ln.Close()
-
-	req, err := http.NewRequest("GET", "http://localhost:8082", nil)
-	assert.Nil(t, err, "Should be able to create a  Request")
-
-	resp, err := http.DefaultClient.Do(req)
-	assert.Nil(t, err, "Sending the request must not return an error")
-	assert.NotNil(t, resp, "Request response must not be nil")
+	resp := alicetest.Record(nil, chained, alicetest.NewRequest("GET", "http://localhost/"))
+	assert.NoError(t, resp.Err, "Sending the request must not return an error")
 	assert.Equal(t, fasthttp.StatusOK, resp.StatusCode, "Request response should return an OK status")
-	body, err := ioutil.ReadAll(resp.Body)
-	assert.Nil(t, err, "Reading the body response should not return an error")
-	assert.Equal(t, "t1\nt2\nt3\nt4\napp", string(body), "Request response should return the correct middleware output order")
+	assert.Equal(t, "t1\nt2\nt3\nt4\napp", string(resp.Body), "Request response should return the correct middleware output order")
 }
 
 func TestAppendRespectsImmutability(t *testing.T) {
@@ -139,19 +120,10 @@ func TestExtendAddsHandlersCorrectly(t *testing.T) {
 
 	chained := newChain.Then(testApp)
 
-	ln := startServerOnPort(t, 8083, chained)
-	defer ln.Close()
-
-	req, err := http.NewRequest("GET", "http://localhost:8083", nil)
-	assert.Nil(t, err, "Should be able to create a  Request")
-
-	resp, err := http.DefaultClient.Do(req)
-	assert.Nil(t, err, "Sending the request must not return an error")
-	assert.NotNil(t, resp, "Request response must not be nil")
+	resp := alicetest.Record(nil, chained, alicetest.NewRequest("GET", "http://localhost/"))
+	assert.NoError(t, resp.Err, "Sending the request must not return an error")
 	assert.Equal(t, fasthttp.StatusOK, resp.StatusCode, "Request response should return an OK status")
-	body, err := ioutil.ReadAll(resp.Body)
-	assert.Nil(t, err, "Reading the body response should not return an error")
-	assert.Equal(t, "t1\nt2\nt3\nt4\napp", string(body), "Request response should return the correct middleware output order")
+	assert.Equal(t, "t1\nt2\nt3\nt4\napp", string(resp.Body), "Request response should return the correct middleware output order")
 }
 
 func TestExtendRespectsImmutability(t *testing.T) {
@@ -163,17 +135,366 @@ func TestExtendRespectsImmutability(t *testing.T) {
 func TestDefaultHandler(t *testing.T) {
 	chained := New().Then(nil)
 
-	ln := startServerOnPort(t, 8084, chained)
-	defer ln.Close()
+	resp := alicetest.Record(nil, chained, alicetest.NewRequest("GET", "http://localhost/"))
+	assert.NoError(t, resp.Err, "Sending the request must not return an error")
+	assert.Equal(t, fasthttp.StatusNotFound, resp.StatusCode, "Request response should return a Not Found status")
+	assert.Equal(t, Default404Message, string(resp.Body), "Request response should return the Default404Message")
+}
+
+func TestMergeAddsHandlersCorrectly(t *testing.T) {
+	chain1 := New(tagMiddleware("t1\n"))
+	chain2 := New(tagMiddleware("t2\n"), tagMiddleware("t3\n"))
+	chain3 := New(tagMiddleware("t4\n"))
+	newChain := chain1.Merge(chain2, chain3)
+	assert.Equal(t, 1, len(chain1.constructors), "chain1 should have 1 constructor")
+	assert.Equal(t, 2, len(chain2.constructors), "chain2 should have 2 constructors")
+	assert.Equal(t, 1, len(chain3.constructors), "chain3 should have 1 constructor")
+	assert.Equal(t, 4, len(newChain.constructors), "newChain should have 4 constructors")
 
-	req, err := http.NewRequest("GET", "http://localhost:8084", nil)
-	assert.Nil(t, err, "Should be able to create a  Request")
+	ctx := newTestCtx("GET", "http://localhost/")
+	newChain.Then(testApp)(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Request response should return an OK status")
+	assert.Equal(t, "t1\nt2\nt3\nt4\napp", string(ctx.Response.Body()), "Request response should return the correct middleware output order")
+}
 
-	resp, err := http.DefaultClient.Do(req)
-	assert.Nil(t, err, "Sending the request must not return an error")
-	assert.NotNil(t, resp, "Request response must not be nil")
-	assert.Equal(t, fasthttp.StatusNotFound, resp.StatusCode, "Request response should return a Not Found status")
-	body, err := ioutil.ReadAll(resp.Body)
-	assert.Nil(t, err, "Reading the body response should not return an error")
-	assert.Equal(t, Default404Message, string(body), "Request response should return the Default404Message")
+func TestMergeRespectsImmutability(t *testing.T) {
+	chain1 := New(tagMiddleware(""))
+	chain2 := New(tagMiddleware(""))
+	newChain := chain1.Merge(chain2)
+	assert.NotEqual(t, &chain1.constructors[0], &newChain.constructors[0], "Merge does not respect immutability")
+	assert.NotEqual(t, &chain2.constructors[0], &newChain.constructors[1], "Merge does not respect immutability")
+}
+
+func TestNilConstructorsAreDropped(t *testing.T) {
+	chain := New(nil, tagMiddleware("t1\n"), nil)
+	assert.Equal(t, 1, len(chain.constructors), "New should drop nil constructors")
+
+	chain = chain.Append(tagMiddleware("t2\n"), nil)
+	assert.Equal(t, 2, len(chain.constructors), "Append should drop nil constructors")
+
+	chain = chain.Extend(New(nil, tagMiddleware("t3\n")))
+	assert.Equal(t, 3, len(chain.constructors), "Extend should drop nil constructors")
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	chain.Then(testApp)(ctx)
+	assert.Equal(t, "t1\nt2\nt3\napp", string(ctx.Response.Body()), "Only the non-nil constructors should run")
+}
+
+// passMiddleware is a constructor for middleware
+// that only calls the following handler.
+func passMiddleware(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		next(ctx)
+	}
+}
+
+func benchmarkConstructors(n int) []Constructor {
+	constructors := make([]Constructor, n)
+	for i := range constructors {
+		constructors[i] = passMiddleware
+	}
+	return constructors
+}
+
+var noopApp = fasthttp.RequestHandler(func(ctx *fasthttp.RequestCtx) {})
+
+func benchmarkNewThen(b *testing.B, n int) {
+	constructors := benchmarkConstructors(n)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		New(constructors...).Then(noopApp)
+	}
+}
+
+func BenchmarkNewThen1(b *testing.B)  { benchmarkNewThen(b, 1) }
+func BenchmarkNewThen5(b *testing.B)  { benchmarkNewThen(b, 5) }
+func BenchmarkNewThen20(b *testing.B) { benchmarkNewThen(b, 20) }
+
+func benchmarkDispatch(b *testing.B, n int) {
+	h := New(benchmarkConstructors(n)...).Then(noopApp)
+	ctx := newTestCtx("GET", "http://localhost/")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h(ctx)
+	}
+}
+
+func BenchmarkDispatch1(b *testing.B)  { benchmarkDispatch(b, 1) }
+func BenchmarkDispatch5(b *testing.B)  { benchmarkDispatch(b, 5) }
+func BenchmarkDispatch20(b *testing.B) { benchmarkDispatch(b, 20) }
+
+func TestCloneRespectsImmutability(t *testing.T) {
+	chain := New(tagMiddleware(""))
+	clone := chain.Clone()
+	assert.Equal(t, 1, len(clone.constructors), "Clone should keep the constructors")
+	assert.NotEqual(t, &chain.constructors[0], &clone.constructors[0], "Clone does not respect immutability")
+}
+
+func TestAppendIf(t *testing.T) {
+	chain := New(tagMiddleware("t1\n"))
+
+	appended := chain.AppendIf(true, tagMiddleware("t2\n"))
+	ctx := newTestCtx("GET", "http://localhost/")
+	appended.Then(testApp)(ctx)
+	assert.Equal(t, "t1\nt2\napp", string(ctx.Response.Body()), "AppendIf should append when cond is true")
+	assert.NotEqual(t, &chain.constructors[0], &appended.constructors[0], "AppendIf does not respect immutability")
+
+	skipped := chain.AppendIf(false, tagMiddleware("t2\n"))
+	ctx = newTestCtx("GET", "http://localhost/")
+	skipped.Then(testApp)(ctx)
+	assert.Equal(t, "t1\napp", string(ctx.Response.Body()), "AppendIf should not append when cond is false")
+	assert.NotEqual(t, &chain.constructors[0], &skipped.constructors[0], "AppendIf does not respect immutability")
+}
+
+func TestExtendIf(t *testing.T) {
+	chain := New(tagMiddleware("t1\n"))
+	other := New(tagMiddleware("t2\n"))
+
+	extended := chain.ExtendIf(true, other)
+	ctx := newTestCtx("GET", "http://localhost/")
+	extended.Then(testApp)(ctx)
+	assert.Equal(t, "t1\nt2\napp", string(ctx.Response.Body()), "ExtendIf should extend when cond is true")
+	assert.NotEqual(t, &chain.constructors[0], &extended.constructors[0], "ExtendIf does not respect immutability")
+
+	skipped := chain.ExtendIf(false, other)
+	ctx = newTestCtx("GET", "http://localhost/")
+	skipped.Then(testApp)(ctx)
+	assert.Equal(t, "t1\napp", string(ctx.Response.Body()), "ExtendIf should not extend when cond is false")
+	assert.NotEqual(t, &chain.constructors[0], &skipped.constructors[0], "ExtendIf does not respect immutability")
+}
+
+func TestReverseOrdersHandlersCorrectly(t *testing.T) {
+	chain := New(tagMiddleware("t1\n"), tagMiddleware("t2\n"), tagMiddleware("t3\n"))
+	reversed := chain.Reverse()
+	assert.NotEqual(t, &chain.constructors[0], &reversed.constructors[2], "Reverse does not respect immutability")
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	reversed.Then(testApp)(ctx)
+	assert.Equal(t, "t3\nt2\nt1\napp", string(ctx.Response.Body()), "Request response should return the reversed middleware output order")
+
+	ctx = newTestCtx("GET", "http://localhost/")
+	chain.Then(testApp)(ctx)
+	assert.Equal(t, "t1\nt2\nt3\napp", string(ctx.Response.Body()), "The original chain should keep its order")
+}
+
+func TestValidateNilConstructor(t *testing.T) {
+	chain := Chain{constructors: []Constructor{tagMiddleware(""), nil}}
+	assert.EqualError(t, chain.Validate(), "fastalice: constructor at index 1 is nil", "Validate should name the nil constructor")
+	assert.Panics(t, func() { chain.MustThen(testApp) }, "MustThen should panic on an invalid chain")
+}
+
+func TestValidateNilHandler(t *testing.T) {
+	nilHandler := func(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return nil
+	}
+
+	chain := New(nilHandler)
+	assert.EqualError(t, chain.Validate(), "fastalice: constructor at index 0 returned a nil handler", "Validate should name the misbehaving constructor")
+}
+
+func TestValidateValidChain(t *testing.T) {
+	chain := New(tagMiddleware("t1\n"), testStatusOk)
+	assert.Nil(t, chain.Validate(), "Validate should accept a valid chain")
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(tagMiddleware("t1\n")).MustThen(testApp)(ctx)
+	assert.Equal(t, "t1\napp", string(ctx.Response.Body()), "MustThen should compose a valid chain")
+}
+
+func TestNewFromCopiesSlice(t *testing.T) {
+	slice := []Constructor{tagMiddleware("t1\n"), tagMiddleware("t2\n")}
+	chain := NewFrom(slice)
+
+	slice[0] = tagMiddleware("changed\n")
+	slice = append(slice[:1], tagMiddleware("t3\n"))
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	chain.Then(testApp)(ctx)
+	assert.Equal(t, "t1\nt2\napp", string(ctx.Response.Body()), "Changes to the original slice should not affect the chain")
+}
+
+func TestSetDefaultHandler(t *testing.T) {
+	defer SetDefaultHandler(nil)
+	chained := New().Then(nil)
+
+	SetDefaultHandler(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		ctx.SetContentType("application/json")
+		ctx.WriteString(`{"error":"not found"}`)
+	})
+	ctx := newTestCtx("GET", "http://localhost/")
+	chained(ctx)
+	assert.Equal(t, fasthttp.StatusNotFound, ctx.Response.StatusCode(), "The default handler should be used")
+	assert.Equal(t, `{"error":"not found"}`, string(ctx.Response.Body()), "The default handler should be used")
+
+	SetDefaultHandler(nil)
+	ctx = newTestCtx("GET", "http://localhost/")
+	chained(ctx)
+	assert.Equal(t, Default404Message, string(ctx.Response.Body()), "Resetting the default handler should restore the Default404Message")
+
+	assert.True(t, funcsEqual(New().Then(testApp), testApp), "Then should still return the app directly")
+}
+
+func TestEqual(t *testing.T) {
+	chain := New(testStatusOk, passMiddleware)
+	assert.True(t, chain.Equal(chain.Clone()), "A chain should equal its clone")
+	assert.True(t, chain.Equal(New(testStatusOk, passMiddleware)), "Chains of the same constructors should be equal")
+	assert.False(t, chain.Equal(New(passMiddleware, testStatusOk)), "Reordered chains should not be equal")
+	assert.False(t, chain.Equal(New(testStatusOk)), "Chains of different lengths should not be equal")
+	assert.False(t, chain.Equal(New(testStatusOk, RequestID())), "Chains of different constructors should not be equal")
+}
+
+func TestWhenRunsMiddlewareConditionally(t *testing.T) {
+	isAdmin := func(ctx *fasthttp.RequestCtx) bool {
+		return string(ctx.Path()) == "/admin"
+	}
+	chain := New(tagMiddleware("t1\n")).When(isAdmin, tagMiddleware("auth\n"), tagMiddleware("audit\n")).Append(tagMiddleware("t2\n"))
+
+	ctx := newTestCtx("GET", "http://localhost/admin")
+	chain.Then(testApp)(ctx)
+	assert.Equal(t, "t1\nauth\naudit\nt2\napp", string(ctx.Response.Body()), "Matching requests should run the conditional middleware")
+
+	ctx = newTestCtx("GET", "http://localhost/health")
+	chain.Then(testApp)(ctx)
+	assert.Equal(t, "t1\nt2\napp", string(ctx.Response.Body()), "Other requests should skip the conditional middleware")
+}
+
+// testRouter is a minimal Router
+// matching paths of the form /users/:id.
+type testRouter struct {
+	users fasthttp.RequestHandler
+}
+
+func (r *testRouter) Handler(ctx *fasthttp.RequestCtx) {
+	path := string(ctx.Path())
+	const prefix = "/users/"
+	if len(path) <= len(prefix) || path[:len(prefix)] != prefix {
+		ctx.NotFound()
+		return
+	}
+	ctx.SetUserValue("id", path[len(prefix):])
+	r.users(ctx)
+}
+
+func TestThenRouterKeepsRouteParams(t *testing.T) {
+	router := &testRouter{users: func(ctx *fasthttp.RequestCtx) {
+		fmt.Fprintf(ctx, "user %s", ctx.UserValue("id"))
+	}}
+	h := New(tagMiddleware("t1\n"), tagMiddleware("t2\n")).ThenRouter(router)
+
+	ctx := newTestCtx("GET", "http://localhost/users/42")
+	h(ctx)
+	assert.Equal(t, "t1\nt2\nuser 42", string(ctx.Response.Body()), "Route params should survive the chain")
+
+	ctx = newTestCtx("GET", "http://localhost/other")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusNotFound, ctx.Response.StatusCode(), "Unrouted requests should be handled by the router")
+}
+
+func TestThenRouterNil(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	New().ThenRouter(nil)(ctx)
+	assert.Equal(t, fasthttp.StatusNotFound, ctx.Response.StatusCode(), "A nil router should fall back to the default handler")
+}
+
+func TestInsertBefore(t *testing.T) {
+	chain := New(tagMiddleware("t1\n"), tagMiddleware("t3\n"))
+	inserted := chain.InsertBefore(1, tagMiddleware("t2\n"))
+	assert.NotEqual(t, &chain.constructors[0], &inserted.constructors[0], "InsertBefore does not respect immutability")
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	inserted.Then(testApp)(ctx)
+	assert.Equal(t, "t1\nt2\nt3\napp", string(ctx.Response.Body()), "InsertBefore should insert before the index")
+
+	ctx = newTestCtx("GET", "http://localhost/")
+	chain.InsertBefore(2, tagMiddleware("t4\n")).Then(testApp)(ctx)
+	assert.Equal(t, "t1\nt3\nt4\napp", string(ctx.Response.Body()), "InsertBefore the length should insert last")
+
+	ctx = newTestCtx("GET", "http://localhost/")
+	chain.Then(testApp)(ctx)
+	assert.Equal(t, "t1\nt3\napp", string(ctx.Response.Body()), "The original chain should be untouched")
+
+	assert.Panics(t, func() { chain.InsertBefore(3, tagMiddleware("")) }, "InsertBefore should panic out of range")
+}
+
+func TestInsertAfter(t *testing.T) {
+	chain := NewNamed(
+		NamedConstructor{"t1", tagMiddleware("t1\n")},
+		NamedConstructor{"t3", tagMiddleware("t3\n")},
+	)
+	inserted := chain.InsertAfter(0, tagMiddleware("t2\n"))
+	assert.Equal(t, []string{"t1", "", "t3"}, inserted.Names(), "InsertAfter should keep the names")
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	inserted.Then(testApp)(ctx)
+	assert.Equal(t, "t1\nt2\nt3\napp", string(ctx.Response.Body()), "InsertAfter should insert after the index")
+
+	assert.Panics(t, func() { chain.InsertAfter(2, tagMiddleware("")) }, "InsertAfter should panic out of range")
+	assert.Panics(t, func() { New().InsertAfter(0, tagMiddleware("")) }, "InsertAfter should panic on an empty chain")
+}
+
+func TestPrepend(t *testing.T) {
+	chain := New(tagMiddleware("t2\n"))
+	prepended := chain.Prepend(tagMiddleware("t1\n"), nil)
+	assert.NotEqual(t, &chain.constructors[0], &prepended.constructors[1], "Prepend does not respect immutability")
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	prepended.Then(testApp)(ctx)
+	assert.Equal(t, "t1\nt2\napp", string(ctx.Response.Body()), "Prepended middleware should run first")
+
+	ctx = newTestCtx("GET", "http://localhost/")
+	chain.Then(testApp)(ctx)
+	assert.Equal(t, "t2\napp", string(ctx.Response.Body()), "The original chain should be untouched")
+}
+
+func TestMap(t *testing.T) {
+	chain := NewNamed(
+		NamedConstructor{"t1", tagMiddleware("t1\n")},
+		NamedConstructor{"t2", tagMiddleware("t2\n")},
+	)
+	var seen []string
+	mapped := chain.Map(func(i int, name string, c Constructor) Constructor {
+		seen = append(seen, fmt.Sprintf("%d:%s", i, name))
+		return New(tagMiddleware("["+name+"]")).Append(c).Then
+	})
+	assert.Equal(t, []string{"0:t1", "1:t2"}, seen, "Map should see every constructor with its index and name")
+	assert.Equal(t, []string{"t1", "t2"}, mapped.Names(), "Map should keep the names")
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	mapped.Then(testApp)(ctx)
+	assert.Equal(t, "[t1]t1\n[t2]t2\napp", string(ctx.Response.Body()), "Mapped constructors should replace the original ones")
+
+	dropped := chain.Map(func(i int, name string, c Constructor) Constructor {
+		if name == "t1" {
+			return nil
+		}
+		return c
+	})
+	assert.Equal(t, []string{"t2"}, dropped.Names(), "Nil results should be dropped")
+}
+
+func TestThenFunc(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(tagMiddleware("t1\n")).ThenFunc(func(ctx *fasthttp.RequestCtx) {
+		ctx.WriteString("func")
+	})(ctx)
+	assert.Equal(t, "t1\nfunc", string(ctx.Response.Body()), "ThenFunc should chain the function")
+
+	ctx = newTestCtx("GET", "http://localhost/")
+	New().ThenFunc(nil)(ctx)
+	assert.Equal(t, fasthttp.StatusNotFound, ctx.Response.StatusCode(), "A nil function should fall back to the default handler")
+}
+
+func TestThenChain(t *testing.T) {
+	platform := New(tagMiddleware("t1\n"), tagMiddleware("t2\n"))
+	service := New(tagMiddleware("t3\n"))
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	platform.ThenChain(service, testApp)(ctx)
+	assert.Equal(t, "t1\nt2\nt3\napp", string(ctx.Response.Body()), "The inner chain should run inside the outer one")
+	assert.Len(t, platform.constructors, 2, "The outer chain should be left untouched")
 }
@@ -0,0 +1,51 @@
+package fastalice
+
+import (
+	"encoding/json"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultChainsPath is the path ChainsEndpoint serves chains at.
+const DefaultChainsPath = "/_alice/chains"
+
+// chainJSON is the JSON description of a chain.
+type chainJSON struct {
+	Length       int               `json:"length"`
+	Constructors []ConstructorInfo `json:"constructors"`
+}
+
+// MarshalJSON describes the chain as a JSON object
+// holding its length and the constructors returned by Explain,
+// so that dashboards can visualize the middleware of a service.
+func (c Chain) MarshalJSON() ([]byte, error) {
+	return json.Marshal(chainJSON{Length: len(c.constructors), Constructors: c.Explain()})
+}
+
+// ChainsEndpoint returns a constructor answering GET requests
+// to DefaultChainsPath with the JSON description of chains, keyed by name,
+// without calling the following handlers. Other requests pass through.
+//
+// Chain descriptions reveal the internals of a service:
+// guards, such as IPFilter or BasicAuth, run before the endpoint answers.
+//
+//	chain := fastalice.New(fastalice.ChainsEndpoint(map[string]fastalice.Chain{"api": api}, fastalice.IPFilter(internal, nil)))
+func ChainsEndpoint(chains map[string]Chain, guards ...Constructor) Constructor {
+	described := make(map[string]Chain, len(chains))
+	for name, chain := range chains {
+		described[name] = chain
+	}
+	handler := New(guards...).Then(func(ctx *fasthttp.RequestCtx) {
+		JSON(ctx, fasthttp.StatusOK, described)
+	})
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if string(ctx.Path()) != DefaultChainsPath || !(ctx.IsGet() || ctx.IsHead()) {
+				next(ctx)
+				return
+			}
+			handler(ctx)
+		}
+	}
+}
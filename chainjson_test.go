@@ -0,0 +1,53 @@
+package fastalice
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestChainMarshalJSON(t *testing.T) {
+	chain := NewNamed(NamedConstructor{Name: "first", Constructor: tagMiddleware("t1")}).
+		When(isAPI, tagMiddleware("t2"))
+
+	b, err := json.Marshal(chain)
+	assert.NoError(t, err, "The chain should be marshaled")
+	var got struct {
+		Length       int
+		Constructors []ConstructorInfo
+	}
+	assert.NoError(t, json.Unmarshal(b, &got), "The description should be JSON")
+	assert.Equal(t, 2, got.Length, "The length should be described")
+	assert.Equal(t, chain.Explain(), got.Constructors, "The constructors should be described as Explain does")
+}
+
+func TestChainsEndpoint(t *testing.T) {
+	api := NewNamed(NamedConstructor{Name: "auth", Constructor: tagMiddleware("auth")})
+	deny := func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if !ctx.Request.Header.HasAcceptEncoding("allowed") {
+				ctx.SetStatusCode(fasthttp.StatusForbidden)
+				return
+			}
+			next(ctx)
+		}
+	}
+	h := New(ChainsEndpoint(map[string]Chain{"api": api}, deny)).Then(testApp)
+
+	ctx := newTestCtx("GET", "http://localhost"+DefaultChainsPath)
+	ctx.Request.Header.Set(fasthttp.HeaderAcceptEncoding, "allowed")
+	h(ctx)
+	var got map[string]struct{ Constructors []ConstructorInfo }
+	assert.NoError(t, json.Unmarshal(ctx.Response.Body(), &got), "The chains should be served as JSON")
+	assert.Equal(t, "auth", got["api"].Constructors[0].Name, "The chains should be described")
+
+	ctx = newTestCtx("GET", "http://localhost"+DefaultChainsPath)
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusForbidden, ctx.Response.StatusCode(), "Guards should run first")
+
+	ctx = newTestCtx("GET", "http://localhost/users")
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Other requests should pass through")
+}
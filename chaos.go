@@ -0,0 +1,63 @@
+package fastalice
+
+import (
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ChaosLatency returns a constructor that injects latency
+// for resilience testing.
+// While enabled reports true, each request is selected
+// with the given probability (from 0 to 1)
+// and delayed by a random duration between min and max
+// before the following handlers run.
+//
+// enabled is checked on every request,
+// so latency can be switched off at runtime;
+// a nil enabled disables injection entirely.
+// The probability can be tuned at runtime
+// with the SettingProbability setting, see ConfigureWith.
+func ChaosLatency(enabled func() bool, min, max time.Duration, probability float64) Constructor {
+	if max < min {
+		min, max = max, min
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if chaosSelected(ctx, enabled, probability) {
+				sleep(min + time.Duration(randFloat64()*float64(max-min)))
+			}
+			next(ctx)
+		}
+	}
+}
+
+// ChaosFault returns a constructor that injects error responses
+// for resilience testing.
+// While enabled reports true, each request is selected
+// with the given probability (from 0 to 1)
+// and answered with status without calling the following handlers.
+//
+// enabled is checked on every request,
+// so faults can be switched off at runtime;
+// a nil enabled disables injection entirely.
+// The probability can be tuned at runtime
+// with the SettingProbability setting, see ConfigureWith.
+func ChaosFault(enabled func() bool, status int, probability float64) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if chaosSelected(ctx, enabled, probability) {
+				ctx.Error(fasthttp.StatusMessage(status), status)
+				return
+			}
+			next(ctx)
+		}
+	}
+}
+
+// chaosSelected reports whether chaos should be injected
+// into the current request.
+func chaosSelected(ctx *fasthttp.RequestCtx, enabled func() bool, probability float64) bool {
+	return enabled != nil && enabled() && randFloat64() < ConfigValue(ctx, SettingProbability, probability)
+}
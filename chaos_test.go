@@ -0,0 +1,69 @@
+package fastalice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestChaosLatencyInjectsDelay(t *testing.T) {
+	var slept []time.Duration
+	defer fakeSleep(&slept)()
+	defer fakeRand(0.5)()
+
+	enabled := func() bool { return true }
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(ChaosLatency(enabled, time.Second, 3*time.Second, 1)).Then(testApp)(ctx)
+
+	assert.Equal(t, []time.Duration{2 * time.Second}, slept, "A delay between min and max should be injected")
+	assert.Equal(t, "app", string(ctx.Response.Body()), "The request should still reach the app")
+}
+
+func TestChaosLatencyDisabled(t *testing.T) {
+	var slept []time.Duration
+	defer fakeSleep(&slept)()
+	defer fakeRand(0)()
+
+	enabled := func() bool { return false }
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(ChaosLatency(enabled, time.Second, 3*time.Second, 1)).Then(testApp)(ctx)
+	New(ChaosLatency(nil, time.Second, 3*time.Second, 1)).Then(testApp)(ctx)
+
+	assert.Empty(t, slept, "No delay should be injected while disabled")
+}
+
+func TestChaosLatencyUnsampled(t *testing.T) {
+	var slept []time.Duration
+	defer fakeSleep(&slept)()
+	defer fakeRand(0.9)()
+
+	enabled := func() bool { return true }
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(ChaosLatency(enabled, time.Second, 3*time.Second, 0.5)).Then(testApp)(ctx)
+
+	assert.Empty(t, slept, "No delay should be injected into unsampled requests")
+}
+
+func TestChaosFaultInjectsError(t *testing.T) {
+	defer fakeRand(0.1)()
+
+	enabled := func() bool { return true }
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(ChaosFault(enabled, fasthttp.StatusBadGateway, 0.5)).Then(testApp)(ctx)
+
+	assert.Equal(t, fasthttp.StatusBadGateway, ctx.Response.StatusCode(), "The configured fault should be injected")
+	assert.NotEqual(t, "app", string(ctx.Response.Body()), "The app should not be reached")
+}
+
+func TestChaosFaultDisabled(t *testing.T) {
+	defer fakeRand(0)()
+
+	enabled := func() bool { return false }
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(ChaosFault(enabled, fasthttp.StatusBadGateway, 1)).Then(testApp)(ctx)
+
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "No fault should be injected while disabled")
+	assert.Equal(t, "app", string(ctx.Response.Body()), "The app should be reached")
+}
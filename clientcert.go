@@ -0,0 +1,202 @@
+package fastalice
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// clientCertKey holds the client certificate found by ClientCert.
+var clientCertKey = NewKey[*x509.Certificate]("clientCert")
+
+// CertOptions configures the ClientCert middleware.
+type CertOptions struct {
+	// Roots, when set, verifies client certificates
+	// the TLS server did not verify, such as with tls.RequestClientCert.
+	// It is required to accept certificates from Header.
+	Roots *x509.CertPool
+	// Header names the request header holding the client certificate
+	// forwarded by a TLS-terminating proxy, PEM encoded,
+	// possibly URL-escaped as with nginx $ssl_client_escaped_cert,
+	// or as base64 DER. It is only read on requests without
+	// a TLS client certificate coming from TrustedProxies.
+	Header string
+	// TrustedProxies lists the IPs and CIDR ranges
+	// of the proxies allowed to forward certificates in Header.
+	TrustedProxies []string
+	// AllowedSANs, when set, lists the DNS names, email addresses,
+	// URIs and IPs of which the certificate must hold at least one.
+	AllowedSANs []string
+	// AllowedOUs, when set, lists the organizational units
+	// of which the certificate subject must hold at least one.
+	AllowedOUs []string
+	// Optional lets requests without a certificate through,
+	// for handlers to check ClientCertificate themselves.
+	Optional bool
+}
+
+// NewClientCert returns a constructor authenticating requests
+// by their client certificate: the certificate verified by the TLS server,
+// or the one forwarded in opts.Header by a trusted proxy
+// and verified with opts.Roots.
+// The certificate can be read with ClientCertificate.
+//
+// Requests without a valid certificate are answered
+// with 401 Unauthorized, unless opts.Optional is set
+// and they have no certificate at all,
+// and those whose certificate is not in the SAN or OU allowlists
+// with 403 Forbidden, without calling the following handlers.
+//
+//	roots := x509.NewCertPool()
+//	roots.AppendCertsFromPEM(caPEM)
+//	fastalice.ClientCert(fastalice.CertOptions{
+//		Roots:       roots,
+//		AllowedSANs: []string{"spiffe://example.org/billing"},
+//	})
+//
+// An error is returned if any of the proxies is malformed,
+// or if opts.Header is set without opts.Roots.
+func NewClientCert(opts CertOptions) (Constructor, error) {
+	trusted, err := parseIPList(opts.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Header != "" && opts.Roots == nil {
+		return nil, errors.New("fastalice: client certificates from a header need Roots to be verified")
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			cert, err := clientCert(ctx, opts, trusted)
+			switch {
+			case err != nil:
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusUnauthorized), fasthttp.StatusUnauthorized)
+				return
+			case cert == nil:
+				if !opts.Optional {
+					ctx.Error(fasthttp.StatusMessage(fasthttp.StatusUnauthorized), fasthttp.StatusUnauthorized)
+					return
+				}
+			case !certAllowed(cert, opts):
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusForbidden), fasthttp.StatusForbidden)
+				return
+			default:
+				Set(ctx, clientCertKey, cert)
+			}
+			next(ctx)
+		}
+	}, nil
+}
+
+// ClientCert is like NewClientCert but panics
+// if any of the proxies is malformed
+// or if opts.Header is set without opts.Roots.
+func ClientCert(opts CertOptions) Constructor {
+	c, err := NewClientCert(opts)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// ClientCertificate returns the client certificate
+// accepted by ClientCert, or nil if there is none.
+func ClientCertificate(ctx *fasthttp.RequestCtx) *x509.Certificate {
+	cert, _ := Get(ctx, clientCertKey)
+	return cert
+}
+
+// clientCert returns the verified client certificate of the request,
+// nil if it has none, or an error if it cannot be verified.
+func clientCert(ctx *fasthttp.RequestCtx, opts CertOptions, trusted ipList) (*x509.Certificate, error) {
+	if state := ctx.TLSConnectionState(); state != nil && len(state.PeerCertificates) > 0 {
+		if len(state.VerifiedChains) > 0 {
+			return state.PeerCertificates[0], nil
+		}
+		if opts.Roots == nil {
+			return nil, errors.New("fastalice: unverified client certificate")
+		}
+		intermediates := x509.NewCertPool()
+		for _, c := range state.PeerCertificates[1:] {
+			intermediates.AddCert(c)
+		}
+		return verifyClientCert(state.PeerCertificates[0], opts.Roots, intermediates)
+	}
+
+	if opts.Header == "" || !trusted.contains(ctx.RemoteIP()) {
+		return nil, nil
+	}
+	raw := strings.TrimSpace(string(ctx.Request.Header.Peek(opts.Header)))
+	if raw == "" {
+		return nil, nil
+	}
+	cert, err := parseForwardedCert(raw)
+	if err != nil {
+		return nil, err
+	}
+	return verifyClientCert(cert, opts.Roots, nil)
+}
+
+// parseForwardedCert parses a certificate forwarded by a proxy.
+func parseForwardedCert(raw string) (*x509.Certificate, error) {
+	if strings.Contains(raw, "%") {
+		unescaped, err := url.QueryUnescape(raw)
+		if err != nil {
+			return nil, err
+		}
+		raw = unescaped
+	}
+	if block, _ := pem.Decode([]byte(raw)); block != nil {
+		return x509.ParseCertificate(block.Bytes)
+	}
+	der, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, errors.New("fastalice: malformed client certificate")
+	}
+	return x509.ParseCertificate(der)
+}
+
+func verifyClientCert(cert *x509.Certificate, roots, intermediates *x509.CertPool) (*x509.Certificate, error) {
+	_, err := cert.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// certAllowed reports whether cert passes the SAN and OU allowlists.
+func certAllowed(cert *x509.Certificate, opts CertOptions) bool {
+	if len(opts.AllowedSANs) > 0 {
+		sans := append(append([]string{}, cert.DNSNames...), cert.EmailAddresses...)
+		for _, u := range cert.URIs {
+			sans = append(sans, u.String())
+		}
+		for _, ip := range cert.IPAddresses {
+			sans = append(sans, ip.String())
+		}
+		if !anyString(sans, opts.AllowedSANs) {
+			return false
+		}
+	}
+	return len(opts.AllowedOUs) == 0 || anyString(cert.Subject.OrganizationalUnit, opts.AllowedOUs)
+}
+
+// anyString reports whether any of values is in allowed.
+func anyString(values, allowed []string) bool {
+	for _, v := range values {
+		if containsString(allowed, v) {
+			return true
+		}
+	}
+	return false
+}
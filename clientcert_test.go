@@ -0,0 +1,165 @@
+package fastalice
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+// testPKI is a CA issuing test certificates.
+type testPKI struct {
+	t     *testing.T
+	cert  *x509.Certificate
+	key   *ecdsa.PrivateKey
+	roots *x509.CertPool
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.NoError(t, err, "The CA should be created")
+	cert, _ := x509.ParseCertificate(der)
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	return &testPKI{t: t, cert: cert, key: key, roots: roots}
+}
+
+// issue returns a certificate for tmpl and its key.
+func (p *testPKI) issue(tmpl *x509.Certificate, usage x509.ExtKeyUsage) tls.Certificate {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl.SerialNumber = big.NewInt(time.Now().UnixNano())
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	tmpl.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, p.cert, &key.PublicKey, p.key)
+	assert.NoError(p.t, err, "The certificate should be issued")
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func clientTemplate(cn, ou string, uri string) *x509.Certificate {
+	u, _ := url.Parse(uri)
+	return &x509.Certificate{
+		Subject: pkix.Name{CommonName: cn, OrganizationalUnit: []string{ou}},
+		URIs:    []*url.URL{u},
+	}
+}
+
+func certPEM(c tls.Certificate) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Certificate[0]}))
+}
+
+func TestClientCertTLS(t *testing.T) {
+	pki := newTestPKI(t)
+	server := pki.issue(&x509.Certificate{DNSNames: []string{"localhost"}}, x509.ExtKeyUsageServerAuth)
+
+	var seen string
+	h := New(ClientCert(CertOptions{AllowedOUs: []string{"billing"}})).Then(func(ctx *fasthttp.RequestCtx) {
+		seen = ClientCertificate(ctx).Subject.CommonName
+	})
+
+	ln := fasthttputil.NewInmemoryListener()
+	defer ln.Close()
+	go fasthttp.Serve(tls.NewListener(ln, &tls.Config{
+		Certificates: []tls.Certificate{server},
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		ClientCAs:    pki.roots,
+	}), h)
+
+	get := func(cert *tls.Certificate) int {
+		cfg := &tls.Config{RootCAs: pki.roots, ServerName: "localhost"}
+		if cert != nil {
+			cfg.Certificates = []tls.Certificate{*cert}
+		}
+		client := &fasthttp.Client{Dial: func(addr string) (net.Conn, error) {
+			conn, err := ln.Dial()
+			if err != nil {
+				return nil, err
+			}
+			return tls.Client(conn, cfg), nil
+		}}
+		req := fasthttp.AcquireRequest()
+		defer fasthttp.ReleaseRequest(req)
+		req.SetRequestURI("http://localhost/")
+		resp := &fasthttp.Response{}
+		assert.NoError(t, client.Do(req, resp), "The request should be served")
+		return resp.StatusCode()
+	}
+
+	billing := pki.issue(clientTemplate("svc-billing", "billing", "spiffe://example.org/billing"), x509.ExtKeyUsageClientAuth)
+	assert.Equal(t, fasthttp.StatusOK, get(&billing), "An allowed certificate should be accepted")
+	assert.Equal(t, "svc-billing", seen, "The certificate should be exposed")
+
+	other := pki.issue(clientTemplate("svc-other", "other", "spiffe://example.org/other"), x509.ExtKeyUsageClientAuth)
+	assert.Equal(t, fasthttp.StatusForbidden, get(&other), "A certificate outside of the OU allowlist should be forbidden")
+	assert.Equal(t, fasthttp.StatusUnauthorized, get(nil), "Requests without a certificate should be rejected")
+}
+
+func TestClientCertHeader(t *testing.T) {
+	pki := newTestPKI(t)
+	client := pki.issue(clientTemplate("svc-billing", "billing", "spiffe://example.org/billing"), x509.ExtKeyUsageClientAuth)
+	h := New(ClientCert(CertOptions{
+		Roots:          pki.roots,
+		Header:         "X-Client-Cert",
+		TrustedProxies: []string{"10.0.0.1"},
+		AllowedSANs:    []string{"spiffe://example.org/billing"},
+	})).Then(testApp)
+
+	ctx := newTestCtxFromIP("GET", "http://localhost/", "10.0.0.1")
+	ctx.Request.Header.Set("X-Client-Cert", url.QueryEscape(certPEM(client)))
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "A certificate forwarded by a trusted proxy should be accepted")
+
+	ctx = newTestCtxFromIP("GET", "http://localhost/", "192.0.2.1")
+	ctx.Request.Header.Set("X-Client-Cert", url.QueryEscape(certPEM(client)))
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "Certificates forwarded by untrusted clients should be ignored")
+
+	rogue := newTestPKI(t).issue(clientTemplate("svc-billing", "billing", "spiffe://example.org/billing"), x509.ExtKeyUsageClientAuth)
+	ctx = newTestCtxFromIP("GET", "http://localhost/", "10.0.0.1")
+	ctx.Request.Header.Set("X-Client-Cert", url.QueryEscape(certPEM(rogue)))
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "Forwarded certificates should be verified")
+
+	other := pki.issue(clientTemplate("svc-other", "other", "spiffe://example.org/other"), x509.ExtKeyUsageClientAuth)
+	ctx = newTestCtxFromIP("GET", "http://localhost/", "10.0.0.1")
+	ctx.Request.Header.Set("X-Client-Cert", url.QueryEscape(certPEM(other)))
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusForbidden, ctx.Response.StatusCode(), "A certificate outside of the SAN allowlist should be forbidden")
+}
+
+func TestClientCertOptional(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(ClientCert(CertOptions{Optional: true})).Then(testApp)(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Optional certificates should let requests without one through")
+	assert.Nil(t, ClientCertificate(ctx), "No certificate should be exposed")
+}
+
+func TestNewClientCertErrors(t *testing.T) {
+	_, err := NewClientCert(CertOptions{Header: "X-Client-Cert"})
+	assert.Error(t, err, "Header certificates without roots should be an error")
+
+	_, err = NewClientCert(CertOptions{TrustedProxies: []string{"nope"}})
+	assert.Error(t, err, "Malformed proxies should be an error")
+}
@@ -0,0 +1,164 @@
+package fastalice
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Doer performs an outbound HTTP request, as fasthttp.Client.Do does.
+type Doer func(req *fasthttp.Request, resp *fasthttp.Response) error
+
+// ClientConstructor is a constructor for outbound middleware,
+// wrapping the Doer performing the request.
+type ClientConstructor func(next Doer) Doer
+
+// ClientChain is the outbound counterpart of Chain:
+// a list of ClientConstructor wrapping the calls of a fasthttp client,
+// so that retries, authentication and instrumentation of outbound
+// requests follow the same style as inbound ones.
+// Like Chain, it is immutable.
+//
+//	api := fastalice.NewClientChain(
+//		fastalice.ClientObserve(recordCall),
+//		fastalice.ClientRetry(fastalice.RetryPolicy{}),
+//		fastalice.ClientBearer(tokens.Token),
+//	).Then(client.Do)
+//	err := api(req, resp)
+type ClientChain struct {
+	constructors []ClientConstructor
+}
+
+// NewClientChain creates a new client chain with the given constructors,
+// the first one wrapping the others. Nil constructors are dropped.
+func NewClientChain(constructors ...ClientConstructor) ClientChain {
+	return ClientChain{}.Append(constructors...)
+}
+
+// Append returns a new client chain adding constructors
+// as the last ones before the request is performed.
+func (c ClientChain) Append(constructors ...ClientConstructor) ClientChain {
+	joined := make([]ClientConstructor, 0, len(c.constructors)+len(constructors))
+	joined = append(joined, c.constructors...)
+	for _, constructor := range constructors {
+		if constructor != nil {
+			joined = append(joined, constructor)
+		}
+	}
+	return ClientChain{constructors: joined}
+}
+
+// Extend returns a new client chain adding the constructors of chain
+// as the last ones before the request is performed.
+func (c ClientChain) Extend(chain ClientChain) ClientChain {
+	return c.Append(chain.constructors...)
+}
+
+// Then wraps do with the constructors of the chain, the first one outermost.
+// A nil do performs requests with fasthttp.Do.
+func (c ClientChain) Then(do Doer) Doer {
+	if do == nil {
+		do = fasthttp.Do
+	}
+	for i := len(c.constructors) - 1; i >= 0; i-- {
+		do = c.constructors[i](do)
+	}
+	return do
+}
+
+// ClientRetry returns a client constructor performing requests again
+// when they fail or are answered with one of policy.StatusCodes,
+// for requests with one of policy.Methods,
+// waiting with exponential backoff between attempts.
+// Requests with a streamed body, which cannot be sent twice, are never retried.
+// The response and error of the last attempt are returned.
+func ClientRetry(policy RetryPolicy) ClientConstructor {
+	retryCodes, methods := policy.normalize()
+
+	return func(next Doer) Doer {
+		return func(req *fasthttp.Request, resp *fasthttp.Response) error {
+			if !methods[string(req.Header.Method())] || req.IsBodyStream() {
+				return next(req, resp)
+			}
+
+			backoff := policy.Backoff
+			for attempt := 1; ; attempt++ {
+				err := next(req, resp)
+				if attempt == policy.MaxAttempts || err == nil && !retryCodes[resp.StatusCode()] {
+					return err
+				}
+				resp.Reset()
+				backoff = policy.wait(backoff)
+			}
+		}
+	}
+}
+
+// ClientBearer returns a client constructor authenticating requests
+// with the bearer token returned by token, such as from a cache
+// of OAuth2 client credentials. Requests are not performed
+// when token fails.
+func ClientBearer(token func() (string, error)) ClientConstructor {
+	return func(next Doer) Doer {
+		return func(req *fasthttp.Request, resp *fasthttp.Response) error {
+			t, err := token()
+			if err != nil {
+				return fmt.Errorf("fastalice: bearer token: %w", err)
+			}
+			req.Header.Set(fasthttp.HeaderAuthorization, "Bearer "+t)
+			return next(req, resp)
+		}
+	}
+}
+
+// ClientObserve returns a client constructor calling fn
+// once every request is performed, with its outcome and duration,
+// such as to record metrics or log outbound calls.
+// resp is only meaningful when err is nil.
+func ClientObserve(fn func(req *fasthttp.Request, resp *fasthttp.Response, err error, d time.Duration)) ClientConstructor {
+	return func(next Doer) Doer {
+		return func(req *fasthttp.Request, resp *fasthttp.Response) error {
+			start := now()
+			err := next(req, resp)
+			fn(req, resp, err, now().Sub(start))
+			return err
+		}
+	}
+}
+
+// ClientForward returns a client constructor copying headers
+// of the incoming request of ctx to outbound requests,
+// so that calls made while serving it can be correlated.
+// They default to DefaultRequestIDHeader, whose value is taken
+// from RequestID when it set one, and the W3C traceparent and tracestate.
+// Headers already set on outbound requests are kept.
+//
+// Since it captures ctx, the chain should be built for each request.
+//
+//	do := fastalice.NewClientChain(fastalice.ClientForward(ctx)).Extend(api).Then(client.Do)
+func ClientForward(ctx *fasthttp.RequestCtx, headers ...string) ClientConstructor {
+	if len(headers) == 0 {
+		headers = []string{DefaultRequestIDHeader, "traceparent", "tracestate"}
+	}
+	values := make(map[string]string, len(headers))
+	for _, name := range headers {
+		if v := ctx.Request.Header.Peek(name); len(v) > 0 {
+			values[name] = string(v)
+		}
+	}
+	if id := GetRequestID(ctx); id != "" && containsString(headers, DefaultRequestIDHeader) {
+		values[DefaultRequestIDHeader] = id
+	}
+
+	return func(next Doer) Doer {
+		return func(req *fasthttp.Request, resp *fasthttp.Response) error {
+			for name, v := range values {
+				if len(req.Header.Peek(name)) == 0 {
+					req.Header.Set(name, v)
+				}
+			}
+			return next(req, resp)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package fastalice
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// fakeDoer answers requests with the given statuses in turn,
+// failing with err instead for zero statuses.
+func fakeDoer(calls *int, statuses ...int) Doer {
+	return func(req *fasthttp.Request, resp *fasthttp.Response) error {
+		status := statuses[*calls]
+		*calls++
+		if status == 0 {
+			return fasthttp.ErrConnectionClosed
+		}
+		resp.SetStatusCode(status)
+		return nil
+	}
+}
+
+func TestClientChainOrder(t *testing.T) {
+	var order []string
+	tag := func(name string) ClientConstructor {
+		return func(next Doer) Doer {
+			return func(req *fasthttp.Request, resp *fasthttp.Response) error {
+				order = append(order, name)
+				return next(req, resp)
+			}
+		}
+	}
+
+	calls := 0
+	do := NewClientChain(tag("m1"), nil).Append(tag("m2")).Extend(NewClientChain(tag("m3"))).Then(fakeDoer(&calls, 200))
+	assert.NoError(t, do(&fasthttp.Request{}, &fasthttp.Response{}), "The request should be performed")
+	assert.Equal(t, []string{"m1", "m2", "m3"}, order, "Constructors should run in order")
+	assert.Equal(t, 1, calls, "The doer should be called")
+}
+
+func TestClientRetry(t *testing.T) {
+	var slept []time.Duration
+	defer fakeSleep(&slept)()
+
+	calls := 0
+	do := NewClientChain(ClientRetry(RetryPolicy{MaxAttempts: 4})).Then(fakeDoer(&calls, 0, 503, 200))
+	resp := &fasthttp.Response{}
+	assert.NoError(t, do(&fasthttp.Request{}, resp), "The request should succeed eventually")
+	assert.Equal(t, fasthttp.StatusOK, resp.StatusCode(), "The last response should be kept")
+	assert.Equal(t, 3, calls, "Errors and retryable statuses should be retried")
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}, slept, "Retries should back off")
+
+	calls = 0
+	req := &fasthttp.Request{}
+	req.Header.SetMethod(fasthttp.MethodPost)
+	do = NewClientChain(ClientRetry(RetryPolicy{})).Then(fakeDoer(&calls, 0))
+	assert.Error(t, do(req, resp), "The error should be returned")
+	assert.Equal(t, 1, calls, "Non-idempotent requests should not be retried")
+}
+
+func TestClientBearerAndForward(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set("traceparent", "00-trace-span-01")
+	New(RequestID(RequestIDGenerator(func() string { return "req-1" }))).Then(func(ctx *fasthttp.RequestCtx) {})(ctx)
+
+	var sent *fasthttp.Request
+	do := NewClientChain(
+		ClientForward(ctx),
+		ClientBearer(func() (string, error) { return "s3cret", nil }),
+	).Then(func(req *fasthttp.Request, resp *fasthttp.Response) error {
+		sent = req
+		return nil
+	})
+
+	assert.NoError(t, do(&fasthttp.Request{}, &fasthttp.Response{}), "The request should be performed")
+	assert.Equal(t, "Bearer s3cret", string(sent.Header.Peek(fasthttp.HeaderAuthorization)), "The token should be injected")
+	assert.Equal(t, "req-1", string(sent.Header.Peek(DefaultRequestIDHeader)), "The request ID should be forwarded")
+	assert.Equal(t, "00-trace-span-01", string(sent.Header.Peek("traceparent")), "The trace context should be forwarded")
+
+	failing := NewClientChain(ClientBearer(func() (string, error) { return "", errors.New("expired") })).Then(nil)
+	assert.Error(t, failing(&fasthttp.Request{}, &fasthttp.Response{}), "Token errors should fail the request")
+}
+
+func TestClientObserve(t *testing.T) {
+	defer fakeClock(time.Millisecond)()
+	var observed time.Duration
+	var status int
+	calls := 0
+	do := NewClientChain(ClientObserve(func(req *fasthttp.Request, resp *fasthttp.Response, err error, d time.Duration) {
+		observed, status = d, resp.StatusCode()
+	})).Then(fakeDoer(&calls, 201))
+
+	assert.NoError(t, do(&fasthttp.Request{}, &fasthttp.Response{}), "The request should be performed")
+	assert.Equal(t, time.Millisecond, observed, "The duration should be observed")
+	assert.Equal(t, fasthttp.StatusCreated, status, "The response should be observed")
+}
@@ -0,0 +1,15 @@
+package fastalice
+
+import (
+	"math/rand"
+	"time"
+)
+
+// now, sleep and randFloat64 are the clock and random source
+// used by time-dependent and probabilistic middleware.
+// Tests replace them to make such middleware deterministic.
+var (
+	now         = time.Now
+	sleep       = time.Sleep
+	randFloat64 = rand.Float64
+)
@@ -0,0 +1,36 @@
+package fastalice
+
+import (
+	"math/rand"
+	"time"
+)
+
+// fakeClock replaces the package clock with one
+// that advances by step on every reading.
+// The returned function restores the real clock.
+func fakeClock(step time.Duration) func() {
+	t := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time {
+		t = t.Add(step)
+		return t
+	}
+	return func() { now = time.Now }
+}
+
+// fakeSleep replaces the package sleep with one
+// that records the requested durations instead of blocking.
+// The returned function restores the real sleep.
+func fakeSleep(slept *[]time.Duration) func() {
+	sleep = func(d time.Duration) {
+		*slept = append(*slept, d)
+	}
+	return func() { sleep = time.Sleep }
+}
+
+// fakeRand replaces the package random source
+// with one that always returns v.
+// The returned function restores the real source.
+func fakeRand(v float64) func() {
+	randFloat64 = func() float64 { return v }
+	return func() { randFloat64 = rand.Float64 }
+}
@@ -0,0 +1,80 @@
+package fastalice
+
+import (
+	"sync"
+
+	"github.com/valyala/fasthttp"
+)
+
+// coalescedCall is an in-flight request other requests wait on.
+type coalescedCall struct {
+	done chan struct{}
+	// resp is the response to replay,
+	// or nil when it cannot be replayed.
+	resp *fasthttp.Response
+}
+
+// Coalesce returns a constructor that deduplicates
+// identical GET and HEAD requests in flight:
+// while a request is being served, requests with the same key
+// wait for it and get a copy of its response
+// instead of calling the following handlers.
+// A nil keyFn keys requests by method and full URI,
+// and requests for which it returns an empty string are not coalesced.
+//
+// The response is replayed as is, cookies included,
+// so keyFn must tell apart requests getting different responses,
+// such as those of different users.
+// When the first request panics or streams its response,
+// waiting requests are served by the following handlers instead.
+func Coalesce(keyFn func(ctx *fasthttp.RequestCtx) string) Constructor {
+	if keyFn == nil {
+		keyFn = func(ctx *fasthttp.RequestCtx) string {
+			return string(ctx.Method()) + " " + string(ctx.URI().FullURI())
+		}
+	}
+	var mu sync.Mutex
+	calls := make(map[string]*coalescedCall)
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if !ctx.IsGet() && !ctx.IsHead() {
+				next(ctx)
+				return
+			}
+			key := keyFn(ctx)
+			if key == "" {
+				next(ctx)
+				return
+			}
+
+			mu.Lock()
+			if c, ok := calls[key]; ok {
+				mu.Unlock()
+				<-c.done
+				if c.resp == nil {
+					next(ctx)
+					return
+				}
+				c.resp.CopyTo(&ctx.Response)
+				return
+			}
+			c := &coalescedCall{done: make(chan struct{})}
+			calls[key] = c
+			mu.Unlock()
+
+			defer func() {
+				mu.Lock()
+				delete(calls, key)
+				mu.Unlock()
+				close(c.done)
+			}()
+
+			next(ctx)
+			if !IsStreaming(ctx) && !ctx.Hijacked() {
+				c.resp = &fasthttp.Response{}
+				ctx.Response.CopyTo(c.resp)
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package fastalice
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestCoalesce(t *testing.T) {
+	var calls int32
+	entered, release := make(chan struct{}, 4), make(chan struct{})
+	h := New(Coalesce(nil)).Then(func(ctx *fasthttp.RequestCtx) {
+		atomic.AddInt32(&calls, 1)
+		entered <- struct{}{}
+		<-release
+		ctx.Response.Header.Set("X-Answer", "42")
+		ctx.SetStatusCode(fasthttp.StatusCreated)
+		ctx.SetBodyString("expensive")
+	})
+
+	ctxs := make([]*fasthttp.RequestCtx, 4)
+	var wg sync.WaitGroup
+	for i := range ctxs {
+		ctxs[i] = newTestCtx("GET", "http://localhost/report")
+		wg.Add(1)
+		go func(ctx *fasthttp.RequestCtx) {
+			defer wg.Done()
+			h(ctx)
+		}(ctxs[i])
+		if i == 0 {
+			<-entered
+		}
+	}
+	// Give the other requests time to wait on the first one.
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls, "Identical requests should be served once")
+	for _, ctx := range ctxs {
+		assert.Equal(t, fasthttp.StatusCreated, ctx.Response.StatusCode(), "The status should be replayed")
+		assert.Equal(t, "42", string(ctx.Response.Header.Peek("X-Answer")), "Headers should be replayed")
+		assert.Equal(t, "expensive", string(ctx.Response.Body()), "The body should be replayed")
+	}
+}
+
+func TestCoalesceSkips(t *testing.T) {
+	var calls int
+	h := New(Coalesce(func(ctx *fasthttp.RequestCtx) string {
+		return string(ctx.QueryArgs().Peek("user"))
+	})).Then(func(ctx *fasthttp.RequestCtx) {
+		calls++
+	})
+
+	h(newTestCtx("POST", "http://localhost/report?user=bob"))
+	h(newTestCtx("GET", "http://localhost/report"))
+	h(newTestCtx("GET", "http://localhost/report?user=bob"))
+	assert.Equal(t, 3, calls, "Requests should go through when not coalesced")
+}
+
+func TestCoalescePanic(t *testing.T) {
+	entered, release := make(chan struct{}, 2), make(chan struct{})
+	first := true
+	h := New(Coalesce(nil)).Then(func(ctx *fasthttp.RequestCtx) {
+		if first {
+			first = false
+			entered <- struct{}{}
+			<-release
+			panic("boom")
+		}
+		testApp(ctx)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer func() { recover() }()
+		h(newTestCtx("GET", "http://localhost/"))
+	}()
+	<-entered
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	h(ctx)
+	<-done
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Waiting requests should be served when the first one panics")
+}
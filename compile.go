@@ -0,0 +1,93 @@
+package fastalice
+
+import (
+	"github.com/valyala/fasthttp"
+)
+
+// Stage is a middleware step that runs before the following handlers,
+// reporting whether they should run.
+// A stage that answers the request itself returns false.
+//
+// Unlike constructors, stages are plain functions,
+// so Compile can run consecutive stages in a single loop
+// instead of nesting one closure per middleware.
+type Stage func(ctx *fasthttp.RequestCtx) bool
+
+// AppendStages extends a chain, adding the specified stages
+// as the last ones in the request flow.
+// In a chain composed with Then they behave as regular constructors;
+// Compile runs consecutive stages in a single loop.
+//
+// AppendStages returns a new chain, leaving the original one untouched.
+// Nil stages are dropped.
+func (c Chain) AppendStages(stages ...Stage) Chain {
+	staged := Chain{
+		constructors: make([]Constructor, 0, len(stages)),
+		meta:         make([]constructorMeta, 0, len(stages)),
+	}
+	for _, s := range stages {
+		if s == nil {
+			continue
+		}
+		staged.constructors = append(staged.constructors, stageConstructor(s))
+		staged.meta = append(staged.meta, constructorMeta{stage: s})
+	}
+	return join(c, staged)
+}
+
+// stageConstructor returns a constructor running s before next.
+func stageConstructor(s Stage) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if s(ctx) {
+				next(ctx)
+			}
+		}
+	}
+}
+
+// Compile is like Then, but runs each run of consecutive stages
+// added with AppendStages in a single loop,
+// avoiding a nested call per stage on long chains.
+// Other constructors are composed as in Then.
+//
+// The gain only matters for chains of many cheap stages;
+// BenchmarkCompiledDispatch measures it against Then.
+func (c Chain) Compile(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+	if h == nil {
+		return serveDefault
+	}
+
+	for i := len(c.constructors) - 1; i >= 0; {
+		if c.metaAt(i).stage == nil {
+			h = c.constructors[i](h)
+			i--
+			continue
+		}
+
+		j := i
+		for j >= 0 && c.metaAt(j).stage != nil {
+			j--
+		}
+		stages := make([]Stage, 0, i-j)
+		for k := j + 1; k <= i; k++ {
+			stages = append(stages, c.metaAt(k).stage)
+		}
+		h = runStages(stages, h)
+		i = j
+	}
+	return h
+}
+
+// runStages returns a handler running stages in order,
+// then next if they all let the request through.
+func runStages(stages []Stage, next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		for _, s := range stages {
+			if !s(ctx) {
+				return
+			}
+		}
+		next(ctx)
+	}
+}
@@ -0,0 +1,83 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func tagStage(tag string) Stage {
+	return func(ctx *fasthttp.RequestCtx) bool {
+		ctx.WriteString(tag)
+		return true
+	}
+}
+
+func TestCompileMatchesThen(t *testing.T) {
+	chain := New(tagMiddleware("t1\n")).
+		AppendStages(tagStage("s1\n"), nil, tagStage("s2\n")).
+		Append(tagMiddleware("t2\n")).
+		AppendStages(tagStage("s3\n"))
+
+	then := newTestCtx("GET", "http://localhost/")
+	chain.Then(testApp)(then)
+	compiled := newTestCtx("GET", "http://localhost/")
+	chain.Compile(testApp)(compiled)
+
+	assert.Equal(t, "t1\ns1\ns2\nt2\ns3\napp", string(compiled.Response.Body()), "Compiled chains should run in order")
+	assert.Equal(t, string(then.Response.Body()), string(compiled.Response.Body()), "Compile should behave like Then")
+}
+
+func TestCompileStageStops(t *testing.T) {
+	deny := func(ctx *fasthttp.RequestCtx) bool {
+		ctx.SetStatusCode(fasthttp.StatusForbidden)
+		return false
+	}
+	chain := New().AppendStages(tagStage("s1\n"), deny, tagStage("s2\n"))
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	chain.Compile(testApp)(ctx)
+	assert.Equal(t, fasthttp.StatusForbidden, ctx.Response.StatusCode(), "A stage returning false should answer the request")
+	assert.Equal(t, "s1\n", string(ctx.Response.Body()), "Later stages and the handler should not run")
+}
+
+func TestCompileKeepsStagesThroughComposition(t *testing.T) {
+	chain := New().AppendStages(tagStage("s1\n")).Reverse().Clone()
+	assert.NotNil(t, chain.metaAt(0).stage, "Stages should survive composition")
+	assert.Nil(t, chain.Map(func(i int, name string, c Constructor) Constructor { return c }).metaAt(0).stage, "Mapped stages should become regular constructors")
+}
+
+func benchmarkStages(n int) Chain {
+	stages := make([]Stage, n)
+	for i := range stages {
+		stages[i] = func(ctx *fasthttp.RequestCtx) bool { return true }
+	}
+	return New().AppendStages(stages...)
+}
+
+func benchmarkStagedDispatch(b *testing.B, h fasthttp.RequestHandler) {
+	ctx := newTestCtx("GET", "http://localhost/")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h(ctx)
+	}
+}
+
+func BenchmarkNestedDispatch5(b *testing.B) {
+	benchmarkStagedDispatch(b, benchmarkStages(5).Then(noopApp))
+}
+
+func BenchmarkNestedDispatch20(b *testing.B) {
+	benchmarkStagedDispatch(b, benchmarkStages(20).Then(noopApp))
+}
+
+func BenchmarkCompiledDispatch5(b *testing.B) {
+	benchmarkStagedDispatch(b, benchmarkStages(5).Compile(noopApp))
+}
+
+func BenchmarkCompiledDispatch20(b *testing.B) {
+	benchmarkStagedDispatch(b, benchmarkStages(20).Compile(noopApp))
+}
@@ -0,0 +1,118 @@
+package fastalice
+
+import (
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultCompressMinSize is the smallest response body
+// compressed by Compress unless CompressMinSize is given.
+const DefaultCompressMinSize = 1024
+
+// defaultCompressTypes are the content types compressed by Compress
+// unless CompressContentTypes is given.
+var defaultCompressTypes = []string{
+	"text/",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"image/svg+xml",
+}
+
+// compressConfig holds the settings of Compress.
+type compressConfig struct {
+	level        int
+	brotliLevel  int
+	minSize      int
+	contentTypes []string
+}
+
+// CompressOption configures Compress.
+type CompressOption func(*compressConfig)
+
+// CompressMinSize sets the smallest response body that is compressed.
+func CompressMinSize(n int) CompressOption {
+	return func(c *compressConfig) { c.minSize = n }
+}
+
+// CompressContentTypes sets the content types that are compressed.
+// A type ending with a slash, such as "text/", matches any subtype.
+func CompressContentTypes(types ...string) CompressOption {
+	return func(c *compressConfig) { c.contentTypes = types }
+}
+
+// CompressBrotliLevel sets the brotli compression level,
+// which defaults to fasthttp.CompressBrotliDefaultCompression.
+func CompressBrotliLevel(level int) CompressOption {
+	return func(c *compressConfig) { c.brotliLevel = level }
+}
+
+// Compress returns a constructor that compresses the response body
+// with brotli, gzip or deflate, preferred in that order,
+// according to the Accept-Encoding request header.
+// level is the gzip and deflate compression level,
+// such as fasthttp.CompressDefaultCompression.
+//
+// Only responses of a compressible content type,
+// at least DefaultCompressMinSize long, are compressed;
+// already encoded and streaming responses are left alone,
+// as are responses of hijacked connections.
+//
+// Compression happens once the following handlers have run,
+// so middleware reading the body, such as ETag or Cache,
+// should come after Compress in the chain to see it uncompressed.
+func Compress(level int, opts ...CompressOption) Constructor {
+	cfg := compressConfig{
+		level:        level,
+		brotliLevel:  fasthttp.CompressBrotliDefaultCompression,
+		minSize:      DefaultCompressMinSize,
+		contentTypes: defaultCompressTypes,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			next(ctx)
+
+			resp := &ctx.Response
+			resp.Header.Add(fasthttp.HeaderVary, fasthttp.HeaderAcceptEncoding)
+			if ctx.IsHead() || ctx.Hijacked() || IsStreaming(ctx) ||
+				len(resp.Header.Peek(fasthttp.HeaderContentEncoding)) > 0 ||
+				len(resp.Body()) < cfg.minSize ||
+				!compressible(cfg.contentTypes, mediaType(resp.Header.ContentType())) {
+				return
+			}
+
+			var encoded []byte
+			h := &ctx.Request.Header
+			switch {
+			case h.HasAcceptEncoding("br"):
+				encoded = fasthttp.AppendBrotliBytesLevel(nil, resp.Body(), cfg.brotliLevel)
+				resp.Header.Set(fasthttp.HeaderContentEncoding, "br")
+			case h.HasAcceptEncoding("gzip"):
+				encoded = fasthttp.AppendGzipBytesLevel(nil, resp.Body(), cfg.level)
+				resp.Header.Set(fasthttp.HeaderContentEncoding, "gzip")
+			case h.HasAcceptEncoding("deflate"):
+				encoded = fasthttp.AppendDeflateBytesLevel(nil, resp.Body(), cfg.level)
+				resp.Header.Set(fasthttp.HeaderContentEncoding, "deflate")
+			default:
+				return
+			}
+			resp.SetBodyRaw(encoded)
+		}
+	}
+}
+
+// compressible reports whether the media type mt
+// matches one of types.
+func compressible(types []string, mt string) bool {
+	for _, t := range types {
+		if mt == t || strings.HasSuffix(t, "/") && strings.HasPrefix(mt, t) {
+			return true
+		}
+	}
+	return false
+}
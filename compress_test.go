@@ -0,0 +1,97 @@
+package fastalice
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+var compressBody = strings.Repeat("fastalice ", 200)
+
+func textApp(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("text/plain; charset=utf-8")
+	ctx.WriteString(compressBody)
+}
+
+func TestCompressEncodings(t *testing.T) {
+	h := New(Compress(fasthttp.CompressDefaultCompression)).Then(textApp)
+
+	for accept, encoding := range map[string]string{
+		"gzip, deflate, br": "br",
+		"gzip, deflate":     "gzip",
+		"deflate":           "deflate",
+		"":                  "",
+	} {
+		ctx := newTestCtx("GET", "http://localhost/")
+		ctx.Request.Header.Set(fasthttp.HeaderAcceptEncoding, accept)
+		h(ctx)
+
+		assert.Equal(t, encoding, string(ctx.Response.Header.Peek(fasthttp.HeaderContentEncoding)), "Accept-Encoding %q should select the encoding", accept)
+		assert.Equal(t, "Accept-Encoding", string(ctx.Response.Header.Peek(fasthttp.HeaderVary)), "The response should vary on Accept-Encoding")
+
+		var body []byte
+		var err error
+		switch encoding {
+		case "br":
+			body, err = ctx.Response.BodyUnbrotli()
+		case "gzip":
+			body, err = ctx.Response.BodyGunzip()
+		case "deflate":
+			body, err = ctx.Response.BodyInflate()
+		default:
+			body = ctx.Response.Body()
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, compressBody, string(body), "The body should survive compression")
+	}
+}
+
+func TestCompressFilters(t *testing.T) {
+	small := func(ctx *fasthttp.RequestCtx) {
+		ctx.SetContentType("text/plain")
+		ctx.WriteString("small")
+	}
+	binary := func(ctx *fasthttp.RequestCtx) {
+		ctx.SetContentType("image/png")
+		ctx.WriteString(compressBody)
+	}
+	encoded := func(ctx *fasthttp.RequestCtx) {
+		textApp(ctx)
+		ctx.Response.Header.Set(fasthttp.HeaderContentEncoding, "identity")
+	}
+
+	for name, app := range map[string]fasthttp.RequestHandler{"small": small, "binary": binary, "encoded": encoded} {
+		ctx := newTestCtx("GET", "http://localhost/")
+		ctx.Request.Header.Set(fasthttp.HeaderAcceptEncoding, "gzip")
+		New(Compress(fasthttp.CompressDefaultCompression)).Then(app)(ctx)
+		assert.NotEqual(t, "gzip", string(ctx.Response.Header.Peek(fasthttp.HeaderContentEncoding)), "The %s response should not be compressed", name)
+	}
+}
+
+func TestCompressOptions(t *testing.T) {
+	binary := func(ctx *fasthttp.RequestCtx) {
+		ctx.SetContentType("image/bmp")
+		ctx.WriteString("small")
+	}
+	ctx := newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set(fasthttp.HeaderAcceptEncoding, "gzip")
+	New(Compress(fasthttp.CompressBestSpeed, CompressMinSize(0), CompressContentTypes("image/"))).Then(binary)(ctx)
+
+	body, err := ctx.Response.BodyGunzip()
+	assert.NoError(t, err)
+	assert.Equal(t, "small", string(body), "Options should widen what is compressed")
+}
+
+func TestCompressBeforeETag(t *testing.T) {
+	plain := newTestCtx("GET", "http://localhost/")
+	New(ETag(false)).Then(textApp)(plain)
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set(fasthttp.HeaderAcceptEncoding, "gzip")
+	New(Compress(fasthttp.CompressDefaultCompression), ETag(false)).Then(textApp)(ctx)
+
+	assert.Equal(t, "gzip", string(ctx.Response.Header.Peek(fasthttp.HeaderContentEncoding)), "The response should be compressed")
+	assert.Equal(t, string(plain.Response.Header.Peek(fasthttp.HeaderETag)), string(ctx.Response.Header.Peek(fasthttp.HeaderETag)), "ETag should hash the uncompressed body when placed after Compress")
+}
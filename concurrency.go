@@ -0,0 +1,73 @@
+package fastalice
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ConcurrencyLimit returns a constructor that lets at most max requests
+// be inside the following handlers at the same time.
+//
+// When all max slots are taken, up to queue more requests
+// wait for one to free up, for at most timeout
+// or indefinitely when timeout is not positive.
+// Requests beyond the queue, and those still waiting after timeout,
+// are answered with 503 Service Unavailable.
+//
+// Unlike fasthttp.Server.Concurrency, which bounds the whole server,
+// the limit covers only the handlers it wraps,
+// so it can protect a single expensive route.
+// It is shared by every handler built from the returned constructor.
+// It panics if max is not positive.
+func ConcurrencyLimit(max int, queue int, timeout time.Duration) Constructor {
+	if max <= 0 {
+		panic("fastalice: ConcurrencyLimit needs a positive max")
+	}
+	slots := make(chan struct{}, max)
+	var waiting int64
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if !acquireSlot(ctx, slots, &waiting, int64(queue), timeout) {
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusServiceUnavailable), fasthttp.StatusServiceUnavailable)
+				return
+			}
+			defer func() { <-slots }()
+			next(ctx)
+		}
+	}
+}
+
+// acquireSlot takes a slot, waiting in the queue when they are all taken,
+// and reports whether it got one.
+func acquireSlot(ctx *fasthttp.RequestCtx, slots chan struct{}, waiting *int64, queue int64, timeout time.Duration) bool {
+	select {
+	case slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	if atomic.AddInt64(waiting, 1) > queue {
+		atomic.AddInt64(waiting, -1)
+		return false
+	}
+	defer atomic.AddInt64(waiting, -1)
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		expired = t.C
+	}
+
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-expired:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,87 @@
+package fastalice
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// blockingApp returns a handler signaling entered
+// and blocking until release is closed.
+func blockingApp(entered chan<- struct{}, release <-chan struct{}) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		entered <- struct{}{}
+		<-release
+		testApp(ctx)
+	}
+}
+
+func TestConcurrencyLimitSheds(t *testing.T) {
+	entered, release := make(chan struct{}, 1), make(chan struct{})
+	h := New(ConcurrencyLimit(1, 0, 0)).Then(blockingApp(entered, release))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	first := newTestCtx("GET", "http://localhost/")
+	go func() {
+		defer wg.Done()
+		h(first)
+	}()
+	<-entered
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, ctx.Response.StatusCode(), "Requests beyond the limit should be shed")
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, fasthttp.StatusOK, first.Response.StatusCode(), "Requests within the limit should be served")
+}
+
+func TestConcurrencyLimitQueues(t *testing.T) {
+	entered, release := make(chan struct{}, 2), make(chan struct{})
+	h := New(ConcurrencyLimit(1, 1, time.Second)).Then(blockingApp(entered, release))
+
+	var wg sync.WaitGroup
+	ctxs := []*fasthttp.RequestCtx{newTestCtx("GET", "http://localhost/"), newTestCtx("GET", "http://localhost/")}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		h(ctxs[0])
+	}()
+	<-entered
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		h(ctxs[1])
+	}()
+
+	// Give the second request time to queue.
+	time.Sleep(10 * time.Millisecond)
+
+	close(release)
+	wg.Wait()
+	for _, ctx := range ctxs {
+		assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Queued requests should be served once a slot frees up")
+	}
+}
+
+func TestConcurrencyLimitTimeout(t *testing.T) {
+	entered, release := make(chan struct{}, 1), make(chan struct{})
+	h := New(ConcurrencyLimit(1, 1, 10*time.Millisecond)).Then(blockingApp(entered, release))
+	defer close(release)
+
+	go h(newTestCtx("GET", "http://localhost/"))
+	<-entered
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, ctx.Response.StatusCode(), "Requests queued past the timeout should be shed")
+}
+
+func TestConcurrencyLimitInvalid(t *testing.T) {
+	assert.Panics(t, func() { ConcurrencyLimit(0, 0, 0) }, "A non-positive max should panic")
+}
@@ -0,0 +1,162 @@
+package fastalice
+
+import (
+	"reflect"
+	"sync/atomic"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Names of the settings read by shipped middleware
+// configured with Configure.
+const (
+	// SettingProbability overrides the probability
+	// of ChaosLatency and ChaosFault.
+	SettingProbability = "probability"
+	// SettingLimit overrides the Limit of the policy of RateLimit.
+	SettingLimit = "limit"
+)
+
+// Option changes settings of a Config.
+type Option func(settings map[string]interface{})
+
+// Setting returns an option setting name to value.
+func Setting(name string, value interface{}) Option {
+	return func(settings map[string]interface{}) { settings[name] = value }
+}
+
+// Settings returns an option setting every entry of m,
+// such as the options a MiddlewareFactory is given.
+func Settings(m map[string]interface{}) Option {
+	return func(settings map[string]interface{}) {
+		for name, value := range m {
+			settings[name] = value
+		}
+	}
+}
+
+// Config holds the runtime-tunable settings of a middleware,
+// such as sampling rates and limits, keyed by name.
+// It is safe for concurrent use: settings are published
+// as immutable snapshots, so reading them never takes a lock.
+type Config struct {
+	current atomic.Value // map[string]interface{}
+}
+
+// NewConfig creates a config holding the settings set by opts.
+func NewConfig(opts ...Option) *Config {
+	c := &Config{}
+	c.Apply(opts...)
+	return c
+}
+
+// Apply changes the settings by applying opts to a copy of them,
+// which requests pick up as soon as it is published.
+// Concurrent calls to Apply must be serialized by the caller.
+func (c *Config) Apply(opts ...Option) {
+	old := c.settings()
+	settings := make(map[string]interface{}, len(old)+len(opts))
+	for name, value := range old {
+		settings[name] = value
+	}
+	for _, opt := range opts {
+		opt(settings)
+	}
+	c.current.Store(settings)
+}
+
+// Get returns the value of the setting name.
+func (c *Config) Get(name string) (interface{}, bool) {
+	v, ok := c.settings()[name]
+	return v, ok
+}
+
+// Snapshot returns a copy of the settings.
+func (c *Config) Snapshot() map[string]interface{} {
+	settings := c.settings()
+	snapshot := make(map[string]interface{}, len(settings))
+	for name, value := range settings {
+		snapshot[name] = value
+	}
+	return snapshot
+}
+
+func (c *Config) settings() map[string]interface{} {
+	settings, _ := c.current.Load().(map[string]interface{})
+	return settings
+}
+
+// configKey holds the config of the running middleware.
+var configKey = NewKey[*Config]("config")
+
+// Configure is like ConfigureWith,
+// for settings fixed when the chain is built,
+// such as those given to a MiddlewareFactory:
+//
+//	registry.Register("chaos", func(options map[string]interface{}) (fastalice.Constructor, error) {
+//		return fastalice.Configure(fastalice.ChaosFault(enabled, 503, 0), fastalice.Settings(options)), nil
+//	})
+func Configure(c Constructor, opts ...Option) Constructor {
+	return ConfigureWith(c, NewConfig(opts...))
+}
+
+// ConfigureWith returns a constructor running c with the settings of cfg,
+// which c reads with ConfigValue on every request.
+// Changes applied to cfg are seen by the next requests,
+// so settings can be tuned at runtime without rebuilding the chain.
+//
+// The following handlers do not see cfg,
+// so middleware configured separately do not mix their settings.
+func ConfigureWith(c Constructor, cfg *Config) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		inner := c(func(ctx *fasthttp.RequestCtx) {
+			restore := swapConfig(ctx, nil)
+			next(ctx)
+			restore()
+		})
+
+		return func(ctx *fasthttp.RequestCtx) {
+			restore := swapConfig(ctx, cfg)
+			inner(ctx)
+			restore()
+		}
+	}
+}
+
+// swapConfig makes cfg the config of the request
+// and returns a function restoring the previous one.
+func swapConfig(ctx *fasthttp.RequestCtx, cfg *Config) func() {
+	prev, _ := Get(ctx, configKey)
+	Set(ctx, configKey, cfg)
+	return func() { Set(ctx, configKey, prev) }
+}
+
+// ConfigValue returns the setting name of the middleware running ctx,
+// as set by Configure or ConfigureWith,
+// or fallback when it is not set or not of type T.
+// Numeric settings are converted to numeric types,
+// as configuration formats do not tell integers from floats.
+func ConfigValue[T any](ctx *fasthttp.RequestCtx, name string, fallback T) T {
+	cfg, _ := Get(ctx, configKey)
+	if cfg == nil {
+		return fallback
+	}
+	value, ok := cfg.Get(name)
+	if !ok {
+		return fallback
+	}
+	if v, ok := value.(T); ok {
+		return v
+	}
+
+	v, target := reflect.ValueOf(value), reflect.TypeOf(fallback)
+	if target != nil && v.IsValid() && isNumber(v.Kind()) && isNumber(target.Kind()) {
+		return v.Convert(target).Interface().(T)
+	}
+	return fallback
+}
+
+// isNumber reports whether k is an integer or floating-point kind.
+func isNumber(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
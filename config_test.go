@@ -0,0 +1,66 @@
+package fastalice
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// settingMiddleware writes the value of the "rate" setting.
+func settingMiddleware(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		ctx.WriteString(strconv.FormatFloat(ConfigValue(ctx, "rate", 0.5), 'f', -1, 64) + "\n")
+		next(ctx)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	cfg := NewConfig(Setting("rate", 0.25))
+	h := New(ConfigureWith(settingMiddleware, cfg), settingMiddleware).Then(testApp)
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, "0.25\n0.5\napp", string(ctx.Response.Body()), "Only the configured middleware should see its settings")
+
+	cfg.Apply(Settings(map[string]interface{}{"rate": 1}))
+	ctx = newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, "1\n0.5\napp", string(ctx.Response.Body()), "Applied settings should be seen by the next requests")
+}
+
+func TestConfigureNested(t *testing.T) {
+	outer := func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			next(ctx)
+			ctx.WriteString(ConfigValue(ctx, "name", "none"))
+		}
+	}
+	inner := func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			ctx.WriteString(ConfigValue(ctx, "name", "none") + "\n")
+			next(ctx)
+		}
+	}
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(Configure(outer, Setting("name", "outer")), Configure(inner, Setting("name", "inner"))).Then(func(ctx *fasthttp.RequestCtx) {})(ctx)
+	assert.Equal(t, "inner\nouter", string(ctx.Response.Body()), "Settings should be restored after the following handlers")
+}
+
+func TestConfigureShipped(t *testing.T) {
+	defer fakeRand(0.5)()
+	on := func() bool { return true }
+	cfg := NewConfig()
+	h := New(ConfigureWith(ChaosFault(on, fasthttp.StatusServiceUnavailable, 1), cfg)).Then(testApp)
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, ctx.Response.StatusCode(), "The built-in probability should apply")
+
+	cfg.Apply(Setting(SettingProbability, 0))
+	ctx = newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "The tuned probability should apply")
+}
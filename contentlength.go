@@ -0,0 +1,25 @@
+package fastalice
+
+import (
+	"github.com/valyala/fasthttp"
+)
+
+// FixContentLength returns a constructor
+// that makes the Content-Length response header
+// match the actual length of the response body
+// once the following handlers have run.
+//
+// Streamed responses are left untouched,
+// since their length is not known upfront.
+func FixContentLength() Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			next(ctx)
+
+			if ctx.Response.IsBodyStream() {
+				return
+			}
+			ctx.Response.Header.SetContentLength(len(ctx.Response.Body()))
+		}
+	}
+}
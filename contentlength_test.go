@@ -0,0 +1,30 @@
+package fastalice
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestFixContentLengthCorrectsHeader(t *testing.T) {
+	h := New(FixContentLength()).Then(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetBodyString("hello")
+		ctx.Response.Header.SetContentLength(42)
+	})
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, 5, ctx.Response.Header.ContentLength(), "Content-Length should match the body length")
+}
+
+func TestFixContentLengthSkipsStreams(t *testing.T) {
+	h := New(FixContentLength()).Then(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetBodyStream(bytes.NewBufferString("hello"), -1)
+	})
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, -1, ctx.Response.Header.ContentLength(), "Streamed responses should keep their Content-Length")
+}
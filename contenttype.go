@@ -0,0 +1,42 @@
+package fastalice
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// RequireContentType returns a constructor that rejects
+// POST, PUT and PATCH requests whose Content-Type is not
+// one of expected with 415 Unsupported Media Type,
+// without calling the following handlers.
+// Parameters such as charset are ignored in the comparison.
+//
+// Requests with other methods always pass.
+func RequireContentType(expected ...string) Constructor {
+	allowed := make(map[string]bool, len(expected))
+	for _, e := range expected {
+		allowed[mediaType([]byte(e))] = true
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if (ctx.IsPost() || ctx.IsPut() || ctx.IsPatch()) &&
+				!allowed[mediaType(ctx.Request.Header.ContentType())] {
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusUnsupportedMediaType), fasthttp.StatusUnsupportedMediaType)
+				return
+			}
+			next(ctx)
+		}
+	}
+}
+
+// mediaType returns the lowercased media type of a Content-Type value,
+// without its parameters.
+func mediaType(contentType []byte) string {
+	if i := bytes.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.ToLower(string(bytes.TrimSpace(contentType)))
+}
@@ -0,0 +1,28 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestRequireContentTypeMatch(t *testing.T) {
+	ctx := newTestCtx("POST", "http://localhost/")
+	ctx.Request.Header.SetContentType("Application/JSON; charset=utf-8")
+	New(RequireContentType("application/json")).Then(testApp)(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Matching content types should pass")
+}
+
+func TestRequireContentTypeMismatch(t *testing.T) {
+	ctx := newTestCtx("PUT", "http://localhost/")
+	ctx.Request.Header.SetContentType("text/plain")
+	New(RequireContentType("application/json", "application/xml")).Then(testApp)(ctx)
+	assert.Equal(t, fasthttp.StatusUnsupportedMediaType, ctx.Response.StatusCode(), "Mismatching content types should be rejected")
+}
+
+func TestRequireContentTypeBodylessMethod(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(RequireContentType("application/json")).Then(testApp)(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Methods without bodies should bypass the check")
+}
@@ -0,0 +1,55 @@
+package fastalice
+
+import (
+	"context"
+
+	"github.com/valyala/fasthttp"
+)
+
+// contextKey holds the context.Context bridged by WithContext.
+var contextKey = NewKey[context.Context]("context")
+
+// WithContext returns a constructor giving every request
+// a context.Context derived from parent,
+// for downstream code built on the standard library,
+// such as database or gRPC clients. Handlers get it with Context.
+//
+// The context is cancelled once the following handlers return,
+// when the server shuts down, or when parent is cancelled.
+//
+// fasthttp does not watch the connection while a handler runs,
+// so a client disconnecting is only noticed
+// when the response is written: long calls
+// should also be bounded by a deadline, see DeadlineFromHeader.
+func WithContext(parent context.Context) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			c, cancel := context.WithCancel(parent)
+			defer cancel()
+
+			if shutdown := ctx.Done(); shutdown != nil {
+				go func() {
+					select {
+					case <-shutdown:
+						cancel()
+					case <-c.Done():
+					}
+				}()
+			}
+
+			Set(ctx, contextKey, c)
+			next(ctx)
+		}
+	}
+}
+
+// Context returns the context.Context of the request
+// set by WithContext. Outside of it, ctx itself is returned:
+// fasthttp.RequestCtx is a context.Context
+// cancelled only when the server shuts down.
+func Context(ctx *fasthttp.RequestCtx) context.Context {
+	if c, ok := Get(ctx, contextKey); ok {
+		return c
+	}
+	return ctx
+}
@@ -0,0 +1,41 @@
+package fastalice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+type contextTestKey struct{}
+
+func TestWithContext(t *testing.T) {
+	parent := context.WithValue(context.Background(), contextTestKey{}, "parent")
+
+	var c context.Context
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(WithContext(parent)).Then(func(ctx *fasthttp.RequestCtx) {
+		c = Context(ctx)
+		assert.NoError(t, c.Err(), "The context should be live while the request is served")
+	})(ctx)
+
+	assert.Equal(t, "parent", c.Value(contextTestKey{}), "The context should derive from parent")
+	assert.Equal(t, context.Canceled, c.Err(), "The context should be cancelled once the request is served")
+}
+
+func TestWithContextParentCancelled(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var err error
+	New(WithContext(parent)).Then(func(ctx *fasthttp.RequestCtx) {
+		err = Context(ctx).Err()
+	})(newTestCtx("GET", "http://localhost/"))
+	assert.Equal(t, context.Canceled, err, "Cancelling parent should cancel the request context")
+}
+
+func TestContextWithoutMiddleware(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	assert.Equal(t, context.Context(ctx), Context(ctx), "The request itself should be returned")
+}
@@ -0,0 +1,137 @@
+package fastalice
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// CORSOptions configures the CORS middleware.
+type CORSOptions struct {
+	// AllowedOrigins lists the origins allowed to make
+	// cross-origin requests, such as "https://example.com".
+	// "*" allows any origin, and a single "*" wildcard
+	// such as "https://*.example.com" matches a pattern.
+	AllowedOrigins []string
+	// AllowOriginFunc, when not nil, is consulted
+	// for origins not matched by AllowedOrigins.
+	AllowOriginFunc func(origin string) bool
+	// AllowedMethods lists the methods allowed in preflight requests.
+	// It defaults to GET, HEAD and POST.
+	AllowedMethods []string
+	// AllowedHeaders lists the request headers allowed
+	// in preflight requests; "*" allows any of them.
+	AllowedHeaders []string
+	// ExposedHeaders lists the response headers
+	// readable by the client.
+	ExposedHeaders []string
+	// AllowCredentials allows requests with credentials.
+	// The request origin is then echoed even when "*" is allowed,
+	// as browsers reject a wildcard with credentials.
+	AllowCredentials bool
+	// MaxAge is how long preflight results may be cached.
+	MaxAge time.Duration
+}
+
+// CORS returns a constructor that implements
+// cross-origin resource sharing as configured by opts.
+//
+// Preflight requests are answered with 204 No Content
+// without reaching the following handlers.
+// Other requests from allowed origins get the CORS response headers
+// and go on; requests from other origins go on without them,
+// leaving the browser to block the response.
+func CORS(opts CORSOptions) Constructor {
+	methods := opts.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{fasthttp.MethodGet, fasthttp.MethodHead, fasthttp.MethodPost}
+	}
+	allowMethods := strings.ToUpper(strings.Join(methods, ", "))
+	allowHeaders := strings.Join(opts.AllowedHeaders, ", ")
+	anyHeader := false
+	for _, h := range opts.AllowedHeaders {
+		anyHeader = anyHeader || h == "*"
+	}
+	exposeHeaders := strings.Join(opts.ExposedHeaders, ", ")
+	var maxAge string
+	if opts.MaxAge > 0 {
+		maxAge = strconv.FormatInt(int64(opts.MaxAge/time.Second), 10)
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			origin := string(ctx.Request.Header.Peek(fasthttp.HeaderOrigin))
+			preflight := ctx.IsOptions() && len(ctx.Request.Header.Peek(fasthttp.HeaderAccessControlRequestMethod)) > 0
+
+			h := &ctx.Response.Header
+			h.Add(fasthttp.HeaderVary, fasthttp.HeaderOrigin)
+			if preflight {
+				h.Add(fasthttp.HeaderVary, fasthttp.HeaderAccessControlRequestMethod)
+				h.Add(fasthttp.HeaderVary, fasthttp.HeaderAccessControlRequestHeaders)
+			}
+
+			allowed, wildcard := corsOriginAllowed(opts, origin)
+			if origin == "" || !allowed {
+				if preflight {
+					ctx.SetStatusCode(fasthttp.StatusNoContent)
+					return
+				}
+				next(ctx)
+				return
+			}
+
+			if wildcard && !opts.AllowCredentials {
+				h.Set(fasthttp.HeaderAccessControlAllowOrigin, "*")
+			} else {
+				h.Set(fasthttp.HeaderAccessControlAllowOrigin, origin)
+			}
+			if opts.AllowCredentials {
+				h.Set(fasthttp.HeaderAccessControlAllowCredentials, "true")
+			}
+
+			if !preflight {
+				if exposeHeaders != "" {
+					h.Set(fasthttp.HeaderAccessControlExposeHeaders, exposeHeaders)
+				}
+				next(ctx)
+				return
+			}
+
+			h.Set(fasthttp.HeaderAccessControlAllowMethods, allowMethods)
+			if anyHeader {
+				if requested := ctx.Request.Header.Peek(fasthttp.HeaderAccessControlRequestHeaders); len(requested) > 0 {
+					h.SetBytesV(fasthttp.HeaderAccessControlAllowHeaders, requested)
+				}
+			} else if allowHeaders != "" {
+				h.Set(fasthttp.HeaderAccessControlAllowHeaders, allowHeaders)
+			}
+			if maxAge != "" {
+				h.Set(fasthttp.HeaderAccessControlMaxAge, maxAge)
+			}
+			ctx.SetStatusCode(fasthttp.StatusNoContent)
+		}
+	}
+}
+
+// corsOriginAllowed reports whether origin is allowed by opts,
+// and whether it was allowed by the "*" wildcard.
+func corsOriginAllowed(opts CORSOptions, origin string) (allowed, wildcard bool) {
+	for _, o := range opts.AllowedOrigins {
+		if o == "*" {
+			return true, true
+		}
+		if i := strings.IndexByte(o, '*'); i >= 0 {
+			prefix, suffix := o[:i], o[i+1:]
+			if len(origin) >= len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				return true, false
+			}
+			continue
+		}
+		if strings.EqualFold(o, origin) {
+			return true, false
+		}
+	}
+	return opts.AllowOriginFunc != nil && opts.AllowOriginFunc(origin), false
+}
@@ -0,0 +1,109 @@
+package fastalice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func newCORSCtx(method, origin string) *fasthttp.RequestCtx {
+	ctx := newTestCtx(method, "http://localhost/")
+	if origin != "" {
+		ctx.Request.Header.Set(fasthttp.HeaderOrigin, origin)
+	}
+	return ctx
+}
+
+func TestCORSPreflight(t *testing.T) {
+	h := New(CORS(CORSOptions{
+		AllowedOrigins: []string{"https://example.com"},
+		AllowedMethods: []string{"GET", "put"},
+		AllowedHeaders: []string{"Content-Type", "X-Token"},
+		MaxAge:         10 * time.Minute,
+	})).Then(testApp)
+
+	ctx := newCORSCtx("OPTIONS", "https://example.com")
+	ctx.Request.Header.Set(fasthttp.HeaderAccessControlRequestMethod, "PUT")
+	h(ctx)
+
+	assert.Equal(t, fasthttp.StatusNoContent, ctx.Response.StatusCode(), "Preflight requests should be short-circuited")
+	assert.Equal(t, "", string(ctx.Response.Body()), "Preflight requests should not reach the handler")
+	assert.Equal(t, "https://example.com", string(ctx.Response.Header.Peek(fasthttp.HeaderAccessControlAllowOrigin)), "The origin should be allowed")
+	assert.Equal(t, "GET, PUT", string(ctx.Response.Header.Peek(fasthttp.HeaderAccessControlAllowMethods)), "The methods should be listed")
+	assert.Equal(t, "Content-Type, X-Token", string(ctx.Response.Header.Peek(fasthttp.HeaderAccessControlAllowHeaders)), "The headers should be listed")
+	assert.Equal(t, "600", string(ctx.Response.Header.Peek(fasthttp.HeaderAccessControlMaxAge)), "The max age should be set in seconds")
+}
+
+func TestCORSPreflightAnyHeader(t *testing.T) {
+	h := New(CORS(CORSOptions{AllowedOrigins: []string{"*"}, AllowedHeaders: []string{"*"}})).Then(testApp)
+
+	ctx := newCORSCtx("OPTIONS", "https://example.com")
+	ctx.Request.Header.Set(fasthttp.HeaderAccessControlRequestMethod, "GET")
+	ctx.Request.Header.Set(fasthttp.HeaderAccessControlRequestHeaders, "X-Custom")
+	h(ctx)
+
+	assert.Equal(t, "*", string(ctx.Response.Header.Peek(fasthttp.HeaderAccessControlAllowOrigin)), "Any origin should be allowed")
+	assert.Equal(t, "X-Custom", string(ctx.Response.Header.Peek(fasthttp.HeaderAccessControlAllowHeaders)), "The requested headers should be echoed")
+	assert.Equal(t, "GET, HEAD, POST", string(ctx.Response.Header.Peek(fasthttp.HeaderAccessControlAllowMethods)), "The default methods should be listed")
+}
+
+func TestCORSSimpleRequest(t *testing.T) {
+	h := New(CORS(CORSOptions{
+		AllowedOrigins:   []string{"*"},
+		ExposedHeaders:   []string{"X-Request-Id"},
+		AllowCredentials: true,
+	})).Then(testApp)
+
+	ctx := newCORSCtx("GET", "https://example.com")
+	h(ctx)
+
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Simple requests should reach the handler")
+	assert.Equal(t, "https://example.com", string(ctx.Response.Header.Peek(fasthttp.HeaderAccessControlAllowOrigin)), "The origin should be echoed with credentials")
+	assert.Equal(t, "true", string(ctx.Response.Header.Peek(fasthttp.HeaderAccessControlAllowCredentials)), "Credentials should be allowed")
+	assert.Equal(t, "X-Request-Id", string(ctx.Response.Header.Peek(fasthttp.HeaderAccessControlExposeHeaders)), "The exposed headers should be listed")
+	assert.Equal(t, "Origin", string(ctx.Response.Header.Peek(fasthttp.HeaderVary)), "The response should vary on the origin")
+}
+
+func TestCORSOriginMatching(t *testing.T) {
+	opts := CORSOptions{
+		AllowedOrigins:  []string{"https://*.example.com", "https://example.org"},
+		AllowOriginFunc: func(origin string) bool { return origin == "https://partner.net" },
+	}
+	h := New(CORS(opts)).Then(testApp)
+
+	for origin, allowed := range map[string]bool{
+		"https://api.example.com": true,
+		"https://EXAMPLE.org":     true,
+		"https://partner.net":     true,
+		"https://example.com":     false,
+		"https://evil.com":        false,
+	} {
+		ctx := newCORSCtx("GET", origin)
+		h(ctx)
+		assert.Equal(t, "app", string(ctx.Response.Body()), "Requests should reach the handler")
+		if allowed {
+			assert.Equal(t, origin, string(ctx.Response.Header.Peek(fasthttp.HeaderAccessControlAllowOrigin)), "Origin %s should be allowed", origin)
+		} else {
+			assert.Empty(t, ctx.Response.Header.Peek(fasthttp.HeaderAccessControlAllowOrigin), "Origin %s should not be allowed", origin)
+		}
+	}
+}
+
+func TestCORSDisallowedPreflight(t *testing.T) {
+	h := New(CORS(CORSOptions{AllowedOrigins: []string{"https://example.com"}})).Then(testApp)
+
+	ctx := newCORSCtx("OPTIONS", "https://evil.com")
+	ctx.Request.Header.Set(fasthttp.HeaderAccessControlRequestMethod, "GET")
+	h(ctx)
+
+	assert.Equal(t, fasthttp.StatusNoContent, ctx.Response.StatusCode(), "Disallowed preflights should still be answered")
+	assert.Empty(t, ctx.Response.Header.Peek(fasthttp.HeaderAccessControlAllowOrigin), "Disallowed preflights should not allow the origin")
+}
+
+func TestCORSWithoutOrigin(t *testing.T) {
+	ctx := newCORSCtx("OPTIONS", "")
+	New(CORS(CORSOptions{AllowedOrigins: []string{"*"}})).Then(testApp)(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Plain OPTIONS requests should reach the handler")
+}
@@ -0,0 +1,27 @@
+package fastalice
+
+import (
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// CostAttribution returns a constructor that attributes
+// the cost of each request to the team returned by extract.
+// Once the following handlers have run,
+// record is called with the team,
+// the sizes of the request and response bodies
+// and the time spent serving the request.
+// Streamed response bodies are not read:
+// their size is their Content-Length, or -1 when it is unknown.
+func CostAttribution(extract func(ctx *fasthttp.RequestCtx) string, record func(team string, reqBytes, respBytes int, dur time.Duration)) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			start := now()
+			next(ctx)
+			dur := now().Sub(start)
+
+			record(extract(ctx), len(ctx.Request.Body()), responseSize(ctx), dur)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package fastalice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestCostAttributionRecordsRequest(t *testing.T) {
+	defer fakeClock(time.Second)()
+
+	var (
+		team                string
+		reqBytes, respBytes int
+		dur                 time.Duration
+	)
+	cost := CostAttribution(
+		func(ctx *fasthttp.RequestCtx) string {
+			return string(ctx.Request.Header.Peek("X-Team"))
+		},
+		func(tm string, req, resp int, d time.Duration) {
+			team, reqBytes, respBytes, dur = tm, req, resp, d
+		},
+	)
+
+	ctx := newTestCtx("POST", "http://localhost/")
+	ctx.Request.Header.Set("X-Team", "search")
+	ctx.Request.SetBodyString("query")
+	New(cost).Then(testApp)(ctx)
+
+	assert.Equal(t, "search", team, "Cost should be attributed to the extracted team")
+	assert.Equal(t, 5, reqBytes, "Request size should be the body length")
+	assert.Equal(t, 3, respBytes, "Response size should be the body length")
+	assert.Equal(t, time.Second, dur, "Duration should be measured around the handler")
+}
+
+func TestCostAttributionSSE(t *testing.T) {
+	respBytes := 0
+	cost := CostAttribution(
+		func(ctx *fasthttp.RequestCtx) string { return "search" },
+		func(team string, req, resp int, d time.Duration) { respBytes = resp },
+	)
+
+	assert.True(t, returnsWithin(New(cost).Then(endlessSSE), newTestCtx("GET", "http://localhost/events"), time.Second), "Endless streams should not be read")
+	assert.Equal(t, -1, respBytes, "Streams of unknown length should be recorded as -1")
+}
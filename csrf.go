@@ -0,0 +1,247 @@
+package fastalice
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// CSRFMode selects how CSRF tokens are checked.
+type CSRFMode int
+
+const (
+	// CSRFDoubleSubmit keeps the token in a cookie
+	// and requires requests to echo it in a header or form field.
+	// It needs no server-side state.
+	CSRFDoubleSubmit CSRFMode = iota
+	// CSRFSynchronizer keeps the token server-side,
+	// in a CSRFTokenStore keyed by session,
+	// and requires requests to send it in a header or form field.
+	CSRFSynchronizer
+)
+
+// Default names used by CSRF when CSRFOptions leaves them empty.
+const (
+	DefaultCSRFCookieName = "_csrf"
+	DefaultCSRFHeaderName = "X-CSRF-Token"
+	DefaultCSRFFormField  = "csrf_token"
+)
+
+// CSRFTokenStore holds synchronizer tokens by session key.
+// Implementations must be safe for concurrent use.
+type CSRFTokenStore interface {
+	// Load returns the token saved for key, if any.
+	Load(key string) (token string, ok bool)
+	// Save records token for key.
+	Save(key, token string)
+}
+
+// CSRFOptions configures the CSRF middleware.
+type CSRFOptions struct {
+	// Mode selects the double-submit-cookie
+	// or the synchronizer-token pattern.
+	Mode CSRFMode
+
+	// HeaderName is the request header carrying the token,
+	// DefaultCSRFHeaderName by default.
+	HeaderName string
+	// FormField is the form field carrying the token
+	// when the header is absent, DefaultCSRFFormField by default.
+	FormField string
+
+	// CookieName is the cookie holding the token in CSRFDoubleSubmit mode,
+	// DefaultCSRFCookieName by default.
+	CookieName string
+	// CookiePath and CookieDomain scope the cookie;
+	// CookiePath defaults to "/".
+	CookiePath   string
+	CookieDomain string
+	// CookieMaxAge is the cookie lifetime;
+	// zero makes it a session cookie.
+	CookieMaxAge time.Duration
+	// CookieSecure restricts the cookie to HTTPS.
+	CookieSecure bool
+	// CookieSameSite sets the SameSite attribute,
+	// fasthttp.CookieSameSiteLaxMode by default.
+	CookieSameSite fasthttp.CookieSameSite
+
+	// Store holds the tokens in CSRFSynchronizer mode.
+	Store CSRFTokenStore
+	// SessionID returns the session key of the request
+	// in CSRFSynchronizer mode. Requests without one
+	// get no token, so their state-changing requests are rejected.
+	SessionID func(ctx *fasthttp.RequestCtx) string
+
+	// ErrorHandler answers rejected requests.
+	// It defaults to 403 Forbidden.
+	ErrorHandler fasthttp.RequestHandler
+}
+
+// csrfTokenKey holds the token of the request.
+var csrfTokenKey = NewKey[string]("csrfToken")
+
+// CSRF returns a constructor protecting against
+// cross-site request forgery as configured by opts.
+//
+// Requests with a safe method (GET, HEAD, OPTIONS and TRACE)
+// go on, and are issued a token when they lack one.
+// Requests with any other method must send the token
+// in the header or form field, or they are rejected
+// without calling the following handlers.
+// Handlers read the token to embed in pages with CSRFToken.
+//
+// CSRF panics when CSRFSynchronizer mode is selected
+// without a Store or SessionID.
+func CSRF(opts CSRFOptions) Constructor {
+	if opts.HeaderName == "" {
+		opts.HeaderName = DefaultCSRFHeaderName
+	}
+	if opts.FormField == "" {
+		opts.FormField = DefaultCSRFFormField
+	}
+	if opts.CookieName == "" {
+		opts.CookieName = DefaultCSRFCookieName
+	}
+	if opts.CookiePath == "" {
+		opts.CookiePath = "/"
+	}
+	if opts.CookieSameSite == fasthttp.CookieSameSiteDisabled {
+		opts.CookieSameSite = fasthttp.CookieSameSiteLaxMode
+	}
+	if opts.ErrorHandler == nil {
+		opts.ErrorHandler = func(ctx *fasthttp.RequestCtx) {
+			ctx.Error(fasthttp.StatusMessage(fasthttp.StatusForbidden), fasthttp.StatusForbidden)
+		}
+	}
+	if opts.Mode == CSRFSynchronizer && (opts.Store == nil || opts.SessionID == nil) {
+		panic("fastalice: CSRFSynchronizer mode requires a Store and SessionID")
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			var token, sessionID string
+			if opts.Mode == CSRFSynchronizer {
+				sessionID = opts.SessionID(ctx)
+				if sessionID != "" {
+					token, _ = opts.Store.Load(sessionID)
+				}
+			} else {
+				token = string(ctx.Request.Header.Cookie(opts.CookieName))
+			}
+
+			if !csrfSafeMethod(ctx) {
+				if token == "" || !ValidCSRFToken(token, csrfSubmittedToken(ctx, opts)) {
+					opts.ErrorHandler(ctx)
+					return
+				}
+			} else if token == "" {
+				switch {
+				case opts.Mode == CSRFDoubleSubmit:
+					token = GenerateCSRFToken()
+					setCSRFCookie(ctx, opts, token)
+				case sessionID != "":
+					token = GenerateCSRFToken()
+					opts.Store.Save(sessionID, token)
+				}
+			}
+
+			if token != "" {
+				Set(ctx, csrfTokenKey, token)
+			}
+			next(ctx)
+		}
+	}
+}
+
+// CSRFToken returns the CSRF token of the request,
+// to be embedded in forms or handed to scripts.
+// It returns an empty string outside of the CSRF middleware.
+func CSRFToken(ctx *fasthttp.RequestCtx) string {
+	token, _ := Get(ctx, csrfTokenKey)
+	return token
+}
+
+// GenerateCSRFToken returns a new random token
+// holding 32 bytes of entropy, encoded in URL-safe base64.
+func GenerateCSRFToken() string {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic("fastalice: cannot generate CSRF token: " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(b[:])
+}
+
+// ValidCSRFToken reports whether the submitted token
+// matches the expected one, in constant time.
+func ValidCSRFToken(expected, submitted string) bool {
+	return expected != "" && subtle.ConstantTimeCompare([]byte(expected), []byte(submitted)) == 1
+}
+
+// csrfSafeMethod reports whether the request method
+// is not expected to change state.
+func csrfSafeMethod(ctx *fasthttp.RequestCtx) bool {
+	return ctx.IsGet() || ctx.IsHead() || ctx.IsOptions() || ctx.IsTrace()
+}
+
+// csrfSubmittedToken returns the token sent with the request,
+// from the header or else from the form body.
+func csrfSubmittedToken(ctx *fasthttp.RequestCtx, opts CSRFOptions) string {
+	if token := ctx.Request.Header.Peek(opts.HeaderName); len(token) > 0 {
+		return string(token)
+	}
+	if token := ctx.PostArgs().Peek(opts.FormField); len(token) > 0 {
+		return string(token)
+	}
+	if form, err := ctx.MultipartForm(); err == nil {
+		if values := form.Value[opts.FormField]; len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+func setCSRFCookie(ctx *fasthttp.RequestCtx, opts CSRFOptions, token string) {
+	c := fasthttp.AcquireCookie()
+	defer fasthttp.ReleaseCookie(c)
+
+	c.SetKey(opts.CookieName)
+	c.SetValue(token)
+	c.SetPath(opts.CookiePath)
+	c.SetDomain(opts.CookieDomain)
+	if opts.CookieMaxAge > 0 {
+		c.SetMaxAge(int(opts.CookieMaxAge / time.Second))
+	}
+	c.SetSecure(opts.CookieSecure)
+	c.SetSameSite(opts.CookieSameSite)
+	ctx.Response.Header.SetCookie(c)
+}
+
+// memoryCSRFTokenStore is an in-memory CSRFTokenStore.
+type memoryCSRFTokenStore struct {
+	mu     sync.RWMutex
+	tokens map[string]string
+}
+
+// NewMemoryCSRFTokenStore returns an in-memory CSRFTokenStore.
+// Tokens are never forgotten,
+// so it suits tests and small deployments.
+func NewMemoryCSRFTokenStore() CSRFTokenStore {
+	return &memoryCSRFTokenStore{tokens: make(map[string]string)}
+}
+
+func (s *memoryCSRFTokenStore) Load(key string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	token, ok := s.tokens[key]
+	return token, ok
+}
+
+func (s *memoryCSRFTokenStore) Save(key, token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[key] = token
+}
@@ -0,0 +1,103 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// responseCookie returns the value of the cookie set by the response.
+func responseCookie(ctx *fasthttp.RequestCtx, name string) string {
+	c := fasthttp.AcquireCookie()
+	defer fasthttp.ReleaseCookie(c)
+	c.SetKey(name)
+	if !ctx.Response.Header.Cookie(c) {
+		return ""
+	}
+	return string(c.Value())
+}
+
+func TestCSRFDoubleSubmit(t *testing.T) {
+	var seen string
+	h := New(CSRF(CSRFOptions{})).Then(func(ctx *fasthttp.RequestCtx) {
+		seen = CSRFToken(ctx)
+		testApp(ctx)
+	})
+
+	ctx := newTestCtx("GET", "http://localhost/form")
+	h(ctx)
+	token := responseCookie(ctx, DefaultCSRFCookieName)
+	assert.NotEmpty(t, token, "Safe requests should be issued a token cookie")
+	assert.Equal(t, token, seen, "Handlers should see the issued token")
+
+	ctx = newTestCtx("POST", "http://localhost/form")
+	ctx.Request.Header.SetCookie(DefaultCSRFCookieName, token)
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusForbidden, ctx.Response.StatusCode(), "Requests without a submitted token should be rejected")
+
+	ctx = newTestCtx("POST", "http://localhost/form")
+	ctx.Request.Header.SetCookie(DefaultCSRFCookieName, token)
+	ctx.Request.Header.Set(DefaultCSRFHeaderName, "forged")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusForbidden, ctx.Response.StatusCode(), "Requests with a wrong token should be rejected")
+
+	ctx = newTestCtx("POST", "http://localhost/form")
+	ctx.Request.Header.SetCookie(DefaultCSRFCookieName, token)
+	ctx.Request.Header.Set(DefaultCSRFHeaderName, token)
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Requests echoing the token in the header should go on")
+
+	ctx = newTestCtx("POST", "http://localhost/form")
+	ctx.Request.Header.SetCookie(DefaultCSRFCookieName, token)
+	ctx.Request.Header.SetContentType("application/x-www-form-urlencoded")
+	ctx.Request.SetBodyString(DefaultCSRFFormField + "=" + token)
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Requests echoing the token in the form should go on")
+}
+
+func TestCSRFSynchronizer(t *testing.T) {
+	store := NewMemoryCSRFTokenStore()
+	var seen string
+	h := New(CSRF(CSRFOptions{
+		Mode:  CSRFSynchronizer,
+		Store: store,
+		SessionID: func(ctx *fasthttp.RequestCtx) string {
+			return string(ctx.Request.Header.Peek("X-Session"))
+		},
+	})).Then(func(ctx *fasthttp.RequestCtx) {
+		seen = CSRFToken(ctx)
+		testApp(ctx)
+	})
+
+	ctx := newTestCtx("GET", "http://localhost/form")
+	ctx.Request.Header.Set("X-Session", "s1")
+	h(ctx)
+	token, ok := store.Load("s1")
+	assert.True(t, ok, "Safe requests should get a stored token")
+	assert.Equal(t, token, seen, "Handlers should see the stored token")
+	assert.Empty(t, responseCookie(ctx, DefaultCSRFCookieName), "No cookie should be set in synchronizer mode")
+
+	ctx = newTestCtx("POST", "http://localhost/form")
+	ctx.Request.Header.Set("X-Session", "s2")
+	ctx.Request.Header.Set(DefaultCSRFHeaderName, token)
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusForbidden, ctx.Response.StatusCode(), "Tokens of other sessions should be rejected")
+
+	ctx = newTestCtx("POST", "http://localhost/form")
+	ctx.Request.Header.Set("X-Session", "s1")
+	ctx.Request.Header.Set(DefaultCSRFHeaderName, token)
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Requests with the session token should go on")
+}
+
+func TestCSRFSynchronizerRequiresStore(t *testing.T) {
+	assert.Panics(t, func() { CSRF(CSRFOptions{Mode: CSRFSynchronizer}) }, "CSRF should panic without a store")
+}
+
+func TestValidCSRFToken(t *testing.T) {
+	token := GenerateCSRFToken()
+	assert.NotEqual(t, token, GenerateCSRFToken(), "Generated tokens should differ")
+	assert.True(t, ValidCSRFToken(token, token), "Matching tokens should be valid")
+	assert.False(t, ValidCSRFToken("", ""), "Empty tokens should never be valid")
+}
@@ -0,0 +1,91 @@
+package fastalice
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DeadlineFromHeader returns a constructor that enforces
+// a client-supplied timeout read from the given request header,
+// capped at max, on the following handlers.
+// Requests without a valid timeout get max; a non-positive max
+// leaves them, and the cap, out.
+//
+// A "grpc-timeout" header is read in the gRPC format,
+// an integer followed by a unit among H, M, S, m, u and n,
+// such as "500m" for 500 milliseconds.
+// Other headers, such as X-Request-Timeout, take either
+// a Go duration such as "1.5s", or a number of seconds.
+//
+// The context returned by Context carries the deadline,
+// so downstream calls give up with it, and requests
+// not served in time are answered with 504 Gateway Timeout,
+// as with TimeoutWithCode. Within a Timeout,
+// the earliest of both deadlines wins.
+func DeadlineFromHeader(header string, max time.Duration) Constructor {
+	grpc := strings.EqualFold(header, "grpc-timeout")
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			d, ok := parseTimeoutHeader(string(ctx.Request.Header.Peek(header)), grpc)
+			if !ok || (max > 0 && d > max) {
+				d = max
+			}
+			if d <= 0 {
+				next(ctx)
+				return
+			}
+
+			c, cancel := context.WithTimeout(Context(ctx), d)
+			defer cancel()
+			Set(ctx, contextKey, c)
+
+			msg := fasthttp.StatusMessage(fasthttp.StatusGatewayTimeout)
+			runWithTimeout(ctx, next, d, msg, fasthttp.StatusGatewayTimeout)
+		}
+	}
+}
+
+// grpcTimeoutUnits maps the units of grpc-timeout headers to durations.
+var grpcTimeoutUnits = map[byte]time.Duration{
+	'H': time.Hour,
+	'M': time.Minute,
+	'S': time.Second,
+	'm': time.Millisecond,
+	'u': time.Microsecond,
+	'n': time.Nanosecond,
+}
+
+// parseTimeoutHeader parses a timeout header value,
+// in the gRPC format when grpc is true.
+func parseTimeoutHeader(v string, grpc bool) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+
+	if grpc {
+		unit, ok := grpcTimeoutUnits[v[len(v)-1]]
+		if !ok || len(v) > 9 {
+			return 0, false
+		}
+		n, err := strconv.ParseUint(v[:len(v)-1], 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return time.Duration(n) * unit, true
+	}
+
+	d, err := time.ParseDuration(v)
+	if secs, ferr := strconv.ParseFloat(v, 64); ferr == nil {
+		d, err = time.Duration(secs*float64(time.Second)), nil
+	}
+	if err != nil || d <= 0 {
+		return 0, false
+	}
+	return d, true
+}
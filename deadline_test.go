@@ -0,0 +1,93 @@
+package fastalice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestParseTimeoutHeader(t *testing.T) {
+	cases := []struct {
+		v    string
+		grpc bool
+		want time.Duration
+		ok   bool
+	}{
+		{"500m", true, 500 * time.Millisecond, true},
+		{"2S", true, 2 * time.Second, true},
+		{"1H", true, time.Hour, true},
+		{"5x", true, 0, false},
+		{"123456789S", true, 0, false},
+		{"1.5s", false, 1500 * time.Millisecond, true},
+		{"2", false, 2 * time.Second, true},
+		{"-1s", false, 0, false},
+		{"soon", false, 0, false},
+		{"", false, 0, false},
+	}
+	for _, c := range cases {
+		d, ok := parseTimeoutHeader(c.v, c.grpc)
+		assert.Equal(t, c.ok, ok, "Parsing %q should report validity", c.v)
+		assert.Equal(t, c.want, d, "Parsing %q should give its duration", c.v)
+	}
+}
+
+func TestDeadlineFromHeader(t *testing.T) {
+	var remaining time.Duration
+	h := New(DeadlineFromHeader("X-Request-Timeout", time.Minute)).Then(func(ctx *fasthttp.RequestCtx) {
+		deadline, ok := Context(ctx).Deadline()
+		assert.True(t, ok, "The context should carry a deadline")
+		remaining = time.Until(deadline)
+	})
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set("X-Request-Timeout", "2s")
+	h(ctx)
+	assert.InDelta(t, float64(2*time.Second), float64(remaining), float64(time.Second), "The client timeout should be used")
+
+	ctx = newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set("X-Request-Timeout", "1h")
+	h(ctx)
+	assert.InDelta(t, float64(time.Minute), float64(remaining), float64(time.Second), "The client timeout should be capped")
+
+	ctx = newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.InDelta(t, float64(time.Minute), float64(remaining), float64(time.Second), "The cap should apply without a client timeout")
+}
+
+func TestDeadlineFromHeaderExpires(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	slow := func(ctx *fasthttp.RequestCtx) {
+		select {
+		case <-Context(ctx).Done():
+		case <-release:
+		}
+		ctx.WriteString("late")
+	}
+
+	h := New(DeadlineFromHeader("grpc-timeout", time.Minute)).Then(slow)
+	resp := serveInmemory(t, func(ctx *fasthttp.RequestCtx) {
+		ctx.Request.Header.Set("grpc-timeout", "10m")
+		h(ctx)
+	}, "http://localhost/")
+	assert.Equal(t, fasthttp.StatusGatewayTimeout, resp.StatusCode(), "Expired requests should get a 504")
+}
+
+func TestDeadlineFromHeaderWithinTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	slow := func(ctx *fasthttp.RequestCtx) { <-release }
+
+	h := New(Timeout(time.Minute, "too slow"), DeadlineFromHeader("grpc-timeout", time.Minute)).Then(slow)
+	resp := serveInmemory(t, func(ctx *fasthttp.RequestCtx) {
+		ctx.Request.Header.Set("grpc-timeout", "10m")
+		h(ctx)
+	}, "http://localhost/")
+	assert.Equal(t, fasthttp.StatusGatewayTimeout, resp.StatusCode(), "A shorter client deadline should win over Timeout")
+
+	h = New(Timeout(10*time.Millisecond, "too slow"), DeadlineFromHeader("grpc-timeout", time.Minute)).Then(slow)
+	resp = serveInmemory(t, h, "http://localhost/")
+	assert.Equal(t, fasthttp.StatusRequestTimeout, resp.StatusCode(), "A shorter Timeout should win over the client deadline")
+}
@@ -0,0 +1,185 @@
+package fastalice
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Format is a body encoding identified by its media type.
+// Unmarshal decodes a body into a value for Decode,
+// and Marshal encodes a value for the rendering helpers;
+// either may be nil when the format is one-way.
+type Format struct {
+	MediaType string
+	Unmarshal func(data []byte, v interface{}) error
+	Marshal   func(v interface{}) ([]byte, error)
+}
+
+var (
+	// JSONFormat encodes bodies with encoding/json.
+	JSONFormat = Format{"application/json", json.Unmarshal, json.Marshal}
+	// XMLFormat encodes bodies with encoding/xml.
+	XMLFormat = Format{"application/xml", xml.Unmarshal, xml.Marshal}
+	// FormFormat decodes URL-encoded forms into structs,
+	// matching fields by their "form" tag or else by name.
+	// Fields may be strings, booleans, numbers
+	// or slices of them.
+	FormFormat = Format{"application/x-www-form-urlencoded", unmarshalForm, nil}
+)
+
+// decodedBodyKey holds the decoded request body.
+var decodedBodyKey = NewKey[interface{}]("decodedBody")
+
+// Decode returns a constructor that decodes the request body
+// into the value returned by into, usually a pointer to a new struct,
+// according to the request Content-Type.
+// The formats default to JSONFormat, XMLFormat and FormFormat.
+//
+// Requests with a Content-Type not matching any format
+// are answered with 415 Unsupported Media Type,
+// and requests whose body cannot be decoded with 400 Bad Request,
+// without calling the following handlers.
+// They read the decoded value with DecodedBody.
+// Streamed bodies are consumed, not kept in the request,
+// when Streaming asks for StreamBodies.
+//
+//	type createUser struct {
+//		Name string `json:"name" form:"name"`
+//	}
+//	chain.Append(fastalice.Decode(func() interface{} { return new(createUser) }))
+func Decode(into func() interface{}, formats ...Format) Constructor {
+	if len(formats) == 0 {
+		formats = []Format{JSONFormat, XMLFormat, FormFormat}
+	}
+	byType := make(map[string]Format, len(formats))
+	for _, f := range formats {
+		if f.Unmarshal != nil {
+			byType[mediaType([]byte(f.MediaType))] = f
+		}
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			f, ok := byType[mediaType(ctx.Request.Header.ContentType())]
+			if !ok {
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusUnsupportedMediaType), fasthttp.StatusUnsupportedMediaType)
+				return
+			}
+
+			var body []byte
+			if keepStreaming(ctx) {
+				var err error
+				if body, err = readStream(&ctx.Request); err != nil {
+					ctx.Error(fasthttp.StatusMessage(fasthttp.StatusBadRequest), fasthttp.StatusBadRequest)
+					return
+				}
+			} else {
+				body = ctx.PostBody()
+			}
+			v := into()
+			if err := f.Unmarshal(body, v); err != nil {
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusBadRequest), fasthttp.StatusBadRequest)
+				return
+			}
+			Set(ctx, decodedBodyKey, v)
+			next(ctx)
+		}
+	}
+}
+
+// DecodedBody returns the request body decoded by Decode,
+// or nil outside of it.
+func DecodedBody(ctx *fasthttp.RequestCtx) interface{} {
+	v, _ := Get(ctx, decodedBodyKey)
+	return v
+}
+
+// unmarshalForm decodes a URL-encoded form into the struct v points to.
+func unmarshalForm(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("fastalice: form bodies decode into struct pointers only")
+	}
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+
+	rv = rv.Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Tag.Get("form")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		vs, ok := values[name]
+		if !ok {
+			continue
+		}
+
+		fv := rv.Field(i)
+		if fv.Kind() == reflect.Slice {
+			s := reflect.MakeSlice(fv.Type(), len(vs), len(vs))
+			for j, raw := range vs {
+				if err := setFormValue(s.Index(j), raw); err != nil {
+					return fmt.Errorf("fastalice: form field %q: %w", name, err)
+				}
+			}
+			fv.Set(s)
+			continue
+		}
+		if err := setFormValue(fv, vs[0]); err != nil {
+			return fmt.Errorf("fastalice: form field %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// setFormValue parses raw into v according to its kind.
+func setFormValue(v reflect.Value, raw string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported kind %s", v.Kind())
+	}
+	return nil
+}
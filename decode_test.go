@@ -0,0 +1,63 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+type decodeTarget struct {
+	Name  string   `json:"name" xml:"name" form:"name"`
+	Age   int      `json:"age" xml:"age" form:"age"`
+	Tags  []string `json:"tags" xml:"tag" form:"tag"`
+	Admin bool     `json:"-" xml:"-" form:"-"`
+}
+
+func decodeRequest(contentType, body string) (*fasthttp.RequestCtx, *decodeTarget) {
+	var got *decodeTarget
+	ctx := newTestCtx("POST", "http://localhost/users")
+	ctx.Request.Header.SetContentType(contentType)
+	ctx.Request.SetBodyString(body)
+	New(Decode(func() interface{} { return new(decodeTarget) })).Then(func(ctx *fasthttp.RequestCtx) {
+		got, _ = DecodedBody(ctx).(*decodeTarget)
+	})(ctx)
+	return ctx, got
+}
+
+func TestDecodeFormats(t *testing.T) {
+	want := &decodeTarget{Name: "alice", Age: 30, Tags: []string{"a", "b"}}
+
+	_, got := decodeRequest("application/json; charset=utf-8", `{"name":"alice","age":30,"tags":["a","b"]}`)
+	assert.Equal(t, want, got, "JSON bodies should be decoded")
+
+	_, got = decodeRequest("application/xml", `<user><name>alice</name><age>30</age><tag>a</tag><tag>b</tag></user>`)
+	assert.Equal(t, want, got, "XML bodies should be decoded")
+
+	_, got = decodeRequest("application/x-www-form-urlencoded", "name=alice&age=30&tag=a&tag=b&Admin=true")
+	assert.Equal(t, want, got, "Form bodies should be decoded")
+}
+
+func TestDecodeErrors(t *testing.T) {
+	ctx, got := decodeRequest("text/plain", "alice")
+	assert.Equal(t, fasthttp.StatusUnsupportedMediaType, ctx.Response.StatusCode(), "Unknown content types should be rejected")
+	assert.Nil(t, got, "Rejected requests should not reach the app")
+
+	ctx, _ = decodeRequest("application/json", `{"name":`)
+	assert.Equal(t, fasthttp.StatusBadRequest, ctx.Response.StatusCode(), "Malformed bodies should be rejected")
+
+	ctx, _ = decodeRequest("application/x-www-form-urlencoded", "age=old")
+	assert.Equal(t, fasthttp.StatusBadRequest, ctx.Response.StatusCode(), "Malformed form values should be rejected")
+}
+
+func TestDecodeRestrictedFormats(t *testing.T) {
+	var called bool
+	ctx := newTestCtx("POST", "http://localhost/users")
+	ctx.Request.Header.SetContentType("application/xml")
+	ctx.Request.SetBodyString("<user/>")
+	New(Decode(func() interface{} { return new(decodeTarget) }, JSONFormat)).Then(func(ctx *fasthttp.RequestCtx) {
+		called = true
+	})(ctx)
+	assert.False(t, called, "Formats not listed should be rejected")
+	assert.Equal(t, fasthttp.StatusUnsupportedMediaType, ctx.Response.StatusCode(), "Formats not listed should be rejected")
+}
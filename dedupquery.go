@@ -0,0 +1,67 @@
+package fastalice
+
+import (
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DedupStrategy selects how DedupQuery collapses
+// repeated query arguments.
+type DedupStrategy int
+
+const (
+	// DedupFirstWins keeps the first value of a repeated argument.
+	DedupFirstWins DedupStrategy = iota
+	// DedupLastWins keeps the last value of a repeated argument.
+	DedupLastWins
+	// DedupJoin joins all the values of a repeated argument
+	// with commas, in order.
+	DedupJoin
+)
+
+// DedupQuery returns a constructor that collapses
+// repeated query arguments according to strategy,
+// rewriting the query string before the following handlers run.
+// Arguments keep the position of their first occurrence.
+func DedupQuery(strategy DedupStrategy) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			dedupQuery(ctx, strategy)
+			next(ctx)
+		}
+	}
+}
+
+func dedupQuery(ctx *fasthttp.RequestCtx, strategy DedupStrategy) {
+	var (
+		keys   []string
+		values = make(map[string][]string)
+	)
+	ctx.QueryArgs().VisitAll(func(k, v []byte) {
+		key := string(k)
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+		values[key] = append(values[key], string(v))
+	})
+	if len(keys) == ctx.QueryArgs().Len() {
+		return
+	}
+
+	args := fasthttp.AcquireArgs()
+	defer fasthttp.ReleaseArgs(args)
+
+	for _, key := range keys {
+		vs := values[key]
+		switch strategy {
+		case DedupLastWins:
+			args.Add(key, vs[len(vs)-1])
+		case DedupJoin:
+			args.Add(key, strings.Join(vs, ","))
+		default:
+			args.Add(key, vs[0])
+		}
+	}
+	ctx.URI().SetQueryStringBytes(args.QueryString())
+}
@@ -0,0 +1,41 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func dedupQueryResult(strategy DedupStrategy, uri string) (string, string) {
+	var query, tag string
+	h := New(DedupQuery(strategy)).Then(func(ctx *fasthttp.RequestCtx) {
+		query = string(ctx.URI().QueryString())
+		tag = string(ctx.QueryArgs().Peek("tag"))
+	})
+	h(newTestCtx("GET", uri))
+	return query, tag
+}
+
+func TestDedupQueryFirstWins(t *testing.T) {
+	query, tag := dedupQueryResult(DedupFirstWins, "http://localhost/?tag=a&page=1&tag=b")
+	assert.Equal(t, "tag=a&page=1", query, "Only the first value should be kept")
+	assert.Equal(t, "a", tag, "Handlers should see the first value")
+}
+
+func TestDedupQueryLastWins(t *testing.T) {
+	query, tag := dedupQueryResult(DedupLastWins, "http://localhost/?tag=a&page=1&tag=b")
+	assert.Equal(t, "tag=b&page=1", query, "Only the last value should be kept")
+	assert.Equal(t, "b", tag, "Handlers should see the last value")
+}
+
+func TestDedupQueryJoin(t *testing.T) {
+	query, tag := dedupQueryResult(DedupJoin, "http://localhost/?tag=a&page=1&tag=b")
+	assert.Equal(t, "tag=a%2Cb&page=1", query, "Values should be joined")
+	assert.Equal(t, "a,b", tag, "Handlers should see the joined values")
+}
+
+func TestDedupQueryWithoutRepeats(t *testing.T) {
+	query, _ := dedupQueryResult(DedupJoin, "http://localhost/?b=2&a=1")
+	assert.Equal(t, "b=2&a=1", query, "Queries without repeated keys should be left untouched")
+}
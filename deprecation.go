@@ -0,0 +1,139 @@
+package fastalice
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultVersionHeader is the response header carrying
+// the version stamped on a chain by Version.
+const DefaultVersionHeader = "API-Version"
+
+// chainVersionKey holds the version stamped by Version.
+var chainVersionKey = NewKey[string]("chainVersion")
+
+// Version returns a new chain stamping every request with version,
+// such as "v1", in the DefaultVersionHeader response header,
+// so that clients and deprecation events tell which API version served them.
+// The following handlers read it with ChainVersion.
+// The stamp is a constructor named "version", run first.
+//
+//	v1 := api.Version("v1").Prepend(fastalice.Deprecated(nil, "v1 is replaced by v2", sunset))
+func (c Chain) Version(version string) Chain {
+	stamp := func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			Set(ctx, chainVersionKey, version)
+			next(ctx)
+			ctx.Response.Header.Set(DefaultVersionHeader, version)
+		}
+	}
+	return join(namedChain([]NamedConstructor{{Name: "version", Constructor: stamp}}), c)
+}
+
+// ChainVersion returns the version stamped on the chain serving the request,
+// or an empty string if there is none.
+func ChainVersion(ctx *fasthttp.RequestCtx) string {
+	version, _ := Get(ctx, chainVersionKey)
+	return version
+}
+
+// DeprecationEvent describes a request hitting deprecated middleware.
+type DeprecationEvent struct {
+	Message string    `json:"message"`
+	Sunset  time.Time `json:"sunset"`
+	// Version is the version of the chain, see Version.
+	Version   string `json:"version,omitempty"`
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	RequestID string `json:"request_id,omitempty"`
+	// Client is the client IP, to find the consumers to migrate.
+	Client string `json:"client"`
+}
+
+// deprecationHandler holds the handler set by SetDeprecationHandler.
+var deprecationHandler atomic.Value
+
+// deprecationHolder wraps a deprecation handler
+// so that it can be stored in an atomic.Value.
+type deprecationHolder struct {
+	fn func(DeprecationEvent)
+}
+
+// SetDeprecationHandler sets the function called with an event
+// every time a request hits middleware wrapped by Deprecated,
+// such as DeprecationLogger. Passing nil stops the events.
+// It is safe to call while serving requests.
+func SetDeprecationHandler(fn func(DeprecationEvent)) {
+	deprecationHandler.Store(deprecationHolder{fn})
+}
+
+// DeprecationLogger returns a deprecation handler writing events
+// to w as JSON lines.
+func DeprecationLogger(w io.Writer) func(DeprecationEvent) {
+	var mu sync.Mutex
+	return func(e DeprecationEvent) {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		w.Write(append(b, '\n'))
+	}
+}
+
+// Deprecated returns a constructor running c, marking its responses
+// as deprecated so that consumers get to migrate before sunset:
+// they carry a Deprecation header, a Warning header with msg,
+// and a Sunset header unless sunset is zero.
+// An event is sent to the handler set by SetDeprecationHandler for every request.
+//
+// A nil c deprecates the rest of the chain, such as a whole API version:
+//
+//	fastalice.SetDeprecationHandler(fastalice.DeprecationLogger(os.Stderr))
+//	v1 := api.Version("v1").Prepend(fastalice.Deprecated(nil, "v1 is replaced by v2", sunset))
+func Deprecated(c Constructor, msg string, sunset time.Time) Constructor {
+	warning := "299 - " + strconv.Quote(msg)
+	var sunsetDate string
+	if !sunset.IsZero() {
+		sunsetDate = string(fasthttp.AppendHTTPDate(nil, sunset))
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		wrapped := next
+		if c != nil {
+			wrapped = c(next)
+		}
+
+		return func(ctx *fasthttp.RequestCtx) {
+			wrapped(ctx)
+
+			h := &ctx.Response.Header
+			h.Set("Deprecation", "true")
+			h.Add("Warning", warning)
+			if sunsetDate != "" {
+				if current, err := fasthttp.ParseHTTPDate(h.Peek("Sunset")); err != nil || sunset.Before(current) {
+					h.Set("Sunset", sunsetDate)
+				}
+			}
+
+			if holder, ok := deprecationHandler.Load().(deprecationHolder); ok && holder.fn != nil {
+				holder.fn(DeprecationEvent{
+					Message:   msg,
+					Sunset:    sunset,
+					Version:   ChainVersion(ctx),
+					Method:    string(ctx.Method()),
+					Path:      string(ctx.Path()),
+					RequestID: GetRequestID(ctx),
+					Client:    ClientIP(ctx).String(),
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package fastalice
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestDeprecated(t *testing.T) {
+	var buf bytes.Buffer
+	SetDeprecationHandler(DeprecationLogger(&buf))
+	defer SetDeprecationHandler(nil)
+
+	early := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	late := time.Date(2031, 6, 1, 0, 0, 0, 0, time.UTC)
+	h := New(
+		Deprecated(nil, "v1 is replaced by v2", late),
+		Deprecated(tagMiddleware("legacy-auth "), "use OAuth", early),
+	).Version("v1").Then(testApp)
+
+	ctx := newTestCtx("GET", "http://localhost/orders")
+	h(ctx)
+	assert.Equal(t, "legacy-auth app", string(ctx.Response.Body()), "Deprecated middleware should still run")
+	assert.Equal(t, "v1", string(ctx.Response.Header.Peek(DefaultVersionHeader)), "The version should be stamped")
+	assert.Equal(t, "true", string(ctx.Response.Header.Peek("Deprecation")), "Responses should be marked deprecated")
+	assert.Equal(t, "Tue, 01 Jan 2030 00:00:00 GMT", string(ctx.Response.Header.Peek("Sunset")), "The earliest sunset should be announced")
+
+	var warnings []string
+	ctx.Response.Header.VisitAll(func(k, v []byte) {
+		if string(k) == "Warning" {
+			warnings = append(warnings, string(v))
+		}
+	})
+	assert.Equal(t, []string{`299 - "use OAuth"`, `299 - "v1 is replaced by v2"`}, warnings, "Every deprecation should warn")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	assert.Len(t, lines, 2, "Every deprecation should be logged")
+	var e DeprecationEvent
+	assert.NoError(t, json.Unmarshal(lines[1], &e), "Events should be JSON")
+	assert.Equal(t, "v1 is replaced by v2", e.Message, "The message should be logged")
+	assert.Equal(t, "v1", e.Version, "The version should be logged")
+	assert.Equal(t, "/orders", e.Path, "The path should be logged")
+	assert.True(t, late.Equal(e.Sunset), "The sunset should be logged")
+}
+
+func TestChainVersion(t *testing.T) {
+	var version string
+	chain := New(tagMiddleware("t1 ")).Version("2024-01")
+	h := chain.Then(func(ctx *fasthttp.RequestCtx) { version = ChainVersion(ctx) })
+
+	h(newTestCtx("GET", "http://localhost/"))
+	assert.Equal(t, "2024-01", version, "Handlers should read the version")
+	assert.Equal(t, []string{"version", ""}, chain.Names(), "The stamp should be named")
+}
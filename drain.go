@@ -0,0 +1,109 @@
+package fastalice
+
+import (
+	"context"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Drainer tracks the requests in flight through a handler,
+// so a server can stop taking new requests
+// and wait for the current ones before shutting down:
+//
+//	d := fastalice.NewDrainer()
+//	server := &fasthttp.Server{Handler: d.Wrap(chain.Then(app))}
+//	...
+//	d.Shutdown(ctx)
+//	server.Shutdown()
+//
+// Calling Shutdown before fasthttp.Server.Shutdown
+// lets load balancers see 503s and closed connections
+// while requests are drained, and bounds the wait with ctx,
+// which fasthttp.Server.Shutdown does not.
+type Drainer struct {
+	mu       sync.Mutex
+	inFlight int
+	draining bool
+	idle     chan struct{}
+}
+
+// NewDrainer creates a Drainer.
+func NewDrainer() *Drainer {
+	return &Drainer{idle: make(chan struct{})}
+}
+
+// Wrap returns a handler calling h and counting the requests in flight.
+// Once Shutdown was called, new requests are answered
+// with 503 Service Unavailable without calling h,
+// and every response asks the client to close its connection.
+//
+// Wrap the chained handler, not the final one inside the chain,
+// so that draining requests skip the middleware too.
+func (d *Drainer) Wrap(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		d.mu.Lock()
+		if d.draining {
+			d.mu.Unlock()
+			ctx.Error(fasthttp.StatusMessage(fasthttp.StatusServiceUnavailable), fasthttp.StatusServiceUnavailable)
+			closeConnection(ctx)
+			return
+		}
+		d.inFlight++
+		d.mu.Unlock()
+
+		defer d.done(ctx)
+		h(ctx)
+	}
+}
+
+func (d *Drainer) done(ctx *fasthttp.RequestCtx) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.inFlight--
+	if d.draining {
+		closeConnection(ctx)
+		if d.inFlight == 0 {
+			close(d.idle)
+		}
+	}
+}
+
+// InFlight returns the number of requests being served.
+func (d *Drainer) InFlight() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.inFlight
+}
+
+// Shutdown starts rejecting new requests
+// and waits for the requests in flight to finish.
+// It returns ctx.Err() if ctx is done first;
+// requests are still rejected afterwards.
+// Calling Shutdown again waits again.
+func (d *Drainer) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.draining {
+		d.draining = true
+		if d.inFlight == 0 {
+			close(d.idle)
+		}
+	}
+	d.mu.Unlock()
+
+	select {
+	case <-d.idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// closeConnection asks the client to close its connection,
+// unless it is served over HTTP/2, which has no Connection header.
+func closeConnection(ctx *fasthttp.RequestCtx) {
+	if !ProtocolInfo(ctx).HTTP2() {
+		ctx.SetConnectionClose()
+	}
+}
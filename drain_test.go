@@ -0,0 +1,72 @@
+package fastalice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestDrainerShutdown(t *testing.T) {
+	d := NewDrainer()
+	started, release := make(chan struct{}), make(chan struct{})
+	h := d.Wrap(func(ctx *fasthttp.RequestCtx) {
+		close(started)
+		<-release
+		testApp(ctx)
+	})
+
+	inFlight := newTestCtx("GET", "http://localhost/")
+	served := make(chan struct{})
+	go func() {
+		h(inFlight)
+		close(served)
+	}()
+	<-started
+	assert.Equal(t, 1, d.InFlight(), "The request should be counted")
+
+	shutdown := make(chan error)
+	go func() { shutdown <- d.Shutdown(context.Background()) }()
+
+	assert.Eventually(t, func() bool {
+		ctx := newTestCtx("GET", "http://localhost/")
+		h(ctx)
+		return ctx.Response.StatusCode() == fasthttp.StatusServiceUnavailable
+	}, time.Second, time.Millisecond, "New requests should be rejected while draining")
+
+	select {
+	case <-shutdown:
+		t.Fatal("Shutdown should wait for requests in flight")
+	default:
+	}
+
+	close(release)
+	<-served
+	assert.NoError(t, <-shutdown, "Shutdown should return once drained")
+	assert.Equal(t, "app", string(inFlight.Response.Body()), "Requests in flight should complete")
+	assert.True(t, inFlight.Response.ConnectionClose(), "Drained responses should close the connection")
+}
+
+func TestDrainerShutdownIdle(t *testing.T) {
+	d := NewDrainer()
+	assert.NoError(t, d.Shutdown(context.Background()), "An idle drainer should shut down at once")
+	assert.NoError(t, d.Shutdown(context.Background()), "Shutdown should be callable again")
+}
+
+func TestDrainerShutdownTimeout(t *testing.T) {
+	d := NewDrainer()
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go d.Wrap(func(ctx *fasthttp.RequestCtx) {
+		close(started)
+		<-release
+	})(newTestCtx("GET", "http://localhost/"))
+	defer close(release)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, d.Shutdown(ctx), "Shutdown should give up when ctx is done")
+}
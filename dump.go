@@ -0,0 +1,145 @@
+package fastalice
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultDumpBodySize is the number of body bytes
+// written by Dump unless DumpOptions.MaxBodySize is set.
+const DefaultDumpBodySize = 4096
+
+// DefaultDumpRedactedHeaders lists the headers
+// whose values are hidden by Dump unless DumpOptions.RedactHeaders is set.
+var DefaultDumpRedactedHeaders = []string{
+	fasthttp.HeaderAuthorization,
+	fasthttp.HeaderProxyAuthorization,
+	fasthttp.HeaderCookie,
+	fasthttp.HeaderSetCookie,
+}
+
+// dumpRedacted replaces the values of redacted headers.
+const dumpRedacted = "[REDACTED]"
+
+// DumpOptions configures the Dump middleware.
+type DumpOptions struct {
+	// Match, when not nil, selects the requests to dump.
+	// All requests are dumped by default.
+	Match func(ctx *fasthttp.RequestCtx) bool
+	// MaxBodySize is the number of bytes written
+	// of each request and response body,
+	// which defaults to DefaultDumpBodySize.
+	// A negative value leaves bodies out.
+	MaxBodySize int
+	// RedactHeaders lists the headers whose values are hidden,
+	// which defaults to DefaultDumpRedactedHeaders.
+	RedactHeaders []string
+}
+
+// Dump returns a constructor that writes a full dump
+// of the requests matched by opts and of their responses to w,
+// once the following handlers have run.
+// The request is captured before the following handlers run,
+// so that their changes do not show.
+//
+// Each dump is written with a single call to w,
+// serialized across concurrent requests.
+// Bodies of streaming responses are not dumped,
+// nor streamed request bodies kept streaming by Streaming.
+//
+// Dumping is meant for debugging and is costly;
+// to switch it on and off at runtime, name it and add it to
+// or remove it from a SwappableChain:
+//
+//	chain := fastalice.NewNamed(
+//		fastalice.NamedConstructor{Name: "dump", Constructor: fastalice.Dump(os.Stderr, fastalice.DumpOptions{})},
+//		fastalice.NamedConstructor{Name: "auth", Constructor: auth},
+//	)
+//	sc := fastalice.NewSwappable(chain, app)
+//	sc.Swap(chain.Remove("dump"))
+func Dump(w io.Writer, opts DumpOptions) Constructor {
+	maxBody := opts.MaxBodySize
+	if maxBody == 0 {
+		maxBody = DefaultDumpBodySize
+	}
+	names := opts.RedactHeaders
+	if names == nil {
+		names = DefaultDumpRedactedHeaders
+	}
+	redact := make(map[string]bool, len(names))
+	for _, name := range names {
+		redact[strings.ToLower(name)] = true
+	}
+	var mu sync.Mutex
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if opts.Match != nil && !opts.Match(ctx) {
+				next(ctx)
+				return
+			}
+
+			var buf bytes.Buffer
+			req := &ctx.Request
+			fmt.Fprintf(&buf, "%s %s %s\n", req.Header.Method(), ctx.URI().RequestURI(), protocol(ctx))
+			dumpHeaders(&buf, req.Header.VisitAll, redact)
+			if keepStreaming(ctx) {
+				buf.WriteString("[streaming body]\n\n")
+			} else {
+				dumpBody(&buf, req.Body(), maxBody)
+			}
+
+			next(ctx)
+
+			resp := &ctx.Response
+			code := resp.StatusCode()
+			fmt.Fprintf(&buf, "%s %d %s\n", protocol(ctx), code, fasthttp.StatusMessage(code))
+			dumpHeaders(&buf, resp.Header.VisitAll, redact)
+			if IsStreaming(ctx) {
+				buf.WriteString("[streaming body]\n\n")
+			} else {
+				dumpBody(&buf, resp.Body(), maxBody)
+			}
+
+			mu.Lock()
+			w.Write(buf.Bytes())
+			mu.Unlock()
+		}
+	}
+}
+
+// dumpHeaders writes the headers visited by visit to buf,
+// hiding the values of redacted ones.
+func dumpHeaders(buf *bytes.Buffer, visit func(func(k, v []byte)), redact map[string]bool) {
+	visit(func(k, v []byte) {
+		buf.Write(k)
+		buf.WriteString(": ")
+		if redact[strings.ToLower(string(k))] {
+			buf.WriteString(dumpRedacted)
+		} else {
+			buf.Write(v)
+		}
+		buf.WriteByte('\n')
+	})
+	buf.WriteByte('\n')
+}
+
+// dumpBody writes up to max bytes of body to buf,
+// noting how many were left out.
+func dumpBody(buf *bytes.Buffer, body []byte, max int) {
+	if max < 0 || len(body) == 0 {
+		return
+	}
+	if len(body) > max {
+		buf.Write(body[:max])
+		fmt.Fprintf(buf, "\n[%d more bytes]", len(body)-max)
+	} else {
+		buf.Write(body)
+	}
+	buf.WriteString("\n\n")
+}
@@ -0,0 +1,73 @@
+package fastalice
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestDump(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := newTestCtx("POST", "http://localhost/orders?id=1")
+	ctx.Request.Header.Set(fasthttp.HeaderAuthorization, "Bearer secret")
+	ctx.Request.Header.Set("X-Trace", "abc")
+	ctx.Request.SetBodyString("hello")
+
+	New(Dump(&buf, DumpOptions{})).Then(testApp)(ctx)
+
+	out := buf.String()
+	assert.Contains(t, out, "POST /orders?id=1 HTTP/1.1\n", "The request line should be dumped")
+	assert.Contains(t, out, "X-Trace: abc\n", "Request headers should be dumped")
+	assert.Contains(t, out, "Authorization: [REDACTED]\n", "Sensitive headers should be redacted")
+	assert.NotContains(t, out, "secret", "Redacted values should not leak")
+	assert.Contains(t, out, "\nhello\n", "The request body should be dumped")
+	assert.Contains(t, out, "HTTP/1.1 200 OK\n", "The status line should be dumped")
+	assert.Contains(t, out, "\napp\n", "The response body should be dumped")
+}
+
+func TestDumpBodyCap(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := newTestCtx("POST", "http://localhost/")
+	ctx.Request.SetBodyString(strings.Repeat("x", 10))
+
+	New(Dump(&buf, DumpOptions{MaxBodySize: 4})).Then(testApp)(ctx)
+	assert.Contains(t, buf.String(), "xxxx\n[6 more bytes]", "Bodies should be capped")
+
+	buf.Reset()
+	New(Dump(&buf, DumpOptions{MaxBodySize: -1})).Then(testApp)(newTestCtx("GET", "http://localhost/"))
+	assert.NotContains(t, buf.String(), "app", "Bodies should be left out")
+}
+
+func TestDumpMatch(t *testing.T) {
+	var buf bytes.Buffer
+	dump := Dump(&buf, DumpOptions{Match: func(ctx *fasthttp.RequestCtx) bool {
+		return bytes.HasPrefix(ctx.Path(), []byte("/api/"))
+	}})
+
+	New(dump).Then(testApp)(newTestCtx("GET", "http://localhost/static/app.js"))
+	assert.Zero(t, buf.Len(), "Unmatched requests should not be dumped")
+
+	New(dump).Then(testApp)(newTestCtx("GET", "http://localhost/api/orders"))
+	assert.Contains(t, buf.String(), "GET /api/orders", "Matched requests should be dumped")
+}
+
+func TestDumpToggle(t *testing.T) {
+	var buf bytes.Buffer
+	chain := NewNamed(NamedConstructor{Name: "dump", Constructor: Dump(&buf, DumpOptions{})})
+	sc := NewSwappable(Chain{}, testApp)
+
+	sc.Handler()(newTestCtx("GET", "http://localhost/"))
+	assert.Zero(t, buf.Len(), "Requests should not be dumped before swapping dumping in")
+
+	sc.Swap(chain)
+	sc.Handler()(newTestCtx("GET", "http://localhost/"))
+	assert.NotZero(t, buf.Len(), "Requests should be dumped once swapped in")
+
+	buf.Reset()
+	sc.Swap(chain.Remove("dump"))
+	sc.Handler()(newTestCtx("GET", "http://localhost/"))
+	assert.Zero(t, buf.Len(), "Requests should not be dumped once swapped out")
+}
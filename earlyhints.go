@@ -0,0 +1,61 @@
+package fastalice
+
+import (
+	"bytes"
+
+	"github.com/valyala/fasthttp"
+)
+
+// EarlyHints returns a constructor sending the Link header values
+// returned by links, such as "</app.css>; rel=preload; as=style",
+// in a 103 Early Hints response before the following handlers run,
+// so that browsers start fetching them while the page is rendered.
+// The links are also set on the final response when it succeeds,
+// for clients and proxies that ignore or turn them into hints themselves.
+//
+// fasthttp cannot flush responses buffered for earlier pipelined requests,
+// so hints are only written on the first HTTP/1.1 request of a connection,
+// when the browser loads the page;
+// HTTP/1.0 clients, which cannot handle them, and HTTP/2,
+// whose framing is left to its add-on, only get the final links.
+//
+//	chain := fastalice.New(fastalice.EarlyHints(func(ctx *fasthttp.RequestCtx) []string {
+//		return []string{"</static/app.css>; rel=preload; as=style"}
+//	}))
+func EarlyHints(links func(ctx *fasthttp.RequestCtx) []string) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			hints := links(ctx)
+			if len(hints) == 0 {
+				next(ctx)
+				return
+			}
+			if ctx.ConnRequestNum() == 1 && ProtocolInfo(ctx).Version == "HTTP/1.1" {
+				writeEarlyHints(ctx, hints)
+			}
+
+			next(ctx)
+
+			if ctx.Response.StatusCode() < fasthttp.StatusBadRequest && !ctx.Hijacked() {
+				for _, link := range hints {
+					ctx.Response.Header.Add("Link", link)
+				}
+			}
+		}
+	}
+}
+
+// writeEarlyHints writes a 103 Early Hints response
+// straight to the connection of ctx.
+// Write errors are left for the final response to run into.
+func writeEarlyHints(ctx *fasthttp.RequestCtx, links []string) {
+	var b bytes.Buffer
+	b.WriteString("HTTP/1.1 103 Early Hints\r\n")
+	for _, link := range links {
+		b.WriteString("Link: ")
+		b.WriteString(link)
+		b.WriteString("\r\n")
+	}
+	b.WriteString("\r\n")
+	ctx.Conn().Write(b.Bytes())
+}
@@ -0,0 +1,71 @@
+package fastalice
+
+import (
+	"bufio"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestEarlyHints(t *testing.T) {
+	h := New(EarlyHints(func(ctx *fasthttp.RequestCtx) []string {
+		if string(ctx.Path()) == "/api" {
+			return nil
+		}
+		return []string{"</app.css>; rel=preload; as=style", "</app.js>; rel=preload; as=script"}
+	})).Then(testApp)
+
+	ln := fasthttputil.NewInmemoryListener()
+	defer ln.Close()
+	go fasthttp.Serve(ln, h)
+	conn, err := ln.Dial()
+	assert.NoError(t, err, "The listener should accept connections")
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+
+	io.WriteString(conn, "GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	hints := readResponseHead(t, br)
+	assert.Equal(t, []string{
+		"HTTP/1.1 103 Early Hints",
+		"Link: </app.css>; rel=preload; as=style",
+		"Link: </app.js>; rel=preload; as=script",
+	}, hints, "The hints should come first")
+
+	var resp fasthttp.Response
+	assert.NoError(t, resp.Read(br), "The final response should follow")
+	assert.Equal(t, fasthttp.StatusOK, resp.StatusCode(), "The final response should be served")
+	assert.Equal(t, "</app.css>; rel=preload; as=style", string(resp.Header.Peek("Link")), "The links should be set on the final response")
+
+	io.WriteString(conn, "GET /page HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	resp.Reset()
+	assert.NoError(t, resp.Read(br), "The response should be read")
+	assert.Equal(t, fasthttp.StatusOK, resp.StatusCode(), "Later requests on the connection should get no hints")
+	assert.NotEmpty(t, resp.Header.Peek("Link"), "Later requests should still get the links")
+}
+
+func TestEarlyHintsSkipsFailedResponses(t *testing.T) {
+	h := New(EarlyHints(func(ctx *fasthttp.RequestCtx) []string {
+		return []string{"</app.css>; rel=preload; as=style"}
+	})).Then(func(ctx *fasthttp.RequestCtx) {
+		ctx.Error("missing", fasthttp.StatusNotFound)
+	})
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Empty(t, ctx.Response.Header.Peek("Link"), "Failed responses should get no links")
+}
+
+// readResponseHead reads the lines of a response head from br.
+func readResponseHead(t *testing.T, br *bufio.Reader) []string {
+	var lines []string
+	for {
+		line, err := br.ReadString('\n')
+		if !assert.NoError(t, err, "The head should be read") || line == "\r\n" {
+			return lines
+		}
+		lines = append(lines, line[:len(line)-2])
+	}
+}
@@ -0,0 +1,105 @@
+package fastalice
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ErrorFilter classifies an error returned through the error-aware chain:
+// it returns the error to pass on, possibly wrapped or mapped
+// to another one, or nil to suppress it.
+type ErrorFilter func(ctx *fasthttp.RequestCtx, err error) error
+
+// OnError returns error-aware middleware passing the errors
+// returned by the following handlers through filters, in order,
+// before those placed before it in the chain, such as Problems,
+// see them. Once a filter suppresses an error, the following ones
+// are not called.
+//
+//	chained := fastalice.New().AppendErr(
+//		fastalice.Problems(),
+//		fastalice.OnError(fastalice.MapError(sql.ErrNoRows, fasthttp.StatusNotFound, "not_found")),
+//	).ThenErr(h, nil)
+func OnError(filters ...ErrorFilter) ErrorConstructor {
+	return func(next Handler) Handler {
+		return func(ctx *fasthttp.RequestCtx) error {
+			err := next(ctx)
+			for _, filter := range filters {
+				if err == nil {
+					break
+				}
+				err = filter(ctx, err)
+			}
+			return err
+		}
+	}
+}
+
+// MapError returns an ErrorFilter turning errors matching target,
+// as errors.Is reports, into an Error with the given status and code,
+// caused by the original error.
+// Errors already carrying an Error are left as they are.
+func MapError(target error, status int, code string) ErrorFilter {
+	return func(ctx *fasthttp.RequestCtx, err error) error {
+		var e *Error
+		if errors.As(err, &e) || !errors.Is(err, target) {
+			return err
+		}
+		return &Error{Status: status, Code: code, Err: err}
+	}
+}
+
+// SuppressError returns an ErrorFilter suppressing errors
+// matching target, as errors.Is reports,
+// such as context.Canceled when clients go away.
+func SuppressError(target error) ErrorFilter {
+	return func(ctx *fasthttp.RequestCtx, err error) error {
+		if errors.Is(err, target) {
+			return nil
+		}
+		return err
+	}
+}
+
+// PanicError is the error a panic is turned into by RecoverAsError.
+type PanicError struct {
+	// Value is the recovered value.
+	Value interface{}
+	// Stack is the stack trace of the panicking goroutine,
+	// truncated to 64KB.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("fastalice: panic: %v", e.Value)
+}
+
+// Unwrap returns the recovered value when it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// RecoverAsError returns error-aware middleware recovering from panics
+// in the following handlers and returning them as a *PanicError,
+// so that they go through the error filters and renderer
+// placed before it in the chain like any other error.
+func RecoverAsError() ErrorConstructor {
+	return func(next Handler) Handler {
+		return func(ctx *fasthttp.RequestCtx) (err error) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+				stack := make([]byte, maxStackSize)
+				stack = stack[:runtime.Stack(stack, false)]
+				err = &PanicError{Value: r, Stack: stack}
+			}()
+			return next(ctx)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package fastalice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+var errNoRows = errors.New("no rows in result set")
+
+func TestOnErrorMapsErrors(t *testing.T) {
+	h := New().AppendErr(
+		Problems(),
+		OnError(MapError(errNoRows, fasthttp.StatusNotFound, "not_found")),
+	).ThenErr(func(ctx *fasthttp.RequestCtx) error {
+		return fmt.Errorf("loading user: %w", errNoRows)
+	}, nil)
+
+	ctx := newTestCtx("GET", "http://localhost/users/7")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusNotFound, ctx.Response.StatusCode(), "Mapped errors should get their status")
+	assert.Equal(t, "not_found", decodeProblem(t, ctx)["code"], "Mapped errors should get their code")
+}
+
+func TestMapErrorKeepsErrors(t *testing.T) {
+	filter := MapError(errNoRows, fasthttp.StatusNotFound, "not_found")
+	original := NewError(fasthttp.StatusGone, "gone", "").Wrap(errNoRows)
+	assert.Same(t, original, filter(nil, original), "Errors already carrying an Error should be kept")
+
+	other := errors.New("other")
+	assert.Same(t, other, filter(nil, other), "Unrelated errors should be kept")
+}
+
+func TestOnErrorSuppressesAndChains(t *testing.T) {
+	var seen []string
+	record := func(name string) ErrorFilter {
+		return func(ctx *fasthttp.RequestCtx, err error) error {
+			seen = append(seen, name)
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	var handled error
+	h := New().AppendErr(OnError(record("first"), SuppressError(context.Canceled), record("last"))).
+		ThenErr(func(ctx *fasthttp.RequestCtx) error {
+			return context.Canceled
+		}, func(ctx *fasthttp.RequestCtx, err error) { handled = err })
+
+	h(newTestCtx("GET", "http://localhost/"))
+	assert.Equal(t, []string{"first"}, seen, "Filters after a suppression should not run")
+	assert.Nil(t, handled, "Suppressed errors should not reach the error handler")
+}
+
+func TestRecoverAsError(t *testing.T) {
+	var handled error
+	h := New().AppendErr(RecoverAsError()).Append(tagMiddleware("plain")).
+		ThenErr(func(ctx *fasthttp.RequestCtx) error {
+			panic(errNoRows)
+		}, func(ctx *fasthttp.RequestCtx, err error) { handled = err })
+
+	assert.NotPanics(t, func() { h(newTestCtx("GET", "http://localhost/")) }, "Panics should be recovered")
+	var pe *PanicError
+	assert.True(t, errors.As(handled, &pe), "The panic should become a PanicError")
+	assert.NotEmpty(t, pe.Stack, "The stack should be captured")
+	assert.True(t, errors.Is(handled, errNoRows), "A panicking error should be unwrapped")
+}
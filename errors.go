@@ -0,0 +1,99 @@
+package fastalice
+
+import (
+	"github.com/valyala/fasthttp"
+)
+
+// Handler is a request handler that can fail.
+// Returning an error lets the chain handle it centrally,
+// instead of every handler writing its own error response.
+type Handler func(ctx *fasthttp.RequestCtx) error
+
+// ErrorConstructor is a constructor for error-aware middleware.
+// Such middleware can short-circuit a request by returning an error,
+// and inspect, wrap or suppress the errors returned by next.
+type ErrorConstructor func(next Handler) Handler
+
+// handlerErrorKey is the key carrying a pending error
+// through middleware that is not error-aware.
+var handlerErrorKey = NewKey[error]("handlerError")
+
+// setHandlerError marks err as the pending error of the request.
+func setHandlerError(ctx *fasthttp.RequestCtx, err error) {
+	Set(ctx, handlerErrorKey, err)
+}
+
+// takeHandlerError returns and clears the pending error of the request.
+func takeHandlerError(ctx *fasthttp.RequestCtx) error {
+	err, _ := Get(ctx, handlerErrorKey)
+	if err != nil {
+		Delete(ctx, handlerErrorKey)
+	}
+	return err
+}
+
+// DefaultErrorHandler is used by ThenErr when no error handler is given.
+// It answers with 500 Internal Server Error,
+// without leaking the error message to the client.
+func DefaultErrorHandler(ctx *fasthttp.RequestCtx, err error) {
+	ctx.Error(fasthttp.StatusMessage(fasthttp.StatusInternalServerError), fasthttp.StatusInternalServerError)
+}
+
+// ErrorAware adapts an ErrorConstructor into a Constructor,
+// so it can be part of a chain along with regular middleware.
+// Errors travel through regular middleware untouched,
+// up to the nearest error-aware middleware or to ThenErr.
+func ErrorAware(ec ErrorConstructor) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		h := ec(func(ctx *fasthttp.RequestCtx) error {
+			next(ctx)
+			return takeHandlerError(ctx)
+		})
+
+		return func(ctx *fasthttp.RequestCtx) {
+			if err := h(ctx); err != nil {
+				setHandlerError(ctx, err)
+			}
+		}
+	}
+}
+
+// AppendErr extends a chain with error-aware middleware,
+// as the last ones in the request flow.
+//
+// AppendErr returns a new chain, leaving the original one untouched.
+func (c Chain) AppendErr(constructors ...ErrorConstructor) Chain {
+	adapted := make([]Constructor, 0, len(constructors))
+	for _, ec := range constructors {
+		if ec != nil {
+			adapted = append(adapted, ErrorAware(ec))
+		}
+	}
+	return c.Append(adapted...)
+}
+
+// ThenErr chains the middleware with an error-aware handler
+// and returns the final fasthttp.RequestHandler.
+// Any error returned by h or by error-aware middleware,
+// and not handled along the way, is passed to onError,
+// or to DefaultErrorHandler when onError is nil.
+//
+//	chained := fastalice.New(m1).AppendErr(auth).ThenErr(h, renderError)
+func (c Chain) ThenErr(h Handler, onError func(ctx *fasthttp.RequestCtx, err error)) fasthttp.RequestHandler {
+	if onError == nil {
+		onError = DefaultErrorHandler
+	}
+
+	chained := c.Then(func(ctx *fasthttp.RequestCtx) {
+		if err := h(ctx); err != nil {
+			setHandlerError(ctx, err)
+		}
+	})
+
+	return func(ctx *fasthttp.RequestCtx) {
+		chained(ctx)
+		if err := takeHandlerError(ctx); err != nil {
+			onError(ctx, err)
+		}
+	}
+}
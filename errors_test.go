@@ -0,0 +1,96 @@
+package fastalice
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+var errUnauthorized = errors.New("unauthorized")
+
+func renderTestError(ctx *fasthttp.RequestCtx, err error) {
+	status := fasthttp.StatusInternalServerError
+	if errors.Is(err, errUnauthorized) {
+		status = fasthttp.StatusUnauthorized
+	}
+	ctx.Error(err.Error(), status)
+}
+
+func requireToken(next Handler) Handler {
+	return func(ctx *fasthttp.RequestCtx) error {
+		if len(ctx.Request.Header.Peek("X-Token")) == 0 {
+			return errUnauthorized
+		}
+		return next(ctx)
+	}
+}
+
+func TestThenErrShortCircuit(t *testing.T) {
+	h := New(tagMiddleware("t1\n")).AppendErr(requireToken).ThenErr(func(ctx *fasthttp.RequestCtx) error {
+		ctx.WriteString("app")
+		return nil
+	}, renderTestError)
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "Errors returned by middleware should reach the error handler")
+	assert.Equal(t, "unauthorized", string(ctx.Response.Body()), "The error handler should write the response")
+
+	ctx = newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set("X-Token", "abc")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Requests without errors should pass")
+	assert.Equal(t, "t1\napp", string(ctx.Response.Body()), "Requests without errors should reach the handler")
+}
+
+func TestThenErrThroughRegularMiddleware(t *testing.T) {
+	var wrapped error
+	wrap := func(next Handler) Handler {
+		return func(ctx *fasthttp.RequestCtx) error {
+			if err := next(ctx); err != nil {
+				wrapped = fmt.Errorf("wrapped: %w", err)
+				return wrapped
+			}
+			return nil
+		}
+	}
+
+	var handled error
+	h := New().AppendErr(wrap).Append(tagMiddleware("t1\n")).ThenErr(func(ctx *fasthttp.RequestCtx) error {
+		return errUnauthorized
+	}, func(ctx *fasthttp.RequestCtx, err error) {
+		handled = err
+	})
+
+	h(newTestCtx("GET", "http://localhost/"))
+	assert.True(t, errors.Is(wrapped, errUnauthorized), "Error-aware middleware should see errors through regular middleware")
+	assert.Equal(t, wrapped, handled, "The error handler should receive the wrapped error")
+}
+
+func TestThenErrSuppressedError(t *testing.T) {
+	suppress := func(next Handler) Handler {
+		return func(ctx *fasthttp.RequestCtx) error {
+			next(ctx)
+			ctx.SetStatusCode(fasthttp.StatusAccepted)
+			return nil
+		}
+	}
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	New().AppendErr(suppress).ThenErr(func(ctx *fasthttp.RequestCtx) error {
+		return errors.New("ignored")
+	}, nil)(ctx)
+	assert.Equal(t, fasthttp.StatusAccepted, ctx.Response.StatusCode(), "Suppressed errors should not reach the error handler")
+}
+
+func TestThenErrDefaultErrorHandler(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	New().ThenErr(func(ctx *fasthttp.RequestCtx) error {
+		return errors.New("secret detail")
+	}, nil)(ctx)
+	assert.Equal(t, fasthttp.StatusInternalServerError, ctx.Response.StatusCode(), "Unhandled errors should return Internal Server Error")
+	assert.NotContains(t, string(ctx.Response.Body()), "secret detail", "The default handler should not leak error messages")
+}
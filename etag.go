@@ -0,0 +1,90 @@
+package fastalice
+
+import (
+	"bytes"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// etagConfig holds the settings of ETag.
+type etagConfig struct {
+	maxSize int
+}
+
+// ETagOption configures ETag.
+type ETagOption func(*etagConfig)
+
+// ETagMaxSize skips tagging responses whose body
+// is larger than size bytes, sparing the cost of hashing them.
+func ETagMaxSize(size int) ETagOption {
+	return func(c *etagConfig) { c.maxSize = size }
+}
+
+// ETag returns a constructor that tags successful responses
+// with an ETag computed from their body,
+// once the following handlers have run.
+// When weak is true, the tag is marked weak ("W/" prefix),
+// telling clients that equal tags imply equivalent,
+// not byte-identical, responses.
+// A tag already set by the following handlers is kept.
+// GET and HEAD requests whose If-None-Match matches the tag
+// are answered with 304 Not Modified and an empty body.
+//
+// Non-2xx and streaming responses are left untouched,
+// as are responses over the ETagMaxSize limit.
+func ETag(weak bool, opts ...ETagOption) Constructor {
+	var cfg etagConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			next(ctx)
+
+			status := ctx.Response.StatusCode()
+			if status < 200 || status > 299 || IsStreaming(ctx) {
+				return
+			}
+
+			tag := string(ctx.Response.Header.Peek(fasthttp.HeaderETag))
+			if tag == "" {
+				body := ctx.Response.Body()
+				if cfg.maxSize > 0 && len(body) > cfg.maxSize {
+					return
+				}
+				h := fnv.New64a()
+				h.Write(body)
+				tag = `"` + strconv.FormatUint(h.Sum64(), 16) + `"`
+				if weak {
+					tag = "W/" + tag
+				}
+				ctx.Response.Header.Set(fasthttp.HeaderETag, tag)
+			}
+
+			if (ctx.IsGet() || ctx.IsHead()) && etagMatches(ctx.Request.Header.Peek(fasthttp.HeaderIfNoneMatch), tag) {
+				ctx.NotModified()
+				ctx.Response.Header.Set(fasthttp.HeaderETag, tag)
+			}
+		}
+	}
+}
+
+// etagMatches reports whether an If-None-Match header value
+// matches tag, using the weak comparison.
+func etagMatches(ifNoneMatch []byte, tag string) bool {
+	for _, candidate := range bytes.Split(ifNoneMatch, []byte(",")) {
+		candidate = bytes.TrimSpace(candidate)
+		if string(candidate) == "*" {
+			return true
+		}
+		candidate = bytes.TrimPrefix(candidate, []byte("W/"))
+		if string(candidate) == strings.TrimPrefix(tag, "W/") {
+			return true
+		}
+	}
+	return false
+}
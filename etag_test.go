@@ -0,0 +1,73 @@
+package fastalice
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestETagConditionalGet(t *testing.T) {
+	h := New(ETag(false)).Then(testApp)
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	tag := string(ctx.Response.Header.Peek(fasthttp.HeaderETag))
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "The first request should return OK")
+	assert.NotEmpty(t, tag, "The first request should return an ETag")
+	assert.Equal(t, "app", string(ctx.Response.Body()), "The first request should return the body")
+
+	ctx = newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set(fasthttp.HeaderIfNoneMatch, tag)
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusNotModified, ctx.Response.StatusCode(), "A matching If-None-Match should return Not Modified")
+	assert.Empty(t, ctx.Response.Body(), "Not Modified responses should have no body")
+}
+
+func TestETagMismatch(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set(fasthttp.HeaderIfNoneMatch, `"other"`)
+	New(ETag(false)).Then(testApp)(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "A stale If-None-Match should return OK")
+	assert.Equal(t, "app", string(ctx.Response.Body()), "A stale If-None-Match should return the body")
+}
+
+func TestETagSkipsErrors(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(ETag(false)).Then(func(ctx *fasthttp.RequestCtx) {
+		ctx.Error("nope", fasthttp.StatusNotFound)
+	})(ctx)
+	assert.Empty(t, ctx.Response.Header.Peek(fasthttp.HeaderETag), "Error responses should not be tagged")
+}
+
+func TestETagWeak(t *testing.T) {
+	h := New(ETag(true)).Then(testApp)
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	tag := string(ctx.Response.Header.Peek(fasthttp.HeaderETag))
+	assert.True(t, strings.HasPrefix(tag, `W/"`), "Weak tags should be marked")
+
+	ctx = newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set(fasthttp.HeaderIfNoneMatch, strings.TrimPrefix(tag, "W/"))
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusNotModified, ctx.Response.StatusCode(), "Weak comparison should match strong candidates")
+	assert.Equal(t, tag, string(ctx.Response.Header.Peek(fasthttp.HeaderETag)), "Not Modified responses should carry the tag")
+}
+
+func TestETagKeepsHandlerTag(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set(fasthttp.HeaderIfNoneMatch, `"v2"`)
+	New(ETag(false)).Then(func(ctx *fasthttp.RequestCtx) {
+		ctx.Response.Header.Set(fasthttp.HeaderETag, `"v2"`)
+		testApp(ctx)
+	})(ctx)
+	assert.Equal(t, fasthttp.StatusNotModified, ctx.Response.StatusCode(), "Tags set by handlers should be used")
+}
+
+func TestETagMaxSize(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(ETag(false, ETagMaxSize(2))).Then(testApp)(ctx)
+	assert.Empty(t, ctx.Response.Header.Peek(fasthttp.HeaderETag), "Large responses should not be tagged")
+}
@@ -0,0 +1,132 @@
+package fastalice
+
+import (
+	"reflect"
+	"runtime"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ConstructorInfo describes a constructor of a chain,
+// as returned by Explain.
+type ConstructorInfo struct {
+	// Index is the position of the constructor in the request flow.
+	Index int    `json:"index"`
+	Name  string `json:"name,omitempty"`
+	Group string `json:"group,omitempty"`
+	// Func is the name of the constructor function,
+	// such as "github.com/brunvieira/fastalice.Logger.func1".
+	Func string `json:"func"`
+	// Condition is the name of the predicate function
+	// of a constructor added by When, and Conditional
+	// describes the constructors it runs.
+	Condition   string            `json:"condition,omitempty"`
+	Conditional []ConstructorInfo `json:"conditional,omitempty"`
+}
+
+// Explain returns a description of the constructors of the chain,
+// in request flow order, without calling any of them.
+// Constructors added by When are described with their predicate
+// and the constructors they run for matching requests.
+//
+//	for _, info := range chain.Explain() {
+//		fmt.Println(info.Index, info.Name, info.Func)
+//	}
+func (c Chain) Explain() []ConstructorInfo {
+	infos := make([]ConstructorInfo, len(c.constructors))
+	for i, cons := range c.constructors {
+		meta := c.metaAt(i)
+		infos[i] = ConstructorInfo{
+			Index: i,
+			Name:  meta.name,
+			Group: meta.group,
+			Func:  funcName(cons),
+		}
+		if meta.when != nil {
+			infos[i].Condition = funcName(meta.when.predicate)
+			infos[i].Conditional = meta.when.chain.Explain()
+		}
+	}
+	return infos
+}
+
+// funcName returns the name of the function fn.
+func funcName(fn interface{}) string {
+	if f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()); f != nil {
+		return f.Name()
+	}
+	return ""
+}
+
+// TraceEvent describes a middleware invocation
+// traced by TraceRequests.
+type TraceEvent struct {
+	// RequestID is the fasthttp ID of the request.
+	RequestID uint64
+	Index     int
+	Name      string
+	Start     time.Time
+	// Duration is the time spent in the middleware,
+	// including the handlers it called.
+	Duration time.Duration
+	// Next reports whether the middleware called the following handler;
+	// it is false when the middleware short-circuited the request.
+	Next bool
+	// Status is the response status code
+	// when the middleware returned.
+	Status int
+}
+
+// TraceRequests returns a new chain calling sink
+// each time one of its constructors' middleware returns,
+// leaving the original one untouched.
+// Events of a request are emitted innermost first,
+// so the last one is for the first middleware.
+//
+// It is meant to debug ordering and short-circuits,
+// and can be swapped in and out at runtime with a SwappableChain:
+//
+//	sc.Swap(chain.TraceRequests(func(e fastalice.TraceEvent) {
+//		log.Printf("%d %s next=%v status=%d", e.RequestID, e.Name, e.Next, e.Status)
+//	}))
+//
+// sink is called on the request path
+// and may be called concurrently.
+func (c Chain) TraceRequests(sink func(TraceEvent)) Chain {
+	return c.Map(func(i int, name string, cons Constructor) Constructor {
+		return traced(i, name, cons, sink)
+	})
+}
+
+// traced wraps the constructor at index i to trace it.
+func traced(i int, name string, c Constructor, sink func(TraceEvent)) Constructor {
+	key := NewKey[*bool]("trace")
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		inner := c(func(ctx *fasthttp.RequestCtx) {
+			if called, ok := Get(ctx, key); ok {
+				*called = true
+			}
+			next(ctx)
+		})
+
+		return func(ctx *fasthttp.RequestCtx) {
+			called := false
+			Set(ctx, key, &called)
+			start := now()
+			inner(ctx)
+			Delete(ctx, key)
+
+			sink(TraceEvent{
+				RequestID: ctx.ID(),
+				Index:     i,
+				Name:      name,
+				Start:     start,
+				Duration:  now().Sub(start),
+				Next:      called,
+				Status:    ctx.Response.StatusCode(),
+			})
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package fastalice
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func isAPI(ctx *fasthttp.RequestCtx) bool {
+	return bytes.HasPrefix(ctx.Path(), []byte("/api/"))
+}
+
+func TestExplain(t *testing.T) {
+	chain := NewNamed(NamedConstructor{Name: "first", Constructor: tagMiddleware("t1\n")}).
+		When(isAPI, tagMiddleware("t2\n"), tagMiddleware("t3\n")).
+		Append(tagMiddleware("t4\n"))
+
+	infos := chain.Explain()
+	assert.Len(t, infos, 3, "Every constructor should be described")
+	assert.Equal(t, "first", infos[0].Name, "Names should be described")
+	assert.Contains(t, infos[0].Func, "tagMiddleware", "Constructor functions should be described")
+	assert.Equal(t, 1, infos[1].Index, "Positions should be described")
+	assert.Contains(t, infos[1].Condition, "isAPI", "Predicates should be described")
+	assert.Len(t, infos[1].Conditional, 2, "Conditional constructors should be described")
+	assert.Empty(t, infos[2].Condition, "Unconditional constructors should have no predicate")
+}
+
+func TestTraceRequests(t *testing.T) {
+	deny := func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			ctx.SetStatusCode(fasthttp.StatusForbidden)
+		}
+	}
+	chain := NewNamed(
+		NamedConstructor{Name: "tag", Constructor: tagMiddleware("t1\n")},
+		NamedConstructor{Name: "deny", Constructor: deny},
+		NamedConstructor{Name: "never", Constructor: tagMiddleware("t3\n")},
+	)
+
+	var events []TraceEvent
+	chain.TraceRequests(func(e TraceEvent) { events = append(events, e) }).Then(testApp)(newTestCtx("GET", "http://localhost/"))
+
+	if assert.Len(t, events, 2, "Only invoked middleware should be traced") {
+		assert.Equal(t, "deny", events[0].Name, "Events should be emitted innermost first")
+		assert.False(t, events[0].Next, "Short-circuits should be reported")
+		assert.Equal(t, fasthttp.StatusForbidden, events[0].Status, "The status should be reported")
+		assert.Equal(t, "tag", events[1].Name, "Outer middleware should be traced")
+		assert.True(t, events[1].Next, "Calls to the following handler should be reported")
+	}
+}
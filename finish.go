@@ -0,0 +1,37 @@
+package fastalice
+
+import (
+	"github.com/valyala/fasthttp"
+)
+
+// finishHooksKey is the key
+// under which finish hooks are registered.
+var finishHooksKey = NewKey[[]func()]("finishHooks")
+
+// OnFinish registers fn to run once the response is fully prepared,
+// but before it is sent.
+// Hooks are run by FinishHooks, in reverse order of registration;
+// they are never run if FinishHooks is not part of the chain.
+func OnFinish(ctx *fasthttp.RequestCtx, fn func()) {
+	hooks, _ := Get(ctx, finishHooksKey)
+	Set(ctx, finishHooksKey, append(hooks, fn))
+}
+
+// FinishHooks returns a constructor that runs the hooks
+// registered with OnFinish once the following handlers have run,
+// last registered first.
+//
+// It should be placed first in the chain,
+// so that every other middleware can register hooks.
+func FinishHooks() Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			next(ctx)
+
+			hooks, _ := Get(ctx, finishHooksKey)
+			for i := len(hooks) - 1; i >= 0; i-- {
+				hooks[i]()
+			}
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestFinishHooksRunInLIFOOrder(t *testing.T) {
+	var order []string
+	register := func(name string) Constructor {
+		return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+			return func(ctx *fasthttp.RequestCtx) {
+				OnFinish(ctx, func() { order = append(order, name) })
+				next(ctx)
+			}
+		}
+	}
+
+	h := New(FinishHooks(), register("first"), register("second")).Then(func(ctx *fasthttp.RequestCtx) {
+		order = append(order, "handler")
+	})
+	h(newTestCtx("GET", "http://localhost/"))
+
+	assert.Equal(t, []string{"handler", "second", "first"}, order, "Hooks should run after the handler in LIFO order")
+}
+
+func TestFinishHooksCanTweakResponse(t *testing.T) {
+	h := New(FinishHooks()).Then(func(ctx *fasthttp.RequestCtx) {
+		OnFinish(ctx, func() { ctx.Response.Header.Set("X-Finished", "yes") })
+		ctx.WriteString("app")
+	})
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, "yes", string(ctx.Response.Header.Peek("X-Finished")), "Hooks should be able to set final headers")
+}
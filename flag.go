@@ -0,0 +1,266 @@
+package fastalice
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultFlagPollInterval is how often an HTTPFlagProvider
+// fetches its flags unless HTTPFlagOptions.Interval is set.
+const DefaultFlagPollInterval = 30 * time.Second
+
+// FlagState is the state of a feature flag.
+type FlagState struct {
+	Enabled bool `json:"enabled"`
+	// Percent is the share of requests, from 0 to 100,
+	// the flag is on for when enabled.
+	Percent float64 `json:"percent"`
+}
+
+// FlagProvider provides the state of feature flags.
+// Implementations must be safe for concurrent use.
+type FlagProvider interface {
+	// Flag returns the state of the flag name.
+	// Unknown flags are off.
+	Flag(name string) (FlagState, error)
+}
+
+// flagConfig holds the settings of Flagged.
+type flagConfig struct {
+	key func(ctx *fasthttp.RequestCtx) string
+	ttl time.Duration
+}
+
+// FlagOption configures Flagged.
+type FlagOption func(*flagConfig)
+
+// FlagKey selects requests by a hash of the key returned by fn,
+// such as a user ID, instead of at random,
+// so that a key keeps seeing the middleware as the share grows.
+// Requests for which fn returns an empty string are selected at random.
+func FlagKey(fn func(ctx *fasthttp.RequestCtx) string) FlagOption {
+	return func(c *flagConfig) { c.key = fn }
+}
+
+// FlagCacheTTL caches the state of the flag for d
+// instead of asking the provider on every request.
+func FlagCacheTTL(d time.Duration) FlagOption {
+	return func(c *flagConfig) { c.ttl = d }
+}
+
+// Flagged returns a constructor running c only for the requests
+// the flag named flag of provider is on for,
+// so that middleware can be rolled out to a share of traffic
+// and turned on or off without redeploying.
+// Other requests, and every request while the provider fails,
+// go on with the following handlers.
+//
+//	flags := fastalice.NewMemoryFlagProvider()
+//	flags.Set("new-auth", fastalice.FlagState{Enabled: true, Percent: 10})
+//	chain := fastalice.New(fastalice.Flagged("new-auth", flags, newAuth, fastalice.FlagKey(userID)))
+func Flagged(flag string, provider FlagProvider, c Constructor, opts ...FlagOption) Constructor {
+	var cfg flagConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	state := cfg.lookup(flag, provider)
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		wrapped := c(next)
+
+		return func(ctx *fasthttp.RequestCtx) {
+			s, err := state()
+			if err != nil || !s.Enabled || !cfg.selected(ctx, s.Percent) {
+				next(ctx)
+				return
+			}
+			wrapped(ctx)
+		}
+	}
+}
+
+// lookup returns a function returning the state of flag,
+// cached for cfg.ttl.
+func (cfg *flagConfig) lookup(flag string, provider FlagProvider) func() (FlagState, error) {
+	if cfg.ttl <= 0 {
+		return func() (FlagState, error) { return provider.Flag(flag) }
+	}
+
+	var (
+		mu      sync.Mutex
+		cached  FlagState
+		expires time.Time
+	)
+	return func() (FlagState, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if t := now(); !t.Before(expires) {
+			s, err := provider.Flag(flag)
+			if err != nil {
+				return FlagState{}, err
+			}
+			cached, expires = s, t.Add(cfg.ttl)
+		}
+		return cached, nil
+	}
+}
+
+// selected reports whether the request falls in the percent share.
+func (cfg *flagConfig) selected(ctx *fasthttp.RequestCtx, percent float64) bool {
+	if percent >= 100 {
+		return true
+	}
+	if cfg.key != nil {
+		if key := cfg.key(ctx); key != "" {
+			h := fnv.New64a()
+			h.Write([]byte(key))
+			return float64(h.Sum64())/math.MaxUint64*100 < percent
+		}
+	}
+	return randFloat64()*100 < percent
+}
+
+// MemoryFlagProvider is a FlagProvider holding flags in memory,
+// set by the application, such as from an admin endpoint.
+type MemoryFlagProvider struct {
+	flags atomic.Value // map[string]FlagState
+	mu    sync.Mutex   // serializes Set
+}
+
+// NewMemoryFlagProvider returns a MemoryFlagProvider with every flag off.
+func NewMemoryFlagProvider() *MemoryFlagProvider {
+	p := &MemoryFlagProvider{}
+	p.flags.Store(map[string]FlagState{})
+	return p
+}
+
+// Set sets the state of the flag name.
+func (p *MemoryFlagProvider) Set(name string, state FlagState) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	old := p.flags.Load().(map[string]FlagState)
+	flags := make(map[string]FlagState, len(old)+1)
+	for n, s := range old {
+		flags[n] = s
+	}
+	flags[name] = state
+	p.flags.Store(flags)
+}
+
+// Flag returns the state of the flag name.
+func (p *MemoryFlagProvider) Flag(name string) (FlagState, error) {
+	return p.flags.Load().(map[string]FlagState)[name], nil
+}
+
+// HTTPFlagOptions configures an HTTPFlagProvider.
+type HTTPFlagOptions struct {
+	// Interval is how often the flags are fetched,
+	// which defaults to DefaultFlagPollInterval.
+	Interval time.Duration
+	// Client fetches the flags. It defaults to a new fasthttp.Client.
+	Client *fasthttp.Client
+	// OnError is called with the errors of failed fetches,
+	// after which the last fetched flags are kept.
+	OnError func(err error)
+}
+
+// HTTPFlagProvider is a FlagProvider polling its flags from a URL
+// serving a JSON object mapping flag names to their state:
+//
+//	{"new-auth": {"enabled": true, "percent": 10}}
+//
+// Flags enabled without a percent are on for every request.
+type HTTPFlagProvider struct {
+	url   string
+	opts  HTTPFlagOptions
+	flags atomic.Value // map[string]FlagState
+	stop  chan struct{}
+	once  sync.Once
+}
+
+// NewHTTPFlagProvider returns an HTTPFlagProvider polling url,
+// once it fetched the flags successfully.
+// An error is returned if that first fetch fails.
+// Close stops the polling.
+func NewHTTPFlagProvider(url string, opts HTTPFlagOptions) (*HTTPFlagProvider, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultFlagPollInterval
+	}
+	if opts.Client == nil {
+		opts.Client = &fasthttp.Client{}
+	}
+	p := &HTTPFlagProvider{url: url, opts: opts, stop: make(chan struct{})}
+	if err := p.fetch(); err != nil {
+		return nil, err
+	}
+	go p.poll()
+	return p, nil
+}
+
+// Flag returns the state of the flag name as last fetched.
+func (p *HTTPFlagProvider) Flag(name string) (FlagState, error) {
+	return p.flags.Load().(map[string]FlagState)[name], nil
+}
+
+// Close stops polling the flags. The last fetched flags are kept.
+func (p *HTTPFlagProvider) Close() error {
+	p.once.Do(func() { close(p.stop) })
+	return nil
+}
+
+func (p *HTTPFlagProvider) poll() {
+	ticker := time.NewTicker(p.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.fetch(); err != nil && p.opts.OnError != nil {
+				p.opts.OnError(err)
+			}
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// fetch fetches the flags and publishes them.
+func (p *HTTPFlagProvider) fetch() error {
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(p.url)
+	req.Header.Set(fasthttp.HeaderAccept, "application/json")
+	if err := p.opts.Client.DoTimeout(req, resp, p.opts.Interval); err != nil {
+		return fmt.Errorf("fastalice: cannot fetch flags: %w", err)
+	}
+	if resp.StatusCode() != fasthttp.StatusOK {
+		return fmt.Errorf("fastalice: cannot fetch flags: status %d", resp.StatusCode())
+	}
+
+	var raw map[string]struct {
+		Enabled bool     `json:"enabled"`
+		Percent *float64 `json:"percent"`
+	}
+	if err := json.Unmarshal(resp.Body(), &raw); err != nil {
+		return fmt.Errorf("fastalice: invalid flags: %w", err)
+	}
+	flags := make(map[string]FlagState, len(raw))
+	for name, f := range raw {
+		s := FlagState{Enabled: f.Enabled, Percent: 100}
+		if f.Percent != nil {
+			s.Percent = *f.Percent
+		}
+		flags[name] = s
+	}
+	p.flags.Store(flags)
+	return nil
+}
@@ -0,0 +1,178 @@
+package fastalice
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+// countingProvider wraps a FlagProvider counting its lookups.
+type countingProvider struct {
+	FlagProvider
+	calls int
+	err   error
+}
+
+func (p *countingProvider) Flag(name string) (FlagState, error) {
+	p.calls++
+	if p.err != nil {
+		return FlagState{}, p.err
+	}
+	return p.FlagProvider.Flag(name)
+}
+
+func TestFlaggedFollowsProvider(t *testing.T) {
+	flags := NewMemoryFlagProvider()
+	h := New(Flagged("tag", flags, tagMiddleware("flagged"))).Then(testApp)
+
+	ctx := newTestCtx("GET", "/")
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "An unknown flag should be off")
+
+	flags.Set("tag", FlagState{Enabled: true, Percent: 100})
+	ctx = newTestCtx("GET", "/")
+	h(ctx)
+	assert.Equal(t, "flaggedapp", string(ctx.Response.Body()), "The middleware should run once the flag is on")
+
+	flags.Set("tag", FlagState{Enabled: false, Percent: 100})
+	ctx = newTestCtx("GET", "/")
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "The middleware should be skipped once the flag is off")
+}
+
+func TestFlaggedPercent(t *testing.T) {
+	flags := NewMemoryFlagProvider()
+	flags.Set("tag", FlagState{Enabled: true, Percent: 25})
+	h := New(Flagged("tag", flags, tagMiddleware("flagged"))).Then(testApp)
+
+	defer fakeRand(0.2)()
+	ctx := newTestCtx("GET", "/")
+	h(ctx)
+	assert.Equal(t, "flaggedapp", string(ctx.Response.Body()), "Requests within the share should run the middleware")
+
+	randFloat64 = func() float64 { return 0.3 }
+	ctx = newTestCtx("GET", "/")
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Requests outside of the share should skip the middleware")
+}
+
+func TestFlaggedKeyIsSticky(t *testing.T) {
+	flags := NewMemoryFlagProvider()
+	key := FlagKey(func(ctx *fasthttp.RequestCtx) string { return string(ctx.QueryArgs().Peek("user")) })
+	h := New(Flagged("tag", flags, tagMiddleware("flagged"), key)).Then(testApp)
+
+	seen := func(percent float64) map[string]bool {
+		flags.Set("tag", FlagState{Enabled: true, Percent: percent})
+		on := map[string]bool{}
+		for _, user := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+			ctx := newTestCtx("GET", "/?user="+user)
+			h(ctx)
+			on[user] = string(ctx.Response.Body()) == "flaggedapp"
+		}
+		return on
+	}
+
+	low, high := seen(30), seen(70)
+	assert.Equal(t, low, seen(30), "A key should get the same answer for the same share")
+	for user, on := range low {
+		if on {
+			assert.True(t, high[user], "A key selected at %d%% should stay selected as the share grows", 30)
+		}
+	}
+	assert.NotEqual(t, low, high, "A larger share should select more keys")
+}
+
+func TestFlaggedCacheTTL(t *testing.T) {
+	defer fakeClock(time.Second)()
+	memory := NewMemoryFlagProvider()
+	memory.Set("tag", FlagState{Enabled: true, Percent: 100})
+	provider := &countingProvider{FlagProvider: memory}
+	h := New(Flagged("tag", provider, tagMiddleware("flagged"), FlagCacheTTL(3*time.Second))).Then(testApp)
+
+	for i := 0; i < 3; i++ {
+		h(newTestCtx("GET", "/"))
+	}
+	assert.Equal(t, 1, provider.calls, "The flag should be cached for the TTL")
+
+	h(newTestCtx("GET", "/"))
+	assert.Equal(t, 2, provider.calls, "The flag should be looked up again once the TTL expired")
+}
+
+func TestFlaggedProviderError(t *testing.T) {
+	provider := &countingProvider{err: errors.New("down")}
+	h := New(Flagged("tag", provider, tagMiddleware("flagged"))).Then(testApp)
+
+	ctx := newTestCtx("GET", "/")
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "The middleware should be skipped while the provider fails")
+}
+
+func TestHTTPFlagProvider(t *testing.T) {
+	ln := fasthttputil.NewInmemoryListener()
+	defer ln.Close()
+	doc := make(chan string, 1)
+	doc <- `{"tag": {"enabled": true}, "half": {"enabled": true, "percent": 50}}`
+	current := ""
+	go fasthttp.Serve(ln, func(ctx *fasthttp.RequestCtx) {
+		select {
+		case current = <-doc:
+		default:
+		}
+		if current == "" {
+			ctx.Error("unavailable", fasthttp.StatusServiceUnavailable)
+			return
+		}
+		ctx.SetContentType("application/json")
+		ctx.WriteString(current)
+	})
+
+	errs := make(chan error, 10)
+	p, err := NewHTTPFlagProvider("http://flags/", HTTPFlagOptions{
+		Interval: 10 * time.Millisecond,
+		Client:   &fasthttp.Client{Dial: func(addr string) (net.Conn, error) { return ln.Dial() }},
+		OnError:  func(err error) { errs <- err },
+	})
+	assert.NoError(t, err, "The provider should fetch the flags")
+	defer p.Close()
+
+	s, _ := p.Flag("tag")
+	assert.Equal(t, FlagState{Enabled: true, Percent: 100}, s, "A flag without percent should be fully on")
+	s, _ = p.Flag("half")
+	assert.Equal(t, FlagState{Enabled: true, Percent: 50}, s, "The percent should be read")
+	s, _ = p.Flag("unknown")
+	assert.False(t, s.Enabled, "An unknown flag should be off")
+
+	doc <- `{"tag": {"enabled": false}}`
+	assert.Eventually(t, func() bool {
+		s, _ := p.Flag("tag")
+		return !s.Enabled
+	}, time.Second, 5*time.Millisecond, "The flags should be polled")
+
+	doc <- `not json`
+	select {
+	case err := <-errs:
+		assert.Error(t, err, "A failed fetch should be reported")
+	case <-time.After(time.Second):
+		t.Fatal("A failed fetch should be reported")
+	}
+	s, _ = p.Flag("tag")
+	assert.Equal(t, FlagState{Enabled: false, Percent: 100}, s, "The last fetched flags should be kept")
+}
+
+func TestHTTPFlagProviderFirstFetchFails(t *testing.T) {
+	ln := fasthttputil.NewInmemoryListener()
+	defer ln.Close()
+	go fasthttp.Serve(ln, func(ctx *fasthttp.RequestCtx) {
+		ctx.Error("unavailable", fasthttp.StatusServiceUnavailable)
+	})
+
+	_, err := NewHTTPFlagProvider("http://flags/", HTTPFlagOptions{
+		Client: &fasthttp.Client{Dial: func(addr string) (net.Conn, error) { return ln.Dial() }},
+	})
+	assert.Error(t, err, "The provider should fail when the flags cannot be fetched")
+}
@@ -0,0 +1,635 @@
+package fastalice
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+)
+
+// GraphQLOptions configures the GraphQL middleware.
+type GraphQLOptions struct {
+	// MaxDepth is the maximum nesting of the selection sets
+	// of an operation, fragments included. Zero means no limit.
+	MaxDepth int
+	// MaxComplexity is the maximum number of fields
+	// an operation selects, fragments included. Zero means no limit.
+	MaxComplexity int
+	// PersistedQueries enables automatic persisted queries (APQ)
+	// when set, caching queries by their SHA-256 hash.
+	PersistedQueries PersistedQueryStore
+}
+
+// PersistedQueryStore holds the queries of automatic persisted queries
+// by their hex-encoded SHA-256 hash.
+// Implementations must be safe for concurrent use.
+type PersistedQueryStore interface {
+	// Load returns the query saved under hash, if any.
+	Load(hash string) (query string, ok bool)
+	// Save records query under hash.
+	Save(hash, query string)
+}
+
+// GraphQLOperation describes an operation of a GraphQL request.
+type GraphQLOperation struct {
+	// Type is "query", "mutation" or "subscription".
+	Type string
+	// Name is the name of the operation, empty when anonymous.
+	Name       string
+	Depth      int
+	Complexity int
+}
+
+// graphQLOperationsKey holds the operations of the request.
+var graphQLOperationsKey = NewKey[[]GraphQLOperation]("graphQLOperations")
+
+// GraphQLOperations returns the operations of the request
+// found by GraphQL, one per request of a batch,
+// such as to label logs or metrics by operation name.
+func GraphQLOperations(ctx *fasthttp.RequestCtx) []GraphQLOperation {
+	ops, _ := Get(ctx, graphQLOperationsKey)
+	return ops
+}
+
+// graphQLRequest is a GraphQL request over HTTP.
+// Other members are kept as is when the request is rewritten.
+type graphQLRequest map[string]json.RawMessage
+
+// GraphQL returns a constructor that parses GraphQL requests,
+// sent as JSON or application/graphql with POST, or in the query string
+// with GET, before the terminal GraphQL handler executes them.
+//
+// It records their operations, read with GraphQLOperations,
+// and answers requests exceeding opts.MaxDepth or opts.MaxComplexity,
+// or that do not parse, with 400 Bad Request and a GraphQL error body,
+// without calling the following handlers.
+//
+// With opts.PersistedQueries, requests sending only the hash
+// of a known query get it filled in, so that the following handlers
+// see a regular request; unknown hashes are answered with
+// the PERSISTED_QUERY_NOT_FOUND error clients expect
+// before retrying with the full query, which is then saved.
+//
+// The query is only parsed enough to find operations and measure them:
+// it is not validated against a schema.
+func GraphQL(opts GraphQLOptions) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			reqs, batch, err := readGraphQL(ctx)
+			if err != nil {
+				graphQLError(ctx, fasthttp.StatusBadRequest, err.Error(), "")
+				return
+			}
+
+			rewritten := false
+			ops := make([]GraphQLOperation, 0, len(reqs))
+			for _, req := range reqs {
+				query, code, err := resolveGraphQLQuery(req, opts.PersistedQueries)
+				switch {
+				case code != "":
+					graphQLError(ctx, fasthttp.StatusOK, err.Error(), code)
+					return
+				case err != nil:
+					graphQLError(ctx, fasthttp.StatusBadRequest, err.Error(), "")
+					return
+				}
+				if _, ok := req["query"]; !ok {
+					req["query"], _ = json.Marshal(query)
+					rewritten = true
+				}
+
+				var name string
+				json.Unmarshal(req["operationName"], &name)
+				op, err := analyzeGraphQL(query, name)
+				if err != nil {
+					graphQLError(ctx, fasthttp.StatusBadRequest, err.Error(), "GRAPHQL_PARSE_FAILED")
+					return
+				}
+				if opts.MaxDepth > 0 && op.Depth > opts.MaxDepth {
+					graphQLError(ctx, fasthttp.StatusBadRequest, fmt.Sprintf("query depth %d exceeds the maximum of %d", op.Depth, opts.MaxDepth), "")
+					return
+				}
+				if opts.MaxComplexity > 0 && op.Complexity > opts.MaxComplexity {
+					graphQLError(ctx, fasthttp.StatusBadRequest, fmt.Sprintf("query complexity %d exceeds the maximum of %d", op.Complexity, opts.MaxComplexity), "")
+					return
+				}
+				ops = append(ops, op)
+			}
+
+			if rewritten {
+				writeGraphQL(ctx, reqs, batch)
+			}
+			Set(ctx, graphQLOperationsKey, ops)
+			next(ctx)
+		}
+	}
+}
+
+// readGraphQL returns the GraphQL requests of ctx,
+// reporting whether they were sent as a batch.
+func readGraphQL(ctx *fasthttp.RequestCtx) ([]graphQLRequest, bool, error) {
+	if ctx.IsGet() {
+		req := graphQLRequest{}
+		args := ctx.QueryArgs()
+		if q := args.Peek("query"); q != nil {
+			req["query"], _ = json.Marshal(string(q))
+		}
+		if name := args.Peek("operationName"); name != nil {
+			req["operationName"], _ = json.Marshal(string(name))
+		}
+		for _, member := range []string{"variables", "extensions"} {
+			if v := args.Peek(member); len(v) > 0 {
+				if !json.Valid(v) {
+					return nil, false, fmt.Errorf("invalid %s", member)
+				}
+				req[member] = append(json.RawMessage(nil), v...)
+			}
+		}
+		return []graphQLRequest{req}, false, nil
+	}
+
+	body := ctx.PostBody()
+	if mediaType(ctx.Request.Header.ContentType()) == "application/graphql" {
+		q, _ := json.Marshal(string(body))
+		return []graphQLRequest{{"query": q}}, false, nil
+	}
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		var reqs []graphQLRequest
+		if err := json.Unmarshal(body, &reqs); err != nil || len(reqs) == 0 {
+			return nil, false, errors.New("invalid batched GraphQL request")
+		}
+		return reqs, true, nil
+	}
+	var req graphQLRequest
+	if err := json.Unmarshal(body, &req); err != nil || req == nil {
+		return nil, false, errors.New("invalid GraphQL request")
+	}
+	return []graphQLRequest{req}, false, nil
+}
+
+// writeGraphQL replaces the request of ctx with reqs,
+// in the query string of GET requests or else as a JSON body.
+func writeGraphQL(ctx *fasthttp.RequestCtx, reqs []graphQLRequest, batch bool) {
+	if ctx.IsGet() {
+		var query string
+		json.Unmarshal(reqs[0]["query"], &query)
+		ctx.QueryArgs().Set("query", query)
+		ctx.URI().SetQueryStringBytes(ctx.QueryArgs().QueryString())
+		return
+	}
+
+	var body []byte
+	if batch {
+		body, _ = json.Marshal(reqs)
+	} else {
+		body, _ = json.Marshal(reqs[0])
+	}
+	ctx.Request.Header.SetContentType("application/json")
+	ctx.Request.SetBody(body)
+}
+
+// resolveGraphQLQuery returns the query of req,
+// loading it from or saving it to store for persisted queries.
+// Errors clients recover from carry an error code.
+func resolveGraphQLQuery(req graphQLRequest, store PersistedQueryStore) (string, string, error) {
+	var query string
+	if raw, ok := req["query"]; ok {
+		if err := json.Unmarshal(raw, &query); err != nil {
+			return "", "", errors.New("invalid query")
+		}
+	}
+
+	var ext struct {
+		PersistedQuery *struct {
+			Version int    `json:"version"`
+			Hash    string `json:"sha256Hash"`
+		} `json:"persistedQuery"`
+	}
+	json.Unmarshal(req["extensions"], &ext)
+	pq := ext.PersistedQuery
+	if pq == nil {
+		if query == "" {
+			return "", "", errors.New("missing query")
+		}
+		return query, "", nil
+	}
+	if store == nil {
+		return "", "PERSISTED_QUERY_NOT_SUPPORTED", errors.New("PersistedQueryNotSupported")
+	}
+	if pq.Version != 1 {
+		return "", "", errors.New("unsupported persisted query version")
+	}
+
+	if query == "" {
+		if query, ok := store.Load(pq.Hash); ok {
+			return query, "", nil
+		}
+		return "", "PERSISTED_QUERY_NOT_FOUND", errors.New("PersistedQueryNotFound")
+	}
+	sum := sha256.Sum256([]byte(query))
+	if hex.EncodeToString(sum[:]) != pq.Hash {
+		return "", "", errors.New("provided sha does not match query")
+	}
+	store.Save(pq.Hash, query)
+	return query, "", nil
+}
+
+// graphQLError answers the request with a GraphQL error body.
+func graphQLError(ctx *fasthttp.RequestCtx, status int, msg, code string) {
+	type graphQLErr struct {
+		Message    string            `json:"message"`
+		Extensions map[string]string `json:"extensions,omitempty"`
+	}
+	e := graphQLErr{Message: msg}
+	if code != "" {
+		e.Extensions = map[string]string{"code": code}
+	}
+	JSON(ctx, status, struct {
+		Errors []graphQLErr `json:"errors"`
+	}{[]graphQLErr{e}})
+}
+
+// memoryPersistedQueryStore is an in-memory PersistedQueryStore.
+type memoryPersistedQueryStore struct {
+	mu      sync.RWMutex
+	max     int
+	queries map[string]string
+}
+
+// NewMemoryPersistedQueryStore returns an in-memory PersistedQueryStore
+// holding up to max queries, forgetting an arbitrary one when full.
+// A max of zero or less means no limit.
+func NewMemoryPersistedQueryStore(max int) PersistedQueryStore {
+	return &memoryPersistedQueryStore{max: max, queries: make(map[string]string)}
+}
+
+func (s *memoryPersistedQueryStore) Load(hash string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	query, ok := s.queries[hash]
+	return query, ok
+}
+
+func (s *memoryPersistedQueryStore) Save(hash, query string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.queries[hash]; !ok && s.max > 0 && len(s.queries) >= s.max {
+		for h := range s.queries {
+			delete(s.queries, h)
+			break
+		}
+	}
+	s.queries[hash] = query
+}
+
+// graphQLToken is a lexical token of a GraphQL document:
+// a punctuator, a name, or kind 's' for strings and 'v' for numbers.
+type graphQLToken struct {
+	kind byte
+	text string
+}
+
+// lexGraphQL splits query into tokens,
+// skipping whitespace, commas and comments.
+func lexGraphQL(query string) ([]graphQLToken, error) {
+	var tokens []graphQLToken
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' || c == 0xEF || c == 0xBB || c == 0xBF:
+			i++
+		case c == '#':
+			for i < len(query) && query[i] != '\n' && query[i] != '\r' {
+				i++
+			}
+		case c == '"':
+			end := -1
+			if len(query) >= i+3 && query[i:i+3] == `"""` {
+				for j := i + 3; j+3 <= len(query); j++ {
+					if query[j] == '\\' && len(query) >= j+4 && query[j+1:j+4] == `"""` {
+						j += 3
+					} else if query[j:j+3] == `"""` {
+						end = j + 3
+						break
+					}
+				}
+			} else {
+				for j := i + 1; j < len(query) && query[j] != '\n'; j++ {
+					if query[j] == '\\' {
+						j++
+					} else if query[j] == '"' {
+						end = j + 1
+						break
+					}
+				}
+			}
+			if end < 0 {
+				return nil, errors.New("unterminated string")
+			}
+			tokens = append(tokens, graphQLToken{'s', query[i:end]})
+			i = end
+		case c == '.':
+			if len(query) < i+3 || query[i:i+3] != "..." {
+				return nil, fmt.Errorf("unexpected %q", c)
+			}
+			tokens = append(tokens, graphQLToken{'.', "..."})
+			i += 3
+		case bytes.IndexByte([]byte("!$&()[]{}:=@|"), c) >= 0:
+			tokens = append(tokens, graphQLToken{c, string(c)})
+			i++
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i + 1
+			for j < len(query) && (query[j] == '_' || query[j] >= 'a' && query[j] <= 'z' || query[j] >= 'A' && query[j] <= 'Z' || query[j] >= '0' && query[j] <= '9') {
+				j++
+			}
+			tokens = append(tokens, graphQLToken{'n', query[i:j]})
+			i = j
+		case c == '-' || c >= '0' && c <= '9':
+			j := i + 1
+			for j < len(query) && (query[j] >= '0' && query[j] <= '9' || query[j] == '.' || query[j] == 'e' || query[j] == 'E' || query[j] == '+' || query[j] == '-') {
+				j++
+			}
+			tokens = append(tokens, graphQLToken{'v', query[i:j]})
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected %q", c)
+		}
+	}
+	return tokens, nil
+}
+
+// graphQLSelection is a field, fragment spread or inline fragment
+// of a selection set.
+type graphQLSelection struct {
+	// spread is the name of a spread fragment.
+	spread string
+	// field reports whether the selection is a field.
+	field    bool
+	children []graphQLSelection
+}
+
+// graphQLParser parses the selection sets of a GraphQL document.
+type graphQLParser struct {
+	tokens []graphQLToken
+	pos    int
+}
+
+func (p *graphQLParser) peek() graphQLToken {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return graphQLToken{}
+}
+
+func (p *graphQLParser) next() graphQLToken {
+	t := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+	return t
+}
+
+// expect consumes a token of the given kind, or fails.
+func (p *graphQLParser) expect(kind byte) (graphQLToken, error) {
+	t := p.next()
+	if t.kind != kind {
+		if t.kind == 0 {
+			return t, errors.New("unexpected end of query")
+		}
+		return t, fmt.Errorf("unexpected %q", t.text)
+	}
+	return t, nil
+}
+
+// skipBalanced skips a group opened by the current token,
+// such as arguments or variable definitions.
+func (p *graphQLParser) skipBalanced(open, close byte) error {
+	if _, err := p.expect(open); err != nil {
+		return err
+	}
+	for depth := 1; depth > 0; {
+		switch p.next().kind {
+		case open:
+			depth++
+		case close:
+			depth--
+		case 0:
+			return errors.New("unexpected end of query")
+		}
+	}
+	return nil
+}
+
+// skipDirectives skips the directives at the current position.
+func (p *graphQLParser) skipDirectives() error {
+	for p.peek().kind == '@' {
+		p.next()
+		if _, err := p.expect('n'); err != nil {
+			return err
+		}
+		if p.peek().kind == '(' {
+			if err := p.skipBalanced('(', ')'); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// selectionSet parses a selection set.
+func (p *graphQLParser) selectionSet() ([]graphQLSelection, error) {
+	if _, err := p.expect('{'); err != nil {
+		return nil, err
+	}
+	var set []graphQLSelection
+	for p.peek().kind != '}' {
+		var sel graphQLSelection
+		if p.peek().kind == '.' {
+			p.next()
+			if t := p.peek(); t.kind == 'n' && t.text != "on" {
+				sel.spread = p.next().text
+				if err := p.skipDirectives(); err != nil {
+					return nil, err
+				}
+				set = append(set, sel)
+				continue
+			}
+			if p.peek().text == "on" {
+				p.next()
+				if _, err := p.expect('n'); err != nil {
+					return nil, err
+				}
+			}
+		} else {
+			sel.field = true
+			if _, err := p.expect('n'); err != nil {
+				return nil, err
+			}
+			if p.peek().kind == ':' {
+				p.next()
+				if _, err := p.expect('n'); err != nil {
+					return nil, err
+				}
+			}
+			if p.peek().kind == '(' {
+				if err := p.skipBalanced('(', ')'); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if err := p.skipDirectives(); err != nil {
+			return nil, err
+		}
+		if p.peek().kind == '{' || !sel.field {
+			children, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			sel.children = children
+		}
+		set = append(set, sel)
+	}
+	p.next()
+	return set, nil
+}
+
+// analyzeGraphQL finds the operation named name in query,
+// or its only operation when name is empty, and measures it.
+func analyzeGraphQL(query, name string) (GraphQLOperation, error) {
+	tokens, err := lexGraphQL(query)
+	if err != nil {
+		return GraphQLOperation{}, err
+	}
+	p := &graphQLParser{tokens: tokens}
+
+	type operation struct {
+		GraphQLOperation
+		set []graphQLSelection
+	}
+	var ops []operation
+	fragments := make(map[string][]graphQLSelection)
+	for p.peek().kind != 0 {
+		var op operation
+		switch t := p.peek(); {
+		case t.kind == '{':
+			op.Type = "query"
+		case t.kind == 'n' && t.text == "fragment":
+			p.next()
+			frag, err := p.expect('n')
+			if err != nil {
+				return GraphQLOperation{}, err
+			}
+			if on, err := p.expect('n'); err != nil || on.text != "on" {
+				return GraphQLOperation{}, errors.New(`expected "on"`)
+			}
+			if _, err := p.expect('n'); err != nil {
+				return GraphQLOperation{}, err
+			}
+			if err := p.skipDirectives(); err != nil {
+				return GraphQLOperation{}, err
+			}
+			set, err := p.selectionSet()
+			if err != nil {
+				return GraphQLOperation{}, err
+			}
+			fragments[frag.text] = set
+			continue
+		case t.kind == 'n' && (t.text == "query" || t.text == "mutation" || t.text == "subscription"):
+			op.Type = p.next().text
+			if p.peek().kind == 'n' {
+				op.Name = p.next().text
+			}
+			if p.peek().kind == '(' {
+				if err := p.skipBalanced('(', ')'); err != nil {
+					return GraphQLOperation{}, err
+				}
+			}
+			if err := p.skipDirectives(); err != nil {
+				return GraphQLOperation{}, err
+			}
+		default:
+			return GraphQLOperation{}, fmt.Errorf("unexpected %q", t.text)
+		}
+		if op.set, err = p.selectionSet(); err != nil {
+			return GraphQLOperation{}, err
+		}
+		ops = append(ops, op)
+	}
+
+	var selected *operation
+	for i := range ops {
+		if ops[i].Name == name || (name == "" && len(ops) == 1) {
+			selected = &ops[i]
+			break
+		}
+	}
+	if selected == nil {
+		if name == "" {
+			return GraphQLOperation{}, errors.New("operationName is required for documents with several operations")
+		}
+		return GraphQLOperation{}, fmt.Errorf("unknown operation %q", name)
+	}
+
+	m := graphQLMeasurer{fragments: fragments, done: make(map[string][2]int), active: make(map[string]bool)}
+	selected.Depth, selected.Complexity, err = m.measure(selected.set)
+	return selected.GraphQLOperation, err
+}
+
+// graphQLMeasurer measures selection sets,
+// expanding each fragment once.
+type graphQLMeasurer struct {
+	fragments map[string][]graphQLSelection
+	// done holds the depth and complexity of measured fragments,
+	// and active the fragments being measured, to detect cycles.
+	done   map[string][2]int
+	active map[string]bool
+}
+
+// measure returns the depth and number of fields of set.
+func (m *graphQLMeasurer) measure(set []graphQLSelection) (depth, complexity int, err error) {
+	for _, sel := range set {
+		var d, c int
+		if sel.spread != "" {
+			if d, c, err = m.fragment(sel.spread); err != nil {
+				return 0, 0, err
+			}
+		} else if d, c, err = m.measure(sel.children); err != nil {
+			return 0, 0, err
+		}
+		if sel.field {
+			d++
+			c++
+		}
+		if d > depth {
+			depth = d
+		}
+		if complexity += c; complexity < 0 {
+			// Saturate instead of overflowing on pathological fragments.
+			complexity = int(^uint(0) >> 1)
+		}
+	}
+	return depth, complexity, nil
+}
+
+func (m *graphQLMeasurer) fragment(name string) (int, int, error) {
+	if r, ok := m.done[name]; ok {
+		return r[0], r[1], nil
+	}
+	set, ok := m.fragments[name]
+	if !ok {
+		return 0, 0, fmt.Errorf("unknown fragment %q", name)
+	}
+	if m.active[name] {
+		return 0, 0, fmt.Errorf("fragment %q spreads itself", name)
+	}
+	m.active[name] = true
+	d, c, err := m.measure(set)
+	m.active[name] = false
+	m.done[name] = [2]int{d, c}
+	return d, c, err
+}
@@ -0,0 +1,137 @@
+package fastalice
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// postGraphQL serves body through h as a JSON GraphQL request.
+func postGraphQL(h fasthttp.RequestHandler, body string) *fasthttp.RequestCtx {
+	ctx := newTestCtx("POST", "http://localhost/graphql")
+	ctx.Request.Header.SetContentType("application/json")
+	ctx.Request.SetBodyString(body)
+	h(ctx)
+	return ctx
+}
+
+func TestAnalyzeGraphQL(t *testing.T) {
+	query := `
+		# comment with { braces }
+		query Hero($ep: Episode = JEDI) @cached {
+			hero(episode: $ep, filter: {name: "}{"}) {
+				name
+				...Friends
+				... on Droid { primaryFunction }
+			}
+		}
+		mutation Like { like(id: 1) { count } }
+		fragment Friends on Character {
+			friends { name, friends { name } }
+		}`
+
+	op, err := analyzeGraphQL(query, "Hero")
+	assert.NoError(t, err, "The query should parse")
+	assert.Equal(t, GraphQLOperation{Type: "query", Name: "Hero", Depth: 4, Complexity: 7}, op, "Fragments should count toward depth and complexity")
+
+	op, err = analyzeGraphQL(query, "Like")
+	assert.NoError(t, err, "The mutation should parse")
+	assert.Equal(t, GraphQLOperation{Type: "mutation", Name: "Like", Depth: 2, Complexity: 2}, op, "The named operation should be measured")
+
+	_, err = analyzeGraphQL(query, "")
+	assert.Error(t, err, "Documents with several operations need an operation name")
+
+	op, err = analyzeGraphQL(`{ me { id } }`, "")
+	assert.NoError(t, err, "Shorthand queries should parse")
+	assert.Equal(t, GraphQLOperation{Type: "query", Depth: 2, Complexity: 2}, op, "Shorthand queries should be anonymous queries")
+
+	_, err = analyzeGraphQL(`{ a { ...F } } fragment F on T { ...F }`, "")
+	assert.Error(t, err, "Fragment cycles should be rejected")
+	_, err = analyzeGraphQL(`{ a { b }`, "")
+	assert.Error(t, err, "Unbalanced queries should be rejected")
+}
+
+func TestGraphQLLimits(t *testing.T) {
+	var seen []GraphQLOperation
+	h := New(GraphQL(GraphQLOptions{MaxDepth: 2, MaxComplexity: 3})).Then(func(ctx *fasthttp.RequestCtx) {
+		seen = GraphQLOperations(ctx)
+		testApp(ctx)
+	})
+
+	ctx := postGraphQL(h, `{"query": "query Me { me { id name } }", "operationName": "Me"}`)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Queries within limits should go on")
+	assert.Equal(t, []GraphQLOperation{{Type: "query", Name: "Me", Depth: 2, Complexity: 3}}, seen, "Handlers should see the operation")
+
+	ctx = postGraphQL(h, `{"query": "{ me { friends { id } } }"}`)
+	assert.Equal(t, fasthttp.StatusBadRequest, ctx.Response.StatusCode(), "Deep queries should be rejected")
+	assert.Contains(t, string(ctx.Response.Body()), "query depth 3 exceeds the maximum of 2", "The error should be reported")
+
+	ctx = postGraphQL(h, `[{"query": "{ a }"}, {"query": "{ a b c d }"}]`)
+	assert.Contains(t, string(ctx.Response.Body()), "query complexity 4 exceeds the maximum of 3", "Every request of a batch should be checked")
+
+	ctx = postGraphQL(h, `{"query": "{ a "}`)
+	assert.Equal(t, fasthttp.StatusBadRequest, ctx.Response.StatusCode(), "Invalid queries should be rejected")
+
+	ctx = newTestCtx("GET", "http://localhost/graphql?query="+url.QueryEscape("{ a }"))
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "GET queries should go on")
+
+	ctx = newTestCtx("POST", "http://localhost/graphql")
+	ctx.Request.Header.SetContentType("application/graphql")
+	ctx.Request.SetBodyString("{ a { b { c } } }")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusBadRequest, ctx.Response.StatusCode(), "application/graphql bodies should be checked")
+}
+
+func TestGraphQLPersistedQueries(t *testing.T) {
+	var body, query string
+	h := New(GraphQL(GraphQLOptions{PersistedQueries: NewMemoryPersistedQueryStore(10)})).Then(func(ctx *fasthttp.RequestCtx) {
+		body = string(ctx.PostBody())
+		query = string(ctx.QueryArgs().Peek("query"))
+	})
+
+	q := "{ me { id } }"
+	sum := sha256.Sum256([]byte(q))
+	ext := `{"persistedQuery": {"version": 1, "sha256Hash": "` + hex.EncodeToString(sum[:]) + `"}}`
+
+	ctx := postGraphQL(h, `{"extensions": `+ext+`}`)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Unknown hashes should be answered with 200")
+	assert.Contains(t, string(ctx.Response.Body()), "PERSISTED_QUERY_NOT_FOUND", "Unknown hashes should be reported to the client")
+
+	ctx = postGraphQL(h, `{"query": "{ me { name } }", "extensions": `+ext+`}`)
+	assert.Contains(t, string(ctx.Response.Body()), "provided sha does not match query", "Mismatching hashes should be rejected")
+
+	postGraphQL(h, `{"query": "`+q+`", "extensions": `+ext+`}`)
+	body = ""
+	postGraphQL(h, `{"extensions": `+ext+`, "variables": {"x": 1}}`)
+	var got map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(body), &got), "The rewritten body should be JSON")
+	assert.Equal(t, q, got["query"], "Known hashes should get their query filled in")
+	assert.Equal(t, map[string]interface{}{"x": float64(1)}, got["variables"], "Other members should be kept")
+
+	ctx = newTestCtx("GET", "http://localhost/graphql?extensions="+url.QueryEscape(ext))
+	h(ctx)
+	assert.Equal(t, q, query, "GET requests should get the query in the query string")
+
+	ctx = postGraphQL(New(GraphQL(GraphQLOptions{})).Then(testApp), `{"extensions": `+ext+`}`)
+	assert.Contains(t, string(ctx.Response.Body()), "PERSISTED_QUERY_NOT_SUPPORTED", "APQ should be refused without a store")
+}
+
+func TestMemoryPersistedQueryStoreBound(t *testing.T) {
+	store := NewMemoryPersistedQueryStore(2)
+	store.Save("a", "1")
+	store.Save("b", "2")
+	store.Save("c", "3")
+	n := 0
+	for _, h := range []string{"a", "b", "c"} {
+		if _, ok := store.Load(h); ok {
+			n++
+		}
+	}
+	assert.Equal(t, 2, n, "The store should hold at most max queries")
+}
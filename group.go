@@ -0,0 +1,63 @@
+package fastalice
+
+import (
+	"github.com/valyala/fasthttp"
+)
+
+// ThenOptions tunes how ThenWith composes a chain.
+type ThenOptions struct {
+	// Disable lists the groups whose constructors are left out.
+	Disable []string
+}
+
+// Group extends a chain, adding the specified constructors
+// as the last ones in the request flow, tagged with group,
+// so that they can be left out together by ThenWith.
+//
+// Group returns a new chain, leaving the original one untouched.
+//
+//	chain := fastalice.New(recoverer).
+//	    Group("observability", logger, metrics).
+//	    Append(auth)
+//	// in tests, skip the observability middleware
+//	h := chain.ThenWith(app, fastalice.ThenOptions{Disable: []string{"observability"}})
+func (c Chain) Group(group string, constructors ...Constructor) Chain {
+	grouped := Chain{constructors: constructors, meta: make([]constructorMeta, len(constructors))}
+	for i := range grouped.meta {
+		grouped.meta[i].group = group
+	}
+	return join(c, grouped)
+}
+
+// Groups returns the group of each constructor of the chain, in order.
+// Constructors outside any group are listed as an empty string.
+func (c Chain) Groups() []string {
+	groups := make([]string, len(c.constructors))
+	for i := range groups {
+		groups[i] = c.groupAt(i)
+	}
+	return groups
+}
+
+// ThenWith is like Then, leaving out the constructors
+// of the groups disabled by opts.
+func (c Chain) ThenWith(h fasthttp.RequestHandler, opts ThenOptions) fasthttp.RequestHandler {
+	return c.WithoutGroups(opts.Disable...).Then(h)
+}
+
+// WithoutGroups returns a new chain without the constructors
+// of the given groups, leaving the original one untouched,
+// such as to disable them at runtime with SwappableChain.Swap.
+func (c Chain) WithoutGroups(groups ...string) Chain {
+	if len(groups) == 0 {
+		return c.Clone()
+	}
+
+	parts := make([]Chain, 0, len(c.constructors))
+	for i := range c.constructors {
+		if g := c.groupAt(i); g == "" || !containsString(groups, g) {
+			parts = append(parts, c.slice(i, i+1))
+		}
+	}
+	return join(parts...)
+}
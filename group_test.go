@@ -0,0 +1,43 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupThenWith(t *testing.T) {
+	chain := New(tagMiddleware("t1\n")).
+		Group("observability", tagMiddleware("log\n"), tagMiddleware("metrics\n")).
+		Group("auth", tagMiddleware("auth\n"))
+
+	assert.Equal(t, []string{"", "observability", "observability", "auth"}, chain.Groups(), "Groups should list every constructor")
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	chain.ThenWith(testApp, ThenOptions{})(ctx)
+	assert.Equal(t, "t1\nlog\nmetrics\nauth\napp", string(ctx.Response.Body()), "No group should be disabled by default")
+
+	ctx = newTestCtx("GET", "http://localhost/")
+	chain.ThenWith(testApp, ThenOptions{Disable: []string{"observability"}})(ctx)
+	assert.Equal(t, "t1\nauth\napp", string(ctx.Response.Body()), "Disabled groups should be left out")
+
+	ctx = newTestCtx("GET", "http://localhost/")
+	chain.Then(testApp)(ctx)
+	assert.Equal(t, "t1\nlog\nmetrics\nauth\napp", string(ctx.Response.Body()), "The chain should be untouched")
+}
+
+func TestGroupsSurviveComposition(t *testing.T) {
+	chain := New(tagMiddleware("")).Group("g", tagMiddleware(""))
+	assert.Equal(t, []string{"", "g", ""}, chain.Append(tagMiddleware("")).Groups(), "Groups should survive Append")
+	assert.Equal(t, []string{"g", ""}, chain.Reverse().Groups(), "Groups should survive Reverse")
+	assert.Equal(t, []string{"", "g"}, chain.Clone().Groups(), "Groups should survive Clone")
+	assert.Equal(t, []string{"", "g", "", "g"}, chain.Extend(chain).Groups(), "Groups should survive Extend")
+}
+
+func TestWithoutGroups(t *testing.T) {
+	chain := New(tagMiddleware("a")).Group("g", tagMiddleware("b"))
+	ctx := newTestCtx("GET", "http://localhost/")
+	chain.WithoutGroups("g").Then(testApp)(ctx)
+	assert.Equal(t, "aapp", string(ctx.Response.Body()), "Constructors of the given groups should be left out")
+	assert.Equal(t, []string{"", "g"}, chain.Groups(), "The original chain should be untouched")
+}
@@ -0,0 +1,86 @@
+package fastalice
+
+import (
+	"sort"
+
+	"github.com/valyala/fasthttp"
+)
+
+// SetHeaders returns a constructor that stamps
+// the given constant headers on every response.
+//
+// Headers are set before the following handlers run,
+// so they act as defaults that downstream handlers can override.
+// The map is copied, so later changes to it have no effect.
+func SetHeaders(headers map[string]string) Constructor {
+	keys := make([]string, 0, len(headers))
+	values := make([]string, 0, len(headers))
+	for k, v := range headers {
+		keys = append(keys, k)
+		values = append(values, v)
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			for i, k := range keys {
+				ctx.Response.Header.Set(k, values[i])
+			}
+			next(ctx)
+		}
+	}
+}
+
+// Headers returns a constructor applying a response header policy
+// once the following handlers have run, when when reports true for the request
+// or when is nil:
+// the headers named by the keys of remove are removed,
+// or only when they hold its value if that value is not empty;
+// then those of set are set, replacing the values of the handlers;
+// then those of add are added next to any existing value.
+//
+//	fastalice.Headers(
+//		map[string]string{"Cache-Control": "no-store"},
+//		map[string]string{"Vary": "Accept-Language"},
+//		map[string]string{"X-Powered-By": ""},
+//		nil,
+//	)
+//
+// fasthttp writes its own Server header when the response has none;
+// set fasthttp.Server.NoDefaultServerHeader when removing it.
+// The maps are copied, so later changes to them have no effect.
+func Headers(set, add, remove map[string]string, when func(ctx *fasthttp.RequestCtx) bool) Constructor {
+	sets, adds, removes := headerPairs(set), headerPairs(add), headerPairs(remove)
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			next(ctx)
+			if when != nil && !when(ctx) {
+				return
+			}
+
+			h := &ctx.Response.Header
+			for _, kv := range removes {
+				if kv[1] == "" || string(h.Peek(kv[0])) == kv[1] {
+					h.Del(kv[0])
+				}
+			}
+			for _, kv := range sets {
+				h.Set(kv[0], kv[1])
+			}
+			for _, kv := range adds {
+				h.Add(kv[0], kv[1])
+			}
+		}
+	}
+}
+
+// headerPairs returns the name and value of every header of m,
+// sorted by name so that they are applied in a stable order.
+func headerPairs(m map[string]string) [][2]string {
+	pairs := make([][2]string, 0, len(m))
+	for k, v := range m {
+		pairs = append(pairs, [2]string{k, v})
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i][0] < pairs[j][0] })
+	return pairs
+}
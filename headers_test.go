@@ -0,0 +1,69 @@
+package fastalice
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestSetHeaders(t *testing.T) {
+	headers := map[string]string{
+		"X-Build":   "1.2.3",
+		"X-Powered": "fastalice",
+	}
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(SetHeaders(headers)).Then(testApp)(ctx)
+	assert.Equal(t, "1.2.3", string(ctx.Response.Header.Peek("X-Build")), "Static headers should be set")
+	assert.Equal(t, "fastalice", string(ctx.Response.Header.Peek("X-Powered")), "Static headers should be set")
+}
+
+func TestSetHeadersCanBeOverridden(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(SetHeaders(map[string]string{"X-Build": "1.2.3"})).Then(func(ctx *fasthttp.RequestCtx) {
+		ctx.Response.Header.Set("X-Build", "custom")
+	})(ctx)
+	assert.Equal(t, "custom", string(ctx.Response.Header.Peek("X-Build")), "Downstream handlers should be able to override static headers")
+}
+
+func TestHeadersPolicy(t *testing.T) {
+	h := New(Headers(
+		map[string]string{"Cache-Control": "no-store"},
+		map[string]string{"Vary": "Accept-Language"},
+		map[string]string{"X-Powered-By": "", "X-Debug": "on"},
+		nil,
+	)).Then(func(ctx *fasthttp.RequestCtx) {
+		ctx.Response.Header.Set("Cache-Control", "max-age=60")
+		ctx.Response.Header.Set("Vary", "Accept-Encoding")
+		ctx.Response.Header.Set("X-Powered-By", "php")
+		ctx.Response.Header.Set("X-Debug", "off")
+	})
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, "no-store", string(ctx.Response.Header.Peek("Cache-Control")), "Set headers should replace the values of the handler")
+	var vary []string
+	ctx.Response.Header.VisitAll(func(k, v []byte) {
+		if string(k) == "Vary" {
+			vary = append(vary, string(v))
+		}
+	})
+	assert.Equal(t, []string{"Accept-Encoding", "Accept-Language"}, vary, "Added headers should be kept next to the values of the handler")
+	assert.Empty(t, ctx.Response.Header.Peek("X-Powered-By"), "Removed headers should be removed whatever their value")
+	assert.Equal(t, "off", string(ctx.Response.Header.Peek("X-Debug")), "Headers removed by value should be kept with another value")
+}
+
+func TestHeadersWhen(t *testing.T) {
+	apiOnly := func(ctx *fasthttp.RequestCtx) bool { return bytes.HasPrefix(ctx.Path(), []byte("/api/")) }
+	h := New(Headers(map[string]string{"Cache-Control": "no-store"}, nil, nil, apiOnly)).Then(testApp)
+
+	ctx := newTestCtx("GET", "http://localhost/api/users")
+	h(ctx)
+	assert.Equal(t, "no-store", string(ctx.Response.Header.Peek("Cache-Control")), "The policy should apply when the predicate holds")
+
+	ctx = newTestCtx("GET", "http://localhost/static/app.js")
+	h(ctx)
+	assert.Empty(t, ctx.Response.Header.Peek("Cache-Control"), "The policy should not apply when the predicate does not hold")
+}
@@ -0,0 +1,162 @@
+package fastalice
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// HealthCheck returns a constructor that answers requests to path
+// without calling the following handlers:
+// with 200 and "ok" when check returns nil,
+// or with 503 and the error message otherwise.
+// A nil check always reports healthy.
+//
+// Requests to any other path pass through.
+func HealthCheck(path string, check func() error) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if string(ctx.Path()) != path {
+				next(ctx)
+				return
+			}
+
+			if check != nil {
+				if err := check(); err != nil {
+					ctx.Error(err.Error(), fasthttp.StatusServiceUnavailable)
+					return
+				}
+			}
+			ctx.SetStatusCode(fasthttp.StatusOK)
+			ctx.SetBodyString("ok")
+		}
+	}
+}
+
+// DefaultHealthTimeout bounds every check run by Health
+// unless HealthOptions sets a timeout.
+const DefaultHealthTimeout = 5 * time.Second
+
+// Checker probes a dependency, such as pinging a database.
+// It should give up once ctx is done.
+type Checker func(ctx context.Context) error
+
+// HealthOptions configures Health.
+type HealthOptions struct {
+	// LivenessPath answers whether the process is alive,
+	// "/livez" by default; it runs LivenessChecks.
+	LivenessPath   string
+	LivenessChecks map[string]Checker
+	// ReadinessPath answers whether the process can serve traffic,
+	// "/readyz" by default; it runs ReadinessChecks.
+	ReadinessPath   string
+	ReadinessChecks map[string]Checker
+	// Timeout bounds every check, DefaultHealthTimeout by default.
+	Timeout time.Duration
+}
+
+// HealthReport is the JSON body written by Health.
+type HealthReport struct {
+	// Status is "ok" when every check passed, "fail" otherwise.
+	Status string                       `json:"status"`
+	Checks map[string]HealthCheckResult `json:"checks,omitempty"`
+}
+
+// HealthCheckResult is the outcome of one check in a HealthReport.
+type HealthCheckResult struct {
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// Health returns a constructor that answers requests
+// to the liveness and readiness paths with a JSON HealthReport,
+// without calling the following handlers:
+// 200 OK when all of their checks pass,
+// 503 Service Unavailable otherwise.
+// Checks run concurrently, each bounded by the timeout;
+// a check still running when it expires fails.
+//
+// Requests to any other path pass through.
+func Health(opts HealthOptions) Constructor {
+	if opts.LivenessPath == "" {
+		opts.LivenessPath = "/livez"
+	}
+	if opts.ReadinessPath == "" {
+		opts.ReadinessPath = "/readyz"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultHealthTimeout
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			var checks map[string]Checker
+			switch string(ctx.Path()) {
+			case opts.LivenessPath:
+				checks = opts.LivenessChecks
+			case opts.ReadinessPath:
+				checks = opts.ReadinessChecks
+			default:
+				next(ctx)
+				return
+			}
+
+			report := runHealthChecks(Context(ctx), checks, opts.Timeout)
+			code := fasthttp.StatusOK
+			if report.Status != "ok" {
+				code = fasthttp.StatusServiceUnavailable
+			}
+			ctx.Response.Header.Set(fasthttp.HeaderCacheControl, "no-store")
+			JSON(ctx, code, report)
+		}
+	}
+}
+
+// runHealthChecks runs checks concurrently and reports their outcome.
+func runHealthChecks(parent context.Context, checks map[string]Checker, timeout time.Duration) HealthReport {
+	report := HealthReport{Status: "ok", Checks: make(map[string]HealthCheckResult, len(checks))}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check Checker) {
+			defer wg.Done()
+
+			c, cancel := context.WithTimeout(parent, timeout)
+			defer cancel()
+			start := now()
+			done := make(chan error, 1)
+			go func() { done <- check(c) }()
+
+			var err error
+			select {
+			case err = <-done:
+			case <-c.Done():
+				err = c.Err()
+			}
+
+			result := HealthCheckResult{
+				Status:     "ok",
+				DurationMS: float64(now().Sub(start)) / float64(time.Millisecond),
+			}
+			if err != nil {
+				result.Status, result.Error = "fail", err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if err != nil {
+				report.Status = "fail"
+			}
+		}(name, check)
+	}
+	wg.Wait()
+	return report
+}
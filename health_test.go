@@ -0,0 +1,90 @@
+package fastalice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestHealthCheckHealthy(t *testing.T) {
+	for _, check := range []func() error{nil, func() error { return nil }} {
+		ctx := newTestCtx("GET", "http://localhost/healthz")
+		New(HealthCheck("/healthz", check)).Then(testApp)(ctx)
+		assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Healthy checks should return OK")
+		assert.Equal(t, "ok", string(ctx.Response.Body()), "Healthy checks should not reach the app")
+	}
+}
+
+func TestHealthCheckFailing(t *testing.T) {
+	check := func() error { return errors.New("database unreachable") }
+
+	ctx := newTestCtx("GET", "http://localhost/healthz")
+	New(HealthCheck("/healthz", check)).Then(testApp)(ctx)
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, ctx.Response.StatusCode(), "Failing checks should return Service Unavailable")
+	assert.Equal(t, "database unreachable", string(ctx.Response.Body()), "Failing checks should report the error")
+}
+
+func TestHealthCheckOtherPath(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/users")
+	New(HealthCheck("/healthz", nil)).Then(testApp)(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Other paths should reach the app")
+}
+
+func TestHealthReports(t *testing.T) {
+	h := New(Health(HealthOptions{
+		LivenessChecks: map[string]Checker{
+			"self": func(ctx context.Context) error { return nil },
+		},
+		ReadinessChecks: map[string]Checker{
+			"db":    func(ctx context.Context) error { return nil },
+			"cache": func(ctx context.Context) error { return errors.New("connection refused") },
+			"slow": func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		},
+		Timeout: 10 * time.Millisecond,
+	})).Then(testApp)
+
+	ctx := newTestCtx("GET", "http://localhost/livez")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Passing checks should return OK")
+	var report HealthReport
+	assert.NoError(t, json.Unmarshal(ctx.Response.Body(), &report))
+	assert.Equal(t, "ok", report.Status, "Passing checks should be reported")
+	assert.Equal(t, "ok", report.Checks["self"].Status, "Every check should be reported")
+
+	ctx = newTestCtx("GET", "http://localhost/readyz")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, ctx.Response.StatusCode(), "Failing checks should return Service Unavailable")
+	report = HealthReport{}
+	assert.NoError(t, json.Unmarshal(ctx.Response.Body(), &report))
+	assert.Equal(t, "fail", report.Status, "Failing checks should be reported")
+	assert.Equal(t, "ok", report.Checks["db"].Status, "Passing checks should be reported")
+	assert.Equal(t, "connection refused", report.Checks["cache"].Error, "Check errors should be reported")
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["slow"].Error, "Slow checks should time out")
+
+	ctx = newTestCtx("GET", "http://localhost/users")
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Other paths should reach the app")
+}
+
+func TestHealthReportsDuration(t *testing.T) {
+	defer fakeClock(25 * time.Millisecond)()
+	h := New(Health(HealthOptions{
+		LivenessChecks: map[string]Checker{
+			"self": func(ctx context.Context) error { return nil },
+		},
+	})).Then(testApp)
+
+	ctx := newTestCtx("GET", "http://localhost/livez")
+	h(ctx)
+	var report HealthReport
+	assert.NoError(t, json.Unmarshal(ctx.Response.Body(), &report))
+	assert.Equal(t, 25.0, report.Checks["self"].DurationMS, "Check durations should be measured with the package clock")
+}
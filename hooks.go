@@ -0,0 +1,45 @@
+package fastalice
+
+import (
+	"github.com/valyala/fasthttp"
+)
+
+// OnBefore returns a new chain that calls fn
+// right before the terminal handler,
+// once every middleware of the chain has run.
+// Middleware appended later run before fn.
+func (c Chain) OnBefore(fn func(ctx *fasthttp.RequestCtx)) Chain {
+	return c.Append(func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			fn(ctx)
+			next(ctx)
+		}
+	})
+}
+
+// OnAfter returns a new chain that calls fn
+// once the terminal handler has returned, as After does.
+// fn also runs when the terminal handler panics,
+// so it suits cleaning up per-request resources.
+func (c Chain) OnAfter(fn func(ctx *fasthttp.RequestCtx)) Chain {
+	return c.Append(After(fn))
+}
+
+// OnPanic returns a new chain that calls fn
+// with the recovered value when the terminal handler panics.
+// The panic then goes on, so that a recovering middleware
+// earlier in the chain, such as RecoverWithStack,
+// can still turn it into a response.
+func (c Chain) OnPanic(fn func(ctx *fasthttp.RequestCtx, recovered interface{})) Chain {
+	return c.Append(func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			defer func() {
+				if r := recover(); r != nil {
+					fn(ctx, r)
+					panic(r)
+				}
+			}()
+			next(ctx)
+		}
+	})
+}
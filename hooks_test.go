@@ -0,0 +1,37 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestHooksRunAroundHandler(t *testing.T) {
+	chain := New(tagMiddleware("t1\n")).
+		OnBefore(func(ctx *fasthttp.RequestCtx) { ctx.WriteString("before\n") }).
+		OnAfter(func(ctx *fasthttp.RequestCtx) { ctx.WriteString("\nafter") })
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	chain.Then(testApp)(ctx)
+	assert.Equal(t, "t1\nbefore\napp\nafter", string(ctx.Response.Body()), "Hooks should run around the handler")
+}
+
+func TestOnPanic(t *testing.T) {
+	var recovered interface{}
+	var cleaned bool
+	chain := New(RecoverWithStack(nil)).
+		OnAfter(func(ctx *fasthttp.RequestCtx) { cleaned = true }).
+		OnPanic(func(ctx *fasthttp.RequestCtx, r interface{}) { recovered = r })
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	chain.Then(panickingApp)(ctx)
+	assert.NotNil(t, recovered, "OnPanic should see the panic")
+	assert.True(t, cleaned, "OnAfter should run when the handler panics")
+	assert.Equal(t, fasthttp.StatusInternalServerError, ctx.Response.StatusCode(), "The panic should reach the recovering middleware")
+
+	recovered = nil
+	ctx = newTestCtx("GET", "http://localhost/")
+	chain.Then(testApp)(ctx)
+	assert.Nil(t, recovered, "OnPanic should not run without a panic")
+}
@@ -0,0 +1,79 @@
+package fastalice
+
+import (
+	"github.com/valyala/fasthttp"
+)
+
+// Protocol describes the protocol a request was received over,
+// as returned by ProtocolInfo.
+type Protocol struct {
+	// Version is "HTTP/1.0", "HTTP/1.1" or "HTTP/2".
+	Version string
+	TLS     bool
+	// ALPN is the protocol negotiated with TLS ALPN, such as "h2".
+	ALPN string
+	// H2C reports whether the request asks to upgrade
+	// its connection to cleartext HTTP/2.
+	H2C bool
+}
+
+// HTTP2 reports whether the request was received over HTTP/2.
+func (p Protocol) HTTP2() bool {
+	return p.Version == "HTTP/2"
+}
+
+// http2Key marks requests served over HTTP/2 by MarkHTTP2.
+var http2Key = NewKey[bool]("http2")
+
+// MarkHTTP2 records that the request is served over HTTP/2.
+// fasthttp only speaks HTTP/1.x: HTTP/2 add-ons, or an adapter
+// in front of them, should call it for every request they hand over
+// when ProtocolInfo cannot tell from the connection,
+// such as for cleartext HTTP/2.
+func MarkHTTP2(ctx *fasthttp.RequestCtx) {
+	Set(ctx, http2Key, true)
+}
+
+// ProtocolInfo returns the protocol of the request.
+// It is HTTP/2 when the request was marked by MarkHTTP2,
+// when TLS negotiated "h2" with ALPN,
+// or for the "PRI" preface of prior-knowledge HTTP/2.
+func ProtocolInfo(ctx *fasthttp.RequestCtx) Protocol {
+	p := Protocol{Version: "HTTP/1.0", TLS: ctx.IsTLS()}
+	if state := ctx.TLSConnectionState(); state != nil {
+		p.ALPN = state.NegotiatedProtocol
+	}
+	marked, _ := Get(ctx, http2Key)
+	switch {
+	case marked || p.ALPN == "h2" || string(ctx.Method()) == "PRI":
+		p.Version = "HTTP/2"
+	case ctx.Request.Header.IsHTTP11():
+		p.Version = "HTTP/1.1"
+		p.H2C = IsUpgrade(ctx) && string(ctx.Request.Header.Peek(fasthttp.HeaderUpgrade)) == "h2c"
+	}
+	return p
+}
+
+// HTTP2Aware wraps a constructor so that it is skipped
+// for requests served over HTTP/2 or upgrading to cleartext HTTP/2,
+// whose responses are framed by the HTTP/2 add-on.
+// Use it for middleware that buffers or rewrites the response body
+// or sets connection-specific headers, which HTTP/2 forbids.
+//
+// Shipped middleware needs no wrapping: responses to HTTP/2 requests
+// count as streaming, see IsStreaming, so Compress, ETag, Cache,
+// Buffer and the like leave them alone,
+// and Drainer does not ask HTTP/2 clients to close their connection.
+func HTTP2Aware(c Constructor) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		wrapped := c(next)
+
+		return func(ctx *fasthttp.RequestCtx) {
+			if p := ProtocolInfo(ctx); p.HTTP2() || p.H2C {
+				next(ctx)
+				return
+			}
+			wrapped(ctx)
+		}
+	}
+}
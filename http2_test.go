@@ -0,0 +1,62 @@
+package fastalice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestProtocolInfo(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	assert.Equal(t, Protocol{Version: "HTTP/1.1"}, ProtocolInfo(ctx), "Plain requests should be HTTP/1.1")
+
+	ctx = newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set(fasthttp.HeaderConnection, "Upgrade, HTTP2-Settings")
+	ctx.Request.Header.Set(fasthttp.HeaderUpgrade, "h2c")
+	assert.True(t, ProtocolInfo(ctx).H2C, "h2c upgrades should be detected")
+
+	ctx = newTestCtx("GET", "http://localhost/")
+	MarkHTTP2(ctx)
+	assert.True(t, ProtocolInfo(ctx).HTTP2(), "Marked requests should be HTTP/2")
+	assert.Equal(t, "HTTP/2", protocol(ctx), "The logged protocol should be HTTP/2")
+
+	ctx = newTestCtx("PRI", "http://localhost/*")
+	assert.True(t, ProtocolInfo(ctx).HTTP2(), "The prior-knowledge preface should be HTTP/2")
+}
+
+func TestHTTP2Aware(t *testing.T) {
+	h := New(HTTP2Aware(tagMiddleware("buffered"))).Then(testApp)
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, "bufferedapp", string(ctx.Response.Body()), "HTTP/1.1 requests should go through the middleware")
+
+	ctx = newTestCtx("GET", "http://localhost/")
+	MarkHTTP2(ctx)
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "HTTP/2 requests should skip the middleware")
+}
+
+func TestShippedMiddlewareOnHTTP2(t *testing.T) {
+	body := make([]byte, 2*DefaultCompressMinSize)
+	h := New(Compress(fasthttp.CompressDefaultCompression), ETag(false)).Then(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetContentType("text/plain")
+		ctx.SetBody(body)
+	})
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set(fasthttp.HeaderAcceptEncoding, "gzip")
+	MarkHTTP2(ctx)
+	h(ctx)
+	assert.Empty(t, ctx.Response.Header.Peek(fasthttp.HeaderContentEncoding), "Compress should leave HTTP/2 responses alone")
+	assert.Empty(t, ctx.Response.Header.Peek(fasthttp.HeaderETag), "ETag should leave HTTP/2 responses alone")
+
+	d := NewDrainer()
+	d.Shutdown(context.Background())
+	ctx = newTestCtx("GET", "http://localhost/")
+	MarkHTTP2(ctx)
+	d.Wrap(testApp)(ctx)
+	assert.False(t, ctx.Response.ConnectionClose(), "HTTP/2 clients should not be asked to close the connection")
+}
@@ -0,0 +1,206 @@
+package fastalice
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// LocaleBundle holds the messages of each supported locale.
+// It is meant to be filled at startup:
+// Add must not be called while requests are served.
+type LocaleBundle struct {
+	defaultLocale string
+	locales       []string
+	// names maps lowercase locales to their registered form.
+	names    map[string]string
+	messages map[string]map[string]string
+}
+
+// NewLocaleBundle creates an empty bundle
+// falling back to defaultLocale, such as "en".
+func NewLocaleBundle(defaultLocale string) *LocaleBundle {
+	b := &LocaleBundle{
+		names:    make(map[string]string),
+		messages: make(map[string]map[string]string),
+	}
+	b.defaultLocale = b.add(defaultLocale)
+	return b
+}
+
+// add registers locale and returns its canonical form.
+func (b *LocaleBundle) add(locale string) string {
+	key := strings.ToLower(locale)
+	if _, ok := b.messages[key]; !ok {
+		b.messages[key] = make(map[string]string)
+		b.names[key] = locale
+		b.locales = append(b.locales, locale)
+	}
+	return key
+}
+
+// Add adds messages, keyed by message key, to locale,
+// such as "en" or "pt-BR", registering it if needed.
+// Messages may hold fmt verbs filled by Translate.
+func (b *LocaleBundle) Add(locale string, messages map[string]string) {
+	m := b.messages[b.add(locale)]
+	for key, msg := range messages {
+		m[key] = msg
+	}
+}
+
+// Locales returns the registered locales, in the order they were added.
+func (b *LocaleBundle) Locales() []string {
+	return append([]string(nil), b.locales...)
+}
+
+// Translate returns the message key in locale,
+// falling back to its base language, then to the default locale
+// and finally to key itself.
+// When args are given, the message is formatted with fmt.Sprintf.
+func (b *LocaleBundle) Translate(locale, key string, args ...interface{}) string {
+	msg := key
+	for _, l := range []string{strings.ToLower(locale), baseLanguage(locale), b.defaultLocale} {
+		if m, ok := b.messages[l][key]; ok {
+			msg = m
+			break
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// match returns the registered locale best matching tag in lowercase,
+// or an empty string if there is none.
+func (b *LocaleBundle) match(tag string) string {
+	tag = strings.ToLower(tag)
+	if _, ok := b.messages[tag]; ok {
+		return tag
+	}
+	base := baseLanguage(tag)
+	if _, ok := b.messages[base]; ok {
+		return base
+	}
+	for _, l := range b.locales {
+		if baseLanguage(l) == base {
+			return strings.ToLower(l)
+		}
+	}
+	return ""
+}
+
+// baseLanguage returns the language of a tag, such as "pt" for "pt-BR".
+func baseLanguage(tag string) string {
+	tag = strings.ToLower(tag)
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		return tag[:i]
+	}
+	return tag
+}
+
+// negotiatedLocale is the locale of a request and the bundle it is from.
+type negotiatedLocale struct {
+	bundle *LocaleBundle
+	locale string
+}
+
+// emptyBundle translates keys into themselves.
+var emptyBundle = NewLocaleBundle("")
+
+// localeKey holds the locale negotiated by I18n.
+var localeKey = NewKey[negotiatedLocale]("locale")
+
+// i18nConfig holds the settings of I18n.
+type i18nConfig struct {
+	cookie string
+	query  string
+}
+
+// I18nOption configures I18n.
+type I18nOption func(*i18nConfig)
+
+// I18nCookie lets the cookie name,
+// such as one set by a language picker,
+// override the Accept-Language header.
+func I18nCookie(name string) I18nOption {
+	return func(c *i18nConfig) { c.cookie = name }
+}
+
+// I18nQuery lets the query argument name, such as "lang",
+// override the Accept-Language header and the cookie.
+func I18nQuery(name string) I18nOption {
+	return func(c *i18nConfig) { c.query = name }
+}
+
+// I18n returns a constructor that negotiates the locale of each request
+// among those of bundle, from the Accept-Language header
+// and the overrides given as options,
+// falling back to the default locale of the bundle.
+//
+// The locale can be read with Locale, and messages
+// translated into it with T. It is also sent
+// in the Content-Language response header.
+func I18n(bundle *LocaleBundle, opts ...I18nOption) Constructor {
+	var cfg i18nConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			locale := bundle.names[negotiateLocale(ctx, bundle, cfg)]
+			Set(ctx, localeKey, negotiatedLocale{bundle, locale})
+			ctx.Response.Header.Add(fasthttp.HeaderVary, fasthttp.HeaderAcceptLanguage)
+			next(ctx)
+			ctx.Response.Header.Set(fasthttp.HeaderContentLanguage, locale)
+		}
+	}
+}
+
+// negotiateLocale returns the locale of the request.
+func negotiateLocale(ctx *fasthttp.RequestCtx, bundle *LocaleBundle, cfg i18nConfig) string {
+	if cfg.query != "" {
+		if l := bundle.match(string(ctx.QueryArgs().Peek(cfg.query))); l != "" {
+			return l
+		}
+	}
+	if cfg.cookie != "" {
+		if l := bundle.match(string(ctx.Request.Header.Cookie(cfg.cookie))); l != "" {
+			return l
+		}
+	}
+
+	ranges := parseAccept(string(ctx.Request.Header.Peek(fasthttp.HeaderAcceptLanguage)))
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+	for _, r := range ranges {
+		if r.q <= 0 || r.typ == "*" {
+			continue
+		}
+		if l := bundle.match(r.typ); l != "" {
+			return l
+		}
+	}
+	return bundle.defaultLocale
+}
+
+// Locale returns the locale negotiated by I18n,
+// or an empty string if there is none.
+func Locale(ctx *fasthttp.RequestCtx) string {
+	n, _ := Get(ctx, localeKey)
+	return n.locale
+}
+
+// T translates the message key into the locale negotiated by I18n,
+// as LocaleBundle.Translate does.
+// Without I18n, key itself is returned.
+func T(ctx *fasthttp.RequestCtx, key string, args ...interface{}) string {
+	n, ok := Get(ctx, localeKey)
+	if !ok {
+		n.bundle = emptyBundle
+	}
+	return n.bundle.Translate(n.locale, key, args...)
+}
@@ -0,0 +1,71 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func testBundle() *LocaleBundle {
+	b := NewLocaleBundle("en")
+	b.Add("en", map[string]string{"hello": "Hello, %s!", "bye": "Bye"})
+	b.Add("pt-BR", map[string]string{"hello": "Olá, %s!"})
+	b.Add("fr", map[string]string{"hello": "Bonjour, %s !"})
+	return b
+}
+
+func TestI18nAcceptLanguage(t *testing.T) {
+	var locale, msg, fallback string
+	h := New(I18n(testBundle())).Then(func(ctx *fasthttp.RequestCtx) {
+		locale, msg, fallback = Locale(ctx), T(ctx, "hello", "Ana"), T(ctx, "bye")
+	})
+
+	for header, want := range map[string]string{
+		"pt-BR,pt;q=0.9,en;q=0.8": "pt-BR",
+		"pt":                      "pt-BR",
+		"de;q=1, fr;q=0.5":        "fr",
+		"en;q=0.1, fr;q=0.9":      "fr",
+		"de":                      "en",
+		"":                        "en",
+	} {
+		ctx := newTestCtx("GET", "http://localhost/")
+		ctx.Request.Header.Set(fasthttp.HeaderAcceptLanguage, header)
+		h(ctx)
+		assert.Equal(t, want, locale, "Accept-Language %q should be negotiated", header)
+		assert.Equal(t, want, string(ctx.Response.Header.Peek(fasthttp.HeaderContentLanguage)), "Content-Language should be set")
+	}
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set(fasthttp.HeaderAcceptLanguage, "pt-BR")
+	h(ctx)
+	assert.Equal(t, "Olá, Ana!", msg, "Messages should be translated and formatted")
+	assert.Equal(t, "Bye", fallback, "Missing messages should fall back to the default locale")
+}
+
+func TestI18nOverrides(t *testing.T) {
+	var locale string
+	h := New(I18n(testBundle(), I18nCookie("lang"), I18nQuery("lang"))).Then(func(ctx *fasthttp.RequestCtx) {
+		locale = Locale(ctx)
+	})
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set(fasthttp.HeaderAcceptLanguage, "en")
+	ctx.Request.Header.SetCookie("lang", "fr")
+	h(ctx)
+	assert.Equal(t, "fr", locale, "The cookie should override Accept-Language")
+
+	ctx = newTestCtx("GET", "http://localhost/?lang=pt-br")
+	ctx.Request.Header.SetCookie("lang", "fr")
+	h(ctx)
+	assert.Equal(t, "pt-BR", locale, "The query should override the cookie")
+
+	ctx = newTestCtx("GET", "http://localhost/?lang=xx")
+	h(ctx)
+	assert.Equal(t, "en", locale, "Unknown overrides should be ignored")
+}
+
+func TestTWithoutI18n(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	assert.Equal(t, "hello", T(ctx, "hello"), "The key should be returned without I18n")
+}
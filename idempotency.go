@@ -0,0 +1,219 @@
+package fastalice
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// IdempotencyWait is how long a request waits for a request
+// with the same Idempotency-Key in flight to finish
+// before Idempotency answers it with 409 Conflict.
+var IdempotencyWait = 30 * time.Second
+
+// idempotencyPollInterval is how often a waiting request
+// checks whether the request in flight finished.
+const idempotencyPollInterval = 20 * time.Millisecond
+
+// IdempotentResponse is a response saved by Idempotency.
+type IdempotentResponse struct {
+	// Fingerprint identifies the request the response answered,
+	// so that a key reused for another request is told apart.
+	Fingerprint string
+	// Status, Header and Body are the saved response.
+	Status int
+	Header map[string][]string
+	Body   []byte
+}
+
+// IdempotencyStore holds the keys of Idempotency:
+// locked while their first request is in flight,
+// then with its response until they expire.
+// Implementations backed by Redis let several servers share them.
+// They must be safe for concurrent use.
+type IdempotencyStore interface {
+	// Begin returns the response saved under key, if any.
+	// Otherwise it locks key for ttl if it is not locked,
+	// reporting whether it did.
+	Begin(key string, ttl time.Duration) (resp *IdempotentResponse, locked bool, err error)
+	// Complete saves resp under key for ttl, unlocking it.
+	Complete(key string, resp *IdempotentResponse, ttl time.Duration) error
+	// Release unlocks key without saving a response,
+	// so that the request can be retried.
+	Release(key string) error
+}
+
+// Idempotency returns a constructor making requests with
+// an unsafe method, such as POST, safe to retry:
+// the first response to a given Idempotency-Key header
+// is saved in store for ttl and replayed to retries,
+// with an Idempotent-Replayed header,
+// without calling the following handlers again.
+// Retries arriving while the first request is in flight
+// wait for its response, up to IdempotencyWait,
+// after which they are answered with 409 Conflict.
+//
+// A key reused with another method, path or body
+// is answered with 422 Unprocessable Entity.
+// Server errors, panics and streamed responses are not saved,
+// so that those requests can be retried.
+// Requests without the header go on as usual.
+//
+// Keys are shared by every client, so clients should
+// generate random keys, such as UUIDs; in front of several tenants,
+// place it after a middleware prefixing the header with the tenant.
+func Idempotency(store IdempotencyStore, ttl time.Duration) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			key := string(ctx.Request.Header.Peek("Idempotency-Key"))
+			if key == "" || csrfSafeMethod(ctx) {
+				next(ctx)
+				return
+			}
+			fingerprint := idempotencyFingerprint(ctx)
+
+			deadline := now().Add(IdempotencyWait)
+			for {
+				saved, locked, err := store.Begin(key, ttl)
+				switch {
+				case err != nil:
+					ctx.Error(fasthttp.StatusMessage(fasthttp.StatusServiceUnavailable), fasthttp.StatusServiceUnavailable)
+					return
+				case saved != nil:
+					if saved.Fingerprint != fingerprint {
+						ctx.Error("Idempotency-Key reused for another request", fasthttp.StatusUnprocessableEntity)
+						return
+					}
+					writeIdempotentResponse(ctx, saved)
+					return
+				case locked:
+					serveIdempotent(ctx, next, store, key, fingerprint, ttl)
+					return
+				}
+				if !now().Before(deadline) {
+					ctx.Error("Request with the same Idempotency-Key in progress", fasthttp.StatusConflict)
+					return
+				}
+				sleep(idempotencyPollInterval)
+			}
+		}
+	}
+}
+
+// serveIdempotent serves the first request with key,
+// saving its response or releasing key.
+func serveIdempotent(ctx *fasthttp.RequestCtx, next fasthttp.RequestHandler, store IdempotencyStore, key, fingerprint string, ttl time.Duration) {
+	saved := false
+	defer func() {
+		if !saved {
+			store.Release(key)
+		}
+	}()
+
+	next(ctx)
+
+	resp := &ctx.Response
+	if resp.StatusCode() >= 500 || IsStreaming(ctx) || ctx.Hijacked() {
+		return
+	}
+	r := &IdempotentResponse{
+		Fingerprint: fingerprint,
+		Status:      resp.StatusCode(),
+		Header:      make(map[string][]string),
+		Body:        append([]byte(nil), resp.Body()...),
+	}
+	resp.Header.VisitAll(func(k, v []byte) {
+		switch name := string(k); name {
+		case fasthttp.HeaderContentLength, fasthttp.HeaderDate, fasthttp.HeaderConnection:
+		default:
+			r.Header[name] = append(r.Header[name], string(v))
+		}
+	})
+	saved = store.Complete(key, r, ttl) == nil
+}
+
+// writeIdempotentResponse answers the request with the saved r.
+func writeIdempotentResponse(ctx *fasthttp.RequestCtx, r *IdempotentResponse) {
+	ctx.SetStatusCode(r.Status)
+	for name, values := range r.Header {
+		for i, v := range values {
+			if i == 0 {
+				ctx.Response.Header.Set(name, v)
+			} else {
+				ctx.Response.Header.Add(name, v)
+			}
+		}
+	}
+	ctx.Response.Header.Set("Idempotent-Replayed", "true")
+	ctx.SetBody(r.Body)
+}
+
+// idempotencyFingerprint identifies the request
+// by its method, path and body.
+func idempotencyFingerprint(ctx *fasthttp.RequestCtx) string {
+	h := sha256.New()
+	h.Write(ctx.Method())
+	h.Write([]byte{0})
+	h.Write(ctx.Path())
+	h.Write([]byte{0})
+	h.Write(ctx.PostBody())
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// memoryIdempotencyStore is an in-memory IdempotencyStore.
+type memoryIdempotencyStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryIdempotencyEntry
+	nextSweep time.Time
+}
+
+// memoryIdempotencyEntry is a key of memoryIdempotencyStore,
+// locked while resp is nil.
+type memoryIdempotencyEntry struct {
+	resp    *IdempotentResponse
+	expires time.Time
+}
+
+// NewMemoryIdempotencyStore returns an in-memory IdempotencyStore,
+// for single-server deployments and tests.
+func NewMemoryIdempotencyStore() IdempotencyStore {
+	return &memoryIdempotencyStore{entries: make(map[string]memoryIdempotencyEntry)}
+}
+
+func (s *memoryIdempotencyStore) Begin(key string, ttl time.Duration) (*IdempotentResponse, bool, error) {
+	t := now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !t.Before(s.nextSweep) {
+		for k, e := range s.entries {
+			if !t.Before(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+		s.nextSweep = t.Add(ttl)
+	}
+	if e, ok := s.entries[key]; ok && t.Before(e.expires) {
+		return e.resp, false, nil
+	}
+	s.entries[key] = memoryIdempotencyEntry{expires: t.Add(ttl)}
+	return nil, true, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(key string, resp *IdempotentResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = memoryIdempotencyEntry{resp: resp, expires: now().Add(ttl)}
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
@@ -0,0 +1,148 @@
+package fastalice
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func idempotentCtx(key, body string) *fasthttp.RequestCtx {
+	ctx := newTestCtx("POST", "http://localhost/payments")
+	ctx.Request.Header.Set("Idempotency-Key", key)
+	ctx.Request.SetBodyString(body)
+	return ctx
+}
+
+func TestIdempotencyReplays(t *testing.T) {
+	var calls int32
+	h := New(Idempotency(NewMemoryIdempotencyStore(), time.Hour)).Then(func(ctx *fasthttp.RequestCtx) {
+		n := atomic.AddInt32(&calls, 1)
+		ctx.SetStatusCode(fasthttp.StatusCreated)
+		ctx.Response.Header.Set("Location", "/payments/1")
+		ctx.SetBodyString("payment " + strconv.Itoa(int(n)))
+	})
+
+	ctx := idempotentCtx("k1", `{"amount":10}`)
+	h(ctx)
+	assert.Equal(t, "payment 1", string(ctx.Response.Body()), "The first request should be served")
+
+	ctx = idempotentCtx("k1", `{"amount":10}`)
+	h(ctx)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "Retries should not be served again")
+	assert.Equal(t, fasthttp.StatusCreated, ctx.Response.StatusCode(), "The status should be replayed")
+	assert.Equal(t, "/payments/1", string(ctx.Response.Header.Peek("Location")), "The headers should be replayed")
+	assert.Equal(t, "payment 1", string(ctx.Response.Body()), "The body should be replayed")
+	assert.Equal(t, "true", string(ctx.Response.Header.Peek("Idempotent-Replayed")), "Replays should be marked")
+
+	ctx = idempotentCtx("k1", `{"amount":99}`)
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusUnprocessableEntity, ctx.Response.StatusCode(), "A key reused for another request should be rejected")
+
+	ctx = idempotentCtx("", `{"amount":10}`)
+	h(ctx)
+	assert.Equal(t, "payment 2", string(ctx.Response.Body()), "Requests without a key should be served")
+
+	ctx = newTestCtx("GET", "http://localhost/payments")
+	ctx.Request.Header.Set("Idempotency-Key", "k1")
+	h(ctx)
+	assert.Equal(t, "payment 3", string(ctx.Response.Body()), "Safe methods should be served")
+}
+
+func TestIdempotencyDoesNotSaveFailures(t *testing.T) {
+	fail := true
+	h := New(Idempotency(NewMemoryIdempotencyStore(), time.Hour)).Then(func(ctx *fasthttp.RequestCtx) {
+		if fail {
+			ctx.Error("down", fasthttp.StatusServiceUnavailable)
+			return
+		}
+		ctx.WriteString("ok")
+	})
+
+	ctx := idempotentCtx("k1", "")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, ctx.Response.StatusCode(), "The failure should be answered")
+
+	fail = false
+	ctx = idempotentCtx("k1", "")
+	h(ctx)
+	assert.Equal(t, "ok", string(ctx.Response.Body()), "Retries of failed requests should be served")
+}
+
+func TestIdempotencyReleasesOnPanic(t *testing.T) {
+	store := NewMemoryIdempotencyStore()
+	h := New(Idempotency(store, time.Hour)).Then(func(ctx *fasthttp.RequestCtx) { panic("boom") })
+
+	assert.Panics(t, func() { h(idempotentCtx("k1", "")) }, "The panic should propagate")
+	_, locked, _ := store.Begin("k1", time.Hour)
+	assert.True(t, locked, "The key should be released after a panic")
+}
+
+func TestIdempotencyConcurrentDuplicatesWait(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	h := New(Idempotency(NewMemoryIdempotencyStore(), time.Hour)).Then(func(ctx *fasthttp.RequestCtx) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		ctx.WriteString("done")
+	})
+
+	first := idempotentCtx("k1", "")
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		h(first)
+	}()
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 1 }, time.Second, time.Millisecond, "The first request should be in flight")
+
+	dup := idempotentCtx("k1", "")
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		h(dup)
+	}()
+	time.Sleep(3 * idempotencyPollInterval)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "The duplicate should not be served again")
+	assert.Equal(t, "done", string(dup.Response.Body()), "The duplicate should get the response of the first request")
+}
+
+func TestIdempotencyWaitTimeout(t *testing.T) {
+	defer fakeClock(time.Second)()
+	var slept []time.Duration
+	defer fakeSleep(&slept)()
+	store := NewMemoryIdempotencyStore()
+	store.Begin("k1", time.Hour)
+
+	ctx := idempotentCtx("k1", "")
+	New(Idempotency(store, time.Hour)).Then(testApp)(ctx)
+	assert.Equal(t, fasthttp.StatusConflict, ctx.Response.StatusCode(), "Requests waiting too long should conflict")
+	assert.NotEmpty(t, slept, "The request should have waited")
+}
+
+func TestMemoryIdempotencyStoreSweep(t *testing.T) {
+	clock := time.Unix(0, 0)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+	store := NewMemoryIdempotencyStore().(*memoryIdempotencyStore)
+
+	_, _, _ = store.Begin("a", time.Minute)
+	clock = clock.Add(30 * time.Second)
+	_, _, _ = store.Begin("b", 10*time.Second)
+	clock = clock.Add(15 * time.Second)
+	_, acquired, _ := store.Begin("b", 10*time.Second)
+	assert.True(t, acquired, "An expired key should be acquired again before it is swept")
+	_, _, _ = store.Begin("c", time.Minute)
+	assert.Len(t, store.entries, 3, "Expired keys should be swept at most once per ttl")
+
+	clock = clock.Add(45 * time.Second)
+	_, _, _ = store.Begin("d", time.Minute)
+	assert.Len(t, store.entries, 2, "Expired keys should be swept once the next sweep is due")
+}
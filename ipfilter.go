@@ -0,0 +1,175 @@
+package fastalice
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ipList is a parsed list of individual IPs and CIDR ranges.
+type ipList []*net.IPNet
+
+// parseIPList parses entries that are either
+// individual IPs or CIDR ranges.
+func parseIPList(entries []string) (ipList, error) {
+	list := make(ipList, 0, len(entries))
+	for _, e := range entries {
+		if strings.Contains(e, "/") {
+			_, n, err := net.ParseCIDR(e)
+			if err != nil {
+				return nil, fmt.Errorf("fastalice: invalid CIDR %q: %v", e, err)
+			}
+			list = append(list, n)
+			continue
+		}
+
+		ip := net.ParseIP(e)
+		if ip == nil {
+			return nil, fmt.Errorf("fastalice: invalid IP %q", e)
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		list = append(list, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return list, nil
+}
+
+func (l ipList) contains(ip net.IP) bool {
+	for _, n := range l {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ipFilterConfig holds the settings of IPFilter.
+type ipFilterConfig struct {
+	proxyHops      int
+	trustedProxies []string
+	reject         fasthttp.RequestHandler
+}
+
+// IPFilterOption configures IPFilter.
+type IPFilterOption func(*ipFilterConfig)
+
+// IPFilterProxyHops sets the number of reverse proxies
+// in front of the server, given as IPs and CIDR ranges in trustedProxies,
+// so that the client IP is read from the X-Forwarded-For header
+// they append to: it is the address hops entries from its end,
+// the nearest proxy being the remote address itself.
+// Without X-Forwarded-For, X-Real-IP is used when set.
+//
+// The headers are only read when the remote address
+// and the proxies listed after the client are trusted;
+// otherwise, or when the chain is shorter than hops,
+// the remote address is used, as the headers may be forged.
+// The default of zero uses ClientIP, ignoring those headers.
+func IPFilterProxyHops(hops int, trustedProxies []string) IPFilterOption {
+	return func(c *ipFilterConfig) {
+		c.proxyHops = hops
+		c.trustedProxies = trustedProxies
+	}
+}
+
+// IPFilterRejectHandler sets the handler answering rejected requests,
+// which defaults to answering with 403 Forbidden.
+func IPFilterRejectHandler(h fasthttp.RequestHandler) IPFilterOption {
+	return func(c *ipFilterConfig) { c.reject = h }
+}
+
+// NewIPFilter returns a constructor that restricts access
+// by client IP, answering rejected requests with 403 Forbidden
+// without calling the following handlers.
+//
+// allow and deny hold individual IPs and CIDR ranges.
+// Deny rules take precedence,
+// and an empty allow list allows every IP that is not denied.
+// Rules are parsed once, and an error is returned
+// if any of them, or of the trusted proxies, is malformed,
+// or if IPFilterProxyHops is given no trusted proxy.
+func NewIPFilter(allow []string, deny []string, opts ...IPFilterOption) (Constructor, error) {
+	cfg := ipFilterConfig{
+		reject: func(ctx *fasthttp.RequestCtx) {
+			ctx.Error(fasthttp.StatusMessage(fasthttp.StatusForbidden), fasthttp.StatusForbidden)
+		},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	allowed, err := parseIPList(allow)
+	if err != nil {
+		return nil, err
+	}
+	denied, err := parseIPList(deny)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.proxyHops > 0 && len(cfg.trustedProxies) == 0 {
+		return nil, errors.New("fastalice: IPFilterProxyHops needs trusted proxies")
+	}
+	trusted, err := parseIPList(cfg.trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			ip := forwardedIP(ctx, cfg.proxyHops, trusted)
+			if ip == nil || denied.contains(ip) || (len(allowed) > 0 && !allowed.contains(ip)) {
+				cfg.reject(ctx)
+				return
+			}
+			next(ctx)
+		}
+	}, nil
+}
+
+// IPFilter is like NewIPFilter but panics
+// if any of the rules is malformed.
+// It simplifies building chains from rules known at compile time.
+func IPFilter(allow []string, deny []string, opts ...IPFilterOption) Constructor {
+	c, err := NewIPFilter(allow, deny, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// forwardedIP returns the client IP of a request
+// that went through hops trusted proxies,
+// or nil when the forwarded address is malformed.
+func forwardedIP(ctx *fasthttp.RequestCtx, hops int, trusted ipList) net.IP {
+	if hops <= 0 {
+		return ClientIP(ctx)
+	}
+	remote := ctx.RemoteIP()
+	if !trusted.contains(remote) {
+		return remote
+	}
+
+	xff := ctx.Request.Header.Peek(fasthttp.HeaderXForwardedFor)
+	if len(xff) == 0 {
+		if real := ctx.Request.Header.Peek("X-Real-IP"); len(real) > 0 {
+			return net.ParseIP(strings.TrimSpace(string(real)))
+		}
+		return remote
+	}
+
+	chain := strings.Split(string(xff), ",")
+	i := len(chain) - hops
+	if i < 0 {
+		return remote
+	}
+	for _, proxy := range chain[i+1:] {
+		if ip := net.ParseIP(strings.TrimSpace(proxy)); ip == nil || !trusted.contains(ip) {
+			return remote
+		}
+	}
+	return net.ParseIP(strings.TrimSpace(chain[i]))
+}
@@ -0,0 +1,109 @@
+package fastalice
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// newTestCtxFromIP builds a request context like newTestCtx,
+// coming from the given remote ip.
+func newTestCtxFromIP(method, uri, ip string) *fasthttp.RequestCtx {
+	var req fasthttp.Request
+	req.Header.SetMethod(method)
+	req.SetRequestURI(uri)
+
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Init(&req, &net.TCPAddr{IP: net.ParseIP(ip), Port: 1234}, nil)
+	return ctx
+}
+
+func TestIPFilterDeniedIP(t *testing.T) {
+	h := New(IPFilter(nil, []string{"10.0.0.0/8", "192.168.1.1"})).Then(testApp)
+
+	for _, ip := range []string{"10.1.2.3", "192.168.1.1"} {
+		ctx := newTestCtxFromIP("GET", "http://localhost/admin", ip)
+		h(ctx)
+		assert.Equal(t, fasthttp.StatusForbidden, ctx.Response.StatusCode(), "Denied IPs should be rejected")
+	}
+
+	ctx := newTestCtxFromIP("GET", "http://localhost/admin", "192.168.1.2")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "IPs not denied should pass when no allow list is set")
+}
+
+func TestIPFilterAllowedIP(t *testing.T) {
+	h := New(IPFilter([]string{"127.0.0.1", "10.0.0.0/8"}, []string{"10.0.0.13"})).Then(testApp)
+
+	ctx := newTestCtxFromIP("GET", "http://localhost/admin", "10.0.0.12")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Allowed IPs should pass")
+
+	ctx = newTestCtxFromIP("GET", "http://localhost/admin", "10.0.0.13")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusForbidden, ctx.Response.StatusCode(), "Deny rules should take precedence")
+
+	ctx = newTestCtxFromIP("GET", "http://localhost/admin", "172.16.0.1")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusForbidden, ctx.Response.StatusCode(), "IPs outside the allow list should be rejected")
+}
+
+func TestIPFilterMalformedRule(t *testing.T) {
+	_, err := NewIPFilter([]string{"10.0.0.0/33"}, nil)
+	assert.EqualError(t, err, `fastalice: invalid CIDR "10.0.0.0/33": invalid CIDR address: 10.0.0.0/33`, "Malformed CIDRs should be reported")
+
+	_, err = NewIPFilter(nil, []string{"not-an-ip"})
+	assert.EqualError(t, err, `fastalice: invalid IP "not-an-ip"`, "Malformed IPs should be reported")
+
+	assert.Panics(t, func() { IPFilter([]string{"bogus"}, nil) }, "IPFilter should panic on malformed rules")
+}
+
+// serveForwarded serves a request from remote through h,
+// with the given forwarding headers when not empty.
+func serveForwarded(h fasthttp.RequestHandler, remote, xff, realIP string) int {
+	ctx := newTestCtxFromIP("GET", "http://localhost/admin", remote)
+	if xff != "" {
+		ctx.Request.Header.Set(fasthttp.HeaderXForwardedFor, xff)
+	}
+	if realIP != "" {
+		ctx.Request.Header.Set("X-Real-IP", realIP)
+	}
+	h(ctx)
+	return ctx.Response.StatusCode()
+}
+
+func TestIPFilterProxyHops(t *testing.T) {
+	h := New(IPFilter([]string{"203.0.113.0/24"}, nil, IPFilterProxyHops(2, []string{"10.0.0.0/8"}))).Then(testApp)
+
+	assert.Equal(t, fasthttp.StatusOK, serveForwarded(h, "10.0.0.1", "203.0.113.5, 10.0.0.2", ""), "The client IP should be read past the trusted proxies")
+	assert.Equal(t, fasthttp.StatusForbidden, serveForwarded(h, "10.0.0.1", "203.0.113.5, 198.51.100.1, 10.0.0.2", ""), "Entries forged by clients should be ignored")
+	assert.Equal(t, fasthttp.StatusOK, serveForwarded(h, "10.0.0.1", "", "203.0.113.9"), "X-Real-IP should be used without X-Forwarded-For")
+	assert.Equal(t, fasthttp.StatusForbidden, serveForwarded(h, "10.0.0.1", "garbage, 10.0.0.2", ""), "Malformed addresses should be rejected")
+	assert.Equal(t, fasthttp.StatusForbidden, serveForwarded(h, "10.0.0.1", "", ""), "The remote address should be used without headers")
+}
+
+func TestIPFilterProxyHopsSpoofing(t *testing.T) {
+	h := New(IPFilter([]string{"203.0.113.0/24"}, nil, IPFilterProxyHops(2, []string{"10.0.0.0/8"}))).Then(testApp)
+
+	assert.Equal(t, fasthttp.StatusForbidden, serveForwarded(h, "198.51.100.7", "203.0.113.5, 10.0.0.2", ""), "Untrusted peers should not choose their IP with X-Forwarded-For")
+	assert.Equal(t, fasthttp.StatusForbidden, serveForwarded(h, "198.51.100.7", "", "203.0.113.5"), "Untrusted peers should not choose their IP with X-Real-IP")
+	assert.Equal(t, fasthttp.StatusForbidden, serveForwarded(h, "10.0.0.1", "203.0.113.5", ""), "Chains shorter than the hops should use the remote address")
+	assert.Equal(t, fasthttp.StatusForbidden, serveForwarded(h, "10.0.0.1", "203.0.113.5, 198.51.100.7", ""), "Untrusted proxies in the chain should use the remote address")
+
+	_, err := NewIPFilter(nil, nil, IPFilterProxyHops(1, nil))
+	assert.Error(t, err, "Proxy hops without trusted proxies should be rejected")
+	_, err = NewIPFilter(nil, nil, IPFilterProxyHops(1, []string{"bogus"}))
+	assert.Error(t, err, "Malformed trusted proxies should be rejected")
+}
+
+func TestIPFilterRejectHandler(t *testing.T) {
+	reject := func(ctx *fasthttp.RequestCtx) {
+		ctx.Error("go away", fasthttp.StatusNotFound)
+	}
+	ctx := newTestCtxFromIP("GET", "http://localhost/admin", "10.0.0.1")
+	New(IPFilter(nil, []string{"10.0.0.1"}, IPFilterRejectHandler(reject))).Then(testApp)(ctx)
+	assert.Equal(t, fasthttp.StatusNotFound, ctx.Response.StatusCode(), "The reject handler should answer")
+	assert.Equal(t, "go away", string(ctx.Response.Body()), "The reject handler should answer")
+}
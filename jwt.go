@@ -0,0 +1,290 @@
+package fastalice
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256" // registers SHA-256 for crypto.Hash
+	_ "crypto/sha512" // registers SHA-384 and SHA-512 for crypto.Hash
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// jwtClaimsKey is the key holding
+// the claims of the token validated by JWT.
+var jwtClaimsKey = NewKey[map[string]interface{}]("jwtClaims")
+
+// JWTHeader is the header of a JSON Web Token.
+type JWTHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+	Typ string `json:"typ,omitempty"`
+}
+
+// JWTConfig configures the JWT middleware.
+type JWTConfig struct {
+	// KeyFunc returns the key verifying a token with the given header:
+	// a []byte secret for HS256, HS384 and HS512,
+	// an *rsa.PublicKey for RS256, RS384 and RS512,
+	// or an *ecdsa.PublicKey for ES256, ES384 and ES512.
+	// Selecting the key by header.Kid allows key rotation.
+	KeyFunc func(header JWTHeader) (interface{}, error)
+	// Algorithms restricts the accepted signing algorithms.
+	// When empty, any supported algorithm matching the key is accepted.
+	Algorithms []string
+	// Header is the request header holding the token,
+	// as "Bearer <token>" for Authorization.
+	// It defaults to Authorization when neither Cookie nor Query is set.
+	Header string
+	// Cookie and Query name the cookie and query argument
+	// holding the token. Header, Cookie and Query are tried in order.
+	Cookie string
+	Query  string
+	// Issuer and Audience, when set, must match
+	// the iss and aud claims.
+	Issuer   string
+	Audience string
+	// Leeway is the clock skew tolerated on the exp and nbf claims.
+	Leeway time.Duration
+}
+
+// errNoKeyFunc reports a JWTConfig without a KeyFunc.
+var errNoKeyFunc = errors.New("fastalice: JWT needs a KeyFunc")
+
+// NewJWT returns a constructor that authenticates requests
+// with a JSON Web Token, validating its signature
+// and its exp, nbf, iss and aud claims.
+// Requests without a valid token are answered with 401
+// and a Bearer WWW-Authenticate challenge,
+// without calling the following handlers.
+//
+// The claims of the token can be read with JWTClaims.
+//
+// An error is returned if config.KeyFunc is nil.
+func NewJWT(config JWTConfig) (Constructor, error) {
+	if config.KeyFunc == nil {
+		return nil, errNoKeyFunc
+	}
+	if config.Header == "" && config.Cookie == "" && config.Query == "" {
+		config.Header = fasthttp.HeaderAuthorization
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			claims, err := parseJWT(jwtToken(ctx, config), config)
+			if err != nil {
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusUnauthorized), fasthttp.StatusUnauthorized)
+				ctx.Response.Header.Set(fasthttp.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+				return
+			}
+
+			Set(ctx, jwtClaimsKey, claims)
+			next(ctx)
+		}
+	}, nil
+}
+
+// JWT is like NewJWT but panics if config.KeyFunc is nil.
+func JWT(config JWTConfig) Constructor {
+	c, err := NewJWT(config)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// JWTClaims returns the claims of the token validated by JWT,
+// or nil if there is none.
+// Numeric claims are decoded as json.Number.
+func JWTClaims(ctx *fasthttp.RequestCtx) map[string]interface{} {
+	claims, _ := Get(ctx, jwtClaimsKey)
+	return claims
+}
+
+// jwtToken returns the token of the request
+// from the places named by config.
+func jwtToken(ctx *fasthttp.RequestCtx, config JWTConfig) string {
+	if config.Header != "" {
+		v := ctx.Request.Header.Peek(config.Header)
+		if strings.EqualFold(config.Header, fasthttp.HeaderAuthorization) {
+			if token, ok := parseBearerAuth(v); ok {
+				return token
+			}
+		} else if len(v) > 0 {
+			return string(v)
+		}
+	}
+	if config.Cookie != "" {
+		if v := ctx.Request.Header.Cookie(config.Cookie); len(v) > 0 {
+			return string(v)
+		}
+	}
+	if config.Query != "" {
+		return string(ctx.QueryArgs().Peek(config.Query))
+	}
+	return ""
+}
+
+// jwtHashes maps the supported algorithms to their hash.
+var jwtHashes = map[string]crypto.Hash{
+	"HS256": crypto.SHA256, "HS384": crypto.SHA384, "HS512": crypto.SHA512,
+	"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+	"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+}
+
+// parseJWT verifies token and returns its claims.
+func parseJWT(token string, config JWTConfig) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("fastalice: malformed token")
+	}
+
+	var header JWTHeader
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+	hash, ok := jwtHashes[header.Alg]
+	if !ok {
+		return nil, fmt.Errorf("fastalice: unsupported algorithm %q", header.Alg)
+	}
+	if len(config.Algorithms) > 0 && !containsString(config.Algorithms, header.Alg) {
+		return nil, fmt.Errorf("fastalice: algorithm %q not allowed", header.Alg)
+	}
+	key, err := config.KeyFunc(header)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyJWT(header.Alg, hash, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	return claims, validateJWTClaims(claims, config)
+}
+
+// decodeJWTPart decodes a base64url encoded JSON part of a token into v.
+func decodeJWTPart(part string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+// jwtCurves maps the ECDSA algorithms to the curve of their keys.
+var jwtCurves = map[string]elliptic.Curve{
+	"ES256": elliptic.P256(), "ES384": elliptic.P384(), "ES512": elliptic.P521(),
+}
+
+// verifyJWT checks the signature sig of signed with key,
+// which must be of the type expected by alg,
+// on the curve expected by alg for ECDSA keys.
+func verifyJWT(alg string, hash crypto.Hash, key interface{}, signed string, sig []byte) error {
+	h := hash.New()
+	switch alg[:2] {
+	case "HS":
+		secret, ok := key.([]byte)
+		if !ok {
+			return errors.New("fastalice: HMAC algorithms need a []byte key")
+		}
+		mac := hmac.New(hash.New, secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), sig) {
+			return errors.New("fastalice: invalid signature")
+		}
+		return nil
+	case "RS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("fastalice: RSA algorithms need an *rsa.PublicKey")
+		}
+		h.Write([]byte(signed))
+		return rsa.VerifyPKCS1v15(pub, hash, h.Sum(nil), sig)
+	default:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("fastalice: ECDSA algorithms need an *ecdsa.PublicKey")
+		}
+		if pub.Curve.Params().Name != jwtCurves[alg].Params().Name {
+			return fmt.Errorf("fastalice: %s needs a %s key", alg, jwtCurves[alg].Params().Name)
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return errors.New("fastalice: invalid signature")
+		}
+		h.Write([]byte(signed))
+		r, s := new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(pub, h.Sum(nil), r, s) {
+			return errors.New("fastalice: invalid signature")
+		}
+		return nil
+	}
+}
+
+// validateJWTClaims checks the registered claims against config.
+func validateJWTClaims(claims map[string]interface{}, config JWTConfig) error {
+	t := now()
+	if exp, ok := claims["exp"].(json.Number); ok {
+		sec, err := exp.Int64()
+		if err != nil || !t.Before(time.Unix(sec, 0).Add(config.Leeway)) {
+			return errors.New("fastalice: token expired")
+		}
+	}
+	if nbf, ok := claims["nbf"].(json.Number); ok {
+		sec, err := nbf.Int64()
+		if err != nil || t.Add(config.Leeway).Before(time.Unix(sec, 0)) {
+			return errors.New("fastalice: token not valid yet")
+		}
+	}
+	if config.Issuer != "" && claims["iss"] != config.Issuer {
+		return errors.New("fastalice: invalid issuer")
+	}
+	if config.Audience != "" {
+		switch aud := claims["aud"].(type) {
+		case string:
+			if aud != config.Audience {
+				return errors.New("fastalice: invalid audience")
+			}
+		case []interface{}:
+			found := false
+			for _, a := range aud {
+				found = found || a == config.Audience
+			}
+			if !found {
+				return errors.New("fastalice: invalid audience")
+			}
+		default:
+			return errors.New("fastalice: invalid audience")
+		}
+	}
+	return nil
+}
+
+// containsString reports whether s is in list.
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
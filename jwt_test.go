@@ -0,0 +1,171 @@
+package fastalice
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+var jwtSecret = []byte("jwt-secret")
+
+// signJWT returns a token with the given header and claims
+// signed by sign.
+func signJWT(header JWTHeader, claims map[string]interface{}, sign func(signed []byte) []byte) string {
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(signed)))
+}
+
+func hs256(signed []byte) []byte {
+	mac := hmac.New(sha256.New, jwtSecret)
+	mac.Write(signed)
+	return mac.Sum(nil)
+}
+
+func testJWT(config JWTConfig, token string, set func(ctx *fasthttp.RequestCtx, token string)) *fasthttp.RequestCtx {
+	ctx := newTestCtx("GET", "http://localhost/")
+	set(ctx, token)
+	New(JWT(config)).Then(func(ctx *fasthttp.RequestCtx) {
+		ctx.WriteString("hello " + JWTClaims(ctx)["sub"].(string))
+	})(ctx)
+	return ctx
+}
+
+func bearer(ctx *fasthttp.RequestCtx, token string) {
+	ctx.Request.Header.Set(fasthttp.HeaderAuthorization, "Bearer "+token)
+}
+
+func secretKey(JWTHeader) (interface{}, error) {
+	return jwtSecret, nil
+}
+
+func TestJWTHMAC(t *testing.T) {
+	token := signJWT(JWTHeader{Alg: "HS256"}, map[string]interface{}{"sub": "bob"}, hs256)
+	ctx := testJWT(JWTConfig{KeyFunc: secretKey}, token, bearer)
+	assert.Equal(t, "hello bob", string(ctx.Response.Body()), "Valid tokens should pass with their claims")
+
+	ctx = testJWT(JWTConfig{KeyFunc: secretKey}, token+"x", bearer)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "Tampered tokens should be rejected")
+	assert.Equal(t, `Bearer error="invalid_token"`, string(ctx.Response.Header.Peek(fasthttp.HeaderWWWAuthenticate)), "Rejections should carry a challenge")
+
+	ctx = testJWT(JWTConfig{KeyFunc: secretKey, Algorithms: []string{"RS256"}}, token, bearer)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "Disallowed algorithms should be rejected")
+}
+
+func TestJWTRSA(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	token := signJWT(JWTHeader{Alg: "RS256", Kid: "k2"}, map[string]interface{}{"sub": "bob"}, func(signed []byte) []byte {
+		sum := sha256.Sum256(signed)
+		sig, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+		return sig
+	})
+	rotated := func(h JWTHeader) (interface{}, error) {
+		if h.Kid != "k2" {
+			return nil, errors.New("unknown key")
+		}
+		return &key.PublicKey, nil
+	}
+
+	ctx := testJWT(JWTConfig{KeyFunc: rotated}, token, bearer)
+	assert.Equal(t, "hello bob", string(ctx.Response.Body()), "RSA tokens should be verified with the key selected by kid")
+
+	ctx = testJWT(JWTConfig{KeyFunc: secretKey}, token, bearer)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "Keys of the wrong type should be rejected")
+}
+
+func TestJWTECDSA(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	token := signJWT(JWTHeader{Alg: "ES256"}, map[string]interface{}{"sub": "bob"}, func(signed []byte) []byte {
+		sum := sha256.Sum256(signed)
+		r, s, _ := ecdsa.Sign(rand.Reader, key, sum[:])
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return sig
+	})
+
+	ctx := testJWT(JWTConfig{KeyFunc: func(JWTHeader) (interface{}, error) { return &key.PublicKey, nil }}, token, bearer)
+	assert.Equal(t, "hello bob", string(ctx.Response.Body()), "ECDSA tokens should be verified")
+}
+
+func TestJWTECDSACurveMismatch(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	token := signJWT(JWTHeader{Alg: "ES384"}, map[string]interface{}{"sub": "bob"}, func(signed []byte) []byte {
+		h := crypto.SHA384.New()
+		h.Write(signed)
+		r, s, _ := ecdsa.Sign(rand.Reader, key, h.Sum(nil))
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return sig
+	})
+
+	ctx := testJWT(JWTConfig{KeyFunc: func(JWTHeader) (interface{}, error) { return &key.PublicKey, nil }}, token, bearer)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "ES384 tokens should be rejected with a P-256 key")
+}
+
+func TestJWTRequiresKeyFunc(t *testing.T) {
+	_, err := NewJWT(JWTConfig{})
+	assert.Error(t, err, "NewJWT should reject a config without KeyFunc")
+	assert.Panics(t, func() { JWT(JWTConfig{}) }, "JWT should panic on a config without KeyFunc")
+}
+
+func TestJWTClaimsValidation(t *testing.T) {
+	defer fakeClock(0)()
+	t0 := now().Unix()
+
+	for name, tc := range map[string]struct {
+		claims map[string]interface{}
+		ok     bool
+	}{
+		"valid":         {map[string]interface{}{"sub": "bob", "exp": t0 + 60, "nbf": t0 - 60, "iss": "me", "aud": []string{"api", "web"}}, true},
+		"expired":       {map[string]interface{}{"sub": "bob", "exp": t0 - 60, "iss": "me", "aud": "api"}, false},
+		"within leeway": {map[string]interface{}{"sub": "bob", "exp": t0 - 5, "iss": "me", "aud": "api"}, true},
+		"not yet":       {map[string]interface{}{"sub": "bob", "nbf": t0 + 60, "iss": "me", "aud": "api"}, false},
+		"issuer":        {map[string]interface{}{"sub": "bob", "iss": "other", "aud": "api"}, false},
+		"audience":      {map[string]interface{}{"sub": "bob", "iss": "me", "aud": "web"}, false},
+	} {
+		token := signJWT(JWTHeader{Alg: "HS256"}, tc.claims, hs256)
+		config := JWTConfig{KeyFunc: secretKey, Issuer: "me", Audience: "api", Leeway: 10 * time.Second}
+		ctx := testJWT(config, token, bearer)
+		if tc.ok {
+			assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "The %s token should pass", name)
+		} else {
+			assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "The %s token should be rejected", name)
+		}
+	}
+}
+
+func TestJWTLookup(t *testing.T) {
+	token := signJWT(JWTHeader{Alg: "HS256"}, map[string]interface{}{"sub": "bob"}, hs256)
+	config := JWTConfig{KeyFunc: secretKey, Cookie: "session", Query: "token"}
+
+	ctx := testJWT(config, token, func(ctx *fasthttp.RequestCtx, token string) {
+		ctx.Request.Header.SetCookie("session", token)
+	})
+	assert.Equal(t, "hello bob", string(ctx.Response.Body()), "Tokens should be read from cookies")
+
+	ctx = testJWT(config, token, func(ctx *fasthttp.RequestCtx, token string) {
+		ctx.QueryArgs().Set("token", token)
+	})
+	assert.Equal(t, "hello bob", string(ctx.Response.Body()), "Tokens should be read from the query")
+
+	ctx = testJWT(config, token, bearer)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "The Authorization header should not be read unless configured")
+}
@@ -0,0 +1,111 @@
+package fastalice
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+)
+
+// constructError is the panic value of constructors
+// that fail when the chain is composed, recovered by ThenE.
+type constructError struct {
+	err error
+}
+
+func (e constructError) Error() string {
+	return e.err.Error()
+}
+
+// Lazy returns a constructor deferring the call to fn,
+// and thus any expensive initialization it does,
+// such as loading key sets or compiling expressions,
+// until the chain is composed with Then.
+// fn runs once; the constructor it returns is reused
+// by later calls to Then.
+//
+//	chain := fastalice.New(fastalice.Lazy(func() fastalice.Constructor {
+//		return fastalice.JWT(loadKeys())
+//	}))
+func Lazy(fn func() Constructor) Constructor {
+	return LazyE(func() (Constructor, error) {
+		return fn(), nil
+	})
+}
+
+// LazyE is like Lazy, for initialization that can fail.
+// Its error is returned by ThenE; Then panics with it.
+// A failed initialization is not retried.
+func LazyE(fn func() (Constructor, error)) Constructor {
+	var (
+		once sync.Once
+		cons Constructor
+		err  error
+	)
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		once.Do(func() {
+			cons, err = fn()
+			if err == nil && cons == nil {
+				err = fmt.Errorf("fastalice: lazy constructor resolved to nil")
+			}
+		})
+		if err != nil {
+			panic(constructError{err})
+		}
+		return cons(next)
+	}
+}
+
+// ThenLazy is like Then, but defers composing the chain,
+// and thus resolving its lazy constructors,
+// until the first request goes through the returned handler.
+// Concurrent requests wait for it to complete.
+//
+// Requests are answered with 500 Internal Server Error
+// when composing the chain fails, see ThenE.
+func (c Chain) ThenLazy(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+	var (
+		once    sync.Once
+		handler fasthttp.RequestHandler
+	)
+	return func(ctx *fasthttp.RequestCtx) {
+		once.Do(func() {
+			var err error
+			if handler, err = c.ThenE(h); err != nil {
+				handler = func(ctx *fasthttp.RequestCtx) {
+					ctx.Error(fasthttp.StatusMessage(fasthttp.StatusInternalServerError), fasthttp.StatusInternalServerError)
+				}
+			}
+		})
+		handler(ctx)
+	}
+}
+
+// ThenE is like Then, but returns the error
+// of a constructor failing to compose, such as one added
+// by LazyE or Validated,
+// instead of panicking with it.
+//
+//	handler, err := chain.ThenE(app)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+func (c Chain) ThenE(h fasthttp.RequestHandler) (fasthttp.RequestHandler, error) {
+	return construct(c.Then, h)
+}
+
+// construct calls cons with next,
+// returning the error of a constructor failing to compose.
+// Other panics are propagated.
+func construct(cons Constructor, next fasthttp.RequestHandler) (handler fasthttp.RequestHandler, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			ce, ok := p.(constructError)
+			if !ok {
+				panic(p)
+			}
+			handler, err = nil, ce.err
+		}
+	}()
+	return cons(next), nil
+}
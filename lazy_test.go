@@ -0,0 +1,70 @@
+package fastalice
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestLazyResolvesOnThen(t *testing.T) {
+	calls := 0
+	chain := New(Lazy(func() Constructor {
+		calls++
+		return tagMiddleware("lazy")
+	}))
+	assert.Equal(t, 0, calls, "Lazy should not resolve before Then")
+
+	chain.Then(testApp)
+	h := chain.Then(testApp)
+	assert.Equal(t, 1, calls, "Lazy should resolve once")
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, "lazyapp", string(ctx.Response.Body()), "The resolved constructor should run")
+}
+
+func TestLazyEErrorsSurfaceInThenE(t *testing.T) {
+	chain := New(tagMiddleware("t"), LazyE(func() (Constructor, error) {
+		return nil, errors.New("no keys")
+	}))
+
+	h, err := chain.ThenE(testApp)
+	assert.EqualError(t, err, "no keys", "ThenE should return the lazy error")
+	assert.Nil(t, h, "ThenE should return no handler on error")
+	assert.Panics(t, func() { chain.Then(testApp) }, "Then should panic with the lazy error")
+
+	_, err = New(Lazy(func() Constructor { return nil })).ThenE(testApp)
+	assert.Error(t, err, "Lazy constructors resolving to nil should fail")
+}
+
+func TestThenEPropagatesOtherPanics(t *testing.T) {
+	chain := New(func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		panic("boom")
+	})
+	assert.PanicsWithValue(t, "boom", func() { chain.ThenE(testApp) }, "ThenE should only recover construction errors")
+}
+
+func TestThenLazy(t *testing.T) {
+	calls := 0
+	h := New(Lazy(func() Constructor {
+		calls++
+		return tagMiddleware("lazy")
+	})).ThenLazy(testApp)
+	assert.Equal(t, 0, calls, "ThenLazy should not compose before the first request")
+
+	for i := 0; i < 2; i++ {
+		ctx := newTestCtx("GET", "http://localhost/")
+		h(ctx)
+		assert.Equal(t, "lazyapp", string(ctx.Response.Body()), "Requests should go through the chain")
+	}
+	assert.Equal(t, 1, calls, "ThenLazy should compose once")
+
+	h = New(LazyE(func() (Constructor, error) {
+		return nil, errors.New("no keys")
+	})).ThenLazy(testApp)
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusInternalServerError, ctx.Response.StatusCode(), "Failed compositions should answer 500")
+}
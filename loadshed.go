@@ -0,0 +1,134 @@
+package fastalice
+
+import (
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Priority ranks requests for LoadShed;
+// lower priorities are shed first.
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+	// PriorityCritical requests are never shed.
+	PriorityCritical
+)
+
+// ShedPolicy configures LoadShed.
+// Zero fields take the documented defaults.
+type ShedPolicy struct {
+	// Classify returns the priority of a request,
+	// PriorityNormal for all of them by default.
+	Classify func(ctx *fasthttp.RequestCtx) Priority
+	// Target is the latency requests are expected to stay under,
+	// 50 milliseconds by default.
+	Target time.Duration
+	// Interval is the period over which latency is watched,
+	// 100 milliseconds by default.
+	Interval time.Duration
+	// Overloaded, when not nil, is consulted at the end of each interval
+	// to report pressure the latency does not show, such as high CPU use.
+	Overloaded func() bool
+	// OnShed, when not nil, is called for each shed request.
+	OnShed func(ctx *fasthttp.RequestCtx, p Priority)
+	// OnLevel, when not nil, is called when the shedding level changes:
+	// requests with a priority lower than level are shed.
+	OnLevel func(level Priority)
+}
+
+// shedder is the state of a LoadShed middleware.
+type shedder struct {
+	mu            sync.Mutex
+	level         Priority
+	intervalStart time.Time
+	// minLatency is the lowest latency seen in the interval,
+	// or zero when no request completed in it.
+	minLatency time.Duration
+	seen       bool
+}
+
+// LoadShed returns a constructor that sheds low-priority requests
+// under pressure, answering them with 503 Service Unavailable
+// and a Retry-After header without calling the following handlers.
+//
+// Like CoDel, it watches the lowest latency of the requests
+// completed in each interval: a standing latency above policy.Target
+// means the server is overloaded, while short bursts are tolerated.
+// Each overloaded interval raises the shedding level by one priority,
+// and each interval back under target lowers it by one,
+// so that shedding grows and recedes gradually.
+// Critical requests are never shed.
+func LoadShed(policy ShedPolicy) Constructor {
+	if policy.Classify == nil {
+		policy.Classify = func(*fasthttp.RequestCtx) Priority { return PriorityNormal }
+	}
+	if policy.Target <= 0 {
+		policy.Target = 50 * time.Millisecond
+	}
+	if policy.Interval <= 0 {
+		policy.Interval = 100 * time.Millisecond
+	}
+	s := &shedder{intervalStart: now()}
+	retryAfter := seconds(policy.Interval)
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			p := policy.Classify(ctx)
+			if p < s.admitLevel(policy) {
+				if policy.OnShed != nil {
+					policy.OnShed(ctx, p)
+				}
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusServiceUnavailable), fasthttp.StatusServiceUnavailable)
+				ctx.Response.Header.Set(fasthttp.HeaderRetryAfter, retryAfter)
+				return
+			}
+
+			start := now()
+			next(ctx)
+			s.observe(now().Sub(start))
+		}
+	}
+}
+
+// admitLevel ends the current interval if it is over,
+// and returns the shedding level.
+func (s *shedder) admitLevel(policy ShedPolicy) Priority {
+	s.mu.Lock()
+	t := now()
+	if t.Sub(s.intervalStart) < policy.Interval {
+		level := s.level
+		s.mu.Unlock()
+		return level
+	}
+
+	overloaded := s.seen && s.minLatency > policy.Target ||
+		policy.Overloaded != nil && policy.Overloaded()
+	level := s.level
+	if overloaded && level < PriorityCritical {
+		level++
+	} else if !overloaded && level > PriorityLow {
+		level--
+	}
+	changed := level != s.level
+	s.level, s.intervalStart, s.minLatency, s.seen = level, t, 0, false
+	s.mu.Unlock()
+
+	if changed && policy.OnLevel != nil {
+		policy.OnLevel(level)
+	}
+	return level
+}
+
+// observe records the latency of a served request.
+func (s *shedder) observe(latency time.Duration) {
+	s.mu.Lock()
+	if !s.seen || latency < s.minLatency {
+		s.minLatency, s.seen = latency, true
+	}
+	s.mu.Unlock()
+}
@@ -0,0 +1,75 @@
+package fastalice
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestLoadShed(t *testing.T) {
+	clock := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	latency := 60 * time.Millisecond
+	var shed []Priority
+	var levels []Priority
+	h := New(LoadShed(ShedPolicy{
+		Classify: func(ctx *fasthttp.RequestCtx) Priority {
+			p, _ := strconv.Atoi(string(ctx.Request.Header.Peek("X-Priority")))
+			return Priority(p)
+		},
+		Target:   10 * time.Millisecond,
+		Interval: 100 * time.Millisecond,
+		OnShed:   func(ctx *fasthttp.RequestCtx, p Priority) { shed = append(shed, p) },
+		OnLevel:  func(level Priority) { levels = append(levels, level) },
+	})).Then(func(ctx *fasthttp.RequestCtx) {
+		clock = clock.Add(latency)
+		testApp(ctx)
+	})
+	serve := func(p Priority) int {
+		ctx := newTestCtx("GET", "http://localhost/")
+		ctx.Request.Header.Set("X-Priority", strconv.Itoa(int(p)))
+		h(ctx)
+		return ctx.Response.StatusCode()
+	}
+
+	assert.Equal(t, fasthttp.StatusOK, serve(PriorityLow), "Requests should be served before any pressure")
+	assert.Equal(t, fasthttp.StatusOK, serve(PriorityNormal), "Short bursts should be tolerated")
+
+	// The first interval ended over target.
+	assert.Equal(t, fasthttp.StatusOK, serve(PriorityNormal), "Normal requests should be served at the first level")
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, serve(PriorityLow), "Low requests should be shed first")
+
+	// The second interval ended over target.
+	clock = clock.Add(100 * time.Millisecond)
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, serve(PriorityNormal), "Normal requests should be shed at the second level")
+	latency = time.Millisecond
+	assert.Equal(t, fasthttp.StatusOK, serve(PriorityCritical), "Critical requests should never be shed")
+
+	// The following intervals ended under target.
+	clock = clock.Add(100 * time.Millisecond)
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, serve(PriorityLow), "Shedding should recede one level per interval")
+	clock = clock.Add(100 * time.Millisecond)
+	assert.Equal(t, fasthttp.StatusOK, serve(PriorityLow), "Shedding should stop once latency is back under target")
+
+	assert.Equal(t, []Priority{PriorityLow, PriorityNormal, PriorityLow}, shed, "OnShed should report shed requests")
+	assert.Equal(t, []Priority{1, 2, 1, 0}, levels, "OnLevel should report level changes")
+}
+
+func TestLoadShedOverloaded(t *testing.T) {
+	defer fakeClock(60 * time.Millisecond)()
+
+	overloaded := true
+	h := New(LoadShed(ShedPolicy{Overloaded: func() bool { return overloaded }})).Then(testApp)
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	h(ctx)
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, ctx.Response.StatusCode(), "Reported pressure should shed requests")
+	assert.Equal(t, "1", string(ctx.Response.Header.Peek(fasthttp.HeaderRetryAfter)), "Retry-After should be set")
+}
@@ -0,0 +1,151 @@
+package fastalice
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// LogEntry describes a request served through Logger.
+type LogEntry struct {
+	Time time.Time
+	// RequestID is the ID assigned by RequestID, if any.
+	RequestID string
+	RemoteIP  string
+	User      string
+	Method    string
+	Path      string
+	Protocol  string
+	Status    int
+	// Bytes is the size of the response body,
+	// or -1 for streamed responses of unknown length.
+	Bytes   int
+	Latency time.Duration
+}
+
+// LogFormat formats a log entry into buf,
+// which is written as a single line by Logger.
+type LogFormat func(buf *bytes.Buffer, e LogEntry) error
+
+// CommonLogFormat formats entries in the Common Log Format:
+//
+//	127.0.0.1 - bob [10/Oct/2000:13:55:36 -0700] "GET /index.html HTTP/1.1" 200 2326
+func CommonLogFormat(buf *bytes.Buffer, e LogEntry) error {
+	user := e.User
+	if user == "" {
+		user = "-"
+	}
+	size := "-"
+	if e.Bytes >= 0 {
+		size = strconv.Itoa(e.Bytes)
+	}
+	_, err := fmt.Fprintf(buf, "%s - %s [%s] \"%s %s %s\" %d %s",
+		e.RemoteIP, user, e.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		e.Method, e.Path, e.Protocol, e.Status, size)
+	return err
+}
+
+// JSONLogFormat formats entries as JSON objects,
+// with the latency in milliseconds.
+func JSONLogFormat(buf *bytes.Buffer, e LogEntry) error {
+	return json.NewEncoder(buf).Encode(struct {
+		Time      time.Time `json:"time"`
+		RequestID string    `json:"request_id,omitempty"`
+		RemoteIP  string    `json:"remote_ip"`
+		User      string    `json:"user,omitempty"`
+		Method    string    `json:"method"`
+		Path      string    `json:"path"`
+		Protocol  string    `json:"protocol"`
+		Status    int       `json:"status"`
+		Bytes     int       `json:"bytes"`
+		LatencyMS float64   `json:"latency_ms"`
+	}{
+		e.Time, e.RequestID, e.RemoteIP, e.User, e.Method, e.Path, e.Protocol,
+		e.Status, e.Bytes, float64(e.Latency) / float64(time.Millisecond),
+	})
+}
+
+// NewTemplateLogFormat returns a log format executing
+// the text/template text with the LogEntry, such as:
+//
+//	{{.Method}} {{.Path}} {{.Status}} {{.Latency}}
+//
+// An error is returned if text does not parse.
+func NewTemplateLogFormat(text string) (LogFormat, error) {
+	tmpl, err := template.New("fastalice.log").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("fastalice: invalid log template: %v", err)
+	}
+	return func(buf *bytes.Buffer, e LogEntry) error {
+		return tmpl.Execute(buf, e)
+	}, nil
+}
+
+// TemplateLogFormat is like NewTemplateLogFormat,
+// but it panics if text does not parse.
+// It is meant for formats known at startup.
+func TemplateLogFormat(text string) LogFormat {
+	format, err := NewTemplateLogFormat(text)
+	if err != nil {
+		panic(err)
+	}
+	return format
+}
+
+// Logger returns a constructor that writes an access log line
+// to w in the given format once the following handlers have run.
+// A nil format defaults to CommonLogFormat.
+//
+// Lines are written with a single call to w,
+// serialized across concurrent requests.
+// Entries that fail to format are dropped.
+func Logger(w io.Writer, format LogFormat) Constructor {
+	if format == nil {
+		format = CommonLogFormat
+	}
+	var mu sync.Mutex
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			start := now()
+			next(ctx)
+			latency := now().Sub(start)
+
+			var buf bytes.Buffer
+			err := format(&buf, LogEntry{
+				Time:      start,
+				RequestID: GetRequestID(ctx),
+				RemoteIP:  ClientIP(ctx).String(),
+				User:      BasicAuthUser(ctx),
+				Method:    string(ctx.Method()),
+				Path:      string(ctx.URI().RequestURI()),
+				Protocol:  protocol(ctx),
+				Status:    ctx.Response.StatusCode(),
+				Bytes:     responseSize(ctx),
+				Latency:   latency,
+			})
+			if err != nil {
+				return
+			}
+			if b := buf.Bytes(); len(b) == 0 || b[len(b)-1] != '\n' {
+				buf.WriteByte('\n')
+			}
+
+			mu.Lock()
+			w.Write(buf.Bytes())
+			mu.Unlock()
+		}
+	}
+}
+
+// protocol returns the HTTP version of the request.
+func protocol(ctx *fasthttp.RequestCtx) string {
+	return ProtocolInfo(ctx).Version
+}
@@ -0,0 +1,62 @@
+package fastalice
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggerCommonLogFormat(t *testing.T) {
+	defer fakeClock(5 * time.Millisecond)()
+
+	var out bytes.Buffer
+	ctx := newTestCtxFromIP("GET", "http://localhost/users?page=2", "10.0.0.1")
+	New(Logger(&out, nil)).Then(testApp)(ctx)
+
+	assert.Equal(t, "10.0.0.1 - - [01/Jan/2020:00:00:00 +0000] \"GET /users?page=2 HTTP/1.1\" 200 3\n", out.String(), "The line should follow the Common Log Format")
+}
+
+func TestLoggerJSONFormat(t *testing.T) {
+	defer fakeClock(5 * time.Millisecond)()
+
+	var out bytes.Buffer
+	ctx := newTestCtxFromIP("POST", "http://localhost/users", "10.0.0.1")
+	New(Logger(&out, JSONLogFormat)).Then(testApp)(ctx)
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &entry), "The line should be valid JSON")
+	assert.Equal(t, "POST", entry["method"], "The method should be logged")
+	assert.Equal(t, "/users", entry["path"], "The path should be logged")
+	assert.Equal(t, float64(200), entry["status"], "The status should be logged")
+	assert.Equal(t, float64(3), entry["bytes"], "The response size should be logged")
+	assert.Equal(t, float64(5), entry["latency_ms"], "The latency should be logged")
+	assert.NotContains(t, entry, "user", "Missing users should be omitted")
+}
+
+func TestLoggerTemplateFormat(t *testing.T) {
+	defer fakeClock(5 * time.Millisecond)()
+
+	var out bytes.Buffer
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(Logger(&out, TemplateLogFormat("{{.Method}} {{.Path}} {{.Status}} {{.Latency}}"))).Then(testApp)(ctx)
+
+	assert.Equal(t, "GET / 200 5ms\n", out.String(), "The line should follow the template")
+}
+
+func TestNewTemplateLogFormatInvalid(t *testing.T) {
+	_, err := NewTemplateLogFormat("{{.Method")
+	assert.Error(t, err, "Invalid templates should be rejected")
+	assert.Panics(t, func() { TemplateLogFormat("{{.Method") }, "TemplateLogFormat should panic on invalid templates")
+}
+
+func TestLoggerSSE(t *testing.T) {
+	defer fakeClock(5 * time.Millisecond)()
+
+	var out bytes.Buffer
+	ctx := newTestCtxFromIP("GET", "http://localhost/events", "10.0.0.1")
+	assert.True(t, returnsWithin(New(Logger(&out, nil)).Then(endlessSSE), ctx, time.Second), "Endless streams should not be read")
+	assert.Equal(t, "10.0.0.1 - - [01/Jan/2020:00:00:00 +0000] \"GET /events HTTP/1.1\" 200 -\n", out.String(), "Streams of unknown length should be logged without a size")
+}
@@ -0,0 +1,140 @@
+package fastalice
+
+import (
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// MaintenanceOptions configures a MaintenanceState.
+type MaintenanceOptions struct {
+	// RetryAfter is sent in the Retry-After header
+	// of rejected requests, when positive.
+	RetryAfter time.Duration
+	// Page is the body of rejected requests,
+	// the 503 status message by default.
+	Page string
+	// ContentType is the content type of Page,
+	// text/plain by default.
+	ContentType string
+	// AllowedPaths lists the paths served during maintenance,
+	// such as health checks. A path ending in a slash
+	// allows every path under it.
+	AllowedPaths []string
+	// AllowedIPs lists the client IPs and CIDR ranges
+	// served during maintenance, as seen by ClientIP.
+	AllowedIPs []string
+}
+
+// MaintenanceState is the maintenance mode of Maintenance,
+// toggled at runtime with Enable and Disable or through Handler.
+// It is safe for concurrent use.
+type MaintenanceState struct {
+	enabled    int32
+	retryAfter string
+	page       string
+	mime       string
+	paths      []string
+	ips        ipList
+}
+
+// NewMaintenanceState returns a disabled maintenance state
+// configured by opts.
+// An error is returned if any of the allowed IPs is malformed.
+func NewMaintenanceState(opts MaintenanceOptions) (*MaintenanceState, error) {
+	ips, err := parseIPList(opts.AllowedIPs)
+	if err != nil {
+		return nil, err
+	}
+	s := &MaintenanceState{
+		page:  opts.Page,
+		mime:  opts.ContentType,
+		paths: append([]string(nil), opts.AllowedPaths...),
+		ips:   ips,
+	}
+	if s.page == "" {
+		s.page = fasthttp.StatusMessage(fasthttp.StatusServiceUnavailable)
+	}
+	if s.mime == "" {
+		s.mime = "text/plain; charset=utf-8"
+	}
+	if opts.RetryAfter > 0 {
+		s.retryAfter = seconds(opts.RetryAfter)
+	}
+	return s, nil
+}
+
+// Enable turns maintenance mode on.
+func (s *MaintenanceState) Enable() { atomic.StoreInt32(&s.enabled, 1) }
+
+// Disable turns maintenance mode off.
+func (s *MaintenanceState) Disable() { atomic.StoreInt32(&s.enabled, 0) }
+
+// Enabled reports whether maintenance mode is on.
+func (s *MaintenanceState) Enabled() bool { return atomic.LoadInt32(&s.enabled) != 0 }
+
+// allowed reports whether the request is served during maintenance.
+func (s *MaintenanceState) allowed(ctx *fasthttp.RequestCtx) bool {
+	path := string(ctx.Path())
+	for _, p := range s.paths {
+		if path == p || strings.HasSuffix(p, "/") && strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return len(s.ips) > 0 && s.ips.contains(ClientIP(ctx))
+}
+
+// Handler returns an admin handler toggling maintenance mode:
+// POST enables it, DELETE disables it,
+// and every method answers with the current state as JSON,
+// such as {"enabled":true}.
+// It is meant to be mounted on a protected admin route.
+func (s *MaintenanceState) Handler() fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		switch {
+		case ctx.IsPost():
+			s.Enable()
+		case ctx.IsDelete():
+			s.Disable()
+		}
+		ctx.SetContentType("application/json")
+		json.NewEncoder(ctx).Encode(struct {
+			Enabled bool `json:"enabled"`
+		}{s.Enabled()})
+	}
+}
+
+// Maintenance returns a constructor that answers requests
+// with 503 Service Unavailable, the configured page
+// and a Retry-After header, without calling the following handlers,
+// while state is enabled.
+// Requests for allowed paths or from allowed IPs go on.
+//
+// The state is read atomically on every request,
+// so operators can flip maintenance at runtime without redeploying:
+//
+//	state, _ := fastalice.NewMaintenanceState(fastalice.MaintenanceOptions{
+//		RetryAfter:   10 * time.Minute,
+//		AllowedPaths: []string{"/healthz"},
+//	})
+//	chain := fastalice.New(fastalice.Maintenance(state))
+//	admin.Handle("*", "/maintenance", state.Handler())
+func Maintenance(state *MaintenanceState) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if !state.Enabled() || state.allowed(ctx) {
+				next(ctx)
+				return
+			}
+
+			ctx.Error(state.page, fasthttp.StatusServiceUnavailable)
+			ctx.SetContentType(state.mime)
+			if state.retryAfter != "" {
+				ctx.Response.Header.Set(fasthttp.HeaderRetryAfter, state.retryAfter)
+			}
+		}
+	}
+}
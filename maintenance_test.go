@@ -0,0 +1,79 @@
+package fastalice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestMaintenanceToggle(t *testing.T) {
+	state, err := NewMaintenanceState(MaintenanceOptions{RetryAfter: 2 * time.Minute})
+	assert.NoError(t, err)
+	h := New(Maintenance(state)).Then(testApp)
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Requests should pass while maintenance is off")
+
+	state.Enable()
+	ctx = newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, ctx.Response.StatusCode(), "Requests should be rejected during maintenance")
+	assert.Equal(t, "120", string(ctx.Response.Header.Peek(fasthttp.HeaderRetryAfter)), "Rejections should carry Retry-After")
+
+	state.Disable()
+	ctx = newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Requests should pass once maintenance is over")
+}
+
+func TestMaintenanceAllowlist(t *testing.T) {
+	state, err := NewMaintenanceState(MaintenanceOptions{
+		Page:         "<h1>Back soon</h1>",
+		ContentType:  "text/html; charset=utf-8",
+		AllowedPaths: []string{"/healthz", "/admin/"},
+		AllowedIPs:   []string{"10.0.0.0/8"},
+	})
+	assert.NoError(t, err)
+	state.Enable()
+	h := New(Maintenance(state)).Then(testApp)
+
+	for _, path := range []string{"/healthz", "/admin/users"} {
+		ctx := newTestCtx("GET", "http://localhost"+path)
+		h(ctx)
+		assert.Equal(t, "app", string(ctx.Response.Body()), "Allowed path %s should be served", path)
+	}
+
+	ctx := newTestCtxFromIP("GET", "http://localhost/", "10.1.2.3")
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Allowed IPs should be served")
+
+	ctx = newTestCtxFromIP("GET", "http://localhost/", "192.0.2.1")
+	h(ctx)
+	assert.Equal(t, "<h1>Back soon</h1>", string(ctx.Response.Body()), "The maintenance page should be served")
+	assert.Equal(t, "text/html; charset=utf-8", string(ctx.Response.Header.ContentType()), "The page content type should be set")
+	assert.Empty(t, ctx.Response.Header.Peek(fasthttp.HeaderRetryAfter), "Retry-After should only be set when configured")
+
+	_, err = NewMaintenanceState(MaintenanceOptions{AllowedIPs: []string{"bogus"}})
+	assert.Error(t, err, "Malformed IPs should be rejected")
+}
+
+func TestMaintenanceHandler(t *testing.T) {
+	state, _ := NewMaintenanceState(MaintenanceOptions{})
+	admin := state.Handler()
+
+	ctx := newTestCtx("POST", "http://localhost/maintenance")
+	admin(ctx)
+	assert.True(t, state.Enabled(), "POST should enable maintenance")
+	assert.JSONEq(t, `{"enabled":true}`, string(ctx.Response.Body()), "The state should be reported")
+
+	ctx = newTestCtx("DELETE", "http://localhost/maintenance")
+	admin(ctx)
+	assert.False(t, state.Enabled(), "DELETE should disable maintenance")
+
+	ctx = newTestCtx("GET", "http://localhost/maintenance")
+	admin(ctx)
+	assert.JSONEq(t, `{"enabled":false}`, string(ctx.Response.Body()), "GET should report the state")
+}
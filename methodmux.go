@@ -0,0 +1,108 @@
+package fastalice
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// MethodMux dispatches requests to handlers by HTTP method.
+// It is meant as a lightweight terminal handler:
+//
+//	mux := fastalice.NewMethodMux()
+//	mux.Get(listHandler)
+//	mux.Post(createHandler)
+//	chained := fastalice.New(m1, m2).Then(mux.Handler())
+type MethodMux struct {
+	methods  []string
+	handlers map[string]fasthttp.RequestHandler
+}
+
+// NewMethodMux creates an empty MethodMux.
+func NewMethodMux() *MethodMux {
+	return &MethodMux{handlers: make(map[string]fasthttp.RequestHandler)}
+}
+
+// Handle registers h for requests with the given method,
+// replacing any handler previously registered for it.
+func (m *MethodMux) Handle(method string, h fasthttp.RequestHandler) {
+	method = strings.ToUpper(method)
+	if _, ok := m.handlers[method]; !ok {
+		m.methods = append(m.methods, method)
+	}
+	m.handlers[method] = h
+}
+
+// Get registers h for GET requests.
+func (m *MethodMux) Get(h fasthttp.RequestHandler) { m.Handle(fasthttp.MethodGet, h) }
+
+// Head registers h for HEAD requests.
+func (m *MethodMux) Head(h fasthttp.RequestHandler) { m.Handle(fasthttp.MethodHead, h) }
+
+// Post registers h for POST requests.
+func (m *MethodMux) Post(h fasthttp.RequestHandler) { m.Handle(fasthttp.MethodPost, h) }
+
+// Put registers h for PUT requests.
+func (m *MethodMux) Put(h fasthttp.RequestHandler) { m.Handle(fasthttp.MethodPut, h) }
+
+// Patch registers h for PATCH requests.
+func (m *MethodMux) Patch(h fasthttp.RequestHandler) { m.Handle(fasthttp.MethodPatch, h) }
+
+// Delete registers h for DELETE requests.
+func (m *MethodMux) Delete(h fasthttp.RequestHandler) { m.Handle(fasthttp.MethodDelete, h) }
+
+// Options registers h for OPTIONS requests.
+func (m *MethodMux) Options(h fasthttp.RequestHandler) { m.Handle(fasthttp.MethodOptions, h) }
+
+// Handler returns a fasthttp.RequestHandler
+// running the handler registered for the request method.
+// Requests with any other method are answered
+// with 405 Method Not Allowed and an Allow header
+// listing the registered methods.
+//
+// Handler takes a snapshot of the registered handlers,
+// so later registrations do not affect it.
+func (m *MethodMux) Handler() fasthttp.RequestHandler {
+	handlers := make(map[string]fasthttp.RequestHandler, len(m.handlers))
+	for method, h := range m.handlers {
+		handlers[method] = h
+	}
+	allow := strings.Join(m.methods, ", ")
+
+	return func(ctx *fasthttp.RequestCtx) {
+		if h, ok := handlers[string(ctx.Method())]; ok {
+			h(ctx)
+			return
+		}
+
+		ctx.Error(fasthttp.StatusMessage(fasthttp.StatusMethodNotAllowed), fasthttp.StatusMethodNotAllowed)
+		ctx.Response.Header.Set(fasthttp.HeaderAllow, allow)
+	}
+}
+
+// ThenMethods chains the middleware with handlers
+// dispatched by HTTP method, keyed by method name.
+// Requests with any other method are answered
+// with 405 Method Not Allowed and an Allow header
+// listing the handled methods in alphabetical order.
+//
+//	chain.ThenMethods(map[string]fasthttp.RequestHandler{
+//		fasthttp.MethodGet:  listHandler,
+//		fasthttp.MethodPost: createHandler,
+//	})
+func (c Chain) ThenMethods(handlers map[string]fasthttp.RequestHandler) fasthttp.RequestHandler {
+	methods := make([]string, 0, len(handlers))
+	for method := range handlers {
+		methods = append(methods, method)
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		return strings.ToUpper(methods[i]) < strings.ToUpper(methods[j])
+	})
+
+	mux := NewMethodMux()
+	for _, method := range methods {
+		mux.Handle(method, handlers[method])
+	}
+	return c.Then(mux.Handler())
+}
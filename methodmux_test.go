@@ -0,0 +1,51 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func testMethodMux() fasthttp.RequestHandler {
+	mux := NewMethodMux()
+	mux.Get(func(ctx *fasthttp.RequestCtx) { ctx.WriteString("list") })
+	mux.Post(func(ctx *fasthttp.RequestCtx) { ctx.WriteString("create") })
+	return New(tagMiddleware("t1\n")).Then(mux.Handler())
+}
+
+func TestMethodMuxGet(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/items")
+	testMethodMux()(ctx)
+	assert.Equal(t, "t1\nlist", string(ctx.Response.Body()), "GET should reach the GET handler")
+}
+
+func TestMethodMuxPost(t *testing.T) {
+	ctx := newTestCtx("POST", "http://localhost/items")
+	testMethodMux()(ctx)
+	assert.Equal(t, "t1\ncreate", string(ctx.Response.Body()), "POST should reach the POST handler")
+}
+
+func TestMethodMuxNotAllowed(t *testing.T) {
+	ctx := newTestCtx("DELETE", "http://localhost/items")
+	testMethodMux()(ctx)
+	assert.Equal(t, fasthttp.StatusMethodNotAllowed, ctx.Response.StatusCode(), "Unregistered methods should return Method Not Allowed")
+	assert.Equal(t, "GET, POST", string(ctx.Response.Header.Peek(fasthttp.HeaderAllow)), "The Allow header should list the registered methods")
+}
+
+func TestThenMethods(t *testing.T) {
+	h := New(tagMiddleware("t1\n")).ThenMethods(map[string]fasthttp.RequestHandler{
+		"post":               writer("created"),
+		fasthttp.MethodGet:   writer("list"),
+		fasthttp.MethodPatch: writer("patched"),
+	})
+
+	ctx := newTestCtx("POST", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, "t1\ncreated", string(ctx.Response.Body()), "Requests should go through the chain to their method handler")
+
+	ctx = newTestCtx("DELETE", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusMethodNotAllowed, ctx.Response.StatusCode(), "Unhandled methods should get a 405")
+	assert.Equal(t, "GET, PATCH, POST", string(ctx.Response.Header.Peek(fasthttp.HeaderAllow)), "Allow should list the methods in alphabetical order")
+}
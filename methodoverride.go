@@ -0,0 +1,82 @@
+package fastalice
+
+import (
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// OverrideSource returns the method a request asks to be treated as,
+// or an empty string if it asks for none.
+type OverrideSource func(ctx *fasthttp.RequestCtx) string
+
+// OverrideHeader returns a source reading the method
+// from the request header name, such as X-HTTP-Method-Override.
+func OverrideHeader(name string) OverrideSource {
+	return func(ctx *fasthttp.RequestCtx) string {
+		return string(ctx.Request.Header.Peek(name))
+	}
+}
+
+// OverrideFormField returns a source reading the method
+// from the form field name of urlencoded and multipart bodies,
+// such as _method.
+func OverrideFormField(name string) OverrideSource {
+	return func(ctx *fasthttp.RequestCtx) string {
+		if v := ctx.PostArgs().Peek(name); len(v) > 0 {
+			return string(v)
+		}
+		if form, err := ctx.MultipartForm(); err == nil {
+			if vs := form.Value[name]; len(vs) > 0 {
+				return vs[0]
+			}
+		}
+		return ""
+	}
+}
+
+// overridableMethods lists the methods a POST request may be turned into.
+var overridableMethods = map[string]bool{
+	fasthttp.MethodPut:    true,
+	fasthttp.MethodPatch:  true,
+	fasthttp.MethodDelete: true,
+}
+
+// MethodOverride returns a constructor that lets POST requests
+// be treated as PUT, PATCH or DELETE requests,
+// so that HTML forms can use those methods.
+// The first source returning a method wins;
+// without sources, the X-HTTP-Method-Override header
+// and then the _method form field are consulted.
+//
+//	<form method="POST" action="/posts/1">
+//		<input type="hidden" name="_method" value="DELETE">
+//	</form>
+//
+// Other methods, and requests to turn into other methods, are left alone.
+func MethodOverride(sources ...OverrideSource) Constructor {
+	if len(sources) == 0 {
+		sources = []OverrideSource{
+			OverrideHeader("X-HTTP-Method-Override"),
+			OverrideFormField("_method"),
+		}
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if ctx.IsPost() {
+				for _, source := range sources {
+					method := strings.ToUpper(strings.TrimSpace(source(ctx)))
+					if method == "" {
+						continue
+					}
+					if overridableMethods[method] {
+						ctx.Request.Header.SetMethod(method)
+					}
+					break
+				}
+			}
+			next(ctx)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestMethodOverride(t *testing.T) {
+	var seen string
+	h := New(MethodOverride()).Then(func(ctx *fasthttp.RequestCtx) {
+		seen = string(ctx.Method())
+	})
+
+	ctx := newTestCtx("POST", "http://localhost/posts/1")
+	ctx.Request.Header.Set("X-HTTP-Method-Override", "patch")
+	h(ctx)
+	assert.Equal(t, "PATCH", seen, "The header should override the method")
+
+	ctx = newTestCtx("POST", "http://localhost/posts/1")
+	ctx.Request.Header.SetContentType("application/x-www-form-urlencoded")
+	ctx.Request.SetBodyString("title=x&_method=DELETE")
+	h(ctx)
+	assert.Equal(t, "DELETE", seen, "The form field should override the method")
+
+	ctx = newTestCtx("POST", "http://localhost/posts/1")
+	ctx.Request.Header.Set("X-HTTP-Method-Override", "CONNECT")
+	h(ctx)
+	assert.Equal(t, "POST", seen, "Only PUT, PATCH and DELETE should be allowed")
+
+	ctx = newTestCtx("GET", "http://localhost/posts/1")
+	ctx.Request.Header.Set("X-HTTP-Method-Override", "DELETE")
+	h(ctx)
+	assert.Equal(t, "GET", seen, "Only POST requests should be overridden")
+}
+
+func TestMethodOverrideSources(t *testing.T) {
+	var seen string
+	h := New(MethodOverride(OverrideHeader("X-Method"))).Then(func(ctx *fasthttp.RequestCtx) {
+		seen = string(ctx.Method())
+	})
+
+	ctx := newTestCtx("POST", "http://localhost/")
+	ctx.Request.Header.SetContentType("application/x-www-form-urlencoded")
+	ctx.Request.SetBodyString("_method=DELETE")
+	h(ctx)
+	assert.Equal(t, "POST", seen, "Unlisted sources should be ignored")
+
+	ctx = newTestCtx("POST", "http://localhost/")
+	ctx.Request.Header.Set("X-Method", "PUT")
+	h(ctx)
+	assert.Equal(t, "PUT", seen, "Listed sources should be used")
+}
@@ -0,0 +1,34 @@
+package fastalice
+
+import (
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// MetricsObserver receives the measurements taken by Metrics.
+// Implementations adapt them to a metrics library,
+// such as Prometheus or statsd.
+type MetricsObserver interface {
+	// IncRequest counts a served request.
+	IncRequest(method, path string, status int)
+	// ObserveLatency records the time spent serving a request.
+	ObserveLatency(method, path string, d time.Duration)
+}
+
+// Metrics returns a constructor that times the following handlers
+// and reports every request to obs,
+// along with its final status code.
+func Metrics(obs MetricsObserver) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			start := now()
+			next(ctx)
+			d := now().Sub(start)
+
+			method, path := string(ctx.Method()), string(ctx.Path())
+			obs.IncRequest(method, path, ctx.Response.StatusCode())
+			obs.ObserveLatency(method, path, d)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package fastalice
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeObserver is a MetricsObserver recording what it observes.
+type fakeObserver struct {
+	requests  []string
+	latencies []time.Duration
+}
+
+func (o *fakeObserver) IncRequest(method, path string, status int) {
+	o.requests = append(o.requests, fmt.Sprintf("%s %s %d", method, path, status))
+}
+
+func (o *fakeObserver) ObserveLatency(method, path string, d time.Duration) {
+	o.latencies = append(o.latencies, d)
+}
+
+func TestMetricsObservesRequest(t *testing.T) {
+	defer fakeClock(time.Millisecond)()
+
+	obs := &fakeObserver{}
+	New(Metrics(obs)).Then(testApp)(newTestCtx("POST", "http://localhost/users"))
+
+	assert.Equal(t, []string{"POST /users 200"}, obs.requests, "The request should be counted with its status")
+	assert.Equal(t, []time.Duration{time.Millisecond}, obs.latencies, "The latency should be observed")
+}
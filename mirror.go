@@ -0,0 +1,65 @@
+package fastalice
+
+import (
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultMirrorMaxInFlight is the number of shadow requests
+// Mirror runs at once; further ones are dropped.
+const DefaultMirrorMaxInFlight = 64
+
+// mirroredKey marks the shadow requests of Mirror.
+var mirroredKey = NewKey[bool]("mirrored")
+
+// Mirror returns a constructor replaying a copy of a fraction sampling,
+// between 0 and 1, of the requests to target in the background,
+// such as a new version of a service, while the following handlers
+// serve the real response.
+// Requests are sampled by request ID as Sample does.
+//
+// Shadow responses are discarded and shadow panics recovered,
+// so target cannot affect the real traffic;
+// target can tell shadow requests with IsMirrored,
+// such as to skip side effects.
+// At most DefaultMirrorMaxInFlight shadow requests run at once,
+// and requests with a streamed body are never mirrored.
+func Mirror(target fasthttp.RequestHandler, sampling float64) Constructor {
+	slots := make(chan struct{}, DefaultMirrorMaxInFlight)
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if ctx.Request.IsBodyStream() || !sampled(ctx, sampling) {
+				next(ctx)
+				return
+			}
+			select {
+			case slots <- struct{}{}:
+			default:
+				next(ctx)
+				return
+			}
+
+			shadow := &fasthttp.RequestCtx{}
+			shadow.Init(&ctx.Request, ctx.RemoteAddr(), nil)
+			Set(shadow, mirroredKey, true)
+			if id := GetRequestID(ctx); id != "" {
+				Set(shadow, requestIDKey, id)
+			}
+			go func() {
+				defer func() {
+					recover()
+					<-slots
+				}()
+				target(shadow)
+			}()
+
+			next(ctx)
+		}
+	}
+}
+
+// IsMirrored reports whether the request is a shadow copy made by Mirror.
+func IsMirrored(ctx *fasthttp.RequestCtx) bool {
+	mirrored, _ := Get(ctx, mirroredKey)
+	return mirrored
+}
@@ -0,0 +1,47 @@
+package fastalice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestMirror(t *testing.T) {
+	shadows := make(chan string, 1)
+	target := func(ctx *fasthttp.RequestCtx) {
+		assert.True(t, IsMirrored(ctx), "Shadow requests should be marked")
+		shadows <- string(ctx.Path()) + " " + string(ctx.PostBody())
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		panic("shadow failure")
+	}
+	h := New(Mirror(target, 1)).Then(func(ctx *fasthttp.RequestCtx) {
+		assert.False(t, IsMirrored(ctx), "Real requests should not be marked")
+		ctx.Request.SetBodyString("consumed")
+		ctx.WriteString("primary")
+	})
+
+	ctx := newTestCtx("POST", "http://localhost/orders")
+	ctx.Request.SetBodyString("order")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "The primary response should be served")
+	assert.Equal(t, "primary", string(ctx.Response.Body()), "The primary response should be served")
+
+	select {
+	case got := <-shadows:
+		assert.Equal(t, "/orders order", got, "The shadow should get a copy of the request")
+	case <-time.After(time.Second):
+		t.Fatal("The request should be mirrored")
+	}
+}
+
+func TestMirrorSampling(t *testing.T) {
+	mirrored := false
+	h := New(Mirror(func(ctx *fasthttp.RequestCtx) { mirrored = true }, 0)).Then(testApp)
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "The primary response should be served")
+	assert.False(t, mirrored, "Requests outside the sample should not be mirrored")
+}
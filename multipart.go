@@ -0,0 +1,272 @@
+package fastalice
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// DefaultMultipartMaxPartSize is the size limit of file parts
+	// unless MultipartOptions.MaxPartSize is set.
+	DefaultMultipartMaxPartSize = 32 << 20
+	// DefaultMultipartMaxValueSize is the size limit of other parts
+	// unless MultipartOptions.MaxValueSize is set.
+	DefaultMultipartMaxValueSize = 1 << 20
+	// DefaultMultipartMaxParts is the number of parts allowed
+	// unless MultipartOptions.MaxParts is set.
+	DefaultMultipartMaxParts = 1000
+)
+
+// MultipartOptions configures Multipart.
+type MultipartOptions struct {
+	// Dir is the directory file parts are written to,
+	// the default temporary directory when empty.
+	Dir string
+	// Sink, when set, returns the writer a file part is copied to
+	// instead of a file in Dir, such as an object storage upload.
+	// It is called once the content type of the part is sniffed,
+	// and the writer is closed once the part is copied.
+	Sink func(ctx *fasthttp.RequestCtx, file *MultipartFile) (io.WriteCloser, error)
+	// MaxPartSize limits the size of file parts,
+	// DefaultMultipartMaxPartSize when zero.
+	MaxPartSize int64
+	// MaxValueSize limits the size of other parts, kept in memory,
+	// DefaultMultipartMaxValueSize when zero.
+	MaxValueSize int64
+	// MaxParts limits the number of parts,
+	// DefaultMultipartMaxParts when zero.
+	MaxParts int
+	// AllowedTypes, when not empty, lists the media types
+	// file parts may have, as sniffed from their content.
+	AllowedTypes []string
+}
+
+// MultipartFile is a file part of a multipart body parsed by Multipart.
+type MultipartFile struct {
+	// FormName is the form field name of the part.
+	FormName string
+	// FileName is the file name sent by the client, which should not be trusted.
+	FileName string
+	Header   textproto.MIMEHeader
+	// ContentType is the media type sniffed from the content of the part,
+	// regardless of the type the client declared.
+	ContentType string
+	Size        int64
+	// Path is the file the part was written to,
+	// empty when it went to MultipartOptions.Sink.
+	// The file is removed once the following handlers have run.
+	Path string
+}
+
+// MultipartForm is a multipart body parsed by Multipart.
+type MultipartForm struct {
+	// Values holds the parts that are not files.
+	Values map[string][]string
+	Files  []*MultipartFile
+}
+
+// multipartFormKey holds the form parsed by Multipart.
+var multipartFormKey = NewKey[*MultipartForm]("multipartForm")
+
+// errPartTooLarge reports a part over its size limit.
+var errPartTooLarge = errors.New("fastalice: multipart part too large")
+
+// errPartType reports a file part of a type not allowed.
+var errPartType = errors.New("fastalice: multipart part type not allowed")
+
+// errPartWrite reports a file part that could not be stored.
+var errPartWrite = errors.New("fastalice: cannot store multipart part")
+
+// Multipart returns a constructor parsing multipart/form-data bodies
+// as they are read, writing file parts to disk or to opts.Sink
+// instead of buffering the whole body as fasthttp's MultipartForm does,
+// which makes it suited to large uploads together with Streaming(StreamBodies).
+// The following handlers read the parsed form with GetMultipartForm.
+//
+// Requests that are not multipart/form-data are answered with
+// 415 Unsupported Media Type, as are file parts whose sniffed type
+// is not in opts.AllowedTypes; parts over their limit, or too many parts,
+// with 413 Request Entity Too Large, and malformed bodies with 400 Bad Request.
+//
+//	uploads := fastalice.New(
+//		fastalice.Streaming(fastalice.StreamBodies),
+//		fastalice.Multipart(fastalice.MultipartOptions{MaxPartSize: 100 << 20, AllowedTypes: []string{"image/png", "image/jpeg"}}),
+//	)
+func Multipart(opts MultipartOptions) Constructor {
+	if opts.MaxPartSize <= 0 {
+		opts.MaxPartSize = DefaultMultipartMaxPartSize
+	}
+	if opts.MaxValueSize <= 0 {
+		opts.MaxValueSize = DefaultMultipartMaxValueSize
+	}
+	if opts.MaxParts <= 0 {
+		opts.MaxParts = DefaultMultipartMaxParts
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			mt, params, err := mime.ParseMediaType(string(ctx.Request.Header.ContentType()))
+			if err != nil || mt != "multipart/form-data" || params["boundary"] == "" {
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusUnsupportedMediaType), fasthttp.StatusUnsupportedMediaType)
+				return
+			}
+
+			body := requestBody(ctx)
+			defer body.Close()
+			form := &MultipartForm{Values: make(map[string][]string)}
+			defer form.removeFiles()
+			if err := opts.parse(ctx, multipart.NewReader(body, params["boundary"]), form); err != nil {
+				status := fasthttp.StatusBadRequest
+				switch {
+				case errors.Is(err, errPartTooLarge):
+					status = fasthttp.StatusRequestEntityTooLarge
+				case errors.Is(err, errPartType):
+					status = fasthttp.StatusUnsupportedMediaType
+				case errors.Is(err, errPartWrite):
+					status = fasthttp.StatusInternalServerError
+				}
+				ctx.Error(fasthttp.StatusMessage(status), status)
+				return
+			}
+
+			Set(ctx, multipartFormKey, form)
+			next(ctx)
+		}
+	}
+}
+
+// GetMultipartForm returns the form parsed by Multipart,
+// or nil outside of it.
+func GetMultipartForm(ctx *fasthttp.RequestCtx) *MultipartForm {
+	form, _ := Get(ctx, multipartFormKey)
+	return form
+}
+
+// requestBody returns a reader of the request body,
+// reading streamed bodies as they arrive.
+// Closing it stops reading them.
+func requestBody(ctx *fasthttp.RequestCtx) io.ReadCloser {
+	if !ctx.Request.IsBodyStream() {
+		return io.NopCloser(bytes.NewReader(ctx.PostBody()))
+	}
+	return pipeStream(&ctx.Request)
+}
+
+// parse reads the parts of r into form.
+func (opts *MultipartOptions) parse(ctx *fasthttp.RequestCtx, r *multipart.Reader, form *MultipartForm) error {
+	for n := 0; ; n++ {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if n == opts.MaxParts {
+			return errPartTooLarge
+		}
+
+		if part.FileName() == "" {
+			var value bytes.Buffer
+			if err := copyLimited(&value, part, opts.MaxValueSize); err != nil {
+				return err
+			}
+			form.Values[part.FormName()] = append(form.Values[part.FormName()], value.String())
+			continue
+		}
+		file, err := opts.store(ctx, part)
+		if file != nil {
+			form.Files = append(form.Files, file)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// store sniffs the type of a file part and copies it
+// to a file in Dir or to Sink.
+// The returned file is not nil once a file was created for it,
+// so that it gets removed.
+func (opts *MultipartOptions) store(ctx *fasthttp.RequestCtx, part *multipart.Part) (*MultipartFile, error) {
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(part, sniff)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	sniff = sniff[:n]
+
+	file := &MultipartFile{
+		FormName:    part.FormName(),
+		FileName:    part.FileName(),
+		Header:      part.Header,
+		ContentType: mediaType([]byte(http.DetectContentType(sniff))),
+	}
+	if len(opts.AllowedTypes) > 0 && !containsString(opts.AllowedTypes, file.ContentType) {
+		return nil, errPartType
+	}
+
+	var w io.WriteCloser
+	if opts.Sink != nil {
+		w, err = opts.Sink(ctx, file)
+	} else {
+		var f *os.File
+		if f, err = os.CreateTemp(opts.Dir, "fastalice-upload-"); err == nil {
+			file.Path = f.Name()
+			w = f
+		}
+	}
+	if err != nil {
+		return nil, errPartWrite
+	}
+
+	counted := &countingWriter{w: w}
+	err = copyLimited(counted, io.MultiReader(bytes.NewReader(sniff), part), opts.MaxPartSize)
+	file.Size = counted.n
+	if cerr := w.Close(); err == nil && cerr != nil {
+		err = errPartWrite
+	}
+	return file, err
+}
+
+// copyLimited copies r to w, failing with errPartTooLarge past limit bytes.
+func copyLimited(w io.Writer, r io.Reader, limit int64) error {
+	n, err := io.Copy(w, io.LimitReader(r, limit+1))
+	if err == nil && n > limit {
+		return errPartTooLarge
+	}
+	return err
+}
+
+// countingWriter counts the bytes written to w,
+// failing with errPartWrite when w fails.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	if err != nil {
+		return n, errPartWrite
+	}
+	return n, nil
+}
+
+// removeFiles removes the files the parts of form were written to.
+func (form *MultipartForm) removeFiles() {
+	for _, file := range form.Files {
+		if file.Path != "" {
+			os.Remove(file.Path)
+		}
+	}
+}
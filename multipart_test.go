@@ -0,0 +1,116 @@
+package fastalice
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+func newMultipartCtx(t *testing.T, stream bool, fields map[string]string, files map[string][]byte) *fasthttp.RequestCtx {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for name, value := range fields {
+		assert.NoError(t, w.WriteField(name, value), "The field should be written")
+	}
+	for name, content := range files {
+		fw, err := w.CreateFormFile(name, name+".bin")
+		assert.NoError(t, err, "The file part should be created")
+		fw.Write(content)
+	}
+	assert.NoError(t, w.Close(), "The body should be closed")
+
+	ctx := newTestCtx("POST", "http://localhost/upload")
+	ctx.Request.Header.SetContentType(w.FormDataContentType())
+	if stream {
+		ctx.Request.SetBodyStream(&body, -1)
+	} else {
+		ctx.Request.SetBody(body.Bytes())
+	}
+	return ctx
+}
+
+func TestMultipartToDisk(t *testing.T) {
+	var form *MultipartForm
+	var content []byte
+	h := New(Multipart(MultipartOptions{Dir: t.TempDir()})).Then(func(ctx *fasthttp.RequestCtx) {
+		form = GetMultipartForm(ctx)
+		content, _ = os.ReadFile(form.Files[0].Path)
+	})
+
+	image := append(append([]byte{}, pngHeader...), "pixels"...)
+	for _, stream := range []bool{false, true} {
+		ctx := newMultipartCtx(t, stream, map[string]string{"title": "Holidays"}, map[string][]byte{"photo": image})
+		h(ctx)
+		assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "The upload should be accepted")
+		assert.Equal(t, []string{"Holidays"}, form.Values["title"], "Values should be parsed")
+		file := form.Files[0]
+		assert.Equal(t, "photo", file.FormName, "The form name should be kept")
+		assert.Equal(t, "photo.bin", file.FileName, "The file name should be kept")
+		assert.Equal(t, "image/png", file.ContentType, "The type should be sniffed")
+		assert.Equal(t, int64(len(image)), file.Size, "The size should be counted")
+		assert.Equal(t, image, content, "The part should be written to disk")
+		_, err := os.Stat(file.Path)
+		assert.True(t, os.IsNotExist(err), "The file should be removed after the handlers")
+	}
+}
+
+type sinkBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *sinkBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestMultipartSink(t *testing.T) {
+	sink := &sinkBuffer{}
+	h := New(Multipart(MultipartOptions{
+		Sink: func(ctx *fasthttp.RequestCtx, file *MultipartFile) (io.WriteCloser, error) {
+			return sink, nil
+		},
+	})).Then(func(ctx *fasthttp.RequestCtx) {
+		assert.Empty(t, GetMultipartForm(ctx).Files[0].Path, "Parts sent to the sink should have no path")
+	})
+
+	ctx := newMultipartCtx(t, false, nil, map[string][]byte{"doc": []byte("hello")})
+	h(ctx)
+	assert.Equal(t, "hello", sink.String(), "The part should be copied to the sink")
+	assert.True(t, sink.closed, "The sink should be closed")
+}
+
+func TestMultipartRejections(t *testing.T) {
+	h := New(Multipart(MultipartOptions{
+		Dir:          t.TempDir(),
+		MaxPartSize:  16,
+		MaxValueSize: 4,
+		AllowedTypes: []string{"image/png"},
+	})).Then(testApp)
+
+	for name, tc := range map[string]struct {
+		fields map[string]string
+		files  map[string][]byte
+		status int
+	}{
+		"large file":  {files: map[string][]byte{"photo": append(append([]byte{}, pngHeader...), "0123456789"...)}, status: fasthttp.StatusRequestEntityTooLarge},
+		"large value": {fields: map[string]string{"title": "Holidays"}, status: fasthttp.StatusRequestEntityTooLarge},
+		"type":        {files: map[string][]byte{"doc": []byte("plain text")}, status: fasthttp.StatusUnsupportedMediaType},
+	} {
+		ctx := newMultipartCtx(t, false, tc.fields, tc.files)
+		h(ctx)
+		assert.Equal(t, tc.status, ctx.Response.StatusCode(), "The %s should be rejected", name)
+	}
+
+	ctx := newTestCtx("POST", "http://localhost/upload")
+	ctx.Request.Header.SetContentType("application/json")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusUnsupportedMediaType, ctx.Response.StatusCode(), "Other bodies should be rejected")
+}
@@ -0,0 +1,99 @@
+package fastalice
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/valyala/fasthttp"
+)
+
+// MutableChain is a chain whose constructors
+// can be added and removed while it is serving requests.
+//
+// Every change publishes a new immutable Chain;
+// handlers returned by Handler pick it up on their next request
+// and rebuild themselves once,
+// so serving requests never takes a lock.
+// The zero value is an empty chain ready to use.
+type MutableChain struct {
+	mu      sync.Mutex // serializes writers
+	current atomic.Value
+}
+
+// NewMutable creates a mutable chain
+// holding the given constructors.
+func NewMutable(constructors ...Constructor) *MutableChain {
+	m := &MutableChain{}
+	chain := New(constructors...)
+	m.current.Store(&chain)
+	return m
+}
+
+// Chain returns a snapshot of the current constructors.
+func (m *MutableChain) Chain() Chain {
+	return *m.load()
+}
+
+// load returns the current chain.
+// The zero value stores its empty chain on first use,
+// so that handlers see the same chain until it changes.
+func (m *MutableChain) load() *Chain {
+	chain, _ := m.current.Load().(*Chain)
+	if chain == nil {
+		m.current.CompareAndSwap(nil, &Chain{})
+		chain = m.current.Load().(*Chain)
+	}
+	return chain
+}
+
+// Add appends c as the last constructor in the request flow.
+// A nil constructor is ignored.
+func (m *MutableChain) Add(c Constructor) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	chain := m.load().Append(c)
+	m.current.Store(&chain)
+}
+
+// RemoveAt removes the constructor at index i.
+// It returns an error when i is out of range.
+func (m *MutableChain) RemoveAt(i int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cur := m.load()
+	if i < 0 || i >= len(cur.constructors) {
+		return fmt.Errorf("fastalice: index %d out of range [0, %d)", i, len(cur.constructors))
+	}
+
+	chain := join(cur.slice(0, i), cur.slice(i+1, len(cur.constructors)))
+	m.current.Store(&chain)
+	return nil
+}
+
+// compiledChain is a chain composed with its final handler.
+type compiledChain struct {
+	chain   *Chain
+	handler fasthttp.RequestHandler
+}
+
+// Handler returns a stable fasthttp.RequestHandler
+// that always runs final through the current set of constructors.
+//
+// As with Then, constructors are called again
+// each time the set of constructors changes.
+func (m *MutableChain) Handler(final fasthttp.RequestHandler) fasthttp.RequestHandler {
+	var compiled atomic.Value
+
+	return func(ctx *fasthttp.RequestCtx) {
+		cur := m.load()
+		c, _ := compiled.Load().(*compiledChain)
+		if c == nil || c.chain != cur {
+			c = &compiledChain{cur, cur.Then(final)}
+			compiled.Store(c)
+		}
+		c.handler(ctx)
+	}
+}
@@ -0,0 +1,76 @@
+package fastalice
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMutableChainAddAndRemove(t *testing.T) {
+	m := NewMutable(tagMiddleware("t1\n"))
+	h := m.Handler(testApp)
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, "t1\napp", string(ctx.Response.Body()), "The initial constructors should run")
+
+	m.Add(tagMiddleware("t2\n"))
+	ctx = newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, "t1\nt2\napp", string(ctx.Response.Body()), "Added constructors should run")
+
+	assert.Nil(t, m.RemoveAt(0), "Removing an existing constructor should succeed")
+	ctx = newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, "t2\napp", string(ctx.Response.Body()), "Removed constructors should not run")
+
+	assert.EqualError(t, m.RemoveAt(1), "fastalice: index 1 out of range [0, 1)", "Removing out of range should fail")
+}
+
+func TestMutableChainZeroValue(t *testing.T) {
+	var m MutableChain
+	m.Add(nil)
+	m.Add(tagMiddleware("t1\n"))
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	m.Handler(testApp)(ctx)
+	assert.Equal(t, "t1\napp", string(ctx.Response.Body()), "The zero value should be usable")
+	assert.Equal(t, 1, len(m.Chain().constructors), "Nil constructors should be ignored")
+}
+
+func TestMutableChainZeroValueDoesNotRebuild(t *testing.T) {
+	var m MutableChain
+	h := m.Handler(testApp)
+
+	h(newTestCtx("GET", "http://localhost/"))
+	first := m.load()
+	h(newTestCtx("GET", "http://localhost/"))
+	assert.Same(t, first, m.load(), "The zero value should keep one empty chain, so handlers are not rebuilt")
+}
+
+func TestMutableChainConcurrentUse(t *testing.T) {
+	m := NewMutable()
+	h := m.Handler(testApp)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				h(newTestCtx("GET", "http://localhost/"))
+			}
+		}()
+	}
+
+	for j := 0; j < 200; j++ {
+		m.Add(tagMiddleware(""))
+		if j%2 == 0 {
+			m.RemoveAt(0)
+		}
+	}
+	wg.Wait()
+
+	assert.Equal(t, 100, len(m.Chain().constructors), "Every mutation should be applied")
+}
@@ -0,0 +1,133 @@
+package fastalice
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Mux dispatches requests to handlers by path and method,
+// so that each group of routes can have its own chain.
+//
+//	mux := fastalice.NewMux()
+//	mux.Handle("GET", "/api/", apiChain.Then(apiHandler))
+//	admin := mux.Group("/admin", adminChain)
+//	admin.Handle("POST", "/users", createUser)
+//	fasthttp.ListenAndServe(":8080", fastalice.New(logger).Then(mux.Handler()))
+//
+// A pattern ending in a slash matches every path under it;
+// any other pattern matches its path exactly.
+// The longest matching pattern wins.
+type Mux struct {
+	routes []muxRoute
+
+	// NotFound handles requests matching no pattern.
+	// When nil, the default handler of Then() is used.
+	NotFound fasthttp.RequestHandler
+}
+
+type muxRoute struct {
+	pattern string
+	methods *MethodMux
+}
+
+// NewMux creates an empty Mux.
+func NewMux() *Mux {
+	return &Mux{}
+}
+
+// Handle registers h for requests with the given method
+// whose path matches pattern.
+// An empty method or "*" matches every method.
+func (m *Mux) Handle(method, pattern string, h fasthttp.RequestHandler) {
+	route := m.route(pattern)
+	if method == "" {
+		method = "*"
+	}
+	route.methods.Handle(method, h)
+}
+
+func (m *Mux) route(pattern string) *muxRoute {
+	for i := range m.routes {
+		if m.routes[i].pattern == pattern {
+			return &m.routes[i]
+		}
+	}
+	m.routes = append(m.routes, muxRoute{pattern, NewMethodMux()})
+	return &m.routes[len(m.routes)-1]
+}
+
+// Group returns a group of routes sharing a path prefix and a chain.
+func (m *Mux) Group(prefix string, chain Chain) *MuxGroup {
+	return &MuxGroup{mux: m, prefix: strings.TrimRight(prefix, "/"), chain: chain}
+}
+
+// Handler returns a fasthttp.RequestHandler
+// dispatching requests to the registered handlers.
+// Requests whose path matches but whose method does not
+// are answered with 405 Method Not Allowed.
+//
+// Handler takes a snapshot of the registered handlers,
+// so later registrations do not affect it.
+func (m *Mux) Handler() fasthttp.RequestHandler {
+	type compiledRoute struct {
+		pattern string
+		subtree bool
+		any     fasthttp.RequestHandler
+		methods fasthttp.RequestHandler
+		exact   map[string]fasthttp.RequestHandler
+	}
+
+	routes := make([]compiledRoute, 0, len(m.routes))
+	for _, r := range m.routes {
+		exact := make(map[string]fasthttp.RequestHandler, len(r.methods.handlers))
+		for method, h := range r.methods.handlers {
+			exact[method] = h
+		}
+		routes = append(routes, compiledRoute{
+			pattern: r.pattern,
+			subtree: strings.HasSuffix(r.pattern, "/"),
+			any:     exact["*"],
+			methods: r.methods.Handler(),
+			exact:   exact,
+		})
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		return len(routes[i].pattern) > len(routes[j].pattern)
+	})
+	notFound := New().Then(m.NotFound)
+
+	return func(ctx *fasthttp.RequestCtx) {
+		path := string(ctx.Path())
+		for _, r := range routes {
+			if path != r.pattern && !(r.subtree && strings.HasPrefix(path, r.pattern)) {
+				continue
+			}
+			if h, ok := r.exact[string(ctx.Method())]; ok {
+				h(ctx)
+			} else if r.any != nil {
+				r.any(ctx)
+			} else {
+				r.methods(ctx)
+			}
+			return
+		}
+		notFound(ctx)
+	}
+}
+
+// MuxGroup registers routes on a Mux
+// under a common path prefix and through a common chain.
+type MuxGroup struct {
+	mux    *Mux
+	prefix string
+	chain  Chain
+}
+
+// Handle registers h, wrapped by the group's chain,
+// for requests with the given method
+// whose path matches the group prefix followed by pattern.
+func (g *MuxGroup) Handle(method, pattern string, h fasthttp.RequestHandler) {
+	g.mux.Handle(method, g.prefix+pattern, g.chain.Then(h))
+}
@@ -0,0 +1,55 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func writer(s string) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		ctx.WriteString(s)
+	}
+}
+
+func testMux() fasthttp.RequestHandler {
+	mux := NewMux()
+	mux.Handle("GET", "/api/", New(tagMiddleware("api\n")).Then(writer("list")))
+	mux.Handle("", "/api/health", writer("healthy"))
+
+	admin := mux.Group("/admin/", New(tagMiddleware("auth\n")))
+	admin.Handle("POST", "/users", writer("created"))
+
+	return New(tagMiddleware("log\n")).Then(mux.Handler())
+}
+
+func muxBody(method, uri string) (int, string) {
+	ctx := newTestCtx(method, uri)
+	testMux()(ctx)
+	return ctx.Response.StatusCode(), string(ctx.Response.Body())
+}
+
+func TestMuxRoutesToChains(t *testing.T) {
+	_, body := muxBody("GET", "http://localhost/api/items")
+	assert.Equal(t, "log\napi\nlist", body, "Subtree patterns should match paths under them")
+
+	_, body = muxBody("POST", "http://localhost/api/health")
+	assert.Equal(t, "log\nhealthy", body, "The longest pattern should win and match any method")
+
+	_, body = muxBody("POST", "http://localhost/admin/users")
+	assert.Equal(t, "log\nauth\ncreated", body, "Group routes should run through the group chain")
+}
+
+func TestMuxMethodNotAllowed(t *testing.T) {
+	status, _ := muxBody("DELETE", "http://localhost/api/items")
+	assert.Equal(t, fasthttp.StatusMethodNotAllowed, status, "Matching paths with other methods should return Method Not Allowed")
+}
+
+func TestMuxNotFound(t *testing.T) {
+	status, _ := muxBody("GET", "http://localhost/admin/users/1")
+	assert.Equal(t, fasthttp.StatusNotFound, status, "Exact patterns should not match longer paths")
+
+	status, _ = muxBody("GET", "http://localhost/other")
+	assert.Equal(t, fasthttp.StatusNotFound, status, "Unmatched paths should return Not Found")
+}
@@ -0,0 +1,69 @@
+package fastalice
+
+// NamedConstructor is a constructor labeled with a name,
+// so that chains holding it can be inspected and edited by name.
+type NamedConstructor struct {
+	Name        string
+	Constructor Constructor
+}
+
+// namedChain returns a chain holding the given named constructors.
+func namedChain(constructors []NamedConstructor) Chain {
+	c := Chain{
+		constructors: make([]Constructor, len(constructors)),
+		meta:         make([]constructorMeta, len(constructors)),
+	}
+	for i, nc := range constructors {
+		c.constructors[i], c.meta[i].name = nc.Constructor, nc.Name
+	}
+	return c
+}
+
+// NewNamed creates a new chain like New,
+// from constructors labeled with names.
+//
+//	chain := fastalice.NewNamed(
+//		fastalice.NamedConstructor{Name: "logging", Constructor: logger},
+//		fastalice.NamedConstructor{Name: "auth", Constructor: auth},
+//	)
+//	chain.Names() // ["logging", "auth"]
+func NewNamed(constructors ...NamedConstructor) Chain {
+	return join(namedChain(constructors))
+}
+
+// AppendNamed is like Append, for constructors labeled with names.
+func (c Chain) AppendNamed(constructors ...NamedConstructor) Chain {
+	return join(c, namedChain(constructors))
+}
+
+// Names returns the names of the constructors of the chain, in order.
+// Constructors added without a name are listed as an empty string.
+func (c Chain) Names() []string {
+	names := make([]string, len(c.constructors))
+	for i := range names {
+		names[i] = c.nameAt(i)
+	}
+	return names
+}
+
+// Has reports whether the chain holds a constructor named name.
+func (c Chain) Has(name string) bool {
+	for i := range c.constructors {
+		if c.nameAt(i) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Remove returns a new chain without the constructors named name,
+// leaving the original one untouched.
+func (c Chain) Remove(name string) Chain {
+	parts := make([]Chain, 0, len(c.constructors))
+	for i := range c.constructors {
+		if c.nameAt(i) != name {
+			parts = append(parts, c.slice(i, i+1))
+		}
+	}
+	return join(parts...)
+}
@@ -0,0 +1,51 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamedIntrospection(t *testing.T) {
+	chain := NewNamed(
+		NamedConstructor{"logging", tagMiddleware("log\n")},
+		NamedConstructor{"auth", tagMiddleware("auth\n")},
+	).Append(tagMiddleware("anon\n"))
+
+	assert.Equal(t, []string{"logging", "auth", ""}, chain.Names(), "Names should list every constructor in order")
+	assert.True(t, chain.Has("auth"), "Has should find named constructors")
+	assert.False(t, chain.Has("metrics"), "Has should not find missing constructors")
+}
+
+func TestNamedRemove(t *testing.T) {
+	chain := NewNamed(
+		NamedConstructor{"logging", tagMiddleware("log\n")},
+		NamedConstructor{"auth", tagMiddleware("auth\n")},
+	)
+	removed := chain.Remove("logging")
+
+	assert.Equal(t, []string{"auth"}, removed.Names(), "Remove should drop the named constructor")
+	assert.Equal(t, []string{"logging", "auth"}, chain.Names(), "Remove should not touch the original chain")
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	removed.Then(testApp)(ctx)
+	assert.Equal(t, "auth\napp", string(ctx.Response.Body()), "The removed constructor should not run")
+}
+
+func TestNamesSurviveComposition(t *testing.T) {
+	named := NewNamed(NamedConstructor{"auth", tagMiddleware("auth\n")})
+	chain := New(tagMiddleware("t1\n")).Extend(named).AppendNamed(NamedConstructor{"metrics", tagMiddleware("m\n")})
+
+	assert.Equal(t, []string{"", "auth", "metrics"}, chain.Names(), "Names should survive Extend and AppendNamed")
+	assert.Equal(t, []string{"metrics", "auth", ""}, chain.Reverse().Names(), "Names should survive Reverse")
+	assert.Equal(t, []string{"", "auth", "metrics", "auth"}, chain.Merge(named).Names(), "Names should survive Merge")
+	assert.Equal(t, []string{"", "auth", "metrics"}, chain.Clone().Names(), "Names should survive Clone")
+	assert.Equal(t, []string{"", ""}, New(tagMiddleware(""), tagMiddleware("")).Names(), "Unnamed constructors should be listed as empty names")
+}
+
+func TestMutableChainKeepsNames(t *testing.T) {
+	m := NewMutable()
+	m.Add(tagMiddleware(""))
+	chain := m.Chain().AppendNamed(NamedConstructor{"auth", tagMiddleware("")})
+	assert.Equal(t, []string{"", "auth"}, chain.Names(), "Names should be kept")
+}
@@ -0,0 +1,26 @@
+package fastalice
+
+import (
+	"sync"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Once returns a constructor that runs initFn
+// with the first request going through the chain,
+// before calling the following handlers.
+// initFn runs exactly once across all requests;
+// concurrent requests wait for it to complete.
+//
+// Each call to Then creates a new handler with its own guard,
+// so initFn runs once per composed handler.
+func Once(initFn func(ctx *fasthttp.RequestCtx)) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		var once sync.Once
+
+		return func(ctx *fasthttp.RequestCtx) {
+			once.Do(func() { initFn(ctx) })
+			next(ctx)
+		}
+	}
+}
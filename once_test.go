@@ -0,0 +1,32 @@
+package fastalice
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestOnceRunsExactlyOnce(t *testing.T) {
+	var runs, served int32
+	h := New(Once(func(ctx *fasthttp.RequestCtx) {
+		atomic.AddInt32(&runs, 1)
+	})).Then(func(ctx *fasthttp.RequestCtx) {
+		atomic.AddInt32(&served, 1)
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h(newTestCtx("GET", "http://localhost/"))
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs), "initFn should run exactly once")
+	assert.Equal(t, int32(10), atomic.LoadInt32(&served), "Every request should reach the handler")
+}
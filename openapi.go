@@ -0,0 +1,658 @@
+package fastalice
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError describes why a request does not match
+// an OpenAPI document.
+type ValidationError struct {
+	// In is where the invalid value is:
+	// "path", "query", "header", "cookie" or "body".
+	In string `json:"in"`
+	// Name is the name of the parameter,
+	// or the JSON path of the invalid body value, such as "$.items[0].id".
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+// validateConfig holds the options of OpenAPIValidate.
+type validateConfig struct {
+	basePath      string
+	rejectUnknown bool
+	onInvalid     func(ctx *fasthttp.RequestCtx, errs []ValidationError)
+}
+
+// ValidateOption configures OpenAPIValidate.
+type ValidateOption func(*validateConfig)
+
+// ValidateBasePath sets a prefix stripped from request paths
+// before matching them against the paths of the document,
+// such as "/v1" for a document served under /v1.
+func ValidateBasePath(prefix string) ValidateOption {
+	return func(c *validateConfig) {
+		c.basePath = strings.TrimSuffix(prefix, "/")
+	}
+}
+
+// ValidateRejectUnknown makes OpenAPIValidate answer requests
+// to paths missing from the document with 404 Not Found,
+// and requests with an undocumented method with 405 Method Not Allowed.
+// By default they go on unchecked.
+func ValidateRejectUnknown() ValidateOption {
+	return func(c *validateConfig) {
+		c.rejectUnknown = true
+	}
+}
+
+// ValidateErrorHandler sets the function answering invalid requests.
+// It defaults to a 400 Bad Request with a JSON body of the form
+// {"error": "invalid request", "errors": [{"in": ..., "name": ..., "message": ...}]}.
+func ValidateErrorHandler(fn func(ctx *fasthttp.RequestCtx, errs []ValidationError)) ValidateOption {
+	return func(c *validateConfig) {
+		c.onInvalid = fn
+	}
+}
+
+// defaultValidationErrorHandler answers invalid requests
+// with 400 Bad Request and the errors as JSON.
+func defaultValidationErrorHandler(ctx *fasthttp.RequestCtx, errs []ValidationError) {
+	JSON(ctx, fasthttp.StatusBadRequest, struct {
+		Error  string            `json:"error"`
+		Errors []ValidationError `json:"errors"`
+	}{"invalid request", errs})
+}
+
+// NewOpenAPIValidate returns a constructor validating requests
+// against spec, an OpenAPI 3 document in the JSON or YAML format,
+// before calling the following handlers.
+// Invalid requests are answered by the handler set
+// with ValidateErrorHandler, with 400 Bad Request by default.
+//
+// Path, query, header and cookie parameters are checked
+// for presence and against their schema,
+// and JSON request bodies against the schema of their media type;
+// bodies of other media types are only checked to be documented,
+// undocumented ones being answered with 415 Unsupported Media Type.
+//
+// Schemas support type, nullable, enum, the numeric, length
+// and item count bounds, pattern, required, properties,
+// additionalProperties, items, allOf, anyOf, oneOf
+// and local $ref references; other keywords are ignored.
+//
+// An error is returned if spec does not parse
+// or holds invalid patterns.
+func NewOpenAPIValidate(spec []byte, opts ...ValidateOption) (Constructor, error) {
+	cfg := validateConfig{onInvalid: defaultValidationErrorHandler}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	doc, err := parseOpenAPI(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			path := string(ctx.Path())
+			if cfg.basePath != "" {
+				if !strings.HasPrefix(path, cfg.basePath) {
+					next(ctx)
+					return
+				}
+				path = path[len(cfg.basePath):]
+			}
+
+			route, params := doc.match(path)
+			if route == nil {
+				if cfg.rejectUnknown {
+					ctx.Error(fasthttp.StatusMessage(fasthttp.StatusNotFound), fasthttp.StatusNotFound)
+					return
+				}
+				next(ctx)
+				return
+			}
+			op, ok := route.operations[strings.ToLower(string(ctx.Method()))]
+			if !ok {
+				if cfg.rejectUnknown {
+					ctx.Error(fasthttp.StatusMessage(fasthttp.StatusMethodNotAllowed), fasthttp.StatusMethodNotAllowed)
+					return
+				}
+				next(ctx)
+				return
+			}
+
+			errs, unsupported := doc.validate(ctx, op, params)
+			if unsupported {
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusUnsupportedMediaType), fasthttp.StatusUnsupportedMediaType)
+				return
+			}
+			if len(errs) > 0 {
+				cfg.onInvalid(ctx, errs)
+				return
+			}
+			next(ctx)
+		}
+	}, nil
+}
+
+// OpenAPIValidate is like NewOpenAPIValidate, but panics
+// if spec is invalid. It is meant for documents embedded
+// in the program.
+//
+//	//go:embed openapi.yaml
+//	var spec []byte
+//
+//	chain := fastalice.New(fastalice.OpenAPIValidate(spec))
+func OpenAPIValidate(spec []byte, opts ...ValidateOption) Constructor {
+	c, err := NewOpenAPIValidate(spec, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// openAPIDoc is a parsed OpenAPI document.
+type openAPIDoc struct {
+	root     map[string]interface{}
+	routes   []*openAPIRoute
+	patterns map[string]*regexp.Regexp
+}
+
+// openAPIRoute is a path of an OpenAPI document.
+type openAPIRoute struct {
+	// segments are the segments of the path template;
+	// those naming a parameter, such as "{id}", hold its name in params.
+	segments   []string
+	params     []string
+	literals   int
+	operations map[string]*openAPIOperation
+}
+
+// openAPIOperation is an operation of an OpenAPI document.
+type openAPIOperation struct {
+	params []openAPIParam
+	// body is the request body, or nil if none is documented.
+	body *openAPIBody
+}
+
+type openAPIParam struct {
+	name, in string
+	required bool
+	schema   map[string]interface{}
+}
+
+type openAPIBody struct {
+	required bool
+	// content maps media types to their schema, possibly nil.
+	content map[string]map[string]interface{}
+}
+
+// openAPIMethods are the operations of a path item.
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// parseOpenAPI parses spec.
+// YAML being a superset of JSON, both formats go through yaml.v3.
+func parseOpenAPI(spec []byte) (*openAPIDoc, error) {
+	var root map[string]interface{}
+	if err := yaml.Unmarshal(spec, &root); err != nil {
+		return nil, fmt.Errorf("fastalice: invalid OpenAPI spec: %w", err)
+	}
+	doc := &openAPIDoc{root: root, patterns: make(map[string]*regexp.Regexp)}
+
+	paths, _ := root["paths"].(map[string]interface{})
+	for template, item := range paths {
+		item := doc.resolve(item)
+		route := &openAPIRoute{operations: make(map[string]*openAPIOperation)}
+		for _, seg := range strings.Split(strings.Trim(template, "/"), "/") {
+			if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+				route.params = append(route.params, seg[1:len(seg)-1])
+			} else {
+				route.params = append(route.params, "")
+				route.literals++
+			}
+			route.segments = append(route.segments, seg)
+		}
+
+		shared := doc.params(item["parameters"])
+		for _, method := range openAPIMethods {
+			raw, ok := item[method]
+			if !ok {
+				continue
+			}
+			opItem := doc.resolve(raw)
+			op := &openAPIOperation{params: mergeParams(shared, doc.params(opItem["parameters"]))}
+			if rb, ok := opItem["requestBody"]; ok {
+				op.body = doc.body(doc.resolve(rb))
+			}
+			route.operations[method] = op
+		}
+		doc.routes = append(doc.routes, route)
+	}
+	// Concrete paths win over templated ones, as OpenAPI requires.
+	sort.SliceStable(doc.routes, func(i, j int) bool {
+		return doc.routes[i].literals > doc.routes[j].literals
+	})
+
+	if err := doc.compilePatterns(root); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// compilePatterns compiles the patterns of every schema of v.
+func (d *openAPIDoc) compilePatterns(v interface{}) error {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if p, ok := v["pattern"].(string); ok {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return fmt.Errorf("fastalice: invalid OpenAPI pattern %q: %w", p, err)
+			}
+			d.patterns[p] = re
+		}
+		for _, child := range v {
+			if err := d.compilePatterns(child); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, child := range v {
+			if err := d.compilePatterns(child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolve returns the object v, following its $ref, if any.
+func (d *openAPIDoc) resolve(v interface{}) map[string]interface{} {
+	for i := 0; i < 32; i++ {
+		m, _ := v.(map[string]interface{})
+		ref, ok := m["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#/") {
+			return m
+		}
+		v = d.root
+		for _, key := range strings.Split(ref[2:], "/") {
+			key = strings.ReplaceAll(strings.ReplaceAll(key, "~1", "/"), "~0", "~")
+			obj, _ := v.(map[string]interface{})
+			v = obj[key]
+		}
+	}
+	return nil
+}
+
+// params parses a list of parameters.
+func (d *openAPIDoc) params(v interface{}) []openAPIParam {
+	list, _ := v.([]interface{})
+	params := make([]openAPIParam, 0, len(list))
+	for _, raw := range list {
+		p := d.resolve(raw)
+		name, _ := p["name"].(string)
+		in, _ := p["in"].(string)
+		required, _ := p["required"].(bool)
+		params = append(params, openAPIParam{
+			name:     name,
+			in:       in,
+			required: required || in == "path",
+			schema:   d.resolve(p["schema"]),
+		})
+	}
+	return params
+}
+
+// mergeParams returns the path-level parameters shared,
+// overridden by the operation-level ones own.
+func mergeParams(shared, own []openAPIParam) []openAPIParam {
+	merged := append([]openAPIParam(nil), own...)
+	for _, s := range shared {
+		overridden := false
+		for _, o := range own {
+			overridden = overridden || (o.name == s.name && o.in == s.in)
+		}
+		if !overridden {
+			merged = append(merged, s)
+		}
+	}
+	return merged
+}
+
+// body parses a request body.
+func (d *openAPIDoc) body(rb map[string]interface{}) *openAPIBody {
+	required, _ := rb["required"].(bool)
+	b := &openAPIBody{required: required, content: make(map[string]map[string]interface{})}
+	content, _ := rb["content"].(map[string]interface{})
+	for mt, media := range content {
+		m, _ := media.(map[string]interface{})
+		b.content[strings.ToLower(mt)] = d.resolve(m["schema"])
+	}
+	return b
+}
+
+// match returns the route matching path, with its path parameters.
+func (d *openAPIDoc) match(path string) (*openAPIRoute, map[string]string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, route := range d.routes {
+		if len(route.segments) != len(segments) {
+			continue
+		}
+		var params map[string]string
+		matched := true
+		for i, seg := range segments {
+			if name := route.params[i]; name != "" {
+				if params == nil {
+					params = make(map[string]string, len(route.params))
+				}
+				params[name] = seg
+			} else if seg != route.segments[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return route, params
+		}
+	}
+	return nil, nil
+}
+
+// validate checks the request against op,
+// reporting whether its body has an undocumented media type.
+func (d *openAPIDoc) validate(ctx *fasthttp.RequestCtx, op *openAPIOperation, pathParams map[string]string) ([]ValidationError, bool) {
+	var errs []ValidationError
+	for _, p := range op.params {
+		var values []string
+		switch p.in {
+		case "path":
+			if v, ok := pathParams[p.name]; ok {
+				values = []string{v}
+			}
+		case "query":
+			for _, v := range ctx.QueryArgs().PeekMulti(p.name) {
+				values = append(values, string(v))
+			}
+		case "header":
+			if v := ctx.Request.Header.Peek(p.name); v != nil {
+				values = []string{string(v)}
+			}
+		case "cookie":
+			if v := ctx.Request.Header.Cookie(p.name); v != nil {
+				values = []string{string(v)}
+			}
+		}
+
+		if len(values) == 0 {
+			if p.required {
+				errs = append(errs, ValidationError{p.in, p.name, "is required"})
+			}
+			continue
+		}
+		if p.schema != nil {
+			for _, msg := range d.validateParam(p.schema, values) {
+				errs = append(errs, ValidationError{p.in, p.name, msg})
+			}
+		}
+	}
+
+	if op.body == nil {
+		return errs, false
+	}
+	body := ctx.PostBody()
+	if len(body) == 0 {
+		if op.body.required {
+			errs = append(errs, ValidationError{"body", "$", "is required"})
+		}
+		return errs, false
+	}
+	mt := mediaType(ctx.Request.Header.ContentType())
+	schema, ok := op.body.content[mt]
+	if !ok {
+		if schema, ok = op.body.content[mt[:strings.IndexByte(mt+"/", '/')]+"/*"]; !ok {
+			if schema, ok = op.body.content["*/*"]; !ok {
+				return errs, true
+			}
+		}
+	}
+	if schema == nil || (mt != "application/json" && !strings.HasSuffix(mt, "+json")) {
+		return errs, false
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return append(errs, ValidationError{"body", "$", "is not valid JSON"}), false
+	}
+	for _, e := range d.validateValue(schema, v, "$") {
+		errs = append(errs, ValidationError{"body", e.path, e.msg})
+	}
+	return errs, false
+}
+
+// validateParam checks the values of a parameter against schema,
+// converting them to the type it declares.
+func (d *openAPIDoc) validateParam(schema map[string]interface{}, values []string) []string {
+	var v interface{}
+	if typ, _ := schema["type"].(string); typ == "array" {
+		var items []interface{}
+		itemSchema := d.resolve(schema["items"])
+		for _, value := range values {
+			for _, part := range strings.Split(value, ",") {
+				items = append(items, paramValue(itemSchema, part))
+			}
+		}
+		v = items
+	} else {
+		v = paramValue(schema, values[0])
+	}
+
+	var msgs []string
+	for _, e := range d.validateValue(schema, v, "") {
+		msgs = append(msgs, e.msg)
+	}
+	return msgs
+}
+
+// paramValue converts the string s to the type declared by schema,
+// leaving it a string if it does not convert.
+func paramValue(schema map[string]interface{}, s string) interface{} {
+	switch typ, _ := schema["type"].(string); typ {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(s, 64); err == nil {
+			return json.Number(s)
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	}
+	return s
+}
+
+// schemaError is an error found by validateValue.
+type schemaError struct {
+	path, msg string
+}
+
+// validateValue checks v, decoded from JSON with numbers
+// as json.Number, against schema.
+func (d *openAPIDoc) validateValue(schema map[string]interface{}, v interface{}, path string) []schemaError {
+	schema = d.resolve(schema)
+	if schema == nil {
+		return nil
+	}
+	fail := func(format string, args ...interface{}) []schemaError {
+		return []schemaError{{path, fmt.Sprintf(format, args...)}}
+	}
+
+	if v == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable || schema["type"] == nil {
+			return nil
+		}
+		return fail("must not be null")
+	}
+
+	var errs []schemaError
+	for _, sub := range asList(schema["allOf"]) {
+		errs = append(errs, d.validateValue(d.resolve(sub), v, path)...)
+	}
+	if anyOf := asList(schema["anyOf"]); anyOf != nil && d.countMatches(anyOf, v, path) == 0 {
+		errs = append(errs, fail("must match at least one schema of anyOf")...)
+	}
+	if oneOf := asList(schema["oneOf"]); oneOf != nil && d.countMatches(oneOf, v, path) != 1 {
+		errs = append(errs, fail("must match exactly one schema of oneOf")...)
+	}
+	if enum := asList(schema["enum"]); enum != nil && !inEnum(enum, v) {
+		errs = append(errs, fail("must be one of %v", enum)...)
+	}
+
+	typ, _ := schema["type"].(string)
+	switch v := v.(type) {
+	case string:
+		if typ != "" && typ != "string" {
+			return append(errs, fail("must be of type %s", typ)...)
+		}
+		n := float64(len([]rune(v)))
+		if min, ok := asNumber(schema["minLength"]); ok && n < min {
+			errs = append(errs, fail("must be at least %v characters long", min)...)
+		}
+		if max, ok := asNumber(schema["maxLength"]); ok && n > max {
+			errs = append(errs, fail("must be at most %v characters long", max)...)
+		}
+		if p, ok := schema["pattern"].(string); ok && !d.patterns[p].MatchString(v) {
+			errs = append(errs, fail("must match pattern %s", p)...)
+		}
+	case json.Number:
+		f, _ := v.Float64()
+		if typ != "" && typ != "number" && typ != "integer" {
+			return append(errs, fail("must be of type %s", typ)...)
+		}
+		if typ == "integer" && f != math.Trunc(f) {
+			return append(errs, fail("must be an integer")...)
+		}
+		if min, ok := asNumber(schema["minimum"]); ok {
+			if exclusive, _ := schema["exclusiveMinimum"].(bool); exclusive && f <= min {
+				errs = append(errs, fail("must be greater than %v", min)...)
+			} else if f < min {
+				errs = append(errs, fail("must be greater than or equal to %v", min)...)
+			}
+		}
+		if max, ok := asNumber(schema["maximum"]); ok {
+			if exclusive, _ := schema["exclusiveMaximum"].(bool); exclusive && f >= max {
+				errs = append(errs, fail("must be less than %v", max)...)
+			} else if f > max {
+				errs = append(errs, fail("must be less than or equal to %v", max)...)
+			}
+		}
+	case bool:
+		if typ != "" && typ != "boolean" {
+			return append(errs, fail("must be of type %s", typ)...)
+		}
+	case []interface{}:
+		if typ != "" && typ != "array" {
+			return append(errs, fail("must be of type %s", typ)...)
+		}
+		n := float64(len(v))
+		if min, ok := asNumber(schema["minItems"]); ok && n < min {
+			errs = append(errs, fail("must have at least %v items", min)...)
+		}
+		if max, ok := asNumber(schema["maxItems"]); ok && n > max {
+			errs = append(errs, fail("must have at most %v items", max)...)
+		}
+		if items := d.resolve(schema["items"]); items != nil {
+			for i, item := range v {
+				errs = append(errs, d.validateValue(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case map[string]interface{}:
+		if typ != "" && typ != "object" {
+			return append(errs, fail("must be of type %s", typ)...)
+		}
+		for _, name := range asList(schema["required"]) {
+			if name, ok := name.(string); ok {
+				if _, ok := v[name]; !ok {
+					errs = append(errs, schemaError{path + "." + name, "is required"})
+				}
+			}
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if prop, ok := props[name]; ok {
+				errs = append(errs, d.validateValue(d.resolve(prop), v[name], path+"."+name)...)
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					errs = append(errs, schemaError{path + "." + name, "is not allowed"})
+				}
+			case map[string]interface{}:
+				errs = append(errs, d.validateValue(extra, v[name], path+"."+name)...)
+			}
+		}
+	}
+	return errs
+}
+
+// countMatches returns how many of schemas v matches.
+func (d *openAPIDoc) countMatches(schemas []interface{}, v interface{}, path string) int {
+	n := 0
+	for _, s := range schemas {
+		if len(d.validateValue(d.resolve(s), v, path)) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func asList(v interface{}) []interface{} {
+	list, _ := v.([]interface{})
+	return list
+}
+
+// asNumber returns v, a number decoded by yaml.v3, as a float64.
+func asNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// inEnum reports whether v, decoded from JSON,
+// is one of the values of enum, decoded from the document.
+func inEnum(enum []interface{}, v interface{}) bool {
+	for _, e := range enum {
+		if n, ok := v.(json.Number); ok {
+			f, _ := n.Float64()
+			if ef, ok := asNumber(e); ok && ef == f {
+				return true
+			}
+			continue
+		}
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package fastalice
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+const testOpenAPISpec = `
+openapi: 3.0.3
+info: {title: pets, version: "1"}
+paths:
+  /pets:
+    get:
+      parameters:
+        - {name: limit, in: query, schema: {type: integer, minimum: 1, maximum: 100}}
+        - {name: tag, in: query, schema: {type: array, items: {type: string, enum: [cat, dog]}}}
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema: {$ref: "#/components/schemas/Pet"}
+  /pets/{id}:
+    parameters:
+      - {name: id, in: path, schema: {type: integer}}
+    get:
+      parameters:
+        - {name: X-Tenant, in: header, required: true, schema: {type: string, pattern: "^[a-z]+$"}}
+  /pets/mine:
+    get: {}
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      additionalProperties: false
+      properties:
+        name: {type: string, minLength: 1}
+        age: {type: integer, minimum: 0}
+        tags: {type: array, items: {type: string}, maxItems: 2}
+`
+
+// validationErrors decodes the errors of a default 400 response.
+func validationErrors(t *testing.T, ctx *fasthttp.RequestCtx) []ValidationError {
+	var body struct {
+		Errors []ValidationError `json:"errors"`
+	}
+	assert.NoError(t, json.Unmarshal(ctx.Response.Body(), &body), "The errors should be JSON")
+	return body.Errors
+}
+
+func TestOpenAPIValidateParameters(t *testing.T) {
+	h := New(OpenAPIValidate([]byte(testOpenAPISpec))).Then(testApp)
+
+	ctx := newTestCtx("GET", "http://localhost/pets?limit=10&tag=cat&tag=dog")
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Valid queries should go on")
+
+	ctx = newTestCtx("GET", "http://localhost/pets?limit=0&tag=fish")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusBadRequest, ctx.Response.StatusCode(), "Invalid queries should be rejected")
+	assert.Equal(t, []ValidationError{
+		{"query", "limit", "must be greater than or equal to 1"},
+		{"query", "tag", "must be one of [cat dog]"},
+	}, validationErrors(t, ctx), "Every invalid parameter should be reported")
+
+	ctx = newTestCtx("GET", "http://localhost/pets/abc")
+	h(ctx)
+	assert.Equal(t, []ValidationError{
+		{"header", "X-Tenant", "is required"},
+		{"path", "id", "must be of type integer"},
+	}, validationErrors(t, ctx), "Path and header parameters should be checked")
+
+	ctx = newTestCtx("GET", "http://localhost/pets/mine")
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Concrete paths should win over templated ones")
+
+	ctx = newTestCtx("GET", "http://localhost/pets/1")
+	ctx.Request.Header.Set("X-Tenant", "acme")
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Valid path and header parameters should go on")
+}
+
+func TestOpenAPIValidateBody(t *testing.T) {
+	h := New(OpenAPIValidate([]byte(testOpenAPISpec))).Then(testApp)
+	post := func(contentType, body string) *fasthttp.RequestCtx {
+		ctx := newTestCtx("POST", "http://localhost/pets")
+		ctx.Request.Header.SetContentType(contentType)
+		ctx.Request.SetBodyString(body)
+		h(ctx)
+		return ctx
+	}
+
+	ctx := post("application/json", `{"name":"rex","age":3,"tags":["a"]}`)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Valid bodies should go on")
+
+	ctx = post("application/json", `{"age":1.5,"tags":["a","b",3],"color":"red"}`)
+	assert.Equal(t, []ValidationError{
+		{"body", "$.name", "is required"},
+		{"body", "$.age", "must be an integer"},
+		{"body", "$.color", "is not allowed"},
+		{"body", "$.tags", "must have at most 2 items"},
+		{"body", "$.tags[2]", "must be of type string"},
+	}, validationErrors(t, ctx), "Body errors should be reported with their path")
+
+	ctx = post("application/json", `{`)
+	assert.Equal(t, []ValidationError{{"body", "$", "is not valid JSON"}}, validationErrors(t, ctx), "Malformed bodies should be rejected")
+
+	ctx = post("application/json", ``)
+	assert.Equal(t, []ValidationError{{"body", "$", "is required"}}, validationErrors(t, ctx), "Required bodies should be enforced")
+
+	ctx = post("text/plain", `rex`)
+	assert.Equal(t, fasthttp.StatusUnsupportedMediaType, ctx.Response.StatusCode(), "Undocumented media types should be rejected")
+}
+
+func TestOpenAPIValidateUnknown(t *testing.T) {
+	spec := []byte(testOpenAPISpec)
+
+	ctx := newTestCtx("GET", "http://localhost/owners")
+	New(OpenAPIValidate(spec)).Then(testApp)(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Unknown paths should go on by default")
+
+	strict := New(OpenAPIValidate(spec, ValidateRejectUnknown(), ValidateBasePath("/v1"))).Then(testApp)
+	ctx = newTestCtx("GET", "http://localhost/v1/owners")
+	strict(ctx)
+	assert.Equal(t, fasthttp.StatusNotFound, ctx.Response.StatusCode(), "Unknown paths should be rejected when asked")
+
+	ctx = newTestCtx("DELETE", "http://localhost/v1/pets")
+	strict(ctx)
+	assert.Equal(t, fasthttp.StatusMethodNotAllowed, ctx.Response.StatusCode(), "Undocumented methods should be rejected when asked")
+
+	ctx = newTestCtx("GET", "http://localhost/v1/pets?limit=5")
+	strict(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "The base path should be stripped")
+}
+
+func TestOpenAPIValidateErrorHandler(t *testing.T) {
+	var got []ValidationError
+	h := New(OpenAPIValidate([]byte(testOpenAPISpec), ValidateErrorHandler(func(ctx *fasthttp.RequestCtx, errs []ValidationError) {
+		got = errs
+		ctx.SetStatusCode(fasthttp.StatusUnprocessableEntity)
+	}))).Then(testApp)
+
+	ctx := newTestCtx("GET", "http://localhost/pets?limit=x")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusUnprocessableEntity, ctx.Response.StatusCode(), "The error handler should answer")
+	assert.Equal(t, []ValidationError{{"query", "limit", "must be of type integer"}}, got, "The error handler should get the errors")
+}
+
+func TestNewOpenAPIValidateErrors(t *testing.T) {
+	_, err := NewOpenAPIValidate([]byte("paths: ["))
+	assert.Error(t, err, "Unparsable specs should be rejected")
+
+	_, err = NewOpenAPIValidate([]byte(`{"paths": {"/": {"get": {"parameters": [{"name": "q", "in": "query", "schema": {"pattern": "("}}]}}}}`))
+	assert.Error(t, err, "Invalid patterns should be rejected")
+}
@@ -0,0 +1,77 @@
+package fastalice
+
+import "fmt"
+
+// OrderRule is a constraint on the order of the constructors of a chain,
+// checked by Validate. It is given the names of the constructors,
+// in request flow order, as returned by Names,
+// and returns an error describing the first violation.
+type OrderRule func(names []string) error
+
+// Outermost returns a rule requiring the constructor named name,
+// when the chain holds it, to come first in the request flow,
+// such as a recovery middleware that must see every panic.
+func Outermost(name string) OrderRule {
+	return func(names []string) error {
+		for i, n := range names {
+			if n == name && i != 0 {
+				return fmt.Errorf("fastalice: %q must be outermost, found at index %d", name, i)
+			}
+		}
+		return nil
+	}
+}
+
+// Innermost returns a rule requiring the constructor named name,
+// when the chain holds it, to come last in the request flow.
+func Innermost(name string) OrderRule {
+	return func(names []string) error {
+		for i, n := range names {
+			if n == name && i != len(names)-1 {
+				return fmt.Errorf("fastalice: %q must be innermost, found at index %d", name, i)
+			}
+		}
+		return nil
+	}
+}
+
+// Before returns a rule requiring the constructors named first
+// to come before those named then in the request flow,
+// when the chain holds both. For example, compression must come
+// after ETag so that tags are computed on the uncompressed body:
+//
+//	err := chain.Validate(fastalice.Before("etag", "compress"))
+func Before(first, then string) OrderRule {
+	return func(names []string) error {
+		seen := -1
+		for i, n := range names {
+			switch {
+			case n == then && seen < 0:
+				seen = i
+			case n == first && seen >= 0:
+				return fmt.Errorf("fastalice: %q at index %d must come before %q at index %d", first, i, then, seen)
+			}
+		}
+		return nil
+	}
+}
+
+// NoDuplicates returns a rule rejecting chains
+// holding several constructors under the same name,
+// such as a logger added both by a shared chain and by a route.
+// Unnamed constructors are not checked.
+func NoDuplicates() OrderRule {
+	return func(names []string) error {
+		indexes := make(map[string]int, len(names))
+		for i, n := range names {
+			if n == "" {
+				continue
+			}
+			if j, ok := indexes[n]; ok {
+				return fmt.Errorf("fastalice: %q found at both index %d and %d", n, j, i)
+			}
+			indexes[n] = i
+		}
+		return nil
+	}
+}
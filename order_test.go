@@ -0,0 +1,26 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateOrderRules(t *testing.T) {
+	named := func(names ...string) Chain {
+		var ncs []NamedConstructor
+		for _, n := range names {
+			ncs = append(ncs, NamedConstructor{Name: n, Constructor: tagMiddleware(n)})
+		}
+		return NewNamed(ncs...)
+	}
+	rules := []OrderRule{Outermost("recover"), Innermost("render"), Before("etag", "compress"), NoDuplicates()}
+
+	assert.Nil(t, named("recover", "etag", "log", "compress", "render").Validate(rules...), "A well-ordered chain should be valid")
+	assert.Nil(t, named("log").Append(tagMiddleware("x")).Validate(rules...), "Rules should ignore absent constructors")
+
+	assert.EqualError(t, named("log", "recover").Validate(rules...), `fastalice: "recover" must be outermost, found at index 1`, "Outermost should be enforced")
+	assert.EqualError(t, named("render", "log").Validate(rules...), `fastalice: "render" must be innermost, found at index 0`, "Innermost should be enforced")
+	assert.EqualError(t, named("compress", "log", "etag").Validate(rules...), `fastalice: "etag" at index 2 must come before "compress" at index 0`, "Before should be enforced")
+	assert.EqualError(t, named("log", "auth", "log").Validate(rules...), `fastalice: "log" found at both index 0 and 2`, "Duplicates should be detected")
+}
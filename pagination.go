@@ -0,0 +1,42 @@
+package fastalice
+
+import (
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultPaginationHintHeader is the header set by PaginationHint
+// when no header name is given.
+const DefaultPaginationHintHeader = "X-Result-Truncated"
+
+// PaginationHint returns a constructor that advises clients to paginate
+// when a JSON response body grows over maxBytes.
+// Once the following handlers have run,
+// it sets header to "paginate" on oversized JSON responses.
+// The body itself is never truncated.
+func PaginationHint(maxBytes int, header string) Constructor {
+	if header == "" {
+		header = DefaultPaginationHintHeader
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			next(ctx)
+
+			if !isJSON(ctx.Response.Header.ContentType()) {
+				return
+			}
+			if len(ctx.Response.Body()) > maxBytes {
+				ctx.Response.Header.Set(header, "paginate")
+			}
+		}
+	}
+}
+
+// isJSON reports whether contentType denotes a JSON document,
+// including the structured "+json" suffix types.
+func isJSON(contentType []byte) bool {
+	mt := mediaType(contentType)
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
@@ -0,0 +1,34 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func jsonApp(body string) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		ctx.SetContentType("application/json; charset=utf-8")
+		ctx.SetBodyString(body)
+	}
+}
+
+func TestPaginationHintOversizedResponse(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/items")
+	New(PaginationHint(8, "")).Then(jsonApp(`[1,2,3,4,5,6,7,8]`))(ctx)
+	assert.Equal(t, "paginate", string(ctx.Response.Header.Peek(DefaultPaginationHintHeader)), "Oversized JSON responses should carry the hint")
+	assert.Equal(t, `[1,2,3,4,5,6,7,8]`, string(ctx.Response.Body()), "The body should not be truncated")
+}
+
+func TestPaginationHintSmallResponse(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/items")
+	New(PaginationHint(8, "X-Paginate")).Then(jsonApp(`[1]`))(ctx)
+	assert.Empty(t, ctx.Response.Header.Peek("X-Paginate"), "Small responses should not carry the hint")
+}
+
+func TestPaginationHintIgnoresNonJSON(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/items")
+	New(PaginationHint(1, "")).Then(testApp)(ctx)
+	assert.Empty(t, ctx.Response.Header.Peek(DefaultPaginationHintHeader), "Non-JSON responses should not carry the hint")
+}
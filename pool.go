@@ -0,0 +1,274 @@
+package fastalice
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Backend is an upstream of an UpstreamPool.
+type Backend struct {
+	// URL is the upstream URL the backend was created from.
+	URL string
+
+	target    *proxyTarget
+	inFlight  int64
+	unhealthy int32
+}
+
+// InFlight returns the number of requests
+// currently proxied to the backend.
+func (b *Backend) InFlight() int64 {
+	return atomic.LoadInt64(&b.inFlight)
+}
+
+// Healthy reports whether the backend passed its last health check.
+// Backends are healthy until checked.
+func (b *Backend) Healthy() bool {
+	return atomic.LoadInt32(&b.unhealthy) == 0
+}
+
+func (b *Backend) setHealthy(healthy bool) {
+	var v int32
+	if !healthy {
+		v = 1
+	}
+	atomic.StoreInt32(&b.unhealthy, v)
+}
+
+// Strategy selects the backend serving a request
+// among the healthy ones, which it is never called without.
+type Strategy func(backends []*Backend) *Backend
+
+// RoundRobin returns a Strategy cycling through the backends.
+func RoundRobin() Strategy {
+	var n uint64
+	return func(backends []*Backend) *Backend {
+		i := atomic.AddUint64(&n, 1) - 1
+		return backends[i%uint64(len(backends))]
+	}
+}
+
+// LeastConnections returns a Strategy picking the backend
+// with the fewest requests in flight, the first one on ties.
+func LeastConnections() Strategy {
+	return func(backends []*Backend) *Backend {
+		best := backends[0]
+		for _, b := range backends[1:] {
+			if b.InFlight() < best.InFlight() {
+				best = b
+			}
+		}
+		return best
+	}
+}
+
+// Weighted returns a Strategy spreading requests
+// in proportion to the weight of each backend, keyed by URL,
+// with the smooth weighted round-robin of nginx.
+// Backends without a positive weight get a weight of 1.
+func Weighted(weights map[string]int) Strategy {
+	var (
+		mu      sync.Mutex
+		current = make(map[*Backend]int)
+	)
+	return func(backends []*Backend) *Backend {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var best *Backend
+		total := 0
+		for _, b := range backends {
+			w := weights[b.URL]
+			if w <= 0 {
+				w = 1
+			}
+			total += w
+			current[b] += w
+			if best == nil || current[b] > current[best] {
+				best = b
+			}
+		}
+		current[best] -= total
+		return best
+	}
+}
+
+// HealthProbe configures the active health checks of an UpstreamPool.
+type HealthProbe struct {
+	// Path is requested with GET on every backend;
+	// a 2xx or 3xx answer marks it healthy.
+	Path string
+	// Interval is the time between two rounds of checks.
+	Interval time.Duration
+	// Timeout bounds every check, 2 seconds by default.
+	Timeout time.Duration
+}
+
+// poolConfig holds the settings of ProxyPool.
+type poolConfig struct {
+	proxy  ProxyOptions
+	health *HealthProbe
+}
+
+// PoolOption configures ProxyPool.
+type PoolOption func(*poolConfig)
+
+// PoolProxyOptions sets the options used to proxy to every backend.
+func PoolProxyOptions(opts ProxyOptions) PoolOption {
+	return func(c *poolConfig) { c.proxy = opts }
+}
+
+// PoolHealthCheck enables active health checks.
+// Backends failing them, or failing a proxied request,
+// are taken out of rotation until a check succeeds again.
+func PoolHealthCheck(check HealthProbe) PoolOption {
+	return func(c *poolConfig) { c.health = &check }
+}
+
+// UpstreamPool is a load-balancing reverse proxy
+// over a set of upstreams. Use its Handler method
+// as the final handler of a chain:
+//
+//	pool := fastalice.ProxyPool(upstreams, fastalice.LeastConnections(),
+//		fastalice.PoolHealthCheck(fastalice.HealthProbe{Path: "/healthz", Interval: 5 * time.Second}))
+//	defer pool.Close()
+//	chained := fastalice.New(m1, m2).Then(pool.Handler)
+type UpstreamPool struct {
+	backends []*Backend
+	strategy Strategy
+	health   *HealthProbe
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewProxyPool creates an UpstreamPool balancing requests
+// over upstreams, absolute http or https URLs proxied as by ProxyTo,
+// with strategy. When health checks are enabled,
+// a first round runs before it returns
+// and further rounds run in the background until Close.
+func NewProxyPool(upstreams []string, strategy Strategy, opts ...PoolOption) (*UpstreamPool, error) {
+	var cfg poolConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if len(upstreams) == 0 {
+		return nil, errNoUpstreams
+	}
+
+	p := &UpstreamPool{strategy: strategy, health: cfg.health, stop: make(chan struct{})}
+	for _, u := range upstreams {
+		t, err := newProxyTarget(u, cfg.proxy)
+		if err != nil {
+			return nil, err
+		}
+		p.backends = append(p.backends, &Backend{URL: u, target: t})
+	}
+
+	if p.health != nil {
+		if p.health.Timeout <= 0 {
+			p.health.Timeout = 2 * time.Second
+		}
+		p.CheckHealth()
+		if p.health.Interval > 0 {
+			go p.runHealthChecks()
+		}
+	}
+	return p, nil
+}
+
+// ProxyPool is like NewProxyPool, but panics on error.
+func ProxyPool(upstreams []string, strategy Strategy, opts ...PoolOption) *UpstreamPool {
+	p, err := NewProxyPool(upstreams, strategy, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// errNoUpstreams is returned when a pool is created without upstreams.
+var errNoUpstreams = errors.New("fastalice: proxy pool requires at least one upstream")
+
+// Backends returns the backends of the pool, in the order of upstreams.
+func (p *UpstreamPool) Backends() []*Backend {
+	return append([]*Backend(nil), p.backends...)
+}
+
+// Handler proxies the request to a healthy backend
+// selected by the strategy of the pool.
+// When no backend is healthy, it answers with 503 Service Unavailable.
+func (p *UpstreamPool) Handler(ctx *fasthttp.RequestCtx) {
+	healthy := make([]*Backend, 0, len(p.backends))
+	for _, b := range p.backends {
+		if b.Healthy() {
+			healthy = append(healthy, b)
+		}
+	}
+	if len(healthy) == 0 {
+		ctx.Error(fasthttp.StatusMessage(fasthttp.StatusServiceUnavailable), fasthttp.StatusServiceUnavailable)
+		return
+	}
+
+	b := p.strategy(healthy)
+	atomic.AddInt64(&b.inFlight, 1)
+	err := b.target.serve(ctx)
+	atomic.AddInt64(&b.inFlight, -1)
+	if err != nil && p.health != nil {
+		b.setHealthy(false)
+	}
+}
+
+// CheckHealth runs a round of health checks on every backend
+// and returns once they are over.
+// It does nothing when health checks are not enabled.
+func (p *UpstreamPool) CheckHealth() {
+	if p.health == nil {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, b := range p.backends {
+		wg.Add(1)
+		go func(b *Backend) {
+			defer wg.Done()
+			b.setHealthy(p.check(b))
+		}(b)
+	}
+	wg.Wait()
+}
+
+func (p *UpstreamPool) check(b *Backend) bool {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(b.target.path + p.health.Path)
+	req.Header.SetHost(b.target.host)
+	if err := b.target.client.DoTimeout(req, resp, p.health.Timeout); err != nil {
+		return false
+	}
+	code := resp.StatusCode()
+	return code >= 200 && code < 400
+}
+
+func (p *UpstreamPool) runHealthChecks() {
+	ticker := time.NewTicker(p.health.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.CheckHealth()
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// Close stops the background health checks.
+func (p *UpstreamPool) Close() {
+	p.stopOnce.Do(func() { close(p.stop) })
+}
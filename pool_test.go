@@ -0,0 +1,106 @@
+package fastalice
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// poolUpstreams serves one in-memory upstream per name,
+// answering with its name, and returns their URLs
+// along with the dialer reaching them.
+// Upstreams stored in down refuse connections and drop open ones.
+func poolUpstreams(t *testing.T, names []string, down *sync.Map) ([]string, fasthttp.DialFunc) {
+	if down == nil {
+		down = &sync.Map{}
+	}
+	dials := make(map[string]fasthttp.DialFunc)
+	var urls []string
+	for _, name := range names {
+		name := name
+		dials[name+":80"] = startUpstream(t, func(ctx *fasthttp.RequestCtx) {
+			if _, ok := down.Load(name); ok {
+				ctx.Conn().Close()
+				return
+			}
+			ctx.WriteString(name)
+		})
+		urls = append(urls, "http://"+name)
+	}
+	return urls, func(addr string) (net.Conn, error) {
+		if _, ok := down.Load(addr[:len(addr)-3]); ok {
+			return nil, errors.New("refused")
+		}
+		return dials[addr](addr)
+	}
+}
+
+func poolServe(p *UpstreamPool) string {
+	ctx := newTestCtx("GET", "http://localhost/")
+	p.Handler(ctx)
+	return string(ctx.Response.Body())
+}
+
+func TestProxyPoolRoundRobin(t *testing.T) {
+	urls, dial := poolUpstreams(t, []string{"a", "b", "c"}, nil)
+	p := ProxyPool(urls, RoundRobin(), PoolProxyOptions(ProxyOptions{Dial: dial}))
+	defer p.Close()
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, poolServe(p))
+	}
+	assert.Equal(t, []string{"a", "b", "c", "a"}, got, "Backends should be used in turn")
+}
+
+func TestProxyPoolWeighted(t *testing.T) {
+	urls, dial := poolUpstreams(t, []string{"a", "b"}, nil)
+	p := ProxyPool(urls, Weighted(map[string]int{"http://a": 3}), PoolProxyOptions(ProxyOptions{Dial: dial}))
+	defer p.Close()
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, poolServe(p))
+	}
+	assert.Equal(t, []string{"a", "a", "b", "a"}, got, "Backends should be used in proportion to their weight")
+}
+
+func TestLeastConnections(t *testing.T) {
+	backends := []*Backend{{URL: "a", inFlight: 2}, {URL: "b", inFlight: 1}, {URL: "c", inFlight: 1}}
+	assert.Equal(t, "b", LeastConnections()(backends).URL, "The least loaded backend should be picked")
+}
+
+func TestProxyPoolHealthChecks(t *testing.T) {
+	down := &sync.Map{}
+	down.Store("b", true)
+	urls, dial := poolUpstreams(t, []string{"a", "b"}, down)
+	p := ProxyPool(urls, RoundRobin(),
+		PoolProxyOptions(ProxyOptions{Dial: dial}),
+		PoolHealthCheck(HealthProbe{Path: "/healthz"}))
+	defer p.Close()
+
+	assert.False(t, p.Backends()[1].Healthy(), "Failing backends should be marked unhealthy")
+	assert.Equal(t, "a", poolServe(p), "Unhealthy backends should be skipped")
+	assert.Equal(t, "a", poolServe(p), "Unhealthy backends should be skipped")
+
+	down.Delete("b")
+	p.CheckHealth()
+	assert.True(t, p.Backends()[1].Healthy(), "Recovered backends should be marked healthy")
+
+	down.Store("a", true)
+	down.Store("b", true)
+	poolServe(p)
+	poolServe(p)
+	ctx := newTestCtx("GET", "http://localhost/")
+	p.Handler(ctx)
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, ctx.Response.StatusCode(), "Failed backends should be taken out of rotation")
+}
+
+func TestProxyPoolRequiresUpstreams(t *testing.T) {
+	_, err := NewProxyPool(nil, RoundRobin())
+	assert.Error(t, err, "Pools without upstreams should be rejected")
+}
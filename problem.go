@@ -0,0 +1,212 @@
+package fastalice
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Error is an error describing an HTTP error response,
+// rendered as an RFC 7807 problem by Problems.
+// Error-aware handlers return it to fail with a given status:
+//
+//	return fastalice.NewError(fasthttp.StatusConflict, "out_of_stock", "Only 2 items left.").
+//		With("available", 2)
+type Error struct {
+	// Status is the HTTP status of the response,
+	// 500 Internal Server Error when zero.
+	Status int
+	// Code is a machine-readable code for the error, such as "out_of_stock",
+	// sent as the code extension member.
+	Code string
+	// Type is a URI identifying the problem type, "about:blank" when empty.
+	Type string
+	// Title is a short summary of the problem type,
+	// the status text when empty.
+	Title string
+	// Detail explains this occurrence of the problem to the client.
+	Detail string
+	// Instance is a URI identifying this occurrence of the problem.
+	Instance string
+	// Extensions are additional members of the problem.
+	Extensions map[string]interface{}
+	// Err is the underlying cause, which is never sent to the client.
+	Err error
+}
+
+// NewError returns an Error with the given status, code and detail.
+func NewError(status int, code, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail}
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("fastalice: %d %s", e.status(), e.Code)
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the underlying cause of e.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// With returns a copy of e with the extension member key set to v.
+func (e *Error) With(key string, v interface{}) *Error {
+	c := *e
+	c.Extensions = make(map[string]interface{}, len(e.Extensions)+1)
+	for k, ev := range e.Extensions {
+		c.Extensions[k] = ev
+	}
+	c.Extensions[key] = v
+	return &c
+}
+
+// Wrap returns a copy of e caused by err.
+func (e *Error) Wrap(err error) *Error {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+func (e *Error) status() int {
+	if e.Status == 0 {
+		return fasthttp.StatusInternalServerError
+	}
+	return e.Status
+}
+
+// Problem is an RFC 7807 problem details object.
+type Problem struct {
+	Type     string
+	Title    string
+	Status   int
+	Detail   string
+	Instance string
+	// Extensions are additional members, serialized
+	// next to the standard ones.
+	Extensions map[string]interface{}
+}
+
+// MarshalJSON encodes p with its extension members inline,
+// omitting the empty standard ones.
+func (p Problem) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	for k, v := range map[string]string{"type": p.Type, "title": p.Title, "detail": p.Detail, "instance": p.Instance} {
+		if v != "" {
+			m[k] = v
+		}
+	}
+	m["status"] = p.Status
+	return json.Marshal(m)
+}
+
+// problemConfig holds the settings of Problems.
+type problemConfig struct {
+	translate bool
+	hooks     []func(ctx *fasthttp.RequestCtx, p *Problem)
+}
+
+// ProblemOption configures Problems.
+type ProblemOption func(*problemConfig)
+
+// ProblemTranslate passes the title and detail of problems through T,
+// so that they can be message keys of the LocaleBundle of I18n.
+func ProblemTranslate() ProblemOption {
+	return func(c *problemConfig) { c.translate = true }
+}
+
+// ProblemHook calls fn on every problem before it is written,
+// such as to localize it or add extension members.
+func ProblemHook(fn func(ctx *fasthttp.RequestCtx, p *Problem)) ProblemOption {
+	return func(c *problemConfig) { c.hooks = append(c.hooks, fn) }
+}
+
+// Problems returns error-aware middleware answering errors
+// returned by the following handlers with an RFC 7807
+// application/problem+json response, and reporting them handled.
+// An Error, possibly wrapped, gives the members of the problem;
+// any other error is answered with a bare 500 Internal Server Error problem,
+// without leaking its message to the client.
+//
+//	chained := fastalice.New(fastalice.I18n(bundle)).
+//		AppendErr(fastalice.Problems(fastalice.ProblemTranslate())).
+//		ThenErr(h, nil)
+func Problems(opts ...ProblemOption) ErrorConstructor {
+	var cfg problemConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next Handler) Handler {
+		return func(ctx *fasthttp.RequestCtx) error {
+			err := next(ctx)
+			if err == nil {
+				return nil
+			}
+			p := problemFor(err)
+			if cfg.translate {
+				p.Title = T(ctx, p.Title)
+				if p.Detail != "" {
+					p.Detail = T(ctx, p.Detail)
+				}
+			}
+			for _, hook := range cfg.hooks {
+				hook(ctx, &p)
+			}
+			return WriteProblem(ctx, p)
+		}
+	}
+}
+
+// problemFor returns the problem describing err.
+func problemFor(err error) Problem {
+	var e *Error
+	if !errors.As(err, &e) {
+		return Problem{
+			Type:   "about:blank",
+			Title:  fasthttp.StatusMessage(fasthttp.StatusInternalServerError),
+			Status: fasthttp.StatusInternalServerError,
+		}
+	}
+
+	p := Problem{
+		Type:       e.Type,
+		Title:      e.Title,
+		Status:     e.status(),
+		Detail:     e.Detail,
+		Instance:   e.Instance,
+		Extensions: make(map[string]interface{}, len(e.Extensions)+1),
+	}
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = fasthttp.StatusMessage(p.Status)
+	}
+	for k, v := range e.Extensions {
+		p.Extensions[k] = v
+	}
+	if e.Code != "" {
+		p.Extensions["code"] = e.Code
+	}
+	return p
+}
+
+// WriteProblem answers the request with p as application/problem+json.
+func WriteProblem(ctx *fasthttp.RequestCtx, p Problem) error {
+	if err := JSON(ctx, p.Status, p); err != nil {
+		return err
+	}
+	ctx.SetContentType("application/problem+json")
+	return nil
+}
@@ -0,0 +1,77 @@
+package fastalice
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func decodeProblem(t *testing.T, ctx *fasthttp.RequestCtx) map[string]interface{} {
+	var p map[string]interface{}
+	assert.NoError(t, json.Unmarshal(ctx.Response.Body(), &p), "The problem should be JSON")
+	return p
+}
+
+func TestProblemsFromError(t *testing.T) {
+	cause := errors.New("stock query failed")
+	h := New().AppendErr(Problems()).ThenErr(func(ctx *fasthttp.RequestCtx) error {
+		err := NewError(fasthttp.StatusConflict, "out_of_stock", "Only 2 items left.").With("available", 2).Wrap(cause)
+		return fmt.Errorf("checkout: %w", err)
+	}, nil)
+
+	ctx := newTestCtx("POST", "http://localhost/checkout")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusConflict, ctx.Response.StatusCode(), "The status of the error should be used")
+	assert.Equal(t, "application/problem+json", string(ctx.Response.Header.ContentType()), "The problem media type should be used")
+	assert.Equal(t, map[string]interface{}{
+		"type":      "about:blank",
+		"title":     "Conflict",
+		"status":    float64(409),
+		"detail":    "Only 2 items left.",
+		"code":      "out_of_stock",
+		"available": float64(2),
+	}, decodeProblem(t, ctx), "The problem should carry the error members and extensions")
+}
+
+func TestProblemsHidesOtherErrors(t *testing.T) {
+	var handled error
+	h := New().AppendErr(Problems()).ThenErr(func(ctx *fasthttp.RequestCtx) error {
+		return errors.New("password=hunter2")
+	}, func(ctx *fasthttp.RequestCtx, err error) { handled = err })
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusInternalServerError, ctx.Response.StatusCode(), "Other errors should be 500")
+	assert.NotContains(t, string(ctx.Response.Body()), "hunter2", "The error message should not leak")
+	assert.Nil(t, handled, "The error should be reported handled")
+}
+
+func TestProblemsTranslateAndHook(t *testing.T) {
+	bundle := NewLocaleBundle("en")
+	bundle.Add("fr", map[string]string{"errors.stock": "Rupture de stock"})
+	h := New(I18n(bundle)).AppendErr(Problems(
+		ProblemTranslate(),
+		ProblemHook(func(ctx *fasthttp.RequestCtx, p *Problem) {
+			p.Instance = string(ctx.Path())
+		}),
+	)).ThenErr(func(ctx *fasthttp.RequestCtx) error {
+		return &Error{Status: fasthttp.StatusConflict, Title: "errors.stock"}
+	}, nil)
+
+	ctx := newTestCtx("GET", "http://localhost/cart")
+	ctx.Request.Header.Set(fasthttp.HeaderAcceptLanguage, "fr")
+	h(ctx)
+	p := decodeProblem(t, ctx)
+	assert.Equal(t, "Rupture de stock", p["title"], "The title should be translated")
+	assert.Equal(t, "/cart", p["instance"], "Hooks should change the problem")
+}
+
+func TestErrorMessage(t *testing.T) {
+	err := NewError(fasthttp.StatusNotFound, "no_user", "User 7 not found.").Wrap(errors.New("no rows"))
+	assert.Equal(t, "fastalice: 404 no_user: User 7 not found.: no rows", err.Error(), "The message should describe the error")
+	assert.Equal(t, "no rows", errors.Unwrap(err).Error(), "The cause should be unwrapped")
+}
@@ -0,0 +1,163 @@
+package fastalice
+
+import (
+	"encoding/json"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ConstructorProfile is the profile of a stage of a profiled chain.
+type ConstructorProfile struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+	// Calls is the number of requests that went through the stage.
+	Calls uint64 `json:"calls"`
+	// Time is the total wall time spent in the stage,
+	// excluding the stages it called.
+	Time time.Duration `json:"time_ns"`
+	// Allocs is the total number of heap allocations
+	// made while in the stage.
+	Allocs uint64 `json:"allocs"`
+}
+
+// profileStats accumulates the profile of a stage.
+type profileStats struct {
+	calls, nanos, allocs uint64
+}
+
+// profileMark tracks the time and allocations
+// of a stage during a request.
+type profileMark struct {
+	start      time.Time
+	allocStart uint64
+	nanos      time.Duration
+	allocs     uint64
+}
+
+func (m *profileMark) pause() {
+	m.nanos += now().Sub(m.start)
+	m.allocs += heapAllocs() - m.allocStart
+}
+
+func (m *profileMark) resume() {
+	m.start = now()
+	m.allocStart = heapAllocs()
+}
+
+// ProfiledHandler serves requests through a chain
+// while profiling each of its constructors and the final handler.
+type ProfiledHandler struct {
+	names   []string
+	stats   []profileStats
+	handler fasthttp.RequestHandler
+}
+
+// Profile chains the middleware with h like Then,
+// recording for each constructor, and for h listed last as "handler",
+// the wall time spent in it and the heap allocations it made,
+// excluding the stages it called.
+//
+// Allocations are counted process-wide,
+// so they are only accurate when requests are served one at a time,
+// as in benchmarks. Reading them briefly stops the world,
+// so profiling is meant for finding slow middleware, not for production.
+func Profile(chain Chain, h fasthttp.RequestHandler) *ProfiledHandler {
+	p := &ProfiledHandler{
+		names: append(chain.Names(), "handler"),
+		stats: make([]profileStats, len(chain.constructors)+1),
+	}
+	for i, name := range p.names {
+		if name == "" {
+			p.names[i] = strconv.Itoa(i)
+		}
+	}
+
+	final := p.stage(len(chain.constructors), "handler", func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return next
+	})
+	profiled := chain.Map(p.stage).Append(final)
+	p.handler = profiled.Then(h)
+	return p
+}
+
+// stage wraps the constructor at index i to profile it.
+func (p *ProfiledHandler) stage(i int, _ string, c Constructor) Constructor {
+	key := NewKey[*profileMark]("profile")
+	stats := &p.stats[i]
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		inner := c(func(ctx *fasthttp.RequestCtx) {
+			m, ok := Get(ctx, key)
+			if ok {
+				m.pause()
+			}
+			next(ctx)
+			if ok {
+				m.resume()
+			}
+		})
+
+		return func(ctx *fasthttp.RequestCtx) {
+			m := &profileMark{}
+			Set(ctx, key, m)
+			m.resume()
+			inner(ctx)
+			m.pause()
+			Delete(ctx, key)
+
+			atomic.AddUint64(&stats.calls, 1)
+			atomic.AddUint64(&stats.nanos, uint64(m.nanos))
+			atomic.AddUint64(&stats.allocs, m.allocs)
+		}
+	}
+}
+
+// Handler serves the request through the profiled chain.
+func (p *ProfiledHandler) Handler(ctx *fasthttp.RequestCtx) {
+	p.handler(ctx)
+}
+
+// Snapshot returns the profile of every stage, in request flow order.
+func (p *ProfiledHandler) Snapshot() []ConstructorProfile {
+	profiles := make([]ConstructorProfile, len(p.stats))
+	for i := range p.stats {
+		profiles[i] = ConstructorProfile{
+			Index:  i,
+			Name:   p.names[i],
+			Calls:  atomic.LoadUint64(&p.stats[i].calls),
+			Time:   time.Duration(atomic.LoadUint64(&p.stats[i].nanos)),
+			Allocs: atomic.LoadUint64(&p.stats[i].allocs),
+		}
+	}
+	return profiles
+}
+
+// Reset clears the recorded profiles.
+func (p *ProfiledHandler) Reset() {
+	for i := range p.stats {
+		atomic.StoreUint64(&p.stats[i].calls, 0)
+		atomic.StoreUint64(&p.stats[i].nanos, 0)
+		atomic.StoreUint64(&p.stats[i].allocs, 0)
+	}
+}
+
+// ReportHandler returns a handler answering with
+// the current snapshot as JSON, to be mounted on an admin route.
+func (p *ProfiledHandler) ReportHandler() fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		ctx.SetContentType("application/json")
+		json.NewEncoder(ctx).Encode(p.Snapshot())
+	}
+}
+
+// heapAllocs returns the number of heap allocations
+// made by the process so far.
+func heapAllocs() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.Mallocs
+}
@@ -0,0 +1,70 @@
+package fastalice
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestProfileRecordsStages(t *testing.T) {
+	defer fakeClock(time.Millisecond)()
+
+	var sink [][]byte
+	allocating := func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			for i := 0; i < 100; i++ {
+				sink = append(sink, make([]byte, 64))
+			}
+			next(ctx)
+		}
+	}
+	chain := NewNamed(NamedConstructor{"alloc", allocating}).Append(tagMiddleware("t1\n"))
+	p := Profile(chain, testApp)
+
+	for i := 0; i < 3; i++ {
+		ctx := newTestCtx("GET", "http://localhost/")
+		p.Handler(ctx)
+		assert.Equal(t, "t1\napp", string(ctx.Response.Body()), "The profiled chain should serve requests")
+	}
+
+	snapshot := p.Snapshot()
+	assert.Equal(t, 3, len(snapshot), "Every constructor and the handler should be profiled")
+	assert.Equal(t, "alloc", snapshot[0].Name, "Named constructors should keep their name")
+	assert.Equal(t, "1", snapshot[1].Name, "Unnamed constructors should be named after their index")
+	assert.Equal(t, "handler", snapshot[2].Name, "The handler should be listed last")
+	for _, s := range snapshot {
+		assert.Equal(t, uint64(3), s.Calls, "Stage %s should be called for every request", s.Name)
+		assert.True(t, s.Time > 0, "Stage %s should be timed", s.Name)
+	}
+	assert.True(t, snapshot[0].Allocs >= 300, "Allocations should be attributed to the allocating stage")
+
+	p.Reset()
+	assert.Equal(t, uint64(0), p.Snapshot()[0].Calls, "Reset should clear the profiles")
+}
+
+func TestProfileReportHandler(t *testing.T) {
+	p := Profile(New(tagMiddleware("")), testApp)
+	p.Handler(newTestCtx("GET", "http://localhost/"))
+
+	ctx := newTestCtx("GET", "http://localhost/debug/chain")
+	p.ReportHandler()(ctx)
+
+	var report []ConstructorProfile
+	assert.NoError(t, json.Unmarshal(ctx.Response.Body(), &report), "The report should be valid JSON")
+	assert.Equal(t, 2, len(report), "The report should list every stage")
+	assert.Equal(t, uint64(1), report[1].Calls, "The report should carry the calls")
+}
+
+func BenchmarkProfiledDispatch5(b *testing.B) {
+	p := Profile(New(benchmarkConstructors(5)...), noopApp)
+	ctx := newTestCtx("GET", "http://localhost/")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Handler(ctx)
+	}
+}
@@ -0,0 +1,86 @@
+package fastalice
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/valyala/fasthttp"
+)
+
+// providedKey holds the values given by Provide, by type.
+var providedKey = NewKey[map[reflect.Type]interface{}]("provided")
+
+// Provide returns a constructor making value available
+// to the following handlers with Use, under its type T,
+// so that middleware can get dependencies such as database pools
+// or loggers from the chain instead of capturing them in closures,
+// and tests can provide fakes:
+//
+//	chain := fastalice.New(
+//		fastalice.Provide(db),
+//		fastalice.Provide[Logger](logger),
+//		audit,
+//	)
+//
+//	func audit(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+//		return func(ctx *fasthttp.RequestCtx) {
+//			fastalice.Use[Logger](ctx).Info("request", string(ctx.Path()))
+//			next(ctx)
+//		}
+//	}
+//
+// T may be an interface type, given explicitly, so that
+// handlers depend on the interface rather than on the implementation.
+// A later Provide of the same type replaces the value
+// for the handlers following it.
+func Provide[T any](value T) Constructor {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			defer provide(ctx, t, value)()
+			next(ctx)
+		}
+	}
+}
+
+// provide makes v available to Use under type t,
+// returning a function restoring the value provided before,
+// for the handlers running after the following ones,
+// such as in a Mount or Tenant.
+func provide(ctx *fasthttp.RequestCtx, t reflect.Type, v interface{}) func() {
+	provided, ok := Get(ctx, providedKey)
+	if !ok {
+		provided = make(map[reflect.Type]interface{})
+		Set(ctx, providedKey, provided)
+	}
+	prev, had := provided[t]
+	provided[t] = v
+	return func() {
+		if had {
+			provided[t] = prev
+		} else {
+			delete(provided, t)
+		}
+	}
+}
+
+// Provided returns the value of type T given by Provide
+// to the handlers of the request, and whether there is one.
+func Provided[T any](ctx *fasthttp.RequestCtx) (T, bool) {
+	provided, _ := Get(ctx, providedKey)
+	v, ok := provided[reflect.TypeOf((*T)(nil)).Elem()].(T)
+	return v, ok
+}
+
+// Use returns the value of type T given by Provide
+// to the handlers of the request.
+// It panics if there is none, as a handler using a dependency
+// cannot serve requests on a chain not providing it.
+func Use[T any](ctx *fasthttp.RequestCtx) T {
+	v, ok := Provided[T](ctx)
+	if !ok {
+		panic(fmt.Sprintf("fastalice: no %v provided", reflect.TypeOf((*T)(nil)).Elem()))
+	}
+	return v
+}
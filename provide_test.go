@@ -0,0 +1,48 @@
+package fastalice
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+type testDB struct{ name string }
+
+func TestProvideUse(t *testing.T) {
+	var got *testDB
+	var w io.Writer
+	buf := &bytes.Buffer{}
+	h := New(Provide(&testDB{"primary"}), Provide[io.Writer](buf)).Then(func(ctx *fasthttp.RequestCtx) {
+		got = Use[*testDB](ctx)
+		w = Use[io.Writer](ctx)
+	})
+
+	h(newTestCtx("GET", "http://localhost/"))
+	assert.Equal(t, "primary", got.name, "The provided value should be used")
+	assert.Same(t, buf, w, "Values should be provided under interface types")
+}
+
+func TestProvideOverride(t *testing.T) {
+	var inner, outer string
+	innerChain := New(Provide(&testDB{"replica"})).Then(func(ctx *fasthttp.RequestCtx) {
+		inner = Use[*testDB](ctx).name
+	})
+	h := New(Provide(&testDB{"primary"})).Then(func(ctx *fasthttp.RequestCtx) {
+		innerChain(ctx)
+		outer = Use[*testDB](ctx).name
+	})
+
+	h(newTestCtx("GET", "http://localhost/"))
+	assert.Equal(t, "replica", inner, "A later Provide should replace the value")
+	assert.Equal(t, "primary", outer, "The outer value should be restored")
+}
+
+func TestUseMissing(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	_, ok := Provided[*testDB](ctx)
+	assert.False(t, ok, "Nothing should be provided")
+	assert.PanicsWithValue(t, "fastalice: no *fastalice.testDB provided", func() { Use[*testDB](ctx) }, "Using a missing value should panic")
+}
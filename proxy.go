@@ -0,0 +1,234 @@
+package fastalice
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ProxyOptions configures ProxyTo.
+type ProxyOptions struct {
+	// StripPrefix is removed from the request path
+	// before it is appended to the upstream path.
+	StripPrefix string
+	// PreserveHost forwards the Host header of the request
+	// instead of the upstream host.
+	PreserveHost bool
+	// RequestHeaders are set on the upstream request;
+	// an empty value removes the header.
+	RequestHeaders map[string]string
+	// ResponseHeaders are set on the response to the client;
+	// an empty value removes the header.
+	ResponseHeaders map[string]string
+	// Timeout bounds every upstream call; zero means none.
+	// Calls failing with a timeout are answered
+	// with 504 Gateway Timeout, other failures with 502 Bad Gateway.
+	Timeout time.Duration
+
+	// MaxConns limits the connections pooled to the upstream,
+	// fasthttp.DefaultMaxConnsPerHost by default.
+	MaxConns int
+	// MaxIdleConnDuration closes pooled connections idle for longer,
+	// fasthttp.DefaultMaxIdleConnDuration by default.
+	MaxIdleConnDuration time.Duration
+	// Dial opens upstream connections, fasthttp.Dial by default.
+	Dial fasthttp.DialFunc
+}
+
+// hopHeaders are the hop-by-hop headers not forwarded by proxies.
+var hopHeaders = []string{
+	fasthttp.HeaderConnection,
+	"Keep-Alive",
+	fasthttp.HeaderProxyAuthenticate,
+	fasthttp.HeaderProxyAuthorization,
+	fasthttp.HeaderTE,
+	fasthttp.HeaderTrailer,
+	fasthttp.HeaderTransferEncoding,
+	fasthttp.HeaderUpgrade,
+}
+
+// proxyTarget is an upstream served through a pooled HostClient.
+type proxyTarget struct {
+	client *fasthttp.HostClient
+	host   string
+	path   string
+	opts   ProxyOptions
+}
+
+// newProxyTarget parses upstream, an absolute http or https URL.
+func newProxyTarget(upstream string, opts ProxyOptions) (*proxyTarget, error) {
+	u, err := url.Parse(upstream)
+	if err != nil {
+		return nil, fmt.Errorf("fastalice: invalid upstream %q: %w", upstream, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("fastalice: upstream %q is not an absolute http or https URL", upstream)
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+	return &proxyTarget{
+		client: &fasthttp.HostClient{
+			Addr:                addr,
+			IsTLS:               u.Scheme == "https",
+			MaxConns:            opts.MaxConns,
+			MaxIdleConnDuration: opts.MaxIdleConnDuration,
+			Dial:                opts.Dial,
+		},
+		host: u.Host,
+		path: strings.TrimSuffix(u.Path, "/"),
+		opts: opts,
+	}, nil
+}
+
+// ProxyTo returns a handler forwarding requests to upstream,
+// an absolute http or https URL whose path prefixes the request path.
+// It is meant as the final handler of a chain:
+//
+//	chained := fastalice.New(m1, m2).Then(fastalice.ProxyTo("http://10.0.0.1:8080", fastalice.ProxyOptions{}))
+//
+// Connections to upstream are pooled.
+// Hop-by-hop headers are dropped, X-Forwarded-For,
+// X-Forwarded-Proto and X-Forwarded-Host are set,
+// and the upstream calls are marked for UpstreamTiming.
+// Headers set by earlier middleware are kept
+// unless the upstream response sets them too.
+//
+// ProxyTo panics when upstream is not a valid URL.
+func ProxyTo(upstream string, opts ProxyOptions) fasthttp.RequestHandler {
+	t, err := newProxyTarget(upstream, opts)
+	if err != nil {
+		panic(err)
+	}
+	return func(ctx *fasthttp.RequestCtx) {
+		t.serve(ctx)
+	}
+}
+
+// serve forwards the request to the target
+// and returns the upstream call error, if any.
+func (t *proxyTarget) serve(ctx *fasthttp.RequestCtx) error {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	t.prepareRequest(ctx, req)
+
+	MarkUpstreamStart(ctx)
+	var err error
+	if t.opts.Timeout > 0 {
+		err = t.client.DoTimeout(req, resp, t.opts.Timeout)
+	} else {
+		err = t.client.Do(req, resp)
+	}
+	MarkUpstreamEnd(ctx)
+
+	if err != nil {
+		code := fasthttp.StatusBadGateway
+		if errors.Is(err, fasthttp.ErrTimeout) {
+			code = fasthttp.StatusGatewayTimeout
+		}
+		ctx.Error(fasthttp.StatusMessage(code), code)
+		return err
+	}
+	t.copyResponse(ctx, resp)
+	return nil
+}
+
+func (t *proxyTarget) prepareRequest(ctx *fasthttp.RequestCtx, req *fasthttp.Request) {
+	ctx.Request.CopyTo(req)
+
+	path := ctx.Path()
+	if t.opts.StripPrefix != "" {
+		path = []byte(strings.TrimPrefix(string(path), t.opts.StripPrefix))
+		if len(path) == 0 || path[0] != '/' {
+			path = append([]byte{'/'}, path...)
+		}
+	}
+	uri := t.path + string(path)
+	if q := ctx.URI().QueryString(); len(q) > 0 {
+		uri += "?" + string(q)
+	}
+	req.SetRequestURI(uri)
+
+	h := &req.Header
+	for _, name := range hopHeaders {
+		h.Del(name)
+	}
+	if t.opts.PreserveHost {
+		h.SetHostBytes(ctx.Host())
+	} else {
+		h.SetHost(t.host)
+	}
+
+	ip := ctx.RemoteIP().String()
+	if prior := h.Peek(fasthttp.HeaderXForwardedFor); len(prior) > 0 {
+		ip = string(prior) + ", " + ip
+	}
+	h.Set(fasthttp.HeaderXForwardedFor, ip)
+	proto := "http"
+	if ctx.IsTLS() {
+		proto = "https"
+	}
+	h.Set("X-Forwarded-Proto", proto)
+	h.SetBytesV(fasthttp.HeaderXForwardedHost, ctx.Host())
+
+	for name, value := range t.opts.RequestHeaders {
+		if value == "" {
+			h.Del(name)
+		} else {
+			h.Set(name, value)
+		}
+	}
+}
+
+func (t *proxyTarget) copyResponse(ctx *fasthttp.RequestCtx, resp *fasthttp.Response) {
+	for _, name := range hopHeaders {
+		resp.Header.Del(name)
+	}
+
+	h := &ctx.Response.Header
+	seen := make(map[string]bool)
+	resp.Header.VisitAll(func(k, v []byte) {
+		name := string(k)
+		switch name {
+		case fasthttp.HeaderContentLength:
+			return
+		case fasthttp.HeaderSetCookie:
+			c := fasthttp.AcquireCookie()
+			if c.ParseBytes(v) == nil {
+				h.SetCookie(c)
+			}
+			fasthttp.ReleaseCookie(c)
+			return
+		}
+		if seen[name] {
+			h.AddBytesV(name, v)
+		} else {
+			seen[name] = true
+			h.SetBytesV(name, v)
+		}
+	})
+	for name, value := range t.opts.ResponseHeaders {
+		if value == "" {
+			h.Del(name)
+		} else {
+			h.Set(name, value)
+		}
+	}
+
+	ctx.SetStatusCode(resp.StatusCode())
+	ctx.SetBody(resp.Body())
+}
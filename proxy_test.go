@@ -0,0 +1,88 @@
+package fastalice
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+// startUpstream serves h on an in-memory listener
+// and returns the dialer reaching it.
+func startUpstream(t *testing.T, h fasthttp.RequestHandler) fasthttp.DialFunc {
+	ln := fasthttputil.NewInmemoryListener()
+	t.Cleanup(func() { ln.Close() })
+	go fasthttp.Serve(ln, h)
+	return func(addr string) (net.Conn, error) { return ln.Dial() }
+}
+
+func TestProxyTo(t *testing.T) {
+	var upstreamReq fasthttp.Request
+	dial := startUpstream(t, func(ctx *fasthttp.RequestCtx) {
+		ctx.Request.CopyTo(&upstreamReq)
+		ctx.Response.Header.Set("X-Upstream", "yes")
+		ctx.Response.Header.Set("X-Internal", "secret")
+		ctx.Response.Header.SetCookie(func() *fasthttp.Cookie {
+			c := &fasthttp.Cookie{}
+			c.SetKey("sid")
+			c.SetValue("1")
+			return c
+		}())
+		ctx.SetStatusCode(fasthttp.StatusCreated)
+		ctx.WriteString("upstream")
+	})
+
+	h := New(SecureHeaders(SecureConfig{FrameOptions: "DENY"})).Then(ProxyTo("http://backend:8080/v1", ProxyOptions{
+		StripPrefix:     "/api",
+		RequestHeaders:  map[string]string{"X-Gateway": "fastalice", "Cookie": ""},
+		ResponseHeaders: map[string]string{"X-Internal": ""},
+		Dial:            dial,
+	}))
+
+	ctx := newTestCtxFromIP("GET", "http://example.com/api/users?page=2", "10.0.0.9")
+	ctx.Request.Header.Set(fasthttp.HeaderXForwardedFor, "192.0.2.1")
+	ctx.Request.Header.Set(fasthttp.HeaderConnection, "keep-alive, X-Hop")
+	ctx.Request.Header.SetCookie("session", "abc")
+	h(ctx)
+
+	assert.Equal(t, "/v1/users?page=2", string(upstreamReq.RequestURI()), "The path should be rewritten")
+	assert.Equal(t, "backend:8080", string(upstreamReq.Host()), "The upstream host should be used")
+	assert.Equal(t, "192.0.2.1, 10.0.0.9", string(upstreamReq.Header.Peek(fasthttp.HeaderXForwardedFor)), "X-Forwarded-For should be appended to")
+	assert.Equal(t, "example.com", string(upstreamReq.Header.Peek(fasthttp.HeaderXForwardedHost)), "X-Forwarded-Host should be set")
+	assert.Equal(t, "http", string(upstreamReq.Header.Peek("X-Forwarded-Proto")), "X-Forwarded-Proto should be set")
+	assert.Equal(t, "fastalice", string(upstreamReq.Header.Peek("X-Gateway")), "Request headers should be set")
+	assert.Empty(t, upstreamReq.Header.Peek(fasthttp.HeaderCookie), "Request headers should be removed")
+
+	assert.Equal(t, fasthttp.StatusCreated, ctx.Response.StatusCode(), "The upstream status should be kept")
+	assert.Equal(t, "upstream", string(ctx.Response.Body()), "The upstream body should be kept")
+	assert.Equal(t, "yes", string(ctx.Response.Header.Peek("X-Upstream")), "Upstream headers should be copied")
+	assert.Empty(t, ctx.Response.Header.Peek("X-Internal"), "Response headers should be removed")
+	assert.Equal(t, "DENY", string(ctx.Response.Header.Peek(fasthttp.HeaderXFrameOptions)), "Headers of earlier middleware should be kept")
+	assert.Equal(t, "1", responseCookie(ctx, "sid"), "Upstream cookies should be copied")
+}
+
+func TestProxyToPreserveHost(t *testing.T) {
+	var host string
+	dial := startUpstream(t, func(ctx *fasthttp.RequestCtx) {
+		host = string(ctx.Host())
+	})
+
+	ctx := newTestCtx("GET", "http://example.com/")
+	ProxyTo("http://backend", ProxyOptions{PreserveHost: true, Dial: dial})(ctx)
+	assert.Equal(t, "example.com", host, "The request host should be preserved")
+}
+
+func TestProxyToUpstreamDown(t *testing.T) {
+	ctx := newTestCtx("GET", "http://example.com/")
+	ProxyTo("http://backend", ProxyOptions{
+		Dial: func(addr string) (net.Conn, error) { return nil, errors.New("refused") },
+	})(ctx)
+	assert.Equal(t, fasthttp.StatusBadGateway, ctx.Response.StatusCode(), "Unreachable upstreams should get a 502")
+}
+
+func TestProxyToInvalidUpstream(t *testing.T) {
+	assert.Panics(t, func() { ProxyTo("backend:8080", ProxyOptions{}) }, "Relative upstreams should be rejected")
+}
@@ -0,0 +1,193 @@
+package fastalice
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// QuotaMonthly is a period counting quotas by calendar month, in UTC,
+// for Quota; other periods are aligned on multiples of their duration,
+// so 24 * time.Hour counts by UTC day.
+const QuotaMonthly time.Duration = -1
+
+// QuotaPolicy describes the quotas enforced by Quota.
+type QuotaPolicy struct {
+	// Limit is the usage allowed per period.
+	Limit int64
+	// LimitFor, when set, returns the limit of a key instead of Limit,
+	// such as from the tier of its API key. A negative limit is unlimited.
+	LimitFor func(ctx *fasthttp.RequestCtx, key string) int64
+	// Key returns the key usage is counted under, such as KeyByAPIKey.
+	// It defaults to KeyByIP.
+	// Requests for which it returns an empty string are not counted.
+	Key func(ctx *fasthttp.RequestCtx) string
+	// Cost returns the usage of a request, 1 when nil.
+	Cost func(ctx *fasthttp.RequestCtx) int64
+	// OnOverage is called for requests over the limit,
+	// and reports whether they are served anyway as billed overage.
+	// Without it, they are rejected.
+	OnOverage func(ctx *fasthttp.RequestCtx, usage QuotaUsage) bool
+}
+
+// QuotaUsage is the usage of a key over the current period.
+type QuotaUsage struct {
+	Key   string
+	Used  int64
+	Limit int64
+	// Reset is the end of the period.
+	Reset time.Time
+}
+
+// Remaining returns the usage left in the period.
+func (u QuotaUsage) Remaining() int64 {
+	if u.Used >= u.Limit {
+		return 0
+	}
+	return u.Limit - u.Used
+}
+
+// QuotaStore counts usage over long periods.
+// Implementations backed by external stores, such as Redis or a database,
+// let several servers share quotas, and keep them across restarts.
+type QuotaStore interface {
+	// Add adds n, possibly negative, to the usage of key
+	// in the period ending at reset, and returns the new usage.
+	// Usage starts from zero in every period.
+	Add(key string, n int64, reset time.Time) (int64, error)
+}
+
+// quotaUsageKey holds the usage counted by Quota.
+var quotaUsageKey = NewKey[QuotaUsage]("quotaUsage")
+
+// Quota returns a constructor counting the usage of every key in store
+// over periods of the given length, such as 24 * time.Hour or QuotaMonthly,
+// unlike RateLimit which smooths traffic over short windows.
+// Requests over the quota of their key are answered
+// with 429 Too Many Requests and a Retry-After header until the period ends,
+// without calling the following handlers, unless policy.OnOverage serves them.
+// Rejected requests are not counted.
+//
+// Every counted response carries the Quota-Limit, Quota-Remaining
+// and Quota-Reset headers, and the following handlers read
+// the usage with GetQuotaUsage.
+// Requests are let through when store fails.
+//
+//	chain := fastalice.New(
+//		fastalice.APIKey(keys),
+//		fastalice.Quota(store, fastalice.QuotaMonthly, fastalice.QuotaPolicy{Limit: 100000, Key: fastalice.KeyByAPIKey}),
+//	)
+func Quota(store QuotaStore, period time.Duration, policy QuotaPolicy) Constructor {
+	key := policy.Key
+	if key == nil {
+		key = KeyByIP
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			k := key(ctx)
+			if k == "" {
+				next(ctx)
+				return
+			}
+			limit := policy.Limit
+			if policy.LimitFor != nil {
+				limit = policy.LimitFor(ctx, k)
+			}
+			if limit < 0 {
+				next(ctx)
+				return
+			}
+			cost := int64(1)
+			if policy.Cost != nil {
+				cost = policy.Cost(ctx)
+			}
+
+			t := now()
+			reset := quotaReset(t, period)
+			used, err := store.Add(k, cost, reset)
+			if err != nil {
+				next(ctx)
+				return
+			}
+			usage := QuotaUsage{Key: k, Used: used, Limit: limit, Reset: reset}
+
+			allowed := used <= limit || policy.OnOverage != nil && policy.OnOverage(ctx, usage)
+			if !allowed {
+				store.Add(k, -cost, reset)
+				usage.Used -= cost
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusTooManyRequests), fasthttp.StatusTooManyRequests)
+				ctx.Response.Header.Set(fasthttp.HeaderRetryAfter, seconds(reset.Sub(t)))
+			}
+			h := &ctx.Response.Header
+			h.Set("Quota-Limit", strconv.FormatInt(limit, 10))
+			h.Set("Quota-Remaining", strconv.FormatInt(usage.Remaining(), 10))
+			h.Set("Quota-Reset", seconds(reset.Sub(t)))
+			if allowed {
+				Set(ctx, quotaUsageKey, usage)
+				next(ctx)
+			}
+		}
+	}
+}
+
+// GetQuotaUsage returns the usage counted by Quota for the request,
+// and whether there is one.
+func GetQuotaUsage(ctx *fasthttp.RequestCtx) (QuotaUsage, bool) {
+	return Get(ctx, quotaUsageKey)
+}
+
+// quotaReset returns the end of the period containing t.
+func quotaReset(t time.Time, period time.Duration) time.Time {
+	if period == QuotaMonthly {
+		t = t.UTC()
+		return time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return t.Truncate(period).Add(period)
+}
+
+// quotaCount is the usage of a key in memoryQuotaStore.
+type quotaCount struct {
+	used  int64
+	reset time.Time
+}
+
+// memoryQuotaStore is an in-memory QuotaStore.
+type memoryQuotaStore struct {
+	mu        sync.Mutex
+	counts    map[string]*quotaCount
+	nextSweep time.Time
+}
+
+// NewMemoryQuotaStore returns an in-memory QuotaStore,
+// suitable for a single server whose quotas may be lost on restart.
+// Keys whose period is over are forgotten.
+func NewMemoryQuotaStore() QuotaStore {
+	return &memoryQuotaStore{counts: make(map[string]*quotaCount)}
+}
+
+func (s *memoryQuotaStore) Add(key string, n int64, reset time.Time) (int64, error) {
+	t := now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !t.Before(s.nextSweep) {
+		for k, c := range s.counts {
+			if !t.Before(c.reset) {
+				delete(s.counts, k)
+			}
+		}
+		s.nextSweep = reset
+	}
+
+	c, ok := s.counts[key]
+	if !ok || !c.reset.Equal(reset) {
+		c = &quotaCount{reset: reset}
+		s.counts[key] = c
+	}
+	c.used += n
+	return c.used, nil
+}
@@ -0,0 +1,100 @@
+package fastalice
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func quotaRequest(h fasthttp.RequestHandler, key string) *fasthttp.RequestCtx {
+	ctx := newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set("X-Customer", key)
+	h(ctx)
+	return ctx
+}
+
+func TestQuotaDaily(t *testing.T) {
+	clock := time.Date(2020, 1, 1, 22, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	h := New(Quota(NewMemoryQuotaStore(), 24*time.Hour, QuotaPolicy{Limit: 2, Key: KeyByHeader("X-Customer")})).Then(testApp)
+
+	ctx := quotaRequest(h, "acme")
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Requests within quota should be served")
+	assert.Equal(t, "2", string(ctx.Response.Header.Peek("Quota-Limit")), "The limit should be reported")
+	assert.Equal(t, "1", string(ctx.Response.Header.Peek("Quota-Remaining")), "The remaining usage should be reported")
+	assert.Equal(t, "7200", string(ctx.Response.Header.Peek("Quota-Reset")), "The quota should reset at midnight")
+
+	quotaRequest(h, "acme")
+	ctx = quotaRequest(h, "acme")
+	assert.Equal(t, fasthttp.StatusTooManyRequests, ctx.Response.StatusCode(), "Requests over quota should be rejected")
+	assert.Equal(t, "7200", string(ctx.Response.Header.Peek(fasthttp.HeaderRetryAfter)), "Rejected requests should retry once the period ends")
+	assert.Equal(t, "0", string(ctx.Response.Header.Peek("Quota-Remaining")), "No usage should remain")
+
+	ctx = quotaRequest(h, "globex")
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Keys should have their own quotas")
+
+	clock = clock.Add(2 * time.Hour)
+	ctx = quotaRequest(h, "acme")
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Quotas should reset with the period")
+}
+
+func TestQuotaOverageAndLimits(t *testing.T) {
+	var billed []int64
+	h := New(Quota(NewMemoryQuotaStore(), QuotaMonthly, QuotaPolicy{
+		Key: KeyByHeader("X-Customer"),
+		LimitFor: func(ctx *fasthttp.RequestCtx, key string) int64 {
+			if key == "internal" {
+				return -1
+			}
+			return 10
+		},
+		Cost: func(ctx *fasthttp.RequestCtx) int64 { return 4 },
+		OnOverage: func(ctx *fasthttp.RequestCtx, usage QuotaUsage) bool {
+			billed = append(billed, usage.Used-usage.Limit)
+			return usage.Key == "gold"
+		},
+	})).Then(func(ctx *fasthttp.RequestCtx) {
+		usage, _ := GetQuotaUsage(ctx)
+		ctx.SetBodyString(usage.Key)
+	})
+
+	for i := 0; i < 3; i++ {
+		quotaRequest(h, "gold")
+	}
+	assert.Equal(t, []int64{2}, billed, "Overage should be reported")
+	quotaRequest(h, "silver")
+	quotaRequest(h, "silver")
+	ctx := quotaRequest(h, "silver")
+	assert.Equal(t, fasthttp.StatusTooManyRequests, ctx.Response.StatusCode(), "Refused overage should be rejected")
+	assert.Equal(t, "2", string(ctx.Response.Header.Peek("Quota-Remaining")), "Rejected requests should not be counted")
+
+	for i := 0; i < 5; i++ {
+		ctx = quotaRequest(h, "internal")
+	}
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Negative limits should be unlimited")
+	assert.Empty(t, ctx.Response.Header.Peek("Quota-Limit"), "Unlimited keys should not be counted")
+}
+
+func TestQuotaReset(t *testing.T) {
+	t0 := time.Date(2021, 12, 15, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), quotaReset(t0, QuotaMonthly), "Monthly quotas should reset on the first")
+	assert.Equal(t, time.Date(2021, 12, 16, 0, 0, 0, 0, time.UTC), quotaReset(t0, 24*time.Hour), "Daily quotas should reset at midnight")
+}
+
+type failingQuotaStore struct{}
+
+func (failingQuotaStore) Add(key string, n int64, reset time.Time) (int64, error) {
+	return 0, errors.New("store down")
+}
+
+func TestQuotaStoreFailure(t *testing.T) {
+	h := New(Quota(failingQuotaStore{}, time.Hour, QuotaPolicy{Limit: 1})).Then(testApp)
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Requests should be let through when the store fails")
+}
@@ -0,0 +1,178 @@
+package fastalice
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// seekableBodyKey holds the body set by SetSeekableBody.
+var seekableBodyKey = NewKey[*seekableBody]("seekableBody")
+
+// seekableBody is a response body stream Ranges can seek into.
+// It closes the underlying body unless Ranges replaced it
+// with a stream of the requested range.
+type seekableBody struct {
+	io.ReadSeeker
+	size     int
+	detached bool
+}
+
+func (b *seekableBody) Close() error {
+	if c, ok := b.ReadSeeker.(io.Closer); ok && !b.detached {
+		return c.Close()
+	}
+	return nil
+}
+
+// SetSeekableBody streams body, of size bytes, as the response body,
+// letting Ranges serve byte ranges of it by seeking instead of reading it all.
+// body is closed once sent when it implements io.Closer, as an *os.File does.
+func SetSeekableBody(ctx *fasthttp.RequestCtx, body io.ReadSeeker, size int) {
+	b := &seekableBody{ReadSeeker: body, size: size}
+	ctx.Response.SetBodyStream(b, size)
+	Set(ctx, seekableBodyKey, b)
+}
+
+// Ranges returns a constructor answering GET requests with a Range header
+// with the requested byte range of the 200 OK responses of the following handlers,
+// as a 206 Partial Content response, so that downloads can be resumed.
+// It works on buffered bodies and on those set with SetSeekableBody;
+// other streamed bodies are left untouched.
+//
+// An If-Range header not matching the ETag or Last-Modified
+// of the response, and requests for several ranges,
+// are answered with the whole response;
+// ranges past the end are answered with 416 Range Not Satisfiable.
+//
+// Ranges should be placed before Compress in the chain,
+// so that ranges apply to the compressed body.
+func Ranges() Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			next(ctx)
+
+			if !(ctx.IsGet() || ctx.IsHead()) || ctx.Response.StatusCode() != fasthttp.StatusOK || ctx.Hijacked() {
+				return
+			}
+			seekable, _ := Get(ctx, seekableBodyKey)
+			if seekable == nil && ctx.Response.IsBodyStream() {
+				return
+			}
+			ctx.Response.Header.Set(fasthttp.HeaderAcceptRanges, "bytes")
+
+			header := string(ctx.Request.Header.Peek(fasthttp.HeaderRange))
+			if header == "" || !ctx.IsGet() || !ifRangeMatches(ctx) {
+				return
+			}
+			size := len(ctx.Response.Body())
+			if seekable != nil {
+				size = seekable.size
+			}
+			start, end, ok := parseRange(header, size)
+			if !ok {
+				return
+			}
+			if start < 0 {
+				ctx.Response.ResetBody()
+				ctx.Response.SetStatusCode(fasthttp.StatusRequestedRangeNotSatisfiable)
+				ctx.Response.Header.Set(fasthttp.HeaderContentRange, fmt.Sprintf("bytes */%d", size))
+				return
+			}
+
+			if seekable != nil {
+				if _, err := seekable.Seek(int64(start), io.SeekStart); err != nil {
+					return
+				}
+				seekable.detached = true
+				ctx.Response.SetBodyStream(&rangeBody{
+					Reader: io.LimitReader(seekable.ReadSeeker, int64(end-start+1)),
+					body:   seekable.ReadSeeker,
+				}, end-start+1)
+			} else {
+				ctx.Response.SetBody(ctx.Response.Body()[start : end+1])
+			}
+			ctx.Response.SetStatusCode(fasthttp.StatusPartialContent)
+			ctx.Response.Header.Set(fasthttp.HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+		}
+	}
+}
+
+// rangeBody streams a range of a seekable body,
+// closing the whole body once sent.
+type rangeBody struct {
+	io.Reader
+	body io.ReadSeeker
+}
+
+func (b *rangeBody) Close() error {
+	if c, ok := b.body.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// ifRangeMatches reports whether the If-Range header of the request,
+// if any, matches the ETag or Last-Modified of the response.
+// Weak tags never match, as ranges need byte-identical responses.
+func ifRangeMatches(ctx *fasthttp.RequestCtx) bool {
+	ifRange := string(ctx.Request.Header.Peek(fasthttp.HeaderIfRange))
+	switch {
+	case ifRange == "":
+		return true
+	case strings.HasPrefix(ifRange, `"`):
+		return ifRange == string(ctx.Response.Header.Peek(fasthttp.HeaderETag))
+	default:
+		return ifRange == string(ctx.Response.Header.Peek(fasthttp.HeaderLastModified))
+	}
+}
+
+// parseRange parses a Range header asking for a single byte range
+// of a body of size bytes, returning its first and last offsets.
+// ok is false when the header is malformed or asks for several ranges,
+// start is negative when the range cannot be satisfied.
+func parseRange(header string, size int) (start, end int, ok bool) {
+	spec := strings.TrimPrefix(header, "bytes=")
+	if spec == header || strings.Contains(spec, ",") {
+		return 0, 0, false
+	}
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, 0, false
+	}
+
+	if first == "" {
+		n, err := strconv.Atoi(last)
+		if err != nil || n < 0 {
+			return 0, 0, false
+		}
+		if n == 0 || size == 0 {
+			return -1, 0, true
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, true
+	}
+
+	start, err := strconv.Atoi(first)
+	if err != nil || start < 0 {
+		return 0, 0, false
+	}
+	end = size - 1
+	if last != "" {
+		if end, err = strconv.Atoi(last); err != nil || end < start {
+			return 0, 0, false
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	if start >= size {
+		return -1, 0, true
+	}
+	return start, end, true
+}
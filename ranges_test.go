@@ -0,0 +1,80 @@
+package fastalice
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func serveRange(h fasthttp.RequestHandler, ranges, ifRange string) *fasthttp.RequestCtx {
+	ctx := newTestCtx("GET", "http://localhost/file")
+	ctx.Request.Header.Set(fasthttp.HeaderRange, ranges)
+	if ifRange != "" {
+		ctx.Request.Header.Set(fasthttp.HeaderIfRange, ifRange)
+	}
+	h(ctx)
+	return ctx
+}
+
+func TestRangesBuffered(t *testing.T) {
+	h := New(Ranges()).Then(func(ctx *fasthttp.RequestCtx) {
+		ctx.Response.Header.Set(fasthttp.HeaderETag, `"v1"`)
+		ctx.WriteString("0123456789")
+	})
+
+	for _, tc := range []struct {
+		ranges, ifRange string
+		status          int
+		body, content   string
+	}{
+		{"bytes=2-4", "", fasthttp.StatusPartialContent, "234", "bytes 2-4/10"},
+		{"bytes=7-", "", fasthttp.StatusPartialContent, "789", "bytes 7-9/10"},
+		{"bytes=-3", "", fasthttp.StatusPartialContent, "789", "bytes 7-9/10"},
+		{"bytes=5-100", `"v1"`, fasthttp.StatusPartialContent, "56789", "bytes 5-9/10"},
+		{"bytes=2-4", `"v0"`, fasthttp.StatusOK, "0123456789", ""},
+		{"bytes=0-1,4-5", "", fasthttp.StatusOK, "0123456789", ""},
+		{"items=0-1", "", fasthttp.StatusOK, "0123456789", ""},
+		{"bytes=10-", "", fasthttp.StatusRequestedRangeNotSatisfiable, "", "bytes */10"},
+	} {
+		ctx := serveRange(h, tc.ranges, tc.ifRange)
+		assert.Equal(t, tc.status, ctx.Response.StatusCode(), "The status should match for %s", tc.ranges)
+		assert.Equal(t, tc.body, string(ctx.Response.Body()), "The body should match for %s", tc.ranges)
+		assert.Equal(t, tc.content, string(ctx.Response.Header.Peek(fasthttp.HeaderContentRange)), "The content range should match for %s", tc.ranges)
+		assert.Equal(t, "bytes", string(ctx.Response.Header.Peek(fasthttp.HeaderAcceptRanges)), "Ranges should be advertised for %s", tc.ranges)
+	}
+}
+
+type closingReader struct {
+	*strings.Reader
+	closed bool
+}
+
+func (r *closingReader) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestRangesSeekable(t *testing.T) {
+	var body *closingReader
+	h := New(Ranges()).Then(func(ctx *fasthttp.RequestCtx) {
+		body = &closingReader{Reader: strings.NewReader("abcdefghij")}
+		SetSeekableBody(ctx, body, 10)
+	})
+
+	ctx := serveRange(h, "bytes=3-5", "")
+	assert.Equal(t, fasthttp.StatusPartialContent, ctx.Response.StatusCode(), "Seekable bodies should be ranged")
+	assert.Equal(t, "def", string(ctx.Response.Body()), "The range should be read from the seeked body")
+	assert.True(t, body.closed, "The body should be closed once sent")
+}
+
+func TestRangesSkipsOtherStreams(t *testing.T) {
+	h := New(Ranges()).Then(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetBodyStream(strings.NewReader("abcdefghij"), -1)
+	})
+
+	ctx := serveRange(h, "bytes=3-5", "")
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Other streams should be left untouched")
+	assert.Empty(t, ctx.Response.Header.Peek(fasthttp.HeaderAcceptRanges), "Ranges should not be advertised for other streams")
+}
@@ -0,0 +1,278 @@
+package fastalice
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// RateLimitAlgorithm selects how a Policy counts requests.
+type RateLimitAlgorithm int
+
+const (
+	// TokenBucket allows bursts of up to Limit requests,
+	// refilling Limit tokens evenly over each Window.
+	TokenBucket RateLimitAlgorithm = iota
+	// SlidingWindow allows Limit requests in any Window,
+	// estimated from the counts of the current and previous windows.
+	SlidingWindow
+)
+
+// Policy describes how requests are rate limited.
+type Policy struct {
+	Algorithm RateLimitAlgorithm
+	// Limit is the number of requests allowed per Window.
+	Limit  int
+	Window time.Duration
+	// Key returns the key requests are counted under.
+	// It defaults to KeyByIP.
+	// Requests for which it returns an empty string are not limited.
+	Key func(ctx *fasthttp.RequestCtx) string
+}
+
+// RateLimitResult is the outcome of counting a request.
+type RateLimitResult struct {
+	Allowed bool
+	// Limit is the number of requests allowed per window.
+	Limit int
+	// Remaining is the number of requests left.
+	Remaining int
+	// Reset is the time until the quota is fully available again.
+	Reset time.Duration
+	// RetryAfter is the time until a denied request may be retried.
+	RetryAfter time.Duration
+}
+
+// RateLimitStore counts requests against a policy.
+// Implementations backed by external stores, such as Redis,
+// let several servers share the same limits.
+type RateLimitStore interface {
+	// Take counts a request under key and reports
+	// whether policy allows it.
+	Take(key string, policy Policy) (RateLimitResult, error)
+}
+
+// KeyByIP counts requests by client IP.
+func KeyByIP(ctx *fasthttp.RequestCtx) string {
+	return ClientIP(ctx).String()
+}
+
+// KeyByHeader returns a key function
+// counting requests by the value of the given request header.
+func KeyByHeader(name string) func(ctx *fasthttp.RequestCtx) string {
+	return func(ctx *fasthttp.RequestCtx) string {
+		return string(ctx.Request.Header.Peek(name))
+	}
+}
+
+// KeyByUserValue returns a key function counting requests
+// by the string stored under key with Set,
+// such as the user set by an authentication middleware.
+func KeyByUserValue(key Key[string]) func(ctx *fasthttp.RequestCtx) string {
+	return func(ctx *fasthttp.RequestCtx) string {
+		v, _ := Get(ctx, key)
+		return v
+	}
+}
+
+// KeyByPrincipal counts requests by the principal
+// authenticated by BasicAuth or BearerAuth, see Principal,
+// formatted with fmt.Sprint. Anonymous requests are not counted.
+func KeyByPrincipal(ctx *fasthttp.RequestCtx) string {
+	if principal := Principal(ctx); principal != nil {
+		return fmt.Sprint(principal)
+	}
+	return ""
+}
+
+// errInvalidPolicy reports a Policy without a positive Limit and Window.
+var errInvalidPolicy = errors.New("fastalice: rate limit policy needs a positive Limit and Window")
+
+// NewRateLimit returns a constructor that counts requests in store
+// and answers those exceeding policy with 429 Too Many Requests
+// and a Retry-After header, without calling the following handlers.
+//
+// Every counted response carries the RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset headers.
+// Requests are let through when store fails,
+// so that an unavailable store does not take the service down.
+// The limit can be tuned at runtime
+// with the SettingLimit setting, see ConfigureWith;
+// non-positive settings are ignored.
+//
+// An error is returned if policy.Limit or policy.Window is not positive.
+func NewRateLimit(store RateLimitStore, policy Policy) (Constructor, error) {
+	if policy.Limit <= 0 || policy.Window <= 0 {
+		return nil, errInvalidPolicy
+	}
+	key := policy.Key
+	if key == nil {
+		key = KeyByIP
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			k := key(ctx)
+			if k == "" {
+				next(ctx)
+				return
+			}
+			p := policy
+			if limit := ConfigValue(ctx, SettingLimit, policy.Limit); limit > 0 {
+				p.Limit = limit
+			}
+			res, err := store.Take(k, p)
+			if err != nil {
+				next(ctx)
+				return
+			}
+
+			if !res.Allowed {
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusTooManyRequests), fasthttp.StatusTooManyRequests)
+				ctx.Response.Header.Set(fasthttp.HeaderRetryAfter, seconds(res.RetryAfter))
+			}
+			h := &ctx.Response.Header
+			h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", seconds(res.Reset))
+			if res.Allowed {
+				next(ctx)
+			}
+		}
+	}, nil
+}
+
+// RateLimit is like NewRateLimit but panics
+// if policy.Limit or policy.Window is not positive.
+func RateLimit(store RateLimitStore, policy Policy) Constructor {
+	c, err := NewRateLimit(store, policy)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// seconds formats d as a whole number of seconds, rounded up.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+// rateLimitState is the state of a key in memoryRateLimitStore.
+type rateLimitState struct {
+	// tokens is the number of tokens left for TokenBucket.
+	tokens float64
+	// windowStart, prev and curr are the start of the current window
+	// and the counts of the previous and current ones for SlidingWindow.
+	windowStart time.Time
+	prev, curr  int
+	// last is the time of the last request, and expires
+	// the time the state is back to its initial value.
+	last    time.Time
+	expires time.Time
+}
+
+// memoryRateLimitStore is an in-memory RateLimitStore.
+type memoryRateLimitStore struct {
+	mu        sync.Mutex
+	states    map[string]*rateLimitState
+	nextSweep time.Time
+}
+
+// NewMemoryRateLimitStore returns an in-memory RateLimitStore,
+// suitable for a single server.
+// Keys whose quota is fully available again are forgotten.
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{states: make(map[string]*rateLimitState)}
+}
+
+func (s *memoryRateLimitStore) Take(key string, policy Policy) (RateLimitResult, error) {
+	if policy.Limit <= 0 || policy.Window <= 0 {
+		return RateLimitResult{}, errInvalidPolicy
+	}
+	t := now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !t.Before(s.nextSweep) {
+		for k, st := range s.states {
+			if !t.Before(st.expires) {
+				delete(s.states, k)
+			}
+		}
+		s.nextSweep = t.Add(policy.Window)
+	}
+
+	st, ok := s.states[key]
+	if !ok {
+		st = &rateLimitState{tokens: float64(policy.Limit), last: t}
+		s.states[key] = st
+	}
+	if policy.Algorithm == SlidingWindow {
+		return slidingWindow(st, policy, t), nil
+	}
+	return tokenBucket(st, policy, t), nil
+}
+
+// tokenBucket counts a request at t in st with the TokenBucket algorithm.
+func tokenBucket(st *rateLimitState, policy Policy, t time.Time) RateLimitResult {
+	limit := float64(policy.Limit)
+	perToken := policy.Window / time.Duration(policy.Limit)
+
+	st.tokens = math.Min(limit, st.tokens+float64(t.Sub(st.last))/float64(perToken))
+	st.last = t
+
+	res := RateLimitResult{Limit: policy.Limit}
+	if st.tokens >= 1 {
+		st.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - st.tokens) * float64(perToken))
+	}
+	res.Remaining = int(st.tokens)
+	res.Reset = time.Duration((limit - st.tokens) * float64(perToken))
+	st.expires = t.Add(res.Reset)
+	return res
+}
+
+// slidingWindow counts a request at t in st with the SlidingWindow algorithm.
+func slidingWindow(st *rateLimitState, policy Policy, t time.Time) RateLimitResult {
+	start := t.Truncate(policy.Window)
+	switch {
+	case start.Equal(st.windowStart):
+	case start.Equal(st.windowStart.Add(policy.Window)):
+		st.prev, st.curr = st.curr, 0
+	default:
+		st.prev, st.curr = 0, 0
+	}
+	st.windowStart = start
+	st.last = t
+
+	elapsed := t.Sub(start)
+	weight := 1 - float64(elapsed)/float64(policy.Window)
+	count := int(math.Ceil(float64(st.prev)*weight)) + st.curr
+
+	res := RateLimitResult{Limit: policy.Limit}
+	if count < policy.Limit {
+		st.curr++
+		count++
+		res.Allowed = true
+	} else {
+		res.RetryAfter = policy.Window - elapsed
+	}
+	res.Remaining = policy.Limit - count
+	if res.Remaining < 0 {
+		res.Remaining = 0
+	}
+	res.Reset = policy.Window - elapsed
+	if st.curr > 0 {
+		res.Reset += policy.Window
+	}
+	st.expires = t.Add(res.Reset)
+	return res
+}
@@ -0,0 +1,134 @@
+package fastalice
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestRateLimitTokenBucket(t *testing.T) {
+	defer fakeClock(time.Second)()
+
+	h := New(RateLimit(NewMemoryRateLimitStore(), Policy{Limit: 2, Window: 10 * time.Second})).Then(testApp)
+
+	for i, remaining := range []string{"1", "0"} {
+		ctx := newTestCtxFromIP("GET", "http://localhost/", "10.0.0.1")
+		h(ctx)
+		assert.Equal(t, "app", string(ctx.Response.Body()), "Request %d should be allowed", i)
+		assert.Equal(t, "2", string(ctx.Response.Header.Peek("RateLimit-Limit")), "The limit should be set")
+		assert.Equal(t, remaining, string(ctx.Response.Header.Peek("RateLimit-Remaining")), "The remaining quota should be set")
+	}
+
+	ctx := newTestCtxFromIP("GET", "http://localhost/", "10.0.0.1")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusTooManyRequests, ctx.Response.StatusCode(), "Exceeding requests should be denied")
+	assert.Equal(t, "3", string(ctx.Response.Header.Peek(fasthttp.HeaderRetryAfter)), "Retry-After should be set")
+	assert.Equal(t, "8", string(ctx.Response.Header.Peek("RateLimit-Reset")), "The reset should be set")
+
+	ctx = newTestCtxFromIP("GET", "http://localhost/", "10.0.0.2")
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Other clients should have their own quota")
+}
+
+func TestRateLimitTokenBucketRefills(t *testing.T) {
+	defer fakeClock(5 * time.Second)()
+
+	store := NewMemoryRateLimitStore()
+	policy := Policy{Limit: 1, Window: 10 * time.Second}
+	res, _ := store.Take("k", policy)
+	assert.True(t, res.Allowed, "The first request should be allowed")
+	res, _ = store.Take("k", policy)
+	assert.False(t, res.Allowed, "The bucket should be empty")
+	res, _ = store.Take("k", policy)
+	assert.True(t, res.Allowed, "The bucket should refill over the window")
+}
+
+func TestRateLimitSlidingWindow(t *testing.T) {
+	defer fakeClock(4 * time.Second)()
+
+	store := NewMemoryRateLimitStore()
+	policy := Policy{Algorithm: SlidingWindow, Limit: 2, Window: 10 * time.Second}
+
+	// Readings happen at 4s, 8s, 12s, 16s, 20s and 24s.
+	var allowed []bool
+	for i := 0; i < 6; i++ {
+		res, err := store.Take("k", policy)
+		assert.NoError(t, err)
+		allowed = append(allowed, res.Allowed)
+	}
+	assert.Equal(t, []bool{true, true, false, true, true, false}, allowed, "Requests should be weighted across windows")
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set("X-Api-Key", "secret")
+	userKey := NewKey[string]("user")
+	Set(ctx, userKey, "bob")
+
+	assert.Equal(t, "secret", KeyByHeader("X-Api-Key")(ctx), "KeyByHeader should read the header")
+	assert.Equal(t, "bob", KeyByUserValue(userKey)(ctx), "KeyByUserValue should read the typed value")
+	assert.Equal(t, "", KeyByUserValue(NewKey[string]("missing"))(ctx), "KeyByUserValue should default to an empty key")
+	assert.Equal(t, "", KeyByPrincipal(ctx), "KeyByPrincipal should not count anonymous requests")
+
+	ctx.Request.Header.Set(fasthttp.HeaderAuthorization, basicAuthHeader("admin", "secret"))
+	testBasicAuth()(ctx)
+	assert.Equal(t, "admin", KeyByPrincipal(ctx), "KeyByPrincipal should read the BasicAuth user")
+}
+
+func TestRateLimitSkipsEmptyKeys(t *testing.T) {
+	h := New(RateLimit(NewMemoryRateLimitStore(), Policy{Limit: 1, Window: time.Minute, Key: KeyByHeader("X-Api-Key")})).Then(testApp)
+
+	for i := 0; i < 3; i++ {
+		ctx := newTestCtx("GET", "http://localhost/")
+		h(ctx)
+		assert.Equal(t, "app", string(ctx.Response.Body()), "Requests without a key should not be limited")
+	}
+}
+
+// failingStore is a RateLimitStore that always fails.
+type failingStore struct{}
+
+func (failingStore) Take(key string, policy Policy) (RateLimitResult, error) {
+	return RateLimitResult{}, errors.New("unavailable")
+}
+
+func TestRateLimitStoreFailure(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(RateLimit(failingStore{}, Policy{Limit: 1, Window: time.Minute})).Then(testApp)(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Requests should go on when the store fails")
+	assert.Empty(t, ctx.Response.Header.Peek("RateLimit-Limit"), "No quota should be reported when the store fails")
+}
+
+func TestRateLimitRejectsZeroPolicies(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	for name, policy := range map[string]Policy{
+		"limit":  {Limit: 0, Window: time.Second},
+		"window": {Limit: 1, Window: 0, Algorithm: SlidingWindow},
+	} {
+		_, err := NewRateLimit(store, policy)
+		assert.Error(t, err, "A zero %s should be rejected", name)
+		assert.Panics(t, func() { RateLimit(store, policy) }, "A zero %s should panic", name)
+		_, err = store.Take("k", policy)
+		assert.Error(t, err, "The store should refuse a zero %s", name)
+	}
+}
+
+func TestRateLimitIgnoresNonPositiveOverrides(t *testing.T) {
+	cfg := NewConfig(Setting(SettingLimit, 0))
+	h := New(ConfigureWith(RateLimit(NewMemoryRateLimitStore(), Policy{Limit: 1, Window: time.Hour}), cfg)).Then(testApp)
+
+	ctx := newTestCtxFromIP("GET", "http://localhost/", "10.0.0.1")
+	h(ctx)
+	assert.Equal(t, "1", string(ctx.Response.Header.Peek("RateLimit-Limit")), "A zero override should fall back to the policy")
+	ctx = newTestCtxFromIP("GET", "http://localhost/", "10.0.0.1")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusTooManyRequests, ctx.Response.StatusCode(), "The policy limit should still apply")
+
+	cfg.Apply(Setting(SettingLimit, -5))
+	ctx = newTestCtxFromIP("GET", "http://localhost/", "10.0.0.2")
+	h(ctx)
+	assert.Equal(t, "1", string(ctx.Response.Header.Peek("RateLimit-Limit")), "A negative override should fall back to the policy")
+}
@@ -0,0 +1,88 @@
+package fastalice
+
+import (
+	"net"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// clientIPKey holds the client IP resolved by RealIP.
+var clientIPKey = NewKey[net.IP]("clientIP")
+
+// NewRealIP returns a constructor that resolves
+// the true client IP of requests coming through
+// the trusted reverse proxies, given as IPs and CIDR ranges,
+// and stores it for ClientIP.
+//
+// When the remote address is a trusted proxy,
+// the X-Forwarded-For header is read from its end,
+// skipping trusted addresses: the first untrusted one
+// is the client. Without X-Forwarded-For, X-Real-IP is used.
+// Requests from untrusted addresses keep their remote address,
+// as their headers may be forged.
+//
+// Shipped middleware reading the client IP,
+// such as IPFilter, Logger and KeyByIP, use ClientIP,
+// so placing RealIP first in the chain makes them all agree.
+// An error is returned if any of the proxies is malformed.
+func NewRealIP(trustedProxies []string) (Constructor, error) {
+	trusted, err := parseIPList(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if ip := realIP(ctx, trusted); ip != nil {
+				Set(ctx, clientIPKey, ip)
+			}
+			next(ctx)
+		}
+	}, nil
+}
+
+// RealIP is like NewRealIP but panics
+// if any of the proxies is malformed.
+func RealIP(trustedProxies []string) Constructor {
+	c, err := NewRealIP(trustedProxies)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// ClientIP returns the client IP resolved by RealIP,
+// or the remote address of the request without it.
+func ClientIP(ctx *fasthttp.RequestCtx) net.IP {
+	if ip, ok := Get(ctx, clientIPKey); ok {
+		return ip
+	}
+	return ctx.RemoteIP()
+}
+
+// realIP returns the client IP of a request,
+// or nil when the forwarded address is malformed.
+func realIP(ctx *fasthttp.RequestCtx, trusted ipList) net.IP {
+	remote := ctx.RemoteIP()
+	if !trusted.contains(remote) {
+		return remote
+	}
+
+	xff := ctx.Request.Header.Peek(fasthttp.HeaderXForwardedFor)
+	if len(xff) == 0 {
+		if real := ctx.Request.Header.Peek("X-Real-IP"); len(real) > 0 {
+			return net.ParseIP(strings.TrimSpace(string(real)))
+		}
+		return remote
+	}
+
+	chain := strings.Split(string(xff), ",")
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(chain[i]))
+		if ip == nil || !trusted.contains(ip) || i == 0 {
+			return ip
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestRealIPFromTrustedProxy(t *testing.T) {
+	var got string
+	app := func(ctx *fasthttp.RequestCtx) { got = ClientIP(ctx).String() }
+	realIP := RealIP([]string{"10.0.0.0/8"})
+
+	ctx := newTestCtxFromIP("GET", "http://localhost/", "10.0.0.1")
+	ctx.Request.Header.Set(fasthttp.HeaderXForwardedFor, "1.2.3.4, 203.0.113.7, 10.0.0.2")
+	New(realIP).Then(app)(ctx)
+	assert.Equal(t, "203.0.113.7", got, "The rightmost untrusted address should be the client")
+
+	ctx = newTestCtxFromIP("GET", "http://localhost/", "10.0.0.1")
+	ctx.Request.Header.Set(fasthttp.HeaderXForwardedFor, "10.0.0.3, 10.0.0.2")
+	New(realIP).Then(app)(ctx)
+	assert.Equal(t, "10.0.0.3", got, "The leftmost address should be the client when all are trusted")
+
+	ctx = newTestCtxFromIP("GET", "http://localhost/", "10.0.0.1")
+	ctx.Request.Header.Set("X-Real-IP", "203.0.113.8")
+	New(realIP).Then(app)(ctx)
+	assert.Equal(t, "203.0.113.8", got, "X-Real-IP should be used without X-Forwarded-For")
+}
+
+func TestRealIPFromUntrustedPeer(t *testing.T) {
+	var got string
+	app := func(ctx *fasthttp.RequestCtx) { got = ClientIP(ctx).String() }
+
+	ctx := newTestCtxFromIP("GET", "http://localhost/", "198.51.100.1")
+	ctx.Request.Header.Set(fasthttp.HeaderXForwardedFor, "1.2.3.4")
+	New(RealIP([]string{"10.0.0.0/8"})).Then(app)(ctx)
+	assert.Equal(t, "198.51.100.1", got, "Headers from untrusted peers should be ignored")
+}
+
+func TestRealIPSharedWithMiddleware(t *testing.T) {
+	ctx := newTestCtxFromIP("GET", "http://localhost/", "10.0.0.1")
+	ctx.Request.Header.Set(fasthttp.HeaderXForwardedFor, "203.0.113.7")
+
+	var key string
+	app := func(ctx *fasthttp.RequestCtx) { key = KeyByIP(ctx) }
+	New(RealIP([]string{"10.0.0.1"}), IPFilter([]string{"203.0.113.0/24"}, nil)).Then(app)(ctx)
+
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "IPFilter should see the real client IP")
+	assert.Equal(t, "203.0.113.7", key, "KeyByIP should see the real client IP")
+}
+
+func TestRealIPInvalidProxy(t *testing.T) {
+	_, err := NewRealIP([]string{"not-an-ip"})
+	assert.Error(t, err, "Malformed proxies should be rejected")
+	assert.Panics(t, func() { RealIP([]string{"not-an-ip"}) }, "RealIP should panic on malformed proxies")
+}
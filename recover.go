@@ -0,0 +1,38 @@
+package fastalice
+
+import (
+	"runtime"
+
+	"github.com/valyala/fasthttp"
+)
+
+// maxStackSize bounds the stack trace captured by RecoverWithStack;
+// longer traces are truncated.
+const maxStackSize = 64 << 10
+
+// RecoverWithStack returns a constructor that recovers from panics
+// in the following handlers and answers with 500 Internal Server Error.
+// onPanic, when not nil, is called with the recovered value
+// and the stack trace of the panicking goroutine,
+// truncated to 64KB.
+func RecoverWithStack(onPanic func(ctx *fasthttp.RequestCtx, recovered interface{}, stack []byte)) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+
+				stack := make([]byte, maxStackSize)
+				stack = stack[:runtime.Stack(stack, false)]
+
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusInternalServerError), fasthttp.StatusInternalServerError)
+				if onPanic != nil {
+					onPanic(ctx, r, stack)
+				}
+			}()
+			next(ctx)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func panickingApp(ctx *fasthttp.RequestCtx) {
+	panic("boom")
+}
+
+func TestRecoverWithStackCapturesStack(t *testing.T) {
+	var (
+		recovered interface{}
+		stack     []byte
+	)
+	rec := RecoverWithStack(func(ctx *fasthttp.RequestCtx, r interface{}, s []byte) {
+		recovered, stack = r, s
+	})
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(rec).Then(panickingApp)(ctx)
+
+	assert.Equal(t, fasthttp.StatusInternalServerError, ctx.Response.StatusCode(), "Panics should return Internal Server Error")
+	assert.Equal(t, "boom", recovered, "The recovered value should be reported")
+	assert.Contains(t, string(stack), "panickingApp", "The stack should contain the panicking function")
+}
+
+func TestRecoverWithStackWithoutPanic(t *testing.T) {
+	called := false
+	rec := RecoverWithStack(func(ctx *fasthttp.RequestCtx, r interface{}, s []byte) {
+		called = true
+	})
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(rec).Then(testApp)(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Requests without panics should pass")
+	assert.False(t, called, "The callback should not run without a panic")
+}
@@ -0,0 +1,115 @@
+package fastalice
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MiddlewareFactory builds a constructor from
+// the options given to a middleware in a configuration.
+// options is nil when none were given.
+type MiddlewareFactory func(options map[string]interface{}) (Constructor, error)
+
+// Registry holds named middleware factories,
+// from which chains can be assembled by configuration,
+// so middleware can be reordered or toggled without recompiling.
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[string]MiddlewareFactory
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{factories: make(map[string]MiddlewareFactory)}
+}
+
+// Register makes the middleware built by factory available
+// under name.
+// It panics if name is empty, factory is nil,
+// or name is already registered.
+func (r *Registry) Register(name string, factory MiddlewareFactory) {
+	if name == "" || factory == nil {
+		panic("fastalice: Register needs a name and a factory")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.factories[name]; ok {
+		panic(fmt.Sprintf("fastalice: middleware %q registered twice", name))
+	}
+	r.factories[name] = factory
+}
+
+// RegisterConstructor is like Register,
+// for middleware that take no options.
+func (r *Registry) RegisterConstructor(name string, c Constructor) {
+	r.Register(name, func(map[string]interface{}) (Constructor, error) {
+		return c, nil
+	})
+}
+
+// chainConfig is the configuration read by BuildFromConfig.
+type chainConfig struct {
+	Middleware []struct {
+		Name    string                 `json:"name" yaml:"name"`
+		Enabled *bool                  `json:"enabled" yaml:"enabled"`
+		Options map[string]interface{} `json:"options" yaml:"options"`
+	} `json:"middleware" yaml:"middleware"`
+}
+
+// BuildFromConfig assembles a chain from cfg,
+// in the "json" or "yaml" format,
+// listing registered middleware in request flow order:
+//
+//	middleware:
+//	  - name: requestid
+//	  - name: ratelimit
+//	    options:
+//	      limit: 100
+//	  - name: debug
+//	    enabled: false
+//
+// Middleware are enabled unless told otherwise,
+// and keep their name in the chain.
+// An error is returned if cfg does not parse,
+// names an unknown middleware,
+// or if a factory fails.
+func (r *Registry) BuildFromConfig(cfg []byte, format string) (Chain, error) {
+	var config chainConfig
+	var err error
+	switch strings.ToLower(format) {
+	case "json":
+		err = json.Unmarshal(cfg, &config)
+	case "yaml", "yml":
+		err = yaml.Unmarshal(cfg, &config)
+	default:
+		return Chain{}, fmt.Errorf("fastalice: unknown config format %q", format)
+	}
+	if err != nil {
+		return Chain{}, fmt.Errorf("fastalice: invalid chain config: %v", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	named := make([]NamedConstructor, 0, len(config.Middleware))
+	for i, m := range config.Middleware {
+		if m.Enabled != nil && !*m.Enabled {
+			continue
+		}
+		factory, ok := r.factories[m.Name]
+		if !ok {
+			return Chain{}, fmt.Errorf("fastalice: unknown middleware %q at index %d", m.Name, i)
+		}
+		c, err := factory(m.Options)
+		if err != nil {
+			return Chain{}, fmt.Errorf("fastalice: middleware %q: %v", m.Name, err)
+		}
+		named = append(named, NamedConstructor{Name: m.Name, Constructor: c})
+	}
+	return NewNamed(named...), nil
+}
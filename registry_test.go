@@ -0,0 +1,71 @@
+package fastalice
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testRegistry() *Registry {
+	r := NewRegistry()
+	r.RegisterConstructor("t1", tagMiddleware("t1\n"))
+	r.RegisterConstructor("t2", tagMiddleware("t2\n"))
+	r.Register("tag", func(options map[string]interface{}) (Constructor, error) {
+		text, ok := options["text"].(string)
+		if !ok {
+			return nil, errors.New("text is required")
+		}
+		return tagMiddleware(text), nil
+	})
+	return r
+}
+
+func TestBuildFromConfigYAML(t *testing.T) {
+	cfg := `
+middleware:
+  - name: t2
+  - name: tag
+    options:
+      text: "custom\n"
+  - name: t1
+    enabled: false
+`
+	chain, err := testRegistry().BuildFromConfig([]byte(cfg), "yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"t2", "tag"}, chain.Names(), "Enabled middleware should be named in order")
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	chain.Then(testApp)(ctx)
+	assert.Equal(t, "t2\ncustom\napp", string(ctx.Response.Body()), "The configured chain should run in order")
+}
+
+func TestBuildFromConfigJSON(t *testing.T) {
+	cfg := `{"middleware": [{"name": "t1"}, {"name": "t2", "enabled": true}]}`
+	chain, err := testRegistry().BuildFromConfig([]byte(cfg), "JSON")
+	assert.NoError(t, err)
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	chain.Then(testApp)(ctx)
+	assert.Equal(t, "t1\nt2\napp", string(ctx.Response.Body()), "The configured chain should run in order")
+}
+
+func TestBuildFromConfigErrors(t *testing.T) {
+	r := testRegistry()
+	for cfg, format := range map[string]string{
+		`{"middleware": [{"name": "t1"}]}`:      "toml",
+		`{"middleware": `:                       "json",
+		`{"middleware": [{"name": "missing"}]}`: "json",
+		`{"middleware": [{"name": "tag"}]}`:     "json",
+	} {
+		_, err := r.BuildFromConfig([]byte(cfg), format)
+		assert.Error(t, err, fmt.Sprintf("%s config %s should be rejected", format, cfg))
+	}
+}
+
+func TestRegistryRegisterTwice(t *testing.T) {
+	r := testRegistry()
+	assert.Panics(t, func() { r.RegisterConstructor("t1", tagMiddleware("")) }, "Registering a name twice should panic")
+	assert.Panics(t, func() { r.Register("", nil) }, "Registering without a name should panic")
+}
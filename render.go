@@ -0,0 +1,180 @@
+package fastalice
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// JSON writes v encoded in JSON as the response body,
+// with the given status code.
+// It returns the encoding error, if any,
+// so error-aware handlers can return its result directly:
+//
+//	func show(ctx *fasthttp.RequestCtx) error {
+//		return fastalice.JSON(ctx, fasthttp.StatusOK, user)
+//	}
+func JSON(ctx *fasthttp.RequestCtx, code int, v interface{}) error {
+	return write(ctx, code, JSONFormat, v)
+}
+
+// write encodes v in format f as the response body.
+func write(ctx *fasthttp.RequestCtx, code int, f Format, v interface{}) error {
+	body, err := f.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("fastalice: cannot encode %s response: %w", f.MediaType, err)
+	}
+	ctx.SetStatusCode(code)
+	ctx.SetContentType(f.MediaType)
+	ctx.SetBody(body)
+	return nil
+}
+
+// Negotiate returns the offered media type
+// best matching the Accept request header,
+// or an empty string when none is acceptable.
+// Quality values and wildcards are honored;
+// between equally acceptable offers, the first one wins.
+// Without an Accept header, the first offer is returned.
+func Negotiate(ctx *fasthttp.RequestCtx, offers ...string) string {
+	accept := ctx.Request.Header.Peek(fasthttp.HeaderAccept)
+	if len(accept) == 0 {
+		if len(offers) == 0 {
+			return ""
+		}
+		return offers[0]
+	}
+
+	ranges := parseAccept(string(accept))
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := acceptQuality(ranges, mediaType([]byte(offer))); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// acceptRange is a media range of an Accept header.
+type acceptRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		r := acceptRange{q: 1}
+		mt := strings.ToLower(strings.TrimSpace(params[0]))
+		r.typ, r.subtype = mt, "*"
+		if i := strings.IndexByte(mt, '/'); i >= 0 {
+			r.typ, r.subtype = mt[:i], mt[i+1:]
+		}
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				if q, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					r.q = q
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// acceptQuality returns the quality of mt
+// given by its most specific matching range.
+func acceptQuality(ranges []acceptRange, mt string) float64 {
+	typ, subtype := mt, ""
+	if i := strings.IndexByte(mt, '/'); i >= 0 {
+		typ, subtype = mt[:i], mt[i+1:]
+	}
+
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.typ == typ && r.subtype == subtype:
+			s = 2
+		case r.typ == typ && r.subtype == "*":
+			s = 1
+		case r.typ == "*" && r.subtype == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
+
+// rendered is a response value awaiting serialization by Renderer.
+type rendered struct {
+	code int
+	v    interface{}
+}
+
+// renderKey holds the value passed to Render.
+var renderKey = NewKey[rendered]("render")
+
+// Render records v as the response of the request,
+// to be serialized with the given status code by Renderer
+// in the format preferred by the client.
+func Render(ctx *fasthttp.RequestCtx, code int, v interface{}) {
+	Set(ctx, renderKey, rendered{code, v})
+}
+
+// Renderer returns error-aware middleware that serializes
+// the value passed to Render by the following handlers,
+// in the format negotiated from the Accept request header.
+// The formats default to JSONFormat and XMLFormat;
+// formats without Marshal are ignored.
+//
+// Requests accepting none of them
+// are answered with 406 Not Acceptable.
+// Errors returned by the following handlers
+// and encoding errors are passed up the chain.
+//
+//	chained := fastalice.New(m1).AppendErr(fastalice.Renderer()).ThenErr(func(ctx *fasthttp.RequestCtx) error {
+//		fastalice.Render(ctx, fasthttp.StatusOK, user)
+//		return nil
+//	}, nil)
+func Renderer(formats ...Format) ErrorConstructor {
+	if len(formats) == 0 {
+		formats = []Format{JSONFormat, XMLFormat}
+	}
+	var offers []string
+	byType := make(map[string]Format, len(formats))
+	for _, f := range formats {
+		if f.Marshal != nil {
+			offers = append(offers, f.MediaType)
+			byType[f.MediaType] = f
+		}
+	}
+
+	return func(next Handler) Handler {
+		return func(ctx *fasthttp.RequestCtx) error {
+			if err := next(ctx); err != nil {
+				return err
+			}
+			r, ok := Get(ctx, renderKey)
+			if !ok {
+				return nil
+			}
+			Delete(ctx, renderKey)
+
+			mt := Negotiate(ctx, offers...)
+			if mt == "" {
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusNotAcceptable), fasthttp.StatusNotAcceptable)
+			} else if err := write(ctx, r.code, byType[mt], r.v); err != nil {
+				return err
+			}
+			ctx.Response.Header.Add(fasthttp.HeaderVary, fasthttp.HeaderAccept)
+			return nil
+		}
+	}
+}
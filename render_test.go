@@ -0,0 +1,81 @@
+package fastalice
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+type renderTarget struct {
+	Name string `json:"name" xml:"name"`
+}
+
+func TestJSON(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	err := JSON(ctx, fasthttp.StatusCreated, renderTarget{"alice"})
+	assert.NoError(t, err)
+	assert.Equal(t, fasthttp.StatusCreated, ctx.Response.StatusCode(), "The status code should be set")
+	assert.Equal(t, "application/json", string(ctx.Response.Header.ContentType()), "The content type should be set")
+	assert.Equal(t, `{"name":"alice"}`, string(ctx.Response.Body()), "The value should be encoded")
+
+	assert.Error(t, JSON(ctx, fasthttp.StatusOK, make(chan int)), "Encoding errors should be returned")
+}
+
+func TestNegotiate(t *testing.T) {
+	cases := []struct {
+		accept string
+		offers []string
+		want   string
+	}{
+		{"", []string{"application/json", "application/xml"}, "application/json"},
+		{"application/xml", []string{"application/json", "application/xml"}, "application/xml"},
+		{"application/json;q=0.5, application/xml", []string{"application/json", "application/xml"}, "application/xml"},
+		{"text/*, application/json;q=0.1", []string{"application/json", "text/html"}, "text/html"},
+		{"*/*;q=0.2, application/json;q=0", []string{"application/json", "application/xml"}, "application/xml"},
+		{"text/html", []string{"application/json"}, ""},
+	}
+
+	for _, c := range cases {
+		ctx := newTestCtx("GET", "http://localhost/")
+		if c.accept != "" {
+			ctx.Request.Header.Set(fasthttp.HeaderAccept, c.accept)
+		}
+		assert.Equal(t, c.want, Negotiate(ctx, c.offers...), "Accept %q should select %q", c.accept, c.want)
+	}
+}
+
+func renderRequest(accept string, h Handler) *fasthttp.RequestCtx {
+	ctx := newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set(fasthttp.HeaderAccept, accept)
+	New().AppendErr(Renderer()).ThenErr(h, nil)(ctx)
+	return ctx
+}
+
+func TestRenderer(t *testing.T) {
+	show := func(ctx *fasthttp.RequestCtx) error {
+		Render(ctx, fasthttp.StatusOK, renderTarget{"alice"})
+		return nil
+	}
+
+	ctx := renderRequest("application/xml", show)
+	assert.Equal(t, "<renderTarget><name>alice</name></renderTarget>", string(ctx.Response.Body()), "XML should be rendered when preferred")
+	assert.Equal(t, "application/xml", string(ctx.Response.Header.ContentType()), "The negotiated content type should be set")
+	assert.Equal(t, "Accept", string(ctx.Response.Header.Peek(fasthttp.HeaderVary)), "Responses should vary by Accept")
+
+	ctx = renderRequest("*/*", show)
+	assert.Equal(t, `{"name":"alice"}`, string(ctx.Response.Body()), "JSON should be rendered by default")
+
+	ctx = renderRequest("text/html", show)
+	assert.Equal(t, fasthttp.StatusNotAcceptable, ctx.Response.StatusCode(), "Unacceptable requests should get 406")
+}
+
+func TestRendererPassesErrors(t *testing.T) {
+	ctx := renderRequest("application/json", func(ctx *fasthttp.RequestCtx) error {
+		Render(ctx, fasthttp.StatusOK, renderTarget{"alice"})
+		return errors.New("boom")
+	})
+	assert.Equal(t, fasthttp.StatusInternalServerError, ctx.Response.StatusCode(), "Handler errors should reach the error handler")
+	assert.NotContains(t, string(ctx.Response.Body()), "alice", "Values should not be rendered on error")
+}
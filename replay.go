@@ -0,0 +1,98 @@
+package fastalice
+
+import (
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// NonceStore remembers values, such as request signatures,
+// that must be accepted at most once.
+type NonceStore interface {
+	// Remember records nonce and reports whether it was unseen.
+	// It returns false when nonce was already recorded
+	// and has not expired yet.
+	Remember(nonce string) bool
+}
+
+// memoryNonceStore is an in-memory NonceStore
+// that forgets nonces once their window elapsed.
+type memoryNonceStore struct {
+	window time.Duration
+
+	mu        sync.Mutex
+	expires   map[string]time.Time
+	nextSweep time.Time
+}
+
+// NewMemoryNonceStore returns an in-memory NonceStore
+// remembering every nonce for window,
+// which should match the accepted timestamp skew
+// of the signatures it protects.
+func NewMemoryNonceStore(window time.Duration) NonceStore {
+	return &memoryNonceStore{
+		window:  window,
+		expires: make(map[string]time.Time),
+	}
+}
+
+func (s *memoryNonceStore) Remember(nonce string) bool {
+	t := now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !t.Before(s.nextSweep) {
+		for n, exp := range s.expires {
+			if !t.Before(exp) {
+				delete(s.expires, n)
+			}
+		}
+		s.nextSweep = t.Add(s.window)
+	}
+	if exp, ok := s.expires[nonce]; ok && t.Before(exp) {
+		return false
+	}
+	s.expires[nonce] = t.Add(s.window)
+	return true
+}
+
+// verifyConfig holds the settings of VerifyRequests and WebhookVerify.
+type verifyConfig struct {
+	nonces NonceStore
+}
+
+// VerifyOption configures VerifyRequests and WebhookVerify.
+type VerifyOption func(*verifyConfig)
+
+// RejectReplays records the signature of every verified request in store,
+// and answers requests replaying an already seen signature
+// with 409 Conflict, even though it is valid,
+// without calling the following handlers.
+//
+// The window of store should cover the age accepted for signatures:
+// SignatureMaxAge for VerifyRequests
+// and WebhookTolerance for WebhookVerify.
+func RejectReplays(store NonceStore) VerifyOption {
+	return func(c *verifyConfig) { c.nonces = store }
+}
+
+// newVerifyConfig applies opts.
+func newVerifyConfig(opts []VerifyOption) verifyConfig {
+	var cfg verifyConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// replayed reports whether the verified signature sig was already seen,
+// answering the request with 409 Conflict if so.
+func (c verifyConfig) replayed(ctx *fasthttp.RequestCtx, sig string) bool {
+	if c.nonces == nil || c.nonces.Remember(sig) {
+		return false
+	}
+	ctx.Error(fasthttp.StatusMessage(fasthttp.StatusConflict), fasthttp.StatusConflict)
+	return true
+}
@@ -0,0 +1,88 @@
+package fastalice
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestVerifyRequestsRejectsReplays(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	h := New(VerifyRequests(KeySet{"k1": key.Public()}, RejectReplays(NewMemoryNonceStore(SignatureMaxAge)))).Then(testApp)
+
+	ctx := signedCtx(t, "POST", "http://api.local/orders", `{"id":1}`, "k1", key)
+	replay := &fasthttp.Request{}
+	ctx.Request.CopyTo(replay)
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "The first signed request should pass")
+
+	ctx = &fasthttp.RequestCtx{}
+	ctx.Init(replay, nil, nil)
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusConflict, ctx.Response.StatusCode(), "A valid but replayed signature should be rejected")
+
+	ctx = signedCtx(t, "POST", "http://api.local/orders", `{"id":2}`, "k1", key)
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "A new signature should pass")
+}
+
+func TestVerifyRequestsRecordsOnlyValidSignatures(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	_, other, _ := ed25519.GenerateKey(rand.Reader)
+	h := New(VerifyRequests(KeySet{"k1": key.Public()}, RejectReplays(NewMemoryNonceStore(SignatureMaxAge)))).Then(testApp)
+
+	for i := 0; i < 2; i++ {
+		ctx := signedCtx(t, "GET", "http://api.local/orders", "", "k1", other)
+		h(ctx)
+		assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "Invalid signatures should be rejected, not recorded")
+	}
+}
+
+func TestWebhookVerifyRejectsReplays(t *testing.T) {
+	h := New(WebhookVerify([]byte("s3cret"), GitHubWebhook, RejectReplays(NewMemoryNonceStore(WebhookTolerance)))).Then(testApp)
+	body := `{"action":"opened"}`
+	headers := map[string]string{"X-Hub-Signature-256": "sha256=" + webhookMAC("s3cret", body)}
+
+	ctx := webhookCtx(body, headers)
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "The first delivery should pass")
+
+	ctx = webhookCtx(body, headers)
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusConflict, ctx.Response.StatusCode(), "A replayed delivery should be rejected")
+}
+
+func TestMemoryNonceStoreForgetsAfterWindow(t *testing.T) {
+	defer fakeClock(time.Minute)()
+
+	store := NewMemoryNonceStore(90 * time.Second)
+	assert.True(t, store.Remember("sig"), "An unseen nonce should be accepted")
+	assert.False(t, store.Remember("sig"), "A nonce within its window should be rejected")
+	assert.True(t, store.Remember("sig"), "A nonce past its window should be accepted again")
+}
+
+func TestMemoryNonceStoreSweep(t *testing.T) {
+	clock := time.Unix(0, 0)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+	store := NewMemoryNonceStore(time.Minute).(*memoryNonceStore)
+
+	store.Remember("a")
+	clock = clock.Add(30 * time.Second)
+	store.Remember("b")
+	clock = clock.Add(30 * time.Second)
+	store.Remember("c")
+	assert.Len(t, store.expires, 2, "Expired nonces should be swept once the next sweep is due")
+
+	clock = clock.Add(35 * time.Second)
+	store.Remember("d")
+	assert.Len(t, store.expires, 3, "Expired nonces should be swept at most once per window")
+
+	clock = clock.Add(25 * time.Second)
+	store.Remember("e")
+	assert.Len(t, store.expires, 2, "Expired nonces should be swept once the next sweep is due")
+}
@@ -0,0 +1,94 @@
+package fastalice
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultRequestIDHeader is the header used by RequestID
+// unless RequestIDHeader is given.
+const DefaultRequestIDHeader = "X-Request-ID"
+
+// requestIDKey is the key holding the request ID.
+var requestIDKey = NewKey[string]("requestID")
+
+// requestIDConfig holds the settings of RequestID.
+type requestIDConfig struct {
+	header   string
+	gen      func() string
+	validate func(id string) bool
+}
+
+// RequestIDOption configures RequestID.
+type RequestIDOption func(*requestIDConfig)
+
+// RequestIDHeader sets the header carrying the request ID,
+// which defaults to DefaultRequestIDHeader.
+func RequestIDHeader(name string) RequestIDOption {
+	return func(c *requestIDConfig) { c.header = name }
+}
+
+// RequestIDGenerator sets the function generating missing IDs,
+// which defaults to a random UUID generator.
+func RequestIDGenerator(gen func() string) RequestIDOption {
+	return func(c *requestIDConfig) { c.gen = gen }
+}
+
+// RequestIDValidator sets a function checking incoming IDs;
+// those it rejects are replaced by a generated one.
+// By default any non-empty incoming ID is kept.
+func RequestIDValidator(validate func(id string) bool) RequestIDOption {
+	return func(c *requestIDConfig) { c.validate = validate }
+}
+
+// RequestID returns a constructor that makes sure
+// every request carries an ID in the request ID header.
+// When the incoming request lacks one,
+// an ID is generated.
+//
+// The ID is echoed in the response header
+// and can be read by the following handlers with GetRequestID,
+// so that logging and tracing middleware can correlate requests.
+func RequestID(opts ...RequestIDOption) Constructor {
+	cfg := requestIDConfig{
+		header: DefaultRequestIDHeader,
+		gen:    newUUID,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			id := string(ctx.Request.Header.Peek(cfg.header))
+			if id == "" || cfg.validate != nil && !cfg.validate(id) {
+				id = cfg.gen()
+			}
+
+			Set(ctx, requestIDKey, id)
+			ctx.Response.Header.Set(cfg.header, id)
+			next(ctx)
+		}
+	}
+}
+
+// GetRequestID returns the ID assigned to the request by RequestID,
+// or an empty string if there is none.
+func GetRequestID(ctx *fasthttp.RequestCtx) string {
+	id, _ := Get(ctx, requestIDKey)
+	return id
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
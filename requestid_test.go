@@ -0,0 +1,63 @@
+package fastalice
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestRequestIDGeneratesMissingID(t *testing.T) {
+	var seen string
+	h := New(RequestID(RequestIDGenerator(func() string { return "generated-id" }))).Then(func(ctx *fasthttp.RequestCtx) {
+		seen = GetRequestID(ctx)
+	})
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, "generated-id", seen, "Handlers should see the generated ID")
+	assert.Equal(t, "generated-id", string(ctx.Response.Header.Peek(DefaultRequestIDHeader)), "The generated ID should be echoed in the response")
+}
+
+func TestRequestIDPreservesExistingID(t *testing.T) {
+	var seen string
+	h := New(RequestID(RequestIDHeader("X-Trace"))).Then(func(ctx *fasthttp.RequestCtx) {
+		seen = GetRequestID(ctx)
+	})
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set("X-Trace", "abc123")
+	h(ctx)
+	assert.Equal(t, "abc123", seen, "Handlers should see the incoming ID")
+	assert.Equal(t, "abc123", string(ctx.Response.Header.Peek("X-Trace")), "The incoming ID should be echoed in the response")
+}
+
+func TestRequestIDDefaultGenerator(t *testing.T) {
+	id1, id2 := newUUID(), newUUID()
+	assert.Len(t, id1, 36, "Generated IDs should be formatted as UUIDs")
+	assert.NotEqual(t, id1, id2, "Generated IDs should be unique")
+}
+
+func TestRequestIDValidator(t *testing.T) {
+	valid := func(id string) bool { return len(id) <= 8 }
+	h := New(RequestID(RequestIDValidator(valid), RequestIDGenerator(func() string { return "fresh" }))).Then(testApp)
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set(DefaultRequestIDHeader, "way-too-long-id")
+	h(ctx)
+	assert.Equal(t, "fresh", GetRequestID(ctx), "Rejected IDs should be replaced")
+
+	ctx = newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set(DefaultRequestIDHeader, "short")
+	h(ctx)
+	assert.Equal(t, "short", GetRequestID(ctx), "Accepted IDs should be kept")
+}
+
+func TestRequestIDInLogs(t *testing.T) {
+	var out bytes.Buffer
+	ctx := newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set(DefaultRequestIDHeader, "abc123")
+	New(RequestID(), Logger(&out, TemplateLogFormat("{{.RequestID}}"))).Then(testApp)(ctx)
+	assert.Equal(t, "abc123\n", out.String(), "Logs should carry the request ID")
+}
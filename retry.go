@@ -0,0 +1,114 @@
+package fastalice
+
+import (
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// RetryPolicy configures Retry.
+// Zero fields take the documented defaults.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, 3 by default.
+	MaxAttempts int
+	// StatusCodes lists the response codes worth a retry,
+	// 502, 503 and 504 by default.
+	StatusCodes []int
+	// Methods lists the methods retried,
+	// the idempotent GET, HEAD, OPTIONS, TRACE, PUT and DELETE by default.
+	Methods []string
+	// Backoff is the delay before the first retry,
+	// doubled for every further one; 100ms by default.
+	Backoff time.Duration
+	// MaxBackoff caps the delay between attempts, 2s by default.
+	MaxBackoff time.Duration
+	// Jitter picks each delay at random between zero and its value,
+	// spreading the retries of concurrent requests.
+	Jitter bool
+}
+
+// Retry returns a constructor that calls the following handlers again
+// when they answer with one of policy.StatusCodes,
+// for requests with one of policy.Methods,
+// waiting with exponential backoff between attempts.
+//
+// The response of a failed attempt is discarded,
+// so that partial writes never mix with the next attempt,
+// and the last attempt's response is kept whatever its status.
+// Headers set by the middleware before Retry are kept across attempts.
+// Streaming responses are never retried.
+// It suits handlers proxying to flaky upstreams.
+func Retry(policy RetryPolicy) Constructor {
+	retryCodes, methods := policy.normalize()
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if !methods[string(ctx.Method())] {
+				next(ctx)
+				return
+			}
+
+			var header fasthttp.ResponseHeader
+			ctx.Response.Header.CopyTo(&header)
+			backoff := policy.Backoff
+			for attempt := 1; ; attempt++ {
+				next(ctx)
+				if attempt == policy.MaxAttempts || !retryCodes[ctx.Response.StatusCode()] || IsStreaming(ctx) {
+					return
+				}
+
+				ctx.Response.Reset()
+				header.CopyTo(&ctx.Response.Header)
+				backoff = policy.wait(backoff)
+			}
+		}
+	}
+}
+
+// normalize applies the defaults of policy, returning
+// the status codes and methods it retries as sets.
+func (policy *RetryPolicy) normalize() (map[int]bool, map[string]bool) {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 3
+	}
+	if len(policy.StatusCodes) == 0 {
+		policy.StatusCodes = []int{fasthttp.StatusBadGateway, fasthttp.StatusServiceUnavailable, fasthttp.StatusGatewayTimeout}
+	}
+	if len(policy.Methods) == 0 {
+		policy.Methods = []string{
+			fasthttp.MethodGet, fasthttp.MethodHead, fasthttp.MethodOptions,
+			fasthttp.MethodTrace, fasthttp.MethodPut, fasthttp.MethodDelete,
+		}
+	}
+	if policy.Backoff <= 0 {
+		policy.Backoff = 100 * time.Millisecond
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = 2 * time.Second
+	}
+
+	retryCodes := make(map[int]bool, len(policy.StatusCodes))
+	for _, code := range policy.StatusCodes {
+		retryCodes[code] = true
+	}
+	methods := make(map[string]bool, len(policy.Methods))
+	for _, m := range policy.Methods {
+		methods[strings.ToUpper(m)] = true
+	}
+	return retryCodes, methods
+}
+
+// wait sleeps before a retry for backoff, or a random share of it
+// with Jitter, and returns the backoff of the next retry.
+func (policy *RetryPolicy) wait(backoff time.Duration) time.Duration {
+	d := backoff
+	if policy.Jitter {
+		d = time.Duration(randFloat64() * float64(d))
+	}
+	sleep(d)
+	if backoff *= 2; backoff > policy.MaxBackoff {
+		backoff = policy.MaxBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,80 @@
+package fastalice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// flakyApp fails with 503 and a partial body
+// for its first failures calls.
+func flakyApp(failures int, calls *int) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		*calls++
+		if *calls <= failures {
+			ctx.Response.Header.Set("X-Attempt", "failed")
+			ctx.WriteString("partial")
+			ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+			return
+		}
+		testApp(ctx)
+	}
+}
+
+func TestRetrySucceeds(t *testing.T) {
+	var slept []time.Duration
+	defer fakeSleep(&slept)()
+
+	calls := 0
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(Retry(RetryPolicy{MaxAttempts: 4})).Then(flakyApp(2, &calls))(ctx)
+
+	assert.Equal(t, 3, calls, "Failed attempts should be retried")
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Partial writes of failed attempts should be discarded")
+	assert.Empty(t, ctx.Response.Header.Peek("X-Attempt"), "Headers of failed attempts should be discarded")
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}, slept, "Backoff should double between attempts")
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	var slept []time.Duration
+	defer fakeSleep(&slept)()
+	defer fakeRand(0.5)()
+
+	calls := 0
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(Retry(RetryPolicy{Backoff: time.Second, MaxBackoff: time.Second, Jitter: true})).Then(flakyApp(10, &calls))(ctx)
+
+	assert.Equal(t, 3, calls, "Attempts should stop at MaxAttempts")
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, ctx.Response.StatusCode(), "The last response should be kept")
+	assert.Equal(t, []time.Duration{500 * time.Millisecond, 500 * time.Millisecond}, slept, "Jitter should scale the capped backoff")
+}
+
+func TestRetrySkipsNonIdempotent(t *testing.T) {
+	var slept []time.Duration
+	defer fakeSleep(&slept)()
+
+	calls := 0
+	ctx := newTestCtx("POST", "http://localhost/")
+	New(Retry(RetryPolicy{})).Then(flakyApp(1, &calls))(ctx)
+
+	assert.Equal(t, 1, calls, "POST requests should not be retried")
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, ctx.Response.StatusCode(), "The response should be kept")
+}
+
+func TestRetryKeepsOuterHeaders(t *testing.T) {
+	var slept []time.Duration
+	defer fakeSleep(&slept)()
+
+	calls := 0
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(
+		RequestID(RequestIDGenerator(func() string { return "req-1" })),
+		Retry(RetryPolicy{}),
+	).Then(flakyApp(1, &calls))(ctx)
+
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "The retried request should succeed")
+	assert.Equal(t, "req-1", string(ctx.Response.Header.Peek("X-Request-ID")), "Headers set before Retry should survive retries")
+	assert.Empty(t, ctx.Response.Header.Peek("X-Attempt"), "Headers of failed attempts should be discarded")
+}
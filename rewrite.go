@@ -0,0 +1,161 @@
+package fastalice
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// RewriteRule is a rule applied by Rewrite.
+// It either rewrites the request in place and returns an empty location,
+// or returns the location to redirect the request to,
+// along with the redirect status code.
+type RewriteRule func(ctx *fasthttp.RequestCtx) (location string, code int)
+
+// Rewrite returns a constructor applying rules in order
+// before the rest of the chain.
+// The first rule returning a location answers the request
+// with a redirect to it, 301 Moved Permanently when its code is zero,
+// without calling the following handlers.
+//
+//	chain := fastalice.New(fastalice.Rewrite([]fastalice.RewriteRule{
+//		fastalice.HTTPSRedirect(fasthttp.StatusPermanentRedirect),
+//		fastalice.NonWWWRedirect(0),
+//		fastalice.TrailingSlashRule(fastalice.SlashStrip, 0),
+//		fastalice.RewritePath(`^/v1/(.*)$`, "/api/v1/$1"),
+//	}), logger)
+func Rewrite(rules []RewriteRule) Constructor {
+	rules = append([]RewriteRule(nil), rules...)
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			for _, rule := range rules {
+				location, code := rule(ctx)
+				if location == "" {
+					continue
+				}
+				if code == 0 {
+					code = fasthttp.StatusMovedPermanently
+				}
+				ctx.Redirect(location, code)
+				return
+			}
+			next(ctx)
+		}
+	}
+}
+
+// RewritePath returns a rule rewriting request paths
+// matching the regular expression pattern with replacement,
+// in which $1 and ${name} stand for submatches
+// as in regexp.Regexp.ReplaceAllString.
+// The query string is kept.
+// It panics if pattern does not compile.
+func RewritePath(pattern, replacement string) RewriteRule {
+	re := regexp.MustCompile(pattern)
+
+	return func(ctx *fasthttp.RequestCtx) (string, int) {
+		path := string(ctx.Path())
+		if re.MatchString(path) {
+			ctx.URI().SetPath(re.ReplaceAllString(path, replacement))
+		}
+		return "", 0
+	}
+}
+
+// RedirectPath is like RewritePath,
+// but it redirects the request to the rewritten path with code.
+func RedirectPath(pattern, replacement string, code int) RewriteRule {
+	re := regexp.MustCompile(pattern)
+
+	return func(ctx *fasthttp.RequestCtx) (string, int) {
+		path := string(ctx.Path())
+		if !re.MatchString(path) {
+			return "", 0
+		}
+		return withQuery(ctx, re.ReplaceAllString(path, replacement)), code
+	}
+}
+
+// TrailingSlashRule returns a rule normalizing
+// the trailing slash of request paths as NormalizeTrailingSlash does,
+// redirecting with code in SlashRedirect mode.
+func TrailingSlashRule(mode SlashMode, code int) RewriteRule {
+	return func(ctx *fasthttp.RequestCtx) (string, int) {
+		path := string(ctx.Path())
+		if path == "/" {
+			return "", 0
+		}
+
+		switch mode {
+		case SlashAppend:
+			if !strings.HasSuffix(path, "/") {
+				ctx.URI().SetPath(path + "/")
+			}
+		case SlashRedirect:
+			if strings.HasSuffix(path, "/") {
+				return withQuery(ctx, strings.TrimRight(path, "/")), code
+			}
+		default:
+			if strings.HasSuffix(path, "/") {
+				ctx.URI().SetPath(strings.TrimRight(path, "/"))
+			}
+		}
+		return "", 0
+	}
+}
+
+// HTTPSRedirect returns a rule redirecting plain HTTP requests
+// to the same URL over HTTPS with code.
+// Requests received over TLS, or forwarded by a proxy
+// with X-Forwarded-Proto set to https, are left alone.
+func HTTPSRedirect(code int) RewriteRule {
+	return func(ctx *fasthttp.RequestCtx) (string, int) {
+		if requestScheme(ctx) == "https" {
+			return "", 0
+		}
+		return "https://" + string(ctx.Host()) + string(ctx.URI().RequestURI()), code
+	}
+}
+
+// WWWRedirect returns a rule redirecting requests
+// for hosts without a "www." prefix to the prefixed host with code.
+func WWWRedirect(code int) RewriteRule {
+	return func(ctx *fasthttp.RequestCtx) (string, int) {
+		host := string(ctx.Host())
+		if strings.HasPrefix(strings.ToLower(host), "www.") {
+			return "", 0
+		}
+		return requestScheme(ctx) + "://www." + host + string(ctx.URI().RequestURI()), code
+	}
+}
+
+// NonWWWRedirect returns a rule redirecting requests
+// for hosts with a "www." prefix to the host without it with code.
+func NonWWWRedirect(code int) RewriteRule {
+	return func(ctx *fasthttp.RequestCtx) (string, int) {
+		host := string(ctx.Host())
+		if !strings.HasPrefix(strings.ToLower(host), "www.") {
+			return "", 0
+		}
+		return requestScheme(ctx) + "://" + host[len("www."):] + string(ctx.URI().RequestURI()), code
+	}
+}
+
+// withQuery returns path followed by the query string of the request.
+func withQuery(ctx *fasthttp.RequestCtx, path string) string {
+	if q := ctx.URI().QueryString(); len(q) > 0 {
+		return path + "?" + string(q)
+	}
+	return path
+}
+
+// requestScheme returns the scheme the request was received over,
+// as told by X-Forwarded-Proto when a proxy set it to https.
+func requestScheme(ctx *fasthttp.RequestCtx) string {
+	if ctx.IsTLS() || strings.EqualFold(string(ctx.Request.Header.Peek("X-Forwarded-Proto")), "https") {
+		return "https"
+	}
+	return "http"
+}
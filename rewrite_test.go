@@ -0,0 +1,63 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestRewritePath(t *testing.T) {
+	var seen string
+	h := New(Rewrite([]RewriteRule{
+		RewritePath(`^/v1/(.*)$`, "/api/v1/$1"),
+		TrailingSlashRule(SlashStrip, 0),
+	})).Then(func(ctx *fasthttp.RequestCtx) {
+		seen = string(ctx.URI().RequestURI())
+	})
+
+	h(newTestCtx("GET", "http://localhost/v1/users/?page=2"))
+	assert.Equal(t, "/api/v1/users?page=2", seen, "Rules should rewrite the request in order")
+
+	h(newTestCtx("GET", "http://localhost/other"))
+	assert.Equal(t, "/other", seen, "Unmatched paths should be left alone")
+}
+
+func TestRewriteRedirects(t *testing.T) {
+	for _, tc := range []struct {
+		rule     RewriteRule
+		uri      string
+		location string
+		code     int
+	}{
+		{HTTPSRedirect(fasthttp.StatusPermanentRedirect), "http://example.com/a?b=1", "https://example.com/a?b=1", fasthttp.StatusPermanentRedirect},
+		{WWWRedirect(0), "http://example.com/a", "http://www.example.com/a", fasthttp.StatusMovedPermanently},
+		{NonWWWRedirect(fasthttp.StatusFound), "http://www.example.com/a", "http://example.com/a", fasthttp.StatusFound},
+		{RedirectPath(`^/old/(.*)$`, "/new/$1", 0), "http://example.com/old/page?x=1", "http://example.com/new/page?x=1", fasthttp.StatusMovedPermanently},
+		{TrailingSlashRule(SlashRedirect, fasthttp.StatusFound), "http://example.com/a/", "http://example.com/a", fasthttp.StatusFound},
+	} {
+		ctx := newTestCtx("GET", tc.uri)
+		New(Rewrite([]RewriteRule{tc.rule})).Then(testApp)(ctx)
+		assert.Equal(t, tc.code, ctx.Response.StatusCode(), "%s should be redirected", tc.uri)
+		assert.Equal(t, tc.location, string(ctx.Response.Header.Peek(fasthttp.HeaderLocation)), "%s should be redirected", tc.uri)
+	}
+}
+
+func TestRewriteNoRedirect(t *testing.T) {
+	for _, tc := range []struct {
+		rule RewriteRule
+		uri  string
+	}{
+		{WWWRedirect(0), "http://www.example.com/"},
+		{NonWWWRedirect(0), "http://example.com/"},
+	} {
+		ctx := newTestCtx("GET", tc.uri)
+		New(Rewrite([]RewriteRule{tc.rule})).Then(testApp)(ctx)
+		assert.Equal(t, "app", string(ctx.Response.Body()), "%s should not be redirected", tc.uri)
+	}
+
+	ctx := newTestCtx("GET", "http://example.com/")
+	ctx.Request.Header.Set("X-Forwarded-Proto", "https")
+	New(Rewrite([]RewriteRule{HTTPSRedirect(0)})).Then(testApp)(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Requests forwarded over HTTPS should not be redirected")
+}
@@ -0,0 +1,238 @@
+package fastalice
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// RPCProtocol is the RPC protocol of a request, as found by RPCBridge.
+type RPCProtocol int
+
+const (
+	// RPCNone is a plain HTTP request.
+	RPCNone RPCProtocol = iota
+	// RPCGRPC is a native gRPC request.
+	RPCGRPC
+	// RPCGRPCWeb is a gRPC-Web request, in binary or text mode.
+	RPCGRPCWeb
+	// RPCConnectUnary is a unary Connect request.
+	RPCConnectUnary
+	// RPCConnectStream is a streaming Connect request.
+	RPCConnectStream
+)
+
+var rpcProtocolNames = [...]string{"none", "grpc", "grpc-web", "connect-unary", "connect-stream"}
+
+func (p RPCProtocol) String() string {
+	if p < 0 || int(p) >= len(rpcProtocolNames) {
+		return "RPCProtocol(" + strconv.Itoa(int(p)) + ")"
+	}
+	return rpcProtocolNames[p]
+}
+
+// rpcProtocolKey holds the protocol found by RPCBridge.
+var rpcProtocolKey = NewKey[RPCProtocol]("rpcProtocol")
+
+// RPCProtocolOf returns the RPC protocol of the request found by RPCBridge,
+// or detects it when the request did not go through RPCBridge.
+func RPCProtocolOf(ctx *fasthttp.RequestCtx) RPCProtocol {
+	if p, ok := Get(ctx, rpcProtocolKey); ok {
+		return p
+	}
+	p, _ := detectRPC(ctx)
+	return p
+}
+
+// detectRPC returns the RPC protocol of the request
+// from its Content-Type, reporting whether it uses gRPC-Web text mode.
+func detectRPC(ctx *fasthttp.RequestCtx) (RPCProtocol, bool) {
+	mt := mediaType(ctx.Request.Header.ContentType())
+	switch {
+	case mt == "application/grpc-web-text" || strings.HasPrefix(mt, "application/grpc-web-text+"):
+		return RPCGRPCWeb, true
+	case mt == "application/grpc-web" || strings.HasPrefix(mt, "application/grpc-web+"):
+		return RPCGRPCWeb, false
+	case mt == "application/grpc" || strings.HasPrefix(mt, "application/grpc+"):
+		return RPCGRPC, false
+	case strings.HasPrefix(mt, "application/connect+"):
+		return RPCConnectStream, false
+	case len(ctx.Request.Header.Peek("Connect-Protocol-Version")) > 0:
+		return RPCConnectUnary, false
+	}
+	// Unary Connect GET requests carry the message in the query string.
+	if ctx.IsGet() && ctx.QueryArgs().Has("connect") && ctx.QueryArgs().Has("encoding") {
+		return RPCConnectUnary, false
+	}
+	return RPCNone, false
+}
+
+// RPCBridge returns a constructor letting a chain sit in front of
+// gRPC-Web and Connect handlers adapted into fasthttp,
+// such as Connect-go handlers wrapped with fasthttpadaptor,
+// so that REST and RPC services can share one chain.
+// It should come first in the chain.
+//
+// It detects the protocol of each request from its Content-Type,
+// made available with RPCProtocolOf, and:
+//
+//   - decodes gRPC-Web text mode requests, whose frames are base64 encoded,
+//     so that the following handlers only deal with binary frames,
+//     and encodes their response back; streamed responses are buffered
+//     to do so.
+//   - turns errors answered by middleware, such as a 401 from
+//     authentication or a 429 from rate limiting, into errors of the protocol:
+//     a trailers-only response with grpc-status for gRPC-Web,
+//     a JSON error for unary Connect and an end-of-stream message
+//     for streaming Connect, with the code mapped from the HTTP status
+//     as in the gRPC specification.
+//
+// Native gRPC needs HTTP/2 trailers, which fasthttp does not support:
+// such requests are answered with 415 Unsupported Media Type.
+func RPCBridge() Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			protocol, text := detectRPC(ctx)
+			Set(ctx, rpcProtocolKey, protocol)
+			switch protocol {
+			case RPCNone:
+				next(ctx)
+				return
+			case RPCGRPC:
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusUnsupportedMediaType), fasthttp.StatusUnsupportedMediaType)
+				return
+			}
+
+			var contentType string
+			if text {
+				contentType = string(ctx.Request.Header.ContentType())
+				body, err := base64.StdEncoding.DecodeString(string(ctx.PostBody()))
+				if err != nil {
+					writeRPCError(ctx, protocol, contentType, 13, "invalid base64 request body")
+					return
+				}
+				ctx.Request.SetBody(body)
+				ctx.Request.Header.SetContentType(strings.Replace(contentType, "grpc-web-text", "grpc-web", 1))
+			}
+
+			next(ctx)
+
+			if code, msg, ok := rpcMiddlewareError(ctx, protocol); ok {
+				writeRPCError(ctx, protocol, contentType, code, msg)
+				return
+			}
+			if text {
+				body := base64.StdEncoding.EncodeToString(ctx.Response.Body())
+				ctx.Response.SetBodyString(body)
+				ctx.Response.Header.SetContentType(strings.Replace(string(ctx.Response.Header.ContentType()), "grpc-web", "grpc-web-text", 1))
+			}
+		}
+	}
+}
+
+// rpcMiddlewareError reports whether the response is an HTTP error
+// answered by middleware rather than by the RPC handler,
+// returning its gRPC code and message.
+func rpcMiddlewareError(ctx *fasthttp.RequestCtx, protocol RPCProtocol) (int, string, bool) {
+	status := ctx.Response.StatusCode()
+	mt := mediaType(ctx.Response.Header.ContentType())
+	switch protocol {
+	case RPCGRPCWeb:
+		if status == fasthttp.StatusOK && strings.HasPrefix(mt, "application/grpc-web") {
+			return 0, "", false
+		}
+	case RPCConnectUnary:
+		if status < 400 || mt == "application/json" {
+			return 0, "", false
+		}
+	case RPCConnectStream:
+		if status == fasthttp.StatusOK && strings.HasPrefix(mt, "application/connect+") {
+			return 0, "", false
+		}
+	}
+	if status == fasthttp.StatusOK {
+		return 0, "", false
+	}
+	return grpcCode(status), string(bytes.TrimSpace(ctx.Response.Body())), true
+}
+
+// grpcCode maps an HTTP status to a gRPC status code,
+// as in the gRPC specification.
+func grpcCode(status int) int {
+	switch status {
+	case fasthttp.StatusBadRequest:
+		return 13 // INTERNAL
+	case fasthttp.StatusUnauthorized:
+		return 16 // UNAUTHENTICATED
+	case fasthttp.StatusForbidden:
+		return 7 // PERMISSION_DENIED
+	case fasthttp.StatusNotFound:
+		return 12 // UNIMPLEMENTED
+	case fasthttp.StatusTooManyRequests, fasthttp.StatusBadGateway,
+		fasthttp.StatusServiceUnavailable, fasthttp.StatusGatewayTimeout:
+		return 14 // UNAVAILABLE
+	}
+	return 2 // UNKNOWN
+}
+
+// connectCodes are the names of gRPC codes in the Connect protocol.
+var connectCodes = [...]string{
+	"ok", "canceled", "unknown", "invalid_argument", "deadline_exceeded",
+	"not_found", "already_exists", "permission_denied", "resource_exhausted",
+	"failed_precondition", "aborted", "out_of_range", "unimplemented",
+	"internal", "unavailable", "data_loss", "unauthenticated",
+}
+
+// connectHTTPStatus maps gRPC codes to the HTTP status
+// of unary Connect errors.
+var connectHTTPStatus = [...]int{
+	200, 499, 500, 400, 504, 404, 409, 403, 429, 400, 409, 400, 501, 500, 503, 500, 401,
+}
+
+// writeRPCError answers the request with an error of the given protocol.
+// contentType is the request Content-Type for gRPC-Web text mode.
+func writeRPCError(ctx *fasthttp.RequestCtx, protocol RPCProtocol, contentType string, code int, msg string) {
+	resp := &ctx.Response
+	// Keep headers set by middleware, such as Retry-After or WWW-Authenticate.
+	resp.ResetBody()
+	resp.Header.Del(fasthttp.HeaderContentEncoding)
+
+	switch protocol {
+	case RPCGRPCWeb:
+		if contentType == "" {
+			contentType = "application/grpc-web+proto"
+		}
+		resp.SetStatusCode(fasthttp.StatusOK)
+		resp.Header.SetContentType(contentType)
+		resp.Header.Set("Grpc-Status", strconv.Itoa(code))
+		resp.Header.Set("Grpc-Message", url.PathEscape(msg))
+	case RPCConnectUnary:
+		body, _ := json.Marshal(struct {
+			Code    string `json:"code"`
+			Message string `json:"message,omitempty"`
+		}{connectCodes[code], msg})
+		resp.SetStatusCode(connectHTTPStatus[code])
+		resp.Header.SetContentType("application/json")
+		resp.SetBody(body)
+	case RPCConnectStream:
+		type connectError struct {
+			Code    string `json:"code"`
+			Message string `json:"message,omitempty"`
+		}
+		end, _ := json.Marshal(struct {
+			Error connectError `json:"error"`
+		}{connectError{connectCodes[code], msg}})
+		frame := make([]byte, 5, 5+len(end))
+		frame[0] = 0x02 // end of stream
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(end)))
+		resp.SetStatusCode(fasthttp.StatusOK)
+		resp.Header.SetContentType(string(ctx.Request.Header.ContentType()))
+		resp.SetBody(append(frame, end...))
+	}
+}
@@ -0,0 +1,115 @@
+package fastalice
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// grpcWebApp echoes the request frames as a gRPC-Web response.
+func grpcWebApp(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType(string(ctx.Request.Header.ContentType()))
+	ctx.SetBody(ctx.PostBody())
+}
+
+// denyMiddleware answers every request with 401 Unauthorized.
+func denyMiddleware(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		ctx.Error("unauthorized", fasthttp.StatusUnauthorized)
+		ctx.Response.Header.Set(fasthttp.HeaderWWWAuthenticate, "Bearer")
+	}
+}
+
+func newRPCCtx(contentType, body string) *fasthttp.RequestCtx {
+	ctx := newTestCtx("POST", "http://localhost/pkg.Service/Method")
+	ctx.Request.Header.SetContentType(contentType)
+	ctx.Request.SetBodyString(body)
+	return ctx
+}
+
+func TestRPCProtocolOf(t *testing.T) {
+	for contentType, want := range map[string]RPCProtocol{
+		"application/json":                RPCNone,
+		"application/grpc":                RPCGRPC,
+		"application/grpc-web+proto":      RPCGRPCWeb,
+		"application/grpc-web-text":       RPCGRPCWeb,
+		"application/connect+json":        RPCConnectStream,
+		"application/proto; charset=utf8": RPCNone,
+	} {
+		assert.Equal(t, want, RPCProtocolOf(newRPCCtx(contentType, "")), "%s should be detected", contentType)
+	}
+
+	ctx := newRPCCtx("application/proto", "")
+	ctx.Request.Header.Set("Connect-Protocol-Version", "1")
+	assert.Equal(t, RPCConnectUnary, RPCProtocolOf(ctx), "Unary Connect requests should be detected")
+	assert.Equal(t, "connect-unary", RPCConnectUnary.String(), "Protocols should have a name")
+}
+
+func TestRPCBridgeGRPCWebText(t *testing.T) {
+	var seen string
+	h := New(RPCBridge()).Then(func(ctx *fasthttp.RequestCtx) {
+		seen = string(ctx.Request.Header.ContentType())
+		grpcWebApp(ctx)
+	})
+
+	frame := "\x00\x00\x00\x00\x02hi"
+	ctx := newRPCCtx("application/grpc-web-text+proto", base64.StdEncoding.EncodeToString([]byte(frame)))
+	h(ctx)
+	assert.Equal(t, "application/grpc-web+proto", seen, "Handlers should see a binary request")
+	assert.Equal(t, "application/grpc-web-text+proto", string(ctx.Response.Header.ContentType()), "The response should be in text mode")
+	body, err := base64.StdEncoding.DecodeString(string(ctx.Response.Body()))
+	assert.NoError(t, err, "The response should be base64 encoded")
+	assert.Equal(t, frame, string(body), "The frames should round-trip")
+
+	ctx = newRPCCtx("application/grpc-web-text", "!!")
+	h(ctx)
+	assert.Equal(t, "13", string(ctx.Response.Header.Peek("Grpc-Status")), "Invalid base64 should be an INTERNAL error")
+}
+
+func TestRPCBridgeMiddlewareErrors(t *testing.T) {
+	h := New(RPCBridge(), denyMiddleware).Then(grpcWebApp)
+
+	ctx := newRPCCtx("application/grpc-web+proto", "")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "gRPC-Web errors should be trailers-only responses")
+	assert.Equal(t, "16", string(ctx.Response.Header.Peek("Grpc-Status")), "401 should map to UNAUTHENTICATED")
+	assert.Equal(t, "unauthorized", string(ctx.Response.Header.Peek("Grpc-Message")), "The message should be kept")
+	assert.Equal(t, "Bearer", string(ctx.Response.Header.Peek(fasthttp.HeaderWWWAuthenticate)), "Middleware headers should be kept")
+
+	ctx = newRPCCtx("application/proto", "")
+	ctx.Request.Header.Set("Connect-Protocol-Version", "1")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "Unary Connect errors should keep their status")
+	assert.JSONEq(t, `{"code":"unauthenticated","message":"unauthorized"}`, string(ctx.Response.Body()), "Unary Connect errors should be JSON")
+
+	ctx = newRPCCtx("application/connect+proto", "")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Streaming Connect errors should be in the stream")
+	body := ctx.Response.Body()
+	if assert.True(t, len(body) > 5, "An end-of-stream message should be written") {
+		assert.Equal(t, byte(0x02), body[0], "The end-of-stream flag should be set")
+		assert.JSONEq(t, `{"error":{"code":"unauthenticated","message":"unauthorized"}}`, string(body[5:]), "The error should be in the end-of-stream message")
+	}
+
+	ctx = newRPCCtx("application/json", "")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "Plain HTTP requests should be left alone")
+
+	ctx = newRPCCtx("application/grpc", "")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusUnsupportedMediaType, ctx.Response.StatusCode(), "Native gRPC should be refused")
+}
+
+func TestRPCBridgeHandlerResponses(t *testing.T) {
+	h := New(RPCBridge()).Then(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		ctx.SetContentType("application/json")
+		ctx.SetBodyString(`{"code":"not_found"}`)
+	})
+	ctx := newRPCCtx("application/json", "{}")
+	ctx.Request.Header.Set("Connect-Protocol-Version", "1")
+	h(ctx)
+	assert.Equal(t, `{"code":"not_found"}`, string(ctx.Response.Body()), "Errors of the RPC handler should be left alone")
+}
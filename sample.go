@@ -0,0 +1,54 @@
+package fastalice
+
+import (
+	"hash/fnv"
+	"math"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Sample returns a constructor wrapping the following handlers
+// with c for a fraction rate, between 0 and 1, of the requests,
+// so that expensive middleware such as Dump run only on a sample of traffic.
+//
+// Requests are sampled by hashing the ID set by RequestID,
+// so the same request is sampled alike by every Sample
+// using the same rate, including in other services it is forwarded to;
+// requests without an ID are sampled at random.
+//
+//	chain := fastalice.New(fastalice.RequestID(), fastalice.Sample(0.01, fastalice.Dump(os.Stderr, fastalice.DumpOptions{})))
+func Sample(rate float64, c Constructor) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		wrapped := c(next)
+
+		return func(ctx *fasthttp.RequestCtx) {
+			if sampled(ctx, rate) {
+				wrapped(ctx)
+				return
+			}
+			next(ctx)
+		}
+	}
+}
+
+// sampled reports whether the request falls in the fraction rate of requests.
+func sampled(ctx *fasthttp.RequestCtx, rate float64) bool {
+	if id := GetRequestID(ctx); id != "" {
+		h := fnv.New64a()
+		h.Write([]byte(id))
+		return float64(mix64(h.Sum64()))/math.MaxUint64 < rate
+	}
+	return randFloat64() < rate
+}
+
+// mix64 spreads the bits of an FNV hash, whose high bits barely change
+// between short keys differing only in their last characters,
+// such as sequential request IDs.
+func mix64(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
@@ -0,0 +1,46 @@
+package fastalice
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestSampleByRequestID(t *testing.T) {
+	h := New(RequestID(), Sample(0.25, tagMiddleware("sampled"))).Then(testApp)
+
+	hits := 0
+	for i := 0; i < 1000; i++ {
+		id := fmt.Sprintf("req-%d", i)
+		first := serveWithID(h, id)
+		assert.Equal(t, first, serveWithID(h, id), "A request ID should always be sampled alike")
+		if first == "sampledapp" {
+			hits++
+		}
+	}
+	assert.InDelta(t, 250, hits, 50, "About a quarter of the requests should be sampled")
+}
+
+func TestSampleWithoutRequestID(t *testing.T) {
+	h := New(Sample(0.5, tagMiddleware("sampled"))).Then(testApp)
+
+	restore := fakeRand(0.4)
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	restore()
+	assert.Equal(t, "sampledapp", string(ctx.Response.Body()), "Requests below the rate should be sampled")
+
+	defer fakeRand(0.6)()
+	ctx = newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Requests above the rate should not be sampled")
+}
+
+func serveWithID(h fasthttp.RequestHandler, id string) string {
+	ctx := newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set(DefaultRequestIDHeader, id)
+	h(ctx)
+	return string(ctx.Response.Body())
+}
@@ -0,0 +1,166 @@
+package fastalice
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ScanResult is the verdict of a Scanner on some content.
+type ScanResult struct {
+	Infected bool
+	// Threat names what was found in infected content.
+	Threat string
+}
+
+// Scanner scans content for malware, such as an antivirus daemon.
+type Scanner interface {
+	Scan(r io.Reader) (ScanResult, error)
+}
+
+// ScannerFunc is a function used as a Scanner.
+type ScannerFunc func(r io.Reader) (ScanResult, error)
+
+// Scan calls f(r).
+func (f ScannerFunc) Scan(r io.Reader) (ScanResult, error) { return f(r) }
+
+// ScanBody returns a constructor streaming non-empty request bodies
+// through scanner before the following handlers run,
+// so that infected uploads never reach them.
+// Infected bodies are answered with 422 Unprocessable Entity,
+// and bodies that cannot be scanned with 503 Service Unavailable.
+//
+// Streamed bodies are buffered while they are scanned,
+// and handed to the following handlers once found clean;
+// place MaxBodySize before ScanBody to bound them.
+//
+//	chain := fastalice.New(
+//		fastalice.MaxBodySize(50<<20),
+//		fastalice.ScanBody(&fastalice.ClamdScanner{Address: "127.0.0.1:3310"}),
+//	)
+func ScanBody(scanner Scanner) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			var body io.Reader
+			var buf bytes.Buffer
+			streamed := ctx.Request.IsBodyStream()
+			if streamed {
+				stream := pipeStream(&ctx.Request)
+				defer stream.Close()
+				body = io.TeeReader(stream, &buf)
+			} else if b := ctx.PostBody(); len(b) > 0 {
+				body = bytes.NewReader(b)
+			} else {
+				next(ctx)
+				return
+			}
+
+			result, err := scanner.Scan(body)
+			if err == nil && streamed {
+				// Drain what the scanner left, so that the whole body is kept.
+				_, err = io.Copy(io.Discard, body)
+			}
+			switch {
+			case err != nil:
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusServiceUnavailable), fasthttp.StatusServiceUnavailable)
+				return
+			case result.Infected:
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusUnprocessableEntity), fasthttp.StatusUnprocessableEntity)
+				return
+			}
+			if streamed {
+				ctx.Request.SetBody(buf.Bytes())
+			}
+			next(ctx)
+		}
+	}
+}
+
+// DefaultClamdChunkSize is the size of the chunks
+// a ClamdScanner streams unless ChunkSize is set.
+const DefaultClamdChunkSize = 64 << 10
+
+// ClamdScanner is a Scanner sending content to a ClamAV daemon
+// with its INSTREAM command.
+// Content larger than the StreamMaxLength of the daemon is reported as an error.
+type ClamdScanner struct {
+	// Network is the network of Address, "tcp" when empty;
+	// use "unix" for a local socket.
+	Network string
+	Address string
+	// Timeout bounds a scan, 30 seconds when zero.
+	Timeout time.Duration
+	// ChunkSize is the size of the chunks content is streamed in,
+	// DefaultClamdChunkSize when zero.
+	ChunkSize int
+}
+
+// Scan streams r to the daemon and returns its verdict.
+func (s *ClamdScanner) Scan(r io.Reader) (ScanResult, error) {
+	network, timeout, chunkSize := s.Network, s.Timeout, s.ChunkSize
+	if network == "" {
+		network = "tcp"
+	}
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	if chunkSize <= 0 {
+		chunkSize = DefaultClamdChunkSize
+	}
+
+	conn, err := net.DialTimeout(network, s.Address, timeout)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	w := bufio.NewWriterSize(conn, chunkSize+4)
+	w.WriteString("zINSTREAM\x00")
+	chunk := make([]byte, chunkSize)
+	for {
+		n, err := io.ReadFull(r, chunk)
+		if n > 0 {
+			binary.Write(w, binary.BigEndian, uint32(n))
+			if _, werr := w.Write(chunk[:n]); werr != nil {
+				return ScanResult{}, werr
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return ScanResult{}, err
+		}
+	}
+	binary.Write(w, binary.BigEndian, uint32(0))
+	if err := w.Flush(); err != nil {
+		return ScanResult{}, err
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	return parseClamdReply(strings.TrimSuffix(reply, "\x00"))
+}
+
+// parseClamdReply parses the reply of the daemon to INSTREAM,
+// such as "stream: OK" or "stream: Eicar-Signature FOUND".
+func parseClamdReply(reply string) (ScanResult, error) {
+	verdict := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case verdict == "OK":
+		return ScanResult{}, nil
+	case strings.HasSuffix(verdict, " FOUND"):
+		return ScanResult{Infected: true, Threat: strings.TrimSuffix(verdict, " FOUND")}, nil
+	}
+	return ScanResult{}, fmt.Errorf("fastalice: clamd: %s", reply)
+}
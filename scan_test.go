@@ -0,0 +1,121 @@
+package fastalice
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// signatureScanner flags content containing "EICAR".
+var signatureScanner = ScannerFunc(func(r io.Reader) (ScanResult, error) {
+	b, err := io.ReadAll(r)
+	if bytes.Contains(b, []byte("EICAR")) {
+		return ScanResult{Infected: true, Threat: "Eicar-Signature"}, err
+	}
+	return ScanResult{}, err
+})
+
+func TestScanBody(t *testing.T) {
+	var got string
+	h := New(ScanBody(signatureScanner)).Then(func(ctx *fasthttp.RequestCtx) {
+		got = string(ctx.PostBody())
+	})
+
+	for _, stream := range []bool{false, true} {
+		for body, status := range map[string]int{
+			"clean upload":        fasthttp.StatusOK,
+			"upload EICAR inside": fasthttp.StatusUnprocessableEntity,
+		} {
+			got = ""
+			ctx := newTestCtx("POST", "http://localhost/upload")
+			if stream {
+				ctx.Request.SetBodyStream(strings.NewReader(body), -1)
+			} else {
+				ctx.Request.SetBodyString(body)
+			}
+			h(ctx)
+			assert.Equal(t, status, ctx.Response.StatusCode(), "The status should match for %q", body)
+			if status == fasthttp.StatusOK {
+				assert.Equal(t, body, got, "Clean bodies should reach the handler")
+			} else {
+				assert.Empty(t, got, "Infected bodies should not reach the handler")
+			}
+		}
+	}
+}
+
+func TestScanBodyFailsClosed(t *testing.T) {
+	h := New(ScanBody(ScannerFunc(func(r io.Reader) (ScanResult, error) {
+		return ScanResult{}, errors.New("daemon down")
+	}))).Then(testApp)
+
+	ctx := newTestCtx("POST", "http://localhost/upload")
+	ctx.Request.SetBodyString("data")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, ctx.Response.StatusCode(), "Unscanned bodies should be rejected")
+
+	ctx = newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Empty bodies should not be scanned")
+}
+
+// fakeClamd serves INSTREAM commands on a unix socket,
+// flagging content containing "EICAR".
+func fakeClamd(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "clamd.sock")
+	ln, err := net.Listen("unix", path)
+	assert.NoError(t, err, "The socket should listen")
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			br := bufio.NewReader(conn)
+			cmd, _ := br.ReadString(0)
+			var content []byte
+			for cmd == "zINSTREAM\x00" {
+				var n uint32
+				if binary.Read(br, binary.BigEndian, &n) != nil || n == 0 {
+					break
+				}
+				chunk := make([]byte, n)
+				io.ReadFull(br, chunk)
+				content = append(content, chunk...)
+			}
+			reply := "stream: OK\x00"
+			if bytes.Contains(content, []byte("EICAR")) {
+				reply = "stream: Eicar-Signature FOUND\x00"
+			}
+			conn.Write([]byte(reply))
+			conn.Close()
+		}
+	}()
+	return path
+}
+
+func TestClamdScanner(t *testing.T) {
+	s := &ClamdScanner{Network: "unix", Address: fakeClamd(t), ChunkSize: 4}
+
+	result, err := s.Scan(strings.NewReader("a clean document"))
+	assert.NoError(t, err, "The scan should succeed")
+	assert.False(t, result.Infected, "Clean content should pass")
+
+	result, err = s.Scan(strings.NewReader("some EICAR payload"))
+	assert.NoError(t, err, "The scan should succeed")
+	assert.Equal(t, ScanResult{Infected: true, Threat: "Eicar-Signature"}, result, "The threat should be reported")
+
+	_, err = parseClamdReply("INSTREAM size limit exceeded. ERROR")
+	assert.Error(t, err, "Daemon errors should be reported")
+}
@@ -0,0 +1,46 @@
+package fastalice
+
+import (
+	"reflect"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Scoped returns a constructor creating a resource per request
+// with factory before the following handlers run,
+// and calling its cleanup once they return, even when they panic.
+// The resource is available to them with Use and Provided
+// under its dynamic type, as if given by Provide,
+// such as a database transaction rolled back unless committed:
+//
+//	fastalice.Scoped(func() (interface{}, func()) {
+//		tx, err := db.Begin()
+//		if err != nil {
+//			panic(err)
+//		}
+//		// Rollback is a no-op once the handler committed.
+//		return tx, func() { tx.Rollback() }
+//	})
+//
+//	func createOrder(ctx *fasthttp.RequestCtx) {
+//		tx := fastalice.Use[*sql.Tx](ctx)
+//		...
+//		tx.Commit()
+//	}
+//
+// A nil cleanup is not called. Handlers must not keep the resource
+// beyond the request, as it is cleaned up once they return.
+func Scoped(factory func() (resource interface{}, cleanup func())) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			resource, cleanup := factory()
+			if cleanup != nil {
+				defer cleanup()
+			}
+			if resource != nil {
+				defer provide(ctx, reflect.TypeOf(resource), resource)()
+			}
+			next(ctx)
+		}
+	}
+}
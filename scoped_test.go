@@ -0,0 +1,52 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+type testTx struct {
+	committed, rolledBack bool
+}
+
+func (tx *testTx) Rollback() {
+	if !tx.committed {
+		tx.rolledBack = true
+	}
+}
+
+func TestScoped(t *testing.T) {
+	var txs []*testTx
+	h := New(Scoped(func() (interface{}, func()) {
+		tx := &testTx{}
+		txs = append(txs, tx)
+		return tx, tx.Rollback
+	})).Then(func(ctx *fasthttp.RequestCtx) {
+		tx := Use[*testTx](ctx)
+		if ctx.QueryArgs().Has("panic") {
+			panic("boom")
+		}
+		tx.committed = true
+	})
+
+	h(newTestCtx("POST", "http://localhost/orders"))
+	h(newTestCtx("POST", "http://localhost/orders"))
+	assert.Len(t, txs, 2, "A resource should be created per request")
+	assert.NotSame(t, txs[0], txs[1], "Requests should get their own resource")
+	assert.True(t, txs[0].committed && !txs[0].rolledBack, "A committed resource should not be rolled back")
+
+	assert.Panics(t, func() { h(newTestCtx("POST", "http://localhost/orders?panic")) }, "The panic should propagate")
+	assert.True(t, txs[2].rolledBack, "The cleanup should run on panic")
+}
+
+func TestScopedNilResource(t *testing.T) {
+	cleaned := false
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(Scoped(func() (interface{}, func()) {
+		return nil, func() { cleaned = true }
+	})).Then(testApp)(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "The request should be served")
+	assert.True(t, cleaned, "The cleanup should run without a resource")
+}
@@ -0,0 +1,118 @@
+package fastalice
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// SecureConfig selects the hardening headers set by SecureHeaders.
+// A header whose field is left empty or disabled is not set.
+type SecureConfig struct {
+	// ContentTypeNosniff sets "X-Content-Type-Options: nosniff".
+	ContentTypeNosniff bool
+	// FrameOptions is the X-Frame-Options value,
+	// usually "DENY" or "SAMEORIGIN".
+	FrameOptions string
+	// HSTSMaxAge enables Strict-Transport-Security
+	// with the given max-age.
+	HSTSMaxAge time.Duration
+	// HSTSIncludeSubdomains adds includeSubDomains
+	// to Strict-Transport-Security.
+	HSTSIncludeSubdomains bool
+	// ContentSecurityPolicy is the Content-Security-Policy value.
+	ContentSecurityPolicy string
+	// ReferrerPolicy is the Referrer-Policy value,
+	// such as "strict-origin-when-cross-origin".
+	ReferrerPolicy string
+}
+
+// DefaultSecureConfig returns sane defaults for SecureHeaders:
+// nosniff, frames denied, HSTS for a year
+// and a strict-origin-when-cross-origin referrer policy.
+// No Content-Security-Policy is set,
+// as it depends on the application.
+func DefaultSecureConfig() SecureConfig {
+	return SecureConfig{
+		ContentTypeNosniff: true,
+		FrameOptions:       "DENY",
+		HSTSMaxAge:         365 * 24 * time.Hour,
+		ReferrerPolicy:     "strict-origin-when-cross-origin",
+	}
+}
+
+// secureOverrideKey holds the configuration
+// set for the request with OverrideSecureHeaders.
+var secureOverrideKey = NewKey[SecureConfig]("secureOverride")
+
+// OverrideSecureHeaders replaces, for the current request,
+// the configuration of the SecureHeaders middleware
+// placed before the caller in the chain.
+// Route handlers can use it to relax or tighten headers,
+// such as allowing a page to be framed.
+func OverrideSecureHeaders(ctx *fasthttp.RequestCtx, cfg SecureConfig) {
+	Set(ctx, secureOverrideKey, cfg)
+}
+
+// SecureHeaders returns a constructor that sets
+// the hardening headers selected by cfg
+// before the following handlers run,
+// so they can still be overridden downstream.
+//
+// When a following handler calls OverrideSecureHeaders,
+// the headers are set again from its configuration
+// once the following handlers have run,
+// and those it disables are removed.
+func SecureHeaders(cfg SecureConfig) Constructor {
+	headers := secureHeaders(cfg)
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			h := &ctx.Response.Header
+			for _, kv := range headers {
+				if kv[1] != "" {
+					h.Set(kv[0], kv[1])
+				}
+			}
+			next(ctx)
+
+			override, ok := Get(ctx, secureOverrideKey)
+			if !ok {
+				return
+			}
+			Delete(ctx, secureOverrideKey)
+			for _, kv := range secureHeaders(override) {
+				if kv[1] == "" {
+					h.Del(kv[0])
+				} else {
+					h.Set(kv[0], kv[1])
+				}
+			}
+		}
+	}
+}
+
+// secureHeaders returns the name and value of every header
+// managed by SecureHeaders, with an empty value for those
+// that cfg disables.
+func secureHeaders(cfg SecureConfig) [][2]string {
+	var nosniff, hsts string
+	if cfg.ContentTypeNosniff {
+		nosniff = "nosniff"
+	}
+	if cfg.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.FormatInt(int64(cfg.HSTSMaxAge/time.Second), 10)
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+	}
+
+	return [][2]string{
+		{fasthttp.HeaderXContentTypeOptions, nosniff},
+		{fasthttp.HeaderXFrameOptions, cfg.FrameOptions},
+		{fasthttp.HeaderStrictTransportSecurity, hsts},
+		{fasthttp.HeaderContentSecurityPolicy, cfg.ContentSecurityPolicy},
+		{fasthttp.HeaderReferrerPolicy, cfg.ReferrerPolicy},
+	}
+}
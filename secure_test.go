@@ -0,0 +1,69 @@
+package fastalice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestSecureHeadersEnabled(t *testing.T) {
+	cfg := SecureConfig{
+		ContentTypeNosniff:    true,
+		FrameOptions:          "DENY",
+		HSTSMaxAge:            365 * 24 * time.Hour,
+		HSTSIncludeSubdomains: true,
+		ContentSecurityPolicy: "default-src 'self'",
+	}
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(SecureHeaders(cfg)).Then(testApp)(ctx)
+
+	h := &ctx.Response.Header
+	assert.Equal(t, "nosniff", string(h.Peek(fasthttp.HeaderXContentTypeOptions)), "X-Content-Type-Options should be set")
+	assert.Equal(t, "DENY", string(h.Peek(fasthttp.HeaderXFrameOptions)), "X-Frame-Options should be set")
+	assert.Equal(t, "max-age=31536000; includeSubDomains", string(h.Peek(fasthttp.HeaderStrictTransportSecurity)), "Strict-Transport-Security should be set")
+	assert.Equal(t, "default-src 'self'", string(h.Peek(fasthttp.HeaderContentSecurityPolicy)), "Content-Security-Policy should be set")
+}
+
+func TestSecureHeadersDisabled(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(SecureHeaders(SecureConfig{FrameOptions: "SAMEORIGIN"})).Then(testApp)(ctx)
+
+	h := &ctx.Response.Header
+	assert.Equal(t, "SAMEORIGIN", string(h.Peek(fasthttp.HeaderXFrameOptions)), "X-Frame-Options should be set")
+	assert.Empty(t, h.Peek(fasthttp.HeaderXContentTypeOptions), "X-Content-Type-Options should not be set")
+	assert.Empty(t, h.Peek(fasthttp.HeaderStrictTransportSecurity), "Strict-Transport-Security should not be set")
+	assert.Empty(t, h.Peek(fasthttp.HeaderContentSecurityPolicy), "Content-Security-Policy should not be set")
+}
+
+func TestSecureHeadersDefaults(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(SecureHeaders(DefaultSecureConfig())).Then(testApp)(ctx)
+
+	h := &ctx.Response.Header
+	assert.Equal(t, "nosniff", string(h.Peek(fasthttp.HeaderXContentTypeOptions)), "X-Content-Type-Options should be set")
+	assert.Equal(t, "DENY", string(h.Peek(fasthttp.HeaderXFrameOptions)), "X-Frame-Options should be set")
+	assert.Equal(t, "max-age=31536000", string(h.Peek(fasthttp.HeaderStrictTransportSecurity)), "Strict-Transport-Security should be set")
+	assert.Equal(t, "strict-origin-when-cross-origin", string(h.Peek(fasthttp.HeaderReferrerPolicy)), "Referrer-Policy should be set")
+	assert.Empty(t, h.Peek(fasthttp.HeaderContentSecurityPolicy), "Content-Security-Policy should not be set by default")
+}
+
+func TestSecureHeadersOverride(t *testing.T) {
+	embeddable := func(ctx *fasthttp.RequestCtx) {
+		cfg := DefaultSecureConfig()
+		cfg.FrameOptions = ""
+		cfg.ContentSecurityPolicy = "frame-ancestors https://partner.example"
+		OverrideSecureHeaders(ctx, cfg)
+		testApp(ctx)
+	}
+
+	ctx := newTestCtx("GET", "http://localhost/widget")
+	New(SecureHeaders(DefaultSecureConfig())).Then(embeddable)(ctx)
+
+	h := &ctx.Response.Header
+	assert.Empty(t, h.Peek(fasthttp.HeaderXFrameOptions), "Disabled headers should be removed")
+	assert.Equal(t, "frame-ancestors https://partner.example", string(h.Peek(fasthttp.HeaderContentSecurityPolicy)), "Overridden headers should be set")
+	assert.Equal(t, "nosniff", string(h.Peek(fasthttp.HeaderXContentTypeOptions)), "Kept headers should stay")
+}
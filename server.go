@@ -0,0 +1,90 @@
+package fastalice
+
+import (
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Default timeouts of the servers returned by Chain.Server.
+const (
+	DefaultReadTimeout  = 10 * time.Second
+	DefaultWriteTimeout = 30 * time.Second
+	DefaultIdleTimeout  = 2 * time.Minute
+)
+
+// ServerConfig configures the server returned by Chain.Server.
+// Zero fields take the documented defaults,
+// or those of fasthttp.Server.
+type ServerConfig struct {
+	// Name is sent in the Server response header.
+	Name string
+	// ReadTimeout bounds the reading of a request,
+	// DefaultReadTimeout by default.
+	ReadTimeout time.Duration
+	// WriteTimeout bounds the writing of a response,
+	// DefaultWriteTimeout by default.
+	WriteTimeout time.Duration
+	// IdleTimeout bounds the wait for the next request
+	// on keep-alive connections, DefaultIdleTimeout by default.
+	IdleTimeout time.Duration
+	// MaxRequestBodySize limits request bodies,
+	// fasthttp.DefaultMaxRequestBodySize by default.
+	MaxRequestBodySize int
+	// Concurrency limits the connections served at once.
+	Concurrency int
+	// MaxConnsPerIP limits the connections from a single client IP.
+	MaxConnsPerIP int
+	// DisableKeepalive closes connections after every response.
+	DisableKeepalive bool
+	// CertFile and KeyFile enable TLS with the given
+	// PEM-encoded certificate and key.
+	CertFile string
+	KeyFile  string
+	// Logger receives the errors of the server.
+	Logger fasthttp.Logger
+}
+
+// Server chains the middleware with h and returns
+// a fasthttp.Server serving it, configured by cfg:
+//
+//	s := fastalice.New(m1, m2).Server(app, fastalice.ServerConfig{Name: "api"})
+//	log.Fatal(s.ListenAndServe(":8080"))
+//
+// When cfg sets a certificate, it is loaded at once,
+// and the server is started with ListenAndServeTLS
+// passing empty file names:
+//
+//	log.Fatal(s.ListenAndServeTLS(":8443", "", ""))
+//
+// Server panics when the certificate cannot be loaded.
+func (c Chain) Server(h fasthttp.RequestHandler, cfg ServerConfig) *fasthttp.Server {
+	if cfg.ReadTimeout <= 0 {
+		cfg.ReadTimeout = DefaultReadTimeout
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = DefaultWriteTimeout
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = DefaultIdleTimeout
+	}
+
+	s := &fasthttp.Server{
+		Handler:            c.Then(h),
+		Name:               cfg.Name,
+		ReadTimeout:        cfg.ReadTimeout,
+		WriteTimeout:       cfg.WriteTimeout,
+		IdleTimeout:        cfg.IdleTimeout,
+		MaxRequestBodySize: cfg.MaxRequestBodySize,
+		Concurrency:        cfg.Concurrency,
+		MaxConnsPerIP:      cfg.MaxConnsPerIP,
+		DisableKeepalive:   cfg.DisableKeepalive,
+		Logger:             cfg.Logger,
+	}
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		if err := s.AppendCert(cfg.CertFile, cfg.KeyFile); err != nil {
+			panic("fastalice: cannot load certificate: " + err.Error())
+		}
+	}
+	return s
+}
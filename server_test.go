@@ -0,0 +1,33 @@
+package fastalice
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+func TestChainServer(t *testing.T) {
+	s := New(tagMiddleware("t1\n")).Server(testApp, ServerConfig{Name: "api"})
+	assert.Equal(t, DefaultReadTimeout, s.ReadTimeout, "The read timeout should default")
+	assert.Equal(t, DefaultWriteTimeout, s.WriteTimeout, "The write timeout should default")
+	assert.Equal(t, DefaultIdleTimeout, s.IdleTimeout, "The idle timeout should default")
+
+	ln := fasthttputil.NewInmemoryListener()
+	defer ln.Close()
+	go s.Serve(ln)
+
+	client := &fasthttp.Client{Dial: func(addr string) (net.Conn, error) { return ln.Dial() }}
+	status, body, err := client.Get(nil, "http://localhost/")
+	assert.NoError(t, err, "The request should be served")
+	assert.Equal(t, fasthttp.StatusOK, status, "The server should answer")
+	assert.Equal(t, "t1\napp", string(body), "The server should run the chain")
+}
+
+func TestChainServerBadCertificate(t *testing.T) {
+	assert.Panics(t, func() {
+		New().Server(testApp, ServerConfig{CertFile: "missing.pem", KeyFile: "missing.key"})
+	}, "Unloadable certificates should panic")
+}
@@ -0,0 +1,225 @@
+package fastalice
+
+import (
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultSessionCookieName is the cookie used by Sessions
+// unless SessionCookieName is given.
+const DefaultSessionCookieName = "session"
+
+// DefaultSessionMaxAge is the session lifetime used by Sessions
+// unless SessionMaxAge is given.
+const DefaultSessionMaxAge = 24 * time.Hour
+
+// SessionStore loads and saves session values.
+// The token is the value of the session cookie:
+// an opaque ID for server-side stores,
+// or the encoded values themselves for cookie-backed ones.
+//
+// Implementations backed by Redis, memcached or a database
+// only need to map tokens to values.
+// They must be safe for concurrent use.
+type SessionStore interface {
+	// Load returns the values of the session identified by token.
+	// It returns nil values and no error
+	// when the session is unknown, invalid or expired.
+	Load(token string) (map[string]interface{}, error)
+	// Save stores values for maxAge and returns the token
+	// to send back to the client. token is empty for new sessions.
+	Save(token string, values map[string]interface{}, maxAge time.Duration) (string, error)
+	// Delete forgets the session identified by token.
+	Delete(token string) error
+}
+
+// Session holds the values of a client session.
+// It is not safe for concurrent use,
+// as it belongs to a single request.
+type Session struct {
+	token     string
+	values    map[string]interface{}
+	modified  bool
+	destroyed bool
+}
+
+// IsNew reports whether the session was created by this request.
+func (s *Session) IsNew() bool {
+	return s.token == ""
+}
+
+// Get returns the value stored under key.
+func (s *Session) Get(key string) (interface{}, bool) {
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// Set stores v under key.
+func (s *Session) Set(key string, v interface{}) {
+	if s.values == nil {
+		s.values = make(map[string]interface{})
+	}
+	s.values[key] = v
+	s.modified = true
+}
+
+// Delete removes the value stored under key.
+func (s *Session) Delete(key string) {
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.modified = true
+	}
+}
+
+// Destroy discards the session once the request is served,
+// deleting it from the store and expiring its cookie.
+// Values set afterwards are ignored.
+func (s *Session) Destroy() {
+	s.destroyed = true
+}
+
+// sessionKey holds the session of the request.
+var sessionKey = NewKey[*Session]("session")
+
+// GetSession returns the session of the request,
+// or nil outside of the Sessions middleware.
+func GetSession(ctx *fasthttp.RequestCtx) *Session {
+	s, _ := Get(ctx, sessionKey)
+	return s
+}
+
+// sessionConfig holds the settings of Sessions.
+type sessionConfig struct {
+	cookieName string
+	path       string
+	domain     string
+	maxAge     time.Duration
+	secure     bool
+	sameSite   fasthttp.CookieSameSite
+	onError    func(ctx *fasthttp.RequestCtx, err error)
+}
+
+// SessionOption configures Sessions.
+type SessionOption func(*sessionConfig)
+
+// SessionCookieName sets the session cookie name,
+// which defaults to DefaultSessionCookieName.
+func SessionCookieName(name string) SessionOption {
+	return func(c *sessionConfig) { c.cookieName = name }
+}
+
+// SessionCookiePath sets the path of the session cookie,
+// which defaults to "/".
+func SessionCookiePath(path string) SessionOption {
+	return func(c *sessionConfig) { c.path = path }
+}
+
+// SessionCookieDomain sets the domain of the session cookie.
+func SessionCookieDomain(domain string) SessionOption {
+	return func(c *sessionConfig) { c.domain = domain }
+}
+
+// SessionMaxAge sets how long sessions last after their last change,
+// which defaults to DefaultSessionMaxAge.
+func SessionMaxAge(d time.Duration) SessionOption {
+	return func(c *sessionConfig) { c.maxAge = d }
+}
+
+// SessionSecure restricts the session cookie to HTTPS.
+func SessionSecure(secure bool) SessionOption {
+	return func(c *sessionConfig) { c.secure = secure }
+}
+
+// SessionSameSite sets the SameSite attribute of the session cookie,
+// which defaults to fasthttp.CookieSameSiteLaxMode.
+func SessionSameSite(mode fasthttp.CookieSameSite) SessionOption {
+	return func(c *sessionConfig) { c.sameSite = mode }
+}
+
+// SessionErrorHandler sets the function answering requests
+// whose session cannot be loaded or saved,
+// which defaults to DefaultErrorHandler.
+func SessionErrorHandler(fn func(ctx *fasthttp.RequestCtx, err error)) SessionOption {
+	return func(c *sessionConfig) { c.onError = fn }
+}
+
+// Sessions returns a constructor that loads the session
+// of every request from store, using the session cookie,
+// and makes it available to the following handlers with GetSession.
+//
+// Once they have run, a modified session is saved
+// and its cookie set, while a destroyed one is deleted
+// and its cookie expired. Unmodified sessions are left untouched,
+// so requests that do not use the session set no cookie.
+func Sessions(store SessionStore, opts ...SessionOption) Constructor {
+	cfg := sessionConfig{
+		cookieName: DefaultSessionCookieName,
+		path:       "/",
+		maxAge:     DefaultSessionMaxAge,
+		sameSite:   fasthttp.CookieSameSiteLaxMode,
+		onError:    DefaultErrorHandler,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			s := &Session{}
+			if token := ctx.Request.Header.Cookie(cfg.cookieName); len(token) > 0 {
+				values, err := store.Load(string(token))
+				if err != nil {
+					cfg.onError(ctx, err)
+					return
+				}
+				if values != nil {
+					s.token, s.values = string(token), values
+				}
+			}
+
+			Set(ctx, sessionKey, s)
+			next(ctx)
+
+			switch {
+			case s.destroyed:
+				if s.token == "" {
+					return
+				}
+				if err := store.Delete(s.token); err != nil {
+					cfg.onError(ctx, err)
+					return
+				}
+				cfg.setCookie(ctx, "", -1)
+			case s.modified:
+				token, err := store.Save(s.token, s.values, cfg.maxAge)
+				if err != nil {
+					cfg.onError(ctx, err)
+					return
+				}
+				cfg.setCookie(ctx, token, int(cfg.maxAge/time.Second))
+			}
+		}
+	}
+}
+
+// setCookie sets the session cookie;
+// a negative maxAge expires it.
+func (cfg *sessionConfig) setCookie(ctx *fasthttp.RequestCtx, token string, maxAge int) {
+	c := fasthttp.AcquireCookie()
+	defer fasthttp.ReleaseCookie(c)
+
+	c.SetKey(cfg.cookieName)
+	c.SetValue(token)
+	c.SetPath(cfg.path)
+	c.SetDomain(cfg.domain)
+	if maxAge < 0 {
+		c.SetExpire(fasthttp.CookieExpireDelete)
+	} else {
+		c.SetMaxAge(maxAge)
+	}
+	c.SetHTTPOnly(true)
+	c.SetSecure(cfg.secure)
+	c.SetSameSite(cfg.sameSite)
+	ctx.Response.Header.SetCookie(c)
+}
@@ -0,0 +1,81 @@
+package fastalice
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestSessionsRoundTrip(t *testing.T) {
+	h := New(Sessions(NewMemorySessionStore())).Then(func(ctx *fasthttp.RequestCtx) {
+		s := GetSession(ctx)
+		n, _ := s.Get("visits")
+		count, _ := n.(int)
+		s.Set("visits", count+1)
+		ctx.SetBody(fasthttp.AppendUint(nil, count+1))
+	})
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	token := responseCookie(ctx, DefaultSessionCookieName)
+	assert.NotEmpty(t, token, "A modified session should set the cookie")
+	assert.Equal(t, "1", string(ctx.Response.Body()), "A new session should be empty")
+
+	ctx = newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.SetCookie(DefaultSessionCookieName, token)
+	h(ctx)
+	assert.Equal(t, "2", string(ctx.Response.Body()), "The session should be loaded from the store")
+}
+
+func TestSessionsUntouched(t *testing.T) {
+	var isNew bool
+	h := New(Sessions(NewMemorySessionStore())).Then(func(ctx *fasthttp.RequestCtx) {
+		isNew = GetSession(ctx).IsNew()
+	})
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.SetCookie(DefaultSessionCookieName, "unknown")
+	h(ctx)
+	assert.True(t, isNew, "Unknown tokens should start a new session")
+	assert.Empty(t, ctx.Response.Header.Peek(fasthttp.HeaderSetCookie), "Unmodified sessions should set no cookie")
+}
+
+func TestSessionsDestroy(t *testing.T) {
+	store := NewMemorySessionStore()
+	token, _ := store.Save("", map[string]interface{}{"user": "alice"}, DefaultSessionMaxAge)
+
+	h := New(Sessions(store)).Then(func(ctx *fasthttp.RequestCtx) {
+		GetSession(ctx).Destroy()
+	})
+
+	ctx := newTestCtx("POST", "http://localhost/logout")
+	ctx.Request.Header.SetCookie(DefaultSessionCookieName, token)
+	h(ctx)
+	values, _ := store.Load(token)
+	assert.Nil(t, values, "Destroyed sessions should be deleted")
+	assert.Contains(t, string(ctx.Response.Header.Peek(fasthttp.HeaderSetCookie)), "expires=", "The cookie should be expired")
+}
+
+type failingSessionStore struct{ SessionStore }
+
+func (failingSessionStore) Save(string, map[string]interface{}, time.Duration) (string, error) {
+	return "", errors.New("store down")
+}
+
+func TestSessionsSaveError(t *testing.T) {
+	h := New(Sessions(failingSessionStore{NewMemorySessionStore()})).Then(func(ctx *fasthttp.RequestCtx) {
+		GetSession(ctx).Set("k", "v")
+		testApp(ctx)
+	})
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusInternalServerError, ctx.Response.StatusCode(), "Save errors should be reported")
+}
+
+func TestGetSessionOutsideMiddleware(t *testing.T) {
+	assert.Nil(t, GetSession(newTestCtx("GET", "http://localhost/")), "No session should exist outside the middleware")
+}
@@ -0,0 +1,184 @@
+package fastalice
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memorySessionStore is an in-memory SessionStore.
+type memorySessionStore struct {
+	mu        sync.Mutex
+	sessions  map[string]memorySession
+	nextSweep time.Time
+}
+
+type memorySession struct {
+	values  map[string]interface{}
+	expires time.Time
+}
+
+// NewMemorySessionStore returns a SessionStore
+// keeping sessions in memory, identified by random tokens.
+// Sessions are lost on restart and not shared between processes.
+func NewMemorySessionStore() SessionStore {
+	return &memorySessionStore{sessions: make(map[string]memorySession)}
+}
+
+func (s *memorySessionStore) Load(token string) (map[string]interface{}, error) {
+	t := now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[token]
+	if !ok {
+		return nil, nil
+	}
+	if !t.Before(sess.expires) {
+		delete(s.sessions, token)
+		return nil, nil
+	}
+	return copyValues(sess.values), nil
+}
+
+func (s *memorySessionStore) Save(token string, values map[string]interface{}, maxAge time.Duration) (string, error) {
+	t := now()
+	if token == "" {
+		var b [32]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return "", err
+		}
+		token = hex.EncodeToString(b[:])
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !t.Before(s.nextSweep) {
+		for tok, sess := range s.sessions {
+			if !t.Before(sess.expires) {
+				delete(s.sessions, tok)
+			}
+		}
+		s.nextSweep = t.Add(maxAge)
+	}
+	s.sessions[token] = memorySession{copyValues(values), t.Add(maxAge)}
+	return token, nil
+}
+
+func (s *memorySessionStore) Delete(token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, token)
+	return nil
+}
+
+func copyValues(values map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		c[k] = v
+	}
+	return c
+}
+
+// maxCookieSize is the size browsers are guaranteed to accept for a cookie.
+const maxCookieSize = 4096
+
+// errCookieTooLarge is returned when session values
+// do not fit in a cookie.
+var errCookieTooLarge = errors.New("fastalice: session does not fit in a cookie")
+
+// cookieSessionStore is a SessionStore keeping values
+// in the cookie itself.
+type cookieSessionStore struct {
+	secret []byte
+}
+
+// cookieSession is the signed payload of a cookie session.
+type cookieSession struct {
+	Expires int64                  `json:"e"`
+	Values  map[string]interface{} `json:"v"`
+}
+
+// NewCookieSessionStore returns a SessionStore
+// keeping the values in the session cookie itself,
+// encoded in JSON and signed with HMAC-SHA256 using secret,
+// so no server-side state is needed.
+//
+// Values are readable by the client, though not modifiable,
+// and come back from Load as decoded by encoding/json,
+// with numbers as json.Number.
+// Saving fails when the cookie would exceed 4096 bytes.
+// Delete cannot revoke copies of the cookie kept by the client
+// before they expire.
+func NewCookieSessionStore(secret []byte) SessionStore {
+	if len(secret) == 0 {
+		panic("fastalice: cookie session store requires a secret")
+	}
+	return &cookieSessionStore{secret: secret}
+}
+
+func (s *cookieSessionStore) Load(token string) (map[string]interface{}, error) {
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return nil, nil
+	}
+	payload, sig := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(s.sign(payload))) {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, nil
+	}
+
+	var sess cookieSession
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&sess); err != nil {
+		return nil, nil
+	}
+	if now().Unix() >= sess.Expires {
+		return nil, nil
+	}
+	if sess.Values == nil {
+		sess.Values = make(map[string]interface{})
+	}
+	return sess.Values, nil
+}
+
+func (s *cookieSessionStore) Save(token string, values map[string]interface{}, maxAge time.Duration) (string, error) {
+	raw, err := json.Marshal(cookieSession{
+		Expires: now().Add(maxAge).Unix(),
+		Values:  values,
+	})
+	if err != nil {
+		return "", fmt.Errorf("fastalice: cannot encode session: %w", err)
+	}
+	payload := base64.RawURLEncoding.EncodeToString(raw)
+	token = payload + "." + s.sign(payload)
+	if len(token) > maxCookieSize {
+		return "", errCookieTooLarge
+	}
+	return token, nil
+}
+
+func (s *cookieSessionStore) Delete(token string) error {
+	return nil
+}
+
+func (s *cookieSessionStore) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
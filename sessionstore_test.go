@@ -0,0 +1,73 @@
+package fastalice
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemorySessionStoreExpiry(t *testing.T) {
+	defer fakeClock(time.Minute)()
+	store := NewMemorySessionStore()
+
+	token, err := store.Save("", map[string]interface{}{"k": "v"}, 90*time.Second)
+	assert.NoError(t, err)
+	values, _ := store.Load(token)
+	assert.Equal(t, "v", values["k"], "Sessions should be loaded before they expire")
+	values, _ = store.Load(token)
+	assert.Nil(t, values, "Sessions should expire after maxAge")
+}
+
+func TestMemorySessionStoreSweep(t *testing.T) {
+	clock := time.Unix(0, 0)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+	store := NewMemorySessionStore().(*memorySessionStore)
+
+	_, _ = store.Save("a", nil, time.Minute)
+	clock = clock.Add(30 * time.Second)
+	_, _ = store.Save("b", nil, 10*time.Second)
+	clock = clock.Add(20 * time.Second)
+	_, _ = store.Save("c", nil, time.Minute)
+	assert.Len(t, store.sessions, 3, "Expired sessions should not be swept before the next sweep is due")
+
+	clock = clock.Add(10 * time.Second)
+	_, _ = store.Save("d", nil, time.Minute)
+	assert.Len(t, store.sessions, 2, "Expired sessions should be swept once the next sweep is due")
+}
+
+func TestCookieSessionStore(t *testing.T) {
+	store := NewCookieSessionStore([]byte("secret"))
+
+	token, err := store.Save("", map[string]interface{}{"user": "alice", "n": 3}, time.Hour)
+	assert.NoError(t, err)
+	values, err := store.Load(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", values["user"], "Values should round-trip through the cookie")
+	assert.Equal(t, json.Number("3"), values["n"], "Numbers should be decoded as json.Number")
+
+	tampered := "x" + token[1:]
+	values, _ = store.Load(tampered)
+	assert.Nil(t, values, "Tampered cookies should be rejected")
+
+	other := NewCookieSessionStore([]byte("other"))
+	values, _ = other.Load(token)
+	assert.Nil(t, values, "Cookies signed with another secret should be rejected")
+
+	_, err = store.Save("", map[string]interface{}{"big": strings.Repeat("x", maxCookieSize)}, time.Hour)
+	assert.Error(t, err, "Oversized sessions should not be saved")
+}
+
+func TestCookieSessionStoreExpiry(t *testing.T) {
+	defer fakeClock(time.Hour)()
+	store := NewCookieSessionStore([]byte("secret"))
+
+	token, _ := store.Save("", map[string]interface{}{"k": "v"}, 90*time.Minute)
+	values, _ := store.Load(token)
+	assert.NotNil(t, values, "Sessions should be loaded before they expire")
+	values, _ = store.Load(token)
+	assert.Nil(t, values, "Sessions should expire after maxAge")
+}
@@ -0,0 +1,537 @@
+package fastalice
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// SignatureMaxAge is how far from now the created time
+// of a signature accepted by VerifyRequests or VerifyResponse may be.
+var SignatureMaxAge = 5 * time.Minute
+
+// KeySet maps key IDs to the keys verifying HTTP message signatures:
+// ed25519.PublicKey, *ecdsa.PublicKey on P-256 or P-384,
+// *rsa.PublicKey, or a []byte secret for hmac-sha256.
+type KeySet map[string]crypto.PublicKey
+
+// signatureKeyIDKey holds the ID of the key
+// that verified the request signature.
+var signatureKeyIDKey = NewKey[string]("signatureKeyID")
+
+// SignatureKeyID returns the ID of the key that verified
+// the signature of the request in VerifyRequests,
+// or an empty string if there is none.
+func SignatureKeyID(ctx *fasthttp.RequestCtx) string {
+	id, _ := Get(ctx, signatureKeyIDKey)
+	return id
+}
+
+// signatureLabel labels the signatures added by fastalice.
+const signatureLabel = "sig1"
+
+// SignResponses returns a constructor signing responses
+// with HTTP Message Signatures (RFC 9421) once the following handlers have run,
+// so that clients can check that they come from this service.
+// The signature covers the status, the Content-Type
+// and a Content-Digest (RFC 9530) of the body, which it adds.
+// It is set in the given response header, "Signature" when empty,
+// and its parameters in that header suffixed with "-Input",
+// such as Signature-Input. It names no key:
+// clients verify it with VerifyResponse and the public keys they trust.
+//
+// Streamed responses, see IsStreaming, are not signed,
+// as their body cannot be digested before it is sent.
+// Responses that cannot be signed are replaced with 500 Internal Server Error.
+// SignResponses panics if key is neither an Ed25519, ECDSA P-256 or P-384
+// nor RSA key.
+func SignResponses(key crypto.Signer, header string) Constructor {
+	if _, err := signatureAlgorithm(key.Public()); err != nil {
+		panic(err)
+	}
+	if header == "" {
+		header = "Signature"
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			next(ctx)
+			if IsStreaming(ctx) {
+				return
+			}
+			if err := signMessage(responseMessage(&ctx.Response), []string{"@status"}, header, "", key); err != nil {
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusInternalServerError), fasthttp.StatusInternalServerError)
+			}
+		}
+	}
+}
+
+// VerifyRequests returns a constructor that authenticates requests
+// with HTTP Message Signatures (RFC 9421), as added by SignRequest,
+// verified with the key of keys named by their keyid parameter.
+// The signature must cover the method and path of the request,
+// and its Content-Digest (RFC 9530) when it has a body;
+// its created time must be within SignatureMaxAge of now.
+// Other requests are answered with 401 Unauthorized,
+// without calling the following handlers.
+//
+// The ID of the verifying key can be read with SignatureKeyID.
+// A valid request can be replayed within SignatureMaxAge;
+// RejectReplays prevents it:
+//
+//	fastalice.VerifyRequests(keys, fastalice.RejectReplays(fastalice.NewMemoryNonceStore(fastalice.SignatureMaxAge)))
+func VerifyRequests(keys KeySet, opts ...VerifyOption) Constructor {
+	cfg := newVerifyConfig(opts)
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			keyID, sig, err := verifyMessage(requestMessage(&ctx.Request), "Signature", keys, requestCovered)
+			if err != nil {
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusUnauthorized), fasthttp.StatusUnauthorized)
+				return
+			}
+			if cfg.replayed(ctx, sig) {
+				return
+			}
+			Set(ctx, signatureKeyIDKey, keyID)
+			next(ctx)
+		}
+	}
+}
+
+// SignRequest signs req with HTTP Message Signatures (RFC 9421)
+// as expected by VerifyRequests, covering its method, authority, path
+// and query, its Content-Type and a Content-Digest (RFC 9530) of its body,
+// which it adds. The signature is labeled with keyID.
+func SignRequest(req *fasthttp.Request, keyID string, key crypto.Signer) error {
+	return signMessage(requestMessage(req), []string{"@method", "@authority", "@path", "@query"}, "Signature", keyID, key)
+}
+
+// VerifyResponse verifies the signature added to resp by SignResponses
+// in the given header, "Signature" when empty,
+// with the keys of keys, returning the ID of the verifying key.
+func VerifyResponse(resp *fasthttp.Response, keys KeySet, header string) (string, error) {
+	if header == "" {
+		header = "Signature"
+	}
+	keyID, _, err := verifyMessage(responseMessage(resp), header, keys, func(covered []string) bool {
+		return containsString(covered, "@status")
+	})
+	return keyID, err
+}
+
+// requestCovered reports whether the components covered by
+// a request signature identify the request.
+func requestCovered(covered []string) bool {
+	return containsString(covered, "@method") &&
+		(containsString(covered, "@path") || containsString(covered, "@target-uri") ||
+			containsString(covered, "@request-target"))
+}
+
+// signedMessage gives access to the parts of a request or response
+// that signatures cover.
+type signedMessage struct {
+	header    func(name string) []byte
+	setHeader func(name, value string)
+	// component returns the value of a derived component, such as @method.
+	component func(name string) (string, bool)
+	body      func() []byte
+	visit     func(f func(k, v []byte))
+}
+
+func requestMessage(req *fasthttp.Request) signedMessage {
+	return signedMessage{
+		header:    req.Header.Peek,
+		setHeader: req.Header.Set,
+		component: func(name string) (string, bool) {
+			uri := req.URI()
+			switch name {
+			case "@method":
+				return string(req.Header.Method()), true
+			case "@authority":
+				return strings.ToLower(string(req.Host())), true
+			case "@scheme":
+				return strings.ToLower(string(uri.Scheme())), true
+			case "@target-uri":
+				return string(uri.FullURI()), true
+			case "@request-target":
+				return string(req.RequestURI()), true
+			case "@path":
+				return string(uri.PathOriginal()), true
+			case "@query":
+				return "?" + string(uri.QueryString()), true
+			}
+			return "", false
+		},
+		body:  req.Body,
+		visit: req.Header.VisitAll,
+	}
+}
+
+func responseMessage(resp *fasthttp.Response) signedMessage {
+	return signedMessage{
+		header:    resp.Header.Peek,
+		setHeader: resp.Header.Set,
+		component: func(name string) (string, bool) {
+			if name == "@status" {
+				return strconv.Itoa(resp.StatusCode()), true
+			}
+			return "", false
+		},
+		body:  resp.Body,
+		visit: resp.Header.VisitAll,
+	}
+}
+
+// value returns the value of the component name:
+// a derived component or a header field,
+// whose values are joined as in RFC 9421.
+func (m signedMessage) value(name string) (string, bool) {
+	if strings.HasPrefix(name, "@") {
+		return m.component(name)
+	}
+	var values []string
+	m.visit(func(k, v []byte) {
+		if bytes.EqualFold(k, []byte(name)) {
+			values = append(values, strings.TrimSpace(string(v)))
+		}
+	})
+	return strings.Join(values, ", "), len(values) > 0
+}
+
+// signMessage adds the signature of m covering components,
+// its Content-Type and the Content-Digest of its body, if any,
+// to header and its parameters to header-Input.
+// An empty keyID leaves the keyid parameter out.
+func signMessage(m signedMessage, components []string, header, keyID string, key crypto.Signer) error {
+	alg, err := signatureAlgorithm(key.Public())
+	if err != nil {
+		return err
+	}
+	if len(m.header(fasthttp.HeaderContentType)) > 0 {
+		components = append(components, "content-type")
+	}
+	if body := m.body(); len(body) > 0 {
+		m.setHeader("Content-Digest", contentDigest(body))
+		components = append(components, "content-digest")
+	}
+
+	params := signatureParams(components, now().Unix(), keyID, alg)
+	base, err := signatureBase(m, components, params)
+	if err != nil {
+		return err
+	}
+	sig, err := signBase(key, alg, base)
+	if err != nil {
+		return err
+	}
+	m.setHeader(header+"-Input", signatureLabel+"="+params)
+	m.setHeader(header, signatureLabel+"=:"+base64.StdEncoding.EncodeToString(sig)+":")
+	return nil
+}
+
+// verifyMessage verifies the first signature of m in header
+// made with a key of keys, returning the ID of that key
+// and the verified signature.
+// Signatures without a keyid parameter are tried with every key,
+// and skipped when none verifies them.
+// covered reports whether its covered components are enough.
+func verifyMessage(m signedMessage, header string, keys KeySet, covered func([]string) bool) (string, string, error) {
+	signatures := map[string]string{}
+	for _, member := range splitSFDictionary(string(m.header(header))) {
+		signatures[member[0]] = member[1]
+	}
+
+	for _, member := range splitSFDictionary(string(m.header(header + "-Input"))) {
+		components, params, err := parseSignatureInput(member[1])
+		if err != nil {
+			continue
+		}
+		keyID, ok := params["keyid"]
+		if !ok {
+			ids := make([]string, 0, len(keys))
+			for id := range keys {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			for _, id := range ids {
+				if verifySignature(m, keys[id], components, params, member[1], signatures[member[0]], covered) == nil {
+					return id, signatures[member[0]], nil
+				}
+			}
+			continue
+		}
+		key, ok := keys[keyID]
+		if !ok {
+			continue
+		}
+		if err := verifySignature(m, key, components, params, member[1], signatures[member[0]], covered); err != nil {
+			return "", "", err
+		}
+		return keyID, signatures[member[0]], nil
+	}
+	return "", "", errors.New("fastalice: no signature made with a known key")
+}
+
+// verifySignature verifies the signature of m described by input.
+func verifySignature(m signedMessage, key crypto.PublicKey, components []string, params map[string]string, input, signature string, covered func([]string) bool) error {
+	if !covered(components) {
+		return errors.New("fastalice: signature does not cover the message")
+	}
+	if body := m.body(); len(body) > 0 {
+		if !containsString(components, "content-digest") || !verifyContentDigest(string(m.header("Content-Digest")), body) {
+			return errors.New("fastalice: signature does not cover the body")
+		}
+	}
+
+	t := now()
+	created, err := strconv.ParseInt(params["created"], 10, 64)
+	if err != nil {
+		return errors.New("fastalice: signature without created time")
+	}
+	if d := t.Sub(time.Unix(created, 0)); d > SignatureMaxAge || d < -SignatureMaxAge {
+		return errors.New("fastalice: signature too old")
+	}
+	if v, ok := params["expires"]; ok {
+		expires, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || !t.Before(time.Unix(expires, 0)) {
+			return errors.New("fastalice: signature expired")
+		}
+	}
+
+	if len(signature) < 2 || signature[0] != ':' || signature[len(signature)-1] != ':' {
+		return errors.New("fastalice: malformed signature")
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature[1 : len(signature)-1])
+	if err != nil {
+		return errors.New("fastalice: malformed signature")
+	}
+	alg := params["alg"]
+	if alg == "" {
+		if alg, err = signatureAlgorithm(key); err != nil {
+			return err
+		}
+	}
+	base, err := signatureBase(m, components, input)
+	if err != nil {
+		return err
+	}
+	return verifyBase(alg, key, base, sig)
+}
+
+// signatureAlgorithm returns the algorithm signing with the private key of pub,
+// or verifying with the secret pub.
+func signatureAlgorithm(pub crypto.PublicKey) (string, error) {
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		return "ed25519", nil
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return "ecdsa-p256-sha256", nil
+		case elliptic.P384():
+			return "ecdsa-p384-sha384", nil
+		}
+	case *rsa.PublicKey:
+		return "rsa-pss-sha512", nil
+	case []byte:
+		return "hmac-sha256", nil
+	}
+	return "", fmt.Errorf("fastalice: unsupported signature key %T", pub)
+}
+
+// signBase signs the signature base with key using alg.
+func signBase(key crypto.Signer, alg, base string) ([]byte, error) {
+	switch alg {
+	case "ed25519":
+		return key.Sign(rand.Reader, []byte(base), crypto.Hash(0))
+	case "rsa-pss-sha512":
+		digest := sha512.Sum512([]byte(base))
+		return key.Sign(rand.Reader, digest[:], &rsa.PSSOptions{SaltLength: 64, Hash: crypto.SHA512})
+	}
+
+	hash, size := crypto.SHA256, 32
+	if alg == "ecdsa-p384-sha384" {
+		hash, size = crypto.SHA384, 48
+	}
+	h := hash.New()
+	h.Write([]byte(base))
+	der, err := key.Sign(rand.Reader, h.Sum(nil), hash)
+	if err != nil {
+		return nil, err
+	}
+	// RFC 9421 encodes ECDSA signatures as r and s, not in ASN.1.
+	var rs struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(der, &rs); err != nil {
+		return nil, err
+	}
+	sig := make([]byte, 2*size)
+	rs.R.FillBytes(sig[:size])
+	rs.S.FillBytes(sig[size:])
+	return sig, nil
+}
+
+// verifyBase checks the signature sig of the signature base with key using alg.
+func verifyBase(alg string, key crypto.PublicKey, base string, sig []byte) error {
+	switch alg {
+	case "ed25519":
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(pub, []byte(base), sig) {
+			return errors.New("fastalice: invalid signature")
+		}
+		return nil
+	case "rsa-pss-sha512":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("fastalice: RSA algorithms need an *rsa.PublicKey")
+		}
+		digest := sha512.Sum512([]byte(base))
+		return rsa.VerifyPSS(pub, crypto.SHA512, digest[:], sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto})
+	case "rsa-v1_5-sha256":
+		return verifyJWT("RS256", crypto.SHA256, key, base, sig)
+	case "hmac-sha256":
+		return verifyJWT("HS256", crypto.SHA256, key, base, sig)
+	case "ecdsa-p256-sha256":
+		return verifyJWT("ES256", crypto.SHA256, key, base, sig)
+	case "ecdsa-p384-sha384":
+		return verifyJWT("ES384", crypto.SHA384, key, base, sig)
+	}
+	return fmt.Errorf("fastalice: unsupported signature algorithm %q", alg)
+}
+
+// signatureParams serializes the signature parameters,
+// the value of the @signature-params component.
+func signatureParams(components []string, created int64, keyID, alg string) string {
+	quoted := make([]string, len(components))
+	for i, c := range components {
+		quoted[i] = strconv.Quote(c)
+	}
+	params := "(" + strings.Join(quoted, " ") + ");created=" + strconv.FormatInt(created, 10)
+	if keyID != "" {
+		params += ";keyid=" + strconv.Quote(keyID)
+	}
+	return params + ";alg=" + strconv.Quote(alg)
+}
+
+// signatureBase returns the signature base of m over components,
+// whose signature parameters serialize as params.
+func signatureBase(m signedMessage, components []string, params string) (string, error) {
+	var b strings.Builder
+	for _, c := range components {
+		v, ok := m.value(c)
+		if !ok {
+			return "", fmt.Errorf("fastalice: missing signature component %q", c)
+		}
+		b.WriteString(`"` + c + `": ` + v + "\n")
+	}
+	b.WriteString(`"@signature-params": ` + params)
+	return b.String(), nil
+}
+
+// parseSignatureInput parses a member of Signature-Input,
+// returning its covered components and parameters.
+// Components with parameters are not supported.
+func parseSignatureInput(v string) ([]string, map[string]string, error) {
+	end := strings.IndexByte(v, ')')
+	if !strings.HasPrefix(v, "(") || end < 0 {
+		return nil, nil, errors.New("fastalice: malformed signature input")
+	}
+	var components []string
+	for _, item := range strings.Fields(v[1:end]) {
+		c, err := strconv.Unquote(item)
+		if err != nil || c == "" || strings.ContainsAny(c, `";`) {
+			return nil, nil, errors.New("fastalice: malformed signature input")
+		}
+		components = append(components, c)
+	}
+
+	params := map[string]string{}
+	for _, p := range strings.Split(v[end+1:], ";")[1:] {
+		k, val, _ := strings.Cut(strings.TrimSpace(p), "=")
+		if s, err := strconv.Unquote(val); err == nil {
+			val = s
+		}
+		params[k] = val
+	}
+	return components, params, nil
+}
+
+// splitSFDictionary splits a structured field dictionary (RFC 8941)
+// into its keys and raw values.
+func splitSFDictionary(s string) [][2]string {
+	var members [][2]string
+	add := func(member string) {
+		if k, v, ok := strings.Cut(strings.TrimSpace(member), "="); ok {
+			members = append(members, [2]string{k, v})
+		}
+	}
+
+	start, depth, quoted := 0, 0, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quoted && c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			add(s[start:i])
+			start = i + 1
+		}
+	}
+	add(s[start:])
+	return members
+}
+
+// contentDigest returns the Content-Digest header (RFC 9530) of body.
+func contentDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"
+}
+
+// verifyContentDigest reports whether the Content-Digest header
+// has a SHA-256 or SHA-512 digest matching body, and no mismatching one.
+func verifyContentDigest(header string, body []byte) bool {
+	verified := false
+	for _, member := range splitSFDictionary(header) {
+		var sum []byte
+		switch member[0] {
+		case "sha-256":
+			s := sha256.Sum256(body)
+			sum = s[:]
+		case "sha-512":
+			s := sha512.Sum512(body)
+			sum = s[:]
+		default:
+			continue
+		}
+		want := base64.StdEncoding.EncodeToString(sum)
+		if subtle.ConstantTimeCompare([]byte(member[1]), []byte(":"+want+":")) != 1 {
+			return false
+		}
+		verified = true
+	}
+	return verified
+}
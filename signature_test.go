@@ -0,0 +1,185 @@
+package fastalice
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// signedCtx returns a request context for a request signed by SignRequest.
+func signedCtx(t *testing.T, method, uri, body string, keyID string, key crypto.Signer) *fasthttp.RequestCtx {
+	var req fasthttp.Request
+	req.Header.SetMethod(method)
+	req.SetRequestURI(uri)
+	if body != "" {
+		req.Header.SetContentType("application/json")
+		req.SetBodyString(body)
+	}
+	assert.NoError(t, SignRequest(&req, keyID, key), "The request should be signed")
+
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Init(&req, nil, nil)
+	return ctx
+}
+
+func TestVerifyRequestsAlgorithms(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keys := KeySet{
+		"ed":   edKey.Public(),
+		"p256": p256.Public(),
+		"p384": p384.Public(),
+		"rsa":  rsaKey.Public(),
+	}
+	signers := map[string]crypto.Signer{"ed": edKey, "p256": p256, "p384": p384, "rsa": rsaKey}
+
+	for keyID, key := range signers {
+		var seen string
+		h := New(VerifyRequests(keys)).Then(func(ctx *fasthttp.RequestCtx) {
+			seen = SignatureKeyID(ctx)
+		})
+		ctx := signedCtx(t, "POST", "http://api.local/orders?id=1", `{"qty":1}`, keyID, key)
+		h(ctx)
+		assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "A request signed with %s should be accepted", keyID)
+		assert.Equal(t, keyID, seen, "The verifying key ID should be recorded")
+	}
+}
+
+func TestVerifyRequestsRejects(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	_, other, _ := ed25519.GenerateKey(rand.Reader)
+	keys := KeySet{"k1": key.Public()}
+	h := New(VerifyRequests(keys)).Then(testApp)
+
+	ctx := newTestCtx("GET", "http://api.local/orders")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "Unsigned requests should be rejected")
+
+	ctx = signedCtx(t, "GET", "http://api.local/orders", "", "k1", other)
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "Requests signed with another key should be rejected")
+
+	ctx = signedCtx(t, "GET", "http://api.local/orders", "", "k2", key)
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "Requests signed with an unknown key ID should be rejected")
+
+	ctx = signedCtx(t, "GET", "http://api.local/orders", "", "k1", key)
+	ctx.Request.SetRequestURI("http://api.local/admin")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "Requests whose path changed should be rejected")
+
+	ctx = signedCtx(t, "POST", "http://api.local/orders", `{"qty":1}`, "k1", key)
+	ctx.Request.SetBodyString(`{"qty":100}`)
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "Requests whose body changed should be rejected")
+
+	ctx = signedCtx(t, "GET", "http://api.local/orders", "", "k1", key)
+	ctx.Request.SetBodyString(`{"qty":100}`)
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "Requests with a body not covered by the signature should be rejected")
+
+	ctx = signedCtx(t, "GET", "http://api.local/orders", "", "k1", key)
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Untouched requests should be accepted")
+}
+
+func TestVerifyRequestsMaxAge(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	h := New(VerifyRequests(KeySet{"k1": key.Public()})).Then(testApp)
+
+	ctx := signedCtx(t, "GET", "http://api.local/orders", "", "k1", key)
+	defer fakeClock(SignatureMaxAge + time.Minute)()
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "Old signatures should be rejected")
+}
+
+func TestVerifyRequestsHMAC(t *testing.T) {
+	defer fakeClock(0)()
+	secret := []byte("shared secret")
+	base := strings.Join([]string{
+		`"@method": GET`,
+		`"@path": /orders`,
+		`"@signature-params": ("@method" "@path");created=1577836800;keyid="hook"`,
+	}, "\n")
+	sig := signHMACForTest(secret, base)
+
+	ctx := newTestCtx("GET", "http://api.local/orders")
+	ctx.Request.Header.Set("Signature-Input", `other=("@method");created=1, sig2=("@method" "@path");created=1577836800;keyid="hook"`)
+	ctx.Request.Header.Set("Signature", "other=:AAAA:, sig2=:"+sig+":")
+	New(VerifyRequests(KeySet{"hook": secret})).Then(testApp)(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "A valid HMAC signature among others should be accepted")
+}
+
+func TestSignResponses(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	keys := KeySet{"other": other.Public(), "svc": key.Public()}
+	h := New(SignResponses(key, "")).Then(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetContentType("application/json")
+		ctx.SetStatusCode(fasthttp.StatusCreated)
+		ctx.WriteString(`{"id":1}`)
+	})
+
+	ctx := newTestCtx("GET", "http://api.local/orders")
+	h(ctx)
+	assert.NotEmpty(t, ctx.Response.Header.Peek("Content-Digest"), "The response should carry a digest")
+	assert.NotEmpty(t, ctx.Response.Header.Peek("Signature-Input"), "The signature should default to the Signature header")
+	id, err := VerifyResponse(&ctx.Response, keys, "")
+	assert.NoError(t, err, "The response signature should verify")
+	assert.Equal(t, "svc", id, "The verifying key should be found among the keys")
+
+	ctx.Response.SetStatusCode(fasthttp.StatusOK)
+	_, err = VerifyResponse(&ctx.Response, keys, "")
+	assert.Error(t, err, "A changed status should not verify")
+}
+
+func TestSignResponsesHeader(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	ctx := newTestCtx("GET", "http://api.local/orders")
+	New(SignResponses(key, "X-Signature")).Then(testApp)(ctx)
+
+	assert.Empty(t, ctx.Response.Header.Peek("Signature"), "The default header should not be used")
+	assert.NotEmpty(t, ctx.Response.Header.Peek("X-Signature-Input"), "The parameters should follow the header")
+	_, err := VerifyResponse(&ctx.Response, KeySet{"svc": key.Public()}, "X-Signature")
+	assert.NoError(t, err, "The signature should verify from the given header")
+}
+
+func TestSignResponsesSkipsStreams(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	ctx := newTestCtx("GET", "http://api.local/events")
+	assert.True(t, returnsWithin(New(SignResponses(key, "")).Then(endlessSSE), ctx, time.Second), "Endless streams should not be read")
+	assert.Empty(t, ctx.Response.Header.Peek("Signature"), "Streamed responses should not be signed")
+}
+
+func TestSignResponsesUnsupportedKey(t *testing.T) {
+	assert.Panics(t, func() { SignResponses(unsupportedSigner{}, "") }, "Unsupported keys should panic")
+}
+
+type unsupportedSigner struct{}
+
+func (unsupportedSigner) Public() crypto.PublicKey { return "key" }
+
+func (unsupportedSigner) Sign(_ io.Reader, _ []byte, _ crypto.SignerOpts) ([]byte, error) {
+	return nil, nil
+}
+
+func signHMACForTest(secret []byte, base string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(base))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,44 @@
+package fastalice
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Skip returns a constructor running c only for requests
+// that do not match matcher; matching requests bypass it
+// and go on with the rest of the chain.
+//
+//	chain := fastalice.New(Skip(auth, isPublic), m1)
+func Skip(c Constructor, matcher func(ctx *fasthttp.RequestCtx) bool) Constructor {
+	return conditional(func(ctx *fasthttp.RequestCtx) bool {
+		return !matcher(ctx)
+	}, New(c))
+}
+
+// SkipPaths is like Skip, bypassing c for requests
+// whose path matches one of patterns, as matched by path.Match:
+//
+//	fastalice.SkipPaths(logger, "/health", "/metrics/*")
+//
+// Note that "*" does not match a slash.
+// SkipPaths panics if a pattern is malformed.
+func SkipPaths(c Constructor, patterns ...string) Constructor {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			panic(fmt.Sprintf("fastalice: invalid path pattern %q: %v", p, err))
+		}
+	}
+
+	return Skip(c, func(ctx *fasthttp.RequestCtx) bool {
+		p := string(ctx.Path())
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, p); ok {
+				return true
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,43 @@
+package fastalice
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestSkip(t *testing.T) {
+	isInternal := func(ctx *fasthttp.RequestCtx) bool {
+		return bytes.HasPrefix(ctx.Path(), []byte("/internal/"))
+	}
+	h := New(Skip(tagMiddleware("t1\n"), isInternal), tagMiddleware("t2\n")).Then(testApp)
+
+	ctx := newTestCtx("GET", "http://localhost/internal/x")
+	h(ctx)
+	assert.Equal(t, "t2\napp", string(ctx.Response.Body()), "Matching requests should bypass the middleware")
+
+	ctx = newTestCtx("GET", "http://localhost/public")
+	h(ctx)
+	assert.Equal(t, "t1\nt2\napp", string(ctx.Response.Body()), "Other requests should run the middleware")
+}
+
+func TestSkipPaths(t *testing.T) {
+	h := New(SkipPaths(tagMiddleware("t1\n"), "/health", "/metrics/*")).Then(testApp)
+
+	for path, body := range map[string]string{
+		"/health":          "app",
+		"/metrics/cpu":     "app",
+		"/metrics/cpu/avg": "t1\napp",
+		"/healthz":         "t1\napp",
+	} {
+		ctx := newTestCtx("GET", "http://localhost"+path)
+		h(ctx)
+		assert.Equal(t, body, string(ctx.Response.Body()), "Path %s should be handled accordingly", path)
+	}
+}
+
+func TestSkipPathsInvalidPattern(t *testing.T) {
+	assert.Panics(t, func() { SkipPaths(tagMiddleware(""), "/[") }, "Malformed patterns should panic")
+}
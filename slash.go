@@ -0,0 +1,31 @@
+package fastalice
+
+import "github.com/valyala/fasthttp"
+
+// SlashMode selects how NormalizeTrailingSlash
+// normalizes request paths.
+type SlashMode int
+
+const (
+	// SlashStrip removes the trailing slash from the path
+	// and continues.
+	SlashStrip SlashMode = iota
+	// SlashAppend adds a trailing slash to the path
+	// and continues.
+	SlashAppend
+	// SlashRedirect redirects paths with a trailing slash
+	// to the path without it.
+	SlashRedirect
+)
+
+// NormalizeTrailingSlash returns a constructor that normalizes
+// the trailing slash of request paths according to mode.
+// In strip and append modes the request URI is rewritten in place
+// before the following handlers run;
+// in redirect mode the request is answered
+// with 301 Moved Permanently to the normalized path instead.
+//
+// The root path "/" is always left untouched.
+func NormalizeTrailingSlash(mode SlashMode) Constructor {
+	return Rewrite([]RewriteRule{TrailingSlashRule(mode, fasthttp.StatusMovedPermanently)})
+}
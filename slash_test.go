@@ -0,0 +1,44 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func slashPath(mode SlashMode, uri string) (*fasthttp.RequestCtx, string) {
+	var path string
+	ctx := newTestCtx("GET", uri)
+	New(NormalizeTrailingSlash(mode)).Then(func(ctx *fasthttp.RequestCtx) {
+		path = string(ctx.Path())
+	})(ctx)
+	return ctx, path
+}
+
+func TestNormalizeTrailingSlashStrip(t *testing.T) {
+	_, path := slashPath(SlashStrip, "http://localhost/foo/")
+	assert.Equal(t, "/foo", path, "The trailing slash should be stripped")
+}
+
+func TestNormalizeTrailingSlashAppend(t *testing.T) {
+	_, path := slashPath(SlashAppend, "http://localhost/foo")
+	assert.Equal(t, "/foo/", path, "A trailing slash should be appended")
+}
+
+func TestNormalizeTrailingSlashRedirect(t *testing.T) {
+	ctx, path := slashPath(SlashRedirect, "http://localhost/foo/?page=2")
+	assert.Empty(t, path, "Redirected requests should not reach the handler")
+	assert.Equal(t, fasthttp.StatusMovedPermanently, ctx.Response.StatusCode(), "Redirect mode should answer Moved Permanently")
+	assert.Equal(t, "http://localhost/foo?page=2", string(ctx.Response.Header.Peek(fasthttp.HeaderLocation)), "Redirect mode should point to the normalized path")
+
+	_, path = slashPath(SlashRedirect, "http://localhost/foo")
+	assert.Equal(t, "/foo", path, "Normalized paths should pass")
+}
+
+func TestNormalizeTrailingSlashRoot(t *testing.T) {
+	for _, mode := range []SlashMode{SlashStrip, SlashAppend, SlashRedirect} {
+		_, path := slashPath(mode, "http://localhost/")
+		assert.Equal(t, "/", path, "The root path should be left untouched")
+	}
+}
@@ -0,0 +1,84 @@
+package fastalice
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// RequestSnapshot is a copy of the data of a served request,
+// safe to use once the RequestCtx it comes from has been reused.
+type RequestSnapshot struct {
+	Method    string
+	Path      string
+	URI       string
+	RequestID string
+	ClientIP  net.IP
+	// Status is the status code of the response.
+	Status int
+	// RequestHeader and ResponseHeader are copies of the headers.
+	RequestHeader  http.Header
+	ResponseHeader http.Header
+	// BodySize is the size of the response body,
+	// or -1 when it is streamed.
+	BodySize int
+	// Start is when the request reached the middleware,
+	// and Duration how long the following handlers took.
+	Start    time.Time
+	Duration time.Duration
+}
+
+// Snapshot returns a RequestSnapshot of the request of ctx,
+// with the given start time.
+func Snapshot(ctx *fasthttp.RequestCtx, start time.Time) RequestSnapshot {
+	s := RequestSnapshot{
+		Method:         string(ctx.Method()),
+		Path:           string(ctx.Path()),
+		URI:            string(ctx.URI().RequestURI()),
+		RequestID:      GetRequestID(ctx),
+		ClientIP:       append(net.IP(nil), ClientIP(ctx)...),
+		Status:         ctx.Response.StatusCode(),
+		RequestHeader:  make(http.Header),
+		ResponseHeader: make(http.Header),
+		BodySize:       -1,
+		Start:          start,
+		Duration:       now().Sub(start),
+	}
+	if !IsStreaming(ctx) {
+		s.BodySize = len(ctx.Response.Body())
+	}
+	ctx.Request.Header.VisitAll(func(k, v []byte) {
+		s.RequestHeader.Add(string(k), string(v))
+	})
+	ctx.Response.Header.VisitAll(func(k, v []byte) {
+		s.ResponseHeader.Add(string(k), string(v))
+	})
+	return s
+}
+
+// AfterResponse returns a constructor calling fn with a snapshot
+// of every request once the following handlers have returned.
+// fn runs in the background with Go, on the pool of Background
+// when it is placed before in the chain, so that it never delays
+// the response nor races with fasthttp reusing the RequestCtx.
+//
+// Unlike OnFinish hooks, fn cannot change the response.
+//
+//	chained := fastalice.New(
+//		fastalice.Background(pool),
+//		fastalice.AfterResponse(func(s fastalice.RequestSnapshot) {
+//			metrics.Observe(s.Path, s.Status, s.Duration)
+//		}),
+//	).Then(h)
+func AfterResponse(fn func(snapshot RequestSnapshot)) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			start := now()
+			next(ctx)
+			s := Snapshot(ctx, start)
+			Go(ctx, func() { fn(s) })
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package fastalice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestAfterResponseSnapshot(t *testing.T) {
+	defer fakeClock(time.Millisecond)()
+	pool := NewWorkerPool(1, 10)
+	var got RequestSnapshot
+	h := New(
+		RequestID(RequestIDGenerator(func() string { return "req-1" })),
+		Background(pool),
+		AfterResponse(func(s RequestSnapshot) { got = s }),
+	).Then(func(ctx *fasthttp.RequestCtx) {
+		ctx.Response.Header.Set("X-Cache", "miss")
+		ctx.SetStatusCode(fasthttp.StatusCreated)
+		ctx.WriteString("created")
+	})
+
+	ctx := newTestCtx("POST", "http://localhost/orders?draft=1")
+	ctx.Request.Header.Set("X-Tenant", "acme")
+	h(ctx)
+	ctx.Request.Reset()
+	ctx.Response.Reset()
+	pool.Close()
+
+	assert.Equal(t, "POST", got.Method, "The method should be copied")
+	assert.Equal(t, "/orders", got.Path, "The path should be copied")
+	assert.Equal(t, "/orders?draft=1", got.URI, "The URI should be copied")
+	assert.Equal(t, "req-1", got.RequestID, "The request ID should be copied")
+	assert.Equal(t, fasthttp.StatusCreated, got.Status, "The status should be copied")
+	assert.Equal(t, "acme", got.RequestHeader.Get("X-Tenant"), "Request headers should be copied")
+	assert.Equal(t, "miss", got.ResponseHeader.Get("X-Cache"), "Response headers should be copied")
+	assert.Equal(t, 7, got.BodySize, "The body size should be copied")
+	assert.Equal(t, time.Millisecond, got.Duration, "The duration should be measured")
+}
+
+func TestAfterResponseWithoutBackground(t *testing.T) {
+	done := make(chan RequestSnapshot, 1)
+	h := New(AfterResponse(func(s RequestSnapshot) { done <- s })).Then(testApp)
+
+	h(newTestCtx("GET", "http://localhost/"))
+	select {
+	case s := <-done:
+		assert.Equal(t, fasthttp.StatusOK, s.Status, "The status should be copied")
+	case <-time.After(time.Second):
+		t.Fatal("The callback should run without Background")
+	}
+}
+
+func TestAfterResponseSSE(t *testing.T) {
+	done := make(chan RequestSnapshot, 1)
+	h := New(AfterResponse(func(s RequestSnapshot) { done <- s })).Then(endlessSSE)
+
+	assert.True(t, returnsWithin(h, newTestCtx("GET", "http://localhost/events"), time.Second), "Endless streams should not be read")
+	select {
+	case s := <-done:
+		assert.Equal(t, -1, s.BodySize, "Streamed bodies should have no size")
+	case <-time.After(time.Second):
+		t.Fatal("The callback should run for streamed responses")
+	}
+}
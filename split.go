@@ -0,0 +1,181 @@
+package fastalice
+
+import (
+	"hash/fnv"
+	"math"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultSplitCookieName is the cookie used by Split and SplitArms
+// to remember assignments unless SplitCookieName is given.
+const DefaultSplitCookieName = "_split"
+
+// DefaultSplitCookieMaxAge is how long assignments are remembered
+// unless SplitCookieMaxAge is given.
+const DefaultSplitCookieMaxAge = 30 * 24 * time.Hour
+
+// splitArmKey holds the arm a request was assigned to.
+var splitArmKey = NewKey[string]("splitArm")
+
+// SplitArm is an arm of a traffic split.
+type SplitArm struct {
+	// Name identifies the arm in the assignment cookie
+	// and is returned by GetSplitArm.
+	Name string
+	// Weight is the share of traffic of the arm,
+	// relative to the weights of the other arms.
+	Weight float64
+	// Handler serves the requests of the arm.
+	// When nil, they go on with the following handlers.
+	Handler fasthttp.RequestHandler
+}
+
+// splitConfig holds the settings of SplitArms.
+type splitConfig struct {
+	cookieName string
+	maxAge     time.Duration
+	key        func(ctx *fasthttp.RequestCtx) string
+}
+
+// SplitOption configures Split and SplitArms.
+type SplitOption func(*splitConfig)
+
+// SplitCookieName sets the cookie remembering assignments,
+// which defaults to DefaultSplitCookieName.
+func SplitCookieName(name string) SplitOption {
+	return func(c *splitConfig) { c.cookieName = name }
+}
+
+// SplitCookieMaxAge sets how long assignments are remembered,
+// which defaults to DefaultSplitCookieMaxAge.
+func SplitCookieMaxAge(d time.Duration) SplitOption {
+	return func(c *splitConfig) { c.maxAge = d }
+}
+
+// SplitKey assigns requests by a hash of the key returned by fn,
+// such as a user ID, instead of at random,
+// so the same key always gets the same arm without a cookie.
+// Requests for which fn returns an empty string
+// are assigned at random and remembered by cookie.
+func SplitKey(fn func(ctx *fasthttp.RequestCtx) string) SplitOption {
+	return func(c *splitConfig) { c.key = fn }
+}
+
+// Split returns a constructor sending percent percent of the requests
+// to canary, such as an experimental chain, while the others
+// go on with the following handlers.
+// Assignments are sticky, see SplitArms.
+//
+//	chain := fastalice.New(logger, fastalice.Split(5, canaryChain.Then(app)))
+//
+// Split panics if percent is not between 0 and 100.
+func Split(percent float64, canary fasthttp.RequestHandler, opts ...SplitOption) Constructor {
+	if percent < 0 || percent > 100 {
+		panic("fastalice: Split percent must be between 0 and 100")
+	}
+	return SplitArms([]SplitArm{
+		{Name: "canary", Weight: percent, Handler: canary},
+		{Name: "control", Weight: 100 - percent},
+	}, opts...)
+}
+
+// SplitArms returns a constructor assigning each request
+// to one of arms in proportion to their weights,
+// and serving it with the handler of the arm.
+// The assigned arm can be read with GetSplitArm.
+//
+// Assignments are sticky: they are remembered in a cookie,
+// or derived from a user key with SplitKey.
+// A cookie naming an arm that no longer exists is ignored.
+//
+// SplitArms panics if a weight is negative or if they add up to zero.
+func SplitArms(arms []SplitArm, opts ...SplitOption) Constructor {
+	cfg := splitConfig{
+		cookieName: DefaultSplitCookieName,
+		maxAge:     DefaultSplitCookieMaxAge,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	arms = append([]SplitArm(nil), arms...)
+	var total float64
+	for _, arm := range arms {
+		if arm.Weight < 0 {
+			panic("fastalice: SplitArms weights must not be negative")
+		}
+		total += arm.Weight
+	}
+	if total == 0 {
+		panic("fastalice: SplitArms weights must not all be zero")
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			arm := cfg.assign(ctx, arms, total)
+			Set(ctx, splitArmKey, arm.Name)
+			if arm.Handler != nil {
+				arm.Handler(ctx)
+				return
+			}
+			next(ctx)
+		}
+	}
+}
+
+// assign returns the arm of the request,
+// setting the assignment cookie when it is new.
+func (cfg *splitConfig) assign(ctx *fasthttp.RequestCtx, arms []SplitArm, total float64) *SplitArm {
+	if cfg.key != nil {
+		if key := cfg.key(ctx); key != "" {
+			h := fnv.New64a()
+			h.Write([]byte(key))
+			return pickArm(arms, float64(h.Sum64())/math.MaxUint64*total)
+		}
+	}
+
+	if name := ctx.Request.Header.Cookie(cfg.cookieName); len(name) > 0 {
+		for i := range arms {
+			if arms[i].Name == string(name) && arms[i].Weight > 0 {
+				return &arms[i]
+			}
+		}
+	}
+
+	arm := pickArm(arms, randFloat64()*total)
+	c := fasthttp.AcquireCookie()
+	defer fasthttp.ReleaseCookie(c)
+	c.SetKey(cfg.cookieName)
+	c.SetValue(arm.Name)
+	c.SetPath("/")
+	c.SetMaxAge(int(cfg.maxAge / time.Second))
+	c.SetHTTPOnly(true)
+	ctx.Response.Header.SetCookie(c)
+	return arm
+}
+
+// pickArm returns the arm whose share of the total weight holds x.
+func pickArm(arms []SplitArm, x float64) *SplitArm {
+	var last *SplitArm
+	for i := range arms {
+		if arms[i].Weight == 0 {
+			continue
+		}
+		last = &arms[i]
+		if x < arms[i].Weight {
+			break
+		}
+		x -= arms[i].Weight
+	}
+	return last
+}
+
+// GetSplitArm returns the name of the arm the request
+// was assigned to by Split or SplitArms,
+// or an empty string if there is none.
+func GetSplitArm(ctx *fasthttp.RequestCtx) string {
+	arm, _ := Get(ctx, splitArmKey)
+	return arm
+}
@@ -0,0 +1,70 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func canaryApp(ctx *fasthttp.RequestCtx) {
+	ctx.SetBodyString("canary")
+}
+
+func TestSplit(t *testing.T) {
+	h := New(Split(10, canaryApp)).Then(testApp)
+
+	restore := fakeRand(0.05)
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	restore()
+	assert.Equal(t, "canary", string(ctx.Response.Body()), "Requests within the percentage should go to the canary")
+	assert.Equal(t, "canary", responseCookie(ctx, DefaultSplitCookieName), "The assignment should be remembered")
+	assert.Equal(t, "canary", GetSplitArm(ctx), "The arm should be exposed")
+
+	restore = fakeRand(0.5)
+	ctx = newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Other requests should go on with the chain")
+	assert.Equal(t, "control", responseCookie(ctx, DefaultSplitCookieName), "The assignment should be remembered")
+
+	ctx = newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.SetCookie(DefaultSplitCookieName, "canary")
+	h(ctx)
+	restore()
+	assert.Equal(t, "canary", string(ctx.Response.Body()), "Assignments should be sticky")
+	assert.Empty(t, responseCookie(ctx, DefaultSplitCookieName), "Remembered assignments should not be set again")
+}
+
+func TestSplitArmsByKey(t *testing.T) {
+	arm := func(name string) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) { ctx.SetBodyString(name) }
+	}
+	h := New(SplitArms([]SplitArm{
+		{Name: "a", Weight: 1, Handler: arm("a")},
+		{Name: "b", Weight: 1, Handler: arm("b")},
+		{Name: "c", Weight: 2, Handler: arm("c")},
+	}, SplitKey(func(ctx *fasthttp.RequestCtx) string {
+		return string(ctx.QueryArgs().Peek("user"))
+	}))).Then(testApp)
+
+	seen := make(map[string]bool)
+	for _, user := range []string{"alice", "bob", "carol", "dave", "erin", "frank", "grace", "heidi"} {
+		ctx := newTestCtx("GET", "http://localhost/?user="+user)
+		h(ctx)
+		first := string(ctx.Response.Body())
+		seen[first] = true
+
+		ctx = newTestCtx("GET", "http://localhost/?user="+user)
+		h(ctx)
+		assert.Equal(t, first, string(ctx.Response.Body()), "The same key should get the same arm")
+		assert.Empty(t, responseCookie(ctx, DefaultSplitCookieName), "Keyed assignments should not need a cookie")
+	}
+	assert.True(t, len(seen) > 1, "Keys should be spread across arms")
+}
+
+func TestSplitInvalid(t *testing.T) {
+	assert.Panics(t, func() { Split(120, canaryApp) }, "Percentages above 100 should panic")
+	assert.Panics(t, func() { SplitArms([]SplitArm{{Name: "a"}}) }, "Zero total weight should panic")
+	assert.Panics(t, func() { SplitArms([]SplitArm{{Name: "a", Weight: -1}, {Name: "b", Weight: 2}}) }, "Negative weights should panic")
+}
@@ -0,0 +1,152 @@
+package fastalice
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// streamingKey marks requests whose response is streamed.
+var streamingKey = NewKey[bool]("streaming")
+
+// StreamingResponse returns a constructor marking every request
+// as answered with a streamed response, such as server-sent events.
+// Shipped middleware buffering or rewriting the response body,
+// such as Compress, ETag, Cache, MaxResponseSize and Retry,
+// leave such responses alone; see IsStreaming.
+func StreamingResponse() Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			Set(ctx, streamingKey, true)
+			next(ctx)
+		}
+	}
+}
+
+// IsStreaming reports whether the response is streamed:
+// the request was marked by StreamingResponse or StreamEvents,
+// the body is set as a stream, its content type
+// is text/event-stream, or the request is served over HTTP/2,
+// whose responses are framed by the HTTP/2 add-on; see ProtocolInfo.
+// Middleware buffering the response body should skip such responses.
+func IsStreaming(ctx *fasthttp.RequestCtx) bool {
+	if marked, _ := Get(ctx, streamingKey); marked {
+		return true
+	}
+	return ctx.Response.IsBodyStream() ||
+		mediaType(ctx.Response.Header.ContentType()) == "text/event-stream" ||
+		ProtocolInfo(ctx).HTTP2()
+}
+
+// responseSize returns the size of the response body
+// without reading streamed bodies, which may never end:
+// their Content-Length, or -1 when it is unknown.
+func responseSize(ctx *fasthttp.RequestCtx) int {
+	if ctx.Response.IsBodyStream() {
+		if n := ctx.Response.Header.ContentLength(); n >= 0 {
+			return n
+		}
+		return -1
+	}
+	return len(ctx.Response.Body())
+}
+
+// Event is a server-sent event.
+type Event struct {
+	// ID sets the last event ID of the client, when not empty.
+	ID string
+	// Event is the event type, "message" when empty.
+	Event string
+	// Data is the event payload; it may span several lines.
+	Data string
+	// Retry sets the reconnection delay of the client, when positive.
+	Retry time.Duration
+}
+
+// EventWriter writes server-sent events to the client.
+type EventWriter struct {
+	w *bufio.Writer
+}
+
+// Send writes e and flushes it to the client.
+// An error means the client is gone and streaming should stop.
+func (w *EventWriter) Send(e Event) error {
+	if e.ID != "" {
+		w.field("id", e.ID)
+	}
+	if e.Event != "" {
+		w.field("event", e.Event)
+	}
+	if e.Retry > 0 {
+		w.field("retry", strconv.FormatInt(int64(e.Retry/time.Millisecond), 10))
+	}
+	for _, line := range strings.Split(e.Data, "\n") {
+		w.field("data", line)
+	}
+	w.w.WriteByte('\n')
+	return w.w.Flush()
+}
+
+// Comment writes a comment line, ignored by clients,
+// and flushes it. Periodic comments keep idle connections
+// from being closed by proxies.
+func (w *EventWriter) Comment(text string) error {
+	w.field("", text)
+	return w.w.Flush()
+}
+
+func (w *EventWriter) field(name, value string) {
+	w.w.WriteString(name)
+	w.w.WriteString(": ")
+	w.w.WriteString(value)
+	w.w.WriteByte('\n')
+}
+
+// StreamEvents answers the request with server-sent events
+// written by stream, which runs once the response headers are sent
+// and ends the response when it returns.
+// The request is marked as streaming, see IsStreaming.
+//
+// As with fasthttp.RequestCtx.SetBodyStreamWriter,
+// stream may run after the handler returned
+// and must not access ctx: copy what it needs beforehand.
+//
+//	func events(ctx *fasthttp.RequestCtx) {
+//		topic := string(ctx.QueryArgs().Peek("topic"))
+//		fastalice.StreamEvents(ctx, func(w *fastalice.EventWriter) {
+//			for msg := range subscribe(topic) {
+//				if w.Send(fastalice.Event{Data: msg}) != nil {
+//					return
+//				}
+//			}
+//		})
+//	}
+func StreamEvents(ctx *fasthttp.RequestCtx, stream func(w *EventWriter)) {
+	Set(ctx, streamingKey, true)
+	ctx.SetContentType("text/event-stream")
+	ctx.Response.Header.Set(fasthttp.HeaderCacheControl, "no-cache")
+	ctx.Response.Header.Set("X-Accel-Buffering", "no")
+	ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+		stream(&EventWriter{w})
+	})
+}
+
+// SSE returns a final handler answering every request
+// with the server-sent events written by stream,
+// as StreamEvents does.
+//
+//	chained := chain.Then(fastalice.SSE(func(w *fastalice.EventWriter) {
+//		for range time.Tick(time.Second) {
+//			if w.Send(fastalice.Event{Event: "tick", Data: time.Now().String()}) != nil {
+//				return
+//			}
+//		}
+//	}))
+func SSE(stream func(w *EventWriter)) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		StreamEvents(ctx, stream)
+	}
+}
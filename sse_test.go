@@ -0,0 +1,74 @@
+package fastalice
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestEventWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := &EventWriter{bufio.NewWriter(&buf)}
+
+	assert.NoError(t, w.Send(Event{ID: "1", Event: "update", Data: "a\nb", Retry: 3 * time.Second}))
+	assert.NoError(t, w.Comment("ping"))
+	assert.NoError(t, w.Send(Event{Data: "c"}))
+	assert.Equal(t, "id: 1\nevent: update\nretry: 3000\ndata: a\ndata: b\n\n: ping\ndata: c\n\n", buf.String(), "Events should be encoded and flushed")
+}
+
+func TestSSE(t *testing.T) {
+	h := New(ETag(false), Compress(fasthttp.CompressDefaultCompression)).Then(SSE(func(w *EventWriter) {
+		for i := 0; i < 3; i++ {
+			w.Send(Event{Data: strings.Repeat("x", DefaultCompressMinSize)})
+		}
+	}))
+
+	resp := serveInmemory(t, func(ctx *fasthttp.RequestCtx) {
+		ctx.Request.Header.Set(fasthttp.HeaderAcceptEncoding, "gzip")
+		h(ctx)
+	}, "http://localhost/events")
+	assert.Equal(t, "text/event-stream", string(resp.Header.ContentType()), "The event stream content type should be set")
+	assert.Equal(t, "no-cache", string(resp.Header.Peek(fasthttp.HeaderCacheControl)), "Event streams should not be cached")
+	assert.Empty(t, resp.Header.Peek(fasthttp.HeaderContentEncoding), "Event streams should not be compressed")
+	assert.Empty(t, resp.Header.Peek(fasthttp.HeaderETag), "Event streams should not be tagged")
+	assert.Equal(t, 3, strings.Count(string(resp.Body()), "data: "), "Every event should be streamed")
+}
+
+func TestStreamingResponse(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	assert.False(t, IsStreaming(ctx), "Plain responses should not be streaming")
+
+	ctx.SetContentType("text/event-stream; charset=utf-8")
+	assert.True(t, IsStreaming(ctx), "Event stream responses should be streaming")
+
+	ctx = newTestCtx("GET", "http://localhost/")
+	New(StreamingResponse(), ETag(false)).Then(testApp)(ctx)
+	assert.Empty(t, ctx.Response.Header.Peek(fasthttp.HeaderETag), "Marked responses should be left alone")
+}
+
+// endlessSSE streams events until the client goes away.
+var endlessSSE = SSE(func(w *EventWriter) {
+	for w.Send(Event{Data: "tick"}) == nil {
+	}
+})
+
+// returnsWithin reports whether h serves ctx within d,
+// for middleware that must not read streamed bodies.
+func returnsWithin(h fasthttp.RequestHandler, ctx *fasthttp.RequestCtx, d time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		h(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
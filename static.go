@@ -0,0 +1,82 @@
+package fastalice
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// StaticOptions configures the Static middleware.
+type StaticOptions struct {
+	// Prefix is the path prefix under which files are served,
+	// stripped before looking them up. It defaults to "/".
+	Prefix string
+	// IndexNames are the files served for directory requests,
+	// such as "index.html".
+	IndexNames []string
+	// GenerateIndexPages lists the content of directories
+	// without an index file; otherwise they are forbidden.
+	GenerateIndexPages bool
+	// AcceptByteRange enables Range requests.
+	AcceptByteRange bool
+	// Compress serves compressed files to clients accepting them.
+	Compress bool
+	// MaxAge sets Cache-Control max-age on served files.
+	MaxAge time.Duration
+}
+
+// passedStaticKey records that Static passed the request on.
+var passedStaticKey = NewKey[bool]("staticPassed")
+
+// Static returns a constructor that serves GET and HEAD requests
+// under opts.Prefix with the files found in root, using fasthttp.FS.
+// Requests for missing files, and other requests,
+// go on with the following handlers.
+func Static(root string, opts StaticOptions) Constructor {
+	prefix := opts.Prefix
+	if prefix == "" {
+		prefix = "/"
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	var cacheControl string
+	if opts.MaxAge > 0 {
+		cacheControl = "public, max-age=" + strconv.FormatInt(int64(opts.MaxAge/time.Second), 10)
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		fs := &fasthttp.FS{
+			Root:               root,
+			IndexNames:         opts.IndexNames,
+			GenerateIndexPages: opts.GenerateIndexPages,
+			AcceptByteRange:    opts.AcceptByteRange,
+			Compress:           opts.Compress,
+			PathRewrite:        fasthttp.NewPathPrefixStripper(len(prefix) - 1),
+			PathNotFound: func(ctx *fasthttp.RequestCtx) {
+				Set(ctx, passedStaticKey, true)
+				ctx.Response.Reset()
+				next(ctx)
+			},
+		}
+		serve := fs.NewRequestHandler()
+
+		return func(ctx *fasthttp.RequestCtx) {
+			if !(ctx.IsGet() || ctx.IsHead()) || !strings.HasPrefix(string(ctx.Path()), prefix) {
+				next(ctx)
+				return
+			}
+
+			serve(ctx)
+			if passed, _ := Get(ctx, passedStaticKey); passed {
+				Delete(ctx, passedStaticKey)
+				return
+			}
+			if cacheControl != "" && ctx.Response.StatusCode() == fasthttp.StatusOK {
+				ctx.Response.Header.Set(fasthttp.HeaderCacheControl, cacheControl)
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package fastalice
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func staticRoot(t *testing.T) string {
+	root := t.TempDir()
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(root, "app.js"), []byte("console.log(1)"), 0644))
+	assert.NoError(t, os.Mkdir(filepath.Join(root, "docs"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(root, "docs", "index.html"), []byte("<h1>docs</h1>"), 0644))
+	return root
+}
+
+func TestStaticServesFiles(t *testing.T) {
+	h := New(Static(staticRoot(t), StaticOptions{Prefix: "/assets", IndexNames: []string{"index.html"}, MaxAge: time.Hour})).Then(testApp)
+
+	ctx := newTestCtx("GET", "http://localhost/assets/app.js")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode(), "Existing files should be served")
+	assert.Equal(t, "console.log(1)", string(ctx.Response.Body()), "The file content should be served")
+	assert.Equal(t, "public, max-age=3600", string(ctx.Response.Header.Peek(fasthttp.HeaderCacheControl)), "Cache headers should be set")
+
+	ctx = newTestCtx("GET", "http://localhost/assets/docs/")
+	h(ctx)
+	assert.Equal(t, "<h1>docs</h1>", string(ctx.Response.Body()), "Directory indexes should be served")
+}
+
+func TestStaticPassesThrough(t *testing.T) {
+	h := New(Static(staticRoot(t), StaticOptions{Prefix: "/assets/", MaxAge: time.Hour})).Then(testApp)
+
+	for _, req := range []struct{ method, path string }{
+		{"GET", "/assets/missing.js"},
+		{"POST", "/assets/app.js"},
+		{"GET", "/app.js"},
+	} {
+		ctx := newTestCtx(req.method, "http://localhost"+req.path)
+		h(ctx)
+		assert.Equal(t, "app", string(ctx.Response.Body()), "%s %s should go on to the handler", req.method, req.path)
+		assert.Empty(t, ctx.Response.Header.Peek(fasthttp.HeaderCacheControl), "Passed requests should not get cache headers")
+	}
+}
+
+func TestStaticByteRange(t *testing.T) {
+	h := New(Static(staticRoot(t), StaticOptions{AcceptByteRange: true})).Then(testApp)
+
+	ctx := newTestCtx("GET", "http://localhost/app.js")
+	ctx.Request.Header.Set(fasthttp.HeaderRange, "bytes=0-6")
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusPartialContent, ctx.Response.StatusCode(), "Range requests should be honored")
+	assert.Equal(t, "console", string(ctx.Response.Body()), "Only the range should be served")
+}
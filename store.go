@@ -0,0 +1,95 @@
+package fastalice
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Key identifies a typed value stored in a request
+// with Set and read back with Get.
+//
+// Keys are collision-safe: two keys never clash,
+// even when created with the same name by different libraries,
+// so values can be passed between middleware
+// without agreeing on user value names.
+type Key[T any] struct {
+	name string
+}
+
+// keySeq numbers the keys returned by NewKey.
+var keySeq uint64
+
+// NewKey returns a new key for values of type T.
+// name only helps debugging; every call returns a distinct key.
+// Keys are meant to be package variables:
+//
+//	var userKey = fastalice.NewKey[*User]("user")
+func NewKey[T any](name string) Key[T] {
+	return Key[T]{name: fmt.Sprintf("fastalice.%s#%d", name, atomic.AddUint64(&keySeq, 1))}
+}
+
+// String returns the user value name under which the key stores values.
+func (k Key[T]) String() string {
+	return k.name
+}
+
+// cleanupValue is a value stored with SetWithCleanup.
+// fasthttp closes it once the request is served.
+type cleanupValue[T any] struct {
+	v       T
+	cleanup func(T)
+	done    bool
+}
+
+func (c *cleanupValue[T]) Close() error {
+	if !c.done {
+		c.done = true
+		c.cleanup(c.v)
+	}
+	return nil
+}
+
+// Set stores v in the request under key,
+// replacing the value stored there, if any.
+func Set[T any](ctx *fasthttp.RequestCtx, key Key[T], v T) {
+	release(ctx, key)
+	ctx.SetUserValue(key.name, v)
+}
+
+// SetWithCleanup is like Set, registering cleanup to be called with v
+// once the request is served, or when v is replaced or deleted,
+// for example to return v to a pool.
+func SetWithCleanup[T any](ctx *fasthttp.RequestCtx, key Key[T], v T, cleanup func(T)) {
+	release(ctx, key)
+	ctx.SetUserValue(key.name, &cleanupValue[T]{v: v, cleanup: cleanup})
+}
+
+// Get returns the value stored in the request under key,
+// and whether there is one.
+func Get[T any](ctx *fasthttp.RequestCtx, key Key[T]) (T, bool) {
+	switch v := ctx.UserValue(key.name).(type) {
+	case *cleanupValue[T]:
+		return v.v, true
+	case T:
+		return v, true
+	}
+	var zero T
+	return zero, false
+}
+
+// Delete removes the value stored in the request under key.
+func Delete[T any](ctx *fasthttp.RequestCtx, key Key[T]) {
+	if ctx.UserValue(key.name) != nil {
+		release(ctx, key)
+		ctx.SetUserValue(key.name, nil)
+	}
+}
+
+// release runs the cleanup of the value stored under key, if any.
+func release[T any](ctx *fasthttp.RequestCtx, key Key[T]) {
+	if c, ok := ctx.UserValue(key.name).(*cleanupValue[T]); ok {
+		c.Close()
+	}
+}
@@ -0,0 +1,78 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestStoreSetGet(t *testing.T) {
+	userKey := NewKey[string]("user")
+	ctx := newTestCtx("GET", "http://localhost/")
+
+	_, ok := Get(ctx, userKey)
+	assert.False(t, ok, "Missing values should be reported")
+
+	Set(ctx, userKey, "bob")
+	user, ok := Get(ctx, userKey)
+	assert.True(t, ok, "Stored values should be found")
+	assert.Equal(t, "bob", user, "Stored values should be returned")
+
+	Delete(ctx, userKey)
+	_, ok = Get(ctx, userKey)
+	assert.False(t, ok, "Deleted values should be gone")
+}
+
+func TestStoreKeysDoNotCollide(t *testing.T) {
+	first, second := NewKey[string]("user"), NewKey[int]("user")
+	ctx := newTestCtx("GET", "http://localhost/")
+
+	Set(ctx, first, "bob")
+	Set(ctx, second, 42)
+	user, _ := Get(ctx, first)
+	id, _ := Get[int](ctx, second)
+	assert.Equal(t, "bob", user, "Keys with the same name should not collide")
+	assert.Equal(t, 42, id, "Keys with the same name should not collide")
+	assert.NotEqual(t, first.String(), second.String(), "Keys should have distinct names")
+}
+
+func TestStoreInterfaceValues(t *testing.T) {
+	key := NewKey[interface{}]("any")
+	ctx := newTestCtx("GET", "http://localhost/")
+
+	_, ok := Get(ctx, key)
+	assert.False(t, ok, "Missing interface values should be reported")
+	SetWithCleanup[interface{}](ctx, key, "v", func(interface{}) {})
+	v, _ := Get(ctx, key)
+	assert.Equal(t, "v", v, "Interface values should be unwrapped")
+}
+
+func TestStoreCleanupOnReplace(t *testing.T) {
+	key := NewKey[string]("buf")
+	ctx := newTestCtx("GET", "http://localhost/")
+
+	var released []string
+	release := func(v string) { released = append(released, v) }
+	SetWithCleanup(ctx, key, "first", release)
+	v, _ := Get(ctx, key)
+	assert.Equal(t, "first", v, "Values with cleanup should be returned")
+
+	SetWithCleanup(ctx, key, "second", release)
+	assert.Equal(t, []string{"first"}, released, "Replaced values should be cleaned up")
+	Delete(ctx, key)
+	assert.Equal(t, []string{"first", "second"}, released, "Deleted values should be cleaned up")
+}
+
+func TestStoreCleanupAfterRequest(t *testing.T) {
+	key := NewKey[string]("buf")
+	released := make(chan string, 1)
+	h := func(ctx *fasthttp.RequestCtx) {
+		SetWithCleanup(ctx, key, "pooled", func(v string) { released <- v })
+		testApp(ctx)
+	}
+
+	resp := serveInmemory(t, h, "http://localhost/")
+	assert.Equal(t, "app", string(resp.Body()), "The request should be served")
+	assert.Equal(t, "pooled", <-released, "Values should be cleaned up once the request is served")
+}
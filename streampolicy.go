@@ -0,0 +1,98 @@
+package fastalice
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/valyala/fasthttp"
+)
+
+// StreamPolicy selects how the following handlers
+// receive request bodies streamed by the server.
+type StreamPolicy int
+
+const (
+	// StreamDefault leaves request bodies as received:
+	// shipped middleware needing a streamed body buffer it.
+	StreamDefault StreamPolicy = iota
+	// StreamBodies keeps streamed request bodies streaming,
+	// for handlers reading large uploads as they arrive.
+	// Shipped middleware do not buffer them: MaxBodySize
+	// only checks the declared Content-Length, Dump does not
+	// write them, and Decode reads them without keeping them.
+	StreamBodies
+	// BufferBodies reads streamed request bodies fully
+	// before calling the following handlers.
+	BufferBodies
+)
+
+// streamPolicyKey holds the policy set by Streaming.
+var streamPolicyKey = NewKey[StreamPolicy]("streamPolicy")
+
+// Streaming returns a constructor setting the request body
+// streaming policy of the following handlers,
+// so that routes or chains can opt in or out of streamed bodies.
+// Request bodies are only streamed when the server streams them,
+// or when an adapter sets them as a stream.
+//
+//	uploads := fastalice.New(fastalice.Streaming(fastalice.StreamBodies), auth)
+//	api := fastalice.New(fastalice.Streaming(fastalice.BufferBodies), auth, decode)
+func Streaming(policy StreamPolicy) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			Set(ctx, streamPolicyKey, policy)
+			if policy == BufferBodies && ctx.Request.IsBodyStream() {
+				// Body reads the stream into the request.
+				ctx.Request.Body()
+			}
+			next(ctx)
+		}
+	}
+}
+
+// RequestStreamPolicy returns the policy set by Streaming for the request,
+// or StreamDefault outside of it.
+func RequestStreamPolicy(ctx *fasthttp.RequestCtx) StreamPolicy {
+	policy, _ := Get(ctx, streamPolicyKey)
+	return policy
+}
+
+// keepStreaming reports whether the request body is a stream
+// that must not be buffered, as StreamBodies asks.
+func keepStreaming(ctx *fasthttp.RequestCtx) bool {
+	return ctx.Request.IsBodyStream() && RequestStreamPolicy(ctx) == StreamBodies
+}
+
+// readStream reads the streamed request body
+// without keeping it in the request.
+func readStream(req *fasthttp.Request) ([]byte, error) {
+	var buf bytes.Buffer
+	err := req.BodyWriteTo(&buf)
+	return buf.Bytes(), err
+}
+
+// pipedBody is a reader of a streamed request body
+// written by a goroutine.
+type pipedBody struct {
+	*io.PipeReader
+	done chan struct{}
+}
+
+// pipeStream returns a reader of the streamed body of req,
+// read as it arrives without keeping it in the request.
+// Closing it stops reading the stream and waits until req is left alone.
+func pipeStream(req *fasthttp.Request) io.ReadCloser {
+	pr, pw := io.Pipe()
+	b := &pipedBody{PipeReader: pr, done: make(chan struct{})}
+	go func() {
+		defer close(b.done)
+		pw.CloseWithError(req.BodyWriteTo(pw))
+	}()
+	return b
+}
+
+func (b *pipedBody) Close() error {
+	b.PipeReader.Close()
+	<-b.done
+	return nil
+}
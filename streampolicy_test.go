@@ -0,0 +1,62 @@
+package fastalice
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// newStreamCtx returns a POST request whose body is a stream.
+func newStreamCtx(body string) *fasthttp.RequestCtx {
+	ctx := newTestCtx("POST", "http://localhost/upload")
+	ctx.Request.Header.SetContentType("application/json")
+	ctx.Request.SetBodyStream(strings.NewReader(body), -1)
+	return ctx
+}
+
+func TestStreamingPolicies(t *testing.T) {
+	var streamed bool
+	var policy StreamPolicy
+	app := func(ctx *fasthttp.RequestCtx) {
+		streamed = ctx.Request.IsBodyStream()
+		policy = RequestStreamPolicy(ctx)
+	}
+
+	New(Streaming(StreamBodies), MaxBodySize(4)).Then(app)(newStreamCtx("a large body"))
+	assert.True(t, streamed, "StreamBodies should keep the body streaming through MaxBodySize")
+	assert.Equal(t, StreamBodies, policy, "Handlers should see the policy")
+
+	New(MaxBodySize(64)).Then(app)(newStreamCtx("body"))
+	assert.False(t, streamed, "MaxBodySize should buffer streamed bodies by default")
+
+	New(Streaming(BufferBodies)).Then(app)(newStreamCtx("body"))
+	assert.False(t, streamed, "BufferBodies should buffer streamed bodies")
+
+	ctx := newStreamCtx("body")
+	ctx.Request.Header.SetContentLength(100)
+	New(Streaming(StreamBodies), MaxBodySize(4)).Then(app)(ctx)
+	assert.Equal(t, fasthttp.StatusRequestEntityTooLarge, ctx.Response.StatusCode(), "Declared lengths should still be checked")
+}
+
+func TestStreamingDecodeAndDump(t *testing.T) {
+	type payload struct {
+		Name string `json:"name"`
+	}
+	var decoded interface{}
+	var buf bytes.Buffer
+	h := New(
+		Streaming(StreamBodies),
+		Dump(&buf, DumpOptions{}),
+		Decode(func() interface{} { return new(payload) }),
+	).Then(func(ctx *fasthttp.RequestCtx) {
+		decoded = DecodedBody(ctx)
+	})
+
+	h(newStreamCtx(`{"name":"x"}`))
+	assert.Equal(t, &payload{Name: "x"}, decoded, "Decode should read streamed bodies")
+	assert.Contains(t, buf.String(), "[streaming body]", "Dump should not buffer streamed bodies")
+	assert.NotContains(t, buf.String(), `"name"`, "Dump should not write streamed bodies")
+}
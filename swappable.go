@@ -0,0 +1,60 @@
+package fastalice
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/valyala/fasthttp"
+)
+
+// SwappableChain serves a final handler through a chain
+// that can be replaced as a whole while serving requests,
+// for example on configuration reload.
+//
+// Unlike MutableChain, the replacement chain is composed
+// by Swap itself, so constructors never run on the request path
+// and a request always sees either the old or the new chain.
+type SwappableChain struct {
+	final   fasthttp.RequestHandler
+	mu      sync.Mutex // serializes Swap
+	current atomic.Value
+}
+
+// NewSwappable creates a swappable chain
+// serving final through chain.
+func NewSwappable(chain Chain, final fasthttp.RequestHandler) *SwappableChain {
+	s := &SwappableChain{final: final}
+	s.Swap(chain)
+	return s
+}
+
+// Swap atomically replaces the chain,
+// returning the one it replaced.
+// Requests already being served finish with the old chain.
+func (s *SwappableChain) Swap(chain Chain) Chain {
+	chain = chain.Clone()
+	compiled := &compiledChain{&chain, chain.Then(s.final)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, _ := s.current.Load().(*compiledChain)
+	s.current.Store(compiled)
+	if old == nil {
+		return Chain{}
+	}
+	return *old.chain
+}
+
+// Chain returns the current chain.
+func (s *SwappableChain) Chain() Chain {
+	return *s.current.Load().(*compiledChain).chain
+}
+
+// Handler returns a stable fasthttp.RequestHandler
+// serving every request through the current chain.
+func (s *SwappableChain) Handler() fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		s.current.Load().(*compiledChain).handler(ctx)
+	}
+}
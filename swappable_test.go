@@ -0,0 +1,69 @@
+package fastalice
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestSwappableChainSwap(t *testing.T) {
+	s := NewSwappable(New(tagMiddleware("t1\n")), testApp)
+	h := s.Handler()
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, "t1\napp", string(ctx.Response.Body()), "The initial chain should serve requests")
+
+	old := s.Swap(New(tagMiddleware("t2\n")))
+	assert.True(t, old.Equal(New(tagMiddleware("t1\n"))), "Swap should return the replaced chain")
+
+	ctx = newTestCtx("GET", "http://localhost/")
+	h(ctx)
+	assert.Equal(t, "t2\napp", string(ctx.Response.Body()), "The same handler should serve the new chain")
+	assert.Equal(t, 1, len(s.Chain().constructors), "Chain should return the current chain")
+}
+
+func TestSwappableChainComposesOnSwap(t *testing.T) {
+	var calls int
+	counting := func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		calls++
+		return next
+	}
+	s := NewSwappable(New(), testApp)
+	s.Swap(New(counting))
+	assert.Equal(t, 1, calls, "Swap should compose the chain")
+
+	h := s.Handler()
+	for i := 0; i < 3; i++ {
+		h(newTestCtx("GET", "http://localhost/"))
+	}
+	assert.Equal(t, 1, calls, "Requests should not compose the chain")
+}
+
+func TestSwappableChainConcurrentSwap(t *testing.T) {
+	s := NewSwappable(New(tagMiddleware("a")), testApp)
+	h := s.Handler()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Swap(New(tagMiddleware("b")))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				ctx := newTestCtx("GET", "http://localhost/")
+				h(ctx)
+				body := string(ctx.Response.Body())
+				assert.True(t, body == "aapp" || body == "bapp", "Requests should see a whole chain")
+			}
+		}()
+	}
+	wg.Wait()
+}
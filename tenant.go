@@ -0,0 +1,34 @@
+package fastalice
+
+import "github.com/valyala/fasthttp"
+
+// Tenant returns a handler serving each request
+// with the handler of its tenant, as named by selector,
+// so that tenants can have entirely different chains:
+//
+//	byHost := func(ctx *fasthttp.RequestCtx) string { return string(ctx.Host()) }
+//	fastalice.Tenant(byHost, map[string]fasthttp.RequestHandler{
+//		"acme.example.com":   acmeChain.Then(app),
+//		"globex.example.com": globexChain.Then(app),
+//	}, defaultChain.Then(app))
+//
+// Requests of unknown tenants are served by fallback,
+// or by the default handler of Then() when it is nil.
+// The map is copied, so later changes to it do not affect the handler.
+func Tenant(selector func(ctx *fasthttp.RequestCtx) string, chains map[string]fasthttp.RequestHandler, fallback fasthttp.RequestHandler) fasthttp.RequestHandler {
+	handlers := make(map[string]fasthttp.RequestHandler, len(chains))
+	for tenant, h := range chains {
+		handlers[tenant] = h
+	}
+	if fallback == nil {
+		fallback = serveDefault
+	}
+
+	return func(ctx *fasthttp.RequestCtx) {
+		if h, ok := handlers[selector(ctx)]; ok && h != nil {
+			h(ctx)
+			return
+		}
+		fallback(ctx)
+	}
+}
@@ -0,0 +1,31 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestTenant(t *testing.T) {
+	byHeader := func(ctx *fasthttp.RequestCtx) string {
+		return string(ctx.Request.Header.Peek("X-Tenant"))
+	}
+	chains := map[string]fasthttp.RequestHandler{
+		"acme":   New(tagMiddleware("acme\n")).Then(testApp),
+		"globex": New(tagMiddleware("globex\n")).Then(testApp),
+	}
+	h := Tenant(byHeader, chains, New(tagMiddleware("default\n")).Then(testApp))
+	delete(chains, "acme")
+
+	for tenant, want := range map[string]string{
+		"acme":    "acme\napp",
+		"globex":  "globex\napp",
+		"unknown": "default\napp",
+	} {
+		ctx := newTestCtx("GET", "http://localhost/")
+		ctx.Request.Header.Set("X-Tenant", tenant)
+		h(ctx)
+		assert.Equal(t, want, string(ctx.Response.Body()), "Tenant %q should be served by its chain", tenant)
+	}
+}
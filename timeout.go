@@ -0,0 +1,114 @@
+package fastalice
+
+import (
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// deadlineKey holds the deadline enforced by the outermost
+// Timeout or DeadlineFromHeader of a request.
+var deadlineKey = NewKey[*deadline]("deadline")
+
+// deadline is the deadline of a request,
+// which nested timeouts may shorten.
+type deadline struct {
+	mu         sync.Mutex
+	at         time.Time
+	msg        string
+	statusCode int
+	changed    chan struct{}
+}
+
+// shorten moves the deadline to at, answering with msg and statusCode
+// once it expires, unless the deadline is already earlier.
+func (dl *deadline) shorten(at time.Time, msg string, statusCode int) {
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+	if !at.Before(dl.at) {
+		return
+	}
+	dl.at, dl.msg, dl.statusCode = at, msg, statusCode
+	select {
+	case dl.changed <- struct{}{}:
+	default:
+	}
+}
+
+// runWithTimeout calls next, answering with msg and statusCode
+// when it does not return within d.
+//
+// Like fasthttp.TimeoutHandler, next runs in its own goroutine
+// and the response is marked with ctx.TimeoutErrorWithCode on expiry,
+// so the server does not reuse ctx while next still uses it.
+// Within an outer timeout, next runs in place
+// and only shortens the outer deadline:
+// fasthttp timeout handlers cannot be nested.
+// Unlike fasthttp.TimeoutHandler, it does not take a slot
+// of the server concurrency, which Server.ServeConn leaves unset.
+func runWithTimeout(ctx *fasthttp.RequestCtx, next fasthttp.RequestHandler, d time.Duration, msg string, statusCode int) {
+	at := now().Add(d)
+	if dl, ok := Get(ctx, deadlineKey); ok {
+		dl.shorten(at, msg, statusCode)
+		next(ctx)
+		return
+	}
+
+	dl := &deadline{at: at, msg: msg, statusCode: statusCode, changed: make(chan struct{}, 1)}
+	Set(ctx, deadlineKey, dl)
+	done := make(chan struct{})
+	go func() {
+		next(ctx)
+		close(done)
+	}()
+
+	for {
+		dl.mu.Lock()
+		timer := time.NewTimer(dl.at.Sub(now()))
+		dl.mu.Unlock()
+
+		select {
+		case <-done:
+			timer.Stop()
+			return
+		case <-dl.changed:
+			timer.Stop()
+		case <-timer.C:
+			dl.mu.Lock()
+			msg, statusCode := dl.msg, dl.statusCode
+			dl.mu.Unlock()
+			ctx.TimeoutErrorWithCode(msg, statusCode)
+			return
+		}
+	}
+}
+
+// Timeout returns a constructor that answers with
+// 408 Request Timeout and msg when the following handlers
+// do not return within d.
+//
+// As with fasthttp.TimeoutHandler,
+// the following handlers keep running in their own goroutine
+// after the deadline, and the server does not reuse the
+// request context until they return, so they may keep
+// using it safely. Their changes to the response are ignored.
+// Timeouts may be nested, with each other and with
+// DeadlineFromHeader: the earliest deadline wins.
+// A non-positive d disables the timeout.
+func Timeout(d time.Duration, msg string) Constructor {
+	return TimeoutWithCode(d, msg, fasthttp.StatusRequestTimeout)
+}
+
+// TimeoutWithCode is like Timeout, answering with statusCode instead,
+// such as 503 Service Unavailable.
+func TimeoutWithCode(d time.Duration, msg string, statusCode int) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		if d <= 0 {
+			return next
+		}
+		return func(ctx *fasthttp.RequestCtx) {
+			runWithTimeout(ctx, next, d, msg, statusCode)
+		}
+	}
+}
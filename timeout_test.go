@@ -0,0 +1,109 @@
+package fastalice
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+// serveInmemory serves h on an in-memory listener
+// and returns the response to a GET request for uri.
+func serveInmemory(t *testing.T, h fasthttp.RequestHandler, uri string) *fasthttp.Response {
+	ln := fasthttputil.NewInmemoryListener()
+	defer ln.Close()
+	go fasthttp.Serve(ln, h)
+
+	client := &fasthttp.Client{
+		Dial: func(addr string) (net.Conn, error) { return ln.Dial() },
+	}
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	req.SetRequestURI(uri)
+
+	resp := &fasthttp.Response{}
+	assert.NoError(t, client.Do(req, resp), "The request should be served")
+	return resp
+}
+
+// serveConn serves h with Server.ServeConn over net.Pipe,
+// as alicetest.Record does, and returns the response to a GET request for uri.
+func serveConn(t *testing.T, h fasthttp.RequestHandler, uri string) *fasthttp.Response {
+	server := &fasthttp.Server{Handler: h}
+	client, conn := net.Pipe()
+	defer client.Close()
+	go server.ServeConn(conn)
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	req.SetRequestURI(uri)
+	w := bufio.NewWriter(client)
+	go func() {
+		req.Write(w)
+		w.Flush()
+	}()
+
+	resp := &fasthttp.Response{}
+	assert.NoError(t, resp.Read(bufio.NewReader(client)), "The request should be served")
+	return resp
+}
+
+func TestTimeoutExpires(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	slow := func(ctx *fasthttp.RequestCtx) {
+		<-release
+		ctx.WriteString("late")
+	}
+
+	resp := serveInmemory(t, New(Timeout(10*time.Millisecond, "too slow")).Then(slow), "http://localhost/")
+	assert.Equal(t, fasthttp.StatusRequestTimeout, resp.StatusCode(), "Expired requests should get a 408")
+	assert.Equal(t, "too slow", string(resp.Body()), "Expired requests should get the message")
+}
+
+func TestTimeoutWithCodeExpires(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	slow := func(ctx *fasthttp.RequestCtx) { <-release }
+
+	resp := serveInmemory(t, New(TimeoutWithCode(10*time.Millisecond, "busy", fasthttp.StatusServiceUnavailable)).Then(slow), "http://localhost/")
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, resp.StatusCode(), "Expired requests should get the given status code")
+}
+
+func TestTimeoutInTime(t *testing.T) {
+	resp := serveInmemory(t, New(Timeout(time.Second, "too slow")).Then(testApp), "http://localhost/")
+	assert.Equal(t, fasthttp.StatusOK, resp.StatusCode(), "Fast requests should not time out")
+	assert.Equal(t, "app", string(resp.Body()), "Fast requests should get the handler response")
+}
+
+func TestTimeoutDisabled(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(Timeout(0, "too slow")).Then(testApp)(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "A zero timeout should call the handler directly")
+}
+
+func TestTimeoutNested(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	slow := func(ctx *fasthttp.RequestCtx) { <-release }
+
+	inner := New(Timeout(time.Minute, "outer"), TimeoutWithCode(10*time.Millisecond, "inner", fasthttp.StatusServiceUnavailable)).Then(slow)
+	resp := serveInmemory(t, inner, "http://localhost/")
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, resp.StatusCode(), "A shorter inner timeout should win")
+	assert.Equal(t, "inner", string(resp.Body()), "A shorter inner timeout should answer with its message")
+
+	outer := New(Timeout(10*time.Millisecond, "outer"), TimeoutWithCode(time.Minute, "inner", fasthttp.StatusServiceUnavailable)).Then(slow)
+	resp = serveInmemory(t, outer, "http://localhost/")
+	assert.Equal(t, fasthttp.StatusRequestTimeout, resp.StatusCode(), "A shorter outer timeout should win")
+	assert.Equal(t, "outer", string(resp.Body()), "A shorter outer timeout should answer with its message")
+}
+
+func TestTimeoutServeConn(t *testing.T) {
+	resp := serveConn(t, New(Timeout(time.Second, "too slow")).Then(testApp), "http://localhost/")
+	assert.Equal(t, fasthttp.StatusOK, resp.StatusCode(), "Requests served with ServeConn should not be rejected")
+	assert.Equal(t, "app", string(resp.Body()), "Requests served with ServeConn should reach the handler")
+}
@@ -0,0 +1,26 @@
+package fastalice
+
+import (
+	"github.com/valyala/fasthttp"
+)
+
+// Trace returns a constructor that lets the request
+// take part in distributed tracing
+// without tying the package to a tracing library.
+//
+// start is called before the following handlers run
+// and is expected to begin a span;
+// the function it returns ends that span
+// and is called with the final status code once they returned.
+func Trace(start func(ctx *fasthttp.RequestCtx) func(status int)) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			finish := start(ctx)
+			next(ctx)
+
+			if finish != nil {
+				finish(ctx.Response.StatusCode())
+			}
+		}
+	}
+}
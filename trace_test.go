@@ -0,0 +1,33 @@
+package fastalice
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// fakeTracer records span events in the order they happen.
+type fakeTracer struct {
+	events []string
+}
+
+func (tr *fakeTracer) start(ctx *fasthttp.RequestCtx) func(status int) {
+	name := string(ctx.Path())
+	tr.events = append(tr.events, "begin "+name)
+	return func(status int) {
+		tr.events = append(tr.events, fmt.Sprintf("end %s %d", name, status))
+	}
+}
+
+func TestTraceRecordsSpan(t *testing.T) {
+	tr := &fakeTracer{}
+	h := New(Trace(tr.start)).Then(func(ctx *fasthttp.RequestCtx) {
+		tr.events = append(tr.events, "handler")
+		ctx.SetStatusCode(fasthttp.StatusTeapot)
+	})
+
+	h(newTestCtx("GET", "http://localhost/brew"))
+	assert.Equal(t, []string{"begin /brew", "handler", "end /brew 418"}, tr.events, "The span should wrap the handler and capture its status")
+}
@@ -0,0 +1,76 @@
+package fastalice
+
+import (
+	"database/sql"
+	"reflect"
+
+	"github.com/valyala/fasthttp"
+)
+
+// txType is the type Tx provides transactions under.
+var txType = reflect.TypeOf((*sql.Tx)(nil))
+
+// TxOptions configures the Tx middleware.
+type TxOptions struct {
+	// Isolation is the isolation level of the transactions,
+	// the default level of the driver when zero.
+	Isolation sql.IsolationLevel
+	// ReadOnly begins read-only transactions.
+	ReadOnly bool
+	// Commit, when not nil, reports whether the transaction
+	// of a served request should be committed, replacing the default:
+	// committing when the response status is below 400
+	// and no error is returned through the error-aware chain.
+	Commit func(ctx *fasthttp.RequestCtx) bool
+}
+
+// Tx returns a constructor running the following handlers
+// in a transaction of db, available to them with Use[*sql.Tx] or TxFrom.
+// The transaction is committed once they succeed,
+// with a response status below 400 and no error returned
+// through the error-aware chain,
+// and rolled back otherwise, including when they panic.
+//
+// Requests are answered with 500 Internal Server Error
+// when the transaction cannot begin, without calling the following handlers,
+// or when it cannot commit.
+//
+//	chain := fastalice.New(fastalice.Tx(db, fastalice.TxOptions{Isolation: sql.LevelSerializable}))
+func Tx(db *sql.DB, opts TxOptions) Constructor {
+	commit := opts.Commit
+	if commit == nil {
+		commit = func(ctx *fasthttp.RequestCtx) bool {
+			err, _ := Get(ctx, handlerErrorKey)
+			return err == nil && ctx.Response.StatusCode() < 400
+		}
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly})
+			if err != nil {
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusInternalServerError), fasthttp.StatusInternalServerError)
+				return
+			}
+			// Rollback is a no-op once the transaction committed.
+			defer tx.Rollback()
+			defer provide(ctx, txType, tx)()
+
+			next(ctx)
+
+			if !commit(ctx) {
+				return
+			}
+			if err := tx.Commit(); err != nil {
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusInternalServerError), fasthttp.StatusInternalServerError)
+			}
+		}
+	}
+}
+
+// TxFrom returns the transaction begun by Tx for the request,
+// or nil if there is none.
+func TxFrom(ctx *fasthttp.RequestCtx) *sql.Tx {
+	tx, _ := Provided[*sql.Tx](ctx)
+	return tx
+}
@@ -0,0 +1,114 @@
+package fastalice
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// txDriver is a database driver recording the outcome of transactions.
+type txDriver struct {
+	mu        sync.Mutex
+	events    []string
+	failBegin bool
+}
+
+func (d *txDriver) record(e string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.events = append(d.events, e)
+}
+
+func (d *txDriver) Open(string) (driver.Conn, error) { return &txConn{d}, nil }
+
+type txConn struct{ d *txDriver }
+
+func (c *txConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *txConn) Close() error                        { return nil }
+func (c *txConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *txConn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if c.d.failBegin {
+		return nil, errors.New("down")
+	}
+	c.d.record("begin " + sql.IsolationLevel(opts.Isolation).String())
+	return &txTx{c.d}, nil
+}
+
+type txTx struct{ d *txDriver }
+
+func (t *txTx) Commit() error   { t.d.record("commit"); return nil }
+func (t *txTx) Rollback() error { t.d.record("rollback"); return nil }
+
+var txDriverSeq int
+
+// openTxDB returns a database backed by a new txDriver.
+func openTxDB(t *testing.T) (*sql.DB, *txDriver) {
+	d := &txDriver{}
+	txDriverSeq++
+	name := fmt.Sprintf("fastalice-tx-%d", txDriverSeq)
+	sql.Register(name, d)
+	db, err := sql.Open(name, "")
+	assert.NoError(t, err, "The database should open")
+	return db, d
+}
+
+func TestTxCommitsAndRollsBack(t *testing.T) {
+	db, d := openTxDB(t)
+	defer db.Close()
+
+	var seen *sql.Tx
+	h := New(Tx(db, TxOptions{Isolation: sql.LevelSerializable})).Then(func(ctx *fasthttp.RequestCtx) {
+		seen = Use[*sql.Tx](ctx)
+		if ctx.QueryArgs().Has("fail") {
+			ctx.Error("bad", fasthttp.StatusBadRequest)
+		}
+		if ctx.QueryArgs().Has("panic") {
+			panic("boom")
+		}
+	})
+
+	ctx := newTestCtx("POST", "http://localhost/orders")
+	h(ctx)
+	assert.NotNil(t, seen, "The transaction should be provided")
+	assert.Nil(t, TxFrom(ctx), "The transaction should not outlive the handlers")
+	assert.Equal(t, []string{"begin Serializable", "commit"}, d.events, "Successful requests should commit")
+
+	d.events = nil
+	h(newTestCtx("POST", "http://localhost/orders?fail"))
+	assert.Equal(t, []string{"begin Serializable", "rollback"}, d.events, "Failed requests should roll back")
+
+	d.events = nil
+	assert.Panics(t, func() { h(newTestCtx("POST", "http://localhost/orders?panic")) }, "The panic should propagate")
+	assert.Equal(t, []string{"begin Serializable", "rollback"}, d.events, "Panicking requests should roll back")
+}
+
+func TestTxRollsBackOnHandlerError(t *testing.T) {
+	db, d := openTxDB(t)
+	defer db.Close()
+
+	h := New(Tx(db, TxOptions{})).ThenErr(func(ctx *fasthttp.RequestCtx) error {
+		return errors.New("insert failed")
+	}, nil)
+	h(newTestCtx("POST", "http://localhost/orders"))
+	assert.Equal(t, []string{"begin Default", "rollback"}, d.events, "Requests returning an error should roll back")
+}
+
+func TestTxBeginFails(t *testing.T) {
+	db, d := openTxDB(t)
+	defer db.Close()
+	d.failBegin = true
+
+	ctx := newTestCtx("POST", "http://localhost/orders")
+	New(Tx(db, TxOptions{})).Then(testApp)(ctx)
+	assert.Equal(t, fasthttp.StatusInternalServerError, ctx.Response.StatusCode(), "Requests should fail when the transaction cannot begin")
+}
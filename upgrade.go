@@ -0,0 +1,59 @@
+package fastalice
+
+import (
+	"bytes"
+
+	"github.com/valyala/fasthttp"
+)
+
+// IsUpgrade reports whether the request asks to switch protocols,
+// as WebSocket handshakes do: its Connection header
+// holds the "upgrade" token and its Upgrade header is set.
+func IsUpgrade(ctx *fasthttp.RequestCtx) bool {
+	h := &ctx.Request.Header
+	if len(h.Peek(fasthttp.HeaderUpgrade)) == 0 {
+		return false
+	}
+	for _, token := range bytes.Split(h.Peek(fasthttp.HeaderConnection), []byte(",")) {
+		if bytes.EqualFold(bytes.TrimSpace(token), []byte("upgrade")) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsWebSocketUpgrade reports whether the request
+// is a WebSocket handshake.
+func IsWebSocketUpgrade(ctx *fasthttp.RequestCtx) bool {
+	return IsUpgrade(ctx) && bytes.EqualFold(ctx.Request.Header.Peek(fasthttp.HeaderUpgrade), []byte("websocket"))
+}
+
+// UpgradeAware wraps a constructor so that it is skipped
+// for requests switching protocols, such as WebSocket handshakes,
+// whose connection is hijacked by the final handler.
+// Use it for middleware that buffers, rewrites or measures
+// the response body, which has no meaning once the connection
+// is handed over:
+//
+//	chain := fastalice.New(
+//		fastalice.UpgradeAware(fastalice.Compress(fasthttp.CompressDefaultCompression)),
+//		fastalice.UpgradeAware(fastalice.ETag(false)),
+//		auth,
+//	)
+//	chained := chain.Then(websocketHandler)
+//
+// Middleware that must run before the handshake,
+// such as authentication or rate limiting, should not be wrapped.
+func UpgradeAware(c Constructor) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		wrapped := c(next)
+
+		return func(ctx *fasthttp.RequestCtx) {
+			if IsUpgrade(ctx) {
+				next(ctx)
+				return
+			}
+			wrapped(ctx)
+		}
+	}
+}
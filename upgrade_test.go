@@ -0,0 +1,40 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func newUpgradeCtx() *fasthttp.RequestCtx {
+	ctx := newTestCtx("GET", "http://localhost/ws")
+	ctx.Request.Header.Set(fasthttp.HeaderConnection, "keep-alive, Upgrade")
+	ctx.Request.Header.Set(fasthttp.HeaderUpgrade, "websocket")
+	return ctx
+}
+
+func TestIsUpgrade(t *testing.T) {
+	assert.True(t, IsUpgrade(newUpgradeCtx()), "Handshakes should be detected")
+	assert.True(t, IsWebSocketUpgrade(newUpgradeCtx()), "WebSocket handshakes should be detected")
+
+	ctx := newTestCtx("GET", "http://localhost/ws")
+	ctx.Request.Header.Set(fasthttp.HeaderUpgrade, "websocket")
+	assert.False(t, IsUpgrade(ctx), "Upgrade without Connection: upgrade should not be a handshake")
+
+	ctx = newUpgradeCtx()
+	ctx.Request.Header.Set(fasthttp.HeaderUpgrade, "h2c")
+	assert.False(t, IsWebSocketUpgrade(ctx), "Other protocols should not be WebSocket handshakes")
+}
+
+func TestUpgradeAware(t *testing.T) {
+	h := New(UpgradeAware(tagMiddleware("t1\n")), tagMiddleware("t2\n")).Then(testApp)
+
+	ctx := newUpgradeCtx()
+	h(ctx)
+	assert.Equal(t, "t2\napp", string(ctx.Response.Body()), "Wrapped middleware should be skipped for handshakes")
+
+	ctx = newTestCtx("GET", "http://localhost/ws")
+	h(ctx)
+	assert.Equal(t, "t1\nt2\napp", string(ctx.Response.Body()), "Wrapped middleware should run for other requests")
+}
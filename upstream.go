@@ -0,0 +1,93 @@
+package fastalice
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// upstreamTimingKey is the key
+// holding the upstream timing marks of the request.
+var upstreamTimingKey = NewKey[*upstreamMarks]("upstreamTiming")
+
+// UpstreamTimings is the breakdown of the time
+// a proxied request spent waiting on its upstream.
+type UpstreamTimings struct {
+	// Start is the time elapsed before the upstream call started.
+	Start time.Duration
+	// FirstByte is the time from the start of the upstream call
+	// until its first response byte.
+	FirstByte time.Duration
+	// Total is the duration of the whole upstream call.
+	Total time.Duration
+}
+
+// String formats the timings as key=value pairs
+// suitable for access logs.
+func (t UpstreamTimings) String() string {
+	return fmt.Sprintf("upstream_start=%s upstream_first_byte=%s upstream_total=%s", t.Start, t.FirstByte, t.Total)
+}
+
+type upstreamMarks struct {
+	received, start, firstByte, end time.Time
+}
+
+// UpstreamTiming returns a constructor that lets proxy handlers
+// record the phases of their upstream calls
+// with MarkUpstreamStart, MarkFirstByte and MarkUpstreamEnd.
+// The recorded breakdown is available to logging middleware
+// placed before it through GetUpstreamTimings.
+func UpstreamTiming() Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			Set(ctx, upstreamTimingKey, &upstreamMarks{received: now()})
+			next(ctx)
+		}
+	}
+}
+
+func upstreamMark(ctx *fasthttp.RequestCtx, set func(m *upstreamMarks, t time.Time)) {
+	if m, ok := Get(ctx, upstreamTimingKey); ok {
+		set(m, now())
+	}
+}
+
+// MarkUpstreamStart records that the upstream call is starting.
+// It does nothing unless UpstreamTiming is part of the chain.
+func MarkUpstreamStart(ctx *fasthttp.RequestCtx) {
+	upstreamMark(ctx, func(m *upstreamMarks, t time.Time) { m.start = t })
+}
+
+// MarkFirstByte records that the first byte of the upstream response
+// was received.
+// It does nothing unless UpstreamTiming is part of the chain.
+func MarkFirstByte(ctx *fasthttp.RequestCtx) {
+	upstreamMark(ctx, func(m *upstreamMarks, t time.Time) { m.firstByte = t })
+}
+
+// MarkUpstreamEnd records that the upstream call is over.
+// It does nothing unless UpstreamTiming is part of the chain.
+func MarkUpstreamEnd(ctx *fasthttp.RequestCtx) {
+	upstreamMark(ctx, func(m *upstreamMarks, t time.Time) { m.end = t })
+}
+
+// GetUpstreamTimings returns the upstream timings recorded
+// for the request.
+// ok is false when no upstream call was marked as started.
+// Phases that were not marked are reported as zero.
+func GetUpstreamTimings(ctx *fasthttp.RequestCtx) (timings UpstreamTimings, ok bool) {
+	m, _ := Get(ctx, upstreamTimingKey)
+	if m == nil || m.start.IsZero() {
+		return UpstreamTimings{}, false
+	}
+
+	timings.Start = m.start.Sub(m.received)
+	if !m.firstByte.IsZero() {
+		timings.FirstByte = m.firstByte.Sub(m.start)
+	}
+	if !m.end.IsZero() {
+		timings.Total = m.end.Sub(m.start)
+	}
+	return timings, true
+}
@@ -0,0 +1,47 @@
+package fastalice
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestUpstreamTimingInAccessLog(t *testing.T) {
+	defer fakeClock(time.Millisecond)()
+
+	var entry string
+	accessLog := After(func(ctx *fasthttp.RequestCtx) {
+		timings, ok := GetUpstreamTimings(ctx)
+		if ok {
+			entry = fmt.Sprintf("%s %s %d %s", ctx.Method(), ctx.Path(), ctx.Response.StatusCode(), timings)
+		}
+	})
+	proxy := func(ctx *fasthttp.RequestCtx) {
+		MarkUpstreamStart(ctx)
+		MarkFirstByte(ctx)
+		MarkUpstreamEnd(ctx)
+		ctx.SetStatusCode(fasthttp.StatusOK)
+	}
+
+	New(accessLog, UpstreamTiming()).Then(proxy)(newTestCtx("GET", "http://localhost/api"))
+	assert.Equal(t, "GET /api 200 upstream_start=1ms upstream_first_byte=1ms upstream_total=2ms", entry, "The access log should include the upstream breakdown")
+}
+
+func TestUpstreamTimingWithoutUpstreamCall(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(UpstreamTiming()).Then(testApp)(ctx)
+
+	_, ok := GetUpstreamTimings(ctx)
+	assert.False(t, ok, "No timings should be reported without an upstream call")
+}
+
+func TestUpstreamMarksWithoutMiddleware(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	MarkUpstreamStart(ctx)
+
+	_, ok := GetUpstreamTimings(ctx)
+	assert.False(t, ok, "Marks should be ignored without UpstreamTiming")
+}
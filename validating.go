@@ -0,0 +1,33 @@
+package fastalice
+
+import (
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ValidatingConstructor is middleware whose configuration
+// can be checked before it serves requests,
+// such as middleware loading TLS certificates or JWKS URLs.
+type ValidatingConstructor interface {
+	// Validate reports whether the middleware is correctly configured.
+	Validate() error
+	// Wrap returns the handler running the middleware before next.
+	Wrap(next fasthttp.RequestHandler) fasthttp.RequestHandler
+}
+
+// Validated returns a constructor calling v.Validate
+// each time the chain is composed, before wrapping with v,
+// so that a misconfigured middleware fails chain construction
+// instead of its requests: ThenE and Validate return the error,
+// Then panics with it.
+//
+//	handler, err := fastalice.New(fastalice.Validated(jwks)).ThenE(app)
+func Validated(v ValidatingConstructor) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		if err := v.Validate(); err != nil {
+			panic(constructError{fmt.Errorf("fastalice: invalid %T: %w", v, err)})
+		}
+		return v.Wrap(next)
+	}
+}
@@ -0,0 +1,40 @@
+package fastalice
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// certMiddleware is a ValidatingConstructor requiring a certificate path.
+type certMiddleware struct {
+	path string
+}
+
+func (m certMiddleware) Validate() error {
+	if m.path == "" {
+		return errors.New("missing certificate")
+	}
+	return nil
+}
+
+func (m certMiddleware) Wrap(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return tagMiddleware("cert")(next)
+}
+
+func TestValidatedThenE(t *testing.T) {
+	h, err := New(Validated(certMiddleware{path: "cert.pem"})).ThenE(testApp)
+	if assert.NoError(t, err, "Valid middleware should compose") {
+		ctx := newTestCtx("GET", "http://localhost/")
+		h(ctx)
+		assert.Equal(t, "certapp", string(ctx.Response.Body()), "Valid middleware should run")
+	}
+
+	chain := New(tagMiddleware("t"), Validated(certMiddleware{}))
+	_, err = chain.ThenE(testApp)
+	assert.EqualError(t, err, "fastalice: invalid fastalice.certMiddleware: missing certificate", "ThenE should return the validation error")
+	assert.EqualError(t, chain.Validate(), "fastalice: constructor at index 1 failed: fastalice: invalid fastalice.certMiddleware: missing certificate", "Validate should name the failing constructor")
+	assert.Panics(t, func() { chain.Then(testApp) }, "Then should panic on invalid middleware")
+}
@@ -0,0 +1,86 @@
+package fastalice
+
+import (
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// vhostConfig holds the settings of VHost.
+type vhostConfig struct {
+	fallback fasthttp.RequestHandler
+}
+
+// VHostOption configures VHost.
+type VHostOption func(*vhostConfig)
+
+// VHostDefault sets the handler serving requests for unknown hosts,
+// which defaults to the default handler of Then().
+func VHostDefault(h fasthttp.RequestHandler) VHostOption {
+	return func(c *vhostConfig) { c.fallback = h }
+}
+
+// vhostWildcard is a host pattern such as "*.example.com".
+type vhostWildcard struct {
+	suffix  string
+	handler fasthttp.RequestHandler
+}
+
+// VHost returns a handler serving each request
+// with the handler of its Host, so that one server
+// can serve several sites, each with its own chain:
+//
+//	fastalice.VHost(map[string]fasthttp.RequestHandler{
+//		"example.com":     siteChain.Then(site),
+//		"api.example.com": apiChain.Then(api),
+//		"*.example.com":   blogChain.Then(blog),
+//	}, fastalice.VHostDefault(notFound))
+//
+// Hosts are matched without their port and regardless of case.
+// A pattern starting with "*." matches any subdomain of the rest,
+// exact hosts winning over patterns and longer patterns over shorter ones.
+// The map is copied, so later changes to it do not affect the handler.
+func VHost(hosts map[string]fasthttp.RequestHandler, opts ...VHostOption) fasthttp.RequestHandler {
+	cfg := vhostConfig{fallback: serveDefault}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.fallback == nil {
+		cfg.fallback = serveDefault
+	}
+
+	exact := make(map[string]fasthttp.RequestHandler)
+	var wildcards []vhostWildcard
+	for host, h := range hosts {
+		host = strings.ToLower(host)
+		if strings.HasPrefix(host, "*.") {
+			wildcards = append(wildcards, vhostWildcard{host[1:], h})
+		} else {
+			exact[host] = h
+		}
+	}
+	sort.Slice(wildcards, func(i, j int) bool {
+		return len(wildcards[i].suffix) > len(wildcards[j].suffix)
+	})
+
+	return func(ctx *fasthttp.RequestCtx) {
+		host := strings.ToLower(string(ctx.Host()))
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+
+		if h, ok := exact[host]; ok {
+			h(ctx)
+			return
+		}
+		for _, w := range wildcards {
+			if len(host) > len(w.suffix) && strings.HasSuffix(host, w.suffix) {
+				w.handler(ctx)
+				return
+			}
+		}
+		cfg.fallback(ctx)
+	}
+}
@@ -0,0 +1,35 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestVHost(t *testing.T) {
+	site := func(name string) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) { ctx.SetBodyString(name) }
+	}
+	h := VHost(map[string]fasthttp.RequestHandler{
+		"example.com":        site("main"),
+		"api.example.com":    site("api"),
+		"*.example.com":      site("wildcard"),
+		"*.shop.example.com": site("shop"),
+	}, VHostDefault(site("default")))
+
+	for host, want := range map[string]string{
+		"example.com":         "main",
+		"EXAMPLE.com:8080":    "main",
+		"api.example.com":     "api",
+		"blog.example.com":    "wildcard",
+		"a.b.example.com":     "wildcard",
+		"eu.shop.example.com": "shop",
+		"other.org":           "default",
+		"notexample.com":      "default",
+	} {
+		ctx := newTestCtx("GET", "http://"+host+"/")
+		h(ctx)
+		assert.Equal(t, want, string(ctx.Response.Body()), "Host %q should be served by its site", host)
+	}
+}
@@ -0,0 +1,151 @@
+package fastalice
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// WebhookTolerance is how far from now the timestamp
+// of a webhook accepted by WebhookVerify may be.
+var WebhookTolerance = 5 * time.Minute
+
+// WebhookScheme is a webhook signature scheme.
+type WebhookScheme int
+
+const (
+	// GitHubWebhook is the scheme of GitHub webhooks:
+	// an X-Hub-Signature-256 header holding "sha256="
+	// and the hex HMAC-SHA256 of the body.
+	// It has no timestamp to check.
+	GitHubWebhook WebhookScheme = iota
+	// StripeWebhook is the scheme of Stripe webhooks:
+	// a Stripe-Signature header holding a timestamp t
+	// and one or more v1 hex HMAC-SHA256 of "t.body".
+	StripeWebhook
+	// SlackWebhook is the scheme of Slack requests:
+	// an X-Slack-Request-Timestamp header holding a timestamp t
+	// and an X-Slack-Signature header holding "v0="
+	// and the hex HMAC-SHA256 of "v0:t:body".
+	SlackWebhook
+)
+
+var webhookSchemeNames = [...]string{"github", "stripe", "slack"}
+
+func (s WebhookScheme) String() string {
+	if s < 0 || int(s) >= len(webhookSchemeNames) {
+		return "WebhookScheme(" + strconv.Itoa(int(s)) + ")"
+	}
+	return webhookSchemeNames[s]
+}
+
+// WebhookVerify returns a constructor verifying that webhook requests
+// were signed with secret according to scheme before the following handlers run.
+// Requests with a missing or invalid signature, or with a timestamp
+// further than WebhookTolerance from now, are answered with 401 Unauthorized,
+// without calling the following handlers.
+//
+// Within WebhookTolerance a captured request can still be replayed;
+// RejectReplays prevents it:
+//
+//	fastalice.WebhookVerify(secret, fastalice.StripeWebhook,
+//		fastalice.RejectReplays(fastalice.NewMemoryNonceStore(fastalice.WebhookTolerance)))
+func WebhookVerify(secret []byte, scheme WebhookScheme, opts ...VerifyOption) Constructor {
+	cfg := newVerifyConfig(opts)
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			sig, err := verifyWebhook(&ctx.Request, secret, scheme)
+			if err != nil {
+				ctx.Error(fasthttp.StatusMessage(fasthttp.StatusUnauthorized), fasthttp.StatusUnauthorized)
+				return
+			}
+			if cfg.replayed(ctx, sig) {
+				return
+			}
+			next(ctx)
+		}
+	}
+}
+
+// verifyWebhook checks the signature of req under scheme,
+// returning the verified signature.
+func verifyWebhook(req *fasthttp.Request, secret []byte, scheme WebhookScheme) (string, error) {
+	h := &req.Header
+	switch scheme {
+	case GitHubWebhook:
+		sig := string(h.Peek("X-Hub-Signature-256"))
+		if !strings.HasPrefix(sig, "sha256=") {
+			return "", errors.New("fastalice: missing webhook signature")
+		}
+		return sig, checkWebhookMAC(secret, req.Body(), sig[len("sha256="):])
+
+	case StripeWebhook:
+		var ts string
+		var sigs []string
+		for _, part := range strings.Split(string(h.Peek("Stripe-Signature")), ",") {
+			k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch k {
+			case "t":
+				ts = v
+			case "v1":
+				sigs = append(sigs, v)
+			}
+		}
+		if err := checkWebhookTimestamp(ts); err != nil {
+			return "", err
+		}
+		payload := append([]byte(ts+"."), req.Body()...)
+		for _, sig := range sigs {
+			if checkWebhookMAC(secret, payload, sig) == nil {
+				return sig, nil
+			}
+		}
+		return "", errors.New("fastalice: invalid webhook signature")
+
+	case SlackWebhook:
+		ts := string(h.Peek("X-Slack-Request-Timestamp"))
+		if err := checkWebhookTimestamp(ts); err != nil {
+			return "", err
+		}
+		sig := string(h.Peek("X-Slack-Signature"))
+		if !strings.HasPrefix(sig, "v0=") {
+			return "", errors.New("fastalice: missing webhook signature")
+		}
+		return sig, checkWebhookMAC(secret, append([]byte("v0:"+ts+":"), req.Body()...), sig[len("v0="):])
+	}
+	return "", errors.New("fastalice: unknown webhook scheme " + scheme.String())
+}
+
+// checkWebhookTimestamp checks that the Unix timestamp ts
+// is within WebhookTolerance of now.
+func checkWebhookTimestamp(ts string) error {
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errors.New("fastalice: missing webhook timestamp")
+	}
+	if d := now().Sub(time.Unix(sec, 0)); d > WebhookTolerance || d < -WebhookTolerance {
+		return errors.New("fastalice: webhook timestamp out of tolerance")
+	}
+	return nil
+}
+
+// checkWebhookMAC checks that sig is the hex HMAC-SHA256 of payload.
+func checkWebhookMAC(secret, payload []byte, sig string) error {
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return errors.New("fastalice: malformed webhook signature")
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	if !hmac.Equal(mac.Sum(nil), got) {
+		return errors.New("fastalice: invalid webhook signature")
+	}
+	return nil
+}
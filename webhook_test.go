@@ -0,0 +1,84 @@
+package fastalice
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func webhookMAC(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func webhookCtx(body string, headers map[string]string) *fasthttp.RequestCtx {
+	ctx := newTestCtx("POST", "http://localhost/hooks")
+	ctx.Request.SetBodyString(body)
+	for k, v := range headers {
+		ctx.Request.Header.Set(k, v)
+	}
+	return ctx
+}
+
+func TestWebhookVerifyGitHub(t *testing.T) {
+	h := New(WebhookVerify([]byte("s3cret"), GitHubWebhook)).Then(testApp)
+	body := `{"action":"opened"}`
+
+	ctx := webhookCtx(body, map[string]string{"X-Hub-Signature-256": "sha256=" + webhookMAC("s3cret", body)})
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "A valid GitHub signature should be accepted")
+
+	ctx = webhookCtx(body, map[string]string{"X-Hub-Signature-256": "sha256=" + webhookMAC("other", body)})
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "A signature with another secret should be rejected")
+
+	ctx = webhookCtx(body, nil)
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "A missing signature should be rejected")
+}
+
+func TestWebhookVerifyStripe(t *testing.T) {
+	defer fakeClock(0)()
+	ts := strconv.FormatInt(now().Unix(), 10)
+	h := New(WebhookVerify([]byte("whsec"), StripeWebhook)).Then(testApp)
+	body := `{"type":"charge.succeeded"}`
+
+	sig := "t=" + ts + ",v1=" + webhookMAC("old", ts+"."+body) + ",v1=" + webhookMAC("whsec", ts+"."+body)
+	ctx := webhookCtx(body, map[string]string{"Stripe-Signature": sig})
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "Any matching v1 signature should be accepted")
+
+	ctx = webhookCtx(`{"type":"charge.refunded"}`, map[string]string{"Stripe-Signature": sig})
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "A changed payload should be rejected")
+
+	old := strconv.FormatInt(now().Add(-WebhookTolerance-time.Second).Unix(), 10)
+	ctx = webhookCtx(body, map[string]string{"Stripe-Signature": "t=" + old + ",v1=" + webhookMAC("whsec", old+"."+body)})
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "A timestamp outside of the tolerance should be rejected")
+}
+
+func TestWebhookVerifySlack(t *testing.T) {
+	defer fakeClock(0)()
+	ts := strconv.FormatInt(now().Unix(), 10)
+	h := New(WebhookVerify([]byte("slack"), SlackWebhook)).Then(testApp)
+	body := "token=x&command=/deploy"
+
+	ctx := webhookCtx(body, map[string]string{
+		"X-Slack-Request-Timestamp": ts,
+		"X-Slack-Signature":         "v0=" + webhookMAC("slack", "v0:"+ts+":"+body),
+	})
+	h(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "A valid Slack signature should be accepted")
+
+	ctx = webhookCtx(body, map[string]string{"X-Slack-Signature": "v0=" + webhookMAC("slack", "v0:"+ts+":"+body)})
+	h(ctx)
+	assert.Equal(t, fasthttp.StatusUnauthorized, ctx.Response.StatusCode(), "A missing timestamp should be rejected")
+}
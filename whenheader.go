@@ -0,0 +1,32 @@
+package fastalice
+
+import (
+	"github.com/valyala/fasthttp"
+)
+
+// WhenHeader returns a constructor that picks, for each request,
+// between two middleware depending on a request header:
+// ifMatch is applied when the header name equals value,
+// ifNot otherwise.
+// A nil alternative simply passes the request through.
+//
+// Both alternatives are constructed once, upon a call to Then().
+func WhenHeader(name, value string, ifMatch Constructor, ifNot Constructor) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		matched, notMatched := next, next
+		if ifMatch != nil {
+			matched = ifMatch(next)
+		}
+		if ifNot != nil {
+			notMatched = ifNot(next)
+		}
+
+		return func(ctx *fasthttp.RequestCtx) {
+			if string(ctx.Request.Header.Peek(name)) == value {
+				matched(ctx)
+				return
+			}
+			notMatched(ctx)
+		}
+	}
+}
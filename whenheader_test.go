@@ -0,0 +1,27 @@
+package fastalice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWhenHeaderMatch(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set("X-API-Version", "2")
+	New(WhenHeader("X-API-Version", "2", tagMiddleware("v2\n"), tagMiddleware("v1\n"))).Then(testApp)(ctx)
+	assert.Equal(t, "v2\napp", string(ctx.Response.Body()), "ifMatch should apply when the header matches")
+}
+
+func TestWhenHeaderNoMatch(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	ctx.Request.Header.Set("X-API-Version", "3")
+	New(WhenHeader("X-API-Version", "2", tagMiddleware("v2\n"), tagMiddleware("v1\n"))).Then(testApp)(ctx)
+	assert.Equal(t, "v1\napp", string(ctx.Response.Body()), "ifNot should apply when the header does not match")
+}
+
+func TestWhenHeaderNilAlternative(t *testing.T) {
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(WhenHeader("X-API-Version", "2", tagMiddleware("v2\n"), nil)).Then(testApp)(ctx)
+	assert.Equal(t, "app", string(ctx.Response.Body()), "A nil alternative should pass the request through")
+}
@@ -0,0 +1,141 @@
+package fastalice
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+// requestCtxKey is the key under which WrapHTTP
+// stores the request context, so the net/http side can reach it
+// through the request's context.Context.
+var requestCtxKey = NewKey[*fasthttp.RequestCtx]("requestCtx")
+
+// WrapHTTP adapts a net/http middleware into a Constructor,
+// easing a gradual migration from net/http.
+//
+// The request is converted with fasthttpadaptor
+// and passed through mw. When mw calls its handler,
+// changes it made to the request are copied back
+// before the following handlers run,
+// and their response is handed back to mw.
+// Whatever mw finally writes becomes the response.
+//
+// Any net/http middleware of that shape fits,
+// such as gorilla/handlers or chi middleware.
+//
+// The conversion has a cost on every request,
+// several times that of a native constructor
+// as measured by BenchmarkWrapHTTP,
+// so native constructors should be preferred on hot paths.
+func WrapHTTP(mw func(http.Handler) http.Handler) Constructor {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, ok := r.Context().Value(requestCtxKey.String()).(*fasthttp.RequestCtx)
+			if !ok {
+				http.Error(w, "fastalice: request context lost by net/http middleware", http.StatusInternalServerError)
+				return
+			}
+
+			copyHTTPRequest(&ctx.Request, r)
+			next(ctx)
+			writeHTTPResponse(w, &ctx.Response)
+		})
+		outer := fasthttpadaptor.NewFastHTTPHandler(mw(inner))
+
+		return func(ctx *fasthttp.RequestCtx) {
+			Set(ctx, requestCtxKey, ctx)
+			outer(ctx)
+		}
+	}
+}
+
+// ThenHTTP chains the middleware with a net/http handler
+// and returns a net/http handler,
+// so one chain definition can serve both stacks.
+//
+// Each request is converted to a fasthttp request,
+// passed through the chain, and converted back
+// with fasthttpadaptor before reaching h.
+// The handler h sees a request whose context.Context
+// is the fasthttp request context, not the original one.
+func (c Chain) ThenHTTP(h http.Handler) http.Handler {
+	handler := c.Then(fasthttpadaptor.NewFastHTTPHandler(h))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req fasthttp.Request
+		copyHTTPRequest(&req, r)
+
+		var ctx fasthttp.RequestCtx
+		ctx.Init(&req, remoteAddr(r.RemoteAddr), nil)
+		handler(&ctx)
+		writeHTTPResponse(w, &ctx.Response)
+	})
+}
+
+// remoteAddr parses the RemoteAddr of a net/http request,
+// returning nil when it is not an IP and port.
+func remoteAddr(addr string) net.Addr {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil
+	}
+	p, _ := strconv.Atoi(port)
+	return &net.TCPAddr{IP: net.ParseIP(host), Port: p}
+}
+
+// copyHTTPRequest copies the method, URI, headers and body
+// of a net/http request into a fasthttp one.
+func copyHTTPRequest(dst *fasthttp.Request, r *http.Request) {
+	dst.Header.SetMethod(r.Method)
+	dst.SetRequestURI(r.URL.RequestURI())
+	if r.Host != "" {
+		dst.Header.SetHost(r.Host)
+	}
+
+	var stale []string
+	dst.Header.VisitAll(func(k, v []byte) {
+		switch string(k) {
+		case fasthttp.HeaderHost, fasthttp.HeaderContentLength:
+		default:
+			stale = append(stale, string(k))
+		}
+	})
+	for _, k := range stale {
+		dst.Header.Del(k)
+	}
+	for k, vv := range r.Header {
+		for _, v := range vv {
+			dst.Header.Add(k, v)
+		}
+	}
+
+	if r.Body != nil {
+		if body, err := ioutil.ReadAll(r.Body); err == nil {
+			dst.SetBody(body)
+		}
+	}
+}
+
+// writeHTTPResponse writes a fasthttp response
+// to a net/http response writer, resetting its body
+// so it is not written twice once converted back.
+func writeHTTPResponse(w http.ResponseWriter, resp *fasthttp.Response) {
+	resp.Header.VisitAll(func(k, v []byte) {
+		switch string(k) {
+		case fasthttp.HeaderContentLength, fasthttp.HeaderDate, fasthttp.HeaderServer:
+		default:
+			w.Header().Add(string(k), string(v))
+		}
+	})
+	resp.Header.VisitAllCookie(func(_, v []byte) {
+		w.Header().Add(fasthttp.HeaderSetCookie, string(v))
+	})
+	w.WriteHeader(resp.StatusCode())
+	w.Write(resp.Body())
+	resp.ResetBody()
+}
@@ -0,0 +1,100 @@
+package fastalice
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func httpHeaderMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-From-HTTP", "yes")
+		r.Header.Set("X-Seen-By-HTTP", "yes")
+		next.ServeHTTP(w, r)
+	})
+}
+
+func TestWrapHTTPPassesThrough(t *testing.T) {
+	var seen string
+	h := New(WrapHTTP(httpHeaderMiddleware)).Then(func(ctx *fasthttp.RequestCtx) {
+		seen = string(ctx.Request.Header.Peek("X-Seen-By-HTTP"))
+		ctx.SetStatusCode(fasthttp.StatusCreated)
+		ctx.Response.Header.Set("X-From-Fast", "yes")
+		ctx.WriteString("app")
+	})
+
+	ctx := newTestCtx("POST", "http://localhost/items?x=1")
+	h(ctx)
+	assert.Equal(t, "yes", seen, "Request changes made by the net/http middleware should be visible downstream")
+	assert.Equal(t, fasthttp.StatusCreated, ctx.Response.StatusCode(), "The downstream status should be kept")
+	assert.Equal(t, "yes", string(ctx.Response.Header.Peek("X-From-HTTP")), "Headers set by the net/http middleware should appear")
+	assert.Equal(t, "yes", string(ctx.Response.Header.Peek("X-From-Fast")), "Headers set downstream should be kept")
+	assert.Equal(t, "app", string(ctx.Response.Body()), "The downstream body should be written once")
+}
+
+func TestWrapHTTPShortCircuit(t *testing.T) {
+	deny := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "denied", http.StatusForbidden)
+		})
+	}
+
+	ctx := newTestCtx("GET", "http://localhost/")
+	New(WrapHTTP(deny)).Then(testApp)(ctx)
+	assert.Equal(t, fasthttp.StatusForbidden, ctx.Response.StatusCode(), "The net/http middleware should be able to reject requests")
+	assert.Equal(t, "denied\n", string(ctx.Response.Body()), "The app should not be reached")
+}
+
+// benchmarkHeaderMiddleware serves requests through mw
+// to a handler writing a small body, documenting the cost
+// of the net/http conversion against a native constructor.
+func benchmarkHeaderMiddleware(b *testing.B, mw Constructor) {
+	h := New(mw).Then(testApp)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ctx := newTestCtx("GET", "http://localhost/")
+		ctx.Request.Header.Set("X-Client", "bench")
+		h(ctx)
+	}
+}
+
+func BenchmarkWrapHTTP(b *testing.B) {
+	benchmarkHeaderMiddleware(b, WrapHTTP(httpHeaderMiddleware))
+}
+
+func BenchmarkNativeHeaderMiddleware(b *testing.B) {
+	benchmarkHeaderMiddleware(b, SetHeaders(map[string]string{"X-From-HTTP": "yes"}))
+}
+
+func TestThenHTTP(t *testing.T) {
+	var seenIP, seenHeader string
+	h := New(tagMiddleware("t1\n"), func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			seenIP = ctx.RemoteIP().String()
+			ctx.Request.Header.Set("X-From-Chain", "yes")
+			next(ctx)
+		}
+	}).ThenHTTP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenHeader = r.Header.Get("X-From-Chain")
+		w.Header().Set("X-From-Handler", "yes")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("http"))
+	}))
+
+	r := httptest.NewRequest("POST", "http://example.com/items?x=1", strings.NewReader("body"))
+	r.RemoteAddr = "10.0.0.1:1234"
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusCreated, w.Code, "The handler status should be kept")
+	assert.Equal(t, "t1\nhttp", w.Body.String(), "The chain and the handler should both write the body")
+	assert.Equal(t, "yes", w.Header().Get("X-From-Handler"), "The handler headers should be kept")
+	assert.Equal(t, "yes", seenHeader, "Changes made by the chain should reach the handler")
+	assert.Equal(t, "10.0.0.1", seenIP, "The chain should see the client IP")
+}